@@ -0,0 +1,22 @@
+variable "eu_api_key" {
+  type      = string
+  sensitive = true
+}
+
+# Verify that every rule was copied from the old cell to the new one.
+data "grafana-adaptive-metrics_cell_diff" "migration" {
+  cell_a = {
+    url = "https://prometheus-prod-01-eu-west-0.grafana.net"
+  }
+  cell_b = {
+    url     = "https://prometheus-prod-24-prod-eu-west-2.grafana.net"
+    api_key = var.eu_api_key
+  }
+}
+
+check "migration_complete" {
+  assert {
+    condition     = length(data.grafana-adaptive-metrics_cell_diff.migration.only_in_a) == 0 && length(data.grafana-adaptive-metrics_cell_diff.migration.differing) == 0
+    error_message = "Some rules of the old cell are missing or differ in the new cell."
+  }
+}
@@ -0,0 +1,10 @@
+data "grafana-adaptive-metrics_change_report" "review" {
+  rules_json = file("${path.module}/rules.json")
+  prune      = true
+}
+
+# Written for a PR bot to comment on the changes to the rules.
+resource "local_file" "change_report" {
+  filename = "${path.module}/change_report.json"
+  content  = data.grafana-adaptive-metrics_change_report.review.json
+}
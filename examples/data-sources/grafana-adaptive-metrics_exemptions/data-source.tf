@@ -0,0 +1,5 @@
+data "grafana-adaptive-metrics_exemptions" "all" {}
+
+output "exempt_metrics" {
+  value = [for e in data.grafana-adaptive-metrics_exemptions.all.exemptions : e.metric]
+}
@@ -0,0 +1,9 @@
+data "grafana-adaptive-metrics_impact_preview" "http_requests" {
+  metric       = "http_requests_total"
+  drop_labels  = ["pod", "instance"]
+  aggregations = ["sum:counter"]
+}
+
+output "http_requests_reduction" {
+  value = data.grafana-adaptive-metrics_impact_preview.http_requests.estimate_available ? "${data.grafana-adaptive-metrics_impact_preview.http_requests.series_before} -> ${data.grafana-adaptive-metrics_impact_preview.http_requests.series_after} series (${data.grafana-adaptive-metrics_impact_preview.http_requests.reduction_percent}% fewer)" : "estimate unavailable"
+}
@@ -0,0 +1,11 @@
+data "grafana-adaptive-metrics_metric_evaluation" "http_requests" {
+  metric = "http_requests_total"
+  labels = {
+    pod    = "api-0"
+    method = "GET"
+  }
+}
+
+output "http_requests_rule" {
+  value = "${data.grafana-adaptive-metrics_metric_evaluation.http_requests.outcome} by ${coalesce(data.grafana-adaptive-metrics_metric_evaluation.http_requests.rule_metric, "no rule")}"
+}
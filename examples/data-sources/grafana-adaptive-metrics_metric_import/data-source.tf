@@ -0,0 +1,9 @@
+data "grafana-adaptive-metrics_metric_import" "http_requests_total" {
+  metric = "http_requests_total"
+}
+
+# Run `terraform output -raw http_requests_total_import > http_requests_total.tf`,
+# then apply to import the rule and the exemption of the metric together.
+output "http_requests_total_import" {
+  value = data.grafana-adaptive-metrics_metric_import.http_requests_total.hcl
+}
@@ -0,0 +1,6 @@
+data "grafana-adaptive-metrics_orphaned_series" "all" {}
+
+# The metrics whose aggregated series are left over from deleted rules.
+output "orphaned_metrics" {
+  value = [for o in data.grafana-adaptive-metrics_orphaned_series.all.orphans : o.metric]
+}
@@ -0,0 +1,15 @@
+data "grafana-adaptive-metrics_query_metrics" "dashboards" {
+  expressions = [
+    "sum by (route) (rate(http_requests_total{job=\"api\"}[5m]))",
+    "histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))",
+  ]
+}
+
+# Keep only the labels used by the dashboards for every metric they query.
+resource "grafana-adaptive-metrics_rule" "dashboards" {
+  for_each = toset(data.grafana-adaptive-metrics_query_metrics.dashboards.metrics)
+
+  metric       = each.value
+  keep_labels  = ["job", "route", "le"]
+  aggregations = ["sum:counter"]
+}
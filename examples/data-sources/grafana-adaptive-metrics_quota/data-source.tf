@@ -0,0 +1,9 @@
+data "grafana-adaptive-metrics_quota" "current" {}
+
+# Fail the plan when the stack is about to run out of aggregation rules.
+check "rule_quota" {
+  assert {
+    condition     = coalesce(data.grafana-adaptive-metrics_quota.current.remaining_rule_slots, 100) >= 10
+    error_message = "Fewer than 10 aggregation rules can still be created."
+  }
+}
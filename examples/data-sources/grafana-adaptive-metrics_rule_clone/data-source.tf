@@ -0,0 +1,41 @@
+# A rule for kube_node_info set up like the existing rule for kube_pod_info.
+data "grafana-adaptive-metrics_rule_clone" "node_info" {
+  source_metric = "kube_pod_info"
+  metric        = "kube_node_info"
+}
+
+resource "grafana-adaptive-metrics_rule" "node_info" {
+  metric                = data.grafana-adaptive-metrics_rule_clone.node_info.metric
+  match_type            = data.grafana-adaptive-metrics_rule_clone.node_info.match_type
+  drop                  = data.grafana-adaptive-metrics_rule_clone.node_info.drop
+  keep_labels           = data.grafana-adaptive-metrics_rule_clone.node_info.keep_labels
+  drop_labels           = data.grafana-adaptive-metrics_rule_clone.node_info.drop_labels
+  label_match_mode      = data.grafana-adaptive-metrics_rule_clone.node_info.label_match_mode
+  aggregations          = data.grafana-adaptive-metrics_rule_clone.node_info.aggregations
+  aggregation_interval  = data.grafana-adaptive-metrics_rule_clone.node_info.aggregation_interval
+  aggregation_intervals = data.grafana-adaptive-metrics_rule_clone.node_info.aggregation_intervals
+  aggregation_delay     = data.grafana-adaptive-metrics_rule_clone.node_info.aggregation_delay
+  priority              = data.grafana-adaptive-metrics_rule_clone.node_info.priority
+  rollout_percentage    = data.grafana-adaptive-metrics_rule_clone.node_info.rollout_percentage
+  ingest_sample_rate    = data.grafana-adaptive-metrics_rule_clone.node_info.ingest_sample_rate
+  tags                  = data.grafana-adaptive-metrics_rule_clone.node_info.tags
+
+  dynamic "drop_if" {
+    for_each = data.grafana-adaptive-metrics_rule_clone.node_info.drop_if
+    content {
+      label    = drop_if.value.label
+      operator = drop_if.value.operator
+      value    = drop_if.value.value
+    }
+  }
+
+  dynamic "delay_overrides" {
+    for_each = data.grafana-adaptive-metrics_rule_clone.node_info.delay_overrides
+    content {
+      label    = delay_overrides.value.label
+      operator = delay_overrides.value.operator
+      value    = delay_overrides.value.value
+      delay    = delay_overrides.value.delay
+    }
+  }
+}
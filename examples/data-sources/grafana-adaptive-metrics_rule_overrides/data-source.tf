@@ -0,0 +1,7 @@
+data "grafana-adaptive-metrics_rule_overrides" "all" {}
+
+# The exact rules which repeat the aggregation of the prefix rule they
+# override, and could be removed.
+output "redundant_overrides" {
+  value = [for o in data.grafana-adaptive-metrics_rule_overrides.all.overrides : o.metric if o.applied && !o.changes_behavior]
+}
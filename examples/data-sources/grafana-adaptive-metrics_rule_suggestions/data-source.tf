@@ -0,0 +1,16 @@
+# The metrics queried by a dashboard, for example extracted from its JSON model.
+locals {
+  dashboard_metrics = [
+    "http_request_duration_seconds_bucket",
+    "http_requests_total",
+    "process_cpu_seconds_total",
+  ]
+}
+
+data "grafana-adaptive-metrics_rule_suggestions" "dashboard" {
+  metrics = local.dashboard_metrics
+}
+
+output "unmatched_metrics" {
+  value = data.grafana-adaptive-metrics_rule_suggestions.dashboard.unmatched_metrics
+}
@@ -0,0 +1,22 @@
+data "grafana-adaptive-metrics_rule_validation" "all" {
+  rules = [
+    {
+      metric       = "http_requests_total"
+      drop_labels  = ["pod", "instance"]
+      aggregations = ["sum:counter"]
+    },
+    {
+      metric       = "process_cpu_seconds_total"
+      keep_labels  = ["service"]
+      aggregations = ["sum:counter"]
+    },
+  ]
+}
+
+# Fail the run if any rule is rejected by the API.
+check "rules_valid" {
+  assert {
+    condition     = data.grafana-adaptive-metrics_rule_validation.all.valid
+    error_message = join("\n", [for r in data.grafana-adaptive-metrics_rule_validation.all.results : "${r.metric}: ${r.error}" if !r.valid])
+  }
+}
@@ -0,0 +1,30 @@
+# Every rule.
+data "grafana-adaptive-metrics_rules" "all" {}
+
+# The rules of metrics starting with kube_.
+data "grafana-adaptive-metrics_rules" "kube" {
+  metric_prefix = "kube_"
+}
+
+# Filters combine: the suffix rules which drop their metric.
+data "grafana-adaptive-metrics_rules" "dropped_suffixes" {
+  match_type = "suffix"
+  drop       = true
+}
+
+# The rules owned by a team.
+data "grafana-adaptive-metrics_rules" "observability" {
+  tags = {
+    owner = "observability"
+  }
+}
+
+# The rules the backend files under a group, on backends which filter the
+# rules they list.
+data "grafana-adaptive-metrics_rules" "platform" {
+  group = "platform"
+}
+
+output "dropped_suffixes" {
+  value = [for r in data.grafana-adaptive-metrics_rules.dropped_suffixes.rules : r.metric]
+}
@@ -0,0 +1,15 @@
+# metric,match_type,drop_labels,aggregations
+# http_requests_total,,pod;instance,sum:counter;count
+data "grafana-adaptive-metrics_rules_csv" "rules" {
+  path = "${path.module}/rules.csv"
+}
+
+resource "grafana-adaptive-metrics_rule" "csv" {
+  for_each = { for rule in data.grafana-adaptive-metrics_rules_csv.rules.rules : rule.metric => rule }
+
+  metric       = each.value.metric
+  match_type   = each.value.match_type
+  drop_labels  = each.value.drop_labels
+  aggregations = each.value.aggregations
+  priority     = each.value.priority
+}
@@ -0,0 +1,7 @@
+data "grafana-adaptive-metrics_rules_hcl" "all" {
+}
+
+resource "local_file" "rules" {
+  filename = "${path.module}/rules.tf"
+  content  = data.grafana-adaptive-metrics_rules_hcl.all.hcl
+}
@@ -0,0 +1,7 @@
+data "grafana-adaptive-metrics_rules_snapshot" "backup" {}
+
+# Keep a copy of every rule, which can be restored with the rules_file resource.
+resource "local_file" "rules_backup" {
+  filename = "${path.module}/backups/rules.json"
+  content  = data.grafana-adaptive-metrics_rules_snapshot.backup.json
+}
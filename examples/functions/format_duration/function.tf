@@ -0,0 +1,5 @@
+# Provider functions require Terraform 1.8 or later.
+output "interval" {
+  # "1m30s"
+  value = provider::grafana-adaptive-metrics::format_duration(1.5 * 60)
+}
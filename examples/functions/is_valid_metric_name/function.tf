@@ -0,0 +1,12 @@
+# Provider functions require Terraform 1.8 or later.
+locals {
+  # Metric names collected from several teams, not all of them valid.
+  candidates = ["http_requests_total", "queue-depth", "node_cpu_seconds_total"]
+}
+
+resource "grafana-adaptive-metrics_rule" "drop" {
+  for_each = toset([for m in local.candidates : m if provider::grafana-adaptive-metrics::is_valid_metric_name(m)])
+
+  metric = each.value
+  drop   = true
+}
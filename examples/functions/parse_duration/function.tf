@@ -0,0 +1,13 @@
+# Provider functions require Terraform 1.8 or later.
+locals {
+  interval = "1m30s"
+}
+
+resource "grafana-adaptive-metrics_rule" "example" {
+  metric               = "http_requests_total"
+  drop_labels          = ["pod"]
+  aggregations         = ["sum:counter"]
+  aggregation_interval = local.interval
+  # Delay the aggregation by twice the interval.
+  aggregation_delay = provider::grafana-adaptive-metrics::format_duration(2 * provider::grafana-adaptive-metrics::parse_duration(local.interval))
+}
@@ -0,0 +1,20 @@
+# Provider functions require Terraform 1.8 or later.
+locals {
+  counter_policy = {
+    drop_labels          = ["pod", "instance"]
+    aggregations         = ["sum:counter"]
+    aggregation_interval = "1m"
+  }
+}
+
+resource "grafana-adaptive-metrics_rule_set" "counters" {
+  rules = [
+    provider::grafana-adaptive-metrics::rule_defaults(local.counter_policy, {
+      metric = "http_requests_total"
+    }),
+    provider::grafana-adaptive-metrics::rule_defaults(local.counter_policy, {
+      metric      = "grpc_server_handled_total"
+      drop_labels = ["pod"]
+    }),
+  ]
+}
@@ -0,0 +1,9 @@
+# Provider functions require Terraform 1.8 or later.
+data "grafana-adaptive-metrics_rules_snapshot" "all" {}
+
+# Writes the configuration adopting every existing rule. Moving the file into
+# the configuration and applying it imports the rules without changing them.
+resource "local_file" "adopted_rules" {
+  filename = "${path.module}/adopted_rules.tf.generated"
+  content  = provider::grafana-adaptive-metrics::rules_import_config(data.grafana-adaptive-metrics_rules_snapshot.all.json).hcl
+}
@@ -0,0 +1,12 @@
+# Provider functions require Terraform 1.8 or later.
+locals {
+  metrics = jsondecode(file("${path.module}/dropped_metrics.json"))
+}
+
+resource "grafana-adaptive-metrics_rule" "drop" {
+  for_each = toset(local.metrics)
+
+  # Fails the plan with an explanation if the file contains an invalid name.
+  metric = provider::grafana-adaptive-metrics::validate_metric_name(each.value)
+  drop   = true
+}
@@ -0,0 +1,2 @@
+# The default rule always has the ID "default".
+terraform import grafana-adaptive-metrics_default_rule.default default
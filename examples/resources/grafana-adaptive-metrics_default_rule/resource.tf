@@ -0,0 +1,10 @@
+resource "grafana-adaptive-metrics_default_rule" "default" {
+  drop_labels  = ["pod", "instance"]
+  aggregations = ["sum", "count"]
+}
+
+# Alternatively, drop every metric which is not matched by another rule.
+# resource "grafana-adaptive-metrics_default_rule" "default" {
+#   drop                   = true
+#   confirm_drop_unmatched = true
+# }
@@ -0,0 +1,5 @@
+# Exemptions can be imported by a comma-separated list of their metrics.
+terraform import grafana-adaptive-metrics_exemptions.slo http_requests_total,grpc_server_handled_total
+
+# Every existing exemption can be imported at once.
+terraform import grafana-adaptive-metrics_exemptions.slo '*'
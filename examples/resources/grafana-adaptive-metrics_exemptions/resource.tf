@@ -0,0 +1,8 @@
+resource "grafana-adaptive-metrics_exemptions" "slo" {
+  metrics = [
+    "http_requests_total",
+    "http_request_duration_seconds_bucket",
+    "grpc_server_handled_total",
+  ]
+  reason = "Used by SLO dashboards"
+}
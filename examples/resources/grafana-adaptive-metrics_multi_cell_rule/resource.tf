@@ -0,0 +1,16 @@
+# Apply the same rule to the stacks of two regions.
+resource "grafana-adaptive-metrics_multi_cell_rule" "agent_request_duration_seconds_sum" {
+  metric       = "agent_request_duration_seconds_sum"
+  drop_labels  = ["namespace", "pod"]
+  aggregations = ["sum:counter"]
+
+  target_cells = [
+    {
+      url = "https://prometheus-prod-13-prod-us-east-0.grafana.net"
+    },
+    {
+      url     = "https://prometheus-prod-24-prod-eu-west-2.grafana.net"
+      api_key = var.eu_api_key
+    },
+  ]
+}
@@ -0,0 +1,2 @@
+# Exact rules can be imported by a comma-separated list of their metrics.
+terraform import grafana-adaptive-metrics_multi_metric_rule.kube_state kube_pod_info,kube_pod_status_phase,kube_pod_container_status_restarts_total
@@ -0,0 +1,9 @@
+resource "grafana-adaptive-metrics_multi_metric_rule" "kube_state" {
+  metrics = [
+    "kube_pod_info",
+    "kube_pod_status_phase",
+    "kube_pod_container_status_restarts_total",
+  ]
+  drop_labels  = ["pod", "uid"]
+  aggregations = ["sum:counter"]
+}
@@ -0,0 +1,13 @@
+resource "grafana-adaptive-metrics_prefix_rename" "gateway" {
+  from_prefix = "api_"
+  to_prefix   = "gateway_"
+  on_conflict = "skip"
+}
+
+# The rule resource follows the rule to its new metric.
+resource "grafana-adaptive-metrics_rule" "requests" {
+  metric      = replace("api_requests_total", "/^api_/", "gateway_")
+  drop_labels = ["pod"]
+
+  depends_on = [grafana-adaptive-metrics_prefix_rename.gateway]
+}
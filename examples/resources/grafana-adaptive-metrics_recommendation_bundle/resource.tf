@@ -0,0 +1,4 @@
+resource "grafana-adaptive-metrics_recommendation_bundle" "checkout" {
+  label = "service"
+  value = "checkout"
+}
@@ -0,0 +1,8 @@
+# Rules can be imported by their metric.
+terraform import grafana-adaptive-metrics_rule.prometheus_request_duration_seconds_sum prometheus_request_duration_seconds_sum
+
+# Rules which have an ID assigned by the API can also be imported by their ID.
+terraform import grafana-adaptive-metrics_rule.prometheus_request_duration_seconds_sum 0b4c1c6e-5f36-4c9a-9d1e-8a2f3b7c6d5e
+
+# On backends which support rule names, rules can also be imported by their name.
+terraform import grafana-adaptive-metrics_rule.prometheus_request_duration_seconds_sum prometheus_request_duration
@@ -8,4 +8,71 @@ resource "grafana-adaptive-metrics_rule" "prometheus_request_duration_seconds_su
   metric       = "prometheus_request_duration_seconds_sum"
   drop_labels  = ["container", "instance", "ws"]
   aggregations = ["sum:counter"]
+
+  tags = {
+    owner       = "observability"
+    criticality = "high"
+  }
+}
+
+# Drop only the series of test environments.
+resource "grafana-adaptive-metrics_rule" "http_requests_total" {
+  metric = "http_requests_total"
+  drop   = true
+
+  drop_if {
+    label    = "env"
+    operator = "=~"
+    value    = "test|dev"
+  }
+}
+
+# Calculate every aggregation type supported by the API, including types
+# added in the future.
+resource "grafana-adaptive-metrics_rule" "queue_depth" {
+  metric       = "queue_depth"
+  drop_labels  = ["pod"]
+  aggregations = ["*"]
+}
+
+# Fail rather than wait when the API is slow to apply the rule.
+resource "grafana-adaptive-metrics_rule" "node_cpu_seconds_total" {
+  metric       = "node_cpu_seconds_total"
+  drop_labels  = ["cpu"]
+  aggregations = ["sum:counter"]
+
+  timeouts {
+    create = "2m"
+    update = "2m"
+  }
+}
+
+# Calculate latency percentiles across all pods.
+resource "grafana-adaptive-metrics_rule" "request_latency_seconds" {
+  metric       = "request_latency_seconds"
+  drop_labels  = ["pod"]
+  aggregations = ["p50", "p90", "p99", "p99.9"]
+}
+
+# Aggregate only a tenth of the matching series at first, and raise the
+# percentage once the impact on dashboards and alerts has been checked.
+resource "grafana-adaptive-metrics_rule" "http_requests_total" {
+  metric             = "http_requests_total"
+  drop_labels        = ["pod", "instance"]
+  aggregations       = ["sum:counter"]
+  rollout_percentage = 10
+}
+
+# Only create the rule while the recommendations service still suggests it.
+resource "grafana-adaptive-metrics_rule" "kube_pod_status_phase" {
+  metric                 = "kube_pod_status_phase"
+  drop_labels            = ["pod", "uid"]
+  aggregations           = ["sum"]
+  require_recommendation = true
+}
+
+# Apply one rule to several metric families instead of one rule per prefix.
+resource "grafana-adaptive-metrics_rule" "kubernetes" {
+  match_prefixes = ["kube_pod_", "kube_deployment_", "kube_node_"]
+  drop_labels    = ["uid"]
 }
@@ -0,0 +1,14 @@
+resource "grafana-adaptive-metrics_rule_set" "agent" {
+  rules = [
+    {
+      metric       = "agent_request_duration_seconds_sum"
+      drop_labels  = ["namespace", "pod"]
+      aggregations = ["sum:counter"]
+    },
+    {
+      metric       = "agent_request_duration_seconds_count"
+      drop_labels  = ["namespace", "pod"]
+      aggregations = ["sum:counter"]
+    },
+  ]
+}
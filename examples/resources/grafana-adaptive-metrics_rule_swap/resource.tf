@@ -0,0 +1,5 @@
+# Swap the configurations of the rules of two services for an A/B experiment.
+resource "grafana-adaptive-metrics_rule_swap" "experiment" {
+  metric_a = "checkout_requests_total"
+  metric_b = "payments_requests_total"
+}
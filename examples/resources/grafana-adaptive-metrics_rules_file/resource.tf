@@ -0,0 +1,8 @@
+# rules.json holds an array of rules in the format of the API, e.g.
+# [
+#   {"metric": "agent_request_duration_seconds_sum", "drop_labels": ["pod"], "aggregations": ["sum:counter"]},
+#   {"metric": "agent_build_info", "drop": true}
+# ]
+resource "grafana-adaptive-metrics_rules_file" "agent" {
+  path = "${path.module}/rules.json"
+}
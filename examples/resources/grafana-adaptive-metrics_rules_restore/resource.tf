@@ -0,0 +1,10 @@
+# Restore the rules to a backup taken with the rules_snapshot data source.
+# Apply once without confirm to review the changes, then set it to true.
+resource "grafana-adaptive-metrics_rules_restore" "backup" {
+  snapshot_json = file("${path.module}/backups/rules-2026-10-01.json")
+  confirm       = true
+}
+
+output "restore_changes" {
+  value = grafana-adaptive-metrics_rules_restore.backup.changes_json
+}
@@ -0,0 +1,21 @@
+# The histogram series are aggregated all together or not at all, so that
+# dashboards never see a bucket aggregated without its sum and count.
+resource "grafana-adaptive-metrics_transactional_rule_set" "http_duration" {
+  rules = [
+    {
+      metric       = "http_request_duration_seconds_bucket"
+      drop_labels  = ["pod"]
+      aggregations = ["sum:counter"]
+    },
+    {
+      metric       = "http_request_duration_seconds_sum"
+      drop_labels  = ["pod"]
+      aggregations = ["sum:counter"]
+    },
+    {
+      metric       = "http_request_duration_seconds_count"
+      drop_labels  = ["pod"]
+      aggregations = ["sum:counter"]
+    },
+  ]
+}
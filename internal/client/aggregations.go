@@ -2,8 +2,13 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
 )
@@ -11,11 +16,162 @@ import (
 const (
 	aggregationRulesEndpoint = "/aggregations/rules"
 	aggregationRuleEndpoint  = "/aggregations/rule/%s"
+
+	aggregationRuleByIDEndpoint = "/aggregations/rules/%s"
+
+	aggregationRulesEstimateEndpoint = "/aggregations/rules/estimate"
+	aggregationRulesValidateEndpoint = "/aggregations/rules/validate"
+
+	aggregatedMetricsEndpoint = "/aggregations/aggregated_metrics"
+
+	// aggregationRulesPageSize is the number of rules requested per page by
+	// ListAggregationRules, from backends which support FeatureRulePagination.
+	aggregationRulesPageSize = 1000
+	// nextPageTokenHeader carries the token of the next page of a paginated
+	// response; it is missing from the last page.
+	nextPageTokenHeader = "X-Next-Page-Token"
 )
 
+// AggregationRuleFilter selects the aggregation rules listed by
+// ListAggregationRules. Empty fields match every rule. Backends which support
+// FeatureRuleFilters filter the rules they list; the rules listed by other
+// backends are filtered by the client.
+type AggregationRuleFilter struct {
+	MetricPrefix string
+	MatchType    string
+	Drop         *bool
+	// Group is the group of rules which the backend files a rule under. It
+	// is only known to backends which support FeatureRuleFilters.
+	Group string
+	// Tags must all be set on a rule, with the same values.
+	Tags map[string]string
+}
+
+// Matches reports whether rule is selected by f, for backends which do not
+// filter the rules they list. The group of rules is not known to them, so it
+// is not matched.
+func (f AggregationRuleFilter) Matches(rule model.AggregationRule) bool {
+	if !strings.HasPrefix(rule.Metric, f.MetricPrefix) {
+		return false
+	}
+	if f.MatchType != "" {
+		matchType := rule.MatchType
+		if matchType == "" {
+			matchType = "exact"
+		}
+		if matchType != f.MatchType {
+			return false
+		}
+	}
+	if f.Drop != nil && rule.Drop != *f.Drop {
+		return false
+	}
+	for k, v := range f.Tags {
+		if value, ok := rule.Tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// params returns the query parameters which filter the listed rules on
+// backends which support FeatureRuleFilters. Each tag is a tag parameter of
+// the form key=value.
+func (f AggregationRuleFilter) params() url.Values {
+	params := url.Values{}
+	if f.MetricPrefix != "" {
+		params.Set("metric_prefix", f.MetricPrefix)
+	}
+	if f.MatchType != "" {
+		params.Set("match_type", f.MatchType)
+	}
+	if f.Drop != nil {
+		params.Set("drop", strconv.FormatBool(*f.Drop))
+	}
+	if f.Group != "" {
+		params.Set("group", f.Group)
+	}
+	keys := make([]string, 0, len(f.Tags))
+	for k := range f.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		params.Add("tag", k+"="+f.Tags[k])
+	}
+	return params
+}
+
+// fieldsParams selects the fields of the rules returned by a request, or
+// every field if fields is empty.
+func fieldsParams(fields []string) url.Values {
+	if len(fields) == 0 {
+		return nil
+	}
+	return url.Values{"fields": {strings.Join(fields, ",")}}
+}
+
+// ListAggregationRules requests the rules and calls fn with the ones matching
+// filter. If filtered is set, which requires FeatureRuleFilters, the filter is
+// sent to the API so that only the matching rules are listed; otherwise every
+// rule is listed and filtered by the client, and filter must not have a group.
+// If paginate is set, which requires FeatureRulePagination, the rules are
+// requested one page at a time and fn is called with each page, so that the
+// rules of large tenants are not all held in memory at once. Otherwise every
+// rule is requested at once. It stops at the first error returned by fn.
+func (c *Client) ListAggregationRules(filter AggregationRuleFilter, filtered, paginate bool, fn func(rules []model.AggregationRule) error) error {
+	params := url.Values{}
+	if filtered {
+		params = filter.params()
+	} else if filter.Group != "" {
+		return errors.New("the rules cannot be filtered by group: the API does not filter the rules it lists")
+	}
+	if paginate {
+		params.Set("page_size", strconv.Itoa(aggregationRulesPageSize))
+	}
+
+	for {
+		var page []model.AggregationRule
+		header, err := c.requestWithHeaders("GET", aggregationRulesEndpoint, params, nil, nil, &page)
+		if err != nil {
+			return err
+		}
+
+		matching := page
+		if !filtered {
+			matching = make([]model.AggregationRule, 0, len(page))
+			for _, rule := range page {
+				if filter.Matches(rule) {
+					matching = append(matching, rule)
+				}
+			}
+		}
+		if err := fn(matching); err != nil {
+			return err
+		}
+		if !paginate {
+			return nil
+		}
+
+		token := header.Get(nextPageTokenHeader)
+		if token == "" {
+			return nil
+		}
+		params.Set("page_token", token)
+	}
+}
+
 func (c *Client) AggregationRules() ([]model.AggregationRule, string, error) {
+	return c.AggregationRulesFields(nil)
+}
+
+// AggregationRulesFields is AggregationRules for backends which support
+// FeatureFieldSelection, returning only the given fields of the rules so that
+// fields the provider does not model, such as large server-side metadata, are
+// not sent. Every field is returned if fields is empty.
+func (c *Client) AggregationRulesFields(fields []string) ([]model.AggregationRule, string, error) {
 	var rules []model.AggregationRule
-	header, err := c.requestWithHeaders("GET", aggregationRulesEndpoint, nil, nil, nil, &rules)
+	header, err := c.requestWithHeaders("GET", aggregationRulesEndpoint, fieldsParams(fields), nil, nil, &rules)
 	if err != nil {
 		return rules, "", err
 	}
@@ -50,35 +206,52 @@ func (c *Client) UpdateAggregationRules(rules []model.AggregationRule, etag stri
 	return newEtag, nil
 }
 
-func (c *Client) CreateAggregationRule(rule model.AggregationRule, etag string) (string, error) {
+// savedRuleResponse is the response body of creating or updating a rule. It
+// may contain the saved rule, and non-fatal warnings about it.
+type savedRuleResponse struct {
+	model.AggregationRule
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// CreateAggregationRule creates rule and returns it as created, including the
+// ID assigned by the API if the response contains the created rule, as well as
+// any warnings the API returned about it.
+func (c *Client) CreateAggregationRule(rule model.AggregationRule, etag string) (model.AggregationRule, []string, string, error) {
 	body, err := json.Marshal(rule)
 	if err != nil {
-		return "", err
+		return rule, nil, "", err
 	}
 
 	reqHeader := make(http.Header)
 	reqHeader.Add("If-Match", etag)
 
-	endpoint := fmt.Sprintf(aggregationRuleEndpoint, rule.Metric)
+	endpoint := fmt.Sprintf(aggregationRuleEndpoint, pathSegment(rule.Metric))
 
-	respHeader, err := c.requestWithHeaders("POST", endpoint, nil, reqHeader, body, nil)
+	created := savedRuleResponse{AggregationRule: rule}
+	respHeader, err := c.requestWithHeaders("POST", endpoint, nil, reqHeader, body, &created)
 	if err != nil {
-		return "", err
+		return rule, nil, "", err
 	}
 
 	newEtag := respHeader.Get("ETag")
 	if newEtag == "" {
-		return "", fmt.Errorf("response from %s endpoint missing etag header", endpoint)
+		return rule, nil, "", fmt.Errorf("response from %s endpoint missing etag header", endpoint)
 	}
 
-	return newEtag, nil
+	return created.AggregationRule, created.Warnings, newEtag, nil
 }
 
 func (c *Client) ReadAggregationRule(metric string) (model.AggregationRule, string, error) {
+	return c.ReadAggregationRuleFields(metric, nil)
+}
+
+// ReadAggregationRuleFields is ReadAggregationRule returning only the given
+// fields of the rule, as AggregationRulesFields does.
+func (c *Client) ReadAggregationRuleFields(metric string, fields []string) (model.AggregationRule, string, error) {
 	rule := model.AggregationRule{}
-	endpoint := fmt.Sprintf(aggregationRuleEndpoint, metric)
+	endpoint := fmt.Sprintf(aggregationRuleEndpoint, pathSegment(metric))
 
-	respHeader, err := c.requestWithHeaders("GET", endpoint, nil, nil, nil, &rule)
+	respHeader, err := c.requestWithHeaders("GET", endpoint, fieldsParams(fields), nil, nil, &rule)
 	if err != nil {
 		return rule, "", err
 	}
@@ -91,35 +264,82 @@ func (c *Client) ReadAggregationRule(metric string) (model.AggregationRule, stri
 	return rule, newEtag, nil
 }
 
-func (c *Client) UpdateAggregationRule(rule model.AggregationRule, etag string) (string, error) {
+// UpdateAggregationRule replaces the rule with the ID of rule or, if it has
+// none, the rule for its metric. Updating by ID allows changing the metric.
+// The warnings returned by the API about the rule are returned.
+func (c *Client) UpdateAggregationRule(rule model.AggregationRule, etag string) ([]string, string, error) {
 	body, err := json.Marshal(rule)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	reqHeader := make(http.Header)
 	reqHeader.Add("If-Match", etag)
 
-	endpoint := fmt.Sprintf(aggregationRuleEndpoint, rule.Metric)
+	endpoint := fmt.Sprintf(aggregationRuleEndpoint, pathSegment(rule.Metric))
+	if rule.ID != "" {
+		endpoint = fmt.Sprintf(aggregationRuleByIDEndpoint, pathSegment(rule.ID))
+	}
 
-	respHeader, err := c.requestWithHeaders("PUT", endpoint, nil, reqHeader, body, nil)
+	var updated savedRuleResponse
+	respHeader, err := c.requestWithHeaders("PUT", endpoint, nil, reqHeader, body, &updated)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	newEtag := respHeader.Get("ETag")
 	if newEtag == "" {
-		return "", fmt.Errorf("response from %s endpoint missing etag header", endpoint)
+		return nil, "", fmt.Errorf("response from %s endpoint missing etag header", endpoint)
 	}
 
-	return newEtag, nil
+	return updated.Warnings, newEtag, nil
 }
 
-func (c *Client) DeleteAggregationRule(metric, etag string) (string, error) {
+// PatchAggregationRule changes the fields of the rule with the given ID or,
+// if id is empty, the rule for metric, by the JSON merge patch. The fields
+// missing from patch are left as they are. The warnings returned by the API
+// about the rule are returned.
+func (c *Client) PatchAggregationRule(id, metric string, patch map[string]any, etag string) ([]string, string, error) {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return nil, "", err
+	}
+
 	reqHeader := make(http.Header)
 	reqHeader.Add("If-Match", etag)
+	reqHeader.Add("Content-Type", "application/merge-patch+json")
+
+	endpoint := fmt.Sprintf(aggregationRuleEndpoint, pathSegment(metric))
+	if id != "" {
+		endpoint = fmt.Sprintf(aggregationRuleByIDEndpoint, pathSegment(id))
+	}
+
+	var updated savedRuleResponse
+	respHeader, err := c.requestWithHeaders("PATCH", endpoint, nil, reqHeader, body, &updated)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newEtag := respHeader.Get("ETag")
+	if newEtag == "" {
+		return nil, "", fmt.Errorf("response from %s endpoint missing etag header", endpoint)
+	}
+
+	return updated.Warnings, newEtag, nil
+}
+
+func (c *Client) DeleteAggregationRule(metric, etag string) (string, error) {
+	return c.deleteAggregationRule(fmt.Sprintf(aggregationRuleEndpoint, pathSegment(metric)), etag)
+}
 
-	endpoint := fmt.Sprintf(aggregationRuleEndpoint, metric)
+// DeleteAggregationRuleByID deletes the rule with the given ID.
+func (c *Client) DeleteAggregationRuleByID(id, etag string) (string, error) {
+	return c.deleteAggregationRule(fmt.Sprintf(aggregationRuleByIDEndpoint, pathSegment(id)), etag)
+}
+
+func (c *Client) deleteAggregationRule(endpoint, etag string) (string, error) {
+	reqHeader := make(http.Header)
+	reqHeader.Add("If-Match", etag)
 
 	respHeader, err := c.requestWithHeaders("DELETE", endpoint, nil, reqHeader, nil, nil)
 	if err != nil {
@@ -133,3 +353,35 @@ func (c *Client) DeleteAggregationRule(metric, etag string) (string, error) {
 
 	return newEtag, nil
 }
+
+// EstimateAggregationRule estimates the number of series before and after
+// aggregation if rule was applied. The rule is not saved.
+func (c *Client) EstimateAggregationRule(rule model.AggregationRule) (model.AggregationRuleEstimate, error) {
+	estimate := model.AggregationRuleEstimate{}
+	body, err := json.Marshal(rule)
+	if err != nil {
+		return estimate, err
+	}
+
+	err = c.request("POST", aggregationRulesEstimateEndpoint, nil, body, &estimate)
+	return estimate, err
+}
+
+// AggregatedMetrics returns the metrics which currently have aggregated series,
+// whether or not a rule still produces them.
+func (c *Client) AggregatedMetrics() ([]model.AggregatedMetric, error) {
+	var metrics []model.AggregatedMetric
+	err := c.request("GET", aggregatedMetricsEndpoint, nil, nil, &metrics)
+	return metrics, err
+}
+
+// ValidateAggregationRule asks the API whether it would accept rule, without
+// saving it. A rejected rule results in an ErrStatus whose body explains why.
+func (c *Client) ValidateAggregationRule(rule model.AggregationRule) error {
+	body, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+
+	return c.request("POST", aggregationRulesValidateEndpoint, nil, body, nil)
+}
@@ -2,22 +2,38 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"path"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
 )
 
+// RequestIDHeader is the header which carries the ID of every request, so that
+// it can be correlated with the backend logs.
+const RequestIDHeader = "X-Request-ID"
+
 // Client is a Grafana Cloud API client.
 type Client struct {
 	Cfg     *Config
 	BaseURL url.URL
-	client  *http.Client
+	// ReadBaseURL is the base URL of GET requests, which is BaseURL unless
+	// a separate read URL is configured.
+	ReadBaseURL url.URL
+	client      *http.Client
+
+	// ctx is the context of every request, if set.
+	ctx context.Context
+	// quota is shared by the clients returned by WithContext.
+	quota *quotaRecorder
 }
 
 // Config contains client configuration.
@@ -28,6 +44,16 @@ type Config struct {
 	HTTPHeaders map[string]string
 	Debug       bool
 	HttpClient  *http.Client
+	// RequestIDPrefix is an optional prefix of the ID sent with every request.
+	RequestIDPrefix string
+	// Stats, if set, records every request.
+	Stats *Stats
+	// HTTPLog, if set, logs every request.
+	HTTPLog *HTTPLog
+	// ReadURL is an optional base URL of a read-optimized endpoint, such as
+	// a read replica, to which GET requests are sent instead of the base
+	// URL. Every other request is sent to the base URL.
+	ReadURL string
 }
 
 // New creates a new Grafana client.
@@ -40,11 +66,37 @@ func New(baseURL string, cfg *Config) (*Client, error) {
 		cfg.HttpClient = cleanhttp.DefaultClient()
 	}
 
-	return &Client{
-		Cfg:     cfg,
-		BaseURL: *u,
-		client:  cfg.HttpClient,
-	}, nil
+	c := &Client{
+		Cfg:         cfg,
+		BaseURL:     *u,
+		ReadBaseURL: *u,
+		client:      cfg.HttpClient,
+		quota:       &quotaRecorder{},
+	}
+	if cfg.ReadURL != "" {
+		readURL, err := url.Parse(cfg.ReadURL)
+		if err != nil {
+			return nil, err
+		}
+		c.ReadBaseURL = *readURL
+	}
+
+	return c, nil
+}
+
+// WithContext returns a client sharing the configuration and connections of c
+// whose requests are made with ctx.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	cl := *c
+	cl.ctx = ctx
+	return &cl
+}
+
+func (c *Client) requestContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
 
 func (c *Client) request(method, requestPath string, query url.Values, body []byte, responseStruct interface{}) error {
@@ -52,17 +104,32 @@ func (c *Client) request(method, requestPath string, query url.Values, body []by
 	return err
 }
 
-func (c *Client) requestWithHeaders(method, requestPath string, query url.Values, header http.Header, body []byte, responseStruct interface{}) (http.Header, error) {
-	req, err := c.newRequest(method, requestPath, query, header, bytes.NewReader(body))
+func (c *Client) requestWithHeaders(method, requestPath string, query url.Values, header http.Header, body []byte, responseStruct interface{}) (_ http.Header, err error) {
+	requestID, err := c.newRequestID()
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.client.Do(req)
+	req, err := c.newRequest(method, requestPath, query, header, requestID, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
+
+	start := time.Now()
+	var status int
+	defer func() {
+		d := time.Since(start)
+		c.Cfg.Stats.record(method, requestPath, d, err)
+		c.Cfg.HTTPLog.record(req, requestID, status, d, err)
+	}()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request ID %s: %w", requestID, err)
+	}
 	defer resp.Body.Close()
+	status = resp.StatusCode
+	c.quota.record(resp.Header)
 
 	bodyContents, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -70,7 +137,7 @@ func (c *Client) requestWithHeaders(method, requestPath string, query url.Values
 	}
 
 	if c.Cfg.Debug {
-		log.Printf("response status %d with body %v", resp.StatusCode, string(bodyContents))
+		log.Printf("response status %d for request %s with body %v", resp.StatusCode, requestID, string(bodyContents))
 	}
 
 	// check status code.
@@ -78,12 +145,17 @@ func (c *Client) requestWithHeaders(method, requestPath string, query url.Values
 	case resp.StatusCode == http.StatusNotFound:
 		return nil, ErrNotFound{
 			BodyContents: bodyContents,
+			RequestID:    requestID,
 		}
 	case resp.StatusCode >= 400:
-		return nil, fmt.Errorf("status: %d, body: %v", resp.StatusCode, string(bodyContents))
+		return nil, ErrStatus{
+			StatusCode:   resp.StatusCode,
+			BodyContents: bodyContents,
+			RequestID:    requestID,
+		}
 	}
 
-	if responseStruct == nil {
+	if responseStruct == nil || len(bodyContents) == 0 {
 		return resp.Header, nil
 	}
 
@@ -95,11 +167,39 @@ func (c *Client) requestWithHeaders(method, requestPath string, query url.Values
 	return resp.Header, nil
 }
 
-func (c *Client) newRequest(method, requestPath string, query url.Values, header http.Header, body io.Reader) (*http.Request, error) {
+// newRequestID returns a new random request ID with the configured prefix.
+func (c *Client) newRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate request ID: %w", err)
+	}
+	return c.Cfg.RequestIDPrefix + hex.EncodeToString(b), nil
+}
+
+// pathSegment escapes s as a single path segment, to be interpreted literally
+// whatever characters it contains.
+func pathSegment(s string) string {
+	if s == "." || s == ".." {
+		return strings.ReplaceAll(s, ".", "%2E")
+	}
+	return url.PathEscape(s)
+}
+
+func (c *Client) newRequest(method, requestPath string, query url.Values, header http.Header, requestID string, body io.Reader) (*http.Request, error) {
 	u := c.BaseURL
-	u.Path = path.Join(u.Path, requestPath)
+	if method == http.MethodGet {
+		u = c.ReadBaseURL
+	}
+	// requestPath is escaped, so that it is not split or cleaned at slashes
+	// and dots which are part of a path segment such as a metric name.
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/") + requestPath
+	unescaped, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	u.Path, u.RawPath = unescaped, rawPath
 	u.RawQuery = query.Encode()
-	req, err := http.NewRequest(method, u.String(), body)
+	req, err := http.NewRequestWithContext(c.requestContext(), method, u.String(), body)
 	if err != nil {
 		return req, err
 	}
@@ -120,9 +220,11 @@ func (c *Client) newRequest(method, requestPath string, query url.Values, header
 		}
 	}
 
+	req.Header.Set(RequestIDHeader, requestID)
+
 	if c.Cfg.Debug {
 		if body == nil {
-			log.Printf("request (%s) to %s with no body data", method, u.String())
+			log.Printf("request %s (%s) to %s with no body data", requestID, method, u.String())
 		} else {
 			reader, ok := body.(*bytes.Reader)
 			if !ok {
@@ -130,7 +232,7 @@ func (c *Client) newRequest(method, requestPath string, query url.Values, header
 			}
 
 			if reader.Len() == 0 {
-				log.Printf("request (%s) to %s with no body data", method, u.String())
+				log.Printf("request %s (%s) to %s with no body data", requestID, method, u.String())
 			} else {
 				contents := make([]byte, reader.Len())
 				if _, err := reader.Read(contents); err != nil {
@@ -139,19 +241,65 @@ func (c *Client) newRequest(method, requestPath string, query url.Values, header
 				if _, err := reader.Seek(0, io.SeekStart); err != nil {
 					return nil, fmt.Errorf("failed to seek body reader to start after logging: %w", err)
 				}
-				log.Printf("request (%s) to %s with body data: %s", method, u.String(), string(contents))
+				log.Printf("request %s (%s) to %s with body data: %s", requestID, method, u.String(), string(contents))
 			}
 		}
 	}
 
-	req.Header.Add("Content-Type", "application/json")
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Add("Content-Type", "application/json")
+	}
 	return req, err
 }
 
 type ErrNotFound struct {
 	BodyContents []byte
+	RequestID    string
 }
 
 func (e ErrNotFound) Error() string {
-	return fmt.Sprintf("status: 404, body: %s", e.BodyContents)
+	return fmt.Sprintf("status: 404, body: %s, request ID: %s", e.BodyContents, e.RequestID)
+}
+
+// ErrStatus is returned for responses with an error status other than 404.
+type ErrStatus struct {
+	StatusCode   int
+	BodyContents []byte
+	RequestID    string
+}
+
+func (e ErrStatus) Error() string {
+	return fmt.Sprintf("status: %d, body: %s, request ID: %s", e.StatusCode, e.BodyContents, e.RequestID)
+}
+
+// FieldError is the rejection of a single field of a request body. Field is
+// the JSON name of the field, optionally followed by a list index such as
+// "keep_labels[1]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors returns the rejected fields of a 4xx response whose body has the
+// form {"field_errors": [{"field": ..., "message": ...}]}. It returns nil for
+// other responses, whose body is not structured.
+func (e ErrStatus) FieldErrors() []FieldError {
+	if e.StatusCode < 400 || e.StatusCode >= 500 {
+		return nil
+	}
+
+	var body struct {
+		FieldErrors []FieldError `json:"field_errors"`
+	}
+	if err := json.Unmarshal(e.BodyContents, &body); err != nil {
+		return nil
+	}
+
+	var errs []FieldError
+	for _, f := range body.FieldErrors {
+		if f.Field != "" {
+			errs = append(errs, f)
+		}
+	}
+	return errs
 }
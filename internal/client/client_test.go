@@ -1,8 +1,13 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -97,6 +102,97 @@ func TestClientAuths(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestClientRequestID(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("GET", "/aggregations/recommendations/config", withRespBody([]byte(`{}`)))
+	s.addExpected("GET", "/aggregations/recommendations/config", withRespBody([]byte(`{}`)))
+	s.addExpected("GET", "/aggregations/recommendations/config",
+		func(r *mockServerResponse) { r.statusCode = http.StatusInternalServerError },
+	)
+
+	c, err := New(s.server.URL, &Config{RequestIDPrefix: "ci-"})
+	require.NoError(t, err)
+
+	_, err = c.AggregationRecommendationsConfig()
+	require.NoError(t, err)
+	_, err = c.AggregationRecommendationsConfig()
+	require.NoError(t, err)
+	_, err = c.AggregationRecommendationsConfig()
+	require.Error(t, err)
+
+	ids := make(map[string]bool)
+	for _, h := range s.reqHeaders {
+		id := h.Get(RequestIDHeader)
+		require.True(t, strings.HasPrefix(id, "ci-"), id)
+		require.False(t, ids[id], "request ID %s was sent twice", id)
+		ids[id] = true
+	}
+	require.Len(t, ids, 3)
+
+	// The ID of a failed request is part of the error.
+	require.Contains(t, err.Error(), s.reqHeaders[2].Get(RequestIDHeader))
+}
+
+func TestClientReadURL(t *testing.T) {
+	primary, replica := newMockServer(t), newMockServer(t)
+	defer primary.close()
+	defer replica.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+	replica.addExpected("GET", "/aggregations/rules",
+		withRespHeader(respHeader),
+		withRespBody([]byte(`[]`)),
+	)
+	primary.addExpected("POST", "/aggregations/rules",
+		withReqBody([]byte(`[]`)),
+		withRespHeader(respHeader),
+	)
+
+	c, err := New(primary.server.URL, &Config{ReadURL: replica.server.URL})
+	require.NoError(t, err)
+
+	// Reads go to the replica, and writes to the primary.
+	_, etag, err := c.AggregationRules()
+	require.NoError(t, err)
+	_, err = c.UpdateAggregationRules([]model.AggregationRule{}, etag)
+	require.NoError(t, err)
+	require.Empty(t, primary.responses)
+	require.Empty(t, replica.responses)
+}
+
+func TestClientQuota(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	quotaHeader := make(http.Header)
+	quotaHeader.Set(RuleLimitHeader, "100")
+	quotaHeader.Set(RuleCountHeader, "42")
+	s.addExpected("GET", "/aggregations/recommendations/config", withRespHeader(quotaHeader), withRespBody([]byte(`{}`)))
+	rateLimitHeader := make(http.Header)
+	rateLimitHeader.Set(RequestsRemainingHeader, "0")
+	rateLimitHeader.Set(RuleCountHeader, "not a number")
+	s.addExpected("GET", "/aggregations/recommendations/config",
+		withRespHeader(rateLimitHeader),
+		func(r *mockServerResponse) { r.statusCode = http.StatusTooManyRequests },
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+	require.Equal(t, model.Quota{}, c.Quota())
+
+	_, err = c.AggregationRecommendationsConfig()
+	require.NoError(t, err)
+	// Failed responses report the quota too.
+	_, err = c.WithContext(context.Background()).AggregationRecommendationsConfig()
+	require.Error(t, err)
+
+	limit, count, remaining := int64(100), int64(42), int64(0)
+	require.Equal(t, model.Quota{RuleLimit: &limit, RuleCount: &count, RequestsRemaining: &remaining}, c.Quota())
+}
+
 func TestAggregationRecommendations(t *testing.T) {
 	s := newMockServer(t)
 	defer s.close()
@@ -150,6 +246,24 @@ func TestAggregationRecommendationsWithAction(t *testing.T) {
 	require.Equal(t, recsPayload, actual)
 }
 
+func TestAggregationRecommendationsForLabel(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("GET", "/aggregations/recommendations",
+		withRespBody(minifiedJson),
+		withParams(url.Values{"label": []string{"service"}, "value": []string{"checkout"}, "action": []string{"add", "keep"}}),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	actual, err := c.AggregationRecommendationsForLabel("service", "checkout", []string{"add", "keep"})
+	require.NoError(t, err)
+
+	require.Equal(t, recsPayload, actual)
+}
+
 func TestUpdateAggregationRecommendationsConfig(t *testing.T) {
 	s := newMockServer(t)
 	defer s.close()
@@ -209,6 +323,96 @@ func TestAggregationRules(t *testing.T) {
 	require.Equal(t, rulesPayload, actualRules)
 }
 
+func TestListAggregationRules(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	nextPage := make(http.Header)
+	nextPage.Set("X-Next-Page-Token", "page-2")
+
+	// The API lists every rule, which are filtered by the client.
+	s.addExpected("GET", "/aggregations/rules",
+		withParams(url.Values{"page_size": []string{"1000"}}),
+		withRespHeader(nextPage),
+		withRespBody([]byte(`[{"metric":"kube_persistentvolumeclaim_created","drop_labels":["persistentvolumeclaim"],"aggregations":["count","sum"],"tags":{"team":"infra","criticality":"high"}},{"metric":"kube_pod_info","drop":true}]`)),
+	)
+	s.addExpected("GET", "/aggregations/rules",
+		withParams(url.Values{"page_size": []string{"1000"}, "page_token": []string{"page-2"}}),
+		withRespBody([]byte(`[{"metric":"kube_persistentvolumeclaim_resource_requests_storage_bytes","drop_labels":["persistentvolumeclaim"],"aggregations":["count","sum"],"tags":{"team":"infra","criticality":"high"}},{"metric":"up"}]`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	drop := false
+	var pages [][]string
+	err = c.ListAggregationRules(AggregationRuleFilter{MetricPrefix: "kube_", Drop: &drop, Tags: map[string]string{"team": "infra", "criticality": "high"}}, false, true, func(rules []model.AggregationRule) error {
+		var metrics []string
+		for _, rule := range rules {
+			metrics = append(metrics, rule.Metric)
+		}
+		pages = append(pages, metrics)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{rulesPayload[0].Metric}, {rulesPayload[1].Metric}}, pages)
+}
+
+func TestListAggregationRulesWithoutPagination(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	// Backends without pagination ignore the parameters, and send no token.
+	s.addExpected("GET", "/aggregations/rules", withRespBody(minifiedJson))
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	var pages [][]model.AggregationRule
+	err = c.ListAggregationRules(AggregationRuleFilter{MetricPrefix: "kube_persistentvolumeclaim_created"}, false, false, func(rules []model.AggregationRule) error {
+		pages = append(pages, rules)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]model.AggregationRule{rulesPayload[:1]}, pages)
+}
+
+func TestListAggregationRulesFiltered(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	// The API only lists the matching rules, so they are passed on as they
+	// are.
+	s.addExpected("GET", "/aggregations/rules",
+		withParams(url.Values{
+			"metric_prefix": {"kube_"},
+			"match_type":    {"exact"},
+			"drop":          {"false"},
+			"group":         {"platform"},
+			"tag":           {"criticality=high", "team=infra"},
+			"page_size":     {"1000"},
+		}),
+		withRespBody(minifiedJson),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	drop := false
+	filter := AggregationRuleFilter{MetricPrefix: "kube_", MatchType: "exact", Drop: &drop, Group: "platform", Tags: map[string]string{"team": "infra", "criticality": "high"}}
+	var pages [][]model.AggregationRule
+	err = c.ListAggregationRules(filter, true, true, func(rules []model.AggregationRule) error {
+		pages = append(pages, rules)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]model.AggregationRule{rulesPayload}, pages)
+
+	// Backends which do not filter the rules cannot filter them by group.
+	err = c.ListAggregationRules(filter, false, true, func([]model.AggregationRule) error { return nil })
+	require.EqualError(t, err, "the rules cannot be filtered by group: the API does not filter the rules it lists")
+}
+
 func TestUpdateAggregationRules(t *testing.T) {
 	s := newMockServer(t)
 	defer s.close()
@@ -255,10 +459,92 @@ func TestCreateAggregationRule(t *testing.T) {
 	c, err := New(s.server.URL, &Config{})
 	require.NoError(t, err)
 
-	newEtag, err := c.CreateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, etag)
+	created, _, newEtag, err := c.CreateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, etag)
 	require.NoError(t, err)
 
 	require.Equal(t, "\"updated-fake-etag\"", newEtag)
+	require.Equal(t, model.AggregationRule{Metric: "test_metric", Drop: true}, created)
+}
+
+func TestCreateAggregationRuleReturnsID(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"updated-fake-etag\"")
+
+	s.addExpected("POST", "/aggregations/rule/test_metric",
+		withReqBody([]byte(`{"metric":"test_metric","drop":true}`)),
+		withRespHeader(respHeader),
+		withRespBody([]byte(`{"id":"r-1","metric":"test_metric","drop":true}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	created, _, _, err := c.CreateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, "\"fake-etag\"")
+	require.NoError(t, err)
+	require.Equal(t, model.AggregationRule{ID: "r-1", Metric: "test_metric", Drop: true}, created)
+}
+
+func TestAggregationRuleWarnings(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"updated-fake-etag\"")
+
+	s.addExpected("POST", "/aggregations/rule/test_metric",
+		withReqBody([]byte(`{"metric":"test_metric","drop":true}`)),
+		withRespHeader(respHeader),
+		withRespBody([]byte(`{"id":"r-1","metric":"test_metric","drop":true,"warnings":["this rule shadows another"]}`)),
+	)
+	s.addExpected("PUT", "/aggregations/rules/r-1",
+		withReqBody([]byte(`{"id":"r-1","metric":"test_metric","drop":true}`)),
+		withRespHeader(respHeader),
+		withRespBody([]byte(`{"warnings":["first","second"]}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	created, warnings, _, err := c.CreateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, "\"fake-etag\"")
+	require.NoError(t, err)
+	require.Equal(t, model.AggregationRule{ID: "r-1", Metric: "test_metric", Drop: true}, created)
+	require.Equal(t, []string{"this rule shadows another"}, warnings)
+
+	warnings, _, err = c.UpdateAggregationRule(created, "\"updated-fake-etag\"")
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, warnings)
+}
+
+func TestAggregationRulePercentiles(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+
+	body := []byte(`{"metric":"test_metric","drop_labels":["pod"],"aggregations":["sum","percentile:0.5","percentile:0.99","percentile:0.999","percentile:0.05"]}`)
+	s.addExpected("POST", "/aggregations/rule/test_metric",
+		withReqBody(body),
+		withRespHeader(respHeader),
+	)
+	s.addExpected("GET", "/aggregations/rule/test_metric",
+		withRespHeader(respHeader),
+		withRespBody(body),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	rule := model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: model.Aggregations{"sum", "p50", "p99", "p99.9", "p5"}}
+	_, _, _, err = c.CreateAggregationRule(rule, "\"fake-etag\"")
+	require.NoError(t, err)
+
+	actual, _, err := c.ReadAggregationRule("test_metric")
+	require.NoError(t, err)
+	require.Equal(t, rule, actual)
 }
 
 func TestReadAggregationRule(t *testing.T) {
@@ -284,6 +570,27 @@ func TestReadAggregationRule(t *testing.T) {
 	require.Equal(t, model.AggregationRule{Metric: "test_metric", Drop: true}, actual)
 }
 
+func TestReadAggregationRuleFields(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+
+	s.addExpected("GET", "/aggregations/rule/test_metric",
+		withParams(url.Values{"fields": {"metric,drop"}}),
+		withRespHeader(respHeader),
+		withRespBody([]byte(`{"metric":"test_metric","drop":true}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	actual, _, err := c.ReadAggregationRuleFields("test_metric", []string{"metric", "drop"})
+	require.NoError(t, err)
+	require.Equal(t, model.AggregationRule{Metric: "test_metric", Drop: true}, actual)
+}
+
 func TestUpdateAggregationRule(t *testing.T) {
 	s := newMockServer(t)
 	defer s.close()
@@ -304,12 +611,87 @@ func TestUpdateAggregationRule(t *testing.T) {
 	c, err := New(s.server.URL, &Config{})
 	require.NoError(t, err)
 
-	newEtag, err := c.UpdateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, etag)
+	_, newEtag, err := c.UpdateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, etag)
 	require.NoError(t, err)
 
 	require.Equal(t, "\"updated-fake-etag\"", newEtag)
 }
 
+func TestAggregationRuleDropIf(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	// Matchers are sent and received in the Prometheus matcher syntax.
+	body := []byte(`{"metric":"test_metric","drop":true,"drop_if":["env=\"test\"","cluster=~\"dev-.*|staging\"","team!=\"say \\\"hi\\\"\"","env!~\"prod|staging\""]}`)
+	rule := model.AggregationRule{
+		Metric: "test_metric",
+		Drop:   true,
+		DropIf: []model.DropMatcher{
+			{Label: "env", Operator: "=", Value: "test"},
+			{Label: "cluster", Operator: "=~", Value: "dev-.*|staging"},
+			{Label: "team", Operator: "!=", Value: `say "hi"`},
+			{Label: "env", Operator: "!~", Value: "prod|staging"},
+		},
+	}
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+
+	s.addExpected("PUT", "/aggregations/rule/test_metric", withReqBody(body), withRespHeader(respHeader))
+	s.addExpected("GET", "/aggregations/rule/test_metric", withRespBody(body), withRespHeader(respHeader))
+	s.addExpected("GET", "/aggregations/rule/test_metric",
+		withRespHeader(respHeader),
+		withRespBody([]byte(`{"metric":"test_metric","drop":true,"drop_if":["env<>\"test\""]}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	_, _, err = c.UpdateAggregationRule(rule, "\"fake-etag\"")
+	require.NoError(t, err)
+
+	actual, _, err := c.ReadAggregationRule("test_metric")
+	require.NoError(t, err)
+	require.Equal(t, rule, actual)
+
+	_, _, err = c.ReadAggregationRule("test_metric")
+	require.ErrorContains(t, err, `invalid label matcher "env<>\"test\"": missing operator`)
+}
+
+func TestAggregationRuleDelayOverrides(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	// The matchers of overrides use the Prometheus matcher syntax, as those
+	// of conditional drops do.
+	body := []byte(`{"metric":"test_metric","aggregations":["sum"],"aggregation_delay":"1m","delay_overrides":[{"matcher":"source=\"edge\"","delay":"10m"},{"matcher":"region=~\"ap-.*\"","delay":"5m"}]}`)
+	rule := model.AggregationRule{
+		Metric:           "test_metric",
+		Aggregations:     []string{"sum"},
+		AggregationDelay: "1m",
+		DelayOverrides: []model.DelayOverride{
+			{Matcher: model.DropMatcher{Label: "source", Operator: "=", Value: "edge"}, Delay: "10m"},
+			{Matcher: model.DropMatcher{Label: "region", Operator: "=~", Value: "ap-.*"}, Delay: "5m"},
+		},
+	}
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+
+	s.addExpected("PUT", "/aggregations/rule/test_metric", withReqBody(body), withRespHeader(respHeader))
+	s.addExpected("GET", "/aggregations/rule/test_metric", withRespBody(body), withRespHeader(respHeader))
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	_, _, err = c.UpdateAggregationRule(rule, "\"fake-etag\"")
+	require.NoError(t, err)
+
+	actual, _, err := c.ReadAggregationRule("test_metric")
+	require.NoError(t, err)
+	require.Equal(t, rule, actual)
+}
+
 func TestDeleteAggregationRule(t *testing.T) {
 	s := newMockServer(t)
 	defer s.close()
@@ -335,6 +717,129 @@ func TestDeleteAggregationRule(t *testing.T) {
 	require.Equal(t, "\"updated-fake-etag\"", newEtag)
 }
 
+func TestAggregationRuleByID(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"updated-fake-etag\"")
+
+	s.addExpected("PUT", "/aggregations/rules/r-1",
+		withReqBody([]byte(`{"id":"r-1","metric":"renamed_metric","drop":true}`)),
+		withRespHeader(respHeader),
+	)
+	s.addExpected("DELETE", "/aggregations/rules/r-1",
+		withRespHeader(respHeader),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	_, _, err = c.UpdateAggregationRule(model.AggregationRule{ID: "r-1", Metric: "renamed_metric", Drop: true}, "\"fake-etag\"")
+	require.NoError(t, err)
+
+	_, err = c.DeleteAggregationRuleByID("r-1", "\"fake-etag\"")
+	require.NoError(t, err)
+}
+
+func TestAggregationRuleSpecialCharacters(t *testing.T) {
+	for metric, escaped := range map[string]string{
+		"http.server.requests": "http.server.requests",
+		"requests{job}_total":  "requests%7Bjob%7D_total",
+		"team/requests_total":  "team%2Frequests_total",
+		"100%_ratio":           "100%25_ratio",
+		"requests?total#1":     "requests%3Ftotal%231",
+		"..":                   "%2E%2E",
+	} {
+		t.Run(metric, func(t *testing.T) {
+			s := newMockServer(t)
+			defer s.close()
+
+			respHeader := make(http.Header)
+			respHeader.Set("ETag", "\"fake-etag\"")
+
+			body := []byte(`{"metric":"` + metric + `","match_type":"suffix"}`)
+			s.addExpected("POST", "/api/aggregations/rule/"+escaped,
+				withReqBody(body),
+				withRespHeader(respHeader),
+			)
+			s.addExpected("GET", "/api/aggregations/rule/"+escaped,
+				withRespHeader(respHeader),
+				withRespBody(body),
+			)
+			s.addExpected("DELETE", "/api/aggregations/rule/"+escaped,
+				withRespHeader(respHeader),
+			)
+
+			c, err := New(s.server.URL+"/api/", &Config{})
+			require.NoError(t, err)
+
+			rule := model.AggregationRule{Metric: metric, MatchType: "suffix"}
+			_, _, _, err = c.CreateAggregationRule(rule, "\"fake-etag\"")
+			require.NoError(t, err)
+
+			actual, _, err := c.ReadAggregationRule(metric)
+			require.NoError(t, err)
+			require.Equal(t, rule, actual)
+
+			_, err = c.DeleteAggregationRule(metric, "\"fake-etag\"")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDefaultRule(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("GET", "/aggregations/default_rule",
+		withRespBody([]byte(`{"drop_labels":["pod"],"aggregations":["sum"]}`)),
+	)
+	s.addExpected("GET", "/aggregations/default_rule",
+		func(r *mockServerResponse) { r.statusCode = http.StatusNotFound },
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	actual, err := c.DefaultRule()
+	require.NoError(t, err)
+	require.Equal(t, model.DefaultRule{DropLabels: []string{"pod"}, Aggregations: []string{"sum"}}, actual)
+
+	_, err = c.DefaultRule()
+	require.ErrorAs(t, err, &ErrNotFound{})
+}
+
+func TestUpdateDefaultRule(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("POST", "/aggregations/default_rule",
+		withReqBody([]byte(`{"drop_labels":["pod"],"aggregations":["sum"],"managed_by":"terraform"}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, c.UpdateDefaultRule(model.DefaultRule{
+		DropLabels:   []string{"pod"},
+		Aggregations: []string{"sum"},
+		ManagedBy:    "terraform",
+	}))
+}
+
+func TestDeleteDefaultRule(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("DELETE", "/aggregations/default_rule")
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, c.DeleteDefaultRule())
+}
+
 func TestCreateExemption(t *testing.T) {
 	s := newMockServer(t)
 	defer s.close()
@@ -426,3 +931,338 @@ func TestDeleteExemption(t *testing.T) {
 	err = c.DeleteExemption("generated-ulid")
 	require.NoError(t, err)
 }
+
+func TestServerInfo(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("GET", "/aggregations/server_info",
+		withRespBody([]byte(`{"supported_aggregations":["count","sum","sum:counter"]}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	info, err := c.ServerInfo()
+	require.NoError(t, err)
+	require.Equal(t, model.ServerInfo{SupportedAggregations: []string{"count", "sum", "sum:counter"}}, info)
+}
+
+func TestProbeFeature(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("GET", "/v1/recommendations/exemptions",
+		withRespBody([]byte(`{"result":[]}`)),
+	)
+	s.addExpected("GET", "/aggregations/aggregated_metrics",
+		func(r *mockServerResponse) { r.statusCode = http.StatusNotFound },
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	supported, err := c.ProbeFeature(FeatureExemptions)
+	require.NoError(t, err)
+	require.True(t, supported)
+
+	supported, err = c.ProbeFeature(FeatureAggregatedMetrics)
+	require.NoError(t, err)
+	require.False(t, supported)
+
+	_, err = c.ProbeFeature("stats")
+	require.Error(t, err)
+}
+
+func TestEstimateAggregationRule(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("POST", "/aggregations/rules/estimate",
+		withReqBody([]byte(`{"metric":"test_metric","drop_labels":["pod"]}`)),
+		withRespBody([]byte(`{"total_series_before_aggregation":1000,"total_series_after_aggregation":100}`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	actual, err := c.EstimateAggregationRule(model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}})
+	require.NoError(t, err)
+
+	require.Equal(t, model.AggregationRuleEstimate{TotalSeriesBeforeAggregation: 1000, TotalSeriesAfterAggregation: 100}, actual)
+}
+
+func TestAggregatedMetrics(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("GET", "/aggregations/aggregated_metrics",
+		withRespBody([]byte(`[{"metric":"http_requests_total","series":12},{"metric":"kube_pod_info","series":3}]`)),
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	actual, err := c.AggregatedMetrics()
+	require.NoError(t, err)
+
+	require.Equal(t, []model.AggregatedMetric{{Metric: "http_requests_total", Series: 12}, {Metric: "kube_pod_info", Series: 3}}, actual)
+}
+
+func TestValidateAggregationRule(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("POST", "/aggregations/rules/validate",
+		withReqBody([]byte(`{"metric":"test_metric","drop_labels":["pod"]}`)),
+	)
+	s.addExpected("POST", "/aggregations/rules/validate",
+		withReqBody([]byte(`{"metric":"test_metric","aggregations":["avg"]}`)),
+		func(r *mockServerResponse) {
+			r.statusCode = http.StatusBadRequest
+			r.respBody = []byte(`unknown aggregation type avg`)
+		},
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, c.ValidateAggregationRule(model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}}))
+
+	err = c.ValidateAggregationRule(model.AggregationRule{Metric: "test_metric", Aggregations: []string{"avg"}})
+	var status ErrStatus
+	require.ErrorAs(t, err, &status)
+	require.Equal(t, http.StatusBadRequest, status.StatusCode)
+	require.Equal(t, "unknown aggregation type avg", string(status.BodyContents))
+}
+
+func TestClientStats(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("POST", "/aggregations/rules/validate",
+		withReqBody([]byte(`{"metric":"test_metric"}`)),
+	)
+	s.addExpected("POST", "/aggregations/rules/validate",
+		withReqBody([]byte(`{"metric":"test_metric"}`)),
+		func(r *mockServerResponse) { r.statusCode = http.StatusBadRequest },
+	)
+	s.addExpected("GET", "/aggregations/default_rule",
+		withRespBody([]byte(`{}`)),
+	)
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+	s.addExpected("POST", "/aggregations/rule/test_metric",
+		withReqBody([]byte(`{"metric":"test_metric"}`)),
+		withRespHeader(respHeader),
+	)
+	s.addExpected("POST", "/aggregations/default_rule",
+		withReqBody([]byte(`{}`)),
+	)
+
+	stats := NewStats()
+	c, err := New(s.server.URL, &Config{Stats: stats})
+	require.NoError(t, err)
+
+	require.NoError(t, c.ValidateAggregationRule(model.AggregationRule{Metric: "test_metric"}))
+	require.Error(t, c.ValidateAggregationRule(model.AggregationRule{Metric: "test_metric"}))
+	_, err = c.DefaultRule()
+	require.NoError(t, err)
+	_, _, _, err = c.CreateAggregationRule(model.AggregationRule{Metric: "test_metric"}, "")
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateDefaultRule(model.DefaultRule{}))
+
+	// POSTs are told apart by their endpoint.
+	byOperation := stats.ByOperation()
+	require.Len(t, byOperation, 4)
+	require.Equal(t, 2, byOperation["validations"].Count)
+	require.Equal(t, 1, byOperation["validations"].Errors)
+	require.Equal(t, 1, byOperation["reads"].Count)
+	require.Equal(t, 0, byOperation["reads"].Errors)
+	require.Equal(t, 1, byOperation["creates"].Count)
+	require.Equal(t, 1, byOperation["updates"].Count)
+	require.LessOrEqual(t, byOperation["validations"].Max, byOperation["validations"].Total)
+
+	summary := stats.Summary()
+	require.Contains(t, summary, "5 API requests, 1 errors: 1 creates with 0 errors")
+	require.Contains(t, summary, "1 reads with 0 errors")
+	require.Contains(t, summary, "1 updates with 0 errors")
+	require.Contains(t, summary, "2 validations with 1 errors")
+}
+
+func TestClientHTTPLog(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	respHeader := make(http.Header)
+	respHeader.Set("ETag", "\"fake-etag\"")
+
+	s.addExpected("POST", "/aggregations/rule/test_metric",
+		withReqBody([]byte(`{"metric":"test_metric","drop":true}`)),
+		withRespHeader(respHeader),
+	)
+	s.addExpected("GET", "/aggregations/rule/test_metric",
+		withRespHeader(respHeader),
+		withRespBody([]byte(`{"metric":"test_metric","drop":true}`)),
+	)
+	s.addExpected("PUT", "/aggregations/rule/test_metric",
+		withReqBody([]byte(`{"metric":"test_metric","drop_labels":["pod"]}`)),
+		func(r *mockServerResponse) {
+			r.statusCode = http.StatusBadRequest
+			r.respBody = []byte(`{"error":"invalid rule"}`)
+		},
+	)
+	s.addExpected("DELETE", "/aggregations/rule/test_metric",
+		withRespHeader(respHeader),
+	)
+
+	path := filepath.Join(t.TempDir(), "http.log")
+	httpLog, err := OpenHTTPLog(path)
+	require.NoError(t, err)
+	c, err := New(s.server.URL, &Config{APIKey: "secret-token", HTTPHeaders: map[string]string{"X-Scope-OrgID": "1"}, HTTPLog: httpLog})
+	require.NoError(t, err)
+
+	_, _, _, err = c.CreateAggregationRule(model.AggregationRule{Metric: "test_metric", Drop: true}, "\"fake-etag\"")
+	require.NoError(t, err)
+	_, _, err = c.ReadAggregationRule("test_metric")
+	require.NoError(t, err)
+	_, _, err = c.UpdateAggregationRule(model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}}, "\"fake-etag\"")
+	require.Error(t, err)
+	_, err = c.DeleteAggregationRule("test_metric", "\"fake-etag\"")
+	require.NoError(t, err)
+	require.NoError(t, httpLog.Close())
+
+	// Requests made once the log is closed are not logged.
+	s.addExpected("GET", "/aggregations/rule/test_metric", withRespHeader(respHeader), withRespBody([]byte(`{}`)))
+	_, _, err = c.ReadAggregationRule("test_metric")
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(contents), "secret-token")
+
+	var entries []HTTPLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		var entry HTTPLogEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 4)
+
+	for i, expected := range []struct {
+		method string
+		status int
+	}{
+		{"POST", http.StatusOK},
+		{"GET", http.StatusOK},
+		{"PUT", http.StatusBadRequest},
+		{"DELETE", http.StatusOK},
+	} {
+		entry := entries[i]
+		require.Equal(t, expected.method, entry.Method)
+		require.Equal(t, expected.status, entry.Status)
+		require.Equal(t, s.server.URL+"/aggregations/rule/test_metric", entry.URL)
+		require.NotEmpty(t, entry.RequestID)
+		require.Equal(t, entry.RequestID, entry.RequestHeaders[http.CanonicalHeaderKey(RequestIDHeader)])
+		require.Equal(t, "REDACTED", entry.RequestHeaders["Authorization"])
+		require.Equal(t, "1", entry.RequestHeaders["X-Scope-Orgid"])
+		require.False(t, entry.Time.IsZero())
+	}
+	require.Empty(t, entries[0].Error)
+	require.Empty(t, entries[0].ResponseBody)
+	require.Contains(t, entries[2].Error, "400")
+	require.Equal(t, `{"error":"invalid rule"}`, entries[2].ResponseBody)
+}
+
+func TestClientHTTPLogRedactsURL(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+	s.addExpected("GET", "/aggregations/default_rule", withRespBody([]byte(`{}`)))
+
+	baseURL, err := url.Parse(s.server.URL)
+	require.NoError(t, err)
+	baseURL.User = url.UserPassword("user", "secret-password")
+
+	path := filepath.Join(t.TempDir(), "http.log")
+	httpLog, err := OpenHTTPLog(path)
+	require.NoError(t, err)
+	c, err := New(baseURL.String(), &Config{HTTPLog: httpLog})
+	require.NoError(t, err)
+
+	_, err = c.DefaultRule()
+	require.NoError(t, err)
+	require.NoError(t, httpLog.Close())
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(contents), "secret-password")
+
+	var entry HTTPLogEntry
+	require.NoError(t, json.Unmarshal(contents, &entry))
+	require.Contains(t, entry.URL, "user:xxxxx@")
+}
+
+func TestErrStatusFieldErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		err      ErrStatus
+		expected []FieldError
+	}{
+		{
+			name: "field errors",
+			err:  ErrStatus{StatusCode: 422, BodyContents: []byte(`{"field_errors":[{"field":"aggregations[0]","message":"unknown type"},{"message":"no field"}]}`)},
+			expected: []FieldError{
+				{Field: "aggregations[0]", Message: "unknown type"},
+			},
+		},
+		{
+			name: "unstructured body",
+			err:  ErrStatus{StatusCode: 400, BodyContents: []byte("invalid rule")},
+		},
+		{
+			name: "server error",
+			err:  ErrStatus{StatusCode: 500, BodyContents: []byte(`{"field_errors":[{"field":"metric","message":"unavailable"}]}`)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.err.FieldErrors())
+		})
+	}
+}
+
+func TestLocks(t *testing.T) {
+	s := newMockServer(t)
+	defer s.close()
+
+	s.addExpected("PUT", "/aggregations/locks/prod-rules",
+		withReqBody([]byte(`{"holder":"run-1"}`)),
+		withRespBody([]byte(`{"holder":"run-1","acquired_at":"2024-05-01T10:00:00Z"}`)),
+	)
+	// The lock held by another holder is returned with the conflict.
+	s.addExpected("PUT", "/aggregations/locks/prod-rules",
+		withReqBody([]byte(`{"holder":"run-2"}`)),
+		withRespBody([]byte(`{"holder":"run-1","acquired_at":"2024-05-01T10:00:00Z"}`)),
+		func(r *mockServerResponse) { r.statusCode = http.StatusConflict },
+	)
+	s.addExpected("DELETE", "/aggregations/locks/prod-rules", withParams(url.Values{"holder": {"run-1"}}))
+	// Releasing a lock which is no longer held succeeds.
+	s.addExpected("DELETE", "/aggregations/locks/prod-rules",
+		withParams(url.Values{"holder": {"run-1"}}),
+		func(r *mockServerResponse) { r.statusCode = http.StatusNotFound },
+	)
+
+	c, err := New(s.server.URL, &Config{})
+	require.NoError(t, err)
+
+	lock, err := c.AcquireLock("prod-rules", "run-1")
+	require.NoError(t, err)
+	require.Equal(t, model.Lock{Holder: "run-1", AcquiredAt: "2024-05-01T10:00:00Z"}, lock)
+
+	_, err = c.AcquireLock("prod-rules", "run-2")
+	var held ErrLockHeld
+	require.ErrorAs(t, err, &held)
+	require.Equal(t, model.Lock{Holder: "run-1", AcquiredAt: "2024-05-01T10:00:00Z"}, held.Lock)
+
+	require.NoError(t, c.ReleaseLock("prod-rules", "run-1"))
+	require.NoError(t, c.ReleaseLock("prod-rules", "run-1"))
+}
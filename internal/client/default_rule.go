@@ -0,0 +1,30 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+const defaultRuleEndpoint = "/aggregations/default_rule"
+
+// DefaultRule returns the default aggregation rule. ErrNotFound is returned
+// when no default rule is set.
+func (c *Client) DefaultRule() (model.DefaultRule, error) {
+	rule := model.DefaultRule{}
+	err := c.request("GET", defaultRuleEndpoint, nil, nil, &rule)
+	return rule, err
+}
+
+func (c *Client) UpdateDefaultRule(rule model.DefaultRule) error {
+	body, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+
+	return c.request("POST", defaultRuleEndpoint, nil, body, nil)
+}
+
+func (c *Client) DeleteDefaultRule() error {
+	return c.request("DELETE", defaultRuleEndpoint, nil, nil, nil)
+}
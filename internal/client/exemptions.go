@@ -30,7 +30,7 @@ func (c *Client) CreateExemption(ex model.Exemption) (model.Exemption, error) {
 
 func (c *Client) ReadExemption(exID string) (model.Exemption, error) {
 	resp := exemptionResp{}
-	endpoint := fmt.Sprintf(exemptionEndpoint, exID)
+	endpoint := fmt.Sprintf(exemptionEndpoint, pathSegment(exID))
 
 	err := c.request("GET", endpoint, nil, nil, &resp)
 	return resp.Result, err
@@ -42,12 +42,12 @@ func (c *Client) UpdateExemption(ex model.Exemption) error {
 		return err
 	}
 
-	endpoint := fmt.Sprintf(exemptionEndpoint, ex.ID)
+	endpoint := fmt.Sprintf(exemptionEndpoint, pathSegment(ex.ID))
 	return c.request("PUT", endpoint, nil, body, nil)
 }
 
 func (c *Client) DeleteExemption(exID string) error {
-	endpoint := fmt.Sprintf(exemptionEndpoint, exID)
+	endpoint := fmt.Sprintf(exemptionEndpoint, pathSegment(exID))
 	return c.request("DELETE", endpoint, nil, nil, nil)
 }
 
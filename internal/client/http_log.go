@@ -0,0 +1,133 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedValue replaces the values of headers which may carry credentials in
+// an HTTPLog.
+const redactedValue = "REDACTED"
+
+// HTTPLog writes a line of JSON describing every request made by a client to a
+// file, so that the requests of a run can be attached to a bug report. It is
+// safe for concurrent use.
+type HTTPLog struct {
+	mu   sync.Mutex
+	file *os.File
+	// failed is set once a write failed, which is only logged once.
+	failed bool
+}
+
+// HTTPLogEntry is a line of an HTTPLog.
+type HTTPLogEntry struct {
+	Time           time.Time         `json:"time"`
+	RequestID      string            `json:"request_id"`
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	RequestHeaders map[string]string `json:"request_headers"`
+	// Status is zero if no response was received.
+	Status     int     `json:"status,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+	// ResponseBody is only logged for failed requests.
+	ResponseBody string `json:"response_body,omitempty"`
+}
+
+// OpenHTTPLog opens the file at path to append an HTTPLog to it, creating it if
+// needed. Only the current user may read a file it creates.
+func OpenHTTPLog(path string) (*HTTPLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the HTTP log: %w", err)
+	}
+	return &HTTPLog{file: f}, nil
+}
+
+// Close closes the file of the log. Requests made afterwards are not logged.
+func (l *HTTPLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// record logs req, which received a response with status after d and failed
+// with err, if not nil. It does nothing on a nil HTTPLog.
+func (l *HTTPLog) record(req *http.Request, requestID string, status int, d time.Duration, err error) {
+	if l == nil {
+		return
+	}
+
+	entry := HTTPLogEntry{
+		Time:           time.Now().UTC(),
+		RequestID:      requestID,
+		Method:         req.Method,
+		URL:            req.URL.Redacted(),
+		RequestHeaders: redactHeaders(req.Header),
+		Status:         status,
+		DurationMS:     float64(d.Microseconds()) / 1000,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		var statusErr ErrStatus
+		var notFound ErrNotFound
+		switch {
+		case errors.As(err, &statusErr):
+			entry.ResponseBody = string(statusErr.BodyContents)
+		case errors.As(err, &notFound):
+			entry.ResponseBody = string(notFound.BodyContents)
+		}
+	}
+
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return
+	}
+	// A single write per entry, so that lines are never interleaved.
+	if _, writeErr := l.file.Write(line); writeErr != nil && !l.failed {
+		l.failed = true
+		log.Printf("[WARN] unable to write to the HTTP log: %s", writeErr)
+	}
+}
+
+// redactHeaders returns the first value of every header, with those of headers
+// which may carry credentials, such as Authorization, redacted.
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		if len(values) == 0 {
+			continue
+		}
+		value := values[0]
+		lower := strings.ToLower(name)
+		for _, sensitive := range []string{"auth", "token", "key", "secret", "cookie", "password"} {
+			if strings.Contains(lower, sensitive) {
+				value = redactedValue
+				break
+			}
+		}
+		out[name] = value
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+const lockEndpoint = "/aggregations/locks/%s"
+
+// ErrLockHeld is returned when a lock is acquired which another holder holds.
+type ErrLockHeld struct {
+	Name string
+	// Lock is the lock as held by the other holder.
+	Lock      model.Lock
+	RequestID string
+}
+
+func (e ErrLockHeld) Error() string {
+	return fmt.Sprintf("lock %q is held by %s, request ID: %s", e.Name, e.Lock.Holder, e.RequestID)
+}
+
+// AcquireLock acquires the named lock for holder. Acquiring a lock which
+// holder already holds succeeds. ErrLockHeld is returned if another holder
+// holds it.
+func (c *Client) AcquireLock(name, holder string) (model.Lock, error) {
+	body, err := json.Marshal(model.Lock{Holder: holder})
+	if err != nil {
+		return model.Lock{}, err
+	}
+
+	var lock model.Lock
+	err = c.request("PUT", fmt.Sprintf(lockEndpoint, pathSegment(name)), nil, body, &lock)
+	var status ErrStatus
+	if errors.As(err, &status) && status.StatusCode == http.StatusConflict {
+		held := ErrLockHeld{Name: name, RequestID: status.RequestID}
+		if json.Unmarshal(status.BodyContents, &held.Lock) != nil || held.Lock.Holder == "" {
+			held.Lock = model.Lock{Holder: "another holder"}
+		}
+		return model.Lock{}, held
+	}
+	return lock, err
+}
+
+// ReleaseLock releases the named lock if holder holds it. Releasing a lock
+// which is not held is not an error, so that a lock which expired is released
+// without failing.
+func (c *Client) ReleaseLock(name, holder string) error {
+	err := c.request("DELETE", fmt.Sprintf(lockEndpoint, pathSegment(name)), url.Values{"holder": {holder}}, nil, nil)
+	var notFound ErrNotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+package client
+
+import (
+	"net/url"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+const metricMetadataEndpoint = "/aggregations/metric_metadata"
+
+// MetricMetadata returns the metadata of metric, such as its type. An
+// ErrNotFound is returned for metrics without metadata.
+func (c *Client) MetricMetadata(metric string) (model.MetricMetadata, error) {
+	metadata := model.MetricMetadata{}
+	err := c.request("GET", metricMetadataEndpoint, url.Values{"metric": {metric}}, nil, &metadata)
+	return metadata, err
+}
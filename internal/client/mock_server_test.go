@@ -25,6 +25,9 @@ type mockServerResponse struct {
 type mockServer struct {
 	server    *httptest.Server
 	responses []*mockServerResponse
+
+	// reqHeaders are the headers of the requests received so far.
+	reqHeaders []http.Header
 }
 
 func newMockServer(t *testing.T) *mockServer {
@@ -36,9 +39,10 @@ func newMockServer(t *testing.T) *mockServer {
 
 		next := s.responses[0]
 		s.responses = s.responses[1:]
+		s.reqHeaders = append(s.reqHeaders, r.Header.Clone())
 
 		require.Equal(t, next.method, r.Method)
-		require.Equal(t, next.path, r.URL.Path)
+		require.Equal(t, next.path, r.URL.EscapedPath())
 		require.Equal(t, next.params, r.URL.Query())
 
 		for k, v := range next.reqHeader {
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// The headers with which the API reports its limits. Backends may send any
+// of them, or none.
+const (
+	RequestLimitHeader      = "X-RateLimit-Limit"
+	RequestsRemainingHeader = "X-RateLimit-Remaining"
+	RuleLimitHeader         = "X-Rule-Limit"
+	RuleCountHeader         = "X-Rule-Count"
+)
+
+// quotaRecorder keeps the latest values of the quota headers seen in
+// responses. It is safe for concurrent use.
+type quotaRecorder struct {
+	mu    sync.Mutex
+	quota model.Quota
+}
+
+// record updates the quota from the headers of a response. Headers which are
+// missing or not integers leave the value seen before.
+func (q *quotaRecorder) record(header http.Header) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for name, field := range map[string]**int64{
+		RequestLimitHeader:      &q.quota.RequestLimit,
+		RequestsRemainingHeader: &q.quota.RequestsRemaining,
+		RuleLimitHeader:         &q.quota.RuleLimit,
+		RuleCountHeader:         &q.quota.RuleCount,
+	} {
+		v, err := strconv.ParseInt(header.Get(name), 10, 64)
+		if err == nil {
+			*field = &v
+		}
+	}
+}
+
+// Quota returns the limits of the API as last reported by its responses to
+// the client, or to any client sharing its configuration through
+// WithContext. The limits which were never reported are nil.
+func (c *Client) Quota() model.Quota {
+	c.quota.mu.Lock()
+	defer c.quota.mu.Unlock()
+	return c.quota.quota
+}
@@ -25,6 +25,21 @@ func (c *Client) AggregationRecommendations(verbose bool, action []string) ([]mo
 	return recs, err
 }
 
+// AggregationRecommendationsForLabel returns the recommendations for the metrics
+// of the series which have the given value for label, such as all metrics of a
+// service or namespace.
+func (c *Client) AggregationRecommendationsForLabel(label, value string, action []string) ([]model.AggregationRecommendation, error) {
+	var recs []model.AggregationRecommendation
+	params := url.Values{}
+	params.Add("label", label)
+	params.Add("value", value)
+	for _, a := range action {
+		params.Add("action", a)
+	}
+	err := c.request("GET", recommendationsEndpoint, params, nil, &recs)
+	return recs, err
+}
+
 func (c *Client) AggregationRecommendationsConfig() (model.AggregationRecommendationConfiguration, error) {
 	config := model.AggregationRecommendationConfiguration{}
 	err := c.request("GET", recommendationsConfigEndpoint, nil, nil, &config)
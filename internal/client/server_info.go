@@ -0,0 +1,103 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+const serverInfoEndpoint = "/aggregations/server_info"
+
+// Feature is an optional API which not every version of the backend
+// provides.
+type Feature string
+
+const (
+	FeatureRecommendations   Feature = "recommendations"
+	FeatureExemptions        Feature = "exemptions"
+	FeatureAggregatedMetrics Feature = "aggregated_metrics"
+	FeatureMatchPrefixes     Feature = "match_prefixes"
+
+	FeatureAggregationIntervals Feature = "aggregation_intervals"
+	FeaturePartialUpdates       Feature = "partial_updates"
+	FeatureLabelPatterns        Feature = "label_patterns"
+	FeatureRuleNames            Feature = "rule_names"
+	FeatureMetricMetadata       Feature = "metric_metadata"
+	FeatureFieldSelection       Feature = "field_selection"
+	FeatureDelayOverrides       Feature = "delay_overrides"
+	FeatureApplyLocks           Feature = "apply_locks"
+	FeatureIngestSampling       Feature = "ingest_sampling"
+	FeatureRulePagination       Feature = "rule_pagination"
+	FeatureRuleFilters          Feature = "rule_filters"
+	FeatureArchivedRules        Feature = "archived_rules"
+	FeatureConditionalDrops     Feature = "conditional_drops"
+	FeaturePercentiles          Feature = "percentile_aggregations"
+	FeatureRollouts             Feature = "rollout_percentage"
+	FeatureAggregatedNames      Feature = "aggregated_metric_names"
+	FeatureRuleTags             Feature = "rule_tags"
+	FeaturePriority             Feature = "rule_priority"
+)
+
+// featureProbes are the endpoints requested to detect whether a backend
+// provides a feature. Each of them is cheap to request and missing from
+// backends without the feature. Features without an endpoint of their own
+// are only provided by backends whose server info lists them.
+var featureProbes = map[Feature]string{
+	FeatureRecommendations:   recommendationsConfigEndpoint,
+	FeatureExemptions:        exemptionsEndpoint,
+	FeatureAggregatedMetrics: aggregatedMetricsEndpoint,
+	FeatureMatchPrefixes:     "",
+
+	FeatureAggregationIntervals: "",
+	FeaturePartialUpdates:       "",
+	FeatureLabelPatterns:        "",
+	FeatureRuleNames:            "",
+	// The metadata endpoint requires a metric.
+	FeatureMetricMetadata: "",
+	FeatureFieldSelection: "",
+	FeatureDelayOverrides: "",
+	// Locks are only acquired, by a PUT.
+	FeatureApplyLocks:     "",
+	FeatureIngestSampling: "",
+	// Pages are only requested by parameters of the rules endpoint.
+	FeatureRulePagination:   "",
+	FeatureRuleFilters:      "",
+	FeatureArchivedRules:    "",
+	FeatureConditionalDrops: "",
+	FeaturePercentiles:      "",
+	FeatureRollouts:         "",
+	FeatureAggregatedNames:  "",
+	FeatureRuleTags:         "",
+	FeaturePriority:         "",
+}
+
+// ServerInfo returns the capabilities of the API.
+func (c *Client) ServerInfo() (model.ServerInfo, error) {
+	info := model.ServerInfo{}
+	err := c.request("GET", serverInfoEndpoint, nil, nil, &info)
+	return info, err
+}
+
+// ProbeFeature reports whether the backend provides feature by requesting one
+// of its endpoints. It is used for backends whose server info does not list
+// their features.
+func (c *Client) ProbeFeature(feature Feature) (bool, error) {
+	endpoint, ok := featureProbes[feature]
+	if !ok {
+		return false, fmt.Errorf("unknown feature %q", feature)
+	}
+	if endpoint == "" {
+		return false, nil
+	}
+
+	err := c.request("GET", endpoint, nil, nil, nil)
+	var notFound ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
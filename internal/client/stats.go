@@ -0,0 +1,125 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// operationNames name the kind of operation performed by the POST requests of
+// an endpoint in summaries. Many POSTs do not create anything: they validate
+// or estimate rules, or replace a configuration. POSTs to other endpoints
+// create what they are sent.
+var operationNames = map[string]string{
+	aggregationRulesValidateEndpoint: "validations",
+	aggregationRulesEstimateEndpoint: "estimates",
+	aggregationRulesEndpoint:         "updates",
+	defaultRuleEndpoint:              "updates",
+	recommendationsConfigEndpoint:    "updates",
+}
+
+// operation returns the kind of operation performed by a request of method to
+// requestPath.
+func operation(method, requestPath string) string {
+	switch method {
+	case http.MethodGet:
+		return "reads"
+	case http.MethodPut, http.MethodPatch:
+		return "updates"
+	case http.MethodDelete:
+		return "deletes"
+	case http.MethodPost:
+		if name, ok := operationNames[requestPath]; ok {
+			return name
+		}
+		return "creates"
+	}
+	return "requests"
+}
+
+// Stats counts the requests made by a client and their latencies by the kind
+// of operation they perform. It is safe for concurrent use.
+type Stats struct {
+	mu          sync.Mutex
+	byOperation map[string]*OperationStats
+}
+
+// OperationStats are the statistics of the requests of one kind of operation.
+type OperationStats struct {
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+func NewStats() *Stats {
+	return &Stats{byOperation: make(map[string]*OperationStats)}
+}
+
+// record records a request of method to requestPath which took d and failed
+// with err, if not nil. It does nothing on a nil Stats.
+func (s *Stats) record(method, requestPath string, d time.Duration, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := operation(method, requestPath)
+	op, ok := s.byOperation[name]
+	if !ok {
+		op = &OperationStats{}
+		s.byOperation[name] = op
+	}
+	op.Count++
+	if err != nil {
+		op.Errors++
+	}
+	op.Total += d
+	op.Max = max(op.Max, d)
+}
+
+// ByOperation returns a copy of the statistics keyed by the kind of
+// operation, such as reads or validations.
+func (s *Stats) ByOperation() map[string]OperationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]OperationStats, len(s.byOperation))
+	for name, op := range s.byOperation {
+		out[name] = *op
+	}
+	return out
+}
+
+// Summary returns a one line summary of the requests, such as
+// "3 API requests, 1 errors: 2 reads with 0 errors, ...".
+func (s *Stats) Summary() string {
+	byOperation := s.ByOperation()
+
+	names := make([]string, 0, len(byOperation))
+	var count, errors int
+	for name, op := range byOperation {
+		names = append(names, name)
+		count += op.Count
+		errors += op.Errors
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		op := byOperation[name]
+		parts[i] = fmt.Sprintf("%d %s with %d errors, %s total, %s average, %s max",
+			op.Count, name, op.Errors, op.Total.Round(time.Millisecond), (op.Total / time.Duration(op.Count)).Round(time.Millisecond), op.Max.Round(time.Millisecond))
+	}
+
+	summary := fmt.Sprintf("%d API requests, %d errors", count, errors)
+	if len(parts) > 0 {
+		summary += ": " + strings.Join(parts, "; ")
+	}
+	return summary
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// apiPercentilePrefix prefixes the quantile of a percentile aggregation in the
+// API, such as percentile:0.99.
+const apiPercentilePrefix = "percentile:"
+
+var (
+	// percentileRE matches the percentile shorthand, such as p99 or p99.9.
+	percentileRE = regexp.MustCompile(`^p([0-9]{1,2})(?:\.([0-9]+))?$`)
+	// apiPercentileRE matches the percentiles of the API by their quantile,
+	// which must be strictly between 0 and 1.
+	apiPercentileRE = regexp.MustCompile(`^percentile:0\.([0-9]*[1-9])$`)
+)
+
+// Aggregations are the aggregation types of a rule. Percentiles are written as
+// p<percent>, such as p99 or p99.9, and sent to the API as
+// percentile:<quantile>, such as percentile:0.99.
+type Aggregations []string
+
+func (a Aggregations) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("null"), nil
+	}
+
+	out := make([]string, len(a))
+	for i, agg := range a {
+		out[i] = agg
+		if quantile, err := ParsePercentile(agg); err == nil {
+			out[i] = apiPercentilePrefix + quantile
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (a *Aggregations) UnmarshalJSON(data []byte) error {
+	var in []string
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in == nil {
+		*a = nil
+		return nil
+	}
+
+	out := make(Aggregations, len(in))
+	for i, agg := range in {
+		out[i] = agg
+		if m := apiPercentileRE.FindStringSubmatch(agg); m != nil {
+			out[i] = percentileFromQuantile(m[1])
+		}
+	}
+	*a = out
+	return nil
+}
+
+// IsPercentile reports whether agg is written as a percentile, whether or not
+// it is a valid one.
+func IsPercentile(agg string) bool {
+	return len(agg) > 1 && agg[0] == 'p' && (agg[1] >= '0' && agg[1] <= '9' || agg[1] == '.')
+}
+
+// ParsePercentile returns the quantile, as a decimal between 0 and 1, of a
+// percentile aggregation such as p99.
+func ParsePercentile(agg string) (string, error) {
+	m := percentileRE.FindStringSubmatch(agg)
+	if m == nil {
+		return "", errors.New("percentiles must be written as p<percent>, such as p50, p99, or p99.9")
+	}
+
+	whole, frac := m[1], strings.TrimRight(m[2], "0")
+	if len(whole) == 2 && whole[0] == '0' {
+		return "", errors.New("the percent of a percentile must not have a leading zero")
+	}
+	if len(whole) == 1 {
+		whole = "0" + whole
+	}
+	if strings.Trim(whole+frac, "0") == "" {
+		return "", errors.New("the percent of a percentile must be greater than 0 and less than 100")
+	}
+
+	return strings.TrimRight("0."+whole+frac, "0"), nil
+}
+
+// percentileFromQuantile returns the percentile shorthand for the decimals of
+// a quantile, such as p99 for the decimals 99 of 0.99.
+func percentileFromQuantile(decimals string) string {
+	if len(decimals) < 2 {
+		decimals += "0"
+	}
+
+	whole, frac := strings.TrimPrefix(decimals[:2], "0"), decimals[2:]
+	if whole == "" {
+		whole = "0"
+	}
+	if frac == "" {
+		return "p" + whole
+	}
+	return "p" + whole + "." + frac
+}
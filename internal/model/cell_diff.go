@@ -0,0 +1,27 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type CellDiffTF struct {
+	CellA TargetCellTF `tfsdk:"cell_a"`
+	CellB TargetCellTF `tfsdk:"cell_b"`
+
+	OnlyInA   []types.String     `tfsdk:"only_in_a"`
+	OnlyInB   []types.String     `tfsdk:"only_in_b"`
+	Differing []RuleDifferenceTF `tfsdk:"differing"`
+}
+
+// RuleDifferenceTF is a metric whose rule differs between two cells.
+type RuleDifferenceTF struct {
+	Metric types.String   `tfsdk:"metric"`
+	Fields []types.String `tfsdk:"fields"`
+}
+
+// ToRuleDifferenceTF describes how the rule a differs from b, which is for the
+// same metric.
+func ToRuleDifferenceTF(a, b AggregationRule) RuleDifferenceTF {
+	return RuleDifferenceTF{
+		Metric: types.StringValue(a.Metric),
+		Fields: toTypesStringSlice(a.Differences(b)),
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type ChangeReportTF struct {
+	RulesJSON  types.String `tfsdk:"rules_json"`
+	Prune      types.Bool   `tfsdk:"prune"`
+	HasChanges types.Bool   `tfsdk:"has_changes"`
+	JSON       types.String `tfsdk:"json"`
+}
+
+// Change report actions.
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// ChangeReport describes the changes which reconcile the existing aggregation
+// rules with the desired ones, for tools reviewing them such as PR bots.
+type ChangeReport struct {
+	Summary ChangeSummary `json:"summary"`
+	Changes []RuleChange  `json:"changes"`
+}
+
+// ChangeSummary counts the changes of a ChangeReport by action.
+type ChangeSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+// RuleChange is the change of the rule for a metric.
+type RuleChange struct {
+	Action string        `json:"action"`
+	Metric string        `json:"metric"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is the change of a field of a rule. Before is null for created
+// rules and fields, and after is null for deleted ones.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// NewRuleChange describes the change of the rule before into the rule after,
+// by the fields in which they differ as Differences tells them. A created rule
+// is a change from the empty rule, and a deleted rule a change into it.
+func NewRuleChange(action string, before, after AggregationRule) (RuleChange, error) {
+	// Every field of the empty rule is null, including those which are
+	// encoded when empty such as metric.
+	var beforeValues, afterValues map[string]any
+	var err error
+	if action != ChangeCreate {
+		if beforeValues, err = before.fieldValues(); err != nil {
+			return RuleChange{}, err
+		}
+	}
+	if action != ChangeDelete {
+		if afterValues, err = after.fieldValues(); err != nil {
+			return RuleChange{}, err
+		}
+	}
+
+	metric := after.Metric
+	if action == ChangeDelete {
+		metric = before.Metric
+	}
+
+	change := RuleChange{Action: action, Metric: metric, Fields: []FieldChange{}}
+	for _, f := range after.Differences(before) {
+		change.Fields = append(change.Fields, FieldChange{Field: f, Before: beforeValues[f], After: afterValues[f]})
+	}
+	return change, nil
+}
@@ -0,0 +1,71 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+// DefaultRuleID is the ID of the default rule in Terraform state; there is
+// only ever one default rule per tenant.
+const DefaultRuleID = "default"
+
+// DefaultRule is the aggregation rule applied to every metric which is not
+// matched by any other rule.
+type DefaultRule struct {
+	Drop       bool     `json:"drop,omitempty"`
+	KeepLabels []string `json:"keep_labels,omitempty"`
+	DropLabels []string `json:"drop_labels,omitempty"`
+
+	Aggregations []string `json:"aggregations,omitempty"`
+
+	AggregationInterval string `json:"aggregation_interval,omitempty"`
+	AggregationDelay    string `json:"aggregation_delay,omitempty"`
+
+	ManagedBy string `json:"managed_by,omitempty"`
+}
+
+func (r DefaultRule) ToTF() DefaultRuleTF {
+	return DefaultRuleTF{
+		ID: types.StringValue(DefaultRuleID),
+
+		Drop:       types.BoolValue(r.Drop),
+		KeepLabels: toTypesStringSlice(r.KeepLabels),
+		DropLabels: toTypesStringSlice(r.DropLabels),
+
+		Aggregations: toTypesStringSlice(r.Aggregations),
+
+		AggregationInterval: types.StringValue(r.AggregationInterval),
+		AggregationDelay:    types.StringValue(r.AggregationDelay),
+
+		ConfirmDropUnmatched: types.BoolNull(),
+	}
+}
+
+type DefaultRuleTF struct {
+	ID types.String `tfsdk:"id"`
+
+	Drop       types.Bool     `tfsdk:"drop"`
+	KeepLabels []types.String `tfsdk:"keep_labels"`
+	DropLabels []types.String `tfsdk:"drop_labels"`
+
+	Aggregations []types.String `tfsdk:"aggregations"`
+
+	AggregationInterval types.String `tfsdk:"aggregation_interval"`
+	AggregationDelay    types.String `tfsdk:"aggregation_delay"`
+
+	ConfirmDropUnmatched types.Bool `tfsdk:"confirm_drop_unmatched"`
+
+	LastUpdated types.String `tfsdk:"-"`
+}
+
+func (r DefaultRuleTF) ToAPIReq() DefaultRule {
+	return DefaultRule{
+		Drop:       r.Drop.ValueBool(),
+		KeepLabels: toStringSlice(r.KeepLabels),
+		DropLabels: toStringSlice(r.DropLabels),
+
+		Aggregations: toStringSlice(r.Aggregations),
+
+		AggregationInterval: r.AggregationInterval.ValueString(),
+		AggregationDelay:    r.AggregationDelay.ValueString(),
+
+		ManagedBy: managedByTF,
+	}
+}
@@ -0,0 +1,57 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+// DelayOverride replaces the aggregation delay of a rule for the series
+// matching a label matcher, such as those of a source whose samples are known
+// to arrive late. The API applies the first override whose matcher matches a
+// series, and the aggregation delay of the rule to series matching none.
+type DelayOverride struct {
+	Matcher DropMatcher `json:"matcher"`
+	Delay   string      `json:"delay"`
+}
+
+func (o DelayOverride) ToTF() DelayOverrideTF {
+	return DelayOverrideTF{
+		Label:    types.StringValue(o.Matcher.Label),
+		Operator: types.StringValue(o.Matcher.Operator),
+		Value:    types.StringValue(o.Matcher.Value),
+		Delay:    types.StringValue(o.Delay),
+	}
+}
+
+type DelayOverrideTF struct {
+	Label    types.String `tfsdk:"label"`
+	Operator types.String `tfsdk:"operator"`
+	Value    types.String `tfsdk:"value"`
+	Delay    types.String `tfsdk:"delay"`
+}
+
+// ToAPIReq returns the override; as for drop matchers, an unset operator is
+// "=".
+func (o DelayOverrideTF) ToAPIReq() DelayOverride {
+	return DelayOverride{
+		Matcher: DropMatcherTF{Label: o.Label, Operator: o.Operator, Value: o.Value}.ToAPIReq(),
+		Delay:   o.Delay.ValueString(),
+	}
+}
+
+func toDelayOverridesTF(in []DelayOverride) []DelayOverrideTF {
+	out := make([]DelayOverrideTF, len(in))
+	for i, o := range in {
+		out[i] = o.ToTF()
+	}
+	return out
+}
+
+func toDelayOverrides(in []DelayOverrideTF) []DelayOverride {
+	if len(in) == 0 {
+		return nil
+	}
+
+	out := make([]DelayOverride, len(in))
+	for i, o := range in {
+		out[i] = o.ToAPIReq()
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// DropMatcherOperators are the operators of label matchers, as in Prometheus
+// selectors.
+var DropMatcherOperators = []string{"=", "!=", "=~", "!~"}
+
+var dropMatcherLabelRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+
+// DropMatcher is a label matcher of a conditional drop or of a delay override.
+// The API represents it as a Prometheus matcher such as env="test" or
+// env=~"test|staging".
+type DropMatcher struct {
+	Label    string
+	Operator string
+	Value    string
+}
+
+func (m DropMatcher) String() string {
+	return m.Label + m.Operator + strconv.Quote(m.Value)
+}
+
+func (m DropMatcher) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+func (m *DropMatcher) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDropMatcher(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ParseDropMatcher parses a Prometheus label matcher such as env="test".
+func ParseDropMatcher(s string) (DropMatcher, error) {
+	label := dropMatcherLabelRE.FindString(s)
+	if label == "" {
+		return DropMatcher{}, fmt.Errorf("invalid label matcher %q: missing label name", s)
+	}
+	rest := s[len(label):]
+
+	// Two character operators are checked first, as "=" is a prefix of "=~".
+	var op string
+	for _, candidate := range []string{"=~", "!~", "!=", "="} {
+		if strings.HasPrefix(rest, candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return DropMatcher{}, fmt.Errorf("invalid label matcher %q: missing operator", s)
+	}
+
+	value, err := strconv.Unquote(rest[len(op):])
+	if err != nil {
+		return DropMatcher{}, fmt.Errorf("invalid label matcher %q: value must be a quoted string", s)
+	}
+
+	return DropMatcher{Label: label, Operator: op, Value: value}, nil
+}
+
+func (m DropMatcher) ToTF() DropMatcherTF {
+	return DropMatcherTF{
+		Label:    types.StringValue(m.Label),
+		Operator: types.StringValue(m.Operator),
+		Value:    types.StringValue(m.Value),
+	}
+}
+
+type DropMatcherTF struct {
+	Label    types.String `tfsdk:"label"`
+	Operator types.String `tfsdk:"operator"`
+	Value    types.String `tfsdk:"value"`
+}
+
+// ToAPIReq returns the matcher; an unset operator, as in a configuration
+// before defaults are applied, is "=".
+func (m DropMatcherTF) ToAPIReq() DropMatcher {
+	op := m.Operator.ValueString()
+	if op == "" {
+		op = "="
+	}
+
+	return DropMatcher{
+		Label:    m.Label.ValueString(),
+		Operator: op,
+		Value:    m.Value.ValueString(),
+	}
+}
+
+func toDropMatchersTF(in []DropMatcher) []DropMatcherTF {
+	out := make([]DropMatcherTF, len(in))
+	for i, m := range in {
+		out[i] = m.ToTF()
+	}
+	return out
+}
+
+func toDropMatchers(in []DropMatcherTF) []DropMatcher {
+	if len(in) == 0 {
+		return nil
+	}
+
+	out := make([]DropMatcher, len(in))
+	for i, m := range in {
+		out[i] = m.ToAPIReq()
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// EffectiveRuleAttributeTypes are the attributes of the effective values of a
+// rule resource.
+var EffectiveRuleAttributeTypes = map[string]attr.Type{
+	"metric":                types.StringType,
+	"match_type":            types.StringType,
+	"label_match_mode":      types.StringType,
+	"aggregations":          types.ListType{ElemType: types.StringType},
+	"aggregation_intervals": types.MapType{ElemType: types.StringType},
+	"rollout_percentage":    types.Int64Type,
+	"auto_import":           types.BoolType,
+}
+
+// EffectiveRuleTF are the values a rule is applied with once the defaults of
+// the provider and the API are resolved.
+type EffectiveRuleTF struct {
+	Metric         types.String `tfsdk:"metric"`
+	MatchType      types.String `tfsdk:"match_type"`
+	LabelMatchMode types.String `tfsdk:"label_match_mode"`
+
+	Aggregations         []types.String          `tfsdk:"aggregations"`
+	AggregationIntervals map[string]types.String `tfsdk:"aggregation_intervals"`
+
+	RolloutPercentage types.Int64 `tfsdk:"rollout_percentage"`
+	AutoImport        types.Bool  `tfsdk:"auto_import"`
+}
+
+// EffectiveRule returns the effective values of rule, as sent to the API with
+// the metric prefix and every aggregation type expanded. autoImport is whether
+// the rule is imported rather than created if it already exists.
+func EffectiveRule(rule AggregationRule, autoImport bool) EffectiveRuleTF {
+	matchType := rule.MatchType
+	switch {
+	case len(rule.MatchPrefixes) > 0:
+		matchType = "prefix"
+	case matchType == "":
+		matchType = "exact"
+	}
+	labelMatchMode := rule.LabelMatchMode
+	if labelMatchMode == "" {
+		labelMatchMode = "exact"
+	}
+
+	var aggregations []string
+	intervals := make(map[string]types.String)
+	if !rule.Drop {
+		aggregations = slices.Clone(rule.Aggregations)
+		slices.Sort(aggregations)
+		for _, aggregation := range aggregations {
+			// Types with neither interval are aggregated at the interval
+			// of the API, which it does not report.
+			interval := rule.AggregationIntervals[aggregation]
+			if interval == "" {
+				interval = rule.AggregationInterval
+			}
+			if interval != "" {
+				intervals[aggregation] = types.StringValue(interval)
+			}
+		}
+	}
+
+	rollout := int64(100)
+	if rule.RolloutPercentage != nil {
+		rollout = *rule.RolloutPercentage
+	}
+
+	return EffectiveRuleTF{
+		Metric:         types.StringValue(rule.Metric),
+		MatchType:      types.StringValue(matchType),
+		LabelMatchMode: types.StringValue(labelMatchMode),
+
+		Aggregations:         toTypesStringSlice(aggregations),
+		AggregationIntervals: intervals,
+
+		RolloutPercentage: types.Int64Value(rollout),
+		AutoImport:        types.BoolValue(autoImport),
+	}
+}
@@ -53,3 +53,24 @@ func (e ExemptionTF) ToAPIReq() Exemption {
 		Reason:                 e.Reason.ValueString(),
 	}
 }
+
+type ExemptionsTF struct {
+	Metrics []types.String `tfsdk:"metrics"`
+	Reason  types.String   `tfsdk:"reason"`
+}
+
+// MetricNames returns the exempt metrics, in order.
+func (e ExemptionsTF) MetricNames() []string {
+	return toStringSlice(e.Metrics)
+}
+
+type ExemptionsDataTF struct {
+	Exemptions []ExemptionTF `tfsdk:"exemptions"`
+}
+
+type MetricImportTF struct {
+	Metric      types.String `tfsdk:"metric"`
+	RuleID      types.String `tfsdk:"rule_id"`
+	ExemptionID types.String `tfsdk:"exemption_id"`
+	HCL         types.String `tfsdk:"hcl"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type ImpactPreviewTF struct {
+	Metric    types.String `tfsdk:"metric"`
+	MatchType types.String `tfsdk:"match_type"`
+
+	Drop       types.Bool     `tfsdk:"drop"`
+	KeepLabels []types.String `tfsdk:"keep_labels"`
+	DropLabels []types.String `tfsdk:"drop_labels"`
+
+	Aggregations []types.String `tfsdk:"aggregations"`
+
+	AggregationInterval types.String `tfsdk:"aggregation_interval"`
+	AggregationDelay    types.String `tfsdk:"aggregation_delay"`
+
+	EstimateAvailable types.Bool    `tfsdk:"estimate_available"`
+	ExistingRule      types.Bool    `tfsdk:"existing_rule"`
+	MatchedSeries     types.Int64   `tfsdk:"matched_series"`
+	SeriesBefore      types.Int64   `tfsdk:"series_before"`
+	SeriesAfter       types.Int64   `tfsdk:"series_after"`
+	ReductionPercent  types.Float64 `tfsdk:"reduction_percent"`
+}
+
+// Rule returns the proposed rule.
+func (p ImpactPreviewTF) Rule() AggregationRule {
+	return RuleSetRuleTF{
+		Metric:              p.Metric,
+		MatchType:           p.MatchType,
+		Drop:                p.Drop,
+		KeepLabels:          p.KeepLabels,
+		DropLabels:          p.DropLabels,
+		Aggregations:        p.Aggregations,
+		AggregationInterval: p.AggregationInterval,
+		AggregationDelay:    p.AggregationDelay,
+	}.ToAPIReq()
+}
@@ -0,0 +1,9 @@
+package model
+
+// Lock is a named lock of the API, which is held by a single holder at a time
+// until it is released.
+type Lock struct {
+	Holder string `json:"holder"`
+	// AcquiredAt is set by the API, as an RFC 3339 timestamp.
+	AcquiredAt string `json:"acquired_at,omitempty"`
+}
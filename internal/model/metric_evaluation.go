@@ -0,0 +1,28 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+// Outcomes of a metric evaluation.
+const (
+	OutcomeUnaffected           = "unaffected"
+	OutcomeDropped              = "dropped"
+	OutcomeConditionallyDropped = "conditionally_dropped"
+	OutcomeAggregated           = "aggregated"
+)
+
+type MetricEvaluationTF struct {
+	Metric types.String            `tfsdk:"metric"`
+	Labels map[string]types.String `tfsdk:"labels"`
+
+	RuleMetric    types.String   `tfsdk:"rule_metric"`
+	RuleID        types.String   `tfsdk:"rule_id"`
+	MatchingRules []types.String `tfsdk:"matching_rules"`
+
+	Outcome              types.String            `tfsdk:"outcome"`
+	Aggregations         []types.String          `tfsdk:"aggregations"`
+	AggregationIntervals map[string]types.String `tfsdk:"aggregation_intervals"`
+	AggregationDelay     types.String            `tfsdk:"aggregation_delay"`
+	RolloutPercentage    types.Int64             `tfsdk:"rollout_percentage"`
+	KeptLabels           []types.String          `tfsdk:"kept_labels"`
+	DroppedLabels        []types.String          `tfsdk:"dropped_labels"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// Metric types of MetricMetadata, as in Prometheus.
+const (
+	MetricTypeCounter   = "counter"
+	MetricTypeGauge     = "gauge"
+	MetricTypeHistogram = "histogram"
+)
+
+// MetricMetadata describes a metric, as reported by the instrumentation which
+// exposes it.
+type MetricMetadata struct {
+	Metric string `json:"metric"`
+	Type   string `json:"type"`
+	Help   string `json:"help,omitempty"`
+	Unit   string `json:"unit,omitempty"`
+	// Labels are the names of the labels of the series of the metric. They
+	// are nil if the backend does not report them.
+	Labels []string `json:"labels,omitempty"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type MultiCellRuleTF struct {
+	// Note: these fields are copied from RuleTF because tfsdk doesn't support struct embedding.
+	Metric    types.String `tfsdk:"metric"`
+	MatchType types.String `tfsdk:"match_type"`
+
+	Drop       types.Bool     `tfsdk:"drop"`
+	KeepLabels []types.String `tfsdk:"keep_labels"`
+	DropLabels []types.String `tfsdk:"drop_labels"`
+
+	Aggregations []types.String `tfsdk:"aggregations"`
+
+	AggregationInterval types.String `tfsdk:"aggregation_interval"`
+	AggregationDelay    types.String `tfsdk:"aggregation_delay"`
+
+	Priority types.Int64 `tfsdk:"priority"`
+
+	TargetCells  []TargetCellTF `tfsdk:"target_cells"`
+	AppliedCells []types.String `tfsdk:"applied_cells"`
+}
+
+type TargetCellTF struct {
+	URL    types.String `tfsdk:"url"`
+	APIKey types.String `tfsdk:"api_key"`
+}
+
+func (r MultiCellRuleTF) ToAPIReq() AggregationRule {
+	return RuleSetRuleTF{
+		Metric:    r.Metric,
+		MatchType: r.MatchType,
+
+		Drop:       r.Drop,
+		KeepLabels: r.KeepLabels,
+		DropLabels: r.DropLabels,
+
+		Aggregations: r.Aggregations,
+
+		AggregationInterval: r.AggregationInterval,
+		AggregationDelay:    r.AggregationDelay,
+
+		Priority: r.Priority,
+	}.ToAPIReq()
+}
+
+// TargetURLs returns the URL of every target cell, in order.
+func (r MultiCellRuleTF) TargetURLs() []string {
+	urls := make([]string, len(r.TargetCells))
+	for i, c := range r.TargetCells {
+		urls[i] = c.URL.ValueString()
+	}
+	return urls
+}
+
+// Cell returns the target cell with the given URL. A cell which is no longer
+// targeted has no API key.
+func (r MultiCellRuleTF) Cell(url string) TargetCellTF {
+	for _, c := range r.TargetCells {
+		if c.URL.ValueString() == url {
+			return c
+		}
+	}
+	return TargetCellTF{URL: types.StringValue(url), APIKey: types.StringNull()}
+}
@@ -0,0 +1,75 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type MultiMetricRuleTF struct {
+	Metrics   []types.String `tfsdk:"metrics"`
+	MatchType types.String   `tfsdk:"match_type"`
+
+	// Note: these fields are copied from RuleTF because tfsdk doesn't support struct embedding.
+	Drop       types.Bool     `tfsdk:"drop"`
+	KeepLabels []types.String `tfsdk:"keep_labels"`
+	DropLabels []types.String `tfsdk:"drop_labels"`
+
+	Aggregations []types.String `tfsdk:"aggregations"`
+
+	AggregationInterval types.String `tfsdk:"aggregation_interval"`
+	AggregationDelay    types.String `tfsdk:"aggregation_delay"`
+
+	Priority types.Int64 `tfsdk:"priority"`
+
+	OnConflict types.String `tfsdk:"on_conflict"`
+}
+
+// MetricNames returns the names of the metrics, in order.
+func (r MultiMetricRuleTF) MetricNames() []string {
+	return toStringSlice(r.Metrics)
+}
+
+// ToAPIReq returns the rule for one of the metrics.
+func (r MultiMetricRuleTF) ToAPIReq(metric string) AggregationRule {
+	return RuleSetRuleTF{
+		Metric:    types.StringValue(metric),
+		MatchType: r.MatchType,
+
+		Drop:       r.Drop,
+		KeepLabels: r.KeepLabels,
+		DropLabels: r.DropLabels,
+
+		Aggregations: r.Aggregations,
+
+		AggregationInterval: r.AggregationInterval,
+		AggregationDelay:    r.AggregationDelay,
+
+		Priority: r.Priority,
+	}.ToAPIReq()
+}
+
+// ToAPIReqs returns the rule for every metric, in order.
+func (r MultiMetricRuleTF) ToAPIReqs() []AggregationRule {
+	rules := make([]AggregationRule, len(r.Metrics))
+	for i, metric := range r.MetricNames() {
+		rules[i] = r.ToAPIReq(metric)
+	}
+	return rules
+}
+
+// ToMultiMetricRuleTF returns the settings of r applied to metrics.
+func (r AggregationRule) ToMultiMetricRuleTF(metrics []string) MultiMetricRuleTF {
+	tf := r.ToRuleSetRuleTF()
+	return MultiMetricRuleTF{
+		Metrics:   toTypesStringSlice(metrics),
+		MatchType: tf.MatchType,
+
+		Drop:       tf.Drop,
+		KeepLabels: tf.KeepLabels,
+		DropLabels: tf.DropLabels,
+
+		Aggregations: tf.Aggregations,
+
+		AggregationInterval: tf.AggregationInterval,
+		AggregationDelay:    tf.AggregationDelay,
+
+		Priority: tf.Priority,
+	}
+}
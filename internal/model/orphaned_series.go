@@ -0,0 +1,26 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+// AggregatedMetric is a metric which has aggregated series.
+type AggregatedMetric struct {
+	Metric string `json:"metric"`
+	// Series is the number of aggregated series of the metric.
+	Series int64 `json:"series"`
+}
+
+func (m AggregatedMetric) ToTF() AggregatedMetricTF {
+	return AggregatedMetricTF{
+		Metric: types.StringValue(m.Metric),
+		Series: types.Int64Value(m.Series),
+	}
+}
+
+type AggregatedMetricTF struct {
+	Metric types.String `tfsdk:"metric"`
+	Series types.Int64  `tfsdk:"series"`
+}
+
+type OrphanedSeriesTF struct {
+	Orphans []AggregatedMetricTF `tfsdk:"orphans"`
+}
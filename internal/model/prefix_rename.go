@@ -0,0 +1,14 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type PrefixRenameTF struct {
+	FromPrefix       types.String `tfsdk:"from_prefix"`
+	ToPrefix         types.String `tfsdk:"to_prefix"`
+	OnConflict       types.String `tfsdk:"on_conflict"`
+	IncludeUnmanaged types.Bool   `tfsdk:"include_unmanaged"`
+
+	// Renamed maps the old metric of each renamed rule to its new one.
+	Renamed types.Map  `tfsdk:"renamed"`
+	Skipped types.List `tfsdk:"skipped"`
+}
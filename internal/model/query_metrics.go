@@ -0,0 +1,8 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type QueryMetricsTF struct {
+	Expressions []types.String `tfsdk:"expressions"`
+	Metrics     []types.String `tfsdk:"metrics"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+// Quota are the limits of the API as reported in the headers of its
+// responses. Each of them is nil if the API did not report it.
+type Quota struct {
+	RequestLimit      *int64
+	RequestsRemaining *int64
+	RuleLimit         *int64
+	RuleCount         *int64
+}
+
+func (q Quota) ToTF() QuotaTF {
+	remainingRuleSlots := types.Int64Null()
+	if q.RuleLimit != nil && q.RuleCount != nil {
+		remainingRuleSlots = types.Int64Value(max(*q.RuleLimit-*q.RuleCount, 0))
+	}
+
+	return QuotaTF{
+		RuleLimit:          types.Int64PointerValue(q.RuleLimit),
+		RuleCount:          types.Int64PointerValue(q.RuleCount),
+		RemainingRuleSlots: remainingRuleSlots,
+		RequestLimit:       types.Int64PointerValue(q.RequestLimit),
+		RemainingRequests:  types.Int64PointerValue(q.RequestsRemaining),
+	}
+}
+
+type QuotaTF struct {
+	RuleLimit          types.Int64 `tfsdk:"rule_limit"`
+	RuleCount          types.Int64 `tfsdk:"rule_count"`
+	RemainingRuleSlots types.Int64 `tfsdk:"remaining_rule_slots"`
+	RequestLimit       types.Int64 `tfsdk:"request_limit"`
+	RemainingRequests  types.Int64 `tfsdk:"remaining_requests"`
+}
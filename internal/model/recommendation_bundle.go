@@ -0,0 +1,20 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RecommendationBundleTF struct {
+	Label types.String    `tfsdk:"label"`
+	Value types.String    `tfsdk:"value"`
+	Rules []RuleSetRuleTF `tfsdk:"rules"`
+
+	LastUpdated types.String `tfsdk:"-"`
+}
+
+func (b RecommendationBundleTF) ToAPIReq() []AggregationRule {
+	return RuleSetTF{Rules: b.Rules}.ToAPIReq()
+}
+
+// Metrics returns the metric of every rule in the bundle, in order.
+func (b RecommendationBundleTF) Metrics() []string {
+	return RuleSetTF{Rules: b.Rules}.Metrics()
+}
@@ -1,77 +1,466 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 const managedByTF = "terraform"
 
 type AggregationRule struct {
+	ID string `json:"id,omitempty"`
+	// Name identifies the rule independently of its metric, on backends
+	// which store it.
+	Name      string `json:"name,omitempty"`
 	Metric    string `json:"metric"`
 	MatchType string `json:"match_type,omitempty"`
 
-	Drop       bool     `json:"drop,omitempty"`
-	KeepLabels []string `json:"keep_labels,omitempty"`
-	DropLabels []string `json:"drop_labels,omitempty"`
+	// MatchPrefixes are the prefixes of a rule matching the metrics which
+	// start with any of them. As the API identifies rules by their metric,
+	// Metric is then the first prefix and MatchType is prefix.
+	MatchPrefixes []string `json:"match_prefixes,omitempty"`
+
+	Drop       bool          `json:"drop,omitempty"`
+	DropIf     []DropMatcher `json:"drop_if,omitempty"`
+	KeepLabels []string      `json:"keep_labels,omitempty"`
+	DropLabels []string      `json:"drop_labels,omitempty"`
+	// LabelMatchMode is how the entries of KeepLabels and DropLabels match
+	// label names: exact, glob, or regex. They are label names if it is
+	// empty, as with exact.
+	LabelMatchMode string `json:"label_match_mode,omitempty"`
 
-	Aggregations []string `json:"aggregations,omitempty"`
+	Aggregations Aggregations `json:"aggregations,omitempty"`
 
 	AggregationInterval string `json:"aggregation_interval,omitempty"`
-	AggregationDelay    string `json:"aggregation_delay,omitempty"`
+	// AggregationIntervals override AggregationInterval for some of the
+	// aggregation types, keyed by type.
+	AggregationIntervals map[string]string `json:"aggregation_intervals,omitempty"`
+	AggregationDelay     string            `json:"aggregation_delay,omitempty"`
+	// DelayOverrides replace AggregationDelay for the series matching
+	// them, in order.
+	DelayOverrides []DelayOverride `json:"delay_overrides,omitempty"`
+
+	Priority int64 `json:"priority,omitempty"`
+
+	// RolloutPercentage is the share of the matching series which is
+	// aggregated, for a gradual rollout of the rule. All of them are
+	// aggregated if it is nil, as if it were 100.
+	RolloutPercentage *int64 `json:"rollout_percentage,omitempty"`
+
+	// IngestSampleRate is the share of the series matching the rule which is
+	// ingested, sampled before it is aggregated, for backends which support
+	// ingestion sampling. Every series is ingested if it is nil, as if it
+	// were 1.
+	IngestSampleRate *float64 `json:"ingest_sample_rate,omitempty"`
+
+	// Tags categorize rules, for example by owner or criticality.
+	Tags map[string]string `json:"tags,omitempty"`
 
 	ManagedBy string `json:"managed_by,omitempty"`
 
 	Ingest bool `json:"ingest,omitempty"`
+
+	// Archived rules are kept by the API but no longer applied.
+	Archived bool `json:"archived,omitempty"`
+
+	// RecommendedAggregationInterval is a hint of the API for the
+	// aggregation interval of the metric, based on its scrape interval. It is
+	// empty if there is no recommendation.
+	RecommendedAggregationInterval string `json:"recommended_aggregation_interval,omitempty"`
+
+	// CreatedAt and UpdatedAt are set by the API, as RFC 3339 timestamps.
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// aggregationRuleFields are the JSON names of the fields of AggregationRule.
+var aggregationRuleFields = func() []string {
+	t := reflect.TypeOf(AggregationRule{})
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}()
+
+// AggregationRuleFields returns the names of the fields of a rule which the
+// provider models, as the API names them.
+func AggregationRuleFields() []string {
+	return slices.Clone(aggregationRuleFields)
 }
 
 func (r AggregationRule) ToTF() RuleTF {
+	matchType := r.MatchType
+	recommendedInterval := types.StringNull()
+	if r.RecommendedAggregationInterval != "" {
+		recommendedInterval = types.StringValue(r.RecommendedAggregationInterval)
+	}
+	var aggregationIntervals map[string]types.String
+	if len(r.AggregationIntervals) > 0 {
+		aggregationIntervals = toTypesStringMap(r.AggregationIntervals)
+	}
+	name := types.StringNull()
+	if r.Name != "" {
+		name = types.StringValue(r.Name)
+	}
+	labelMatchMode := types.StringNull()
+	if r.LabelMatchMode != "" {
+		labelMatchMode = types.StringValue(r.LabelMatchMode)
+	}
+	var matchPrefixes []types.String
+	if len(r.MatchPrefixes) > 0 {
+		// The match type is implied by the prefixes.
+		matchType = ""
+		matchPrefixes = toTypesStringSlice(r.MatchPrefixes)
+	}
+
 	return RuleTF{
-		Metric:    types.StringValue(r.Metric),
-		MatchType: types.StringValue(r.MatchType),
+		ID:            types.StringValue(r.ID),
+		Name:          name,
+		Metric:        types.StringValue(r.Metric),
+		MatchType:     types.StringValue(matchType),
+		MatchPrefixes: matchPrefixes,
 
 		Drop:       types.BoolValue(r.Drop),
+		DropIf:     toDropMatchersTF(r.DropIf),
 		KeepLabels: toTypesStringSlice(r.KeepLabels),
 		DropLabels: toTypesStringSlice(r.DropLabels),
 
+		LabelMatchMode: labelMatchMode,
+
 		Aggregations: toTypesStringSlice(r.Aggregations),
 
-		AggregationInterval: types.StringValue(r.AggregationInterval),
-		AggregationDelay:    types.StringValue(r.AggregationDelay),
+		AggregationInterval:  types.StringValue(r.AggregationInterval),
+		AggregationIntervals: aggregationIntervals,
+		AggregationDelay:     types.StringValue(r.AggregationDelay),
+		DelayOverrides:       toDelayOverridesTF(r.DelayOverrides),
+
+		Priority:          types.Int64Value(r.Priority),
+		RolloutPercentage: types.Int64PointerValue(r.RolloutPercentage),
+		IngestSampleRate:  types.Float64PointerValue(r.IngestSampleRate),
+		Tags:              toTypesStringMap(r.Tags),
+
+		Archived: types.BoolValue(r.Archived),
+
+		RecommendedAggregationInterval: recommendedInterval,
+		AggregatedMetricNames:          types.ListNull(types.StringType),
+		ContentID:                      types.StringNull(),
+		Effective:                      types.ObjectNull(EffectiveRuleAttributeTypes),
+		Timeouts:                       timeouts.Value{Object: types.ObjectNull(TimeoutsAttributeTypes)},
+	}
+}
+
+// AggregatedMetricNames returns the names of the series which the rule
+// writes, <metric>:<aggregation> for each of its aggregations in order. A rule
+// which drops its metric writes none.
+func (r AggregationRule) AggregatedMetricNames() []string {
+	names := []string{}
+	if r.Drop {
+		return names
+	}
+
+	aggregations := slices.Clone(r.Aggregations)
+	slices.Sort(aggregations)
+	for _, aggregation := range aggregations {
+		names = append(names, r.Metric+":"+aggregation)
 	}
+	return names
+}
+
+// ContentID returns a hash of how the rule aggregates metrics, which is the
+// same for rules which only differ in the order of their lists, or in their
+// ID, tags, archived flag, and other metadata.
+func (r AggregationRule) ContentID() string {
+	sorted := func(s []string) []string {
+		s = append([]string{}, s...)
+		slices.Sort(s)
+		return s
+	}
+	dropIf := make([]string, len(r.DropIf))
+	for i, m := range r.DropIf {
+		dropIf[i] = m.String()
+	}
+	matchType := r.MatchType
+	if matchType == "" {
+		matchType = "exact"
+	}
+	metric := r.Metric
+	if len(r.MatchPrefixes) > 0 {
+		// The metric is the first prefix, which depends on their order.
+		metric = ""
+	}
+
+	fields := []any{
+		metric, matchType, sorted(r.MatchPrefixes),
+		r.Drop, sorted(dropIf), sorted(r.KeepLabels), sorted(r.DropLabels), r.LabelMatchMode,
+		sorted(r.Aggregations), r.AggregationInterval, r.AggregationIntervals, r.AggregationDelay,
+		r.Priority, r.RolloutPercentage,
+	}
+	if len(r.DelayOverrides) > 0 {
+		// Appended only when set, so that the content ID of rules without
+		// overrides is unchanged. Their order matters, the first matching
+		// override applying.
+		fields = append(fields, r.DelayOverrides)
+	}
+	if r.IngestSampleRate != nil {
+		// Appended only when set, like the delay overrides.
+		fields = append(fields, *r.IngestSampleRate)
+	}
+	content, _ := json.Marshal(fields)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// TimeoutsAttributeTypes are the attributes of the timeouts block of a rule
+// resource.
+var TimeoutsAttributeTypes = map[string]attr.Type{
+	"create": types.StringType,
+	"read":   types.StringType,
+	"update": types.StringType,
+	"delete": types.StringType,
 }
 
 type RuleTF struct {
-	Metric    types.String `tfsdk:"metric"`
-	MatchType types.String `tfsdk:"match_type"`
+	ID            types.String   `tfsdk:"id"`
+	Name          types.String   `tfsdk:"name"`
+	Metric        types.String   `tfsdk:"metric"`
+	MatchType     types.String   `tfsdk:"match_type"`
+	MatchPrefixes []types.String `tfsdk:"match_prefixes"`
+
+	Drop       types.Bool      `tfsdk:"drop"`
+	DropIf     []DropMatcherTF `tfsdk:"drop_if"`
+	KeepLabels []types.String  `tfsdk:"keep_labels"`
+	DropLabels []types.String  `tfsdk:"drop_labels"`
 
-	Drop       types.Bool     `tfsdk:"drop"`
-	KeepLabels []types.String `tfsdk:"keep_labels"`
-	DropLabels []types.String `tfsdk:"drop_labels"`
+	LabelMatchMode types.String `tfsdk:"label_match_mode"`
 
 	Aggregations []types.String `tfsdk:"aggregations"`
 
-	AggregationInterval types.String `tfsdk:"aggregation_interval"`
-	AggregationDelay    types.String `tfsdk:"aggregation_delay"`
+	AggregationInterval  types.String            `tfsdk:"aggregation_interval"`
+	AggregationIntervals map[string]types.String `tfsdk:"aggregation_intervals"`
+	AggregationDelay     types.String            `tfsdk:"aggregation_delay"`
+	DelayOverrides       []DelayOverrideTF       `tfsdk:"delay_overrides"`
+
+	Priority          types.Int64             `tfsdk:"priority"`
+	RolloutPercentage types.Int64             `tfsdk:"rollout_percentage"`
+	IngestSampleRate  types.Float64           `tfsdk:"ingest_sample_rate"`
+	Tags              map[string]types.String `tfsdk:"tags"`
+
+	AutoImport            types.Bool     `tfsdk:"auto_import"`
+	OnConflict            types.String   `tfsdk:"on_conflict"`
+	AllowBroadMatch       types.Bool     `tfsdk:"allow_broad_match"`
+	IgnoreMetricType      types.Bool     `tfsdk:"ignore_metric_type"`
+	IgnoreMissingLabels   types.Bool     `tfsdk:"ignore_missing_labels"`
+	IgnoreOverlaps        types.Bool     `tfsdk:"ignore_overlaps"`
+	RequireRecommendation types.Bool     `tfsdk:"require_recommendation"`
+	DestroyAction         types.String   `tfsdk:"destroy_action"`
+	AllowMissingOnDestroy types.Bool     `tfsdk:"allow_missing_on_destroy"`
+	DrainBeforeDelete     types.String   `tfsdk:"drain_before_delete"`
+	Archived              types.Bool     `tfsdk:"archived"`
+	ForceOutsideWindow    types.Bool     `tfsdk:"force_outside_window"`
+	Timeouts              timeouts.Value `tfsdk:"timeouts"`
 
-	AutoImport types.Bool `tfsdk:"auto_import"`
+	RecommendedAggregationInterval types.String `tfsdk:"recommended_aggregation_interval"`
+	AggregatedMetricNames          types.List   `tfsdk:"aggregated_metric_names"`
+	ContentID                      types.String `tfsdk:"content_id"`
+	// Effective are the EffectiveRuleTF of the rule.
+	Effective types.Object `tfsdk:"effective"`
 
 	LastUpdated types.String `tfsdk:"-"`
 }
 
 func (r RuleTF) ToAPIReq() AggregationRule {
-	return AggregationRule{
+	rule := AggregationRule{
+		ID:        r.ID.ValueString(),
+		Name:      r.Name.ValueString(),
 		Metric:    r.Metric.ValueString(),
 		MatchType: r.MatchType.ValueString(),
 
 		Drop:       r.Drop.ValueBool(),
+		DropIf:     toDropMatchers(r.DropIf),
 		KeepLabels: toStringSlice(r.KeepLabels),
 		DropLabels: toStringSlice(r.DropLabels),
 
+		LabelMatchMode: r.LabelMatchMode.ValueString(),
+
 		Aggregations: toStringSlice(r.Aggregations),
 
-		AggregationInterval: r.AggregationInterval.ValueString(),
-		AggregationDelay:    r.AggregationDelay.ValueString(),
+		AggregationInterval:  r.AggregationInterval.ValueString(),
+		AggregationIntervals: toStringMap(r.AggregationIntervals),
+		AggregationDelay:     r.AggregationDelay.ValueString(),
+		DelayOverrides:       toDelayOverrides(r.DelayOverrides),
+
+		Priority:          r.Priority.ValueInt64(),
+		RolloutPercentage: r.RolloutPercentage.ValueInt64Pointer(),
+		IngestSampleRate:  r.IngestSampleRate.ValueFloat64Pointer(),
+		Tags:              toStringMap(r.Tags),
 
 		ManagedBy: managedByTF,
+
+		Archived: r.Archived.ValueBool(),
+	}
+
+	if prefixes := toStringSlice(r.MatchPrefixes); len(prefixes) > 0 {
+		rule.Metric = prefixes[0]
+		rule.MatchType = "prefix"
+		rule.MatchPrefixes = prefixes
+	}
+	return rule
+}
+
+// AggregationRuleEstimate is the estimated effect of an aggregation rule on
+// the number of series.
+type AggregationRuleEstimate struct {
+	TotalSeriesBeforeAggregation int64 `json:"total_series_before_aggregation"`
+	TotalSeriesAfterAggregation  int64 `json:"total_series_after_aggregation"`
+}
+
+// SavedSeries returns the number of series which the rule saves.
+func (e AggregationRuleEstimate) SavedSeries() int64 {
+	return e.TotalSeriesBeforeAggregation - e.TotalSeriesAfterAggregation
+}
+
+type RulesHCLTF struct {
+	HCL types.String `tfsdk:"hcl"`
+}
+
+type RulesTF struct {
+	MetricPrefix types.String            `tfsdk:"metric_prefix"`
+	MatchType    types.String            `tfsdk:"match_type"`
+	Drop         types.Bool              `tfsdk:"drop"`
+	Group        types.String            `tfsdk:"group"`
+	Tags         map[string]types.String `tfsdk:"tags"`
+
+	Rules []RuleSetRuleTF `tfsdk:"rules"`
+}
+
+// IsManaged reports whether the rule is managed by Terraform.
+func (r AggregationRule) IsManaged() bool {
+	return r.ManagedBy == managedByTF
+}
+
+// Equal reports whether two rules aggregate metrics in the same way. Metadata
+// such as ID, ManagedBy, hints and the timestamps is not compared.
+func (r AggregationRule) Equal(o AggregationRule) bool {
+	return len(r.Differences(o)) == 0
+}
+
+// Differences returns the names of the fields in which two rules differ, in
+// the order of the API, ignoring metadata as Equal does.
+func (r AggregationRule) Differences(o AggregationRule) []string {
+	var fields []string
+	for _, f := range []struct {
+		name  string
+		equal bool
+	}{
+		{"name", r.Name == o.Name},
+		{"metric", r.Metric == o.Metric},
+		{"match_type", r.MatchType == o.MatchType},
+		{"match_prefixes", slices.Equal(r.MatchPrefixes, o.MatchPrefixes)},
+		{"drop", r.Drop == o.Drop},
+		{"drop_if", slices.Equal(r.DropIf, o.DropIf)},
+		{"keep_labels", slices.Equal(r.KeepLabels, o.KeepLabels)},
+		{"drop_labels", slices.Equal(r.DropLabels, o.DropLabels)},
+		{"label_match_mode", r.LabelMatchMode == o.LabelMatchMode},
+		// Aggregations are a set, so their order does not matter.
+		{"aggregations", equalUnordered(r.Aggregations, o.Aggregations)},
+		{"aggregation_interval", r.AggregationInterval == o.AggregationInterval},
+		{"aggregation_intervals", maps.Equal(r.AggregationIntervals, o.AggregationIntervals)},
+		{"aggregation_delay", r.AggregationDelay == o.AggregationDelay},
+		{"delay_overrides", slices.Equal(r.DelayOverrides, o.DelayOverrides)},
+		{"priority", r.Priority == o.Priority},
+		{"rollout_percentage", equalPointers(r.RolloutPercentage, o.RolloutPercentage)},
+		{"ingest_sample_rate", equalPointers(r.IngestSampleRate, o.IngestSampleRate)},
+		{"tags", maps.Equal(r.Tags, o.Tags)},
+		{"archived", r.Archived == o.Archived},
+	} {
+		if !f.equal {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// MergePatch returns a JSON merge patch (RFC 7386) which changes o into r. It
+// only has the fields in which they differ, and ManagedBy if it changed, so
+// that the fields the provider does not manage are left as they are. A field
+// which is empty in r is null in the patch, to remove it.
+func (r AggregationRule) MergePatch(o AggregationRule) (map[string]any, error) {
+	fields := r.Differences(o)
+	if r.ManagedBy != o.ManagedBy {
+		fields = append(fields, "managed_by")
+	}
+
+	values, err := r.fieldValues()
+	if err != nil {
+		return nil, err
+	}
+
+	patch := make(map[string]any, len(fields))
+	for _, f := range fields {
+		patch[f] = values[f]
+	}
+	return patch, nil
+}
+
+// fieldValues returns the fields of r by their names in the API, as they are
+// encoded in JSON. Empty fields are missing.
+func (r AggregationRule) fieldValues() (map[string]any, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := json.Unmarshal(body, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// ComparedFields returns the fields of r which are compared by Differences
+// and set, by their names in the API, with the aggregations sorted, so that
+// rules which are Equal have the same compared fields.
+func (r AggregationRule) ComparedFields() (map[string]any, error) {
+	r.Aggregations = slices.Clone(r.Aggregations)
+	slices.Sort(r.Aggregations)
+
+	values, err := r.fieldValues()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]any)
+	for _, f := range r.Differences(AggregationRule{}) {
+		fields[f] = values[f]
+	}
+	return fields, nil
+}
+
+// Matchers returns a prefix rule for each of the prefixes of r, or r itself if
+// it has none, to tell which metrics it matches.
+func (r AggregationRule) Matchers() []AggregationRule {
+	if len(r.MatchPrefixes) == 0 {
+		return []AggregationRule{r}
+	}
+
+	matchers := make([]AggregationRule, len(r.MatchPrefixes))
+	for i, prefix := range r.MatchPrefixes {
+		m := r
+		m.Metric = prefix
+		m.MatchType = "prefix"
+		m.MatchPrefixes = nil
+		matchers[i] = m
 	}
+	return matchers
 }
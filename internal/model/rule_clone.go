@@ -0,0 +1,73 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RuleCloneTF struct {
+	SourceMetric types.String `tfsdk:"source_metric"`
+	Metric       types.String `tfsdk:"metric"`
+
+	MatchType      types.String      `tfsdk:"match_type"`
+	Drop           types.Bool        `tfsdk:"drop"`
+	DropIf         []DropMatcherTF   `tfsdk:"drop_if"`
+	KeepLabels     []types.String    `tfsdk:"keep_labels"`
+	DropLabels     []types.String    `tfsdk:"drop_labels"`
+	LabelMatchMode types.String      `tfsdk:"label_match_mode"`
+	Aggregations   []types.String    `tfsdk:"aggregations"`
+	DelayOverrides []DelayOverrideTF `tfsdk:"delay_overrides"`
+
+	AggregationInterval  types.String            `tfsdk:"aggregation_interval"`
+	AggregationIntervals map[string]types.String `tfsdk:"aggregation_intervals"`
+	AggregationDelay     types.String            `tfsdk:"aggregation_delay"`
+
+	Priority          types.Int64             `tfsdk:"priority"`
+	RolloutPercentage types.Int64             `tfsdk:"rollout_percentage"`
+	IngestSampleRate  types.Float64           `tfsdk:"ingest_sample_rate"`
+	Tags              map[string]types.String `tfsdk:"tags"`
+}
+
+// Clone sets the attributes of c to those of rule, the rule for the source
+// metric, keeping the metric of c. Attributes which rule does not set are null,
+// so that a resource given them uses its defaults. The ID and name of rule are
+// not copied, as they identify it, nor whether it is archived.
+func (c RuleCloneTF) Clone(rule AggregationRule) RuleCloneTF {
+	stringOrNull := func(s string) types.String {
+		if s == "" {
+			return types.StringNull()
+		}
+		return types.StringValue(s)
+	}
+	var keepLabels, dropLabels, aggregations []types.String
+	if len(rule.KeepLabels) > 0 {
+		keepLabels = toTypesStringSlice(rule.KeepLabels)
+	}
+	if len(rule.DropLabels) > 0 {
+		dropLabels = toTypesStringSlice(rule.DropLabels)
+	}
+	if len(rule.Aggregations) > 0 {
+		aggregations = toTypesStringSlice(rule.Aggregations)
+	}
+	var intervals, tags map[string]types.String
+	if len(rule.AggregationIntervals) > 0 {
+		intervals = toTypesStringMap(rule.AggregationIntervals)
+	}
+	if len(rule.Tags) > 0 {
+		tags = toTypesStringMap(rule.Tags)
+	}
+
+	c.MatchType = stringOrNull(rule.MatchType)
+	c.Drop = types.BoolValue(rule.Drop)
+	c.DropIf = toDropMatchersTF(rule.DropIf)
+	c.KeepLabels = keepLabels
+	c.DropLabels = dropLabels
+	c.LabelMatchMode = stringOrNull(rule.LabelMatchMode)
+	c.Aggregations = aggregations
+	c.DelayOverrides = toDelayOverridesTF(rule.DelayOverrides)
+	c.AggregationInterval = stringOrNull(rule.AggregationInterval)
+	c.AggregationIntervals = intervals
+	c.AggregationDelay = stringOrNull(rule.AggregationDelay)
+	c.Priority = types.Int64Value(rule.Priority)
+	c.RolloutPercentage = types.Int64PointerValue(rule.RolloutPercentage)
+	c.IngestSampleRate = types.Float64PointerValue(rule.IngestSampleRate)
+	c.Tags = tags
+	return c
+}
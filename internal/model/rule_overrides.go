@@ -0,0 +1,37 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+// RuleOverrideTF describes how an exact rule relates to the prefix rules which
+// also match its metric.
+type RuleOverrideTF struct {
+	Metric          types.String   `tfsdk:"metric"`
+	PrefixRules     []types.String `tfsdk:"prefix_rules"`
+	OverriddenRule  types.String   `tfsdk:"overridden_rule"`
+	Applied         types.Bool     `tfsdk:"applied"`
+	ChangesBehavior types.Bool     `tfsdk:"changes_behavior"`
+	DifferingFields []types.String `tfsdk:"differing_fields"`
+}
+
+type RuleOverridesTF struct {
+	Overrides []RuleOverrideTF `tfsdk:"overrides"`
+}
+
+// ToRuleOverrideTF describes how rule overrides prefixRules, the prefix rules
+// which also match its metric in order of precedence, of which the first is
+// the overridden rule. differing are the fields in which rule aggregates the
+// metric differently from it.
+func ToRuleOverrideTF(rule AggregationRule, prefixRules []AggregationRule, applied bool, differing []string) RuleOverrideTF {
+	names := make([]string, len(prefixRules))
+	for i, r := range prefixRules {
+		names[i] = r.Metric
+	}
+	return RuleOverrideTF{
+		Metric:          types.StringValue(rule.Metric),
+		PrefixRules:     toTypesStringSlice(names),
+		OverriddenRule:  types.StringValue(prefixRules[0].Metric),
+		Applied:         types.BoolValue(applied),
+		ChangesBehavior: types.BoolValue(applied && len(differing) > 0),
+		DifferingFields: toTypesStringSlice(differing),
+	}
+}
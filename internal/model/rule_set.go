@@ -0,0 +1,96 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RuleSetTF struct {
+	Rules              []RuleSetRuleTF `tfsdk:"rules"`
+	Prune              types.Bool      `tfsdk:"prune"`
+	AllowBroadMatch    types.Bool      `tfsdk:"allow_broad_match"`
+	ForceOutsideWindow types.Bool      `tfsdk:"force_outside_window"`
+
+	DependsOnMetric map[string]types.String `tfsdk:"depends_on_metric"`
+
+	LastUpdated types.String `tfsdk:"-"`
+}
+
+func (s RuleSetTF) ToAPIReq() []AggregationRule {
+	rules := make([]AggregationRule, len(s.Rules))
+	for i, r := range s.Rules {
+		rules[i] = r.ToAPIReq()
+	}
+	return rules
+}
+
+// DependsOn returns the metric of the rule which each rule depends on, keyed by
+// the metric of the rule.
+func (s RuleSetTF) DependsOn() map[string]string {
+	dependsOn := make(map[string]string, len(s.DependsOnMetric))
+	for metric, dependency := range s.DependsOnMetric {
+		dependsOn[metric] = dependency.ValueString()
+	}
+	return dependsOn
+}
+
+// Metrics returns the metric of every rule in the set, in order.
+func (s RuleSetTF) Metrics() []string {
+	metrics := make([]string, len(s.Rules))
+	for i, r := range s.Rules {
+		metrics[i] = r.Metric.ValueString()
+	}
+	return metrics
+}
+
+type RuleSetRuleTF struct {
+	// Note: these fields are copied from RuleTF because tfsdk doesn't support struct embedding.
+	Metric    types.String `tfsdk:"metric"`
+	MatchType types.String `tfsdk:"match_type"`
+
+	Drop       types.Bool     `tfsdk:"drop"`
+	KeepLabels []types.String `tfsdk:"keep_labels"`
+	DropLabels []types.String `tfsdk:"drop_labels"`
+
+	Aggregations []types.String `tfsdk:"aggregations"`
+
+	AggregationInterval types.String `tfsdk:"aggregation_interval"`
+	AggregationDelay    types.String `tfsdk:"aggregation_delay"`
+
+	Priority types.Int64 `tfsdk:"priority"`
+}
+
+func (r AggregationRule) ToRuleSetRuleTF() RuleSetRuleTF {
+	return RuleSetRuleTF{
+		Metric:    types.StringValue(r.Metric),
+		MatchType: types.StringValue(r.MatchType),
+
+		Drop:       types.BoolValue(r.Drop),
+		KeepLabels: toTypesStringSlice(r.KeepLabels),
+		DropLabels: toTypesStringSlice(r.DropLabels),
+
+		Aggregations: toTypesStringSlice(r.Aggregations),
+
+		AggregationInterval: types.StringValue(r.AggregationInterval),
+		AggregationDelay:    types.StringValue(r.AggregationDelay),
+
+		Priority: types.Int64Value(r.Priority),
+	}
+}
+
+func (r RuleSetRuleTF) ToAPIReq() AggregationRule {
+	return AggregationRule{
+		Metric:    r.Metric.ValueString(),
+		MatchType: r.MatchType.ValueString(),
+
+		Drop:       r.Drop.ValueBool(),
+		KeepLabels: toStringSlice(r.KeepLabels),
+		DropLabels: toStringSlice(r.DropLabels),
+
+		Aggregations: toStringSlice(r.Aggregations),
+
+		AggregationInterval: r.AggregationInterval.ValueString(),
+		AggregationDelay:    r.AggregationDelay.ValueString(),
+
+		Priority: r.Priority.ValueInt64(),
+
+		ManagedBy: managedByTF,
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RuleSuggestionsTF struct {
+	Metrics          []types.String  `tfsdk:"metrics"`
+	Rules            []RuleSetRuleTF `tfsdk:"rules"`
+	UnmatchedMetrics []types.String  `tfsdk:"unmatched_metrics"`
+}
+
+// GetMetrics returns the metrics to suggest rules for.
+func (tf RuleSuggestionsTF) GetMetrics() []string {
+	return toStringSlice(tf.Metrics)
+}
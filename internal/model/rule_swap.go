@@ -0,0 +1,8 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RuleSwapTF struct {
+	MetricA types.String `tfsdk:"metric_a"`
+	MetricB types.String `tfsdk:"metric_b"`
+}
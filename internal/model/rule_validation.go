@@ -0,0 +1,20 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RuleValidationTF struct {
+	Rules   []RuleSetRuleTF          `tfsdk:"rules"`
+	Valid   types.Bool               `tfsdk:"valid"`
+	Results []RuleValidationResultTF `tfsdk:"results"`
+}
+
+// ToAPIReq returns the rules to validate.
+func (tf RuleValidationTF) ToAPIReq() []AggregationRule {
+	return RuleSetTF{Rules: tf.Rules}.ToAPIReq()
+}
+
+type RuleValidationResultTF struct {
+	Metric types.String `tfsdk:"metric"`
+	Valid  types.Bool   `tfsdk:"valid"`
+	Error  types.String `tfsdk:"error"`
+}
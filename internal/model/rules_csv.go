@@ -0,0 +1,142 @@
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type RulesCSVTF struct {
+	Path    types.String `tfsdk:"path"`
+	Content types.String `tfsdk:"content"`
+
+	Rules []RuleSetRuleTF `tfsdk:"rules"`
+}
+
+// RulesCSVColumns are the columns of a rules CSV file, named after the fields
+// of the API. List columns hold semicolon-separated values.
+var RulesCSVColumns = []string{
+	"metric", "match_type", "drop", "keep_labels", "drop_labels",
+	"aggregations", "aggregation_interval", "aggregation_delay", "priority",
+}
+
+// CSVRule is a rule parsed from a row of a rules CSV file.
+type CSVRule struct {
+	// Row is the line of the file on which the row starts, counting from 1.
+	Row  int
+	Rule AggregationRule
+}
+
+// ParseRulesCSV parses a CSV file of aggregation rules with a header row
+// naming its columns, which may be in any order. Only the metric column is
+// required; empty cells leave their field unset. Unknown columns are
+// rejected, as they are most likely misspelled rule settings. Errors name the
+// row they were found on.
+func ParseRulesCSV(data []byte) ([]CSVRule, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the file has no header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, column := range header {
+		// Spreadsheets may start the file with a byte order mark.
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		header[i] = column
+		if !slices.Contains(RulesCSVColumns, column) {
+			return nil, fmt.Errorf("row 1: unknown column %q; the columns must be among %s", column, strings.Join(RulesCSVColumns, ", "))
+		}
+		if slices.Contains(header[:i], column) {
+			return nil, fmt.Errorf("row 1: duplicate column %q", column)
+		}
+	}
+	if !slices.Contains(header, "metric") {
+		return nil, errors.New("row 1: missing the metric column")
+	}
+
+	var rules []CSVRule
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rules, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("row %d: %w", parseErr.StartLine, parseErr.Err)
+			}
+			return nil, err
+		}
+
+		row, _ := r.FieldPos(0)
+		rule, err := parseCSVRule(header, record)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		rules = append(rules, CSVRule{Row: row, Rule: rule})
+	}
+}
+
+func parseCSVRule(header, record []string) (AggregationRule, error) {
+	var rule AggregationRule
+	for i, column := range header {
+		value := strings.TrimSpace(record[i])
+		if value == "" {
+			continue
+		}
+
+		switch column {
+		case "metric":
+			rule.Metric = value
+		case "match_type":
+			rule.MatchType = value
+		case "drop":
+			drop, err := strconv.ParseBool(value)
+			if err != nil {
+				return AggregationRule{}, fmt.Errorf("drop is %q; it must be true or false", value)
+			}
+			rule.Drop = drop
+		case "keep_labels":
+			rule.KeepLabels = splitCSVList(value)
+		case "drop_labels":
+			rule.DropLabels = splitCSVList(value)
+		case "aggregations":
+			rule.Aggregations = splitCSVList(value)
+		case "aggregation_interval":
+			rule.AggregationInterval = value
+		case "aggregation_delay":
+			rule.AggregationDelay = value
+		case "priority":
+			priority, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return AggregationRule{}, fmt.Errorf("priority is %q; it must be an integer", value)
+			}
+			rule.Priority = priority
+		}
+	}
+	return rule, nil
+}
+
+// splitCSVList splits a semicolon-separated list, dropping empty entries such
+// as the one after a trailing semicolon.
+func splitCSVList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
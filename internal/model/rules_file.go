@@ -0,0 +1,82 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+type RulesFileTF struct {
+	Path            types.String   `tfsdk:"path"`
+	AllowBroadMatch types.Bool     `tfsdk:"allow_broad_match"`
+	Metrics         []types.String `tfsdk:"metrics"`
+	RulesHash       types.String   `tfsdk:"rules_hash"`
+}
+
+// Managed returns a rule for every managed metric. Only the metric is set, as
+// is needed to tell which existing rules are owned by the resource.
+func (f RulesFileTF) Managed() []AggregationRule {
+	rules := make([]AggregationRule, len(f.Metrics))
+	for i, m := range f.Metrics {
+		rules[i] = AggregationRule{Metric: m.ValueString()}
+	}
+	return rules
+}
+
+// RulesSnapshot is a backup of every aggregation rule, as exported by the
+// rules_snapshot data source. The rules keep their IDs and timestamps.
+type RulesSnapshot struct {
+	TakenAt string            `json:"taken_at"`
+	Rules   []AggregationRule `json:"rules"`
+}
+
+type RulesSnapshotTF struct {
+	TakenAt   types.String `tfsdk:"taken_at"`
+	RuleCount types.Int64  `tfsdk:"rule_count"`
+	JSON      types.String `tfsdk:"json"`
+}
+
+// ParseRulesFile parses a JSON array of aggregation rules in the format of the
+// API, or a RulesSnapshot. Unknown fields are rejected, as they are most likely
+// misspelled rule settings, and IDs, timestamps and hints of the API are
+// ignored.
+func ParseRulesFile(data []byte) ([]AggregationRule, error) {
+	rules, err := ParseRulesExport(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		rules[i].ID = ""
+		rules[i].CreatedAt = ""
+		rules[i].UpdatedAt = ""
+		rules[i].RecommendedAggregationInterval = ""
+		rules[i].ManagedBy = managedByTF
+	}
+	return rules, nil
+}
+
+// ParseRulesExport parses rules as ParseRulesFile does, but keeps everything
+// set by the API, such as the IDs which identify the rules to import.
+func ParseRulesExport(data []byte) ([]AggregationRule, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var rules []AggregationRule
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var snapshot RulesSnapshot
+		if err := dec.Decode(&snapshot); err != nil {
+			return nil, err
+		}
+		rules = snapshot.Rules
+	} else if err := dec.Decode(&rules); err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the rules")
+	}
+	return rules, nil
+}
@@ -0,0 +1,17 @@
+package model
+
+import "github.com/hashicorp/terraform-plugin-framework/types"
+
+type RulesRestoreTF struct {
+	SnapshotJSON types.String `tfsdk:"snapshot_json"`
+	Confirm      types.Bool   `tfsdk:"confirm"`
+
+	// Created, Updated, and Deleted are the metrics of the rules the restore
+	// creates, updates, and deletes, and ChangesJSON the change report of
+	// them.
+	Created     types.List   `tfsdk:"created"`
+	Updated     types.List   `tfsdk:"updated"`
+	Deleted     types.List   `tfsdk:"deleted"`
+	ChangesJSON types.String `tfsdk:"changes_json"`
+	Restored    types.Bool   `tfsdk:"restored"`
+}
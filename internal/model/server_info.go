@@ -0,0 +1,26 @@
+package model
+
+// ServerInfo describes the capabilities of the Adaptive Metrics API.
+type ServerInfo struct {
+	// SupportedAggregations are the aggregation types which may be used in
+	// rules.
+	SupportedAggregations []string `json:"supported_aggregations"`
+
+	// Features are the optional APIs provided by the backend, such as
+	// "exemptions". Older backends do not report them.
+	Features []string `json:"features,omitempty"`
+
+	// DeprecatedAggregations are the aggregation types which will be removed
+	// from a later version of the backend. Older backends do not report them.
+	DeprecatedAggregations []AggregationDeprecation `json:"deprecated_aggregations,omitempty"`
+}
+
+// AggregationDeprecation describes an aggregation type which is deprecated.
+type AggregationDeprecation struct {
+	Type string `json:"type"`
+	// Replacement is the type to use instead, if there is one.
+	Replacement string `json:"replacement,omitempty"`
+	// RemovalDate is when the type is removed, if it has been decided, as a
+	// date such as "2025-01-31".
+	RemovalDate string `json:"removal_date,omitempty"`
+}
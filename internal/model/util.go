@@ -1,6 +1,11 @@
 package model
 
-import "github.com/hashicorp/terraform-plugin-framework/types"
+import (
+	"cmp"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
 
 func toTypesStringSlice(in []string) []types.String {
 	out := make([]types.String, len(in))
@@ -10,10 +15,59 @@ func toTypesStringSlice(in []string) []types.String {
 	return out
 }
 
+// toStringSlice converts a list attribute into a slice. A null list results in
+// a nil slice, so that it is omitted from requests, while an empty list
+// results in an empty slice.
 func toStringSlice(in []types.String) []string {
+	if in == nil {
+		return nil
+	}
+
 	out := make([]string, len(in))
 	for i, s := range in {
 		out[i] = s.ValueString()
 	}
 	return out
 }
+
+func toTypesStringMap(in map[string]string) map[string]types.String {
+	out := make(map[string]types.String, len(in))
+	for k, v := range in {
+		out[k] = types.StringValue(v)
+	}
+	return out
+}
+
+// toStringMap converts a map attribute into a map. As with toStringSlice, a
+// null map results in a nil map, so that it is omitted from requests.
+func toStringMap(in map[string]types.String) map[string]string {
+	if in == nil {
+		return nil
+	}
+
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v.ValueString()
+	}
+	return out
+}
+
+// equalPointers reports whether a and b are both nil or point to the same
+// value.
+func equalPointers[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// equalUnordered reports whether a and b hold the same elements, in any order.
+func equalUnordered[S ~[]E, E cmp.Ordered](a, b S) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"slices"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// allAggregations is the sentinel standing for every aggregation type
+// supported by the API, as in aggregations = ["*"]. It is expanded when a rule
+// is sent to the API, so that rules using it pick up new aggregation types.
+const allAggregations = "*"
+
+// hasAllAggregations reports whether aggregations is the allAggregations
+// sentinel.
+func hasAllAggregations(aggregations []string) bool {
+	return len(aggregations) == 1 && aggregations[0] == allAggregations
+}
+
+// collapseAllAggregations restores the allAggregations sentinel in rule, read
+// from the API, if it is configured and the rule computes exactly the
+// supported aggregations. A rule which lacks a supported aggregation, such as
+// one added since the rule was applied, is left as it is so that it is
+// updated. If the supported aggregations are unavailable, the rule is assumed
+// to be unchanged.
+func (r *AggregationRules) collapseAllAggregations(rule model.AggregationRule, configured []string) model.AggregationRule {
+	if !hasAllAggregations(configured) {
+		return rule
+	}
+
+	supported, err := r.SupportedAggregations()
+	if err == nil {
+		actual, want := slices.Clone(rule.Aggregations), slices.Clone(supported)
+		slices.Sort(actual)
+		slices.Sort(want)
+		if !slices.Equal(actual, want) {
+			return rule
+		}
+	}
+
+	rule.Aggregations = []string{allAggregations}
+	return rule
+}
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+)
+
+// applyLock is the lock of the backend named by the provider's lock_id. It is
+// acquired before the first change to an aggregation rule and held until the
+// provider exits, so that concurrent runs sharing a lock_id cannot change the
+// rules at the same time. Plans and refreshes do not acquire it. A nil
+// applyLock is never acquired.
+type applyLock struct {
+	client       *client.Client
+	capabilities *capabilities
+
+	name   string
+	holder string
+	// timeout is how long a lock held by another run is waited for.
+	timeout time.Duration
+	// retryInterval is how often a held lock is requested again while it is
+	// waited for.
+	retryInterval time.Duration
+	// releaseTimeout bounds the release of the lock, including retries, so
+	// that it completes before Terraform kills the provider, which it does
+	// shortly after asking it to stop.
+	releaseTimeout time.Duration
+
+	mu   sync.Mutex
+	held bool
+}
+
+func newApplyLock(c *client.Client, caps *capabilities, name string, timeout time.Duration) *applyLock {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+
+	return &applyLock{
+		client:         c,
+		capabilities:   caps,
+		name:           name,
+		holder:         fmt.Sprintf("terraform on %s (pid %d)", host, os.Getpid()),
+		timeout:        timeout,
+		retryInterval:  5 * time.Second,
+		releaseTimeout: time.Second,
+	}
+}
+
+// errLockHeld is returned for changes while another run holds the lock.
+type errLockHeld struct {
+	name string
+	held client.ErrLockHeld
+}
+
+func (e errLockHeld) Error() string {
+	since := ""
+	if e.held.Lock.AcquiredAt != "" {
+		since = " since " + e.held.Lock.AcquiredAt
+	}
+	return fmt.Sprintf("another apply holds the lock %q: it is held by %s%s. Wait for that run to finish, or set the provider's lock_timeout to wait for it", e.name, e.held.Lock.Holder, since)
+}
+
+// acquire acquires the lock unless it is already held. If another run holds
+// it, it is requested again until the timeout or ctx is done. Failures are
+// not cached, so that the next change tries again.
+func (l *applyLock) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held {
+		return nil
+	}
+
+	if l.capabilities.require(client.FeatureApplyLocks).HasError() {
+		return fmt.Errorf("this Adaptive Metrics backend does not support %s, so lock_id cannot be used", featureNames[client.FeatureApplyLocks])
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	for {
+		_, err := l.client.AcquireLock(l.name, l.holder)
+		var held client.ErrLockHeld
+		switch {
+		case err == nil:
+			l.held = true
+			return nil
+		case !errors.As(err, &held):
+			return fmt.Errorf("unable to acquire the lock %q: %w", l.name, err)
+		case !time.Now().Add(l.retryInterval).Before(deadline):
+			return errLockHeld{name: l.name, held: held}
+		}
+
+		select {
+		case <-time.After(l.retryInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for the lock %q: %w", l.name, ctx.Err())
+		}
+	}
+}
+
+// release releases the lock if it is held. It gives up after releaseTimeout,
+// leaving the lock to be expired by the backend.
+func (l *applyLock) release() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.held {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.releaseTimeout)
+	defer cancel()
+	if err := l.client.WithContext(ctx).ReleaseLock(l.name, l.holder); err != nil {
+		return fmt.Errorf("unable to release the lock %q: %w", l.name, err)
+	}
+	l.held = false
+	return nil
+}
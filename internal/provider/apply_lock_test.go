@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// lockedRules returns the rules of api, changed under the apply lock name.
+func lockedRules(t *testing.T, api *mockAPI, name string) (*AggregationRules, *applyLock) {
+	t.Helper()
+
+	rules := api.aggregationRules()
+	rules.capabilities = newCapabilities(api.client())
+	rules.lock = newApplyLock(api.client(), rules.capabilities, name, 0)
+	rules.lock.retryInterval = 10 * time.Millisecond
+	return rules, rules.lock
+}
+
+func TestApplyLock(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureApplyLocks)}
+	rules, lock := lockedRules(t, api, "prod-rules")
+
+	// Reads do not acquire the lock.
+	_, err := rules.Read("test_metric")
+	require.Error(t, err)
+	require.Empty(t, api.locks)
+
+	// The lock is acquired once, before the first change.
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "a", Drop: true}))
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "b", Drop: true}))
+	require.Equal(t, lock.holder, api.locks["prod-rules"].Holder)
+	require.Equal(t, 1, countRequests(api, "PUT /aggregations/locks/prod-rules"))
+
+	require.NoError(t, lock.release())
+	require.Empty(t, api.locks)
+	require.Equal(t, 1, countRequests(api, "DELETE /aggregations/locks/prod-rules"))
+
+	// Releasing a lock which is not held makes no request.
+	require.NoError(t, lock.release())
+	require.Equal(t, 1, countRequests(api, "DELETE /aggregations/locks/prod-rules"))
+}
+
+func TestApplyLockContention(t *testing.T) {
+	t.Run("held", func(t *testing.T) {
+		api := newMockAPI(t)
+		api.features = []string{string(client.FeatureApplyLocks)}
+		api.locks["prod-rules"] = model.Lock{Holder: "terraform on ci-1 (pid 42)", AcquiredAt: "2024-05-01T09:00:00Z"}
+		rules, _ := lockedRules(t, api, "prod-rules")
+
+		err := rules.Create(model.AggregationRule{Metric: "a", Drop: true})
+		require.EqualError(t, err, `another apply holds the lock "prod-rules": it is held by terraform on ci-1 (pid 42) since 2024-05-01T09:00:00Z. Wait for that run to finish, or set the provider's lock_timeout to wait for it`)
+		require.Empty(t, api.metrics())
+	})
+
+	t.Run("released while waiting", func(t *testing.T) {
+		api := newMockAPI(t)
+		api.features = []string{string(client.FeatureApplyLocks)}
+		api.locks["prod-rules"] = model.Lock{Holder: "other run"}
+		rules, lock := lockedRules(t, api, "prod-rules")
+		lock.timeout = 10 * time.Second
+
+		time.AfterFunc(50*time.Millisecond, func() {
+			api.mu.Lock()
+			defer api.mu.Unlock()
+			delete(api.locks, "prod-rules")
+		})
+
+		require.NoError(t, rules.Create(model.AggregationRule{Metric: "a", Drop: true}))
+		require.Equal(t, lock.holder, api.locks["prod-rules"].Holder)
+		require.Greater(t, countRequests(api, "PUT /aggregations/locks/prod-rules"), 1)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		api := newMockAPI(t)
+		api.features = []string{string(client.FeatureApplyLocks)}
+		api.locks["prod-rules"] = model.Lock{Holder: "other run"}
+		rules, lock := lockedRules(t, api, "prod-rules")
+		lock.timeout = 50 * time.Millisecond
+
+		err := rules.Create(model.AggregationRule{Metric: "a", Drop: true})
+		require.ErrorContains(t, err, `another apply holds the lock "prod-rules": it is held by other run.`)
+		require.Greater(t, countRequests(api, "PUT /aggregations/locks/prod-rules"), 1)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		api := newMockAPI(t)
+		api.features = []string{string(client.FeatureApplyLocks)}
+		api.locks["prod-rules"] = model.Lock{Holder: "other run"}
+		_, lock := lockedRules(t, api, "prod-rules")
+		lock.timeout = 2 * time.Hour
+		lock.retryInterval = time.Hour
+
+		// The lock is requested once, then the wait ends with ctx rather
+		// than report the other run as still holding the lock.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := lock.acquire(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualError(t, err, `waiting for the lock "prod-rules": context canceled`)
+		require.False(t, lock.held)
+		require.Equal(t, 1, countRequests(api, "PUT /aggregations/locks/prod-rules"))
+	})
+}
+
+func TestApplyLockUnsupported(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{}
+	rules, _ := lockedRules(t, api, "prod-rules")
+
+	err := rules.Create(model.AggregationRule{Metric: "a", Drop: true})
+	require.EqualError(t, err, "this Adaptive Metrics backend does not support apply locks, so lock_id cannot be used")
+	require.Empty(t, api.metrics())
+}
+
+func TestReleaseApplyLocks(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureApplyLocks)}
+	rules, lock := lockedRules(t, api, "prod-rules")
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "a", Drop: true}))
+
+	ReleaseApplyLocks(&AdaptiveMetricsProvider{locks: []*applyLock{lock}})
+	require.Empty(t, api.locks)
+}
+
+func TestReleaseApplyLocksTimeout(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureApplyLocks)}
+	rules, lock := lockedRules(t, api, "prod-rules")
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "a", Drop: true}))
+
+	// A backend which does not answer in time does not block the exit of
+	// the provider; the lock is left to expire.
+	api.blockRequests()
+	lock.releaseTimeout = 20 * time.Millisecond
+	ReleaseApplyLocks(&AdaptiveMetricsProvider{locks: []*applyLock{lock}})
+	require.True(t, lock.held)
+}
+
+func countRequests(api *mockAPI, request string) int {
+	n := 0
+	for _, r := range api.requestLog() {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ApplyWindowTF is the provider's apply_window setting.
+type ApplyWindowTF struct {
+	Days     []types.String `tfsdk:"days"`
+	Start    types.String   `tfsdk:"start"`
+	End      types.String   `tfsdk:"end"`
+	TimeZone types.String   `tfsdk:"time_zone"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// applyWindow is a recurring maintenance window outside of which aggregation
+// rules must not be changed. It opens at start on each of its days and
+// closes at end, which is on the following day if it is not after start.
+type applyWindow struct {
+	days       map[time.Weekday]bool
+	start, end time.Duration
+	location   *time.Location
+
+	// now returns the current time; it is replaced in tests.
+	now func() time.Time
+}
+
+// errOutsideApplyWindow is returned for changes made outside of the apply
+// window.
+type errOutsideApplyWindow struct {
+	window *applyWindow
+	next   time.Time
+}
+
+func (e errOutsideApplyWindow) Error() string {
+	return fmt.Sprintf("aggregation rules may only be changed %s, and the next window opens at %s. Set force_outside_window = true on a rule, rule_set, or transactional_rule_set resource to apply its change anyway; other resources cannot change rules outside of the window",
+		e.window, e.next.Format("Mon 2006-01-02 15:04 MST"))
+}
+
+func parseApplyWindow(tf ApplyWindowTF) (*applyWindow, error) {
+	w := &applyWindow{days: make(map[time.Weekday]bool), location: time.UTC, now: time.Now}
+
+	for _, d := range tf.Days {
+		day, ok := weekdays[strings.ToLower(d.ValueString())]
+		if !ok {
+			return nil, fmt.Errorf("got the day %q; days must be one of mon, tue, wed, thu, fri, sat, or sun", d.ValueString())
+		}
+		w.days[day] = true
+	}
+	if len(w.days) == 0 {
+		for _, day := range weekdays {
+			w.days[day] = true
+		}
+	}
+
+	var err error
+	if w.start, err = parseTimeOfDay(tf.Start.ValueString()); err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	if w.end, err = parseTimeOfDay(tf.End.ValueString()); err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("start and end are both %s; the window must not be empty", tf.Start.ValueString())
+	}
+
+	if tz := tf.TimeZone.ValueString(); tz != "" {
+		if w.location, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid time_zone: %w", err)
+		}
+	}
+
+	return w, nil
+}
+
+// parseTimeOfDay parses a time of day in the format HH:MM.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("got %q; it must be a time of day such as 09:30", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// check returns an errOutsideApplyWindow if the window is closed. A nil
+// window is always open.
+func (w *applyWindow) check() error {
+	if w == nil {
+		return nil
+	}
+
+	now := w.now().In(w.location)
+	if w.contains(now) {
+		return nil
+	}
+	return errOutsideApplyWindow{window: w, next: w.next(now)}
+}
+
+// contains reports whether the window is open at t, which is in its location.
+func (w *applyWindow) contains(t time.Time) bool {
+	opens, closes := at(t, 0, w.start), at(t, 0, w.end)
+
+	if w.start < w.end {
+		return w.days[t.Weekday()] && !t.Before(opens) && t.Before(closes)
+	}
+	// The window spans midnight, so it may have opened on the previous day.
+	yesterday := at(t, -1, 0).Weekday()
+	return (w.days[t.Weekday()] && !t.Before(opens)) || (w.days[yesterday] && t.Before(closes))
+}
+
+// next returns the time after t at which the window opens next.
+func (w *applyWindow) next(t time.Time) time.Time {
+	for offset := 0; offset <= 7; offset++ {
+		opens := at(t, offset, w.start)
+		if w.days[opens.Weekday()] && opens.After(t) {
+			return opens
+		}
+	}
+	// Unreachable: every window opens at least once a week.
+	return t
+}
+
+// at returns the time of day timeOfDay on the day days after the day of t, in
+// the location of t. The wall clock time is kept on days on which the clocks
+// change for daylight saving time, unlike when adding timeOfDay to midnight.
+func at(t time.Time, days int, timeOfDay time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+days, int(timeOfDay/time.Hour), int(timeOfDay%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// String describes the window, such as "on mon, tue from 09:00 to 17:00 UTC".
+func (w *applyWindow) String() string {
+	var days []string
+	for _, name := range []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"} {
+		if w.days[weekdays[name]] {
+			days = append(days, name)
+		}
+	}
+	timeOfDay := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	return fmt.Sprintf("on %s from %s to %s %s", strings.Join(days, ", "), timeOfDay(w.start), timeOfDay(w.end), w.location)
+}
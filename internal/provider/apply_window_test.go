@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyWindow(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		window   ApplyWindowTF
+		now      time.Time
+		contains bool
+		next     time.Time
+	}{
+		{
+			name:     "inside",
+			window:   ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00")},
+			now:      time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC),
+			contains: true,
+		},
+		{
+			name:   "before start",
+			window: ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00")},
+			now:    time.Date(2026, 3, 2, 8, 59, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "at end",
+			window: ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00")},
+			now:    time.Date(2026, 3, 2, 17, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "other day",
+			window: ApplyWindowTF{Days: toTypesStrings([]string{"tue", "Thu"}), Start: types.StringValue("09:00"), End: types.StringValue("17:00")},
+			now:    time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "next week",
+			window: ApplyWindowTF{Days: toTypesStrings([]string{"mon"}), Start: types.StringValue("09:00"), End: types.StringValue("17:00")},
+			now:    time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "overnight after midnight",
+			window:   ApplyWindowTF{Days: toTypesStrings([]string{"fri"}), Start: types.StringValue("22:00"), End: types.StringValue("02:00")},
+			now:      time.Date(2026, 3, 7, 1, 0, 0, 0, time.UTC),
+			contains: true,
+		},
+		{
+			name:   "overnight on the following night",
+			window: ApplyWindowTF{Days: toTypesStrings([]string{"fri"}), Start: types.StringValue("22:00"), End: types.StringValue("02:00")},
+			now:    time.Date(2026, 3, 7, 23, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 13, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "time zone",
+			window: ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00"), TimeZone: types.StringValue("Europe/Berlin")},
+			now:    time.Date(2026, 3, 2, 16, 30, 0, 0, time.UTC),
+			next:   time.Date(2026, 3, 3, 9, 0, 0, 0, berlin),
+		},
+		{
+			// The clocks are put forward at 02:00 on 2026-03-29.
+			name:     "daylight saving time starts",
+			window:   ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00"), TimeZone: types.StringValue("Europe/Berlin")},
+			now:      time.Date(2026, 3, 29, 9, 30, 0, 0, berlin),
+			contains: true,
+		},
+		{
+			name:   "before start on daylight saving time",
+			window: ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00"), TimeZone: types.StringValue("Europe/Berlin")},
+			now:    time.Date(2026, 3, 29, 8, 30, 0, 0, berlin),
+			next:   time.Date(2026, 3, 29, 9, 0, 0, 0, berlin),
+		},
+		{
+			// The clocks are put back at 03:00 on 2026-10-25.
+			name:     "daylight saving time ends",
+			window:   ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00"), TimeZone: types.StringValue("Europe/Berlin")},
+			now:      time.Date(2026, 10, 25, 16, 30, 0, 0, berlin),
+			contains: true,
+		},
+		{
+			name:   "at end on daylight saving time",
+			window: ApplyWindowTF{Start: types.StringValue("09:00"), End: types.StringValue("17:00"), TimeZone: types.StringValue("Europe/Berlin")},
+			now:    time.Date(2026, 10, 25, 17, 0, 0, 0, berlin),
+			next:   time.Date(2026, 10, 26, 9, 0, 0, 0, berlin),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := parseApplyWindow(tc.window)
+			require.NoError(t, err)
+			w.now = func() time.Time { return tc.now }
+
+			err = w.check()
+			if tc.contains {
+				require.NoError(t, err)
+				return
+			}
+			var outside errOutsideApplyWindow
+			require.ErrorAs(t, err, &outside)
+			require.True(t, tc.next.Equal(outside.next), "next window at %s, expected %s", outside.next, tc.next)
+			require.Contains(t, err.Error(), "force_outside_window")
+		})
+	}
+}
+
+func TestParseApplyWindowErrors(t *testing.T) {
+	for _, tc := range []ApplyWindowTF{
+		{Days: toTypesStrings([]string{"monday"}), Start: types.StringValue("09:00"), End: types.StringValue("17:00")},
+		{Start: types.StringValue("9am"), End: types.StringValue("17:00")},
+		{Start: types.StringValue("09:00"), End: types.StringValue("24:00")},
+		{Start: types.StringValue("09:00"), End: types.StringValue("09:00")},
+		{Start: types.StringValue("09:00"), End: types.StringValue("17:00"), TimeZone: types.StringValue("Mars/Olympus")},
+	} {
+		_, err := parseApplyWindow(tc)
+		require.Error(t, err, tc)
+	}
+}
+
+func TestApplyWindowUnset(t *testing.T) {
+	var w *applyWindow
+	require.NoError(t, w.check())
+}
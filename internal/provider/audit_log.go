@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// auditLog appends a line of JSON describing every change the provider makes
+// to the aggregation rules to a file, as an audit trail of the rule changes
+// applied by Terraform. It is safe for concurrent use, and a nil auditLog
+// records nothing.
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	// failed is set once a write failed, which is only logged once.
+	failed bool
+}
+
+// auditEntry is a line of an auditLog. Before is nil for created rules and
+// After for deleted rules; Changed lists the fields of updated rules which
+// differ. Before is also nil for an updated rule which was neither cached nor
+// could be read before the update, such as one renamed to a new metric. The rules are as sent to and returned by the API, with the metric
+// prefix of the provider.
+type auditEntry struct {
+	Time      time.Time              `json:"time"`
+	Operation string                 `json:"operation"`
+	Metric    string                 `json:"metric"`
+	Cell      string                 `json:"cell,omitempty"`
+	Changed   []string               `json:"changed,omitempty"`
+	Before    *model.AggregationRule `json:"before"`
+	After     *model.AggregationRule `json:"after"`
+}
+
+// openAuditLog opens the file at path to append an auditLog to it, creating
+// it if needed. Only the current user may read a file it creates.
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the audit log: %w", err)
+	}
+	return &auditLog{file: f}, nil
+}
+
+// close closes the file of the log. Changes made afterwards are not recorded.
+func (l *auditLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// record logs that the rule before was changed into after by operation, one
+// of create, update, or delete, in the cell at the given URL, or in the
+// provider's stack if cell is empty.
+func (l *auditLog) record(operation, metric, cell string, before, after *model.AggregationRule) {
+	if l == nil {
+		return
+	}
+
+	entry := auditEntry{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Metric:    metric,
+		Cell:      cell,
+		Before:    before,
+		After:     after,
+	}
+	if before != nil && after != nil {
+		entry.Changed = after.Differences(*before)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return
+	}
+	// A single write per entry to a file opened for appending, so that the
+	// lines of concurrent changes, even of several runs, are never
+	// interleaved.
+	if _, err := l.file.Write(line); err != nil && !l.failed {
+		l.failed = true
+		log.Printf("[WARN] unable to write to the audit log: %s", err)
+	}
+}
@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// readAuditLog returns the entries of the audit log at path.
+func readAuditLog(t *testing.T, path string) []auditEntry {
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		var entry auditEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "team_old", Drop: true})
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(logPath)
+	require.NoError(t, err)
+
+	rules := NewAggregationRules(api.client(), "team_")
+	rules.audit = audit
+	require.NoError(t, rules.Init())
+
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "a", Drop: true}))
+	require.NoError(t, rules.Update(model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}}))
+	require.NoError(t, rules.Apply(ruleSetChanges{
+		Create: []model.AggregationRule{{Metric: "b", Drop: true}},
+		Delete: []model.AggregationRule{{Metric: "old"}},
+	}))
+	require.NoError(t, rules.Delete(model.AggregationRule{Metric: "a"}))
+
+	// Failed changes are not recorded.
+	require.Error(t, rules.Create(model.AggregationRule{Metric: "b", Drop: true}))
+	require.NoError(t, audit.close())
+
+	entries := readAuditLog(t, logPath)
+	var ops []string
+	for _, e := range entries {
+		ops = append(ops, e.Operation+" "+e.Metric)
+		require.False(t, e.Time.IsZero())
+	}
+	require.Equal(t, []string{"create team_a", "update team_a", "create team_b", "delete team_old", "delete team_a"}, ops)
+
+	require.Nil(t, entries[0].Before)
+	require.Equal(t, "team_a", entries[0].After.Metric)
+	require.True(t, entries[0].After.Drop)
+
+	require.Equal(t, []string{"drop", "drop_labels", "aggregations"}, entries[1].Changed)
+	require.True(t, entries[1].Before.Drop)
+	require.Equal(t, []string{"pod"}, entries[1].After.DropLabels)
+
+	require.Equal(t, "team_old", entries[3].Before.Metric)
+	require.Nil(t, entries[3].After)
+}
+
+func TestAuditLogBulk(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "a", Drop: true},
+		model.AggregationRule{Metric: "b", Drop: true},
+		model.AggregationRule{Metric: "c", Drop: true},
+	)
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(logPath)
+	require.NoError(t, err)
+
+	rules := api.aggregationRules()
+	rules.audit = audit
+	require.NoError(t, rules.ApplyBulk(ruleSetChanges{
+		Create: []model.AggregationRule{{Metric: "d", Drop: true}},
+		Update: []model.AggregationRule{{Metric: "c", DropLabels: []string{"pod"}}},
+		Delete: []model.AggregationRule{{Metric: "a"}},
+	}))
+	require.NoError(t, audit.close())
+
+	// The rules of the bulk request which did not change are not recorded.
+	var ops []string
+	for _, e := range readAuditLog(t, logPath) {
+		ops = append(ops, e.Operation+" "+e.Metric)
+	}
+	require.Equal(t, []string{"delete a", "update c", "create d"}, ops)
+}
+
+func TestAuditLogUncachedUpdate(t *testing.T) {
+	api := newMockAPI(t)
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(logPath)
+	require.NoError(t, err)
+
+	rules := api.aggregationRules()
+	rules.audit = audit
+	require.NoError(t, rules.Init())
+
+	// The rule was created by someone else since the rules were listed, so
+	// it is read before it is updated.
+	api.mu.Lock()
+	api.rules["a"] = model.AggregationRule{Metric: "a", Drop: true}
+	api.mu.Unlock()
+	require.NoError(t, rules.Update(model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}}))
+	require.NoError(t, audit.close())
+	require.Contains(t, api.requestLog(), "GET /aggregations/rule/a")
+
+	entries := readAuditLog(t, logPath)
+	require.Len(t, entries, 1)
+	require.Equal(t, "update", entries[0].Operation)
+	require.NotNil(t, entries[0].Before)
+	require.True(t, entries[0].Before.Drop)
+	require.Equal(t, []string{"drop", "drop_labels"}, entries[0].Changed)
+}
+
+func TestAuditLogConcurrent(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	p := &AdaptiveMetricsProvider{}
+
+	// Instances of the provider auditing to the same file share it.
+	audit, err := p.openAuditLog(logPath)
+	require.NoError(t, err)
+	shared, err := p.openAuditLog(logPath)
+	require.NoError(t, err)
+	require.Same(t, audit, shared)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rule := model.AggregationRule{Metric: fmt.Sprintf("metric_%d", i), DropLabels: []string{strings.Repeat("label", 100)}}
+			audit.record("create", rule.Metric, "", nil, &rule)
+		}(i)
+	}
+	wg.Wait()
+	CloseAuditLogs(p)
+
+	// Every line is a whole entry.
+	entries := readAuditLog(t, logPath)
+	require.Len(t, entries, 50)
+
+	// Changes made once the log is closed are not recorded.
+	audit.record("delete", "metric_0", "", nil, nil)
+	require.Len(t, readAuditLog(t, logPath), 50)
+}
@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// featureNames describe the optional features in diagnostics.
+var featureNames = map[client.Feature]string{
+	client.FeatureRecommendations:   "recommendations",
+	client.FeatureExemptions:        "exemptions",
+	client.FeatureAggregatedMetrics: "listing the metrics with aggregated series",
+	client.FeatureMatchPrefixes:     "rules matching several prefixes",
+
+	client.FeatureAggregationIntervals: "intervals per aggregation type",
+	client.FeaturePartialUpdates:       "partial updates of rules",
+	client.FeatureLabelPatterns:        "label patterns in keep_labels and drop_labels",
+	client.FeatureRuleNames:            "rule names",
+	client.FeatureMetricMetadata:       "metric metadata",
+	client.FeatureFieldSelection:       "field selection",
+	client.FeatureDelayOverrides:       "aggregation delays per label matcher",
+	client.FeatureApplyLocks:           "apply locks",
+	client.FeatureIngestSampling:       "ingestion sampling",
+	client.FeatureRulePagination:       "listing rules by page",
+	client.FeatureRuleFilters:          "filtering the listed rules, such as by group",
+	client.FeatureArchivedRules:        "archived rules",
+	client.FeatureConditionalDrops:     "conditional drops with drop_if",
+	client.FeaturePercentiles:          "percentile aggregations such as p99",
+	client.FeatureRollouts:             "gradual rollouts with rollout_percentage",
+	client.FeatureAggregatedNames:      "naming aggregated series by their metric and aggregation type",
+	client.FeatureRuleTags:             "rule tags",
+	client.FeaturePriority:             "rule priorities",
+}
+
+// capabilities detects which optional features the backend supports, so that
+// resources and data sources for a missing feature report it clearly rather
+// than with the 404 of its endpoints. The features are taken from the server
+// info if it lists them, and probed otherwise. Every result is cached for the
+// run. A nil capabilities assumes that every feature is supported.
+//
+// The provider shares one capabilities among all of its resources and data
+// sources, and its AggregationRules, so that the server info is requested at
+// most once per run.
+type capabilities struct {
+	client *client.Client
+
+	mu sync.Mutex
+	// infoLoaded is set once the server info has been requested. infoErr
+	// is the ErrNotFound of backends which do not provide it, which list no
+	// features.
+	infoLoaded bool
+	info       model.ServerInfo
+	infoErr    error
+	supported  map[client.Feature]bool
+}
+
+func newCapabilities(c *client.Client) *capabilities {
+	return &capabilities{client: c, supported: make(map[client.Feature]bool)}
+}
+
+// require reports an error if the backend does not support feature. If that
+// cannot be determined, the feature is assumed to be supported so that the
+// request for it reports what went wrong.
+func (c *capabilities) require(feature client.Feature) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if c == nil {
+		return diags
+	}
+
+	if supported, err := c.supports(feature); err == nil && !supported {
+		diags.AddError(
+			"Feature not supported by backend",
+			fmt.Sprintf("This Adaptive Metrics backend does not support %s. It may be running an older version which does not provide this API yet.", featureNames[feature]),
+		)
+	}
+	return diags
+}
+
+// requireRule reports an error for each optional feature which rule uses but
+// the backend does not support, before the rule is sent to it.
+func (c *capabilities) requireRule(rule model.AggregationRule) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if len(rule.MatchPrefixes) > 0 {
+		diags.Append(c.require(client.FeatureMatchPrefixes)...)
+	}
+	if len(rule.AggregationIntervals) > 0 {
+		diags.Append(c.require(client.FeatureAggregationIntervals)...)
+	}
+	if len(rule.DelayOverrides) > 0 {
+		diags.Append(c.require(client.FeatureDelayOverrides)...)
+	}
+	if len(rule.DropIf) > 0 {
+		diags.Append(c.require(client.FeatureConditionalDrops)...)
+	}
+	if usesLabelPatterns(rule) {
+		diags.Append(c.require(client.FeatureLabelPatterns)...)
+	}
+	if rule.IngestSampleRate != nil {
+		diags.Append(c.require(client.FeatureIngestSampling)...)
+	}
+	if slices.ContainsFunc(rule.Aggregations, model.IsPercentile) {
+		diags.Append(c.require(client.FeaturePercentiles)...)
+	}
+	if rule.RolloutPercentage != nil {
+		diags.Append(c.require(client.FeatureRollouts)...)
+	}
+	if len(rule.Tags) > 0 {
+		diags.Append(c.require(client.FeatureRuleTags)...)
+	}
+	if rule.Priority != 0 {
+		diags.Append(c.require(client.FeaturePriority)...)
+	}
+	return diags
+}
+
+// supports reports whether the backend provides feature. Failed requests are
+// not cached, so that they are retried.
+func (c *capabilities) supports(feature client.Feature) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if supported, ok := c.supported[feature]; ok {
+		return supported, nil
+	}
+
+	if err := c.loadInfo(); err != nil {
+		return false, err
+	}
+
+	var supported bool
+	if c.info.Features != nil {
+		supported = slices.Contains(c.info.Features, string(feature))
+	} else {
+		var err error
+		if supported, err = c.client.ProbeFeature(feature); err != nil {
+			return false, err
+		}
+	}
+
+	c.supported[feature] = supported
+	return supported, nil
+}
+
+// serverInfo returns the server info of the backend, which is requested once.
+// Failed requests are not cached, so that they are retried.
+func (c *capabilities) serverInfo() (model.ServerInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.loadInfo(); err != nil {
+		return model.ServerInfo{}, err
+	}
+	return c.info, c.infoErr
+}
+
+// loadInfo requests the server info unless it has been already. It must be
+// called with mu held.
+func (c *capabilities) loadInfo() error {
+	if c.infoLoaded {
+		return nil
+	}
+
+	info, err := c.client.ServerInfo()
+	var notFound client.ErrNotFound
+	switch {
+	case err == nil:
+		c.info = info
+	case errors.As(err, &notFound):
+		c.infoErr = err
+	default:
+		return err
+	}
+	c.infoLoaded = true
+	return nil
+}
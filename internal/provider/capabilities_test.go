@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+)
+
+func TestCapabilitiesFromServerInfo(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{"exemptions"}
+	caps := newCapabilities(api.client())
+
+	for i := 0; i < 2; i++ {
+		require.False(t, caps.require(client.FeatureExemptions).HasError())
+
+		diags := caps.require(client.FeatureRecommendations)
+		require.True(t, diags.HasError())
+		require.Equal(t, "Feature not supported by backend", diags.Errors()[0].Summary())
+		require.Contains(t, diags.Errors()[0].Detail(), "does not support recommendations")
+	}
+
+	// The server info is requested once, and the features are not probed.
+	require.Equal(t, []string{"GET /aggregations/server_info"}, api.requestLog())
+}
+
+func TestCapabilitiesProbed(t *testing.T) {
+	api := newMockAPI(t)
+	caps := newCapabilities(api.client())
+
+	for i := 0; i < 2; i++ {
+		require.False(t, caps.require(client.FeatureExemptions).HasError())
+		require.True(t, caps.require(client.FeatureAggregatedMetrics).HasError())
+		// Features without an endpoint of their own cannot be probed.
+		require.True(t, caps.require(client.FeatureMatchPrefixes).HasError())
+	}
+
+	// Each feature is probed once.
+	require.Equal(t, []string{
+		"GET /aggregations/server_info",
+		"GET " + mockExemptionsPath,
+		"GET /aggregations/aggregated_metrics",
+	}, api.requestLog())
+}
+
+func TestCapabilitiesSharedByResources(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.features = []string{"match_prefixes"}
+	api.supportedAggregations = []string{"count", "sum"}
+
+	c := api.client()
+	rules := api.aggregationRules()
+	caps := newCapabilities(c)
+	rules.capabilities = caps
+	data := &resourceData{aggRules: rules, client: c, capabilities: caps}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		r := &ruleResource{}
+		r.Configure(ctx, fwresource.ConfigureRequest{ProviderData: data}, &fwresource.ConfigureResponse{})
+		d := &exemptionsDatasource{}
+		d.Configure(ctx, datasource.ConfigureRequest{ProviderData: data}, &datasource.ConfigureResponse{})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.False(t, r.capabilities.require(client.FeatureMatchPrefixes).HasError())
+			assert.True(t, d.capabilities.require(client.FeatureExemptions).HasError())
+			_, err := r.rules.SupportedAggregations()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// The server info is requested once for every resource, data source,
+	// and the supported aggregations.
+	require.Equal(t, []string{"GET /aggregations/rules", "GET /aggregations/server_info"}, api.requestLog())
+}
+
+func TestCapabilitiesNil(t *testing.T) {
+	var caps *capabilities
+	require.False(t, caps.require(client.FeatureExemptions).HasError())
+}
+
+func TestExemptionsDatasourceUnsupported(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.features = []string{"recommendations"}
+	d := &exemptionsDatasource{client: api.client(), capabilities: newCapabilities(api.client())}
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{}, resp)
+
+	require.Len(t, resp.Diagnostics.Errors(), 1)
+	require.Equal(t, "Feature not supported by backend", resp.Diagnostics.Errors()[0].Summary())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support exemptions")
+	require.NotContains(t, api.requestLog(), "GET "+mockExemptionsPath)
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type cellDiffDatasource struct {
+	cells *cellRules
+}
+
+var (
+	_ datasource.DataSource              = &cellDiffDatasource{}
+	_ datasource.DataSourceWithConfigure = &cellDiffDatasource{}
+)
+
+func newCellDiffDatasource() datasource.DataSource {
+	return &cellDiffDatasource{}
+}
+
+func (d *cellDiffDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.cells = data.cells
+}
+
+func (d *cellDiffDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_cell_diff", req.ProviderTypeName)
+}
+
+func cellAttribute(description string) schema.SingleNestedAttribute {
+	return schema.SingleNestedAttribute{
+		Required:    true,
+		Description: description,
+		Attributes: map[string]schema.Attribute{
+			"url": schema.StringAttribute{
+				Required:    true,
+				Description: "The URL of the Adaptive Metrics API of the cell.",
+			},
+			"api_key": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "The API key or service account token of the cell. Defaults to the provider's api_key.",
+			},
+		},
+	}
+}
+
+func (d *cellDiffDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Compares the aggregation rules of two cells, such as the stacks of different regions, for example to verify that a migration copied every rule. Rules are compared by metric, within the provider's metric_prefix, ignoring metadata such as their IDs. Nothing is changed.",
+		Attributes: map[string]schema.Attribute{
+			"cell_a": cellAttribute("The first cell to compare."),
+			"cell_b": cellAttribute("The second cell to compare."),
+			"only_in_a": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics which only have a rule in cell_a, ordered by metric.",
+			},
+			"only_in_b": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics which only have a rule in cell_b, ordered by metric.",
+			},
+			"differing": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The metrics whose rule differs between the cells, ordered by metric.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The metric of the rule.",
+						},
+						"fields": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The fields of the rule which differ, such as drop_labels or aggregations.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *cellDiffDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var tf model.CellDiffTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &tf)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Both cells are read at once, and before giving up, so that the errors
+	// of both are reported together.
+	cells := []model.TargetCellTF{tf.CellA, tf.CellB}
+	rules := make([][]model.AggregationRule, len(cells))
+	errs := parallel(ctx, 0, len(cells), func(_ context.Context, i int) error {
+		var err error
+		rules[i], err = d.readCell(cells[i])
+		return err
+	})
+	for i, p := range []path.Path{path.Root("cell_a"), path.Root("cell_b")} {
+		if errs[i] != nil {
+			resp.Diagnostics.AddAttributeError(p, "Unable to read the rules of cell", fmt.Sprintf("The rules of the cell at %s could not be read: %s", cells[i].URL.ValueString(), errs[i]))
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	a, b := rules[0], rules[1]
+
+	rulesB := make(map[string]model.AggregationRule, len(b))
+	for _, rule := range b {
+		rulesB[rule.Metric] = rule
+	}
+
+	tf.OnlyInA, tf.OnlyInB, tf.Differing = []types.String{}, []types.String{}, []model.RuleDifferenceTF{}
+	for _, rule := range a {
+		other, ok := rulesB[rule.Metric]
+		delete(rulesB, rule.Metric)
+		switch {
+		case !ok:
+			tf.OnlyInA = append(tf.OnlyInA, types.StringValue(rule.Metric))
+		case !rule.Equal(other):
+			tf.Differing = append(tf.Differing, model.ToRuleDifferenceTF(rule, other))
+		}
+	}
+	// List returns the rules ordered by metric.
+	for _, rule := range b {
+		if _, ok := rulesB[rule.Metric]; ok {
+			tf.OnlyInB = append(tf.OnlyInB, types.StringValue(rule.Metric))
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+// readCell lists the rules of cell.
+func (d *cellDiffDatasource) readCell(cell model.TargetCellTF) ([]model.AggregationRule, error) {
+	rules, err := d.cells.get(cell.URL.ValueString(), cell.APIKey.ValueString())
+	if err != nil {
+		return nil, err
+	}
+	return rules.List(), nil
+}
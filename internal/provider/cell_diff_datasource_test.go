@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// readCellDiff reads the cell_diff data source comparing the cells at urlA and
+// urlB.
+func readCellDiff(t *testing.T, urlA, urlB string) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+	d := &cellDiffDatasource{cells: newCellRules("", func(url, _ string) (*client.Client, error) {
+		return client.New(url, &client.Config{})
+	})}
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.CellDiffTF{
+		CellA: model.TargetCellTF{URL: types.StringValue(urlA), APIKey: types.StringNull()},
+		CellB: model.TargetCellTF{URL: types.StringValue(urlB), APIKey: types.StringNull()},
+	}).HasError())
+	config := tfsdk.Config{Schema: schemaResp.Schema, Raw: state.Raw}
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+func TestCellDiffDatasource(t *testing.T) {
+	a := newMockAPI(t,
+		model.AggregationRule{ID: "1", Metric: "same", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "changed", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "only_a", Drop: true},
+		model.AggregationRule{Metric: "reordered", Aggregations: []string{"sum", "count"}},
+	)
+	b := newMockAPI(t,
+		model.AggregationRule{ID: "2", Metric: "same", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "changed", DropLabels: []string{"namespace"}, Aggregations: []string{"sum"}, Priority: 1},
+		model.AggregationRule{Metric: "only_b", Drop: true},
+		// The aggregations are a set, so rules which only list them in a
+		// different order do not differ.
+		model.AggregationRule{Metric: "reordered", Aggregations: []string{"count", "sum"}},
+	)
+
+	resp := readCellDiff(t, a.server.URL, b.server.URL)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.CellDiffTF
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.Equal(t, toTypesStrings([]string{"only_a"}), tf.OnlyInA)
+	require.Equal(t, toTypesStrings([]string{"only_b"}), tf.OnlyInB)
+	require.Equal(t, []model.RuleDifferenceTF{{
+		Metric: types.StringValue("changed"),
+		Fields: toTypesStrings([]string{"drop_labels", "priority"}),
+	}}, tf.Differing)
+}
+
+func TestCellDiffDatasourceCellErrors(t *testing.T) {
+	a, b := newMockAPI(t), newMockAPI(t)
+	a.failOn(http.MethodGet, "/aggregations/rules", http.StatusUnauthorized)
+	b.failOn(http.MethodGet, "/aggregations/rules", http.StatusInternalServerError)
+
+	// Both cells are reported.
+	resp := readCellDiff(t, a.server.URL, b.server.URL)
+	require.Equal(t, []path.Path{path.Root("cell_a"), path.Root("cell_b")}, errorPaths(resp.Diagnostics))
+	require.Contains(t, resp.Diagnostics[0].Detail(), a.server.URL)
+}
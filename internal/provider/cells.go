@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"sync"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+)
+
+// cellRules gives access to the aggregation rules of the cells targeted by
+// multi-cell rules. The rules of each cell are loaded when the cell is first
+// used and then shared by all resources.
+type cellRules struct {
+	// newClient creates a client for the API at url, authenticating with
+	// apiKey or, if it is empty, with the provider's API key.
+	newClient    func(url, apiKey string) (*client.Client, error)
+	metricPrefix string
+	// audit records the changes to the rules of every cell.
+	audit *auditLog
+	// window blocks the changes to the rules of every cell outside of the
+	// provider's apply_window.
+	window *applyWindow
+
+	mu     sync.Mutex
+	byCell map[cellKey]*cellEntry
+}
+
+// cellKey identifies the rules of a cell by its URL and the API key they are
+// loaded with, as different keys may be scoped to different rules, so that a
+// resource never uses the rules, or the client, loaded with another key.
+type cellKey struct {
+	url    string
+	apiKey string
+}
+
+// cellEntry holds the rules of a cell once they are loaded. Its mutex is held
+// while they are loaded, so that different cells are loaded in parallel but
+// each only once.
+type cellEntry struct {
+	mu    sync.Mutex
+	rules *AggregationRules
+}
+
+func newCellRules(metricPrefix string, newClient func(url, apiKey string) (*client.Client, error)) *cellRules {
+	return &cellRules{
+		newClient:    newClient,
+		metricPrefix: metricPrefix,
+		byCell:       make(map[cellKey]*cellEntry),
+	}
+}
+
+// get returns the rules of the cell at url, loaded with apiKey. A cell whose
+// rules cannot be loaded is retried on the next call.
+func (c *cellRules) get(url, apiKey string) (*AggregationRules, error) {
+	key := cellKey{url: url, apiKey: apiKey}
+	c.mu.Lock()
+	entry, ok := c.byCell[key]
+	if !ok {
+		entry = &cellEntry{}
+		c.byCell[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.rules != nil {
+		return entry.rules, nil
+	}
+
+	cl, err := c.newClient(url, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	rules := NewAggregationRules(cl, c.metricPrefix)
+	rules.audit, rules.auditCell = c.audit, url
+	rules.window = c.window
+	if err := rules.Init(); err != nil {
+		return nil, err
+	}
+
+	entry.rules = rules
+	return rules, nil
+}
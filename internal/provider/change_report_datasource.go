@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type changeReportDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &changeReportDatasource{}
+	_ datasource.DataSourceWithConfigure = &changeReportDatasource{}
+)
+
+func newChangeReportDatasource() datasource.DataSource {
+	return &changeReportDatasource{}
+}
+
+func (d *changeReportDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rules = data.aggRules
+}
+
+func (d *changeReportDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_change_report", req.ProviderTypeName)
+}
+
+func (d *changeReportDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Compares a desired set of aggregation rules with the existing rules within the provider's `metric_prefix` and reports the changes which would reconcile them as JSON, for tools reviewing them such as PR bots and policy engines. Nothing is changed.\n\n" +
+			"Rules are compared by metric, ignoring metadata such as their IDs, in the same way as the drift of rules is detected. The report has a `summary` counting the creates, updates, and deletes, and a list of `changes`, ordered by action and then by metric. Each change has its `action`, `metric`, and the `fields` which change, each with its `field` name in the API and its `before` and `after` values. `before` is null for created rules and `after` for deleted ones, as is the value of a field that is unset.\n\n" +
+			"```json\n" +
+			"{\n" +
+			"  \"summary\": {\"create\": 0, \"update\": 1, \"delete\": 0},\n" +
+			"  \"changes\": [\n" +
+			"    {\n" +
+			"      \"action\": \"update\",\n" +
+			"      \"metric\": \"http_requests_total\",\n" +
+			"      \"fields\": [{\"field\": \"drop_labels\", \"before\": [\"pod\"], \"after\": [\"pod\", \"instance\"]}]\n" +
+			"    }\n" +
+			"  ]\n" +
+			"}\n" +
+			"```",
+		Attributes: map[string]schema.Attribute{
+			"rules_json": schema.StringAttribute{
+				Required:    true,
+				Description: "The desired rules, as a JSON array of rules in the format of the API or a backup exported by the rules_snapshot data source, for example from jsonencode or file.",
+			},
+			"prune": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to report every existing rule which is not desired and not managed by Terraform as deleted, as a rule set with prune would delete them. Otherwise no deletes are reported. Defaults to false.",
+			},
+			"has_changes": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether any rule would be created, updated, or deleted.",
+			},
+			"json": schema.StringAttribute{
+				Computed:    true,
+				Description: "The change report as indented JSON.",
+			},
+		},
+	}
+}
+
+func (d *changeReportDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var tf model.ChangeReportTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &tf)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	desired, diags := parseDesiredRules(d.rules, tf.RulesJSON.ValueString(), path.Root("rules_json"))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	report, err := changeReport(diffRuleSet(d.rules.List(), nil, desired, tf.Prune.ValueBool()), d.rules.List())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to build change report", err.Error())
+		return
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to encode change report", err.Error())
+		return
+	}
+
+	tf.HasChanges = types.BoolValue(len(report.Changes) > 0)
+	tf.JSON = types.StringValue(string(data))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+// parseDesiredRules parses and validates the desired rules set by the
+// attribute attr. Problems with individual rules are reported against attr.
+// The allAggregations sentinel is expanded, as for rules files, so that the
+// rules compare equal to the ones the API returns once they are applied.
+func parseDesiredRules(aggRules *AggregationRules, data string, attr path.Path) ([]model.AggregationRule, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	rules, err := model.ParseRulesFile([]byte(data))
+	if err != nil {
+		diags.AddAttributeError(attr, "Invalid "+attr.String(), fmt.Sprintf("Unable to parse the rules: %s", err))
+		return nil, diags
+	}
+
+	known := make([]*model.AggregationRule, len(rules))
+	for i := range rules {
+		known[i] = &rules[i]
+	}
+	for _, v := range validateRuleSet(known) {
+		detail := v.Detail()
+		if withPath, ok := v.(diag.DiagnosticWithPath); ok {
+			detail = fmt.Sprintf("%s: %s", withPath.Path(), detail)
+		}
+		if v.Severity() == diag.SeverityError {
+			diags.AddAttributeError(attr, v.Summary(), detail)
+		} else {
+			diags.AddAttributeWarning(attr, v.Summary(), detail)
+		}
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	for i, rule := range rules {
+		if hasAllAggregations(rule.Aggregations) {
+			supported, err := aggRules.SupportedAggregations()
+			if err != nil {
+				diags.AddAttributeError(attr, "Unable to expand aggregations", fmt.Sprintf("The rule for %q uses %q: %s", rule.Metric, allAggregations, err))
+				return nil, diags
+			}
+			rules[i].Aggregations = slices.Clone(supported)
+		}
+	}
+	return rules, diags
+}
+
+// changeReport describes changes, which reconcile the current rules with the
+// desired ones, with the fields of each rule that change.
+func changeReport(changes ruleSetChanges, current []model.AggregationRule) (model.ChangeReport, error) {
+	existing := make(map[string]model.AggregationRule, len(current))
+	for _, rule := range current {
+		existing[rule.Metric] = rule
+	}
+
+	report := model.ChangeReport{
+		Summary: model.ChangeSummary{Create: len(changes.Create), Update: len(changes.Update), Delete: len(changes.Delete)},
+		Changes: []model.RuleChange{},
+	}
+	add := func(action string, before, after model.AggregationRule) error {
+		change, err := model.NewRuleChange(action, before, after)
+		if err != nil {
+			return fmt.Errorf("failed to compare rules: %w", err)
+		}
+		report.Changes = append(report.Changes, change)
+		return nil
+	}
+
+	for _, rule := range changes.Create {
+		if err := add(model.ChangeCreate, model.AggregationRule{}, rule); err != nil {
+			return model.ChangeReport{}, err
+		}
+	}
+	for _, rule := range changes.Update {
+		if err := add(model.ChangeUpdate, existing[rule.Metric], rule); err != nil {
+			return model.ChangeReport{}, err
+		}
+	}
+	for _, rule := range changes.Delete {
+		if err := add(model.ChangeDelete, rule, model.AggregationRule{}); err != nil {
+			return model.ChangeReport{}, err
+		}
+	}
+	return report, nil
+}
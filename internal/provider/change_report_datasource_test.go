@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readChangeReport(t *testing.T, d *changeReportDatasource, rulesJSON string, prune bool) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.ChangeReportTF{
+		RulesJSON:  types.StringValue(rulesJSON),
+		Prune:      types.BoolValue(prune),
+		HasChanges: types.BoolNull(),
+		JSON:       types.StringNull(),
+	}).HasError())
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: state.Raw}}, resp)
+	return resp
+}
+
+func TestChangeReportDatasource(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum:counter"}},
+		model.AggregationRule{Metric: "b", DropLabels: []string{"pod"}},
+		model.AggregationRule{Metric: "c", Drop: true},
+	)
+	d := &changeReportDatasource{rules: api.aggregationRules()}
+
+	rulesJSON := `[
+		{"metric": "a", "drop_labels": ["pod", "instance"], "aggregations": ["sum:counter"]},
+		{"metric": "b", "drop_labels": ["pod"]},
+		{"metric": "d", "drop": true}
+	]`
+
+	for _, tc := range []struct {
+		name     string
+		prune    bool
+		expected string
+	}{
+		{
+			name: "without prune",
+			expected: `{
+				"summary": {"create": 1, "update": 1, "delete": 0},
+				"changes": [
+					{"action": "create", "metric": "d", "fields": [
+						{"field": "metric", "before": null, "after": "d"},
+						{"field": "drop", "before": null, "after": true}
+					]},
+					{"action": "update", "metric": "a", "fields": [
+						{"field": "drop_labels", "before": ["pod"], "after": ["pod", "instance"]}
+					]}
+				]
+			}`,
+		},
+		{
+			name:  "prune",
+			prune: true,
+			expected: `{
+				"summary": {"create": 1, "update": 1, "delete": 1},
+				"changes": [
+					{"action": "create", "metric": "d", "fields": [
+						{"field": "metric", "before": null, "after": "d"},
+						{"field": "drop", "before": null, "after": true}
+					]},
+					{"action": "update", "metric": "a", "fields": [
+						{"field": "drop_labels", "before": ["pod"], "after": ["pod", "instance"]}
+					]},
+					{"action": "delete", "metric": "c", "fields": [
+						{"field": "metric", "before": "c", "after": null},
+						{"field": "drop", "before": true, "after": null}
+					]}
+				]
+			}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := readChangeReport(t, d, rulesJSON, tc.prune)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+			var tf model.ChangeReportTF
+			require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+			require.True(t, tf.HasChanges.ValueBool())
+			require.JSONEq(t, tc.expected, tf.JSON.ValueString())
+		})
+	}
+
+	// Nothing is changed.
+	require.Equal(t, []string{"a", "b", "c"}, api.metrics())
+}
+
+func TestChangeReportDatasourceNoChanges(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}})
+	d := &changeReportDatasource{rules: api.aggregationRules()}
+
+	resp := readChangeReport(t, d, `[{"metric": "a", "drop_labels": ["pod"]}]`, true)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.ChangeReportTF
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.False(t, tf.HasChanges.ValueBool())
+	require.JSONEq(t, `{"summary": {"create": 0, "update": 0, "delete": 0}, "changes": []}`, tf.JSON.ValueString())
+}
+
+func TestChangeReportDatasourceInvalidRules(t *testing.T) {
+	api := newMockAPI(t)
+	d := &changeReportDatasource{rules: api.aggregationRules()}
+
+	for rulesJSON, summary := range map[string]string{
+		`[{"metric": "a", "drop_lables": ["pod"]}]`: "Invalid rules_json",
+		`[{"metric": "a"}, {"metric": "a"}]`:        "Duplicate metric",
+	} {
+		resp := readChangeReport(t, d, rulesJSON, false)
+		require.True(t, resp.Diagnostics.HasError(), rulesJSON)
+		require.Equal(t, summary, resp.Diagnostics.Errors()[0].Summary())
+		require.Equal(t, []path.Path{path.Root("rules_json")}, errorPaths(resp.Diagnostics))
+	}
+}
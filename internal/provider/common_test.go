@@ -27,6 +27,10 @@ func CheckAccTestsEnabled(t *testing.T) {
 	t.Skip("Set TF_ACC=true to enable acceptance tests.")
 }
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
 const letters = "abcdefghijklmnopqrstuvwxyz"
 
 func RandString(n int) string {
@@ -48,7 +52,7 @@ func AggregationRulesForAccTest(t *testing.T) *AggregationRules {
 	})
 	require.NoError(t, err)
 
-	aggRules := NewAggregationRules(c)
+	aggRules := NewAggregationRules(c, "")
 	require.NoError(t, aggRules.Init())
 
 	return aggRules
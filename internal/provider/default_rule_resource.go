@@ -0,0 +1,263 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type defaultRuleResource struct {
+	client *client.Client
+
+	requiredKeepLabels    []string
+	allowedMetricPatterns []string
+}
+
+var (
+	_ resource.Resource                   = &defaultRuleResource{}
+	_ resource.ResourceWithConfigure      = &defaultRuleResource{}
+	_ resource.ResourceWithImportState    = &defaultRuleResource{}
+	_ resource.ResourceWithModifyPlan     = &defaultRuleResource{}
+	_ resource.ResourceWithValidateConfig = &defaultRuleResource{}
+)
+
+func newDefaultRuleResource() resource.Resource {
+	return &defaultRuleResource{}
+}
+
+func (r *defaultRuleResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = data.client
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+}
+
+func (r *defaultRuleResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_default_rule", req.ProviderTypeName)
+}
+
+func (r *defaultRuleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manages the default aggregation rule, which applies to every metric that is not matched by any other rule. A tenant has at most one default rule, so only one instance of this resource may exist.\n\n" +
+			"~> **Warning:** With `drop = true`, every metric which is not matched by another rule is dropped before it is stored, including metrics which are first sent after the rule is applied, such as those of a newly deployed service. Their samples cannot be recovered, and dashboards and alerts based on them stop working without an error. As this affects the whole tenant, dropping requires `confirm_drop_unmatched = true`.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Always 'default'.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+
+			"drop": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultBoolFalse{},
+				Description: "Set to true to skip both ingestion and aggregation and drop every unmatched metric entirely. Requires confirm_drop_unmatched.",
+			},
+			"confirm_drop_unmatched": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Must be set to true along with drop, to confirm that every metric which is not matched by another aggregation rule is to be dropped.",
+			},
+			"keep_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of labels to keep; labels not in this array will be aggregated.",
+			},
+			"drop_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of labels that will be aggregated.",
+			},
+
+			"aggregations": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of aggregation types to calculate for the metrics.",
+			},
+
+			"aggregation_interval": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "The interval at which to generate the aggregated series.",
+			},
+			"aggregation_delay": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "The delay until aggregation is performed.",
+			},
+		},
+	}
+}
+
+func (r *defaultRuleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config model.DefaultRuleTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if config.Drop.ValueBool() && !config.ConfirmDropUnmatched.ValueBool() && !config.ConfirmDropUnmatched.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("drop"),
+			"Dropping unmatched metrics is not confirmed",
+			"With drop = true, the default rule drops every metric which is not matched by another aggregation rule, including metrics which are first sent later. Their samples cannot be recovered. Set confirm_drop_unmatched = true to drop them anyway.",
+		)
+	}
+}
+
+func (r *defaultRuleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan model.DefaultRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The default rule is checked as a rule without a metric.
+	rule := plan.ToAPIReq()
+	asRule := model.AggregationRule{Drop: rule.Drop, KeepLabels: rule.KeepLabels, DropLabels: rule.DropLabels}
+	resp.Diagnostics.Append(validateRequiredKeepLabels(asRule, r.requiredKeepLabels, path.Empty())...)
+	resp.Diagnostics.Append(validateAllowedMetric(asRule, r.allowedMetricPatterns, path.Empty())...)
+	if resp.Diagnostics.HasError() || !plan.Drop.ValueBool() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state model.DefaultRuleTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() || state.Drop.ValueBool() {
+			return
+		}
+	}
+
+	resp.Diagnostics.AddAttributeWarning(
+		path.Root("drop"),
+		"Unmatched metrics will be dropped",
+		"Once applied, the default rule drops every metric which is not matched by another aggregation rule. Check that every metric which should be kept has a rule.",
+	)
+}
+
+func (r *defaultRuleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.DefaultRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var notFound client.ErrNotFound
+	_, err := r.client.DefaultRule()
+	switch {
+	case err == nil:
+		resp.Diagnostics.AddError(
+			"Default aggregation rule already exists",
+			fmt.Sprintf("Only one default rule can be managed. Import the existing default rule with the ID %q instead.", model.DefaultRuleID),
+		)
+		return
+	case !errors.As(err, &notFound):
+		resp.Diagnostics.AddError("Unable to check for an existing default aggregation rule", err.Error())
+		return
+	}
+
+	if err := r.client.UpdateDefaultRule(plan.ToAPIReq()); err != nil {
+		resp.Diagnostics.AddError("Unable to create default aggregation rule", err.Error())
+		return
+	}
+
+	plan.ID = types.StringValue(model.DefaultRuleID)
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *defaultRuleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state model.DefaultRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rule, err := r.client.DefaultRule()
+	if err != nil {
+		var notFound client.ErrNotFound
+		if errors.As(err, &notFound) {
+			resp.Diagnostics.AddWarning("Unable to read default aggregation rule", err.Error())
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Unable to read default aggregation rule", err.Error())
+		return
+	}
+
+	tf := rule.ToTF()
+	tf.ConfirmDropUnmatched = state.ConfirmDropUnmatched
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+func (r *defaultRuleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.DefaultRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.UpdateDefaultRule(plan.ToAPIReq()); err != nil {
+		resp.Diagnostics.AddError("Unable to update default aggregation rule", err.Error())
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *defaultRuleResource) Delete(_ context.Context, _ resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if err := r.client.DeleteDefaultRule(); err != nil {
+		resp.Diagnostics.AddError("Unable to delete default aggregation rule", err.Error())
+	}
+}
+
+func (r *defaultRuleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if req.ID != model.DefaultRuleID {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("The default aggregation rule must be imported with the ID %q, got %q.", model.DefaultRuleID, req.ID),
+		)
+		return
+	}
+
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
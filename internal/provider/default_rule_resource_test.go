@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestAccDefaultRuleResource(t *testing.T) {
+	CheckAccTestsEnabled(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create + Read.
+			{
+				Config: providerConfig + `
+resource "grafana-adaptive-metrics_default_rule" "test" {
+	drop_labels = ["pod"]
+	aggregations = ["sum"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "id", "default"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "drop", "false"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "drop_labels.#", "1"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "drop_labels.0", "pod"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "aggregations.#", "1"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "aggregations.0", "sum"),
+				),
+			},
+			// ImportState.
+			{
+				ResourceName:      "grafana-adaptive-metrics_default_rule.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateId:     "default",
+			},
+			// A second default rule cannot be created.
+			{
+				Config: providerConfig + `
+resource "grafana-adaptive-metrics_default_rule" "test" {
+	drop_labels = ["pod"]
+	aggregations = ["sum"]
+}
+
+resource "grafana-adaptive-metrics_default_rule" "other" {
+	drop                   = true
+	confirm_drop_unmatched = true
+}
+`,
+				ExpectError: regexp.MustCompile("Default aggregation rule already exists"),
+			},
+			// Update + Read.
+			{
+				Config: providerConfig + `
+resource "grafana-adaptive-metrics_default_rule" "test" {
+	drop_labels = ["pod", "instance"]
+	aggregations = ["sum", "count"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "drop_labels.#", "2"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_default_rule.test", "aggregations.#", "2"),
+				),
+			},
+			// Delete happens automatically.
+		},
+	})
+}
+
+func TestDefaultRuleResourceDropRequiresConfirmation(t *testing.T) {
+	ctx := context.Background()
+	r := &defaultRuleResource{}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for _, tc := range []struct {
+		name        string
+		drop        bool
+		confirm     types.Bool
+		expectError bool
+	}{
+		{name: "aggregate", confirm: types.BoolNull()},
+		{name: "drop", drop: true, confirm: types.BoolNull(), expectError: true},
+		{name: "drop not confirmed", drop: true, confirm: types.BoolValue(false), expectError: true},
+		{name: "drop confirmed", drop: true, confirm: types.BoolValue(true)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tf := model.DefaultRule{Drop: tc.drop}.ToTF()
+			tf.ConfirmDropUnmatched = tc.confirm
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, tf).HasError())
+
+			resp := &fwresource.ValidateConfigResponse{}
+			r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: state.Raw}}, resp)
+			require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), resp.Diagnostics)
+		})
+	}
+}
+
+func TestDefaultRuleResourceModifyPlanWarnsAboutDrop(t *testing.T) {
+	aggregating := model.DefaultRule{DropLabels: []string{"pod"}}.ToTF()
+	dropping := model.DefaultRule{Drop: true}.ToTF()
+	dropping.ConfirmDropUnmatched = types.BoolValue(true)
+
+	for _, tc := range []struct {
+		name        string
+		prior       any
+		planned     model.DefaultRuleTF
+		expectWarns bool
+	}{
+		{name: "create aggregating", planned: aggregating},
+		{name: "create dropping", planned: dropping, expectWarns: true},
+		{name: "start dropping", prior: aggregating, planned: dropping, expectWarns: true},
+		{name: "keep dropping", prior: dropping, planned: dropping},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := modifyPlan(t, &defaultRuleResource{}, tc.prior, tc.planned)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			require.Equal(t, tc.expectWarns, resp.Diagnostics.WarningsCount() > 0, resp.Diagnostics)
+		})
+	}
+}
+
+func TestDefaultRuleResourceModifyPlanPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		resource    defaultRuleResource
+		planned     model.DefaultRule
+		expectError bool
+	}{
+		{name: "keeps required", resource: defaultRuleResource{requiredKeepLabels: []string{"cluster"}}, planned: model.DefaultRule{KeepLabels: []string{"cluster"}}},
+		{name: "drops required", resource: defaultRuleResource{requiredKeepLabels: []string{"cluster"}}, planned: model.DefaultRule{DropLabels: []string{"cluster"}}, expectError: true},
+		{name: "allowed", resource: defaultRuleResource{allowedMetricPatterns: []string{"team_a_.*", ".*"}}, planned: model.DefaultRule{DropLabels: []string{"pod"}}},
+		{name: "not allowed", resource: defaultRuleResource{allowedMetricPatterns: []string{"team_a_.*"}}, planned: model.DefaultRule{DropLabels: []string{"pod"}}, expectError: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := modifyPlan(t, &tc.resource, nil, tc.planned.ToTF())
+			require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), resp.Diagnostics)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promDurationUnits are the units of Prometheus durations, from the largest
+// to the smallest. A year is always 365 days.
+var promDurationUnits = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"y", 365 * 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+}
+
+var promDurationRE = regexp.MustCompile(`^(?:([0-9]+)y)?(?:([0-9]+)w)?(?:([0-9]+)d)?(?:([0-9]+)h)?(?:([0-9]+)m)?(?:([0-9]+)s)?(?:([0-9]+)ms)?$`)
+
+// parsePromDuration parses a duration in the format used by Prometheus and
+// the API, such as "1m30s" or "1d": integers followed by a unit, each unit
+// at most once and from the largest to the smallest.
+func parsePromDuration(s string) (time.Duration, error) {
+	if s == "0" {
+		return 0, nil
+	}
+
+	matches := promDurationRE.FindStringSubmatch(s)
+	if s == "" || matches == nil {
+		return 0, fmt.Errorf("invalid duration %q, expected a duration such as 1m30s", s)
+	}
+
+	var d time.Duration
+	for i, unit := range promDurationUnits {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(matches[i+1], 10, 64)
+		if err != nil || n > int64((1<<63-1)/unit.duration) {
+			return 0, fmt.Errorf("duration %q is too long", s)
+		}
+		d += time.Duration(n) * unit.duration
+		if d < 0 {
+			return 0, fmt.Errorf("duration %q is too long", s)
+		}
+	}
+
+	return d, nil
+}
+
+// formatPromDuration formats d in the format parsed by parsePromDuration,
+// using hours as the largest unit, such as "24h" rather than "1d", as the
+// API does not accept days, weeks, or years in aggregation rules. Durations
+// which are negative or not a whole number of milliseconds cannot be
+// represented.
+func formatPromDuration(d time.Duration) (string, error) {
+	switch {
+	case d < 0:
+		return "", errors.New("duration must not be negative")
+	case d%time.Millisecond != 0:
+		return "", errors.New("duration must be a whole number of milliseconds")
+	case d == 0:
+		return "0s", nil
+	}
+
+	var sb strings.Builder
+	for _, unit := range promDurationUnits {
+		if unit.duration > time.Hour {
+			continue
+		}
+		if n := d / unit.duration; n > 0 {
+			sb.WriteString(strconv.FormatInt(int64(n), 10))
+			sb.WriteString(unit.name)
+			d -= n * unit.duration
+		}
+	}
+
+	return sb.String(), nil
+}
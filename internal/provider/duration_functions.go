@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+type parseDurationFunction struct{}
+
+var _ function.Function = &parseDurationFunction{}
+
+func newParseDurationFunction() function.Function {
+	return &parseDurationFunction{}
+}
+
+func (f *parseDurationFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_duration"
+}
+
+func (f *parseDurationFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Parses a Prometheus duration into seconds",
+		MarkdownDescription: "Returns the number of seconds of a duration in the format used by Prometheus and aggregation rules, such as `1m30s`, `1d` or `500ms`. A year is 365 days.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "duration",
+				Description: "The duration to parse.",
+			},
+		},
+		Return: function.NumberReturn{},
+	}
+}
+
+func (f *parseDurationFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var s string
+	resp.Error = req.Arguments.Get(ctx, &s)
+	if resp.Error != nil {
+		return
+	}
+
+	d, err := parsePromDuration(s)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, big.NewFloat(d.Seconds()))
+}
+
+type formatDurationFunction struct{}
+
+var _ function.Function = &formatDurationFunction{}
+
+func newFormatDurationFunction() function.Function {
+	return &formatDurationFunction{}
+}
+
+func (f *formatDurationFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "format_duration"
+}
+
+func (f *formatDurationFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Formats seconds as a Prometheus duration",
+		MarkdownDescription: "Returns a number of seconds as a duration in the format used by Prometheus and aggregation rules, such as `1m30s`, so that intervals computed in HCL can be used in rules. Hours are the largest unit, as rules do not accept days, weeks, or years: a day is `24h`. The number must not be negative and must be a whole number of milliseconds.",
+		Parameters: []function.Parameter{
+			function.NumberParameter{
+				Name:        "seconds",
+				Description: "The number of seconds to format.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *formatDurationFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var seconds *big.Float
+	resp.Error = req.Arguments.Get(ctx, &seconds)
+	if resp.Error != nil {
+		return
+	}
+
+	ms := new(big.Float).Mul(seconds, big.NewFloat(1000))
+	if !ms.IsInt() {
+		resp.Error = function.NewArgumentFuncError(0, "duration must be a whole number of milliseconds")
+		return
+	}
+	n, acc := ms.Int64()
+	if acc != big.Exact || n > int64((1<<63-1)/time.Millisecond) {
+		resp.Error = function.NewArgumentFuncError(0, "duration is too long")
+		return
+	}
+
+	s, err := formatPromDuration(time.Duration(n) * time.Millisecond)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, s)
+}
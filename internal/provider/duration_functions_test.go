@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestParsePromDuration(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected time.Duration
+		invalid  bool
+	}{
+		{in: "0s", expected: 0},
+		{in: "0", expected: 0},
+		{in: "90s", expected: 90 * time.Second},
+		{in: "1m30s", expected: 90 * time.Second},
+		{in: "1500ms", expected: 1500 * time.Millisecond},
+		{in: "1d12h", expected: 36 * time.Hour},
+		{in: "1y1w", expected: 372 * 24 * time.Hour},
+		{in: "", invalid: true},
+		{in: "1.5m", invalid: true},
+		{in: "30s1m", invalid: true},
+		{in: "-1m", invalid: true},
+		{in: "1", invalid: true},
+		{in: "300000y", invalid: true},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			d, err := parsePromDuration(tc.in)
+			if tc.invalid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, d)
+		})
+	}
+}
+
+func TestFormatPromDuration(t *testing.T) {
+	for _, tc := range []struct {
+		in       time.Duration
+		expected string
+	}{
+		{in: 0, expected: "0s"},
+		{in: 90 * time.Second, expected: "1m30s"},
+		{in: 500 * time.Millisecond, expected: "500ms"},
+		{in: 24 * time.Hour, expected: "24h"},
+		{in: 372 * 24 * time.Hour, expected: "8928h"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			s, err := formatPromDuration(tc.in)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, s)
+
+			// Formatted durations parse back to the same duration.
+			d, err := parsePromDuration(s)
+			require.NoError(t, err)
+			require.Equal(t, tc.in, d)
+
+			// They are accepted by the rules, such as for their interval.
+			rule := model.AggregationRule{Metric: "up", DropLabels: []string{"pod"}, AggregationInterval: s, AggregationDelay: s}
+			require.Empty(t, validateRuleSchema(rule, path.Empty()))
+			require.False(t, validateRule(rule, path.Empty()).HasError())
+		})
+	}
+
+	_, err := formatPromDuration(-time.Second)
+	require.Error(t, err)
+	_, err = formatPromDuration(time.Microsecond)
+	require.Error(t, err)
+}
+
+func runFunction(f function.Function, ret attr.Value, args ...attr.Value) (attr.Value, *function.FuncError) {
+	req := function.RunRequest{Arguments: function.NewArgumentsData(args)}
+	resp := function.RunResponse{Result: function.NewResultData(ret)}
+	f.Run(context.Background(), req, &resp)
+	return resp.Result.Value(), resp.Error
+}
+
+func TestParseDurationFunction(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected *big.Float
+		err      *function.FuncError
+	}{
+		{in: "0s", expected: big.NewFloat(0)},
+		{in: "1m30s", expected: big.NewFloat(90)},
+		{in: "1500ms", expected: big.NewFloat(1.5)},
+		{in: "1.5m", err: function.NewArgumentFuncError(0, `invalid duration "1.5m", expected a duration such as 1m30s`)},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			result, funcErr := runFunction(newParseDurationFunction(), types.NumberUnknown(), types.StringValue(tc.in))
+			require.Equal(t, tc.err, funcErr)
+			if tc.err == nil {
+				require.Zero(t, tc.expected.Cmp(result.(types.Number).ValueBigFloat()))
+			}
+		})
+	}
+}
+
+func TestFormatDurationFunction(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		in       *big.Float
+		expected string
+		err      *function.FuncError
+	}{
+		{name: "zero", in: big.NewFloat(0), expected: "0s"},
+		{name: "fractional minutes", in: big.NewFloat(1.5 * 60), expected: "1m30s"},
+		{name: "fractional seconds", in: big.NewFloat(2.25), expected: "2s250ms"},
+		{name: "hours", in: big.NewFloat(7200), expected: "2h"},
+		{name: "sub-millisecond", in: big.NewFloat(0.0005), err: function.NewArgumentFuncError(0, "duration must be a whole number of milliseconds")},
+		{name: "negative", in: big.NewFloat(-60), err: function.NewArgumentFuncError(0, "duration must not be negative")},
+		{name: "too long", in: big.NewFloat(1e18), err: function.NewArgumentFuncError(0, "duration is too long")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, funcErr := runFunction(newFormatDurationFunction(), types.StringUnknown(), types.NumberValue(tc.in))
+			require.Equal(t, tc.err, funcErr)
+			if tc.err == nil {
+				require.Equal(t, types.StringValue(tc.expected), result)
+			}
+		})
+	}
+}
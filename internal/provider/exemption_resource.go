@@ -8,7 +8,10 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
@@ -16,7 +19,8 @@ import (
 )
 
 type exemptionResource struct {
-	client *client.Client
+	client       *client.Client
+	capabilities *capabilities
 }
 
 var (
@@ -44,6 +48,7 @@ func (e *exemptionResource) Configure(_ context.Context, req resource.ConfigureR
 	}
 
 	e.client = data.client
+	e.capabilities = data.capabilities
 }
 
 func (e *exemptionResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -56,6 +61,9 @@ func (e *exemptionResource) Schema(_ context.Context, _ resource.SchemaRequest,
 			"id": schema.StringAttribute{
 				Computed:    true,
 				Description: "A UILD that uniquely identifies the exemption.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
 			"metric": schema.StringAttribute{
 				Required:    true,
@@ -83,6 +91,9 @@ func (e *exemptionResource) Schema(_ context.Context, _ resource.SchemaRequest,
 			"created_at": schema.Int64Attribute{
 				Computed:    true,
 				Description: "Unix timestamp of when this exemption was created.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
 			},
 			"updated_at": schema.Int64Attribute{
 				Computed:    true,
@@ -93,6 +104,11 @@ func (e *exemptionResource) Schema(_ context.Context, _ resource.SchemaRequest,
 }
 
 func (e *exemptionResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var plan model.ExemptionTF
 	diags := req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
@@ -113,6 +129,11 @@ func (e *exemptionResource) Create(ctx context.Context, req resource.CreateReque
 }
 
 func (e *exemptionResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var state model.ExemptionTF
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
@@ -130,6 +151,11 @@ func (e *exemptionResource) Read(ctx context.Context, req resource.ReadRequest,
 }
 
 func (e *exemptionResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var plan model.ExemptionTF
 	diags := req.Plan.Get(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
@@ -3,7 +3,9 @@ package provider
 import (
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAccExemptionResource(t *testing.T) {
@@ -70,3 +72,35 @@ resource "grafana-adaptive-metrics_exemption" "test" {
 		},
 	})
 }
+
+func TestExemptionResourcePlanKeepsComputedValues(t *testing.T) {
+	stringList := tftypes.List{ElementType: tftypes.String}
+	prior := map[string]tftypes.Value{
+		"id":                      tftypes.NewValue(tftypes.String, "01HZ5N2V3X6Y8Z0A1B2C3D4E5F"),
+		"metric":                  tftypes.NewValue(tftypes.String, "test_tf_metric"),
+		"keep_labels":             tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "namespace")}),
+		"disable_recommendations": tftypes.NewValue(tftypes.Bool, false),
+		"reason":                  tftypes.NewValue(tftypes.String, ""),
+		"created_at":              tftypes.NewValue(tftypes.Number, 1700000000000),
+		"updated_at":              tftypes.NewValue(tftypes.Number, 1700000000000),
+	}
+	config := map[string]tftypes.Value{
+		"id":                      tftypes.NewValue(tftypes.String, nil),
+		"metric":                  tftypes.NewValue(tftypes.String, "test_tf_metric"),
+		"keep_labels":             tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "namespace")}),
+		"disable_recommendations": tftypes.NewValue(tftypes.Bool, nil),
+		"reason":                  tftypes.NewValue(tftypes.String, nil),
+		"created_at":              tftypes.NewValue(tftypes.Number, nil),
+		"updated_at":              tftypes.NewValue(tftypes.Number, nil),
+	}
+
+	// A no-op plan keeps every value.
+	require.Equal(t, prior, planResourceChange(t, "grafana-adaptive-metrics_exemption", prior, config))
+
+	// An update only leaves the values unknown which are changed by the API.
+	config["reason"] = tftypes.NewValue(tftypes.String, "testing")
+	planned := planResourceChange(t, "grafana-adaptive-metrics_exemption", prior, config)
+	require.Equal(t, prior["id"], planned["id"])
+	require.Equal(t, prior["created_at"], planned["created_at"])
+	require.False(t, planned["updated_at"].IsKnown())
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type exemptionsDatasource struct {
+	client       *client.Client
+	capabilities *capabilities
+}
+
+var (
+	_ datasource.DataSource              = &exemptionsDatasource{}
+	_ datasource.DataSourceWithConfigure = &exemptionsDatasource{}
+)
+
+func newExemptionsDatasource() datasource.DataSource {
+	return &exemptionsDatasource{}
+}
+
+func (d *exemptionsDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = data.client
+	d.capabilities = data.capabilities
+}
+
+func (d *exemptionsDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_exemptions", req.ProviderTypeName)
+}
+
+func (d *exemptionsDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the existing exemptions, ordered by metric.",
+		Attributes: map[string]schema.Attribute{
+			"exemptions": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The existing exemptions.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "A ULID that uniquely identifies the exemption.",
+						},
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the exempt metric.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"disable_recommendations": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the recommendations service exempts this metric from consideration.",
+						},
+						"reason": schema.StringAttribute{
+							Computed:    true,
+							Description: "The reason(s) for this exemption.",
+						},
+						"created_at": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Unix timestamp of when this exemption was created.",
+						},
+						"updated_at": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Unix timestamp of when this exemption was last updated.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *exemptionsDatasource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	resp.Diagnostics.Append(d.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	exemptions, err := d.client.ListExemptions()
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to list exemptions", err.Error())
+		return
+	}
+	sort.Slice(exemptions, func(i, j int) bool { return exemptions[i].Metric < exemptions[j].Metric })
+
+	state := model.ExemptionsDataTF{Exemptions: make([]model.ExemptionTF, len(exemptions))}
+	for i, ex := range exemptions {
+		state.Exemptions[i] = ex.ToTF()
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestExemptionsDatasource(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.exempt("kube_pod_info", "")
+	api.exempt("http_requests_total", "dashboards")
+	d := &exemptionsDatasource{client: api.client()}
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}
+
+	resp := &datasource.ReadResponse{State: state}
+	d.Read(ctx, datasource.ReadRequest{}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.ExemptionsDataTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Len(t, tf.Exemptions, 2)
+	require.Equal(t, "http_requests_total", tf.Exemptions[0].Metric.ValueString())
+	require.Equal(t, "dashboards", tf.Exemptions[0].Reason.ValueString())
+	require.Equal(t, "kube_pod_info", tf.Exemptions[1].Metric.ValueString())
+	require.NotEmpty(t, tf.Exemptions[1].ID.ValueString())
+}
@@ -0,0 +1,318 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// importAllExemptions is the import ID which imports every existing exemption.
+const importAllExemptions = "*"
+
+type exemptionsResource struct {
+	client          *client.Client
+	capabilities    *capabilities
+	continueOnError bool
+}
+
+var (
+	_ resource.Resource                   = &exemptionsResource{}
+	_ resource.ResourceWithConfigure      = &exemptionsResource{}
+	_ resource.ResourceWithImportState    = &exemptionsResource{}
+	_ resource.ResourceWithValidateConfig = &exemptionsResource{}
+)
+
+func newExemptionsResource() resource.Resource {
+	return &exemptionsResource{}
+}
+
+func (e *exemptionsResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	e.client = data.client
+	e.capabilities = data.capabilities
+	e.continueOnError = data.continueOnError
+}
+
+func (e *exemptionsResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_exemptions", req.ProviderTypeName)
+}
+
+func (e *exemptionsResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages a list of exempt metrics as a single resource. Exemptions are created and deleted so that exactly the listed metrics are exempted by this resource; exemptions of other metrics are left untouched. Destroying the resource deletes every exemption it manages. Existing exemptions may be imported by a comma-separated list of their metrics, or by \"*\" to import all of them.",
+		Attributes: map[string]schema.Attribute{
+			"metrics": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "The names of the metrics to exempt. Each metric may only be listed once.",
+			},
+			"reason": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "An optional string detailing the reason(s) for these exemptions. It is set on every exemption managed by this resource.",
+			},
+		},
+	}
+}
+
+func (e *exemptionsResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var metrics types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("metrics"), &metrics)...)
+	if resp.Diagnostics.HasError() || metrics.IsNull() || metrics.IsUnknown() {
+		return
+	}
+
+	elems := make([]types.String, 0, len(metrics.Elements()))
+	resp.Diagnostics.Append(metrics.ElementsAs(ctx, &elems, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(validateMetricList(elems, path.Root("metrics"))...)
+}
+
+func (e *exemptionsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan model.ExemptionsTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := e.reconcile(nil, plan); err != nil {
+		resp.Diagnostics.AddError("Unable to create exemptions", err.Error())
+
+		// Keep track of the exemptions which were created before the failure.
+		if state, err := e.currentState(plan.MetricNames(), plan); err == nil && len(state.Metrics) > 0 {
+			resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+		}
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (e *exemptionsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.ExemptionsTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tf, err := e.currentState(state.MetricNames(), state)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read exemptions", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+func (e *exemptionsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan model.ExemptionsTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.ExemptionsTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := e.reconcile(state.MetricNames(), plan); err != nil {
+		resp.Diagnostics.AddError("Unable to update exemptions", err.Error())
+
+		// Exemptions which could not be deleted yet are kept in state so
+		// that the deletion is retried on the next apply.
+		if tf, err := e.currentState(append(plan.MetricNames(), state.MetricNames()...), plan); err == nil {
+			resp.Diagnostics.Append(resp.State.Set(ctx, tf)...)
+		}
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (e *exemptionsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.ExemptionsTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := e.reconcile(state.MetricNames(), model.ExemptionsTF{}); err != nil {
+		resp.Diagnostics.AddError("Unable to delete exemptions", err.Error())
+	}
+}
+
+func (e *exemptionsResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resp.Diagnostics.Append(e.capabilities.require(client.FeatureExemptions)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	existing, err := e.existing()
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to list exemptions", err.Error())
+		return
+	}
+
+	var metrics []string
+	if req.ID == importAllExemptions {
+		for metric := range existing {
+			metrics = append(metrics, metric)
+		}
+		sort.Strings(metrics)
+	} else {
+		for _, metric := range strings.Split(req.ID, ",") {
+			metric = strings.TrimSpace(metric)
+			if _, ok := existing[metric]; !ok {
+				resp.Diagnostics.AddError("Exemption not found", fmt.Sprintf("There is no exemption for the metric %q.", metric))
+				return
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+
+	tf, err := e.currentState(metrics, model.ExemptionsTF{})
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read exemptions", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, tf)...)
+}
+
+// reconcile creates, updates, and deletes exemptions so that the metrics of
+// desired are exempted with its reason, and the metrics of managed which are no
+// longer desired are not. Existing exemptions of desired metrics are adopted.
+func (e *exemptionsResource) reconcile(managed []string, desired model.ExemptionsTF) error {
+	existing, err := e.existing()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	wanted := make(map[string]bool, len(desired.Metrics))
+	for _, metric := range desired.MetricNames() {
+		wanted[metric] = true
+
+		ex, ok := existing[metric]
+		if ok && ex.Reason == desired.Reason.ValueString() {
+			continue
+		}
+
+		if ok {
+			tf := ex.ToTF()
+			tf.Reason = desired.Reason
+			err = e.client.UpdateExemption(tf.ToAPIReq())
+		} else {
+			_, err = e.client.CreateExemption(model.ExemptionTF{Metric: types.StringValue(metric), Reason: desired.Reason}.ToAPIReq())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to exempt %s: %w", metric, err))
+			if !e.continueOnError {
+				return errs[0]
+			}
+		}
+	}
+
+	for _, metric := range managed {
+		ex, ok := existing[metric]
+		if !ok || wanted[metric] {
+			continue
+		}
+		if err := e.client.DeleteExemption(ex.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete exemption for %s: %w", metric, err))
+			if !e.continueOnError {
+				return errs[0]
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// currentState builds the state from the existing exemptions of the given
+// metrics, in order. Metrics which are no longer exempted are left out, so
+// that they are exempted again on the next apply, and the reason of settings
+// is replaced by the first reason which differs from it.
+func (e *exemptionsResource) currentState(metrics []string, settings model.ExemptionsTF) (model.ExemptionsTF, error) {
+	existing, err := e.existing()
+	if err != nil {
+		return model.ExemptionsTF{}, err
+	}
+
+	tf := model.ExemptionsTF{Metrics: []types.String{}, Reason: settings.Reason}
+	if tf.Reason.IsNull() || tf.Reason.IsUnknown() {
+		tf.Reason = types.StringValue("")
+	}
+
+	seen := make(map[string]bool, len(metrics))
+	reasonDrifted := false
+	for _, metric := range metrics {
+		ex, ok := existing[metric]
+		if !ok || seen[metric] {
+			continue
+		}
+		seen[metric] = true
+		tf.Metrics = append(tf.Metrics, types.StringValue(metric))
+
+		if !reasonDrifted && ex.Reason != tf.Reason.ValueString() {
+			tf.Reason = types.StringValue(ex.Reason)
+			reasonDrifted = true
+		}
+	}
+	return tf, nil
+}
+
+// existing returns the existing exemptions by metric.
+func (e *exemptionsResource) existing() (map[string]model.Exemption, error) {
+	exemptions, err := e.client.ListExemptions()
+	if err != nil {
+		return nil, err
+	}
+
+	byMetric := make(map[string]model.Exemption, len(exemptions))
+	for _, ex := range exemptions {
+		byMetric[ex.Metric] = ex
+	}
+	return byMetric, nil
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func newTestExemptionsResource(t *testing.T, api *mockAPI) (*exemptionsResource, tfsdk.State) {
+	t.Helper()
+
+	r := &exemptionsResource{client: api.client()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	return r, tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+func exemptionsPlan(t *testing.T, schema tfsdk.State, reason string, metrics ...string) tfsdk.Plan {
+	t.Helper()
+
+	plan := tfsdk.Plan{Schema: schema.Schema}
+	require.False(t, plan.Set(context.Background(), model.ExemptionsTF{
+		Metrics: toTypesStrings(metrics),
+		Reason:  types.StringValue(reason),
+	}).HasError())
+	return plan
+}
+
+func toTypesStrings(in []string) []types.String {
+	out := make([]types.String, len(in))
+	for i, s := range in {
+		out[i] = types.StringValue(s)
+	}
+	return out
+}
+
+func TestExemptionsResourceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.exempt("unmanaged", "")
+	api.exempt("b", "")
+	r, empty := newTestExemptionsResource(t, api)
+
+	// Create adopts the existing exemption of b.
+	plan := exemptionsPlan(t, empty, "", "a", "b")
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	require.Equal(t, []string{"a", "b", "unmanaged"}, api.exemptMetrics())
+
+	// Read drops the metrics which are no longer exempted, so that they are
+	// exempted again.
+	ex, _ := api.exemption("a")
+	require.NoError(t, api.client().DeleteExemption(ex.ID))
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+
+	var read model.ExemptionsTF
+	require.False(t, readResp.State.Get(ctx, &read).HasError())
+	require.Equal(t, []string{"b"}, read.MetricNames())
+
+	// Update adds, removes, and changes the reason of exemptions.
+	plan = exemptionsPlan(t, empty, "too expensive", "a", "c")
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	require.Equal(t, []string{"a", "c", "unmanaged"}, api.exemptMetrics())
+	ex, _ = api.exemption("a")
+	require.Equal(t, "too expensive", ex.Reason)
+
+	plan = exemptionsPlan(t, empty, "no longer needed", "a", "c")
+	updateResp = &fwresource.UpdateResponse{State: updateResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: updateResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	ex, _ = api.exemption("c")
+	require.Equal(t, "no longer needed", ex.Reason)
+
+	// Delete leaves the unmanaged exemption untouched.
+	deleteResp := &fwresource.DeleteResponse{}
+	r.Delete(ctx, fwresource.DeleteRequest{State: updateResp.State}, deleteResp)
+	require.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+	require.Equal(t, []string{"unmanaged"}, api.exemptMetrics())
+}
+
+func TestExemptionsResourceErrorStrategy(t *testing.T) {
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		continueOnError bool
+		expected        []string
+	}{
+		"fail fast": {expected: []string{"a", "b", "c", "d"}},
+		"continue":  {continueOnError: true, expected: []string{"a", "b"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := newMockAPI(t)
+			api.exempt("b", "")
+			api.exempt("c", "")
+			api.exempt("d", "")
+			r, empty := newTestExemptionsResource(t, api)
+			r.continueOnError = tc.continueOnError
+
+			ex, _ := api.exemption("b")
+			api.failOn("DELETE", mockExemptionsPath+"/"+ex.ID, http.StatusInternalServerError)
+
+			state := tfsdk.State{Schema: empty.Schema}
+			require.False(t, state.Set(ctx, model.ExemptionsTF{Metrics: toTypesStrings([]string{"b", "c", "d"}), Reason: types.StringValue("")}).HasError())
+
+			resp := &fwresource.UpdateResponse{State: state}
+			r.Update(ctx, fwresource.UpdateRequest{Plan: exemptionsPlan(t, empty, "", "a"), State: state}, resp)
+			require.True(t, resp.Diagnostics.HasError())
+			require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "failed to delete exemption for b")
+			require.Equal(t, tc.expected, api.exemptMetrics())
+
+			// The exemptions which could not be deleted yet are kept in state.
+			var tf model.ExemptionsTF
+			require.False(t, resp.State.Get(ctx, &tf).HasError())
+			require.Equal(t, tc.expected, tf.MetricNames())
+		})
+	}
+}
+
+func TestExemptionsResourceImport(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.exempt("b", "legacy")
+	api.exempt("a", "legacy")
+	r, empty := newTestExemptionsResource(t, api)
+
+	for id, expected := range map[string][]string{
+		"*":    {"a", "b"},
+		"b, a": {"b", "a"},
+	} {
+		resp := &fwresource.ImportStateResponse{State: empty}
+		r.ImportState(ctx, fwresource.ImportStateRequest{ID: id}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		var tf model.ExemptionsTF
+		require.False(t, resp.State.Get(ctx, &tf).HasError())
+		require.Equal(t, expected, tf.MetricNames())
+		require.Equal(t, "legacy", tf.Reason.ValueString())
+	}
+
+	resp := &fwresource.ImportStateResponse{State: empty}
+	r.ImportState(ctx, fwresource.ImportStateRequest{ID: "a,missing"}, resp)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Exemption not found", resp.Diagnostics.Errors()[0].Summary())
+}
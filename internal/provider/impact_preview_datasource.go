@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type impactPreviewDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &impactPreviewDatasource{}
+	_ datasource.DataSourceWithConfigure = &impactPreviewDatasource{}
+)
+
+func newImpactPreviewDatasource() datasource.DataSource {
+	return &impactPreviewDatasource{}
+}
+
+func (d *impactPreviewDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rules = data.aggRules
+}
+
+func (d *impactPreviewDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_impact_preview", req.ProviderTypeName)
+}
+
+func (d *impactPreviewDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Previews the effect of a proposed aggregation rule on the number of series of its metric, without saving it. The series the metric has now, after the existing rule for it if there is one, are compared with the series estimated once the proposed rule replaces it. Backends which cannot estimate rules leave the counts null with a warning rather than failing.",
+		Attributes: map[string]schema.Attribute{
+			"metric": schema.StringAttribute{
+				Required:    true,
+				Description: "The name of the metric to be aggregated.",
+			},
+			"match_type": schema.StringAttribute{
+				Optional:    true,
+				Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
+			},
+
+			"drop": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to skip both ingestion and aggregation and drop the metric entirely.",
+			},
+			"keep_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The array of labels to keep; labels not in this array will be aggregated.",
+			},
+			"drop_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The array of labels that will be aggregated.",
+			},
+
+			"aggregations": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The array of aggregation types to calculate for this metric. Set to [\"*\"] to calculate every aggregation type supported by the API.",
+			},
+
+			"aggregation_interval": schema.StringAttribute{
+				Optional:    true,
+				Description: "The interval at which to generate the aggregated series.",
+			},
+			"aggregation_delay": schema.StringAttribute{
+				Optional:    true,
+				Description: "The delay until aggregation is performed.",
+			},
+
+			"estimate_available": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the backend could estimate the rule. The series counts are null otherwise.",
+			},
+			"existing_rule": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether a rule for the metric exists and is applied, so that series_before is the number of series after it.",
+			},
+			"matched_series": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of series matched by the proposed rule before aggregation, to check that it matches the intended metrics.",
+			},
+			"series_before": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of series of the metric now: the series after the existing rule if there is one, and matched_series otherwise.",
+			},
+			"series_after": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The estimated number of series of the metric once the proposed rule is applied.",
+			},
+			"reduction_percent": schema.Float64Attribute{
+				Computed:    true,
+				Description: "The share of series_before which the proposed rule saves, in percent. It is negative if the proposed rule keeps more series than the existing rule.",
+			},
+		},
+	}
+}
+
+func (d *impactPreviewDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var tf model.ImpactPreviewTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &tf)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rule := tf.Rule()
+	resp.Diagnostics.Append(validateRule(rule, path.Empty())...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tf.EstimateAvailable = types.BoolValue(false)
+	tf.ExistingRule = types.BoolNull()
+	tf.MatchedSeries = types.Int64Null()
+	tf.SeriesBefore = types.Int64Null()
+	tf.SeriesAfter = types.Int64Null()
+	tf.ReductionPercent = types.Float64Null()
+
+	estimate, before, existing, err := d.estimate(ctx, rule)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Unable to estimate the impact of the aggregation rule",
+			fmt.Sprintf("The series counts of the rule for %q are left null: %s", rule.Metric, err),
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+		return
+	}
+
+	tf.EstimateAvailable = types.BoolValue(true)
+	tf.ExistingRule = types.BoolValue(existing)
+	tf.MatchedSeries = types.Int64Value(estimate.TotalSeriesBeforeAggregation)
+	tf.SeriesBefore = types.Int64Value(before)
+	tf.SeriesAfter = types.Int64Value(estimate.TotalSeriesAfterAggregation)
+	tf.ReductionPercent = types.Float64Value(reductionPercent(before, estimate.TotalSeriesAfterAggregation))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+// estimate estimates rule, and returns the number of series of its metric
+// now, which is the number of series after the existing rule for the metric if
+// there is one which is applied. The rule and the existing rule are estimated
+// at once.
+func (d *impactPreviewDatasource) estimate(ctx context.Context, rule model.AggregationRule) (model.AggregationRuleEstimate, int64, bool, error) {
+	var estimate model.AggregationRuleEstimate
+	var current *model.AggregationRuleEstimate
+	errs := parallel(ctx, 0, 2, func(_ context.Context, i int) error {
+		if i == 0 {
+			var err error
+			estimate, err = d.rules.Estimate(rule)
+			return err
+		}
+
+		existing, err := d.rules.Read(rule.Metric)
+		var notFound errRuleNotFound
+		switch {
+		case errors.As(err, &notFound) || (err == nil && existing.Archived):
+			return nil
+		case err != nil:
+			return fmt.Errorf("unable to read the existing rule: %w", err)
+		}
+
+		e, err := d.rules.Estimate(existing)
+		if err != nil {
+			return fmt.Errorf("unable to estimate the existing rule: %w", err)
+		}
+		current = &e
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			return model.AggregationRuleEstimate{}, 0, false, err
+		}
+	}
+
+	if current == nil {
+		return estimate, estimate.TotalSeriesBeforeAggregation, false, nil
+	}
+	return estimate, current.TotalSeriesAfterAggregation, true, nil
+}
+
+// reductionPercent returns the share of before which is saved with after, in
+// percent.
+func reductionPercent(before, after int64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return float64(before-after) / float64(before) * 100
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestImpactPreviewDatasourceEstimate(t *testing.T) {
+	api := newMockAPI(t)
+	api.estimate("http_requests_total", 1000, 100)
+	d := &impactPreviewDatasource{rules: api.aggregationRules()}
+
+	proposed := model.AggregationRule{Metric: "http_requests_total", DropLabels: []string{"pod"}}
+
+	// Without a rule for the metric, the series before are those matched.
+	estimate, before, existing, err := d.estimate(context.Background(), proposed)
+	require.NoError(t, err)
+	require.False(t, existing)
+	require.Equal(t, int64(1000), estimate.TotalSeriesBeforeAggregation)
+	require.Equal(t, int64(100), estimate.TotalSeriesAfterAggregation)
+	require.Equal(t, int64(1000), before)
+
+	// With one, they are those the existing rule keeps.
+	current := model.AggregationRule{Metric: "http_requests_total", DropLabels: []string{"instance"}}
+	require.NoError(t, d.rules.Create(current))
+	api.estimateRule(current, 1000, 400)
+
+	estimate, before, existing, err = d.estimate(context.Background(), proposed)
+	require.NoError(t, err)
+	require.True(t, existing)
+	require.Equal(t, int64(100), estimate.TotalSeriesAfterAggregation)
+	require.Equal(t, int64(400), before)
+	require.Equal(t, 75.0, reductionPercent(before, estimate.TotalSeriesAfterAggregation))
+
+	// Archived rules aggregate nothing.
+	current.Archived = true
+	require.NoError(t, d.rules.Update(current))
+
+	_, before, existing, err = d.estimate(context.Background(), proposed)
+	require.NoError(t, err)
+	require.False(t, existing)
+	require.Equal(t, int64(1000), before)
+
+	// Rules are only estimated, never saved.
+	require.Equal(t, []string{"http_requests_total"}, api.metrics())
+}
+
+func TestImpactPreviewDatasourceEstimateUnavailable(t *testing.T) {
+	api := newMockAPI(t)
+	d := &impactPreviewDatasource{rules: api.aggregationRules()}
+
+	_, _, _, err := d.estimate(context.Background(), model.AggregationRule{Metric: "http_requests_total"})
+	require.Error(t, err)
+}
+
+func TestReductionPercent(t *testing.T) {
+	require.Equal(t, 90.0, reductionPercent(1000, 100))
+	require.Equal(t, -50.0, reductionPercent(100, 150))
+	require.Equal(t, 0.0, reductionPercent(0, 0))
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type metricEvaluationDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &metricEvaluationDatasource{}
+	_ datasource.DataSourceWithConfigure = &metricEvaluationDatasource{}
+)
+
+func newMetricEvaluationDatasource() datasource.DataSource {
+	return &metricEvaluationDatasource{}
+}
+
+func (d *metricEvaluationDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rules = data.aggRules
+}
+
+func (d *metricEvaluationDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_metric_evaluation", req.ProviderTypeName)
+}
+
+func (d *metricEvaluationDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Finds the aggregation rule which governs a metric, and what it does to the metric, to understand which rules cover it. Nothing is changed.\n\n" +
+			"Every existing rule within the provider's `metric_prefix` which matches the metric is considered, except archived rules. The rule with the highest `priority` governs the metric; among rules of the same priority, an exact rule comes before prefix and suffix rules, and then the rule with the longest metric or prefix. Given the labels of a series, its conditional drop, delay overrides, and kept labels are resolved as well.",
+		Attributes: map[string]schema.Attribute{
+			"metric": schema.StringAttribute{
+				Required:    true,
+				Description: "The name of the metric to evaluate, without the provider's metric_prefix.",
+			},
+			"labels": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The labels of a series of the metric. Without them, the parts of the rule which depend on the labels of a series are not resolved.",
+			},
+			"rule_metric": schema.StringAttribute{
+				Computed:    true,
+				Description: "The metric of the rule which governs the metric, as in its configuration. Null if no rule matches the metric.",
+			},
+			"rule_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID of the rule which governs the metric. Null if no rule matches the metric, or if the API assigns no IDs.",
+			},
+			"matching_rules": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics of every rule matching the metric, the governing rule first and then in the order in which they would take over.",
+			},
+			"outcome": schema.StringAttribute{
+				Computed:    true,
+				Description: "What happens to the series of the metric: 'unaffected' if no rule applies to them, 'dropped', 'conditionally_dropped' if the rule drops only the series matching drop_if and no labels are given, or 'aggregated'.",
+			},
+			"aggregations": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The aggregation types computed for the metric, ordered by type. Empty unless the outcome is 'aggregated'.",
+			},
+			"aggregation_intervals": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The interval at which each aggregation type is computed, as for the effective values of the rule resource. Types aggregated at the default interval of the API are omitted.",
+			},
+			"aggregation_delay": schema.StringAttribute{
+				Computed:    true,
+				Description: "The aggregation delay of the series: that of the first delay override matching the labels, or else the aggregation_delay of the rule. Null if the outcome is not 'aggregated' or the rule uses the default delay of the API.",
+			},
+			"rollout_percentage": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The percentage of the series of the metric to which the rule applies. Null if no rule matches the metric.",
+			},
+			"kept_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The names of the given labels which the aggregations keep, ordered by name. Null unless labels are given and the outcome is 'aggregated'.",
+			},
+			"dropped_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The names of the given labels which the aggregations drop, ordered by name. Null unless labels are given and the outcome is 'aggregated'.",
+			},
+		},
+	}
+}
+
+func (d *metricEvaluationDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state model.MetricEvaluationTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var labels map[string]string
+	if state.Labels != nil {
+		labels = make(map[string]string, len(state.Labels))
+		for k, v := range state.Labels {
+			labels[k] = v.ValueString()
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, evaluateMetric(d.rules.List(), state.Metric.ValueString(), labels, state))...)
+}
+
+// evaluateMetric fills the computed attributes of state with the evaluation of
+// metric by rules. labels are those of a series, or nil if none are given.
+func evaluateMetric(rules []model.AggregationRule, metric string, labels map[string]string, state model.MetricEvaluationTF) model.MetricEvaluationTF {
+	matching := governingRules(rules, metric)
+
+	state.MatchingRules = make([]types.String, len(matching))
+	for i, rule := range matching {
+		state.MatchingRules[i] = types.StringValue(rule.Metric)
+	}
+	state.RuleMetric = types.StringNull()
+	state.RuleID = types.StringNull()
+	state.Outcome = types.StringValue(model.OutcomeUnaffected)
+	state.Aggregations = []types.String{}
+	state.AggregationIntervals = map[string]types.String{}
+	state.AggregationDelay = types.StringNull()
+	state.RolloutPercentage = types.Int64Null()
+	state.KeptLabels = nil
+	state.DroppedLabels = nil
+	if len(matching) == 0 {
+		return state
+	}
+
+	rule := matching[0]
+	effective := model.EffectiveRule(rule, false)
+	state.RuleMetric = types.StringValue(rule.Metric)
+	if rule.ID != "" {
+		state.RuleID = types.StringValue(rule.ID)
+	}
+	state.RolloutPercentage = effective.RolloutPercentage
+
+	if rule.Drop {
+		switch {
+		case len(rule.DropIf) == 0:
+			state.Outcome = types.StringValue(model.OutcomeDropped)
+		case labels == nil:
+			state.Outcome = types.StringValue(model.OutcomeConditionallyDropped)
+		case labelsMatch(rule.DropIf, labels):
+			state.Outcome = types.StringValue(model.OutcomeDropped)
+		}
+		return state
+	}
+
+	state.Outcome = types.StringValue(model.OutcomeAggregated)
+	state.Aggregations = effective.Aggregations
+	state.AggregationIntervals = effective.AggregationIntervals
+	if rule.AggregationDelay != "" {
+		state.AggregationDelay = types.StringValue(rule.AggregationDelay)
+	}
+	if labels != nil {
+		for _, o := range rule.DelayOverrides {
+			if labelsMatch([]model.DropMatcher{o.Matcher}, labels) {
+				state.AggregationDelay = types.StringValue(o.Delay)
+				break
+			}
+		}
+
+		names := make([]string, 0, len(labels))
+		for name := range labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		state.KeptLabels, state.DroppedLabels = []types.String{}, []types.String{}
+		for _, name := range names {
+			if labelKept(rule, name) {
+				state.KeptLabels = append(state.KeptLabels, types.StringValue(name))
+			} else {
+				state.DroppedLabels = append(state.DroppedLabels, types.StringValue(name))
+			}
+		}
+	}
+
+	return state
+}
+
+// governingRules returns the rules which are not archived and match metric,
+// ordered by precedence: the highest priority first, then exact rules before
+// prefix and suffix rules, and then the longest match first.
+func governingRules(rules []model.AggregationRule, metric string) []model.AggregationRule {
+	type match struct {
+		rule   model.AggregationRule
+		exact  bool
+		length int
+	}
+
+	var matches []match
+	for _, rule := range rules {
+		if rule.Archived {
+			continue
+		}
+		// Of the prefixes of a rule, the longest which matches counts.
+		found := match{rule: rule, length: -1}
+		for _, m := range rule.Matchers() {
+			var ok bool
+			switch m.MatchType {
+			case "", "exact":
+				ok = m.Metric == metric
+			case "prefix":
+				ok = strings.HasPrefix(metric, m.Metric)
+			case "suffix":
+				ok = strings.HasSuffix(metric, m.Metric)
+			}
+			if ok && len(m.Metric) > found.length {
+				found.exact = m.MatchType == "" || m.MatchType == "exact"
+				found.length = len(m.Metric)
+			}
+		}
+		if found.length >= 0 {
+			matches = append(matches, found)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.rule.Priority != b.rule.Priority:
+			return a.rule.Priority > b.rule.Priority
+		case a.exact != b.exact:
+			return a.exact
+		default:
+			return a.length > b.length
+		}
+	})
+
+	governing := make([]model.AggregationRule, len(matches))
+	for i, m := range matches {
+		governing[i] = m.rule
+	}
+	return governing
+}
+
+// labelsMatch reports whether the labels of a series match all of matchers. A
+// missing label has the empty value, as in Prometheus.
+func labelsMatch(matchers []model.DropMatcher, labels map[string]string) bool {
+	for _, m := range matchers {
+		if !dropMatcherAccepts(m, labels[m.Label]) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelKept reports whether the aggregations of rule keep the label name.
+func labelKept(rule model.AggregationRule, name string) bool {
+	matchesAny := func(entries []string) bool {
+		for _, entry := range entries {
+			if labelMatches(rule.LabelMatchMode, entry, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(rule.KeepLabels) > 0 {
+		return matchesAny(rule.KeepLabels)
+	}
+	return !matchesAny(rule.DropLabels)
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestEvaluateMetric(t *testing.T) {
+	rollout := int64(25)
+	rules := []model.AggregationRule{
+		{ID: "1", Metric: "http_", MatchType: "prefix", Aggregations: []string{"sum"}},
+		{ID: "2", Metric: "http_requests_total", Aggregations: []string{"sum", "count"}, AggregationInterval: "1m", DropLabels: []string{"pod"}},
+		{ID: "3", Metric: "http_requests_", MatchType: "prefix", Aggregations: []string{"max"}},
+		{ID: "4", Metric: "_bucket", MatchType: "suffix", Priority: 10, Drop: true, DropIf: []model.DropMatcher{{Label: "env", Operator: "=", Value: "dev"}}},
+		{ID: "5", Metric: "debug_", MatchType: "prefix", Drop: true},
+		{ID: "6", Metric: "grpc_calls_total", Archived: true, Drop: true},
+		{
+			ID: "7", Metric: "rpc_", MatchType: "prefix", Aggregations: []string{"sum"}, AggregationDelay: "1m",
+			LabelMatchMode: "glob", KeepLabels: []string{"serv*"}, RolloutPercentage: &rollout,
+			DelayOverrides: []model.DelayOverride{{Matcher: model.DropMatcher{Label: "region", Operator: "=~", Value: "eu-.*"}, Delay: "5m"}},
+		},
+	}
+	evaluate := func(metric string, labels map[string]string) model.MetricEvaluationTF {
+		return evaluateMetric(rules, metric, labels, model.MetricEvaluationTF{Metric: types.StringValue(metric)})
+	}
+	metrics := func(e model.MetricEvaluationTF) []string {
+		var metrics []string
+		for _, m := range e.MatchingRules {
+			metrics = append(metrics, m.ValueString())
+		}
+		return metrics
+	}
+
+	// Exact rules come before prefix rules, and then the longest prefix.
+	e := evaluate("http_requests_total", nil)
+	require.Equal(t, "http_requests_total", e.RuleMetric.ValueString())
+	require.Equal(t, "2", e.RuleID.ValueString())
+	require.Equal(t, []string{"http_requests_total", "http_requests_", "http_"}, metrics(e))
+	require.Equal(t, model.OutcomeAggregated, e.Outcome.ValueString())
+	require.Equal(t, []types.String{types.StringValue("count"), types.StringValue("sum")}, e.Aggregations)
+	require.Equal(t, map[string]types.String{"count": types.StringValue("1m"), "sum": types.StringValue("1m")}, e.AggregationIntervals)
+	require.Equal(t, int64(100), e.RolloutPercentage.ValueInt64())
+	require.True(t, e.AggregationDelay.IsNull())
+	require.Nil(t, e.KeptLabels)
+
+	e = evaluate("http_requests_total", map[string]string{"pod": "a", "method": "GET"})
+	require.Equal(t, []types.String{types.StringValue("method")}, e.KeptLabels)
+	require.Equal(t, []types.String{types.StringValue("pod")}, e.DroppedLabels)
+
+	// A higher priority takes over from an exact rule.
+	e = evaluate("http_requests_bucket", nil)
+	require.Equal(t, []string{"_bucket", "http_requests_", "http_"}, metrics(e))
+	require.Equal(t, model.OutcomeConditionallyDropped, e.Outcome.ValueString())
+	require.Empty(t, e.Aggregations)
+
+	e = evaluate("http_requests_bucket", map[string]string{"env": "dev"})
+	require.Equal(t, model.OutcomeDropped, e.Outcome.ValueString())
+
+	e = evaluate("http_requests_bucket", map[string]string{"env": "prod"})
+	require.Equal(t, model.OutcomeUnaffected, e.Outcome.ValueString())
+	require.Equal(t, "_bucket", e.RuleMetric.ValueString())
+
+	e = evaluate("debug_events_total", map[string]string{"env": "prod"})
+	require.Equal(t, model.OutcomeDropped, e.Outcome.ValueString())
+
+	// Delay overrides and label patterns are resolved with the labels.
+	e = evaluate("rpc_calls_total", nil)
+	require.Equal(t, "1m", e.AggregationDelay.ValueString())
+	require.Equal(t, int64(25), e.RolloutPercentage.ValueInt64())
+
+	e = evaluate("rpc_calls_total", map[string]string{"region": "eu-west", "service": "api", "pod": "a"})
+	require.Equal(t, "5m", e.AggregationDelay.ValueString())
+	require.Equal(t, []types.String{types.StringValue("service")}, e.KeptLabels)
+	require.Equal(t, []types.String{types.StringValue("pod"), types.StringValue("region")}, e.DroppedLabels)
+
+	// Archived rules do not match.
+	e = evaluate("grpc_calls_total", nil)
+	require.Equal(t, model.OutcomeUnaffected, e.Outcome.ValueString())
+	require.True(t, e.RuleMetric.IsNull())
+	require.True(t, e.RolloutPercentage.IsNull())
+	require.Empty(t, e.MatchingRules)
+}
+
+func TestGoverningRulesMatchPrefixes(t *testing.T) {
+	rules := []model.AggregationRule{
+		{Metric: "node_cpu", MatchType: "prefix"},
+		{Metric: "node", MatchPrefixes: []string{"node_", "node_cpu_"}},
+	}
+	metrics := func(rules []model.AggregationRule) []string {
+		var metrics []string
+		for _, rule := range rules {
+			metrics = append(metrics, rule.Metric)
+		}
+		return metrics
+	}
+
+	// The longest of the matching prefixes of a rule counts.
+	require.Equal(t, []string{"node", "node_cpu"}, metrics(governingRules(rules, "node_cpu_seconds_total")))
+	require.Equal(t, []string{"node"}, metrics(governingRules(rules, "node_memory_bytes")))
+	require.Empty(t, governingRules(rules, "up"))
+}
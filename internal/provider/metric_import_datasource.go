@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type metricImportDatasource struct {
+	client           *client.Client
+	capabilities     *capabilities
+	rules            *AggregationRules
+	providerTypeName string
+}
+
+var (
+	_ datasource.DataSource              = &metricImportDatasource{}
+	_ datasource.DataSourceWithConfigure = &metricImportDatasource{}
+)
+
+func newMetricImportDatasource() datasource.DataSource {
+	return &metricImportDatasource{}
+}
+
+func (d *metricImportDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = data.client
+	d.capabilities = data.capabilities
+	d.rules = data.aggRules
+}
+
+func (d *metricImportDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	d.providerTypeName = req.ProviderTypeName
+	resp.TypeName = fmt.Sprintf("%s_metric_import", req.ProviderTypeName)
+}
+
+func (d *metricImportDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Looks up the aggregation rule and the exemption of a metric, so that both can be imported into Terraform together. Nothing is changed.\n\n" +
+			"To bring a metric under management, add this data source for it and write its `hcl` to a file, for example with `terraform output -raw`. The file holds an `import` block and a resource block for the rule and for the exemption, for whichever of them exists, so that the next apply imports them without changing them. The data source can then be removed. It fails if the metric has neither a rule nor an exemption.",
+		Attributes: map[string]schema.Attribute{
+			"metric": schema.StringAttribute{
+				Required:    true,
+				Description: "The name of the metric, as for the rule resource.",
+			},
+			"rule_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The import ID of the aggregation rule for the metric: its ID, or its metric if the API assigns no IDs. Null if there is no rule.",
+			},
+			"exemption_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID of the exemption for the metric. Null if there is no exemption, or if the API does not support exemptions.",
+			},
+			"hcl": schema.StringAttribute{
+				Computed:    true,
+				Description: "An import block and a resource block for the rule and the exemption of the metric, in HCL format.",
+			},
+		},
+	}
+}
+
+func (d *metricImportDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state model.MetricImportTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	metric := state.Metric.ValueString()
+
+	var blocks []string
+	state.RuleID = types.StringNull()
+	rule, err := d.rules.Read(metric)
+	var notFound errRuleNotFound
+	switch {
+	case err == nil:
+		id := rule.ID
+		if id == "" {
+			id = rule.Metric
+		}
+		state.RuleID = types.StringValue(id)
+		blocks = append(blocks, hclImport(d.providerTypeName+"_rule", hclResourceName(metric, map[string]bool{}), id)+"\n"+rulesToHCL(d.providerTypeName+"_rule", []model.AggregationRule{rule}))
+	case !errors.As(err, &notFound):
+		resp.Diagnostics.AddError("Unable to read aggregation rule", err.Error())
+		return
+	}
+
+	// The exemption is optional, so a backend without exemptions only
+	// results in no exemption being found.
+	state.ExemptionID = types.StringNull()
+	if !d.capabilities.require(client.FeatureExemptions).HasError() {
+		exemptions, err := d.client.ListExemptions()
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to list exemptions", err.Error())
+			return
+		}
+		for _, ex := range exemptions {
+			if ex.Metric == d.rules.metricPrefix+metric {
+				state.ExemptionID = types.StringValue(ex.ID)
+				blocks = append(blocks, hclImport(d.providerTypeName+"_exemption", hclResourceName(metric, map[string]bool{}), ex.ID)+"\n"+exemptionToHCL(d.providerTypeName+"_exemption", metric, ex))
+				break
+			}
+		}
+	}
+
+	if len(blocks) == 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("metric"),
+			"Metric not found",
+			fmt.Sprintf("There is neither an aggregation rule nor an exemption for %q, so there is nothing to import.", metric),
+		)
+		return
+	}
+
+	state.HCL = types.StringValue(strings.Join(blocks, "\n"))
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// hclImport renders an import block for the resource of the given type and
+// name.
+func hclImport(resourceType, name, id string) string {
+	return fmt.Sprintf("import {\n  to = %s.%s\n  id = %s\n}\n", resourceType, name, hclString(id))
+}
+
+// exemptionToHCL renders a resource block of the given type for ex, named
+// after metric. Only attributes which differ from the resource defaults are
+// included.
+func exemptionToHCL(resourceType, metric string, ex model.Exemption) string {
+	tf := ex.ToTF()
+
+	attrs := []hclAttribute{{"metric", hclString(tf.Metric.ValueString())}}
+	if len(tf.KeepLabels) > 0 {
+		attrs = append(attrs, hclAttribute{"keep_labels", hclStringList(tf.KeepLabels)})
+	}
+	if tf.DisableRecommendations.ValueBool() {
+		attrs = append(attrs, hclAttribute{"disable_recommendations", "true"})
+	}
+	if v := tf.Reason.ValueString(); v != "" {
+		attrs = append(attrs, hclAttribute{"reason", hclString(v)})
+	}
+
+	width := 0
+	for _, attr := range attrs {
+		width = max(width, len(attr.name))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource %s %s {\n", hclString(resourceType), hclString(hclResourceName(metric, map[string]bool{})))
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, "  %-*s = %s\n", width, attr.name, attr.value)
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
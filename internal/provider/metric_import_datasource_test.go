@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readMetricImport(t *testing.T, d *metricImportDatasource, metric string) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+
+	config := tfsdk.Config{Schema: schemaResp.Schema}
+	config.Raw = tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), map[string]tftypes.Value{
+		"metric":       tftypes.NewValue(tftypes.String, metric),
+		"rule_id":      tftypes.NewValue(tftypes.String, nil),
+		"exemption_id": tftypes.NewValue(tftypes.String, nil),
+		"hcl":          tftypes.NewValue(tftypes.String, nil),
+	})
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+func TestMetricImportDatasource(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{ID: "r-1", Metric: "both", DropLabels: []string{"pod"}},
+		model.AggregationRule{ID: "r-2", Metric: "rule_only", Drop: true},
+	)
+	api.exemptions["e-1"] = model.Exemption{ID: "e-1", Metric: "both", KeepLabels: []string{"pod"}, Reason: "debugging"}
+	api.exemptions["e-2"] = model.Exemption{ID: "e-2", Metric: "exemption_only", DisableRecommendations: true}
+	d := &metricImportDatasource{client: api.client(), rules: api.aggregationRules(), providerTypeName: "grafana-adaptive-metrics"}
+
+	resp := readMetricImport(t, d, "both")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	var tf model.MetricImportTF
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.Equal(t, types.StringValue("r-1"), tf.RuleID)
+	require.Equal(t, types.StringValue("e-1"), tf.ExemptionID)
+	require.Equal(t, `import {
+  to = grafana-adaptive-metrics_rule.both
+  id = "r-1"
+}
+
+resource "grafana-adaptive-metrics_rule" "both" {
+  metric      = "both"
+  drop_labels = ["pod"]
+}
+
+import {
+  to = grafana-adaptive-metrics_exemption.both
+  id = "e-1"
+}
+
+resource "grafana-adaptive-metrics_exemption" "both" {
+  metric      = "both"
+  keep_labels = ["pod"]
+  reason      = "debugging"
+}
+`, tf.HCL.ValueString())
+
+	// Only the rule or the exemption which exists is imported.
+	resp = readMetricImport(t, d, "rule_only")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.Equal(t, types.StringValue("r-2"), tf.RuleID)
+	require.True(t, tf.ExemptionID.IsNull())
+
+	resp = readMetricImport(t, d, "exemption_only")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.True(t, tf.RuleID.IsNull())
+	require.Equal(t, types.StringValue("e-2"), tf.ExemptionID)
+	require.Contains(t, tf.HCL.ValueString(), "disable_recommendations = true")
+
+	resp = readMetricImport(t, d, "missing")
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Metric not found", resp.Diagnostics.Errors()[0].Summary())
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// checkMetricName returns an error describing why name is not a valid
+// Prometheus metric name, if it is not.
+func checkMetricName(name string) error {
+	if name == "" {
+		return errors.New("metric name must not be empty")
+	}
+	if !metricNameRE.MatchString(name) {
+		return fmt.Errorf("invalid metric name %q, it must match %s", name, metricNameRE)
+	}
+	return nil
+}
+
+type isValidMetricNameFunction struct{}
+
+var _ function.Function = &isValidMetricNameFunction{}
+
+func newIsValidMetricNameFunction() function.Function {
+	return &isValidMetricNameFunction{}
+}
+
+func (f *isValidMetricNameFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "is_valid_metric_name"
+}
+
+func (f *isValidMetricNameFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Checks whether a string is a valid metric name",
+		MarkdownDescription: "Returns whether a string is a valid Prometheus metric name, that is a non-empty string of letters, digits, `_`, and `:` which does not start with a digit. Use it to filter metric names, such as in a `for_each` expression, before they are used in rules.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "name",
+				Description: "The metric name to check.",
+			},
+		},
+		Return: function.BoolReturn{},
+	}
+}
+
+func (f *isValidMetricNameFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var name string
+	resp.Error = req.Arguments.Get(ctx, &name)
+	if resp.Error != nil {
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, checkMetricName(name) == nil)
+}
+
+type validateMetricNameFunction struct{}
+
+var _ function.Function = &validateMetricNameFunction{}
+
+func newValidateMetricNameFunction() function.Function {
+	return &validateMetricNameFunction{}
+}
+
+func (f *validateMetricNameFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "validate_metric_name"
+}
+
+func (f *validateMetricNameFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Fails unless a string is a valid metric name",
+		MarkdownDescription: "Returns a metric name unchanged if it is a valid Prometheus metric name, and fails with an error explaining why otherwise. Use it to reject invalid metric names, such as ones read from a file, during plan with a clear error.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "name",
+				Description: "The metric name to validate.",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *validateMetricNameFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var name string
+	resp.Error = req.Arguments.Get(ctx, &name)
+	if resp.Error != nil {
+		return
+	}
+
+	if err := checkMetricName(name); err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, name)
+}
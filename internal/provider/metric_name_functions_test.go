@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricNameFunctions(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{in: "http_requests_total"},
+		{in: "job:http_requests:rate5m"},
+		{in: "_hidden"},
+		{in: "Up"},
+		{in: "", err: "metric name must not be empty"},
+		{in: "1xx_responses", err: `invalid metric name "1xx_responses", it must match ^[a-zA-Z_:][a-zA-Z0-9_:]*$`},
+		{in: "http-requests", err: `invalid metric name "http-requests", it must match ^[a-zA-Z_:][a-zA-Z0-9_:]*$`},
+		{in: "http requests", err: `invalid metric name "http requests", it must match ^[a-zA-Z_:][a-zA-Z0-9_:]*$`},
+		{in: "métrique", err: `invalid metric name "métrique", it must match ^[a-zA-Z_:][a-zA-Z0-9_:]*$`},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			result, funcErr := runFunction(newIsValidMetricNameFunction(), types.BoolUnknown(), types.StringValue(tc.in))
+			require.Nil(t, funcErr)
+			require.Equal(t, types.BoolValue(tc.err == ""), result)
+
+			result, funcErr = runFunction(newValidateMetricNameFunction(), types.StringUnknown(), types.StringValue(tc.in))
+			if tc.err != "" {
+				require.Equal(t, function.NewArgumentFuncError(0, tc.err), funcErr)
+				return
+			}
+			require.Nil(t, funcErr)
+			require.Equal(t, types.StringValue(tc.in), result)
+		})
+	}
+}
@@ -0,0 +1,763 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+const (
+	mockRulePathPrefix     = "/aggregations/rule/"
+	mockRuleByIDPathPrefix = "/aggregations/rules/"
+	mockExemptionsPath     = "/v1/recommendations/exemptions"
+	mockLockPathPrefix     = "/aggregations/locks/"
+)
+
+// mockAPI is an in-memory implementation of the aggregation rules API, used to
+// unit test logic that spans several requests.
+type mockAPI struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	version  int
+	rules    map[string]model.AggregationRule
+	failures map[string]int
+	requests []string
+
+	// recommendations are keyed by "<label>=<value>".
+	recommendations map[string][]model.AggregationRecommendation
+	// estimates are keyed by metric; the estimate endpoint is unavailable if
+	// it is nil.
+	estimates map[string]model.AggregationRuleEstimate
+	// ruleEstimates are keyed by the ContentID of a rule, and take precedence
+	// over estimates for its metric.
+	ruleEstimates map[string]model.AggregationRuleEstimate
+	// invalid are the reasons why rules for a metric are rejected by the
+	// validate and bulk endpoints.
+	invalid map[string]string
+	// supportedAggregations, features, and deprecatedAggregations are
+	// returned by the server info endpoint, which is unavailable if all are
+	// nil.
+	supportedAggregations  []string
+	features               []string
+	deprecatedAggregations []model.AggregationDeprecation
+	// latency delays every response, if set.
+	latency time.Duration
+	// block holds every request, if set, until it is closed or the client
+	// gives up on the request.
+	block chan struct{}
+	// pageSize limits the number of rules per page of paginated lists, if
+	// set.
+	pageSize int
+	// warnings are returned when the rule for a metric is created or
+	// updated.
+	warnings map[string][]string
+	// aggregatedMetrics are returned by the aggregated metrics endpoint,
+	// which is unavailable if they are nil.
+	aggregatedMetrics []model.AggregatedMetric
+	// metricMetadata are returned by the metric metadata endpoint, keyed by
+	// metric, which is unavailable if they are nil.
+	metricMetadata map[string]model.MetricMetadata
+	// ruleMetadata are server-side metadata blobs returned in the metadata
+	// field of the rules, keyed by metric, which the provider does not model.
+	ruleMetadata map[string]string
+	// fieldSelections are the fields parameters of the requests.
+	// Only the selected fields are returned if the features include
+	// field_selection.
+	fieldSelections []string
+	// headers are sent with every response.
+	headers http.Header
+	// exemptions are keyed by ID.
+	exemptions      map[string]model.Exemption
+	nextExemptionID int
+	// locks are the held locks, keyed by name.
+	locks map[string]model.Lock
+	// bulkRequests are the metrics of the rules of every bulk request, in
+	// the order of the request.
+	bulkRequests [][]string
+	// groups are the groups which the rules are filed under, keyed by
+	// metric.
+	groups map[string]string
+	// listQueries are the query strings of the requests listing the rules.
+	// The rules are filtered by them if the features include rule_filters.
+	listQueries []string
+}
+
+func newMockAPI(t *testing.T, rules ...model.AggregationRule) *mockAPI {
+	t.Helper()
+
+	m := &mockAPI{
+		t:        t,
+		rules:    make(map[string]model.AggregationRule),
+		failures: make(map[string]int),
+
+		recommendations: make(map[string][]model.AggregationRecommendation),
+		invalid:         make(map[string]string),
+		warnings:        make(map[string][]string),
+		exemptions:      make(map[string]model.Exemption),
+		locks:           make(map[string]model.Lock),
+		groups:          make(map[string]string),
+	}
+	for _, rule := range rules {
+		m.rules[rule.Metric] = rule
+	}
+
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+// failOn makes every request matching method and path respond with status.
+func (m *mockAPI) failOn(method, path string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures[method+" "+path] = status
+}
+
+// recommend sets the recommendations returned for metrics with the given
+// label value.
+func (m *mockAPI) recommend(label, value string, recs ...model.AggregationRecommendation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recommendations[label+"="+value] = recs
+}
+
+// estimate sets the estimated number of series before and after aggregation
+// for rules for metric.
+func (m *mockAPI) estimate(metric string, before, after int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.estimates == nil {
+		m.estimates = make(map[string]model.AggregationRuleEstimate)
+	}
+	m.estimates[metric] = model.AggregationRuleEstimate{TotalSeriesBeforeAggregation: before, TotalSeriesAfterAggregation: after}
+}
+
+// estimateRule sets the estimated number of series before and after
+// aggregation for rule, which differs from other rules for its metric.
+func (m *mockAPI) estimateRule(rule model.AggregationRule, before, after int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ruleEstimates == nil {
+		m.ruleEstimates = make(map[string]model.AggregationRuleEstimate)
+	}
+	m.ruleEstimates[rule.ContentID()] = model.AggregationRuleEstimate{TotalSeriesBeforeAggregation: before, TotalSeriesAfterAggregation: after}
+}
+
+// reject makes the validate endpoint reject rules for metric with reason.
+func (m *mockAPI) reject(metric, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.invalid[metric] = reason
+}
+
+func (m *mockAPI) rule(metric string) (model.AggregationRule, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule, ok := m.rules[metric]
+	return rule, ok
+}
+
+func (m *mockAPI) metrics() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := make([]string, 0, len(m.rules))
+	for metric := range m.rules {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// exempt adds an exemption for metric.
+func (m *mockAPI) exempt(metric, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addExemption(model.Exemption{Metric: metric, Reason: reason})
+}
+
+// exemption returns the exemption for metric.
+func (m *mockAPI) exemption(metric string) (model.Exemption, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ex := range m.exemptions {
+		if ex.Metric == metric {
+			return ex, true
+		}
+	}
+	return model.Exemption{}, false
+}
+
+// exemptMetrics returns the metrics of all exemptions, sorted.
+func (m *mockAPI) exemptMetrics() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := make([]string, 0, len(m.exemptions))
+	for _, ex := range m.exemptions {
+		metrics = append(metrics, ex.Metric)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+func (m *mockAPI) requestLog() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.requests...)
+}
+
+func (m *mockAPI) client() *client.Client {
+	m.t.Helper()
+
+	c, err := client.New(m.server.URL, &client.Config{})
+	require.NoError(m.t, err)
+	return c
+}
+
+func (m *mockAPI) aggregationRules() *AggregationRules {
+	m.t.Helper()
+
+	rules := NewAggregationRules(m.client(), "")
+	require.NoError(m.t, rules.Init())
+	return rules
+}
+
+// setLatency delays every following response by latency.
+func (m *mockAPI) setLatency(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latency = latency
+}
+
+// blockRequests holds every following request until the client gives up on
+// it, such as when its context is done, or the test ends.
+func (m *mockAPI) blockRequests() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	block := make(chan struct{})
+	m.block = block
+	// Cleanups run in reverse, so the requests are released before the
+	// server waits for them to finish.
+	m.t.Cleanup(func() { close(block) })
+}
+
+func (m *mockAPI) etag() string {
+	return fmt.Sprintf("\"%d\"", m.version)
+}
+
+func (m *mockAPI) handle(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	latency, block := m.latency, m.block
+	m.mu.Unlock()
+
+	time.Sleep(latency)
+	if block != nil {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, r.Method+" "+r.URL.Path)
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		m.fieldSelections = append(m.fieldSelections, fields)
+	}
+	for k, v := range m.headers {
+		w.Header()[k] = v
+	}
+
+	if status, ok := m.failures[r.Method+" "+r.URL.Path]; ok {
+		http.Error(w, "injected failure", status)
+		return
+	}
+
+	if r.URL.Path == "/aggregations/rules/estimate" && r.Method == http.MethodPost {
+		m.handleEstimate(w, r)
+		return
+	}
+	if r.URL.Path == "/aggregations/rules/validate" && r.Method == http.MethodPost {
+		m.handleValidate(w, r)
+		return
+	}
+
+	if r.URL.Path == mockExemptionsPath || strings.HasPrefix(r.URL.Path, mockExemptionsPath+"/") {
+		m.handleExemptions(w, r, strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, mockExemptionsPath), "/"))
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, mockLockPathPrefix) {
+		m.handleLock(w, r, strings.TrimPrefix(r.URL.Path, mockLockPathPrefix))
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Header.Get("If-Match") != m.etag() {
+		http.Error(w, "etag mismatch", http.StatusPreconditionFailed)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/aggregations/rules":
+		m.handleRules(w, r)
+	case strings.HasPrefix(r.URL.Path, mockRulePathPrefix):
+		m.handleRule(w, r, strings.TrimPrefix(r.URL.Path, mockRulePathPrefix))
+	case strings.HasPrefix(r.URL.Path, mockRuleByIDPathPrefix):
+		m.handleRuleByID(w, r, strings.TrimPrefix(r.URL.Path, mockRuleByIDPathPrefix))
+	case r.URL.Path == "/aggregations/server_info" && r.Method == http.MethodGet:
+		if m.supportedAggregations == nil && m.features == nil && m.deprecatedAggregations == nil {
+			http.NotFound(w, r)
+			return
+		}
+		m.writeJSON(w, model.ServerInfo{SupportedAggregations: m.supportedAggregations, Features: m.features, DeprecatedAggregations: m.deprecatedAggregations})
+	case r.URL.Path == "/aggregations/aggregated_metrics" && r.Method == http.MethodGet:
+		if m.aggregatedMetrics == nil {
+			http.NotFound(w, r)
+			return
+		}
+		m.writeJSON(w, m.aggregatedMetrics)
+	case r.URL.Path == "/aggregations/metric_metadata" && r.Method == http.MethodGet:
+		metadata, ok := m.metricMetadata[r.URL.Query().Get("metric")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		m.writeJSON(w, metadata)
+	case r.URL.Path == "/aggregations/recommendations" && r.Method == http.MethodGet:
+		m.handleRecommendations(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (m *mockAPI) handleRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		m.listQueries = append(m.listQueries, r.URL.RawQuery)
+		filter, filtered := m.listFilter(query)
+		rules := make([]model.AggregationRule, 0, len(m.rules))
+		for _, rule := range m.rules {
+			if !filtered || filter.Matches(rule) && (filter.Group == "" || m.groups[rule.Metric] == filter.Group) {
+				rules = append(rules, rule)
+			}
+		}
+		sort.Slice(rules, func(i, j int) bool { return rules[i].Metric < rules[j].Metric })
+
+		if query.Has("page_size") {
+			pageSize, err := strconv.Atoi(query.Get("page_size"))
+			require.NoError(m.t, err)
+			if m.pageSize > 0 && m.pageSize < pageSize {
+				pageSize = m.pageSize
+			}
+			start := 0
+			if token := query.Get("page_token"); token != "" {
+				start, err = strconv.Atoi(token)
+				require.NoError(m.t, err)
+			}
+			rules = rules[start:]
+			if len(rules) > pageSize {
+				rules = rules[:pageSize]
+				w.Header().Set("X-Next-Page-Token", strconv.Itoa(start+pageSize))
+			}
+		}
+		objects := make([]map[string]any, len(rules))
+		for i, rule := range rules {
+			objects[i] = m.ruleObject(r, rule)
+		}
+		m.writeJSON(w, objects)
+	case http.MethodPost:
+		var rules []model.AggregationRule
+		if !m.readJSON(w, r, &rules) {
+			return
+		}
+		metrics := make([]string, len(rules))
+		for i, rule := range rules {
+			metrics[i] = rule.Metric
+		}
+		m.bulkRequests = append(m.bulkRequests, metrics)
+
+		var fieldErrors []client.FieldError
+		for i, rule := range rules {
+			if reason, ok := m.invalid[rule.Metric]; ok {
+				fieldErrors = append(fieldErrors, client.FieldError{Field: fmt.Sprintf("[%d].metric", i), Message: reason})
+			}
+		}
+		if len(fieldErrors) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			m.writeJSON(w, map[string][]client.FieldError{"field_errors": fieldErrors})
+			return
+		}
+
+		m.rules = make(map[string]model.AggregationRule)
+		for _, rule := range rules {
+			m.rules[rule.Metric] = rule
+		}
+		m.bump(w)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// listFilter returns the filter of the rules listed for query, if the
+// features include rule_filters. Like older versions of the API, the mock
+// otherwise lists every rule.
+func (m *mockAPI) listFilter(query url.Values) (client.AggregationRuleFilter, bool) {
+	if !slices.Contains(m.features, string(client.FeatureRuleFilters)) {
+		return client.AggregationRuleFilter{}, false
+	}
+
+	filter := client.AggregationRuleFilter{
+		MetricPrefix: query.Get("metric_prefix"),
+		MatchType:    query.Get("match_type"),
+		Group:        query.Get("group"),
+	}
+	if query.Has("drop") {
+		drop, err := strconv.ParseBool(query.Get("drop"))
+		require.NoError(m.t, err)
+		filter.Drop = &drop
+	}
+	for _, tag := range query["tag"] {
+		k, v, ok := strings.Cut(tag, "=")
+		require.True(m.t, ok, tag)
+		if filter.Tags == nil {
+			filter.Tags = make(map[string]string)
+		}
+		filter.Tags[k] = v
+	}
+	return filter, true
+}
+
+func (m *mockAPI) handleRule(w http.ResponseWriter, r *http.Request, metric string) {
+	_, exists := m.rules[metric]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		m.writeJSON(w, m.ruleObject(r, m.rules[metric]))
+	case http.MethodPost:
+		if exists {
+			http.Error(w, "rule already exists", http.StatusConflict)
+			return
+		}
+		m.upsert(w, r)
+	case http.MethodPut:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		m.upsert(w, r)
+	case http.MethodPatch:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		m.patch(w, r, metric)
+	case http.MethodDelete:
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		delete(m.rules, metric)
+		m.bump(w)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRuleByID serves the requests for a rule addressed by its ID. Rules
+// only have an ID if the test created them with one.
+func (m *mockAPI) handleRuleByID(w http.ResponseWriter, r *http.Request, id string) {
+	var metric string
+	for _, rule := range m.rules {
+		if rule.ID == id {
+			metric = rule.Metric
+		}
+	}
+	if metric == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var rule model.AggregationRule
+		if !m.readJSON(w, r, &rule) {
+			return
+		}
+		delete(m.rules, metric)
+		m.rules[rule.Metric] = rule
+		m.bump(w)
+	case http.MethodPatch:
+		m.patch(w, r, metric)
+	case http.MethodDelete:
+		delete(m.rules, metric)
+		m.bump(w)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// rename renames the rule for from outside of the provider, keeping its ID.
+func (m *mockAPI) rename(from, to string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rule := m.rules[from]
+	delete(m.rules, from)
+	rule.Metric = to
+	m.rules[to] = rule
+	m.version++
+}
+
+func (m *mockAPI) handleRecommendations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	actions := query["action"]
+
+	recs := []model.AggregationRecommendation{}
+	for _, rec := range m.recommendations[query.Get("label")+"="+query.Get("value")] {
+		if len(actions) == 0 || slices.Contains(actions, rec.RecommendedAction) {
+			recs = append(recs, rec)
+		}
+	}
+	m.writeJSON(w, recs)
+}
+
+func (m *mockAPI) handleEstimate(w http.ResponseWriter, r *http.Request) {
+	if m.estimates == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var rule model.AggregationRule
+	if !m.readJSON(w, r, &rule) {
+		return
+	}
+	if estimate, ok := m.ruleEstimates[rule.ContentID()]; ok {
+		m.writeJSON(w, estimate)
+		return
+	}
+	m.writeJSON(w, m.estimates[rule.Metric])
+}
+
+func (m *mockAPI) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var rule model.AggregationRule
+	if !m.readJSON(w, r, &rule) {
+		return
+	}
+	if reason, ok := m.invalid[rule.Metric]; ok {
+		http.Error(w, reason, http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExemptions serves the exemptions endpoints, which do not use ETags. An
+// empty id addresses the list of exemptions.
+func (m *mockAPI) handleExemptions(w http.ResponseWriter, r *http.Request, id string) {
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		exemptions := make([]model.Exemption, 0, len(m.exemptions))
+		for _, ex := range m.exemptions {
+			exemptions = append(exemptions, ex)
+		}
+		sort.Slice(exemptions, func(i, j int) bool { return exemptions[i].ID < exemptions[j].ID })
+		m.writeJSON(w, map[string][]model.Exemption{"result": exemptions})
+	case id == "" && r.Method == http.MethodPost:
+		var ex model.Exemption
+		if !m.readJSON(w, r, &ex) {
+			return
+		}
+		m.writeJSON(w, map[string]model.Exemption{"result": m.addExemption(ex)})
+	case id != "" && r.Method == http.MethodPut:
+		var ex model.Exemption
+		if !m.readJSON(w, r, &ex) {
+			return
+		}
+		if _, ok := m.exemptions[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		ex.ID = id
+		m.exemptions[id] = ex
+		w.WriteHeader(http.StatusNoContent)
+	case id != "" && r.Method == http.MethodDelete:
+		if _, ok := m.exemptions[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(m.exemptions, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleLock serves the lock endpoints, which do not use ETags. A lock is
+// acquired with a PUT unless another holder holds it, and released with a
+// DELETE by its holder.
+func (m *mockAPI) handleLock(w http.ResponseWriter, r *http.Request, name string) {
+	lock, held := m.locks[name]
+	switch r.Method {
+	case http.MethodPut:
+		var req model.Lock
+		if !m.readJSON(w, r, &req) {
+			return
+		}
+		if held && lock.Holder != req.Holder {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			require.NoError(m.t, json.NewEncoder(w).Encode(lock))
+			return
+		}
+		if !held {
+			lock = model.Lock{Holder: req.Holder, AcquiredAt: "2024-05-01T10:00:00Z"}
+			m.locks[name] = lock
+		}
+		m.writeJSON(w, lock)
+	case http.MethodDelete:
+		if !held || lock.Holder != r.URL.Query().Get("holder") {
+			http.NotFound(w, r)
+			return
+		}
+		delete(m.locks, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (m *mockAPI) addExemption(ex model.Exemption) model.Exemption {
+	m.nextExemptionID++
+	ex.ID = fmt.Sprintf("ex-%03d", m.nextExemptionID)
+	m.exemptions[ex.ID] = ex
+	return ex
+}
+
+func (m *mockAPI) upsert(w http.ResponseWriter, r *http.Request) {
+	var rule model.AggregationRule
+	if !m.readJSON(w, r, &rule) {
+		return
+	}
+	m.rules[rule.Metric] = rule
+	m.bump(w)
+
+	if warnings := m.warnings[rule.Metric]; len(warnings) > 0 {
+		m.writeJSON(w, map[string][]string{"warnings": warnings})
+	}
+}
+
+// patch applies the JSON merge patch in the body of r to the rule for metric.
+func (m *mockAPI) patch(w http.ResponseWriter, r *http.Request, metric string) {
+	if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	var patch map[string]json.RawMessage
+	if !m.readJSON(w, r, &patch) {
+		return
+	}
+
+	body, err := json.Marshal(m.rules[metric])
+	require.NoError(m.t, err)
+	var fields map[string]json.RawMessage
+	require.NoError(m.t, json.Unmarshal(body, &fields))
+	for k, v := range patch {
+		if string(v) == "null" {
+			delete(fields, k)
+		} else {
+			fields[k] = v
+		}
+	}
+
+	body, err = json.Marshal(fields)
+	require.NoError(m.t, err)
+	var rule model.AggregationRule
+	require.NoError(m.t, json.Unmarshal(body, &rule))
+	delete(m.rules, metric)
+	m.rules[rule.Metric] = rule
+	m.bump(w)
+}
+
+func (m *mockAPI) bump(w http.ResponseWriter) {
+	m.version++
+	w.Header().Set("ETag", m.etag())
+}
+
+func (m *mockAPI) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(m.t, err)
+
+	if err := json.Unmarshal(body, v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// ruleObject returns rule as it is written in responses to r, with its
+// metadata, and with only the fields selected by r if field selection is
+// supported.
+func (m *mockAPI) ruleObject(r *http.Request, rule model.AggregationRule) map[string]any {
+	fields := r.URL.Query().Get("fields")
+	body, err := json.Marshal(rule)
+	require.NoError(m.t, err)
+	var object map[string]any
+	require.NoError(m.t, json.Unmarshal(body, &object))
+	if metadata, ok := m.ruleMetadata[rule.Metric]; ok {
+		object["metadata"] = metadata
+	}
+
+	if fields != "" && slices.Contains(m.features, string(client.FeatureFieldSelection)) {
+		for field := range object {
+			if !slices.Contains(strings.Split(fields, ","), field) {
+				delete(object, field)
+			}
+		}
+	}
+	return object
+}
+
+func (m *mockAPI) writeJSON(w http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	require.NoError(m.t, err)
+
+	w.Header().Set("ETag", m.etag())
+	_, err = w.Write(body)
+	require.NoError(m.t, err)
+}
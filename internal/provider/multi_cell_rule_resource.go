@@ -0,0 +1,478 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type multiCellRuleResource struct {
+	cells *cellRules
+
+	requiredKeepLabels    []string
+	allowedMetricPatterns []string
+}
+
+var (
+	_ resource.Resource                   = &multiCellRuleResource{}
+	_ resource.ResourceWithConfigure      = &multiCellRuleResource{}
+	_ resource.ResourceWithModifyPlan     = &multiCellRuleResource{}
+	_ resource.ResourceWithValidateConfig = &multiCellRuleResource{}
+)
+
+func newMultiCellRuleResource() resource.Resource {
+	return &multiCellRuleResource{}
+}
+
+func (r *multiCellRuleResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.cells = data.cells
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+}
+
+func (r *multiCellRuleResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_multi_cell_rule", req.ProviderTypeName)
+}
+
+func (r *multiCellRuleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Replicates the same aggregation rule to several cells, such as the stacks of different regions. The rule is applied to each cell independently: a cell which fails is reported without undoing the cells which succeeded, and is retried on the next apply.",
+		Attributes: map[string]schema.Attribute{
+			"metric": schema.StringAttribute{
+				Required:    true,
+				Description: "The name of the metric to be aggregated.",
+			},
+			"match_type": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
+			},
+
+			"drop": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultBoolFalse{},
+				Description: "Set to true to skip both ingestion and aggregation and drop the metric entirely.",
+			},
+			"keep_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of labels to keep; labels not in this array will be aggregated.",
+			},
+			"drop_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of labels that will be aggregated.",
+			},
+
+			"aggregations": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of aggregation types to calculate for this metric. Percentiles are written as p<percent>, such as p99 or p99.9. Set to [\"*\"] to calculate every aggregation type supported by the API.",
+			},
+
+			"aggregation_interval": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "The interval at which to generate the aggregated series.",
+			},
+			"aggregation_delay": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "The delay until aggregation is performed.",
+			},
+
+			"priority": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+				Description: "The priority of the rule. When several rules match a metric, the rule with the highest priority is applied. Must not be negative. Defaults to 0.",
+			},
+
+			"target_cells": schema.ListNestedAttribute{
+				Required:    true,
+				Description: "The cells to apply the rule to.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"url": schema.StringAttribute{
+							Required:    true,
+							Description: "The URL of the Adaptive Metrics API of the cell.",
+						},
+						"api_key": schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "The API key or service account token of the cell. Defaults to the provider's api_key.",
+						},
+					},
+				},
+			},
+			"applied_cells": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The URLs of the cells the rule is currently applied to. Cells which failed, or whose rule was changed or deleted outside of Terraform, are missing until the next successful apply.",
+			},
+		},
+	}
+}
+
+func (r *multiCellRuleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	if !req.Config.Raw.IsFullyKnown() {
+		return
+	}
+
+	var cfg model.MultiCellRuleTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateRule(cfg.ToAPIReq(), path.Empty())...)
+
+	seen := make(map[string]bool, len(cfg.TargetCells))
+	for i, url := range cfg.TargetURLs() {
+		if seen[url] {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("target_cells").AtListIndex(i).AtName("url"),
+				"Duplicate target cell",
+				fmt.Sprintf("The cell %q is targeted more than once.", url),
+			)
+		}
+		seen[url] = true
+	}
+}
+
+func (r *multiCellRuleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.validatePolicy(ctx, req.Plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var targets types.List
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("target_cells"), &targets)...)
+	if resp.Diagnostics.HasError() || targets.IsUnknown() {
+		return
+	}
+	var cells []model.TargetCellTF
+	resp.Diagnostics.Append(targets.ElementsAs(ctx, &cells, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	urls := make([]string, len(cells))
+	for i, c := range cells {
+		if c.URL.IsUnknown() {
+			return
+		}
+		urls[i] = c.URL.ValueString()
+	}
+
+	// The rule is planned to be applied to every target cell, so that cells
+	// missing from state are retried.
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("applied_cells"), urls)...)
+}
+
+// validatePolicy checks the planned rule against the provider's
+// required_keep_labels and allowed_metric_patterns, once its settings are
+// known. The plan is read by attribute, as applied_cells is unknown.
+func (r *multiCellRuleResource) validatePolicy(ctx context.Context, plan tfsdk.Plan) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var metric, matchType types.String
+	var drop types.Bool
+	var keepLabels, dropLabels types.List
+	diags.Append(plan.GetAttribute(ctx, path.Root("metric"), &metric)...)
+	diags.Append(plan.GetAttribute(ctx, path.Root("match_type"), &matchType)...)
+	diags.Append(plan.GetAttribute(ctx, path.Root("drop"), &drop)...)
+	diags.Append(plan.GetAttribute(ctx, path.Root("keep_labels"), &keepLabels)...)
+	diags.Append(plan.GetAttribute(ctx, path.Root("drop_labels"), &dropLabels)...)
+	if diags.HasError() {
+		return diags
+	}
+	for _, v := range []attr.Value{metric, matchType, drop, keepLabels, dropLabels} {
+		if v.IsUnknown() {
+			return diags
+		}
+	}
+
+	rule := model.AggregationRule{Metric: metric.ValueString(), MatchType: matchType.ValueString(), Drop: drop.ValueBool()}
+	diags.Append(keepLabels.ElementsAs(ctx, &rule.KeepLabels, false)...)
+	diags.Append(dropLabels.ElementsAs(ctx, &rule.DropLabels, false)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	diags.Append(validateRequiredKeepLabels(rule, r.requiredKeepLabels, path.Empty())...)
+	diags.Append(validateAllowedMetric(rule, r.allowedMetricPatterns, path.Empty())...)
+	return diags
+}
+
+func (r *multiCellRuleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.MultiCellRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rule := plan.ToAPIReq()
+	errs := r.forEachCell(plan.TargetCells, func(rules *AggregationRules) error {
+		return upsertRule(rules, rule)
+	})
+
+	plan.AppliedCells = appliedCells(plan.TargetURLs(), errs)
+	resp.Diagnostics.Append(cellDiagnostics("Unable to create aggregation rule in cell", errs)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *multiCellRuleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state model.MultiCellRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	expected := state.ToAPIReq()
+	applied := make([]types.String, 0, len(state.AppliedCells))
+	for _, url := range state.AppliedCells {
+		rule, err := r.readCell(state, url.ValueString(), expected.Metric)
+		var notFound errRuleNotFound
+		switch {
+		case errors.As(err, &notFound):
+			resp.Diagnostics.AddWarning("Aggregation rule missing from cell", fmt.Sprintf("%s: %s. It will be created again on the next apply.", url.ValueString(), err))
+		case err != nil:
+			// An unreachable cell is assumed to be unchanged, so that an
+			// outage of one cell does not affect the others.
+			resp.Diagnostics.AddWarning("Unable to read aggregation rule from cell", fmt.Sprintf("%s: %s", url.ValueString(), err))
+			applied = append(applied, url)
+		case !rule.Equal(expected):
+			resp.Diagnostics.AddWarning("Aggregation rule changed in cell", fmt.Sprintf("The rule for %q in %s was changed outside of Terraform. It will be updated on the next apply.", expected.Metric, url.ValueString()))
+		default:
+			applied = append(applied, url)
+		}
+	}
+
+	state.AppliedCells = applied
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *multiCellRuleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.MultiCellRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.MultiCellRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prior, rule := state.ToAPIReq(), plan.ToAPIReq()
+	wasApplied := make(map[string]bool, len(state.AppliedCells))
+	for _, url := range state.AppliedCells {
+		wasApplied[url.ValueString()] = true
+	}
+
+	errs := r.forEachCell(plan.TargetCells, func(rules *AggregationRules) error {
+		return upsertRule(rules, rule)
+	})
+	if prior.Metric != rule.Metric {
+		// The rule for the old metric is replaced in the cells it was
+		// applied to.
+		var renamed []model.TargetCellTF
+		for _, c := range plan.TargetCells {
+			if wasApplied[c.URL.ValueString()] {
+				renamed = append(renamed, c)
+			}
+		}
+		for url, err := range r.forEachCell(renamed, func(rules *AggregationRules) error {
+			return deleteRule(rules, prior)
+		}) {
+			if errs[url] == nil {
+				errs[url] = err
+			}
+		}
+	}
+	plan.AppliedCells = appliedCells(plan.TargetURLs(), errs)
+	resp.Diagnostics.Append(cellDiagnostics("Unable to update aggregation rule in cell", errs)...)
+
+	// The rule is deleted from the cells which are no longer targeted. Cells
+	// which fail are kept so that the deletion is retried.
+	var removed []model.TargetCellTF
+	for _, c := range state.TargetCells {
+		if wasApplied[c.URL.ValueString()] && !slices.Contains(plan.TargetURLs(), c.URL.ValueString()) {
+			removed = append(removed, c)
+		}
+	}
+	removeErrs := r.forEachCell(removed, func(rules *AggregationRules) error {
+		return deleteRule(rules, prior)
+	})
+	for _, c := range removed {
+		if removeErrs[c.URL.ValueString()] != nil {
+			plan.AppliedCells = append(plan.AppliedCells, c.URL)
+		}
+	}
+	resp.Diagnostics.Append(cellDiagnostics("Unable to delete aggregation rule from cell", removeErrs)...)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *multiCellRuleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.MultiCellRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var applied []model.TargetCellTF
+	for _, url := range state.AppliedCells {
+		applied = append(applied, state.Cell(url.ValueString()))
+	}
+
+	rule := state.ToAPIReq()
+	errs := r.forEachCell(applied, func(rules *AggregationRules) error {
+		return deleteRule(rules, rule)
+	})
+	if len(errs) == 0 {
+		return
+	}
+
+	// Only the cells which failed remain to be deleted.
+	resp.Diagnostics.Append(cellDiagnostics("Unable to delete aggregation rule from cell", errs)...)
+	state.AppliedCells = nil
+	for _, c := range applied {
+		if errs[c.URL.ValueString()] != nil {
+			state.AppliedCells = append(state.AppliedCells, c.URL)
+		}
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// forEachCell calls fn with the rules of every cell in parallel and returns
+// the errors keyed by the URL of the cell which failed.
+func (r *multiCellRuleResource) forEachCell(cells []model.TargetCellTF, fn func(rules *AggregationRules) error) map[string]error {
+	var mu sync.Mutex
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	for _, c := range cells {
+		wg.Add(1)
+		go func(c model.TargetCellTF) {
+			defer wg.Done()
+
+			rules, err := r.cells.get(c.URL.ValueString(), c.APIKey.ValueString())
+			if err == nil {
+				err = fn(rules)
+			}
+			if err != nil {
+				mu.Lock()
+				errs[c.URL.ValueString()] = err
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// readCell reads the rule for metric from the cell at url.
+func (r *multiCellRuleResource) readCell(state model.MultiCellRuleTF, url, metric string) (model.AggregationRule, error) {
+	c := state.Cell(url)
+	rules, err := r.cells.get(url, c.APIKey.ValueString())
+	if err != nil {
+		return model.AggregationRule{}, err
+	}
+	rule, err := rules.Read(metric)
+	if err != nil {
+		return rule, err
+	}
+	return rules.collapseAllAggregations(rule, state.ToAPIReq().Aggregations), nil
+}
+
+// upsertRule creates rule, or updates it if a rule for its metric exists.
+func upsertRule(rules *AggregationRules, rule model.AggregationRule) error {
+	exists, err := rules.Exists(rule.Metric)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return rules.Update(rule)
+	}
+	return rules.Create(rule)
+}
+
+// deleteRule deletes rule, which is considered deleted if it does not exist.
+func deleteRule(rules *AggregationRules, rule model.AggregationRule) error {
+	var notFound client.ErrNotFound
+	if err := rules.Delete(rule); err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+	return nil
+}
+
+// appliedCells returns the URLs which did not fail, in order.
+func appliedCells(urls []string, errs map[string]error) []types.String {
+	applied := make([]types.String, 0, len(urls))
+	for _, url := range urls {
+		if errs[url] == nil {
+			applied = append(applied, types.StringValue(url))
+		}
+	}
+	return applied
+}
+
+// cellDiagnostics reports every failed cell as an error, sorted by URL.
+func cellDiagnostics(summary string, errs map[string]error) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	urls := make([]string, 0, len(errs))
+	for url := range errs {
+		urls = append(urls, url)
+	}
+	slices.Sort(urls)
+
+	for _, url := range urls {
+		diags.AddError(summary, fmt.Sprintf("%s: %s", url, errs[url]))
+	}
+	return diags
+}
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func newTestMultiCellRuleResource(t *testing.T) (*multiCellRuleResource, tfsdk.State) {
+	t.Helper()
+
+	r := &multiCellRuleResource{cells: newCellRules("", func(url, _ string) (*client.Client, error) {
+		return client.New(url, &client.Config{})
+	})}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	return r, tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+func multiCellRule(metric string, cells ...*mockAPI) model.MultiCellRuleTF {
+	tf := model.MultiCellRuleTF{
+		Metric:              types.StringValue(metric),
+		MatchType:           types.StringValue(""),
+		Drop:                types.BoolValue(false),
+		KeepLabels:          []types.String{},
+		DropLabels:          []types.String{types.StringValue("pod")},
+		Aggregations:        []types.String{types.StringValue("sum")},
+		AggregationInterval: types.StringValue(""),
+		AggregationDelay:    types.StringValue(""),
+		Priority:            types.Int64Value(0),
+		AppliedCells:        []types.String{},
+	}
+	for _, c := range cells {
+		tf.TargetCells = append(tf.TargetCells, model.TargetCellTF{URL: types.StringValue(c.server.URL), APIKey: types.StringNull()})
+		tf.AppliedCells = append(tf.AppliedCells, types.StringValue(c.server.URL))
+	}
+	return tf
+}
+
+func TestCellRulesByAPIKey(t *testing.T) {
+	api := newMockAPI(t)
+	var keys []string
+	cells := newCellRules("", func(url, apiKey string) (*client.Client, error) {
+		keys = append(keys, apiKey)
+		return client.New(url, &client.Config{})
+	})
+
+	first, err := cells.get(api.server.URL, "first")
+	require.NoError(t, err)
+	second, err := cells.get(api.server.URL, "second")
+	require.NoError(t, err)
+	require.NotSame(t, first, second)
+
+	// The rules of a cell are loaded once per API key.
+	again, err := cells.get(api.server.URL, "first")
+	require.NoError(t, err)
+	require.Same(t, first, again)
+	require.Equal(t, []string{"first", "second"}, keys)
+}
+
+func TestMultiCellRuleResourceCreate(t *testing.T) {
+	ctx := context.Background()
+	good, bad := newMockAPI(t), newMockAPI(t)
+	bad.failOn(http.MethodPost, mockRulePathPrefix+"test_tf_metric", http.StatusInternalServerError)
+	r, empty := newTestMultiCellRuleResource(t)
+
+	plan := tfsdk.Plan{Schema: empty.Schema}
+	require.False(t, plan.Set(ctx, multiCellRule("test_tf_metric", good, bad)).HasError())
+
+	resp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+	require.Len(t, resp.Diagnostics.Errors(), 1)
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), bad.server.URL)
+
+	_, ok := good.rule("test_tf_metric")
+	require.True(t, ok)
+	require.Empty(t, bad.metrics())
+
+	// The cell which succeeded is kept in state.
+	var tf model.MultiCellRuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []types.String{types.StringValue(good.server.URL)}, tf.AppliedCells)
+}
+
+func TestMultiCellRuleResourceApplyWindow(t *testing.T) {
+	ctx := context.Background()
+	window, err := parseApplyWindow(ApplyWindowTF{Days: toTypesStrings([]string{"sat"}), Start: types.StringValue("10:00"), End: types.StringValue("12:00")})
+	require.NoError(t, err)
+	// A Monday.
+	window.now = func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) }
+
+	api := newMockAPI(t)
+	r, empty := newTestMultiCellRuleResource(t)
+	r.cells.window = window
+
+	plan := tfsdk.Plan{Schema: empty.Schema}
+	require.False(t, plan.Set(ctx, multiCellRule("test_tf_metric", api)).HasError())
+
+	// The rules of other cells cannot be changed outside of the window.
+	resp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "Sat 2026-03-07 10:00 UTC")
+	require.Empty(t, api.metrics())
+}
+
+func TestMultiCellRuleResourceRead(t *testing.T) {
+	ctx := context.Background()
+	rule := multiCellRule("test_tf_metric").ToAPIReq()
+	same := newMockAPI(t, rule)
+	missing := newMockAPI(t)
+	drifted := rule
+	drifted.DropLabels = []string{"namespace"}
+	changed := newMockAPI(t, drifted)
+	r, state := newTestMultiCellRuleResource(t)
+
+	require.False(t, state.Set(ctx, multiCellRule("test_tf_metric", same, missing, changed)).HasError())
+
+	resp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 2)
+
+	var tf model.MultiCellRuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []types.String{types.StringValue(same.server.URL)}, tf.AppliedCells)
+}
+
+func TestMultiCellRuleResourceUpdateRemovesCell(t *testing.T) {
+	ctx := context.Background()
+	rule := multiCellRule("test_tf_metric").ToAPIReq()
+	kept, removed := newMockAPI(t, rule), newMockAPI(t, rule)
+	r, state := newTestMultiCellRuleResource(t)
+
+	require.False(t, state.Set(ctx, multiCellRule("test_tf_metric", kept, removed)).HasError())
+	updated := multiCellRule("test_tf_metric", kept)
+	updated.DropLabels = []types.String{types.StringValue("namespace")}
+	plan := tfsdk.Plan{Schema: state.Schema}
+	require.False(t, plan.Set(ctx, updated).HasError())
+
+	resp := &fwresource.UpdateResponse{State: state}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: state}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	got, ok := kept.rule("test_tf_metric")
+	require.True(t, ok)
+	require.Equal(t, []string{"namespace"}, got.DropLabels)
+	require.Empty(t, removed.metrics())
+
+	var tf model.MultiCellRuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []types.String{types.StringValue(kept.server.URL)}, tf.AppliedCells)
+}
+
+func TestMultiCellRuleResourceDelete(t *testing.T) {
+	ctx := context.Background()
+	rule := multiCellRule("test_tf_metric").ToAPIReq()
+	good, bad := newMockAPI(t, rule), newMockAPI(t, rule)
+	bad.failOn(http.MethodDelete, mockRulePathPrefix+"test_tf_metric", http.StatusInternalServerError)
+	r, state := newTestMultiCellRuleResource(t)
+
+	require.False(t, state.Set(ctx, multiCellRule("test_tf_metric", good, bad)).HasError())
+
+	resp := &fwresource.DeleteResponse{State: state}
+	r.Delete(ctx, fwresource.DeleteRequest{State: state}, resp)
+	require.Len(t, resp.Diagnostics.Errors(), 1)
+	require.Empty(t, good.metrics())
+	require.Equal(t, []string{"test_tf_metric"}, bad.metrics())
+
+	// Only the cell which failed remains to be deleted.
+	var tf model.MultiCellRuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []types.String{types.StringValue(bad.server.URL)}, tf.AppliedCells)
+}
+
+func TestMultiCellRuleResourceModifyPlanPolicy(t *testing.T) {
+	cell := newMockAPI(t)
+	r, _ := newTestMultiCellRuleResource(t)
+	r.allowedMetricPatterns = []string{"team_a_.*"}
+	r.requiredKeepLabels = []string{"pod"}
+
+	resp := modifyPlan(t, r, nil, multiCellRule("team_a_up", cell))
+	require.Equal(t, []path.Path{path.Root("drop_labels").AtListIndex(0)}, errorPaths(resp.Diagnostics))
+
+	resp = modifyPlan(t, r, nil, multiCellRule("team_b_up", cell))
+	require.Equal(t, []path.Path{path.Root("drop_labels").AtListIndex(0), path.Root("metric")}, errorPaths(resp.Diagnostics))
+}
@@ -0,0 +1,445 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type multiMetricRuleResource struct {
+	rules *AggregationRules
+
+	requiredKeepLabels    []string
+	allowedMetricPatterns []string
+}
+
+var (
+	_ resource.Resource                   = &multiMetricRuleResource{}
+	_ resource.ResourceWithConfigure      = &multiMetricRuleResource{}
+	_ resource.ResourceWithImportState    = &multiMetricRuleResource{}
+	_ resource.ResourceWithModifyPlan     = &multiMetricRuleResource{}
+	_ resource.ResourceWithValidateConfig = &multiMetricRuleResource{}
+)
+
+func newMultiMetricRuleResource() resource.Resource {
+	return &multiMetricRuleResource{}
+}
+
+func (r *multiMetricRuleResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+}
+
+func (r *multiMetricRuleResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_multi_metric_rule", req.ProviderTypeName)
+}
+
+func (r *multiMetricRuleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages an exact match aggregation rule for each of several metrics, all with the same settings. The rules are saved with a single request to the bulk rules endpoint, so that either every change is applied or none of them; the provider's error_strategy does not apply. Existing rules may be imported by a comma-separated list of their metrics; otherwise the apply fails if a rule already exists for a listed metric, unless on_conflict is set to 'update'.",
+		Attributes: map[string]schema.Attribute{
+			"metrics": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "The names of the metrics to be aggregated. Each metric may only be listed once.",
+			},
+			"match_type": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString("exact"),
+				Description: "Specifies how the metrics match to incoming metric names. Only 'exact' is supported, which is the default.",
+			},
+
+			"drop": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultBoolFalse{},
+				Description: "Set to true to skip both ingestion and aggregation and drop the metrics entirely.",
+			},
+			"keep_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of labels to keep; labels not in this array will be aggregated.",
+			},
+			"drop_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of labels that will be aggregated.",
+			},
+
+			"aggregations": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultEmptyList{},
+				Description: "The array of aggregation types to calculate for these metrics. Percentiles are written as p<percent>, such as p99 or p99.9. Set to [\"*\"] to calculate every aggregation type supported by the API.",
+			},
+
+			"aggregation_interval": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "The interval at which to generate the aggregated series.",
+			},
+			"aggregation_delay": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(""),
+				Description: "The delay until aggregation is performed.",
+			},
+
+			"priority": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+				Description: "The priority of the rules. When several rules match a metric, the rule with the highest priority is applied. Must not be negative. Defaults to 0.",
+			},
+
+			"on_conflict": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(onConflictFail),
+				Description: "What happens when a rule already exists for a metric which the resource does not manage yet, when it is created or the metric is added to metrics. Can be 'fail', which fails the apply, or 'update', which overwrites the existing rule with the configuration. Imported rules are managed already. Defaults to 'fail'.",
+			},
+		},
+	}
+}
+
+func (r *multiMetricRuleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	if !req.Config.Raw.IsFullyKnown() {
+		return
+	}
+
+	var cfg model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if len(cfg.Metrics) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("metrics"), "No metrics", "At least one metric must be listed.")
+		return
+	}
+	resp.Diagnostics.Append(validateMetricList(cfg.Metrics, path.Root("metrics"))...)
+
+	switch cfg.OnConflict.ValueString() {
+	case "", onConflictFail, onConflictUpdate:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("on_conflict"),
+			"Invalid on_conflict",
+			fmt.Sprintf("on_conflict is %q; it must be one of 'fail' or 'update'.", cfg.OnConflict.ValueString()),
+		)
+	}
+
+	if mt := cfg.MatchType.ValueString(); !cfg.MatchType.IsNull() && mt != "exact" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("match_type"),
+			"Invalid match_type",
+			fmt.Sprintf("The match_type %q is not supported; rules for several metrics must use 'exact'.", mt),
+		)
+		return
+	}
+	resp.Diagnostics.Append(validateRule(cfg.ToAPIReq(cfg.Metrics[0].ValueString()), path.Empty())...)
+}
+
+func (r *multiMetricRuleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || !req.Plan.Raw.IsFullyKnown() {
+		return
+	}
+
+	var plan model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || len(plan.Metrics) == 0 {
+		return
+	}
+
+	// The rules only differ in their metric, so the labels are checked once.
+	rules := plan.ToAPIReqs()
+	resp.Diagnostics.Append(validateRequiredKeepLabels(rules[0], r.requiredKeepLabels, path.Empty())...)
+	for i, rule := range rules {
+		for _, d := range validateAllowedMetric(rule, r.allowedMetricPatterns, path.Empty()) {
+			resp.Diagnostics.AddAttributeError(path.Root("metrics").AtListIndex(i), d.Summary(), d.Detail())
+		}
+	}
+}
+
+func (r *multiMetricRuleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rules := r.rules.WithContext(ctx)
+	resp.Diagnostics.Append(checkExistingRules(rules, plan, nil)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := ruleSetChanges{Create: plan.ToAPIReqs()}
+	if err := rules.ApplyBulk(changes); err != nil {
+		resp.Diagnostics.Append(bulkRuleError("Unable to create aggregation rules", err, plan.MetricNames())...)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *multiMetricRuleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tf, err := r.currentState(ctx, state.MetricNames(), &state)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read aggregation rules", err.Error())
+		return
+	}
+	if len(tf.Metrics) == 0 {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, tf)...)
+}
+
+func (r *multiMetricRuleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rules := r.rules.WithContext(ctx)
+	resp.Diagnostics.Append(checkExistingRules(rules, plan, state.MetricNames())...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var changes ruleSetChanges
+	for _, rule := range plan.ToAPIReqs() {
+		existing, err := rules.Read(rule.Metric)
+		var notFound errRuleNotFound
+		switch {
+		case errors.As(err, &notFound):
+			changes.Create = append(changes.Create, rule)
+		case err != nil:
+			resp.Diagnostics.AddAttributeError(path.Root("metrics"), "Unable to read aggregation rule", fmt.Sprintf("%s: %s", rule.Metric, err))
+			return
+		case !normalizeExactRule(rules.collapseAllAggregations(existing, rule.Aggregations)).Equal(rule):
+			changes.Update = append(changes.Update, rule)
+		}
+	}
+	for _, metric := range state.MetricNames() {
+		if !slices.Contains(plan.MetricNames(), metric) {
+			changes.Delete = append(changes.Delete, model.AggregationRule{Metric: metric})
+		}
+	}
+
+	if !changes.empty() {
+		if err := rules.ApplyBulk(changes); err != nil {
+			resp.Diagnostics.Append(bulkRuleError("Unable to update aggregation rules", err, plan.MetricNames())...)
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *multiMetricRuleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.MultiMetricRuleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := ruleSetChanges{Delete: state.ToAPIReqs()}
+	if err := r.rules.WithContext(ctx).ApplyBulk(changes); err != nil {
+		resp.Diagnostics.Append(bulkRuleError("Unable to delete aggregation rules", err, state.MetricNames())...)
+	}
+}
+
+func (r *multiMetricRuleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	var metrics []string
+	for _, metric := range strings.Split(req.ID, ",") {
+		metric = strings.TrimSpace(metric)
+
+		rule, err := r.rules.WithContext(ctx).Read(metric)
+		var notFound errRuleNotFound
+		switch {
+		case errors.As(err, &notFound):
+			resp.Diagnostics.AddError("Aggregation rule not found", fmt.Sprintf("There is no aggregation rule for the metric %q.", metric))
+			return
+		case err != nil:
+			resp.Diagnostics.AddError("Unable to read aggregation rule", fmt.Sprintf("%s: %s", metric, err))
+			return
+		case normalizeExactRule(rule).MatchType != "exact":
+			resp.Diagnostics.AddError("Unsupported match_type", fmt.Sprintf("The rule for %q has match_type %q; only exact rules can be imported.", metric, rule.MatchType))
+			return
+		}
+		metrics = append(metrics, metric)
+	}
+
+	tf, err := r.currentState(ctx, metrics, nil)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read aggregation rules", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, tf)...)
+}
+
+// currentState builds the state from the existing rules of the given metrics,
+// in order. Metrics which no longer have a rule are left out, so that their
+// rule is created again on the next apply. The settings are replaced by those
+// of the first rule which differs from them, so that every rule is updated on
+// the next apply; without settings, those of the first rule are used.
+func (r *multiMetricRuleResource) currentState(ctx context.Context, metrics []string, settings *model.MultiMetricRuleTF) (model.MultiMetricRuleTF, error) {
+	rules := r.rules.WithContext(ctx)
+
+	var tf model.MultiMetricRuleTF
+	if settings != nil {
+		tf = *settings
+	}
+	tf.Metrics = []types.String{}
+	onConflict := tf.OnConflict
+	if onConflict.IsNull() {
+		onConflict = types.StringValue(onConflictFail)
+	}
+
+	drifted := false
+	for _, metric := range metrics {
+		rule, err := rules.Read(metric)
+		var notFound errRuleNotFound
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return model.MultiMetricRuleTF{}, fmt.Errorf("%s: %w", metric, err)
+		}
+		tf.Metrics = append(tf.Metrics, types.StringValue(metric))
+		if drifted {
+			continue
+		}
+
+		configured := tf.ToAPIReq(metric)
+		rule = rules.collapseAllAggregations(normalizeExactRule(rule), configured.Aggregations)
+		if settings != nil && rule.Equal(configured) {
+			continue
+		}
+		found := tf.Metrics
+		tf = rule.ToMultiMetricRuleTF(nil)
+		tf.Metrics = found
+		drifted = true
+	}
+	tf.OnConflict = onConflict
+	return tf, nil
+}
+
+// checkExistingRules reports an error for each metric of tf, other than the
+// managed ones, which already has a rule, as applying tf would overwrite it,
+// unless on_conflict is 'update'.
+func checkExistingRules(rules *AggregationRules, tf model.MultiMetricRuleTF, managed []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if tf.OnConflict.ValueString() == onConflictUpdate {
+		return diags
+	}
+
+	for i, metric := range tf.MetricNames() {
+		if slices.Contains(managed, metric) {
+			continue
+		}
+
+		_, err := rules.Read(metric)
+		var notFound errRuleNotFound
+		switch {
+		case errors.As(err, &notFound):
+		case err != nil:
+			diags.AddAttributeError(path.Root("metrics").AtListIndex(i), "Unable to read aggregation rule", fmt.Sprintf("%s: %s", metric, err))
+		default:
+			diags.AddAttributeError(
+				path.Root("metrics").AtListIndex(i),
+				"Aggregation rule already exists",
+				fmt.Sprintf("A rule for %q already exists and is not managed by this resource. Import it, or set on_conflict to 'update' to overwrite it with the configuration.", metric),
+			)
+		}
+	}
+	return diags
+}
+
+// normalizeExactRule spells out the default match type of rule, so that it
+// compares equal to a rule with match_type "exact".
+func normalizeExactRule(rule model.AggregationRule) model.AggregationRule {
+	if rule.MatchType == "" {
+		rule.MatchType = "exact"
+	}
+	return rule
+}
+
+// bulkRuleError reports an error applying the rules for metrics in bulk. The
+// reasons why the API rejected a rule are reported against its metric in the
+// metrics attribute; anything else is reported as a single error under
+// summary.
+func bulkRuleError(summary string, err error, metrics []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var rejected errBulkRejected
+	if !errors.As(err, &rejected) {
+		diags.AddError(summary, err.Error())
+		return diags
+	}
+
+	rejectedMetrics := make([]string, 0, len(rejected.rejected))
+	for metric := range rejected.rejected {
+		rejectedMetrics = append(rejectedMetrics, metric)
+	}
+	sort.Strings(rejectedMetrics)
+
+	for _, metric := range rejectedMetrics {
+		detail := fmt.Sprintf("The API rejected the rule for %q: %s", metric, strings.Join(rejected.rejected[metric], "; "))
+		if i := slices.Index(metrics, metric); i >= 0 {
+			diags.AddAttributeError(path.Root("metrics").AtListIndex(i), summary, detail)
+		} else {
+			diags.AddError(summary, detail)
+		}
+	}
+	return diags
+}
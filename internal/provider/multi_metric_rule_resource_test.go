@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func newTestMultiMetricRuleResource(t *testing.T, api *mockAPI) (*multiMetricRuleResource, tfsdk.State) {
+	t.Helper()
+
+	r := &multiMetricRuleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	return r, tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+func multiMetricRule(metrics ...string) model.MultiMetricRuleTF {
+	return model.MultiMetricRuleTF{
+		Metrics:             toTypesStrings(metrics),
+		MatchType:           types.StringValue("exact"),
+		Drop:                types.BoolValue(false),
+		KeepLabels:          []types.String{},
+		DropLabels:          []types.String{types.StringValue("pod")},
+		Aggregations:        []types.String{types.StringValue("sum")},
+		AggregationInterval: types.StringValue(""),
+		AggregationDelay:    types.StringValue(""),
+		Priority:            types.Int64Value(0),
+		OnConflict:          types.StringValue(onConflictFail),
+	}
+}
+
+// bulkRequests counts the requests to the bulk rules endpoint.
+func bulkRequests(api *mockAPI) int {
+	n := 0
+	for _, req := range api.requestLog() {
+		if req == "POST /aggregations/rules" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMultiMetricRuleResourceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "b", DropLabels: []string{"namespace"}},
+		model.AggregationRule{Metric: "unmanaged", Drop: true},
+	)
+	r, empty := newTestMultiMetricRuleResource(t, api)
+
+	// Create fails rather than replace the existing rule for b.
+	plan := tfsdk.Plan{Schema: empty.Schema}
+	require.False(t, plan.Set(ctx, multiMetricRule("a", "b")).HasError())
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.Equal(t, []path.Path{path.Root("metrics").AtListIndex(1)}, errorPaths(createResp.Diagnostics))
+	require.Equal(t, "Aggregation rule already exists", createResp.Diagnostics.Errors()[0].Summary())
+	require.Equal(t, 0, bulkRequests(api))
+	require.Equal(t, []string{"b", "unmanaged"}, api.metrics())
+
+	// With on_conflict, Create replaces it with a single request.
+	tf := multiMetricRule("a", "b")
+	tf.OnConflict = types.StringValue(onConflictUpdate)
+	require.False(t, plan.Set(ctx, tf).HasError())
+	createResp = &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	require.Equal(t, 1, bulkRequests(api))
+	require.Equal(t, []string{"a", "b", "unmanaged"}, api.metrics())
+	got, _ := api.rule("b")
+	require.Equal(t, []string{"pod"}, got.DropLabels)
+
+	// Update fails rather than take over an unmanaged rule.
+	plan = tfsdk.Plan{Schema: empty.Schema}
+	require.False(t, plan.Set(ctx, multiMetricRule("b", "unmanaged")).HasError())
+	updateResp := &fwresource.UpdateResponse{State: createResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: createResp.State}, updateResp)
+	require.Equal(t, []path.Path{path.Root("metrics").AtListIndex(1)}, errorPaths(updateResp.Diagnostics))
+	require.Equal(t, 1, bulkRequests(api))
+
+	// Update adds and removes individual metrics, leaving the rules which
+	// are unchanged and the unmanaged rule as they are.
+	plan = tfsdk.Plan{Schema: empty.Schema}
+	require.False(t, plan.Set(ctx, multiMetricRule("b", "c")).HasError())
+	updateResp = &fwresource.UpdateResponse{State: createResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: createResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	require.Equal(t, 2, bulkRequests(api))
+	require.Equal(t, []string{"b", "c", "unmanaged"}, api.metrics())
+	got, _ = api.rule("unmanaged")
+	require.True(t, got.Drop)
+
+	// Read drops the metrics whose rule was deleted, so that it is created
+	// again.
+	require.NoError(t, api.aggregationRules().Delete(model.AggregationRule{Metric: "c"}))
+	r.rules = api.aggregationRules()
+	readResp := &fwresource.ReadResponse{State: updateResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: updateResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []string{"b"}, tf.MetricNames())
+
+	// Delete removes the managed rules only.
+	deleteResp := &fwresource.DeleteResponse{}
+	r.Delete(ctx, fwresource.DeleteRequest{State: readResp.State}, deleteResp)
+	require.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+	require.Equal(t, []string{"unmanaged"}, api.metrics())
+}
+
+func TestMultiMetricRuleResourceReadDrift(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		multiMetricRule().ToAPIReq("a"),
+		model.AggregationRule{Metric: "b", MatchType: "exact", DropLabels: []string{"namespace"}, Aggregations: []string{"sum"}},
+	)
+	r, state := newTestMultiMetricRuleResource(t, api)
+	require.False(t, state.Set(ctx, multiMetricRule("a", "b")).HasError())
+
+	resp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	// The settings of the changed rule are read, so that every rule is
+	// updated on the next apply.
+	var tf model.MultiMetricRuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []string{"a", "b"}, tf.MetricNames())
+	require.Equal(t, []types.String{types.StringValue("namespace")}, tf.DropLabels)
+}
+
+func TestMultiMetricRuleResourceRejected(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "a", Drop: true})
+	api.reject("c", "metric is not ingested")
+	r, empty := newTestMultiMetricRuleResource(t, api)
+
+	plan := tfsdk.Plan{Schema: empty.Schema}
+	require.False(t, plan.Set(ctx, multiMetricRule("b", "c")).HasError())
+	resp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+
+	// The error is reported against the rejected metric, and none of the
+	// rules is applied.
+	require.Len(t, resp.Diagnostics.Errors(), 1)
+	d, ok := resp.Diagnostics.Errors()[0].(interface{ Path() path.Path })
+	require.True(t, ok)
+	require.Equal(t, path.Root("metrics").AtListIndex(1), d.Path())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), `The API rejected the rule for "c": metric: metric is not ingested`)
+	require.Equal(t, []string{"a"}, api.metrics())
+	require.True(t, resp.State.Raw.IsNull())
+}
+
+func TestMultiMetricRuleResourceImport(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		multiMetricRule().ToAPIReq("a"),
+		model.AggregationRule{Metric: "b", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "prefix_", MatchType: "prefix"},
+	)
+	r, empty := newTestMultiMetricRuleResource(t, api)
+
+	resp := &fwresource.ImportStateResponse{State: empty}
+	r.ImportState(ctx, fwresource.ImportStateRequest{ID: "a, b"}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.MultiMetricRuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, multiMetricRule("a", "b"), tf)
+
+	for id, summary := range map[string]string{
+		"a,missing": "Aggregation rule not found",
+		"prefix_":   "Unsupported match_type",
+	} {
+		resp := &fwresource.ImportStateResponse{State: empty}
+		r.ImportState(ctx, fwresource.ImportStateRequest{ID: id}, resp)
+		require.True(t, resp.Diagnostics.HasError())
+		require.Equal(t, summary, resp.Diagnostics.Errors()[0].Summary())
+	}
+}
+
+func TestMultiMetricRuleResourceValidateConfig(t *testing.T) {
+	ctx := context.Background()
+
+	prefix := multiMetricRule("a")
+	prefix.MatchType = types.StringValue("prefix")
+	adopt := multiMetricRule("a")
+	adopt.OnConflict = types.StringValue(onConflictAdopt)
+	for name, tc := range map[string]struct {
+		tf       model.MultiMetricRuleTF
+		expected []string
+	}{
+		"valid":      {tf: multiMetricRule("a", "b")},
+		"no metrics": {tf: multiMetricRule(), expected: []string{"No metrics"}},
+		"duplicate":  {tf: multiMetricRule("a", "a"), expected: []string{"Duplicate metric"}},
+		"prefix":     {tf: prefix, expected: []string{"Invalid match_type"}},
+		"adopt":      {tf: adopt, expected: []string{"Invalid on_conflict"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, state := newTestMultiMetricRuleResource(t, newMockAPI(t))
+			require.False(t, state.Set(ctx, tc.tf).HasError())
+
+			resp := &fwresource.ValidateConfigResponse{}
+			r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Schema: state.Schema, Raw: state.Raw}}, resp)
+			var summaries []string
+			for _, d := range resp.Diagnostics.Errors() {
+				summaries = append(summaries, d.Summary())
+			}
+			require.Equal(t, tc.expected, summaries)
+		})
+	}
+}
+
+func TestMultiMetricRuleResourceModifyPlanPolicy(t *testing.T) {
+	r, _ := newTestMultiMetricRuleResource(t, newMockAPI(t))
+	r.allowedMetricPatterns = []string{"team_a_.*"}
+	r.requiredKeepLabels = []string{"cluster"}
+
+	resp := modifyPlan(t, r, nil, multiMetricRule("team_a_up", "team_b_up"))
+	require.Equal(t, []path.Path{path.Root("metrics").AtListIndex(1)}, errorPaths(resp.Diagnostics))
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "team_b_up")
+
+	tf := multiMetricRule("team_a_up", "team_a_down")
+	tf.DropLabels = []types.String{types.StringValue("cluster")}
+	resp = modifyPlan(t, r, nil, tf)
+	require.Equal(t, []path.Path{path.Root("drop_labels").AtListIndex(0)}, errorPaths(resp.Diagnostics))
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type orphanedSeriesDatasource struct {
+	client       *client.Client
+	capabilities *capabilities
+	rules        *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &orphanedSeriesDatasource{}
+	_ datasource.DataSourceWithConfigure = &orphanedSeriesDatasource{}
+)
+
+func newOrphanedSeriesDatasource() datasource.DataSource {
+	return &orphanedSeriesDatasource{}
+}
+
+func (d *orphanedSeriesDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = data.client
+	d.capabilities = data.capabilities
+	d.rules = data.aggRules
+}
+
+func (d *orphanedSeriesDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_orphaned_series", req.ProviderTypeName)
+}
+
+func (d *orphanedSeriesDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists the metrics whose aggregated series are no longer produced by any aggregation rule, for example because the rule was deleted, so that they can be cleaned up. Nothing is changed.\n\n" +
+			"The API lists the metrics which have aggregated series, but not the rule which produced them, so orphans are found by cross-referencing that list with the existing rules: a metric is orphaned unless an aggregation rule matches it by its `match_type`. Rules which drop their metric and archived rules do not produce aggregated series, so they do not count. A metric may therefore be listed for a short time after its rule was changed, until its aggregated series expire. Requires a backend which lists the metrics with aggregated series.",
+		Attributes: map[string]schema.Attribute{
+			"orphans": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The metrics with aggregated series but no aggregation rule, ordered by metric.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the metric.",
+						},
+						"series": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The number of aggregated series of the metric.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *orphanedSeriesDatasource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	resp.Diagnostics.Append(d.capabilities.require(client.FeatureAggregatedMetrics)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	metrics, err := d.client.AggregatedMetrics()
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to list aggregated metrics", err.Error())
+		return
+	}
+
+	var aggregating []model.AggregationRule
+	for _, rule := range d.rules.List() {
+		if !rule.Drop && !rule.Archived {
+			aggregating = append(aggregating, rule)
+		}
+	}
+
+	state := model.OrphanedSeriesTF{Orphans: []model.AggregatedMetricTF{}}
+	for _, m := range metrics {
+		// Metrics outside of the provider's metric prefix are not managed by
+		// it, like the rules of such metrics.
+		if !strings.HasPrefix(m.Metric, d.rules.metricPrefix) {
+			continue
+		}
+		m.Metric = strings.TrimPrefix(m.Metric, d.rules.metricPrefix)
+
+		governed := false
+		for _, rule := range aggregating {
+			if rulesOverlap(model.AggregationRule{Metric: m.Metric}, rule) {
+				governed = true
+				break
+			}
+		}
+		if !governed {
+			state.Orphans = append(state.Orphans, m.ToTF())
+		}
+	}
+	sort.Slice(state.Orphans, func(i, j int) bool {
+		return state.Orphans[i].Metric.ValueString() < state.Orphans[j].Metric.ValueString()
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
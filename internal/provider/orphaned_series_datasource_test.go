@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readOrphanedSeries(t *testing.T, d *orphanedSeriesDatasource) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{}, resp)
+	return resp
+}
+
+func TestOrphanedSeriesDatasource(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "prod_http_requests_total", DropLabels: []string{"pod"}},
+		model.AggregationRule{Metric: "prod_kube_", MatchType: "prefix", DropLabels: []string{"pod"}},
+		model.AggregationRule{Metric: "prod_node_cpu_seconds_total", Drop: true},
+		model.AggregationRule{Metric: "prod_queue_depth", DropLabels: []string{"pod"}, Archived: true},
+	)
+	api.aggregatedMetrics = []model.AggregatedMetric{
+		{Metric: "prod_queue_depth", Series: 4},
+		{Metric: "prod_http_requests_total", Series: 10},
+		{Metric: "prod_kube_pod_info", Series: 20},
+		{Metric: "prod_node_cpu_seconds_total", Series: 8},
+		{Metric: "prod_deleted_metric", Series: 2},
+		{Metric: "staging_deleted_metric", Series: 1},
+	}
+	rules := NewAggregationRules(api.client(), "prod_")
+	require.NoError(t, rules.Init())
+
+	resp := readOrphanedSeries(t, &orphanedSeriesDatasource{client: api.client(), rules: rules})
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.OrphanedSeriesTF
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.Equal(t, []model.AggregatedMetricTF{
+		{Metric: types.StringValue("deleted_metric"), Series: types.Int64Value(2)},
+		{Metric: types.StringValue("node_cpu_seconds_total"), Series: types.Int64Value(8)},
+		{Metric: types.StringValue("queue_depth"), Series: types.Int64Value(4)},
+	}, tf.Orphans)
+}
+
+func TestOrphanedSeriesDatasourceUnavailable(t *testing.T) {
+	api := newMockAPI(t)
+
+	resp := readOrphanedSeries(t, &orphanedSeriesDatasource{client: api.client(), rules: api.aggregationRules()})
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Unable to list aggregated metrics", resp.Diagnostics.Errors()[0].Summary())
+
+	// Backends without the endpoint are detected before it is requested.
+	resp = readOrphanedSeries(t, &orphanedSeriesDatasource{client: api.client(), capabilities: newCapabilities(api.client()), rules: api.aggregationRules()})
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Feature not supported by backend", resp.Diagnostics.Errors()[0].Summary())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support listing the metrics with aggregated series")
+
+	api.features = []string{string(client.FeatureRecommendations)}
+	resp = readOrphanedSeries(t, &orphanedSeriesDatasource{client: api.client(), capabilities: newCapabilities(api.client()), rules: api.aggregationRules()})
+	require.Equal(t, "Feature not supported by backend", resp.Diagnostics.Errors()[0].Summary())
+}
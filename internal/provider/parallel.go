@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// parallel calls fn for every index below n, at most limit at a time, and
+// returns the error of each call by index, so that the results do not depend
+// on the order in which the calls complete. A limit of zero or less runs every
+// call at once. Once ctx is cancelled, the calls which have not started yet
+// are skipped and fail with the error of ctx.
+//
+// Data sources pass the provider's max_concurrent_requests as the limit, so
+// that one with thousands of entries neither starts a goroutine nor sends a
+// request for each of them at once.
+func parallel(ctx context.Context, limit, n int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < limit; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = fn(ctx, i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// inFlightBarrier counts the calls of parallel in flight. Every call waits
+// until want calls are in flight at once, so that whether the limit is reached
+// does not depend on how long the calls take.
+type inFlightBarrier struct {
+	want    int
+	reached chan struct{}
+	once    sync.Once
+
+	mu                sync.Mutex
+	inFlight, maxSeen int
+}
+
+func newInFlightBarrier(want int) *inFlightBarrier {
+	return &inFlightBarrier{want: want, reached: make(chan struct{})}
+}
+
+// call waits for the barrier, failing if it is not reached within a second.
+func (b *inFlightBarrier) call() error {
+	b.mu.Lock()
+	b.inFlight++
+	b.maxSeen = max(b.maxSeen, b.inFlight)
+	if b.inFlight == b.want {
+		b.once.Do(func() { close(b.reached) })
+	}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}()
+
+	select {
+	case <-b.reached:
+		return nil
+	case <-time.After(time.Second):
+		return errors.New("the calls in flight never reached the limit")
+	}
+}
+
+func TestParallel(t *testing.T) {
+	barrier := newInFlightBarrier(3)
+	errs := parallel(context.Background(), 3, 10, func(_ context.Context, i int) error {
+		if err := barrier.call(); err != nil {
+			return err
+		}
+		if i%4 == 0 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	require.LessOrEqual(t, barrier.maxSeen, 3)
+	require.Len(t, errs, 10)
+	for i, err := range errs {
+		if i%4 == 0 {
+			require.EqualError(t, err, "failed", i)
+		} else {
+			require.NoError(t, err, i)
+		}
+	}
+}
+
+func TestParallelCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var mu sync.Mutex
+	var called []int
+	errs := parallel(ctx, 1, 5, func(_ context.Context, i int) error {
+		mu.Lock()
+		called = append(called, i)
+		mu.Unlock()
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	// The calls after the cancellation are not started.
+	require.Equal(t, []int{0, 1}, called)
+	require.Equal(t, []error{nil, nil, context.Canceled, context.Canceled, context.Canceled}, errs)
+}
+
+func TestParallelUnlimited(t *testing.T) {
+	// Every call is in flight at once.
+	barrier := newInFlightBarrier(5)
+	errs := parallel(context.Background(), 0, 5, func(_ context.Context, _ int) error {
+		return barrier.call()
+	})
+	require.Equal(t, make([]error, 5), errs)
+	require.Equal(t, 5, barrier.maxSeen)
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const unmanagedListDescription = "When left unset (null), the value is not managed: it is not sent to the API and any existing value is kept. Set it to `[]` to explicitly manage an empty list."
+
+const unmanagedMapDescription = "When left unset (null), the value is not managed: it is not sent to the API and any existing value is kept. Set it to `{}` to explicitly manage an empty map."
+
+// useStateWhenUnset plans a bool, list, set or map which is not configured with
+// its prior value, so that values which are not managed by Terraform never show
+// up as changes. When there is no prior value, it is planned as null.
+type useStateWhenUnset struct{}
+
+var (
+	_ planmodifier.Bool = useStateWhenUnset{}
+	_ planmodifier.List = useStateWhenUnset{}
+	_ planmodifier.Set  = useStateWhenUnset{}
+	_ planmodifier.Map  = useStateWhenUnset{}
+)
+
+func (m useStateWhenUnset) Description(_ context.Context) string {
+	return "value is kept unchanged when not configured"
+}
+
+func (m useStateWhenUnset) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m useStateWhenUnset) PlanModifyBool(_ context.Context, req planmodifier.BoolRequest, resp *planmodifier.BoolResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	if req.StateValue.IsNull() {
+		resp.PlanValue = types.BoolNull()
+		return
+	}
+	resp.PlanValue = req.StateValue
+}
+
+func (m useStateWhenUnset) PlanModifyList(_ context.Context, req planmodifier.ListRequest, resp *planmodifier.ListResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	if req.StateValue.IsNull() {
+		resp.PlanValue = types.ListNull(types.StringType)
+		return
+	}
+	resp.PlanValue = req.StateValue
+}
+
+func (m useStateWhenUnset) PlanModifySet(_ context.Context, req planmodifier.SetRequest, resp *planmodifier.SetResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	if req.StateValue.IsNull() {
+		resp.PlanValue = types.SetNull(types.StringType)
+		return
+	}
+	resp.PlanValue = req.StateValue
+}
+
+func (m useStateWhenUnset) PlanModifyMap(_ context.Context, req planmodifier.MapRequest, resp *planmodifier.MapResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	if req.StateValue.IsNull() {
+		resp.PlanValue = types.MapNull(types.StringType)
+		return
+	}
+	resp.PlanValue = req.StateValue
+}
+
+// metricFromMatchPrefixes plans the metric of a rule which is configured with
+// match_prefixes instead as its first prefix, which identifies the rule in the
+// API.
+type metricFromMatchPrefixes struct{}
+
+var _ planmodifier.String = metricFromMatchPrefixes{}
+
+func (m metricFromMatchPrefixes) Description(_ context.Context) string {
+	return "value is the first of match_prefixes when not configured"
+}
+
+func (m metricFromMatchPrefixes) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m metricFromMatchPrefixes) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if !req.ConfigValue.IsNull() {
+		return
+	}
+
+	var prefixes types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("match_prefixes"), &prefixes)...)
+	if resp.Diagnostics.HasError() || prefixes.IsNull() || prefixes.IsUnknown() || len(prefixes.Elements()) == 0 {
+		return
+	}
+	if first, ok := prefixes.Elements()[0].(types.String); ok {
+		resp.PlanValue = first
+	}
+}
+
+// useStateForHint plans a computed hint of the API with its prior value, even
+// when that is null, as the hint does not depend on the configuration. It is
+// only unknown until the resource has been created.
+type useStateForHint struct{}
+
+var _ planmodifier.String = useStateForHint{}
+
+func (m useStateForHint) Description(_ context.Context) string {
+	return "value is kept unchanged once the resource exists"
+}
+
+func (m useStateForHint) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m useStateForHint) PlanModifyString(_ context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if req.State.Raw.IsNull() {
+		return
+	}
+	resp.PlanValue = req.StateValue
+}
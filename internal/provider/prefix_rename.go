@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+const (
+	onConflictFail      = "fail"
+	onConflictSkip      = "skip"
+	onConflictOverwrite = "overwrite"
+)
+
+// prefixRename are the requests needed to rename the metrics of the rules
+// starting with one prefix to start with another.
+type prefixRename struct {
+	// Renames are the rules to rename, each with its new metric.
+	Renames []ruleRename
+	// Overwrite are the existing rules for the new metrics, which are deleted
+	// so that the renamed rules replace them.
+	Overwrite []model.AggregationRule
+	// Skipped are the metrics of the rules which are not renamed, as a rule
+	// for their new metric exists.
+	Skipped []string
+}
+
+type ruleRename struct {
+	From, To model.AggregationRule
+}
+
+// planPrefixRename computes the renames of the rules among current whose metric
+// or match prefixes start with from, so that they start with to instead. Only
+// the rules managed by Terraform are renamed unless includeUnmanaged is set.
+// When from is a prefix of to, the rules which already start with to are left
+// as they are, so that renaming again has no effect. onConflict tells what
+// happens when a rule exists for a new metric: an error is returned, the rule
+// is skipped, or the existing rule is overwritten.
+func planPrefixRename(current []model.AggregationRule, from, to, onConflict string, includeUnmanaged bool) (prefixRename, error) {
+	rewrite := func(metric string) string {
+		if !strings.HasPrefix(metric, from) || (strings.HasPrefix(to, from) && strings.HasPrefix(metric, to)) {
+			return metric
+		}
+		return to + strings.TrimPrefix(metric, from)
+	}
+
+	existing := make(map[string]model.AggregationRule, len(current))
+	for _, rule := range current {
+		existing[rule.Metric] = rule
+	}
+
+	var rename prefixRename
+	var conflicts []string
+	for _, rule := range current {
+		if !rule.IsManaged() && !includeUnmanaged {
+			continue
+		}
+
+		next := rule
+		next.Metric = rewrite(rule.Metric)
+		if rule.MatchPrefixes != nil {
+			next.MatchPrefixes = make([]string, len(rule.MatchPrefixes))
+			for i, prefix := range rule.MatchPrefixes {
+				next.MatchPrefixes[i] = rewrite(prefix)
+			}
+			// The API identifies the rule by its first prefix.
+			next.Metric = next.MatchPrefixes[0]
+		}
+		if next.Equal(rule) {
+			continue
+		}
+
+		if next.Metric != rule.Metric {
+			if target, ok := existing[next.Metric]; ok {
+				switch onConflict {
+				case onConflictSkip:
+					rename.Skipped = append(rename.Skipped, rule.Metric)
+					continue
+				case onConflictOverwrite:
+					rename.Overwrite = append(rename.Overwrite, target)
+				default:
+					conflicts = append(conflicts, fmt.Sprintf("%s to %s", rule.Metric, next.Metric))
+					continue
+				}
+			}
+		}
+		rename.Renames = append(rename.Renames, ruleRename{From: rule, To: next})
+	}
+
+	if len(conflicts) > 0 {
+		return prefixRename{}, fmt.Errorf("rules exist for the new metrics of %d rules, renaming %s", len(conflicts), strings.Join(conflicts, ", "))
+	}
+	return rename, nil
+}
+
+// renamedMetrics maps the old metric of each rule renamed by r to its new
+// one.
+func (r prefixRename) renamedMetrics() map[string]string {
+	metrics := make(map[string]string, len(r.Renames))
+	for _, rename := range r.Renames {
+		metrics[rename.From.Metric] = rename.To.Metric
+	}
+	return metrics
+}
+
+// apply makes the renames of r with rules. It stops at the first request that
+// fails, and returns the renames made until then.
+func (r prefixRename) apply(rules *AggregationRules) (map[string]string, error) {
+	for _, target := range r.Overwrite {
+		if err := rules.Delete(target); err != nil {
+			return nil, fmt.Errorf("failed to delete the rule for %s to overwrite it: %w", target.Metric, err)
+		}
+	}
+
+	done := make(map[string]string, len(r.Renames))
+	for _, rename := range r.Renames {
+		var err error
+		if rename.From.ID != "" || rename.From.Metric == rename.To.Metric {
+			// Updating a rule by its ID renames it in place, and a rule
+			// keeping its metric only has its match prefixes changed.
+			err = rules.Update(rename.To)
+		} else {
+			err = rules.Rename(rename.From, rename.To)
+		}
+		if err != nil {
+			return done, fmt.Errorf("failed to rename the rule for %s to %s: %w", rename.From.Metric, rename.To.Metric, err)
+		}
+		done[rename.From.Metric] = rename.To.Metric
+	}
+	return done, nil
+}
@@ -0,0 +1,237 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type prefixRenameResource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ resource.Resource                   = &prefixRenameResource{}
+	_ resource.ResourceWithConfigure      = &prefixRenameResource{}
+	_ resource.ResourceWithValidateConfig = &prefixRenameResource{}
+	_ resource.ResourceWithModifyPlan     = &prefixRenameResource{}
+)
+
+func newPrefixRenameResource() resource.Resource {
+	return &prefixRenameResource{}
+}
+
+func (r *prefixRenameResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+}
+
+func (r *prefixRenameResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_prefix_rename", req.ProviderTypeName)
+}
+
+func (r *prefixRenameResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Renames every aggregation rule whose metric starts with one prefix so that it starts with another, such as when a service renames its metrics. The rules are renamed once, when the resource is created or its prefixes change; destroying the resource does not rename them back. The rule resources managing the renamed rules keep finding them by ID or name, but their metric must be updated in the configuration, for example with replace(), or the next apply renames them back.",
+		Attributes: map[string]schema.Attribute{
+			"from_prefix": schema.StringAttribute{
+				Required:    true,
+				Description: "The prefix of the metrics of the rules to rename.",
+			},
+			"to_prefix": schema.StringAttribute{
+				Required:    true,
+				Description: "The prefix which replaces from_prefix in the metrics of the renamed rules. The rules whose metric already starts with it are left as they are when it starts with from_prefix.",
+			},
+			"on_conflict": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(onConflictFail),
+				Description: "What happens when a rule already exists for the new metric of a renamed rule: 'fail' reports an error before any rule is renamed, 'skip' leaves the rule with its old metric, and 'overwrite' deletes the existing rule and renames the rule in its place. Defaults to 'fail'.",
+			},
+			"include_unmanaged": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to also rename the rules which are not managed by Terraform. Only the rules managed by Terraform are renamed otherwise.",
+			},
+
+			"renamed": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The new metric of each renamed rule, keyed by its old metric.",
+			},
+			"skipped": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics of the rules which were not renamed, as a rule already existed for their new metric.",
+			},
+		},
+	}
+}
+
+func (r *prefixRenameResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg model.PrefixRenameTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !cfg.FromPrefix.IsUnknown() && cfg.FromPrefix.ValueString() == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("from_prefix"), "Invalid from_prefix", "from_prefix must not be empty, as every rule would be renamed.")
+	}
+	if !cfg.FromPrefix.IsUnknown() && !cfg.ToPrefix.IsUnknown() && cfg.FromPrefix.Equal(cfg.ToPrefix) {
+		resp.Diagnostics.AddAttributeError(path.Root("to_prefix"), "Invalid to_prefix", "to_prefix must differ from from_prefix.")
+	}
+
+	switch cfg.OnConflict.ValueString() {
+	case "", onConflictFail, onConflictSkip, onConflictOverwrite:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("on_conflict"),
+			"Invalid on_conflict",
+			fmt.Sprintf("on_conflict is %q; it must be one of 'fail', 'skip', or 'overwrite'.", cfg.OnConflict.ValueString()),
+		)
+	}
+}
+
+func (r *prefixRenameResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.rules == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan model.PrefixRenameTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.FromPrefix.IsUnknown() || plan.ToPrefix.IsUnknown() ||
+		plan.OnConflict.IsUnknown() || plan.IncludeUnmanaged.IsUnknown() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state model.PrefixRenameTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		// The rules have been renamed already.
+		if state.FromPrefix.Equal(plan.FromPrefix) && state.ToPrefix.Equal(plan.ToPrefix) &&
+			state.OnConflict.Equal(plan.OnConflict) && state.IncludeUnmanaged.Equal(plan.IncludeUnmanaged) {
+			return
+		}
+	}
+
+	rename, err := planFor(plan, r.rules.List())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("to_prefix"), "Conflicting aggregation rules", err.Error()+". Set on_conflict to 'skip' or 'overwrite' to rename the other rules.")
+		return
+	}
+
+	resp.Diagnostics.Append(setPrefixRename(ctx, &plan, rename.renamedMetrics(), rename.Skipped)...)
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+func (r *prefixRenameResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.PrefixRenameTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.rename(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *prefixRenameResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+	// The renames are kept as they were made; the rules themselves are
+	// managed by their own resources.
+}
+
+func (r *prefixRenameResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.PrefixRenameTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.rename(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *prefixRenameResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// The rules are not renamed back.
+}
+
+// rename renames the rules as planned and records the renames in plan. The
+// renames are computed again from the current rules, and nothing is renamed
+// if they differ from the planned ones.
+func (r *prefixRenameResource) rename(ctx context.Context, plan *model.PrefixRenameTF) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	rename, err := planFor(*plan, r.rules.List())
+	if err != nil {
+		diags.AddError("Conflicting aggregation rules", err.Error())
+		return diags
+	}
+
+	var planned map[string]string
+	if !plan.Renamed.IsUnknown() {
+		diags.Append(plan.Renamed.ElementsAs(ctx, &planned, false)...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+	if renamed := rename.renamedMetrics(); !plan.Renamed.IsUnknown() && !maps.Equal(renamed, planned) {
+		diags.AddError(
+			"Aggregation rules changed since plan",
+			fmt.Sprintf("The rules to rename from %q to %q changed after the plan was created, so no rule has been renamed. Run the plan again to review the renames.", plan.FromPrefix.ValueString(), plan.ToPrefix.ValueString()),
+		)
+		return diags
+	}
+
+	renamed, err := rename.apply(r.rules.WithContext(ctx).WithWarnings(apiWarnings(&diags)))
+	if err != nil {
+		diags.AddError("Unable to rename aggregation rules", err.Error())
+	}
+	// The renames made before a failure are kept in state.
+	diags.Append(setPrefixRename(ctx, plan, renamed, rename.Skipped)...)
+	return diags
+}
+
+// planFor computes the renames of tf among the current rules.
+func planFor(tf model.PrefixRenameTF, current []model.AggregationRule) (prefixRename, error) {
+	return planPrefixRename(current, tf.FromPrefix.ValueString(), tf.ToPrefix.ValueString(), tf.OnConflict.ValueString(), tf.IncludeUnmanaged.ValueBool())
+}
+
+func setPrefixRename(ctx context.Context, tf *model.PrefixRenameTF, renamed map[string]string, skipped []string) diag.Diagnostics {
+	var diags, d diag.Diagnostics
+	if renamed == nil {
+		renamed = map[string]string{}
+	}
+	tf.Renamed, d = types.MapValueFrom(ctx, types.StringType, renamed)
+	diags.Append(d...)
+	if skipped == nil {
+		skipped = []string{}
+	}
+	tf.Skipped, d = types.ListValueFrom(ctx, types.StringType, skipped)
+	diags.Append(d...)
+	return diags
+}
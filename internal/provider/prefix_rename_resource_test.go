@@ -0,0 +1,225 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestPlanPrefixRename(t *testing.T) {
+	managed := func(rule model.AggregationRule) model.AggregationRule {
+		rule.ManagedBy = "terraform"
+		return rule
+	}
+	current := []model.AggregationRule{
+		managed(model.AggregationRule{Metric: "api_errors_total"}),
+		managed(model.AggregationRule{Metric: "api_requests_total", DropLabels: []string{"pod"}}),
+		managed(model.AggregationRule{Metric: "api_latency", MatchPrefixes: []string{"api_latency", "db_latency"}}),
+		model.AggregationRule{Metric: "api_unmanaged_total"},
+		managed(model.AggregationRule{Metric: "checkout_errors_total", Drop: true}),
+		managed(model.AggregationRule{Metric: "db_queries_total"}),
+	}
+
+	for _, tc := range []struct {
+		name             string
+		from, to         string
+		onConflict       string
+		includeUnmanaged bool
+
+		expectRenamed   map[string]string
+		expectOverwrite []string
+		expectSkipped   []string
+		expectError     string
+	}{
+		{
+			name: "rename",
+			from: "api_", to: "gateway_",
+			expectRenamed: map[string]string{
+				"api_errors_total":   "gateway_errors_total",
+				"api_requests_total": "gateway_requests_total",
+				"api_latency":        "gateway_latency",
+			},
+		},
+		{
+			name: "include unmanaged",
+			from: "api_", to: "gateway_", includeUnmanaged: true,
+			expectRenamed: map[string]string{
+				"api_errors_total":    "gateway_errors_total",
+				"api_requests_total":  "gateway_requests_total",
+				"api_latency":         "gateway_latency",
+				"api_unmanaged_total": "gateway_unmanaged_total",
+			},
+		},
+		{
+			name: "match prefixes",
+			from: "db_", to: "postgres_",
+			expectRenamed: map[string]string{
+				// The rule keeps its metric, only its second prefix is
+				// renamed.
+				"api_latency":      "api_latency",
+				"db_queries_total": "postgres_queries_total",
+			},
+		},
+		{
+			name: "conflict fails",
+			from: "api_", to: "checkout_",
+			expectError: "rules exist for the new metrics of 1 rules, renaming api_errors_total to checkout_errors_total",
+		},
+		{
+			name: "conflict skipped",
+			from: "api_", to: "checkout_", onConflict: onConflictSkip,
+			expectRenamed: map[string]string{
+				"api_requests_total": "checkout_requests_total",
+				"api_latency":        "checkout_latency",
+			},
+			expectSkipped: []string{"api_errors_total"},
+		},
+		{
+			name: "conflict overwritten",
+			from: "api_", to: "checkout_", onConflict: onConflictOverwrite,
+			expectRenamed: map[string]string{
+				"api_errors_total":   "checkout_errors_total",
+				"api_requests_total": "checkout_requests_total",
+				"api_latency":        "checkout_latency",
+			},
+			expectOverwrite: []string{"checkout_errors_total"},
+		},
+		{
+			name: "to extends from",
+			from: "api_", to: "api_errors_",
+			expectRenamed: map[string]string{
+				"api_requests_total": "api_errors_requests_total",
+				"api_latency":        "api_errors_latency",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			onConflict := tc.onConflict
+			if onConflict == "" {
+				onConflict = onConflictFail
+			}
+
+			rename, err := planPrefixRename(current, tc.from, tc.to, onConflict, tc.includeUnmanaged)
+			if tc.expectError != "" {
+				require.EqualError(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expectRenamed, rename.renamedMetrics())
+			var overwrite []string
+			for _, rule := range rename.Overwrite {
+				overwrite = append(overwrite, rule.Metric)
+			}
+			require.Equal(t, tc.expectOverwrite, overwrite)
+			require.Equal(t, tc.expectSkipped, rename.Skipped)
+
+			for _, r := range rename.Renames {
+				require.Equal(t, r.From.DropLabels, r.To.DropLabels, "renamed rules keep their configuration")
+			}
+		})
+	}
+}
+
+func newTestPrefixRenameResource(t *testing.T, api *mockAPI) (*prefixRenameResource, tfsdk.State) {
+	t.Helper()
+
+	r := &prefixRenameResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	return r, tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+func prefixRenameConfig(from, to, onConflict string) model.PrefixRenameTF {
+	return model.PrefixRenameTF{
+		FromPrefix:       types.StringValue(from),
+		ToPrefix:         types.StringValue(to),
+		OnConflict:       types.StringValue(onConflict),
+		IncludeUnmanaged: types.BoolNull(),
+		Renamed:          types.MapUnknown(types.StringType),
+		Skipped:          types.ListUnknown(types.StringType),
+	}
+}
+
+func TestPrefixRenameResourceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{ID: "1", Metric: "api_requests_total", DropLabels: []string{"pod"}, ManagedBy: "terraform"},
+		model.AggregationRule{Metric: "api_errors_total", Aggregations: []string{"count"}, ManagedBy: "terraform"},
+		model.AggregationRule{Metric: "gateway_errors_total", Drop: true, ManagedBy: "terraform"},
+		model.AggregationRule{Metric: "api_unmanaged_total"},
+	)
+	r, empty := newTestPrefixRenameResource(t, api)
+
+	// The conflict on gateway_errors_total is reported during plan.
+	resp := modifyPlan(t, r, nil, prefixRenameConfig("api_", "gateway_", onConflictFail))
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Conflicting aggregation rules", resp.Diagnostics.Errors()[0].Summary())
+
+	resp = modifyPlan(t, r, nil, prefixRenameConfig("api_", "gateway_", onConflictOverwrite))
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	var planned model.PrefixRenameTF
+	require.False(t, resp.Plan.Get(ctx, &planned).HasError())
+	var renamed map[string]string
+	require.False(t, planned.Renamed.ElementsAs(ctx, &renamed, false).HasError())
+	require.Equal(t, map[string]string{
+		"api_requests_total": "gateway_requests_total",
+		"api_errors_total":   "gateway_errors_total",
+	}, renamed)
+
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: resp.Plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+
+	require.Equal(t, []string{"api_unmanaged_total", "gateway_errors_total", "gateway_requests_total"}, api.metrics())
+	got, _ := api.rule("gateway_requests_total")
+	require.Equal(t, "1", got.ID, "the rule with an ID is renamed in place")
+	require.Equal(t, []string{"pod"}, got.DropLabels)
+	got, _ = api.rule("gateway_errors_total")
+	require.Equal(t, model.Aggregations{"count"}, got.Aggregations, "the existing rule is overwritten")
+	require.False(t, got.Drop)
+	require.Contains(t, api.requestLog(), "PUT /aggregations/rules/1")
+
+	var state model.PrefixRenameTF
+	require.False(t, createResp.State.Get(ctx, &state).HasError())
+	require.True(t, state.Renamed.Equal(planned.Renamed))
+
+	// Planning again keeps the renames in state, although the rules no
+	// longer match from_prefix.
+	resp = modifyPlan(t, r, state, state)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	var replanned model.PrefixRenameTF
+	require.False(t, resp.Plan.Get(ctx, &replanned).HasError())
+	require.Equal(t, state, replanned)
+}
+
+func TestPrefixRenameResourceChangedSincePlan(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "api_requests_total", ManagedBy: "terraform"},
+	)
+	r, empty := newTestPrefixRenameResource(t, api)
+
+	resp := modifyPlan(t, r, nil, prefixRenameConfig("api_", "gateway_", onConflictFail))
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	require.NoError(t, api.aggregationRules().Create(model.AggregationRule{Metric: "api_errors_total", ManagedBy: "terraform"}))
+	r.rules = api.aggregationRules()
+
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: resp.Plan}, createResp)
+	require.True(t, createResp.Diagnostics.HasError())
+	require.Equal(t, "Aggregation rules changed since plan", createResp.Diagnostics.Errors()[0].Summary())
+	require.Equal(t, []string{"api_errors_total", "api_requests_total"}, api.metrics())
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// progress logs how many of the changes of a reconciliation have been made,
+// at most once per interval, so that a long apply does not appear to hang.
+// Nothing is logged if the interval is zero.
+type progress struct {
+	total    int
+	done     int
+	interval time.Duration
+	last     time.Time
+
+	// now and report are replaced in tests.
+	now    func() time.Time
+	report func(done, total int)
+}
+
+func newProgress(ctx context.Context, total int, interval time.Duration) *progress {
+	return &progress{
+		total:    total,
+		interval: interval,
+		last:     time.Now(),
+		now:      time.Now,
+		report: func(done, total int) {
+			tflog.Info(ctx, fmt.Sprintf("Reconciled %d/%d aggregation rules", done, total))
+		},
+	}
+}
+
+// step records that a change has been made, whether or not it succeeded.
+func (p *progress) step() {
+	p.done++
+	if p.interval <= 0 {
+		return
+	}
+
+	if now := p.now(); now.Sub(p.last) >= p.interval {
+		p.last = now
+		p.report(p.done, p.total)
+	}
+}
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval time.Duration
+		expected []int
+	}{
+		{name: "reported every interval", interval: 10 * time.Second, expected: []int{3, 6, 9}},
+		{name: "disabled", interval: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+			p := newProgress(context.Background(), 10, tc.interval)
+			p.last = start
+
+			// Every change takes 4 seconds.
+			steps := 0
+			p.now = func() time.Time { return start.Add(time.Duration(steps) * 4 * time.Second) }
+			var reported []int
+			p.report = func(done, total int) {
+				require.Equal(t, 10, total)
+				reported = append(reported, done)
+			}
+
+			for steps = 1; steps <= 10; steps++ {
+				p.step()
+			}
+			require.Equal(t, tc.expected, reported)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// queryMetrics returns the names of the metrics selected by a PromQL
+// expression, in the order in which they are first referenced. Selectors
+// which do not match a single metric name, such as {job="api"} or
+// {__name__=~"http_.*"}, select no metric in particular and are skipped.
+func queryMetrics(expr string) ([]string, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []string
+	seen := make(map[string]bool)
+	parser.Inspect(node, func(node parser.Node, _ []parser.Node) error {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		// The name of the metric is a matcher, whether it is written before
+		// the braces or inside them, quoted or as __name__.
+		for _, m := range selector.LabelMatchers {
+			if m.Name == labels.MetricName && m.Type == labels.MatchEqual && !seen[m.Value] {
+				seen[m.Value] = true
+				metrics = append(metrics, m.Value)
+			}
+		}
+		return nil
+	})
+	return metrics, nil
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMetrics(t *testing.T) {
+	for _, tc := range []struct {
+		expr    string
+		metrics []string
+	}{
+		{expr: "up", metrics: []string{"up"}},
+		{expr: `http_requests_total{job="api", code=~"5.."}`, metrics: []string{"http_requests_total"}},
+		{expr: `sum by (job) (rate(http_requests_total[5m])) / on(job) group_left(team) team_info`, metrics: []string{"http_requests_total", "team_info"}},
+		{expr: `sum(rate(a[5m])) without (pod) + sum without (pod) (rate(b[1h:5m] offset 1d))`, metrics: []string{"a", "b"}},
+		{expr: `histogram_quantile(0.99, sum by (le) (rate(duration_seconds_bucket[5m])))`, metrics: []string{"duration_seconds_bucket"}},
+		{expr: `{__name__="queue_depth", job="worker"} > 1e+3 and queue_depth @ 1609746000`, metrics: []string{"queue_depth"}},
+		{expr: `{"legacy.metric", job="api"}`, metrics: []string{"legacy.metric"}},
+		{expr: `label_replace(up, "dst", "$1", "src", "(.*)")`, metrics: []string{"up"}},
+		{expr: `topk(5, job:requests:rate5m) # recording rule`, metrics: []string{"job:requests:rate5m"}},
+		{expr: `count({job="api"}) or vector(0)`},
+		{expr: `-0x1f * .5 - inf`},
+		{expr: `a * 0x10 + b`, metrics: []string{"a", "b"}},
+		{expr: `{__name__=~"http_.*"}`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			metrics, err := queryMetrics(tc.expr)
+			require.NoError(t, err)
+			require.Equal(t, tc.metrics, metrics)
+		})
+	}
+}
+
+func TestQueryMetricsInvalid(t *testing.T) {
+	for expr, msg := range map[string]string{
+		`sum(rate(a[5m])`:     "1:16: parse error: unclosed left parenthesis",
+		`rate(a[5m]))`:        "1:13: parse error: unexpected right parenthesis ')'",
+		`a[5m`:                "1:5: parse error: unclosed left bracket",
+		`a{job="api"`:         "1:12: parse error: unexpected end of input inside braces",
+		`a{job}`:              `1:6: parse error: unexpected "}" in label matching, expected label matching operator`,
+		`a{job="api}`:         "1:7: parse error: unterminated quoted string",
+		`sum by (job, 1) (a)`: `1:14: parse error: unexpected number "1" in grouping opts, expected label`,
+		`a ! b`:               "1:3: parse error: unexpected character after '!': ' '",
+		`a ; b`:               "1:3: parse error: unexpected character: ';'",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := queryMetrics(expr)
+			require.EqualError(t, err, msg)
+		})
+	}
+}
@@ -7,12 +7,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -22,7 +31,11 @@ import (
 )
 
 // Ensure AdaptiveMetricsProvider satisfies various provider interfaces.
-var _ provider.Provider = &AdaptiveMetricsProvider{}
+var (
+	_ provider.Provider                   = &AdaptiveMetricsProvider{}
+	_ provider.ProviderWithFunctions      = &AdaptiveMetricsProvider{}
+	_ provider.ProviderWithValidateConfig = &AdaptiveMetricsProvider{}
+)
 
 // AdaptiveMetricsProvider defines the provider implementation.
 type AdaptiveMetricsProvider struct {
@@ -30,15 +43,58 @@ type AdaptiveMetricsProvider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+
+	mu sync.Mutex
+	// stats are those of the clients of every configured instance of the
+	// provider which emits an operation summary.
+	stats []*client.Stats
+	// locks are the apply locks of every configured instance of the
+	// provider which sets lock_id.
+	locks []*applyLock
+	// httpLogs are those of every configured instance of the provider which
+	// sets debug_log_file, keyed by path, so that instances logging to the
+	// same file share it.
+	httpLogs map[string]*client.HTTPLog
+	// auditLogs are those of every configured instance of the provider
+	// which sets audit_log_file, keyed by path, like httpLogs.
+	auditLogs map[string]*auditLog
 }
 
 // AdaptiveMetricsProviderModel describes the provider data model.
 type AdaptiveMetricsProviderModel struct {
 	URL         types.String `tfsdk:"url"`
+	ReadURL     types.String `tfsdk:"read_url"`
 	APIKey      types.String `tfsdk:"api_key"`
 	HTTPHeaders types.Map    `tfsdk:"http_headers"`
 	Retries     types.Int64  `tfsdk:"retries"`
 	Debug       types.Bool   `tfsdk:"debug"`
+	AutoImport  types.Bool   `tfsdk:"auto_import"`
+
+	MetricPrefix          types.String   `tfsdk:"metric_prefix"`
+	BroadMatchMinLength   types.Int64    `tfsdk:"broad_match_min_length"`
+	MinSeriesCount        types.Int64    `tfsdk:"min_series_count"`
+	MaxSavingsReduction   types.Int64    `tfsdk:"max_savings_reduction"`
+	SavingsReductionCheck types.String   `tfsdk:"savings_reduction_check"`
+	SchemaValidate        types.Bool     `tfsdk:"schema_validate"`
+	AggregationDelayCheck types.String   `tfsdk:"aggregation_delay_check"`
+	WarnOnDropDrift       types.Bool     `tfsdk:"warn_on_drop_drift"`
+	EnforceOwnership      types.Bool     `tfsdk:"enforce_ownership"`
+	ErrorStrategy         types.String   `tfsdk:"error_strategy"`
+	RequiredKeepLabels    types.List     `tfsdk:"required_keep_labels"`
+	AllowedMetricPatterns types.List     `tfsdk:"allowed_metric_patterns"`
+	ApplyWindow           *ApplyWindowTF `tfsdk:"apply_window"`
+	LockID                types.String   `tfsdk:"lock_id"`
+	LockTimeout           types.String   `tfsdk:"lock_timeout"`
+
+	RequestIDPrefix      types.String `tfsdk:"request_id_prefix"`
+	EmitOperationSummary types.Bool   `tfsdk:"emit_operation_summary"`
+	DebugLogFile         types.String `tfsdk:"debug_log_file"`
+	AuditLogFile         types.String `tfsdk:"audit_log_file"`
+	ProgressInterval     types.String `tfsdk:"progress_interval"`
+
+	MaxIdleConns          types.Int64  `tfsdk:"max_idle_conns"`
+	MaxConcurrentRequests types.Int64  `tfsdk:"max_concurrent_requests"`
+	IdleConnTimeout       types.String `tfsdk:"idle_conn_timeout"`
 
 	UserAgent types.String `json:"-" tfsdk:"-"`
 }
@@ -56,6 +112,39 @@ func getStringOverriddenByEnvOrDefault(s types.String, envKey string, valDefault
 	return valDefault
 }
 
+// validateAPIURL checks that raw is an absolute http or https URL.
+func validateAPIURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("got %q; it must be an absolute http or https URL, such as https://my-prometheus-url.net", raw)
+	}
+	return nil
+}
+
+// getStringListOverriddenByEnv returns the elements of l, or of the
+// comma-separated list in the environment variable envKey if it is set.
+func getStringListOverriddenByEnv(l types.List, envKey string) []string {
+	var values []string
+	if val, ok := os.LookupEnv(envKey); ok {
+		for _, v := range strings.Split(val, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+
+	for _, v := range l.Elements() {
+		if vStr, ok := v.(types.String); ok {
+			values = append(values, vStr.ValueString())
+		}
+	}
+	return values
+}
+
 func getIntOverriddenByEnvOrDefault(s types.Int64, envKey string, valDefault int) (int, error) {
 	val, ok := os.LookupEnv(envKey)
 	if ok {
@@ -82,8 +171,12 @@ func getBooleanOverriddenByEnvOrDefault(s types.Bool, envKey string, valDefault
 	return valDefault, nil
 }
 
+// providerTypeName prefixes the type names of the resources and data sources
+// of the provider.
+const providerTypeName = "grafana-adaptive-metrics"
+
 func (p *AdaptiveMetricsProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
-	resp.TypeName = "grafana-adaptive-metrics"
+	resp.TypeName = providerTypeName
 	resp.Version = p.version
 }
 
@@ -94,6 +187,10 @@ func (p *AdaptiveMetricsProvider) Schema(_ context.Context, _ provider.SchemaReq
 				Optional:            true,
 				MarkdownDescription: "Grafana Cloud's API URL. May alternatively be set via the `GRAFANA_AM_API_URL` environment variable.",
 			},
+			"read_url": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "An optional read-optimized API URL, such as a read replica, to which every GET request is sent while changes are still sent to `url`. The reads must reflect the changes made through `url`, otherwise the rules are reported as changed concurrently. Defaults to `url`. May alternatively be set via the `GRAFANA_AM_READ_URL` environment variable.",
+			},
 			"api_key": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
@@ -113,10 +210,246 @@ func (p *AdaptiveMetricsProvider) Schema(_ context.Context, _ provider.SchemaReq
 				Optional:            true,
 				MarkdownDescription: "Whether to enable debug logging. Defaults to false.",
 			},
+			"max_idle_conns": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum number of idle connections kept open to the Grafana Cloud API for reuse. Defaults to 10. May alternatively be set via the `GRAFANA_AM_MAX_IDLE_CONNS` environment variable.",
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum number of requests made at once by data sources which fan out to many requests, such as `rule_validation` validating many rules. The requests of resources are not limited, as Terraform's `-parallelism` already bounds them. Defaults to 4. May alternatively be set via the `GRAFANA_AM_MAX_CONCURRENT_REQUESTS` environment variable.",
+			},
+			"idle_conn_timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How long an idle connection is kept open before it is closed, as a duration such as `90s`. Defaults to `90s`. May alternatively be set via the `GRAFANA_AM_IDLE_CONN_TIMEOUT` environment variable.",
+			},
+			"auto_import": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Default value of `auto_import` for all rule resources; a value set on the resource takes precedence. Defaults to false. May alternatively be set via the `GRAFANA_AM_AUTO_IMPORT` environment variable.",
+			},
+			"metric_prefix": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "A prefix, such as `prod_`, prepended to the metric of every aggregation rule sent to the API and stripped from every rule read back, so the same configuration can be used across environments. Rules whose metric does not start with the prefix are ignored. Suffix rules cannot be used with a prefix. May alternatively be set via the `GRAFANA_AM_METRIC_PREFIX` environment variable.",
+			},
+			"broad_match_min_length": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The minimum length of the metric of a prefix or suffix rule. Shorter rules are rejected during plan unless `allow_broad_match` is set on the resource. Defaults to 3. May alternatively be set via the `GRAFANA_AM_BROAD_MATCH_MIN_LENGTH` environment variable.",
+			},
+			"required_keep_labels": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				MarkdownDescription: "Labels, such as `cluster` or `namespace`, which every aggregation rule must retain. A rule with `keep_labels` which does not keep all of them, or with `drop_labels` which drops one of them, is rejected during plan. Rules which drop their metric entirely are not checked. The default rule is checked as well. May alternatively be set via the `GRAFANA_AM_REQUIRED_KEEP_LABELS` environment variable as a comma-separated list.",
+			},
+			"allowed_metric_patterns": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				MarkdownDescription: "Regular expressions, such as `team_a_.*`, of the metrics which aggregation rules may be managed for. Each must match the whole metric, as configured without the `metric_prefix`. A rule whose metric, or any of whose `match_prefixes`, matches none of them is rejected during plan. The default rule applies to every metric, so it is only allowed by a pattern which matches the empty string, such as `.*`. Every metric is allowed if unset or empty. May alternatively be set via the `GRAFANA_AM_ALLOWED_METRIC_PATTERNS` environment variable as a comma-separated list.",
+			},
+			"apply_window": schema.SingleNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "A recurring maintenance window outside of which aggregation rules are not created, updated, or deleted. A change applied outside of the window fails with an error which tells when the window opens next. Only the `rule`, `rule_set`, and `transactional_rule_set` resources can override the window with `force_outside_window`; every other resource which changes aggregation rules, including those of other cells, is blocked. Exemptions and the default rule are not aggregation rules, so they are never blocked. Plans and refreshes are not affected. The window is checked against the clock of the machine running Terraform, so it guards against mistakes rather than enforcing a policy.",
+				Attributes: map[string]schema.Attribute{
+					"days": schema.ListAttribute{
+						ElementType:         types.StringType,
+						Optional:            true,
+						MarkdownDescription: "The days on which the window opens, as `mon`, `tue`, `wed`, `thu`, `fri`, `sat`, or `sun`. Defaults to every day.",
+					},
+					"start": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "The time of day at which the window opens, such as `09:00`.",
+					},
+					"end": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "The time of day at which the window closes, such as `17:00`. A window whose end is before its start closes on the following day.",
+					},
+					"time_zone": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "The IANA time zone of `start` and `end`, such as `Europe/Berlin`. Defaults to `UTC`.",
+					},
+				},
+			},
+			"lock_id": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The name of a lock of the backend, such as `prod-rules`, which the provider acquires before it first changes an aggregation rule and releases when it exits, so that concurrent applies configured with the same `lock_id` do not change the rules at the same time. A change made while another run holds the lock fails with an error naming its holder. Plans and refreshes do not acquire the lock. The lock is released within a second of the run ending; a lock left behind by a run which did not exit cleanly, or whose release failed, is held until the backend expires it. Requires a backend which supports apply locks. May alternatively be set via the `GRAFANA_AM_LOCK_ID` environment variable.",
+			},
+			"lock_timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How long to wait for the lock named by `lock_id` while another run holds it, as a duration such as `5m`. Defaults to `0s`, so that a change fails at once if the lock is held. May alternatively be set via the `GRAFANA_AM_LOCK_TIMEOUT` environment variable.",
+			},
+			"min_series_count": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The number of series below which a new or changed aggregation rule is reported with a warning during plan, as a rule for a metric with few series saves little and may be unnecessary. The series are counted by the API's estimate of the rule, so nothing is reported when no estimate is available. The warning is informational only. Defaults to 0, which disables the check. May alternatively be set via the `GRAFANA_AM_MIN_SERIES_COUNT` environment variable.",
+			},
+			"max_savings_reduction": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The number of saved series by which the planned changes of aggregation rules may reduce their savings in total, such as when many rules are deleted at once, before the plan is reported as set by `savings_reduction_check`. The changes of `rule`, `rule_set`, `rules_file`, `recommendation_bundle`, and confirmed `rules_restore` resources are counted. The reduction of each rule is its saved series before the change, by the API's estimate, less those after it; a deleted or archived rule saves none. Increases in the savings of other rules do not offset it, as Terraform plans each resource separately. Once the total exceeds the limit, every resource whose changes reduce the savings further is reported, on the attribute which plans them. Rules which cannot be estimated are not counted. Unset by default, which disables the check. May alternatively be set via the `GRAFANA_AM_MAX_SAVINGS_REDUCTION` environment variable.",
+			},
+			"savings_reduction_check": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How a plan which reduces the savings of aggregation rules by more than `max_savings_reduction` is reported. Can be `error` or `warn`. Defaults to `warn`. May alternatively be set via the `GRAFANA_AM_SAVINGS_REDUCTION_CHECK` environment variable.",
+			},
+			"request_id_prefix": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "A prefix of the random ID sent in the `X-Request-ID` header of every API request. The ID is included in error messages and debug logs so that a failed request can be found in the backend logs. May alternatively be set via the `GRAFANA_AM_REQUEST_ID_PREFIX` environment variable.",
+			},
+			"emit_operation_summary": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to log the number of API requests made by each kind of operation, their errors, and their latencies at info level when the provider exits, for example to understand why a large apply was slow. Nothing is sent anywhere but the Terraform log. Defaults to false. May alternatively be set via the `GRAFANA_AM_EMIT_OPERATION_SUMMARY` environment variable.",
+			},
+			"debug_log_file": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The path of a file to which a line of JSON is appended for every API request, with its method, URL, request headers, response status, and duration, and the response body of failed requests, to attach to a bug report without enabling `TF_LOG`. The values of headers which may carry credentials, such as `Authorization`, are redacted, and request and response bodies are otherwise not logged. The file is created if needed, readable by the current user only. May alternatively be set via the `GRAFANA_AM_DEBUG_LOG_FILE` environment variable.",
+			},
+			"audit_log_file": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The path of a file to which a line of JSON is appended for every aggregation rule the provider creates, updates, or deletes, with the time, the operation, the metric, the cell for multi-cell rules, the rule before and after the change, and the fields which changed, as an audit trail which supplements the Terraform state history. A rule which the provider has not listed is read before it is updated, so that the rule before the change is recorded. Rollbacks of failed changes are recorded like any other change. Each line is appended with a single write, so the changes of concurrent resources and runs sharing the file are never interleaved. The file is created if needed, readable by the current user only. May alternatively be set via the `GRAFANA_AM_AUDIT_LOG_FILE` environment variable.",
+			},
+			"progress_interval": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How often a resource that manages several rules at once, such as `rule_set`, `rules_file`, or `recommendation_bundle`, logs how many of its changes it has made at info level, such as `Reconciled 150/1000 aggregation rules`, so that a long apply does not appear to hang. The changes are made one request at a time, subject to `retries` like any other request. Defaults to `10s`; `0s` disables the log. May alternatively be set via the `GRAFANA_AM_PROGRESS_INTERVAL` environment variable.",
+			},
+			"aggregation_delay_check": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How a rule whose `aggregation_delay` is shorter than its `aggregation_interval` is reported during plan, as such rules produce incomplete aggregations. Can be `error` or `warn`. Defaults to `error`. May alternatively be set via the `GRAFANA_AM_AGGREGATION_DELAY_CHECK` environment variable.",
+			},
+			"warn_on_drop_drift": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to warn when refreshing an aggregation rule whose `drop` value was changed outside of Terraform, as the metric is then dropped entirely instead of aggregated, or the other way around. Defaults to false. May alternatively be set via the `GRAFANA_AM_WARN_ON_DROP_DRIFT` environment variable.",
+			},
+			"enforce_ownership": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to warn when refreshing an aggregation rule which is not marked as managed by Terraform. The provider marks every rule it creates or updates with `managed_by = \"terraform\"`, so a rule without the marker was most likely re-created outside of Terraform, and a rule marked with another owner was taken over by another tool. The marker is restored the next time the rule is updated. Defaults to false. May alternatively be set via the `GRAFANA_AM_ENFORCE_OWNERSHIP` environment variable.",
+			},
+			"error_strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "What to do when a request fails while a resource that manages several rules at once, such as `rule_set`, `rules_file`, `recommendation_bundle`, or `exemptions`, applies its changes. With `fail_fast`, the remaining changes are not attempted. With `continue`, every change is attempted and all failures are reported together. Either way the resource fails, its state is refreshed on the next plan, and Terraform carries on with other resources that do not depend on it as usual. Defaults to `fail_fast`. May alternatively be set via the `GRAFANA_AM_ERROR_STRATEGY` environment variable.",
+			},
+			"schema_validate": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Whether to check every aggregation rule against a schema of the API during plan, so that malformed rules are reported before any request is made. The schema is maintained with the provider and may lag behind the API, so this is opt-in. Defaults to false. May alternatively be set via the `GRAFANA_AM_SCHEMA_VALIDATE` environment variable.",
+			},
 		},
 	}
 }
 
+// ValidateConfig checks the provider configuration for mistakes which can be
+// found without the API, so that terraform validate reports them against their
+// attribute. As in Configure, the environment variables take precedence over
+// the configuration. Configurations with unknown values, such as values only
+// known once other resources are applied, are checked in Configure instead.
+func (p *AdaptiveMetricsProvider) ValidateConfig(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	if !req.Config.Raw.IsFullyKnown() {
+		return
+	}
+
+	var cfg AdaptiveMetricsProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	apiURL := getStringOverriddenByEnvOrDefault(cfg.URL, "GRAFANA_AM_API_URL", "")
+	readURL := getStringOverriddenByEnvOrDefault(cfg.ReadURL, "GRAFANA_AM_READ_URL", "")
+	switch {
+	case apiURL == "" && readURL != "":
+		resp.Diagnostics.AddAttributeError(path.Root("url"), "Missing required attribute 'url'", "read_url only replaces url for the requests which read rules, so url must be set as well. This may alternatively be set via the `GRAFANA_AM_API_URL` environment variable.")
+	case apiURL == "":
+		resp.Diagnostics.AddAttributeError(path.Root("url"), "Missing required attribute 'url'", "This may alternatively be set via the `GRAFANA_AM_API_URL` environment variable.")
+	default:
+		if err := validateAPIURL(apiURL); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("url"), "Invalid url", err.Error())
+		}
+	}
+	if readURL != "" {
+		if err := validateAPIURL(readURL); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("read_url"), "Invalid read_url", err.Error())
+		}
+	}
+
+	for _, attr := range []struct {
+		name  string
+		value types.Bool
+		env   string
+	}{
+		{"debug", cfg.Debug, "GRAFANA_AM_DEBUG"},
+		{"auto_import", cfg.AutoImport, "GRAFANA_AM_AUTO_IMPORT"},
+		{"schema_validate", cfg.SchemaValidate, "GRAFANA_AM_SCHEMA_VALIDATE"},
+		{"emit_operation_summary", cfg.EmitOperationSummary, "GRAFANA_AM_EMIT_OPERATION_SUMMARY"},
+		{"warn_on_drop_drift", cfg.WarnOnDropDrift, "GRAFANA_AM_WARN_ON_DROP_DRIFT"},
+		{"enforce_ownership", cfg.EnforceOwnership, "GRAFANA_AM_ENFORCE_OWNERSHIP"},
+	} {
+		if _, err := getBooleanOverriddenByEnvOrDefault(attr.value, attr.env, false); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(attr.name), "Failed to parse "+attr.env, err.Error())
+		}
+	}
+
+	for _, attr := range []struct {
+		name  string
+		value types.Int64
+		env   string
+	}{
+		{"retries", cfg.Retries, "GRAFANA_AM_RETRIES"},
+		{"max_idle_conns", cfg.MaxIdleConns, "GRAFANA_AM_MAX_IDLE_CONNS"},
+		{"max_concurrent_requests", cfg.MaxConcurrentRequests, "GRAFANA_AM_MAX_CONCURRENT_REQUESTS"},
+		{"broad_match_min_length", cfg.BroadMatchMinLength, "GRAFANA_AM_BROAD_MATCH_MIN_LENGTH"},
+		{"min_series_count", cfg.MinSeriesCount, "GRAFANA_AM_MIN_SERIES_COUNT"},
+		{"max_savings_reduction", cfg.MaxSavingsReduction, "GRAFANA_AM_MAX_SAVINGS_REDUCTION"},
+	} {
+		v, err := getIntOverriddenByEnvOrDefault(attr.value, attr.env, 0)
+		switch {
+		case err != nil:
+			resp.Diagnostics.AddAttributeError(path.Root(attr.name), "Failed to parse "+attr.env, err.Error())
+		case v < 0:
+			resp.Diagnostics.AddAttributeError(path.Root(attr.name), "Invalid "+attr.name, fmt.Sprintf("Got %d; it must not be negative.", v))
+		}
+	}
+
+	for _, attr := range []struct {
+		name  string
+		value types.String
+		env   string
+	}{
+		{"idle_conn_timeout", cfg.IdleConnTimeout, "GRAFANA_AM_IDLE_CONN_TIMEOUT"},
+		{"progress_interval", cfg.ProgressInterval, "GRAFANA_AM_PROGRESS_INTERVAL"},
+		{"lock_timeout", cfg.LockTimeout, "GRAFANA_AM_LOCK_TIMEOUT"},
+	} {
+		raw := getStringOverriddenByEnvOrDefault(attr.value, attr.env, "0s")
+		d, err := time.ParseDuration(raw)
+		switch {
+		case err != nil:
+			resp.Diagnostics.AddAttributeError(path.Root(attr.name), "Failed to parse "+attr.name, err.Error())
+		case d < 0:
+			resp.Diagnostics.AddAttributeError(path.Root(attr.name), "Invalid "+attr.name, fmt.Sprintf("Got %q; it must not be negative.", raw))
+		}
+	}
+
+	if v := getStringOverriddenByEnvOrDefault(cfg.AggregationDelayCheck, "GRAFANA_AM_AGGREGATION_DELAY_CHECK", "error"); v != "error" && v != "warn" {
+		resp.Diagnostics.AddAttributeError(path.Root("aggregation_delay_check"), "Invalid aggregation_delay_check", fmt.Sprintf("Got %q; it must be one of 'error' or 'warn'.", v))
+	}
+	if v := getStringOverriddenByEnvOrDefault(cfg.SavingsReductionCheck, "GRAFANA_AM_SAVINGS_REDUCTION_CHECK", "warn"); v != "error" && v != "warn" {
+		resp.Diagnostics.AddAttributeError(path.Root("savings_reduction_check"), "Invalid savings_reduction_check", fmt.Sprintf("Got %q; it must be one of 'error' or 'warn'.", v))
+	}
+	if v := getStringOverriddenByEnvOrDefault(cfg.ErrorStrategy, "GRAFANA_AM_ERROR_STRATEGY", "fail_fast"); v != "fail_fast" && v != "continue" {
+		resp.Diagnostics.AddAttributeError(path.Root("error_strategy"), "Invalid error_strategy", fmt.Sprintf("Got %q; it must be one of 'fail_fast' or 'continue'.", v))
+	}
+
+	for i, pattern := range getStringListOverriddenByEnv(cfg.AllowedMetricPatterns, "GRAFANA_AM_ALLOWED_METRIC_PATTERNS") {
+		if _, err := regexp.Compile(pattern); err != nil {
+			// Patterns from the environment variable have no element to point at.
+			p := path.Root("allowed_metric_patterns")
+			if _, ok := os.LookupEnv("GRAFANA_AM_ALLOWED_METRIC_PATTERNS"); !ok {
+				p = p.AtListIndex(i)
+			}
+			resp.Diagnostics.AddAttributeError(p, "Invalid allowed_metric_patterns", fmt.Sprintf("The pattern %q is not a valid regular expression: %s.", pattern, err))
+		}
+	}
+
+	if cfg.ApplyWindow != nil {
+		if _, err := parseApplyWindow(*cfg.ApplyWindow); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("apply_window"), "Invalid apply_window", err.Error())
+		}
+	}
+}
+
 func (p *AdaptiveMetricsProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var cfg AdaptiveMetricsProviderModel
 	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
@@ -130,22 +463,144 @@ func (p *AdaptiveMetricsProvider) Configure(ctx context.Context, req provider.Co
 		return
 	}
 
+	if err := validateAPIURL(apiURL); err != nil {
+		resp.Diagnostics.AddError("Invalid url", err.Error())
+		return
+	}
+	readURL := getStringOverriddenByEnvOrDefault(cfg.ReadURL, "GRAFANA_AM_READ_URL", "")
+	if readURL != "" {
+		if err := validateAPIURL(readURL); err != nil {
+			resp.Diagnostics.AddError("Invalid read_url", err.Error())
+			return
+		}
+	}
+
 	apiKey := getStringOverriddenByEnvOrDefault(cfg.APIKey, "GRAFANA_AM_API_KEY", "")
 	debug, err := getBooleanOverriddenByEnvOrDefault(cfg.Debug, "GRAFANA_AM_DEBUG", false)
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_DEBUG", err.Error())
 		return
 	}
+	autoImport, err := getBooleanOverriddenByEnvOrDefault(cfg.AutoImport, "GRAFANA_AM_AUTO_IMPORT", false)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_AUTO_IMPORT", err.Error())
+		return
+	}
 	retries, err := getIntOverriddenByEnvOrDefault(cfg.Retries, "GRAFANA_AM_RETRIES", 3)
 	if err != nil {
 		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_RETRIES", err.Error())
 		return
 	}
-	httpClient := cleanhttp.DefaultClient()
-	if retries > 0 {
-		retryClient := retryablehttp.NewClient()
-		retryClient.RetryMax = retries
-		httpClient = retryClient.StandardClient()
+	maxIdleConns, err := getIntOverriddenByEnvOrDefault(cfg.MaxIdleConns, "GRAFANA_AM_MAX_IDLE_CONNS", 10)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_MAX_IDLE_CONNS", err.Error())
+		return
+	}
+	maxConcurrentRequests, err := getIntOverriddenByEnvOrDefault(cfg.MaxConcurrentRequests, "GRAFANA_AM_MAX_CONCURRENT_REQUESTS", 4)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_MAX_CONCURRENT_REQUESTS", err.Error())
+		return
+	}
+	idleConnTimeout, err := time.ParseDuration(getStringOverriddenByEnvOrDefault(cfg.IdleConnTimeout, "GRAFANA_AM_IDLE_CONN_TIMEOUT", "90s"))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse idle_conn_timeout", err.Error())
+		return
+	}
+	progressInterval, err := time.ParseDuration(getStringOverriddenByEnvOrDefault(cfg.ProgressInterval, "GRAFANA_AM_PROGRESS_INTERVAL", "10s"))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse progress_interval", err.Error())
+		return
+	}
+	broadMatchMinLength, err := getIntOverriddenByEnvOrDefault(cfg.BroadMatchMinLength, "GRAFANA_AM_BROAD_MATCH_MIN_LENGTH", 3)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_BROAD_MATCH_MIN_LENGTH", err.Error())
+		return
+	}
+	minSeriesCount, err := getIntOverriddenByEnvOrDefault(cfg.MinSeriesCount, "GRAFANA_AM_MIN_SERIES_COUNT", 0)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_MIN_SERIES_COUNT", err.Error())
+		return
+	}
+	// A negative limit disables the check.
+	maxSavingsReduction, err := getIntOverriddenByEnvOrDefault(cfg.MaxSavingsReduction, "GRAFANA_AM_MAX_SAVINGS_REDUCTION", -1)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_MAX_SAVINGS_REDUCTION", err.Error())
+		return
+	}
+	schemaValidate, err := getBooleanOverriddenByEnvOrDefault(cfg.SchemaValidate, "GRAFANA_AM_SCHEMA_VALIDATE", false)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_SCHEMA_VALIDATE", err.Error())
+		return
+	}
+	emitOperationSummary, err := getBooleanOverriddenByEnvOrDefault(cfg.EmitOperationSummary, "GRAFANA_AM_EMIT_OPERATION_SUMMARY", false)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_EMIT_OPERATION_SUMMARY", err.Error())
+		return
+	}
+	warnOnDropDrift, err := getBooleanOverriddenByEnvOrDefault(cfg.WarnOnDropDrift, "GRAFANA_AM_WARN_ON_DROP_DRIFT", false)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_WARN_ON_DROP_DRIFT", err.Error())
+		return
+	}
+	enforceOwnership, err := getBooleanOverriddenByEnvOrDefault(cfg.EnforceOwnership, "GRAFANA_AM_ENFORCE_OWNERSHIP", false)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse GRAFANA_AM_ENFORCE_OWNERSHIP", err.Error())
+		return
+	}
+	aggregationDelayCheck := getStringOverriddenByEnvOrDefault(cfg.AggregationDelayCheck, "GRAFANA_AM_AGGREGATION_DELAY_CHECK", "error")
+	if aggregationDelayCheck != "error" && aggregationDelayCheck != "warn" {
+		resp.Diagnostics.AddError("Invalid aggregation_delay_check", fmt.Sprintf("Got %q; it must be one of 'error' or 'warn'.", aggregationDelayCheck))
+		return
+	}
+	savingsReductionCheck := getStringOverriddenByEnvOrDefault(cfg.SavingsReductionCheck, "GRAFANA_AM_SAVINGS_REDUCTION_CHECK", "warn")
+	if savingsReductionCheck != "error" && savingsReductionCheck != "warn" {
+		resp.Diagnostics.AddError("Invalid savings_reduction_check", fmt.Sprintf("Got %q; it must be one of 'error' or 'warn'.", savingsReductionCheck))
+		return
+	}
+	errorStrategy := getStringOverriddenByEnvOrDefault(cfg.ErrorStrategy, "GRAFANA_AM_ERROR_STRATEGY", "fail_fast")
+	if errorStrategy != "fail_fast" && errorStrategy != "continue" {
+		resp.Diagnostics.AddError("Invalid error_strategy", fmt.Sprintf("Got %q; it must be one of 'fail_fast' or 'continue'.", errorStrategy))
+		return
+	}
+	allowedMetricPatterns := getStringListOverriddenByEnv(cfg.AllowedMetricPatterns, "GRAFANA_AM_ALLOWED_METRIC_PATTERNS")
+	for _, pattern := range allowedMetricPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			resp.Diagnostics.AddError("Invalid allowed_metric_patterns", fmt.Sprintf("The pattern %q is not a valid regular expression: %s.", pattern, err))
+			return
+		}
+	}
+	var window *applyWindow
+	if cfg.ApplyWindow != nil {
+		if window, err = parseApplyWindow(*cfg.ApplyWindow); err != nil {
+			resp.Diagnostics.AddError("Invalid apply_window", err.Error())
+			return
+		}
+	}
+	lockTimeout, err := time.ParseDuration(getStringOverriddenByEnvOrDefault(cfg.LockTimeout, "GRAFANA_AM_LOCK_TIMEOUT", "0s"))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to parse lock_timeout", err.Error())
+		return
+	}
+	httpClient := newHTTPClient(retries, maxIdleConns, idleConnTimeout)
+
+	var httpLog *client.HTTPLog
+	if logPath := getStringOverriddenByEnvOrDefault(cfg.DebugLogFile, "GRAFANA_AM_DEBUG_LOG_FILE", ""); logPath != "" {
+		if httpLog, err = p.openHTTPLog(logPath); err != nil {
+			resp.Diagnostics.AddError("Invalid debug_log_file", err.Error())
+			return
+		}
+	}
+	var audit *auditLog
+	if logPath := getStringOverriddenByEnvOrDefault(cfg.AuditLogFile, "GRAFANA_AM_AUDIT_LOG_FILE", ""); logPath != "" {
+		if audit, err = p.openAuditLog(logPath); err != nil {
+			resp.Diagnostics.AddError("Invalid audit_log_file", err.Error())
+			return
+		}
+	}
+
+	var stats *client.Stats
+	if emitOperationSummary {
+		stats = client.NewStats()
 	}
 
 	httpHeaders := make(map[string]string)
@@ -170,23 +625,108 @@ func (p *AdaptiveMetricsProvider) Configure(ctx context.Context, req provider.Co
 		HTTPHeaders: httpHeaders,
 		Debug:       debug,
 		HttpClient:  httpClient,
+
+		RequestIDPrefix: getStringOverriddenByEnvOrDefault(cfg.RequestIDPrefix, "GRAFANA_AM_REQUEST_ID_PREFIX", ""),
+		Stats:           stats,
+		HTTPLog:         httpLog,
+		ReadURL:         readURL,
 	})
 	if err != nil {
 		resp.Diagnostics.AddError("Could not instantiate the API client.", err.Error())
 		return
 	}
 
-	aggRules := NewAggregationRules(c)
+	if stats != nil {
+		p.mu.Lock()
+		p.stats = append(p.stats, stats)
+		p.mu.Unlock()
+	}
+
+	metricPrefix := getStringOverriddenByEnvOrDefault(cfg.MetricPrefix, "GRAFANA_AM_METRIC_PREFIX", "")
+	aggRules := NewAggregationRules(c, metricPrefix)
+	aggRules.continueOnError = errorStrategy == "continue"
+	aggRules.window = window
+	aggRules.progressInterval = progressInterval
+	aggRules.audit = audit
+	caps := newCapabilities(c)
+	aggRules.capabilities = caps
+	if lockID := getStringOverriddenByEnvOrDefault(cfg.LockID, "GRAFANA_AM_LOCK_ID", ""); lockID != "" {
+		aggRules.lock = newApplyLock(c, caps, lockID, lockTimeout)
+		p.mu.Lock()
+		p.locks = append(p.locks, aggRules.lock)
+		p.mu.Unlock()
+	}
 	if err = aggRules.Init(); err != nil {
 		resp.Diagnostics.AddError("Could not initialize internal state.", err.Error())
 		return
 	}
 
-	resp.DataSourceData = c // TODO
-	resp.ResourceData = &resourceData{
-		aggRules: aggRules,
-		client:   c,
+	cells := newCellRules(metricPrefix, func(url, cellAPIKey string) (*client.Client, error) {
+		if cellAPIKey == "" {
+			cellAPIKey = apiKey
+		}
+		return client.New(url, &client.Config{
+			APIKey:      cellAPIKey,
+			HTTPHeaders: httpHeaders,
+			Debug:       debug,
+			HttpClient:  httpClient,
+
+			RequestIDPrefix: getStringOverriddenByEnvOrDefault(cfg.RequestIDPrefix, "GRAFANA_AM_REQUEST_ID_PREFIX", ""),
+			Stats:           stats,
+			HTTPLog:         httpLog,
+		})
+	})
+	cells.audit = audit
+	cells.window = window
+
+	var savings *savingsBudget
+	if maxSavingsReduction >= 0 {
+		savings = newSavingsBudget(int64(maxSavingsReduction), savingsReductionCheck == "warn")
+	}
+
+	data := &resourceData{
+		aggRules:   aggRules,
+		cells:      cells,
+		client:     c,
+		autoImport: autoImport,
+
+		capabilities:          caps,
+		maxConcurrentRequests: maxConcurrentRequests,
+
+		broadMatchMinLength: broadMatchMinLength,
+		minSeriesCount:      minSeriesCount,
+		savings:             savings,
+		schemaValidate:      schemaValidate,
+		requiredKeepLabels:  getStringListOverriddenByEnv(cfg.RequiredKeepLabels, "GRAFANA_AM_REQUIRED_KEEP_LABELS"),
+
+		allowedMetricPatterns: allowedMetricPatterns,
+
+		continueOnError:           errorStrategy == "continue",
+		warnShortAggregationDelay: aggregationDelayCheck == "warn",
+		warnOnDropDrift:           warnOnDropDrift,
+		enforceOwnership:          enforceOwnership,
 	}
+	resp.DataSourceData = data
+	resp.ResourceData = data
+}
+
+// newHTTPClient creates the HTTP client used for all API requests. Idle
+// connections are pooled so that consecutive requests to the API reuse them.
+func newHTTPClient(retries, maxIdleConns int, idleConnTimeout time.Duration) *http.Client {
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.MaxIdleConns = maxIdleConns
+	transport.MaxIdleConnsPerHost = maxIdleConns
+	transport.IdleConnTimeout = idleConnTimeout
+
+	httpClient := &http.Client{Transport: transport}
+	if retries > 0 {
+		retryClient := retryablehttp.NewClient()
+		retryClient.HTTPClient = httpClient
+		retryClient.RetryMax = retries
+		httpClient = retryClient.StandardClient()
+	}
+
+	return httpClient
 }
 
 func (p *AdaptiveMetricsProvider) Resources(_ context.Context) []func() resource.Resource {
@@ -194,12 +734,166 @@ func (p *AdaptiveMetricsProvider) Resources(_ context.Context) []func() resource
 		newRuleResource,
 		newExemptionResource,
 		newRecommendationsConfigResource,
+		newRuleSetResource,
+		newTransactionalRuleSetResource,
+		newRecommendationBundleResource,
+		newDefaultRuleResource,
+		newMultiCellRuleResource,
+		newMultiMetricRuleResource,
+		newRulesFileResource,
+		newExemptionsResource,
+		newPrefixRenameResource,
+		newRuleSwapResource,
+		newRulesRestoreResource,
 	}
 }
 
 func (p *AdaptiveMetricsProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		newRecommendationDatasource,
+		newRulesDatasource,
+		newRulesHCLDatasource,
+		newRuleSuggestionsDatasource,
+		newRuleValidationDatasource,
+		newExemptionsDatasource,
+		newOrphanedSeriesDatasource,
+		newRulesSnapshotDatasource,
+		newMetricImportDatasource,
+		newCellDiffDatasource,
+		newQuotaDatasource,
+		newQueryMetricsDatasource,
+		newChangeReportDatasource,
+		newRulesCSVDatasource,
+		newImpactPreviewDatasource,
+		newMetricEvaluationDatasource,
+		newRuleOverridesDatasource,
+		newRuleCloneDatasource,
+	}
+}
+
+func (p *AdaptiveMetricsProvider) Functions(_ context.Context) []func() function.Function {
+	return []func() function.Function{
+		newRuleDefaultsFunction,
+		newParseDurationFunction,
+		newFormatDurationFunction,
+		newIsValidMetricNameFunction,
+		newValidateMetricNameFunction,
+		newRulesImportConfigFunction,
+	}
+}
+
+// LogOperationSummary logs a summary of the API requests made by the provider
+// if emit_operation_summary is enabled. It is called when the provider server
+// stops at the end of a run.
+func LogOperationSummary(p provider.Provider) {
+	amp, ok := p.(*AdaptiveMetricsProvider)
+	if !ok {
+		return
+	}
+
+	amp.mu.Lock()
+	defer amp.mu.Unlock()
+
+	for _, stats := range amp.stats {
+		log.Printf("[INFO] Adaptive Metrics operation summary: %s", stats.Summary())
+	}
+}
+
+// ReleaseApplyLocks releases the apply locks acquired by the provider. It is
+// called when the provider server stops at the end of a run, so the locks are
+// released at once, each within its release timeout.
+func ReleaseApplyLocks(p provider.Provider) {
+	amp, ok := p.(*AdaptiveMetricsProvider)
+	if !ok {
+		return
+	}
+
+	amp.mu.Lock()
+	defer amp.mu.Unlock()
+
+	errs := parallel(context.Background(), 0, len(amp.locks), func(_ context.Context, i int) error {
+		return amp.locks[i].release()
+	})
+	for _, err := range errs {
+		if err != nil {
+			log.Printf("[WARN] Adaptive Metrics apply lock: %s", err)
+		}
+	}
+}
+
+// openHTTPLog returns the HTTP log written to path, which is opened unless
+// another instance of the provider already logs to it.
+func (p *AdaptiveMetricsProvider) openHTTPLog(path string) (*client.HTTPLog, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if httpLog, ok := p.httpLogs[path]; ok {
+		return httpLog, nil
+	}
+	httpLog, err := client.OpenHTTPLog(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.httpLogs == nil {
+		p.httpLogs = make(map[string]*client.HTTPLog)
+	}
+	p.httpLogs[path] = httpLog
+	return httpLog, nil
+}
+
+// openAuditLog returns the audit log written to path, which is opened unless
+// another instance of the provider already logs to it.
+func (p *AdaptiveMetricsProvider) openAuditLog(path string) (*auditLog, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if audit, ok := p.auditLogs[path]; ok {
+		return audit, nil
+	}
+	audit, err := openAuditLog(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.auditLogs == nil {
+		p.auditLogs = make(map[string]*auditLog)
+	}
+	p.auditLogs[path] = audit
+	return audit, nil
+}
+
+// CloseHTTPLogs closes the files of debug_log_file. It is called when the
+// provider server stops at the end of a run, after its last request.
+func CloseHTTPLogs(p provider.Provider) {
+	amp, ok := p.(*AdaptiveMetricsProvider)
+	if !ok {
+		return
+	}
+
+	amp.mu.Lock()
+	defer amp.mu.Unlock()
+
+	for path, httpLog := range amp.httpLogs {
+		if err := httpLog.Close(); err != nil {
+			log.Printf("[WARN] Unable to close the HTTP log %s: %s", path, err)
+		}
+	}
+}
+
+// CloseAuditLogs closes the files of audit_log_file. It is called when the
+// provider server stops at the end of a run, after its last change.
+func CloseAuditLogs(p provider.Provider) {
+	amp, ok := p.(*AdaptiveMetricsProvider)
+	if !ok {
+		return
+	}
+
+	amp.mu.Lock()
+	defer amp.mu.Unlock()
+
+	for path, audit := range amp.auditLogs {
+		if err := audit.close(); err != nil {
+			log.Printf("[WARN] Unable to close the audit log %s: %s", path, err)
+		}
 	}
 }
 
@@ -214,4 +908,55 @@ func New(version string) func() provider.Provider {
 type resourceData struct {
 	aggRules *AggregationRules
 	client   *client.Client
+
+	// capabilities are the optional features supported by the backend.
+	capabilities *capabilities
+
+	// cells are the rules of the cells targeted by multi-cell rules.
+	cells *cellRules
+
+	// maxConcurrentRequests limits the requests which data sources that
+	// fan out make at once.
+	maxConcurrentRequests int
+
+	// autoImport is the provider-wide default for the rule resource's auto_import attribute.
+	autoImport bool
+
+	// broadMatchMinLength is the minimum metric length of prefix and suffix
+	// rules which do not set allow_broad_match.
+	broadMatchMinLength int
+
+	// minSeriesCount is the number of series below which new or changed rules
+	// are reported as possibly unnecessary. Zero disables the check.
+	minSeriesCount int
+
+	// savings limits how much the planned changes of rules may reduce the
+	// series saved by the rules in total. It is nil if there is no limit.
+	savings *savingsBudget
+
+	// schemaValidate enables checking rules against the API schema during plan.
+	schemaValidate bool
+
+	// requiredKeepLabels are the labels which every rule must retain.
+	requiredKeepLabels []string
+
+	// allowedMetricPatterns are the regular expressions of which the metric
+	// of every rule must match one. Every metric is allowed if it is empty.
+	allowedMetricPatterns []string
+
+	// continueOnError makes resources which manage several objects at once
+	// attempt every change rather than stop at the first failure.
+	continueOnError bool
+
+	// warnShortAggregationDelay reports rules whose aggregation delay is
+	// shorter than their interval as warnings rather than errors.
+	warnShortAggregationDelay bool
+
+	// warnOnDropDrift reports rules whose drop value was changed outside of
+	// Terraform when they are read.
+	warnOnDropDrift bool
+
+	// enforceOwnership reports rules which are not marked as managed by
+	// Terraform when they are read.
+	enforceOwnership bool
 }
@@ -1,8 +1,30 @@
 package provider
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
 )
 
 const (
@@ -15,3 +37,263 @@ var (
 		"grafana-adaptive-metrics": providerserver.NewProtocol6WithError(New("test")()),
 	}
 )
+
+func TestNewHTTPClientReusesConnections(t *testing.T) {
+	for _, retries := range []int{0, 3} {
+		t.Run(fmt.Sprintf("retries=%d", retries), func(t *testing.T) {
+			var newConns atomic.Int32
+			s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					newConns.Add(1)
+				}
+			}
+			s.Start()
+			defer s.Close()
+
+			httpClient := newHTTPClient(retries, 10, 90*time.Second)
+			for i := 0; i < 5; i++ {
+				resp, err := httpClient.Get(s.URL)
+				require.NoError(t, err)
+				_, err = io.Copy(io.Discard, resp.Body)
+				require.NoError(t, err)
+				require.NoError(t, resp.Body.Close())
+			}
+
+			require.Equal(t, int32(1), newConns.Load())
+		})
+	}
+}
+
+func TestValidateAPIURL(t *testing.T) {
+	for raw, valid := range map[string]bool{
+		"https://my-prometheus-url.net":      true,
+		"http://localhost:8080/aggregations": true,
+		"my-prometheus-url.net":              false,
+		"localhost:8080":                     false,
+		"ftp://my-prometheus-url.net":        false,
+		"https://":                           false,
+		"https://my-prometheus-url.net/%zz":  false,
+	} {
+		require.Equal(t, valid, validateAPIURL(raw) == nil, raw)
+	}
+}
+
+func TestProviderValidateConfig(t *testing.T) {
+	// The environment variables of acceptance tests would take precedence.
+	for _, env := range []string{"GRAFANA_AM_API_URL", "GRAFANA_AM_READ_URL", "GRAFANA_AM_RETRIES", "GRAFANA_AM_ERROR_STRATEGY", "GRAFANA_AM_ALLOWED_METRIC_PATTERNS", "GRAFANA_AM_DEBUG", "GRAFANA_AM_LOCK_TIMEOUT"} {
+		t.Setenv(env, "")
+		require.NoError(t, os.Unsetenv(env))
+	}
+
+	ctx := context.Background()
+	p := New("test")()
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+	typ := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	validate := func(t *testing.T, attrs map[string]tftypes.Value) []path.Path {
+		t.Helper()
+		values := make(map[string]tftypes.Value, len(typ.AttributeTypes))
+		for name, attrType := range typ.AttributeTypes {
+			values[name] = tftypes.NewValue(attrType, nil)
+		}
+		for name, v := range attrs {
+			values[name] = v
+		}
+
+		resp := &provider.ValidateConfigResponse{}
+		p.(provider.ProviderWithValidateConfig).ValidateConfig(ctx, provider.ValidateConfigRequest{Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(typ, values),
+		}}, resp)
+		return errorPaths(resp.Diagnostics)
+	}
+	url := tftypes.NewValue(tftypes.String, "https://my-prometheus-url.net")
+
+	for _, tc := range []struct {
+		name     string
+		attrs    map[string]tftypes.Value
+		env      map[string]string
+		expected []path.Path
+	}{
+		{
+			name:  "valid",
+			attrs: map[string]tftypes.Value{"url": url, "retries": tftypes.NewValue(tftypes.Number, 5), "error_strategy": tftypes.NewValue(tftypes.String, "continue")},
+		},
+		{
+			name:     "missing url",
+			expected: []path.Path{path.Root("url")},
+		},
+		{
+			name: "url from the environment",
+			env:  map[string]string{"GRAFANA_AM_API_URL": "https://my-prometheus-url.net"},
+		},
+		{
+			name:     "read_url without url",
+			attrs:    map[string]tftypes.Value{"read_url": url},
+			expected: []path.Path{path.Root("url")},
+		},
+		{
+			name: "malformed urls",
+			attrs: map[string]tftypes.Value{
+				"url":      tftypes.NewValue(tftypes.String, "my-prometheus-url.net"),
+				"read_url": tftypes.NewValue(tftypes.String, "ftp://my-prometheus-url.net"),
+			},
+			expected: []path.Path{path.Root("url"), path.Root("read_url")},
+		},
+		{
+			name: "invalid values",
+			attrs: map[string]tftypes.Value{
+				"url":                     url,
+				"max_concurrent_requests": tftypes.NewValue(tftypes.Number, -1),
+				"idle_conn_timeout":       tftypes.NewValue(tftypes.String, "90"),
+				"progress_interval":       tftypes.NewValue(tftypes.String, "-1s"),
+				"lock_timeout":            tftypes.NewValue(tftypes.String, "5 minutes"),
+				"aggregation_delay_check": tftypes.NewValue(tftypes.String, "ignore"),
+				"max_savings_reduction":   tftypes.NewValue(tftypes.Number, -1),
+				"savings_reduction_check": tftypes.NewValue(tftypes.String, "fail"),
+				"allowed_metric_patterns": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+					tftypes.NewValue(tftypes.String, "team_a_.*"),
+					tftypes.NewValue(tftypes.String, "team_b_(.*"),
+				}),
+			},
+			expected: []path.Path{
+				path.Root("max_concurrent_requests"),
+				path.Root("max_savings_reduction"),
+				path.Root("idle_conn_timeout"),
+				path.Root("progress_interval"),
+				path.Root("lock_timeout"),
+				path.Root("aggregation_delay_check"),
+				path.Root("savings_reduction_check"),
+				path.Root("allowed_metric_patterns").AtListIndex(1),
+			},
+		},
+		{
+			name:     "invalid values from the environment",
+			attrs:    map[string]tftypes.Value{"url": url, "retries": tftypes.NewValue(tftypes.Number, 5)},
+			env:      map[string]string{"GRAFANA_AM_RETRIES": "five", "GRAFANA_AM_DEBUG": "maybe", "GRAFANA_AM_ERROR_STRATEGY": "retry"},
+			expected: []path.Path{path.Root("debug"), path.Root("retries"), path.Root("error_strategy")},
+		},
+		{
+			name: "invalid apply window",
+			attrs: map[string]tftypes.Value{
+				"url": url,
+				"apply_window": tftypes.NewValue(typ.AttributeTypes["apply_window"], map[string]tftypes.Value{
+					"days":      tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+					"start":     tftypes.NewValue(tftypes.String, "25:00"),
+					"end":       tftypes.NewValue(tftypes.String, "17:00"),
+					"time_zone": tftypes.NewValue(tftypes.String, nil),
+				}),
+			},
+			expected: []path.Path{path.Root("apply_window")},
+		},
+		{
+			name: "unknown values are left to Configure",
+			attrs: map[string]tftypes.Value{
+				"url":     tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+				"retries": tftypes.NewValue(tftypes.Number, -1),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			require.Equal(t, tc.expected, validate(t, tc.attrs))
+		})
+	}
+}
+
+// planResourceChange plans an update of a resource from prior to config
+// through the provider server and returns the planned attribute values. Like
+// Terraform, computed attributes which are not configured are proposed with
+// their prior value. A nil prior plans the creation of the resource.
+func planResourceChange(t *testing.T, typeName string, prior, config map[string]tftypes.Value) map[string]tftypes.Value {
+	t.Helper()
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	require.NoError(t, err)
+
+	schemaResp, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	require.NoError(t, err)
+	schema := schemaResp.ResourceSchemas[typeName]
+	require.NotNil(t, schema, "no schema for %s", typeName)
+
+	proposed := make(map[string]tftypes.Value, len(config))
+	for _, attr := range schema.Block.Attributes {
+		proposed[attr.Name] = config[attr.Name]
+		if prior != nil && attr.Computed && config[attr.Name].IsNull() {
+			proposed[attr.Name] = prior[attr.Name]
+		}
+	}
+	for _, block := range schema.Block.BlockTypes {
+		proposed[block.TypeName] = config[block.TypeName]
+	}
+
+	typ := schema.ValueType()
+	dynamicValue := func(attrs map[string]tftypes.Value) *tfprotov6.DynamicValue {
+		v, err := tfprotov6.NewDynamicValue(typ, tftypes.NewValue(typ, attrs))
+		require.NoError(t, err)
+		return &v
+	}
+
+	priorState, err := tfprotov6.NewDynamicValue(typ, tftypes.NewValue(typ, nil))
+	require.NoError(t, err)
+	if prior != nil {
+		priorState = *dynamicValue(prior)
+	}
+
+	resp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       &priorState,
+		ProposedNewState: dynamicValue(proposed),
+		Config:           dynamicValue(config),
+	})
+	require.NoError(t, err)
+	for _, d := range resp.Diagnostics {
+		require.NotEqual(t, tfprotov6.DiagnosticSeverityError, d.Severity, "%s: %s", d.Summary, d.Detail)
+	}
+
+	planned, err := resp.PlannedState.Unmarshal(typ)
+	require.NoError(t, err)
+	var attrs map[string]tftypes.Value
+	require.NoError(t, planned.As(&attrs))
+
+	return attrs
+}
+
+func TestProviderHTTPLog(t *testing.T) {
+	api := newMockAPI(t)
+	p := &AdaptiveMetricsProvider{}
+	logPath := filepath.Join(t.TempDir(), "http.log")
+
+	// Instances of the provider logging to the same file share it.
+	httpLog, err := p.openHTTPLog(logPath)
+	require.NoError(t, err)
+	shared, err := p.openHTTPLog(logPath)
+	require.NoError(t, err)
+	require.Same(t, httpLog, shared)
+
+	c, err := client.New(api.server.URL, &client.Config{HTTPLog: httpLog})
+	require.NoError(t, err)
+	rules := NewAggregationRules(c, "")
+	require.NoError(t, rules.Init())
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "a", Drop: true}))
+	require.NoError(t, rules.Update(model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}}))
+	require.NoError(t, rules.Delete(model.AggregationRule{Metric: "a"}))
+	CloseHTTPLogs(p)
+
+	contents, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	var methods []string
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		var entry client.HTTPLogEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		methods = append(methods, entry.Method)
+	}
+	require.Equal(t, []string{"GET", "POST", "PUT", "DELETE"}, methods)
+}
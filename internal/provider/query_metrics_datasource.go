@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type queryMetricsDatasource struct {
+	metricPrefix string
+}
+
+var (
+	_ datasource.DataSource              = &queryMetricsDatasource{}
+	_ datasource.DataSourceWithConfigure = &queryMetricsDatasource{}
+)
+
+func newQueryMetricsDatasource() datasource.DataSource {
+	return &queryMetricsDatasource{}
+}
+
+func (d *queryMetricsDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.metricPrefix = data.aggRules.metricPrefix
+}
+
+func (d *queryMetricsDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_query_metrics", req.ProviderTypeName)
+}
+
+func (d *queryMetricsDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Extracts the names of the metrics referenced by PromQL expressions, such as the queries of dashboards or alerts, so that rules can be created for exactly the metrics in use with `for_each`. No request is made to the API.\n\n" +
+			"Selectors without a metric name, such as `{job=\"api\"}`, are skipped, as are names containing a colon, which by convention are recording rules or aggregated series rather than ingested metrics. Metrics outside of the provider's `metric_prefix` are skipped and the prefix is removed from the others, like the metrics of rules.",
+		Attributes: map[string]schema.Attribute{
+			"expressions": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "The PromQL expressions. Each expression which cannot be parsed is reported with its position in the list.",
+			},
+			"metrics": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The distinct metrics referenced by the expressions, ordered by name.",
+			},
+		},
+	}
+}
+
+func (d *queryMetricsDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state model.QueryMetricsTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for i, expr := range state.Expressions {
+		if expr.IsNull() || expr.IsUnknown() {
+			continue
+		}
+
+		metrics, err := queryMetrics(expr.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("expressions").AtListIndex(i),
+				"Invalid PromQL expression",
+				fmt.Sprintf("Unable to parse expression %d: %s", i, err),
+			)
+			continue
+		}
+		for _, metric := range metrics {
+			if strings.Contains(metric, ":") || !strings.HasPrefix(metric, d.metricPrefix) {
+				continue
+			}
+			seen[strings.TrimPrefix(metric, d.metricPrefix)] = true
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	names := make([]string, 0, len(seen))
+	for metric := range seen {
+		names = append(names, metric)
+	}
+	sort.Strings(names)
+
+	state.Metrics = make([]types.String, len(names))
+	for i, metric := range names {
+		state.Metrics[i] = types.StringValue(metric)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
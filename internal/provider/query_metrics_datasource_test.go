@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readQueryMetrics(t *testing.T, d *queryMetricsDatasource, expressions ...string) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	typ := schemaResp.Schema.Type().TerraformType(ctx)
+
+	exprs := make([]tftypes.Value, len(expressions))
+	for i, expr := range expressions {
+		exprs[i] = tftypes.NewValue(tftypes.String, expr)
+	}
+	config := tfsdk.Config{
+		Schema: schemaResp.Schema,
+		Raw: tftypes.NewValue(typ, map[string]tftypes.Value{
+			"expressions": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, exprs),
+			"metrics":     tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		}),
+	}
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(typ, nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+func TestQueryMetricsDatasource(t *testing.T) {
+	resp := readQueryMetrics(t, &queryMetricsDatasource{metricPrefix: "prod_"},
+		`sum by (job) (rate(prod_http_requests_total[5m]))`,
+		`prod_queue_depth > 10 and prod_http_requests_total`,
+		`staging_queue_depth`,
+		`prod_job:requests:rate5m`,
+		`{job="api"}`,
+	)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.QueryMetricsTF
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.Equal(t, []types.String{
+		types.StringValue("http_requests_total"),
+		types.StringValue("queue_depth"),
+	}, tf.Metrics)
+}
+
+func TestQueryMetricsDatasourceInvalid(t *testing.T) {
+	resp := readQueryMetrics(t, &queryMetricsDatasource{}, `up`, `sum(up`, `up{`)
+	require.Equal(t, []path.Path{
+		path.Root("expressions").AtListIndex(1),
+		path.Root("expressions").AtListIndex(2),
+	}, errorPaths(resp.Diagnostics))
+	require.Equal(t, "Unable to parse expression 1: 1:7: parse error: unclosed left parenthesis", resp.Diagnostics.Errors()[0].Detail())
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+)
+
+type quotaDatasource struct {
+	client *client.Client
+}
+
+var (
+	_ datasource.DataSource              = &quotaDatasource{}
+	_ datasource.DataSourceWithConfigure = &quotaDatasource{}
+)
+
+func newQuotaDatasource() datasource.DataSource {
+	return &quotaDatasource{}
+}
+
+func (d *quotaDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = data.client
+}
+
+func (d *quotaDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_quota", req.ProviderTypeName)
+}
+
+func (d *quotaDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Reports how close the stack is to the limits of the Adaptive Metrics API, to monitor them from Terraform. No request is made: the values are the latest reported by the API in the `" +
+			client.RuleLimitHeader + "`, `" + client.RuleCountHeader + "`, `" + client.RequestLimitHeader + "`, and `" + client.RequestsRemainingHeader +
+			"` headers of its responses to the provider, such as when it loaded the rules. Each attribute is null if the backend does not send its header.",
+		Attributes: map[string]schema.Attribute{
+			"rule_limit": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The maximum number of aggregation rules.",
+			},
+			"rule_count": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of aggregation rules counted against rule_limit.",
+			},
+			"remaining_rule_slots": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of rules which can still be created, rule_limit minus rule_count. Null unless both are known.",
+			},
+			"request_limit": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of requests allowed in the current rate limit window.",
+			},
+			"remaining_requests": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of requests left in the current rate limit window.",
+			},
+		},
+	}
+}
+
+func (d *quotaDatasource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	state := d.client.Quota().ToTF()
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readQuota(t *testing.T, d *quotaDatasource) model.QuotaTF {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.QuotaTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	return tf
+}
+
+func TestQuotaDatasource(t *testing.T) {
+	api := newMockAPI(t)
+	c := api.client()
+	d := &quotaDatasource{client: c}
+
+	// Backends which send no quota headers have no known limits.
+	rules := NewAggregationRules(c, "")
+	require.NoError(t, rules.Init())
+	require.Equal(t, model.QuotaTF{
+		RuleLimit:          types.Int64Null(),
+		RuleCount:          types.Int64Null(),
+		RemainingRuleSlots: types.Int64Null(),
+		RequestLimit:       types.Int64Null(),
+		RemainingRequests:  types.Int64Null(),
+	}, readQuota(t, d))
+
+	api.headers = http.Header{
+		client.RuleLimitHeader:         {"100"},
+		client.RuleCountHeader:         {"97"},
+		client.RequestLimitHeader:      {"600"},
+		client.RequestsRemainingHeader: {"599"},
+	}
+	require.NoError(t, rules.Init())
+	require.Equal(t, model.QuotaTF{
+		RuleLimit:          types.Int64Value(100),
+		RuleCount:          types.Int64Value(97),
+		RemainingRuleSlots: types.Int64Value(3),
+		RequestLimit:       types.Int64Value(600),
+		RemainingRequests:  types.Int64Value(599),
+	}, readQuota(t, d))
+
+	// The latest value of each header is kept, even if later responses
+	// do not send it.
+	api.headers = http.Header{client.RequestsRemainingHeader: {"598"}}
+	require.NoError(t, rules.Init())
+	tf := readQuota(t, d)
+	require.Equal(t, types.Int64Value(598), tf.RemainingRequests)
+	require.Equal(t, types.Int64Value(3), tf.RemainingRuleSlots)
+}
@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// bundleActions are the recommended actions which result in a rule; metrics
+// recommended for removal are left out of the bundle.
+var bundleActions = []string{"add", "update", "keep"}
+
+type recommendationBundleResource struct {
+	client       *client.Client
+	capabilities *capabilities
+	rules        *AggregationRules
+
+	requiredKeepLabels    []string
+	allowedMetricPatterns []string
+	savings               *savingsBudget
+}
+
+var (
+	_ resource.Resource               = &recommendationBundleResource{}
+	_ resource.ResourceWithConfigure  = &recommendationBundleResource{}
+	_ resource.ResourceWithModifyPlan = &recommendationBundleResource{}
+)
+
+func newRecommendationBundleResource() resource.Resource {
+	return &recommendationBundleResource{}
+}
+
+func (r *recommendationBundleResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = data.client
+	r.capabilities = data.capabilities
+	r.rules = data.aggRules
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+	r.savings = data.savings
+}
+
+func (r *recommendationBundleResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_recommendation_bundle", req.ProviderTypeName)
+}
+
+func (r *recommendationBundleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Applies all aggregation recommendations for the metrics of a service (or any other label value) as a single set of managed rules. The recommendations are fetched during plan; rules for recommended metrics which already exist are taken over by the bundle and deleted when it is destroyed.",
+		Attributes: map[string]schema.Attribute{
+			"label": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString("service"),
+				Description: "The label identifying the group of metrics, such as 'service' or 'namespace'. Defaults to 'service'.",
+			},
+			"value": schema.StringAttribute{
+				Required:    true,
+				Description: "The value of the label whose recommendations are applied.",
+			},
+			"rules": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The recommended aggregation rules managed by this bundle.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the metric to be aggregated.",
+						},
+						"match_type": schema.StringAttribute{
+							Computed:    true,
+							Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
+						},
+
+						"drop": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Set to true to skip both ingestion and aggregation and drop the metric entirely.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"drop_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels that will be aggregated.",
+						},
+
+						"aggregations": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of aggregation types to calculate for this metric.",
+						},
+
+						"aggregation_interval": schema.StringAttribute{
+							Computed:    true,
+							Description: "The interval at which to generate the aggregated series.",
+						},
+						"aggregation_delay": schema.StringAttribute{
+							Computed:    true,
+							Description: "The delay until aggregation is performed.",
+						},
+
+						"priority": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The priority of the rule. When several rules match a metric, the rule with the highest priority is applied.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *recommendationBundleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.client == nil {
+		return
+	}
+
+	var previous []model.AggregationRule
+	if !req.State.Raw.IsNull() {
+		var state model.RecommendationBundleTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		previous = state.ToAPIReq()
+	}
+
+	// Destroying the bundle deletes its rules.
+	if req.Plan.Raw.IsNull() {
+		changes := diffRuleSet(r.rules.List(), previous, nil, false)
+		resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Empty())...)
+		return
+	}
+
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var label, value types.String
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("label"), &label)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("value"), &value)...)
+	if resp.Diagnostics.HasError() || label.IsUnknown() || value.IsUnknown() {
+		return
+	}
+
+	rules, err := r.recommendedRules(label.ValueString(), value.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read aggregation recommendations", err.Error())
+		return
+	}
+
+	tf := make([]model.RuleSetRuleTF, len(rules))
+	for i, rule := range rules {
+		tf[i] = rule.ToRuleSetRuleTF()
+		resp.Diagnostics.Append(validateRequiredKeepLabels(rule, r.requiredKeepLabels, path.Root("rules").AtListIndex(i))...)
+		resp.Diagnostics.Append(validateAllowedMetric(rule, r.allowedMetricPatterns, path.Root("rules").AtListIndex(i))...)
+	}
+	// Bundles do not model tags, so the tags of the current rules are kept.
+	current := r.rules.List()
+	changes := diffRuleSet(current, previous, withExistingTags(current, rules), false)
+	resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Root("rules"))...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("rules"), tf)...)
+}
+
+func (r *recommendationBundleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan model.RecommendationBundleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.checkDrift(plan)...)
+
+	current := r.rules.List()
+	changes := diffRuleSet(current, nil, withExistingTags(current, plan.ToAPIReq()), false)
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&resp.Diagnostics)).Apply(changes); err != nil {
+		resp.Diagnostics.AddError("Unable to create recommendation bundle", err.Error())
+
+		// Keep track of the rules which were created before the failure.
+		if rules := readRules(r.rules, plan.Metrics()); len(rules) > 0 {
+			plan.Rules = rules
+			resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+		}
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *recommendationBundleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state model.RecommendationBundleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.Rules = readRules(r.rules, state.Metrics())
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *recommendationBundleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var plan model.RecommendationBundleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.RecommendationBundleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.checkDrift(plan)...)
+
+	current := r.rules.List()
+	changes := diffRuleSet(current, state.ToAPIReq(), withExistingTags(current, plan.ToAPIReq()), false)
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&resp.Diagnostics)).Apply(changes); err != nil {
+		resp.Diagnostics.AddError("Unable to update recommendation bundle", err.Error())
+
+		// Rules which could not be deleted yet are kept in state so that the
+		// deletion is retried on the next apply.
+		plan.Rules = readRules(r.rules, append(plan.Metrics(), state.Metrics()...))
+		resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *recommendationBundleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.RecommendationBundleTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(r.rules.List(), state.ToAPIReq(), nil, false)
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&resp.Diagnostics)).Apply(changes); err != nil {
+		resp.Diagnostics.AddError("Unable to delete recommendation bundle", err.Error())
+	}
+}
+
+// recommendedRules fetches the rules recommended for the metrics with the
+// given label value, sorted by metric.
+func (r *recommendationBundleResource) recommendedRules(label, value string) ([]model.AggregationRule, error) {
+	recs, err := r.client.AggregationRecommendationsForLabel(label, value, bundleActions)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]model.AggregationRule, len(recs))
+	for i, rec := range recs {
+		rules[i] = rec.AggregationRule
+	}
+	rules = r.rules.Scope(rules)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Metric < rules[j].Metric })
+
+	return rules, nil
+}
+
+// checkDrift fetches the recommendations again and warns if they changed since
+// the plan was created. The planned rules are applied regardless, as they are
+// what was approved.
+func (r *recommendationBundleResource) checkDrift(plan model.RecommendationBundleTF) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	fresh, err := r.recommendedRules(plan.Label.ValueString(), plan.Value.ValueString())
+	if err != nil {
+		diags.AddWarning("Unable to check aggregation recommendations for changes", err.Error())
+		return diags
+	}
+
+	if drifted := driftedMetrics(plan.ToAPIReq(), fresh); len(drifted) > 0 {
+		diags.AddWarning(
+			"Aggregation recommendations changed since plan",
+			fmt.Sprintf("The recommendations for the following metrics changed after the plan was created: %s. The planned rules have been applied; the next plan will include the changes.", strings.Join(drifted, ", ")),
+		)
+	}
+
+	return diags
+}
+
+// driftedMetrics returns the sorted metrics whose rule was added, removed, or
+// changed between planned and fresh.
+func driftedMetrics(planned, fresh []model.AggregationRule) []string {
+	byMetric := make(map[string]model.AggregationRule, len(planned))
+	for _, rule := range planned {
+		byMetric[rule.Metric] = rule
+	}
+
+	var drifted []string
+	for _, rule := range fresh {
+		plannedRule, ok := byMetric[rule.Metric]
+		if !ok || !plannedRule.Equal(rule) {
+			drifted = append(drifted, rule.Metric)
+		}
+		delete(byMetric, rule.Metric)
+	}
+	for metric := range byMetric {
+		drifted = append(drifted, metric)
+	}
+	sort.Strings(drifted)
+
+	return drifted
+}
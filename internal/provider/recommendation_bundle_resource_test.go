@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestRecommendationBundleModifyPlan(t *testing.T) {
+	api := newMockAPI(t)
+	api.recommend("service", "checkout",
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "prod_checkout_requests_total", DropLabels: []string{"pod"}}, RecommendedAction: "add"},
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "prod_checkout_errors_total", Aggregations: []string{"sum:counter"}}, RecommendedAction: "keep"},
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "prod_checkout_latency_seconds"}, RecommendedAction: "remove"},
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "staging_checkout_requests_total"}, RecommendedAction: "add"},
+	)
+	api.recommend("service", "cart",
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "prod_cart_requests_total"}, RecommendedAction: "add"},
+	)
+
+	rules := NewAggregationRules(api.client(), "prod_")
+	require.NoError(t, rules.Init())
+	r := &recommendationBundleResource{client: api.client(), rules: rules}
+
+	resp := modifyPlan(t, r, nil, model.RecommendationBundleTF{Label: types.StringValue("service"), Value: types.StringValue("checkout")})
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var planned []model.RuleSetRuleTF
+	resp.Diagnostics.Append(resp.Plan.GetAttribute(context.Background(), path.Root("rules"), &planned)...)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	require.Equal(t, []model.RuleSetRuleTF{
+		model.AggregationRule{Metric: "checkout_errors_total", Aggregations: []string{"sum:counter"}}.ToRuleSetRuleTF(),
+		model.AggregationRule{Metric: "checkout_requests_total", DropLabels: []string{"pod"}}.ToRuleSetRuleTF(),
+	}, planned)
+}
+
+func TestDriftedMetrics(t *testing.T) {
+	planned := []model.AggregationRule{
+		{Metric: "unchanged"},
+		{Metric: "changed", DropLabels: []string{"pod"}},
+		{Metric: "removed"},
+	}
+	fresh := []model.AggregationRule{
+		{Metric: "added"},
+		{Metric: "changed", DropLabels: []string{"pod", "instance"}},
+		{Metric: "unchanged"},
+	}
+
+	require.Equal(t, []string{"added", "changed", "removed"}, driftedMetrics(planned, fresh))
+	require.Empty(t, driftedMetrics(planned, planned))
+}
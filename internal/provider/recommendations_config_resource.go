@@ -14,7 +14,8 @@ import (
 )
 
 type recommendationsConfigResource struct {
-	client *client.Client
+	client       *client.Client
+	capabilities *capabilities
 }
 
 var (
@@ -41,6 +42,7 @@ func (r *recommendationsConfigResource) Configure(_ context.Context, req resourc
 	}
 
 	r.client = data.client
+	r.capabilities = data.capabilities
 }
 
 func (r *recommendationsConfigResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -62,6 +64,11 @@ func (r *recommendationsConfigResource) Schema(_ context.Context, _ resource.Sch
 }
 
 func (r *recommendationsConfigResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var plan model.AggregationRecommendationConfigurationTF
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
@@ -83,6 +90,11 @@ func (r *recommendationsConfigResource) Create(ctx context.Context, req resource
 }
 
 func (r *recommendationsConfigResource) Read(ctx context.Context, _ resource.ReadRequest, resp *resource.ReadResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	cfg, err := r.client.AggregationRecommendationsConfig()
 	if err != nil {
 		resp.Diagnostics.AddError("Unable to read recommendations config", err.Error())
@@ -94,6 +106,11 @@ func (r *recommendationsConfigResource) Read(ctx context.Context, _ resource.Rea
 }
 
 func (r *recommendationsConfigResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var plan model.AggregationRecommendationConfigurationTF
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 	if resp.Diagnostics.HasError() {
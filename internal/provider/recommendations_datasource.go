@@ -13,7 +13,8 @@ import (
 )
 
 type recommendationDatasource struct {
-	client *client.Client
+	client       *client.Client
+	capabilities *capabilities
 }
 
 var (
@@ -30,7 +31,7 @@ func (r *recommendationDatasource) Configure(_ context.Context, req datasource.C
 		return
 	}
 
-	data, ok := req.ProviderData.(*client.Client)
+	data, ok := req.ProviderData.(*resourceData)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected datasource configure type",
@@ -39,7 +40,8 @@ func (r *recommendationDatasource) Configure(_ context.Context, req datasource.C
 		return
 	}
 
-	r.client = data
+	r.client = data.client
+	r.capabilities = data.capabilities
 }
 
 func (r *recommendationDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -144,6 +146,11 @@ func (r *recommendationDatasource) Schema(_ context.Context, _ datasource.Schema
 }
 
 func (r *recommendationDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	var state model.AggregationRecommendationListTF
 	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
 
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type ruleCloneDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &ruleCloneDatasource{}
+	_ datasource.DataSourceWithConfigure = &ruleCloneDatasource{}
+)
+
+func newRuleCloneDatasource() datasource.DataSource {
+	return &ruleCloneDatasource{}
+}
+
+func (d *ruleCloneDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rules = data.aggRules
+}
+
+func (d *ruleCloneDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rule_clone", req.ProviderTypeName)
+}
+
+func (d *ruleCloneDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	matcherAttributes := func() map[string]schema.Attribute {
+		return map[string]schema.Attribute{
+			"label": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the label to match.",
+			},
+			"operator": schema.StringAttribute{
+				Computed:    true,
+				Description: "How the label value is matched: '=', '!=', '=~', or '!~'.",
+			},
+			"value": schema.StringAttribute{
+				Computed:    true,
+				Description: "The value, or for '=~' and '!~' the regular expression, to match.",
+			},
+		}
+	}
+	overrideAttributes := matcherAttributes()
+	overrideAttributes["delay"] = schema.StringAttribute{
+		Computed:    true,
+		Description: "The aggregation delay of the matching series.",
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Copies the existing aggregation rule for a metric as a rule for another metric, to base a new `rule` resource on it. Every attribute of the rule resource which configures how the metric is aggregated is copied, so that the new rule is set up like the existing one; its `name` is not copied, as names must be unique. Attributes which the existing rule does not set are null, so that the new resource uses its defaults.\n\n" +
+			"The `drop_if` and `delay_overrides` of the rule resource are blocks, which are set from the lists of the same name with `dynamic` blocks, as in the example. Nothing is changed, and the copy does not follow later changes of the existing rule unless the data source is read again.",
+		Attributes: map[string]schema.Attribute{
+			"source_metric": schema.StringAttribute{
+				Required:    true,
+				Description: "The metric of the existing rule to copy.",
+			},
+			"metric": schema.StringAttribute{
+				Required:    true,
+				Description: "The metric of the new rule. It must differ from source_metric.",
+			},
+			"match_type": schema.StringAttribute{
+				Computed:    true,
+				Description: "The match_type of the existing rule.",
+			},
+			"drop": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the existing rule drops its metric.",
+			},
+			"drop_if": schema.ListNestedAttribute{
+				Computed:     true,
+				Description:  "The drop_if matchers of the existing rule.",
+				NestedObject: schema.NestedAttributeObject{Attributes: matcherAttributes()},
+			},
+			"keep_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The keep_labels of the existing rule.",
+			},
+			"drop_labels": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The drop_labels of the existing rule.",
+			},
+			"label_match_mode": schema.StringAttribute{
+				Computed:    true,
+				Description: "The label_match_mode of the existing rule.",
+			},
+			"aggregations": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The aggregations of the existing rule.",
+			},
+			"aggregation_interval": schema.StringAttribute{
+				Computed:    true,
+				Description: "The aggregation_interval of the existing rule.",
+			},
+			"aggregation_intervals": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The aggregation_intervals of the existing rule.",
+			},
+			"aggregation_delay": schema.StringAttribute{
+				Computed:    true,
+				Description: "The aggregation_delay of the existing rule.",
+			},
+			"delay_overrides": schema.ListNestedAttribute{
+				Computed:     true,
+				Description:  "The delay_overrides of the existing rule.",
+				NestedObject: schema.NestedAttributeObject{Attributes: overrideAttributes},
+			},
+			"priority": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The priority of the existing rule.",
+			},
+			"rollout_percentage": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The rollout_percentage of the existing rule.",
+			},
+			"ingest_sample_rate": schema.Float64Attribute{
+				Computed:    true,
+				Description: "The ingest_sample_rate of the existing rule.",
+			},
+			"tags": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The tags of the existing rule.",
+			},
+		},
+	}
+}
+
+func (d *ruleCloneDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state model.RuleCloneTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	source, metric := state.SourceMetric.ValueString(), state.Metric.ValueString()
+	if metric == source {
+		resp.Diagnostics.AddAttributeError(path.Root("metric"), "Invalid metric", fmt.Sprintf("The metric of the copy must differ from source_metric %q, which already has the rule.", source))
+		return
+	}
+
+	rule, err := d.rules.Read(source)
+	var notFound errRuleNotFound
+	switch {
+	case errors.As(err, &notFound):
+		resp.Diagnostics.AddAttributeError(path.Root("source_metric"), "Aggregation rule not found", fmt.Sprintf("There is no aggregation rule for %q to copy.", source))
+		return
+	case err != nil:
+		resp.Diagnostics.AddError("Unable to read aggregation rule", err.Error())
+		return
+	case len(rule.MatchPrefixes) > 0:
+		resp.Diagnostics.AddAttributeError(path.Root("source_metric"), "Unable to copy aggregation rule", fmt.Sprintf("The rule for %q matches several prefixes, which cannot be copied as a rule for a single metric.", source))
+		return
+	}
+
+	if _, err := d.rules.Read(metric); err == nil {
+		resp.Diagnostics.AddAttributeWarning(path.Root("metric"), "Aggregation rule already exists", fmt.Sprintf("There already is an aggregation rule for %q, which a new rule resource for it would conflict with.", metric))
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, state.Clone(rule))...)
+}
@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readRuleClone(t *testing.T, d *ruleCloneDatasource, source, metric string) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+
+	// The configuration sets the metrics and leaves every other attribute
+	// null.
+	values := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, values.Set(ctx, model.RuleCloneTF{
+		SourceMetric:        types.StringValue(source),
+		Metric:              types.StringValue(metric),
+		MatchType:           types.StringNull(),
+		Drop:                types.BoolNull(),
+		LabelMatchMode:      types.StringNull(),
+		AggregationInterval: types.StringNull(),
+		AggregationDelay:    types.StringNull(),
+		Priority:            types.Int64Null(),
+		RolloutPercentage:   types.Int64Null(),
+		IngestSampleRate:    types.Float64Null(),
+	}).HasError())
+	config := tfsdk.Config{Schema: schemaResp.Schema, Raw: values.Raw}
+	resp := &datasource.ReadResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+func TestRuleCloneDatasource(t *testing.T) {
+	ctx := context.Background()
+	rollout := int64(50)
+	rate := 0.1
+	source := model.AggregationRule{
+		ID:                   "rule-1",
+		Name:                 "kube-pods",
+		Metric:               "team_kube_pod_info",
+		MatchType:            "exact",
+		Drop:                 true,
+		DropIf:               []model.DropMatcher{{Label: "env", Operator: "=~", Value: "dev-.*"}},
+		KeepLabels:           []string{"namespace"},
+		LabelMatchMode:       "glob",
+		Aggregations:         []string{"count", "sum"},
+		AggregationInterval:  "1m",
+		AggregationIntervals: map[string]string{"sum": "5m"},
+		AggregationDelay:     "30s",
+		DelayOverrides:       []model.DelayOverride{{Matcher: model.DropMatcher{Label: "region", Operator: "=", Value: "eu"}, Delay: "2m"}},
+		Priority:             3,
+		RolloutPercentage:    &rollout,
+		IngestSampleRate:     &rate,
+		Tags:                 map[string]string{"team": "infra"},
+		Archived:             true,
+	}
+	api := newMockAPI(t, source)
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+	d := &ruleCloneDatasource{rules: rules}
+
+	resp := readRuleClone(t, d, "kube_pod_info", "kube_node_info")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	var clone model.RuleCloneTF
+	require.False(t, resp.State.Get(ctx, &clone).HasError())
+	require.Equal(t, "kube_pod_info", clone.SourceMetric.ValueString())
+
+	// Every field but the metric and those identifying the rule is copied.
+	got := model.RuleTF{
+		Metric:               clone.Metric,
+		MatchType:            clone.MatchType,
+		Drop:                 clone.Drop,
+		DropIf:               clone.DropIf,
+		KeepLabels:           clone.KeepLabels,
+		DropLabels:           clone.DropLabels,
+		LabelMatchMode:       clone.LabelMatchMode,
+		Aggregations:         clone.Aggregations,
+		AggregationInterval:  clone.AggregationInterval,
+		AggregationIntervals: clone.AggregationIntervals,
+		AggregationDelay:     clone.AggregationDelay,
+		DelayOverrides:       clone.DelayOverrides,
+		Priority:             clone.Priority,
+		RolloutPercentage:    clone.RolloutPercentage,
+		IngestSampleRate:     clone.IngestSampleRate,
+		Tags:                 clone.Tags,
+	}.ToAPIReq()
+	want := source
+	want.ID, want.Name, want.Archived = "", "", false
+	want.Metric = "kube_node_info"
+	require.True(t, got.Equal(want), "differences: %v", got.Differences(want))
+	require.Equal(t, want.DelayOverrides, got.DelayOverrides)
+
+	// Unset attributes are null, for the new resource to use its defaults.
+	api = newMockAPI(t, model.AggregationRule{Metric: "team_up", DropLabels: []string{"pod"}})
+	rules = NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+	resp = readRuleClone(t, &ruleCloneDatasource{rules: rules}, "up", "down")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.False(t, resp.State.Get(ctx, &clone).HasError())
+	require.True(t, clone.MatchType.IsNull())
+	require.Nil(t, clone.KeepLabels)
+	require.True(t, clone.AggregationInterval.IsNull())
+	require.True(t, clone.RolloutPercentage.IsNull())
+	require.Nil(t, clone.Tags)
+}
+
+func TestRuleCloneDatasourceErrors(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "up", Drop: true},
+		model.AggregationRule{Metric: "down", Drop: true},
+		model.AggregationRule{Metric: "kube_", MatchPrefixes: []string{"kube_", "node_"}, Drop: true},
+	)
+	d := &ruleCloneDatasource{rules: api.aggregationRules()}
+
+	for _, tc := range []struct {
+		source, metric string
+		summary        string
+	}{
+		{"missing", "new", "Aggregation rule not found"},
+		{"up", "up", "Invalid metric"},
+		{"kube_", "new", "Unable to copy aggregation rule"},
+	} {
+		resp := readRuleClone(t, d, tc.source, tc.metric)
+		require.True(t, resp.Diagnostics.HasError(), tc.source)
+		require.Equal(t, tc.summary, resp.Diagnostics.Errors()[0].Summary(), tc.source)
+	}
+
+	// Copying onto a metric which already has a rule is only warned about.
+	resp := readRuleClone(t, d, "up", "down")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+}
+
+// TestRuleCloneDatasourceSchema guards that every attribute of the rule
+// resource which configures a rule is copied.
+func TestRuleCloneDatasourceSchema(t *testing.T) {
+	ctx := context.Background()
+	var resourceSchema fwresource.SchemaResponse
+	(&ruleResource{}).Schema(ctx, fwresource.SchemaRequest{}, &resourceSchema)
+	var cloneSchema datasource.SchemaResponse
+	(&ruleCloneDatasource{}).Schema(ctx, datasource.SchemaRequest{}, &cloneSchema)
+
+	notCopied := map[string]bool{
+		// The identity and state of the rule.
+		"id": true, "name": true, "match_prefixes": true, "archived": true,
+		// The settings of the resource rather than the rule.
+		"auto_import": true, "on_conflict": true, "allow_broad_match": true, "ignore_metric_type": true,
+		"ignore_missing_labels": true, "ignore_overlaps": true, "require_recommendation": true, "destroy_action": true,
+		"allow_missing_on_destroy": true, "drain_before_delete": true, "force_outside_window": true, "timeouts": true,
+		// Computed by the resource.
+		"recommended_aggregation_interval": true, "aggregated_metric_names": true, "content_id": true, "effective": true,
+	}
+	var names []string
+	for name := range resourceSchema.Schema.Attributes {
+		names = append(names, name)
+	}
+	for name := range resourceSchema.Schema.Blocks {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if !notCopied[name] {
+			require.Contains(t, cloneSchema.Schema.Attributes, name)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// ruleAttributeTypes are the attributes of the rule objects accepted by
+// rule_defaults, the same as those of the rules of a rule set.
+var ruleAttributeTypes = map[string]attr.Type{
+	"metric":               types.StringType,
+	"match_type":           types.StringType,
+	"drop":                 types.BoolType,
+	"keep_labels":          types.ListType{ElemType: types.StringType},
+	"drop_labels":          types.ListType{ElemType: types.StringType},
+	"aggregations":         types.ListType{ElemType: types.StringType},
+	"aggregation_interval": types.StringType,
+	"aggregation_delay":    types.StringType,
+	"priority":             types.Int64Type,
+}
+
+type ruleDefaultsFunction struct{}
+
+var _ function.Function = &ruleDefaultsFunction{}
+
+func newRuleDefaultsFunction() function.Function {
+	return &ruleDefaultsFunction{}
+}
+
+func (f *ruleDefaultsFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "rule_defaults"
+}
+
+func (f *ruleDefaultsFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Merges an aggregation rule with shared defaults",
+		Description: "Returns the rule with the attributes of base overridden by those set in overrides, so that an aggregation policy can be defined once and applied to many metrics. Both arguments are objects with any of the attributes of a rule of a rule set. Attributes which are missing or null in overrides are taken from base; lists are replaced rather than merged. Attributes set in neither are null.",
+		Parameters: []function.Parameter{
+			function.DynamicParameter{
+				Name:        "base",
+				Description: "The default attributes of the rule.",
+			},
+			function.DynamicParameter{
+				Name:        "overrides",
+				Description: "The attributes of the rule which differ from base, typically including metric.",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttributeTypes: ruleAttributeTypes,
+		},
+	}
+}
+
+func (f *ruleDefaultsFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var base, overrides types.Dynamic
+	resp.Error = req.Arguments.Get(ctx, &base, &overrides)
+	if resp.Error != nil {
+		return
+	}
+
+	baseAttrs, err := ruleObjectAttributes(base)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+	overrideAttrs, err := ruleObjectAttributes(overrides)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+
+	rule, err := mergeRuleAttributes(baseAttrs, overrideAttrs)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, rule)
+}
+
+// ruleObjectAttributes returns the attributes of a rule object, rejecting
+// attributes which rules do not have.
+func ruleObjectAttributes(value types.Dynamic) (map[string]attr.Value, error) {
+	var attrs map[string]attr.Value
+	switch v := value.UnderlyingValue().(type) {
+	case nil:
+		return nil, nil
+	case types.Object:
+		attrs = v.Attributes()
+	case types.Map:
+		attrs = v.Elements()
+	default:
+		return nil, fmt.Errorf("must be an object, got %s", v.Type(context.Background()))
+	}
+
+	var unknown []string
+	for name := range attrs {
+		if _, ok := ruleAttributeTypes[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unsupported rule attributes: %s", strings.Join(unknown, ", "))
+	}
+
+	return attrs, nil
+}
+
+// mergeRuleAttributes returns the rule with the attributes of base overridden
+// by the non-null attributes of overrides.
+func mergeRuleAttributes(base, overrides map[string]attr.Value) (model.RuleSetRuleTF, error) {
+	merged := make(map[string]attr.Value, len(ruleAttributeTypes))
+	for name := range ruleAttributeTypes {
+		if v, ok := overrides[name]; ok && !v.IsNull() {
+			merged[name] = v
+		} else if v, ok := base[name]; ok {
+			merged[name] = v
+		}
+	}
+
+	var rule model.RuleSetRuleTF
+	var err error
+	if rule.Metric, err = toRuleString(merged["metric"]); err != nil {
+		return rule, fmt.Errorf("metric %w", err)
+	}
+	if rule.MatchType, err = toRuleString(merged["match_type"]); err != nil {
+		return rule, fmt.Errorf("match_type %w", err)
+	}
+	if rule.Drop, err = toRuleBool(merged["drop"]); err != nil {
+		return rule, fmt.Errorf("drop %w", err)
+	}
+	if rule.KeepLabels, err = toRuleStrings(merged["keep_labels"]); err != nil {
+		return rule, fmt.Errorf("keep_labels %w", err)
+	}
+	if rule.DropLabels, err = toRuleStrings(merged["drop_labels"]); err != nil {
+		return rule, fmt.Errorf("drop_labels %w", err)
+	}
+	if rule.Aggregations, err = toRuleStrings(merged["aggregations"]); err != nil {
+		return rule, fmt.Errorf("aggregations %w", err)
+	}
+	if rule.AggregationInterval, err = toRuleString(merged["aggregation_interval"]); err != nil {
+		return rule, fmt.Errorf("aggregation_interval %w", err)
+	}
+	if rule.AggregationDelay, err = toRuleString(merged["aggregation_delay"]); err != nil {
+		return rule, fmt.Errorf("aggregation_delay %w", err)
+	}
+	if rule.Priority, err = toRuleInt64(merged["priority"]); err != nil {
+		return rule, fmt.Errorf("priority %w", err)
+	}
+
+	return rule, nil
+}
+
+func toRuleString(v attr.Value) (types.String, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.StringNull(), nil
+	case types.String:
+		return v, nil
+	}
+	return types.String{}, fmt.Errorf("must be a string, got %s", v.Type(context.Background()))
+}
+
+func toRuleBool(v attr.Value) (types.Bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.BoolNull(), nil
+	case types.Bool:
+		return v, nil
+	}
+	return types.Bool{}, fmt.Errorf("must be a bool, got %s", v.Type(context.Background()))
+}
+
+func toRuleInt64(v attr.Value) (types.Int64, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.Int64Null(), nil
+	case types.Int64:
+		return v, nil
+	case types.Number:
+		if v.IsNull() || v.IsUnknown() {
+			return types.Int64Null(), nil
+		}
+		if n, acc := v.ValueBigFloat().Int64(); acc == 0 && v.ValueBigFloat().IsInt() {
+			return types.Int64Value(n), nil
+		}
+		return types.Int64{}, fmt.Errorf("must be a whole number, got %v", v.ValueBigFloat())
+	}
+	return types.Int64{}, fmt.Errorf("must be a number, got %s", v.Type(context.Background()))
+}
+
+func toRuleStrings(v attr.Value) ([]types.String, error) {
+	var elems []attr.Value
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case types.Tuple:
+		if v.IsNull() {
+			return nil, nil
+		}
+		elems = v.Elements()
+	case types.List:
+		if v.IsNull() {
+			return nil, nil
+		}
+		elems = v.Elements()
+	case types.Set:
+		if v.IsNull() {
+			return nil, nil
+		}
+		elems = v.Elements()
+	default:
+		return nil, fmt.Errorf("must be a list of strings, got %s", v.Type(context.Background()))
+	}
+
+	strs := make([]types.String, len(elems))
+	for i, elem := range elems {
+		s, ok := elem.(types.String)
+		if !ok {
+			return nil, fmt.Errorf("must be a list of strings, got %s at index %d", elem.Type(context.Background()), i)
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
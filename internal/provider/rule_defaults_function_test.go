@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// ruleObject returns an object with the given attributes, like an object
+// literal in a configuration.
+func ruleObject(attrs map[string]attr.Value) types.Dynamic {
+	attrTypes := make(map[string]attr.Type, len(attrs))
+	for name, v := range attrs {
+		attrTypes[name] = v.Type(context.Background())
+	}
+	return types.DynamicValue(types.ObjectValueMust(attrTypes, attrs))
+}
+
+func stringTuple(values ...string) types.Tuple {
+	elemTypes := make([]attr.Type, len(values))
+	elems := make([]attr.Value, len(values))
+	for i, v := range values {
+		elemTypes[i] = types.StringType
+		elems[i] = types.StringValue(v)
+	}
+	return types.TupleValueMust(elemTypes, elems)
+}
+
+func runRuleDefaults(t *testing.T, base, overrides types.Dynamic) (model.RuleSetRuleTF, *function.FuncError) {
+	t.Helper()
+	ctx := context.Background()
+
+	req := function.RunRequest{Arguments: function.NewArgumentsData([]attr.Value{base, overrides})}
+	resp := function.RunResponse{Result: function.NewResultData(types.ObjectUnknown(ruleAttributeTypes))}
+	newRuleDefaultsFunction().Run(ctx, req, &resp)
+	if resp.Error != nil {
+		return model.RuleSetRuleTF{}, resp.Error
+	}
+
+	var rule model.RuleSetRuleTF
+	obj, ok := resp.Result.Value().(types.Object)
+	require.True(t, ok)
+	require.False(t, obj.As(ctx, &rule, basetypes.ObjectAsOptions{}).HasError())
+	return rule, nil
+}
+
+func TestRuleDefaultsFunction(t *testing.T) {
+	base := ruleObject(map[string]attr.Value{
+		"match_type":           types.StringValue("prefix"),
+		"drop_labels":          stringTuple("pod", "instance"),
+		"aggregations":         stringTuple("sum:counter"),
+		"aggregation_interval": types.StringValue("1m"),
+		"priority":             types.NumberValue(big.NewFloat(1)),
+	})
+
+	rule, funcErr := runRuleDefaults(t, base, ruleObject(map[string]attr.Value{
+		"metric":       types.StringValue("http_requests_total"),
+		"drop_labels":  stringTuple("pod"),
+		"aggregations": types.TupleValueMust(nil, nil),
+		// Null attributes are taken from the base.
+		"aggregation_interval": types.StringNull(),
+	}))
+	require.Nil(t, funcErr)
+	require.Equal(t, model.RuleSetRuleTF{
+		Metric:    types.StringValue("http_requests_total"),
+		MatchType: types.StringValue("prefix"),
+
+		Drop: types.BoolNull(),
+		// Lists are replaced, not merged, and an empty list replaces the base.
+		DropLabels: []types.String{types.StringValue("pod")},
+
+		Aggregations: []types.String{},
+
+		AggregationInterval: types.StringValue("1m"),
+		AggregationDelay:    types.StringNull(),
+
+		Priority: types.Int64Value(1),
+	}, rule)
+}
+
+func TestRuleDefaultsFunctionErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		overrides types.Dynamic
+		expected  *function.FuncError
+	}{
+		{
+			name:      "not an object",
+			overrides: types.DynamicValue(types.StringValue("up")),
+			expected:  function.NewArgumentFuncError(1, "must be an object, got basetypes.StringType"),
+		},
+		{
+			name:      "unsupported attribute",
+			overrides: ruleObject(map[string]attr.Value{"metric": types.StringValue("up"), "auto_import": types.BoolValue(true)}),
+			expected:  function.NewArgumentFuncError(1, "unsupported rule attributes: auto_import"),
+		},
+		{
+			name:      "wrong type",
+			overrides: ruleObject(map[string]attr.Value{"drop_labels": types.StringValue("pod")}),
+			expected:  function.NewFuncError("drop_labels must be a list of strings, got basetypes.StringType"),
+		},
+		{
+			name:      "fractional priority",
+			overrides: ruleObject(map[string]attr.Value{"priority": types.NumberValue(big.NewFloat(1.5))}),
+			expected:  function.NewFuncError("priority must be a whole number, got 1.5"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, funcErr := runRuleDefaults(t, ruleObject(map[string]attr.Value{}), tc.overrides)
+			require.Equal(t, tc.expected, funcErr)
+		})
+	}
+}
+
+func TestRuleDefaultsFunctionServer(t *testing.T) {
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	require.NoError(t, err)
+
+	argument := func(attrs map[string]tftypes.Value) *tfprotov6.DynamicValue {
+		attrTypes := make(map[string]tftypes.Type, len(attrs))
+		for name, v := range attrs {
+			attrTypes[name] = v.Type()
+		}
+		typ := tftypes.Object{AttributeTypes: attrTypes}
+		v, err := tfprotov6.NewDynamicValue(tftypes.DynamicPseudoType, tftypes.NewValue(typ, attrs))
+		require.NoError(t, err)
+		return &v
+	}
+
+	resp, err := server.CallFunction(ctx, &tfprotov6.CallFunctionRequest{
+		Name: "rule_defaults",
+		Arguments: []*tfprotov6.DynamicValue{
+			argument(map[string]tftypes.Value{
+				"drop_labels": tftypes.NewValue(tftypes.Tuple{ElementTypes: []tftypes.Type{tftypes.String}}, []tftypes.Value{tftypes.NewValue(tftypes.String, "pod")}),
+			}),
+			argument(map[string]tftypes.Value{
+				"metric": tftypes.NewValue(tftypes.String, "up"),
+			}),
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp.Error)
+
+	returnType := tftypes.Object{AttributeTypes: make(map[string]tftypes.Type)}
+	for name, typ := range ruleAttributeTypes {
+		returnType.AttributeTypes[name] = typ.TerraformType(ctx)
+	}
+	result, err := resp.Result.Unmarshal(returnType)
+	require.NoError(t, err)
+
+	var attrs map[string]tftypes.Value
+	require.NoError(t, result.As(&attrs))
+	require.Equal(t, tftypes.NewValue(tftypes.String, "up"), attrs["metric"])
+	require.Equal(t, tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{tftypes.NewValue(tftypes.String, "pod")}), attrs["drop_labels"])
+	require.True(t, attrs["aggregations"].IsNull())
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// ruleIdentityFields are the fields of a rule which select the metrics it
+// applies to, or describe it, rather than how it aggregates them.
+var ruleIdentityFields = []string{"name", "metric", "match_type", "match_prefixes", "priority", "tags", "archived"}
+
+type ruleOverridesDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &ruleOverridesDatasource{}
+	_ datasource.DataSourceWithConfigure = &ruleOverridesDatasource{}
+)
+
+func newRuleOverridesDatasource() datasource.DataSource {
+	return &ruleOverridesDatasource{}
+}
+
+func (d *ruleOverridesDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rules = data.aggRules
+}
+
+func (d *ruleOverridesDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rule_overrides", req.ProviderTypeName)
+}
+
+func (d *ruleOverridesDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists the exact aggregation rules whose metric is also matched by a broader prefix rule, and whether each of them changes how the metric is aggregated, so that overrides can be reviewed for being intentional. Nothing is changed.\n\n" +
+			"Of the rules matching a metric, the one with the highest `priority` is applied; among rules of the same priority, exact rules take precedence over prefix rules, and longer prefixes over shorter ones. An exact rule which only repeats the aggregation of the prefix rule it overrides is redundant, while an exact rule which a prefix rule of higher priority outranks has no effect. Archived rules match no metrics, so they are not listed.",
+		Attributes: map[string]schema.Attribute{
+			"overrides": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The exact rules whose metric is also matched by a prefix rule, ordered by metric.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The metric of the exact rule.",
+						},
+						"prefix_rules": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The metric of every prefix rule which also matches the metric, in order of precedence.",
+						},
+						"overridden_rule": schema.StringAttribute{
+							Computed:    true,
+							Description: "The metric of the prefix rule which would be applied to the metric without the exact rule.",
+						},
+						"applied": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the exact rule is applied to the metric; false if a prefix rule of higher priority is applied instead.",
+						},
+						"changes_behavior": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the exact rule is applied and aggregates the metric differently from the overridden rule.",
+						},
+						"differing_fields": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The fields in which the exact rule aggregates the metric differently from the overridden rule, such as drop_labels or aggregations.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *ruleOverridesDatasource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	state := model.RuleOverridesTF{Overrides: ruleOverrides(d.rules.List())}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// ruleOverrides finds the exact rules among rules whose metric is also
+// matched by a prefix rule, ordered by metric.
+func ruleOverrides(rules []model.AggregationRule) []model.RuleOverrideTF {
+	isExact := func(rule model.AggregationRule) bool {
+		return len(rule.MatchPrefixes) == 0 && (rule.MatchType == "" || rule.MatchType == "exact")
+	}
+	isPrefix := func(rule model.AggregationRule) bool {
+		return len(rule.MatchPrefixes) > 0 || rule.MatchType == "prefix"
+	}
+
+	overrides := []model.RuleOverrideTF{}
+	for _, rule := range rules {
+		if rule.Archived || !isExact(rule) {
+			continue
+		}
+
+		governing := governingRules(rules, rule.Metric)
+		var prefixRules []model.AggregationRule
+		for _, other := range governing {
+			if isPrefix(other) {
+				prefixRules = append(prefixRules, other)
+			}
+		}
+		if len(prefixRules) == 0 {
+			continue
+		}
+
+		applied := governing[0].Metric == rule.Metric && isExact(governing[0])
+		var differing []string
+		for _, field := range rule.Differences(prefixRules[0]) {
+			if !slices.Contains(ruleIdentityFields, field) {
+				differing = append(differing, field)
+			}
+		}
+
+		overrides = append(overrides, model.ToRuleOverrideTF(rule, prefixRules, applied, differing))
+	}
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Metric.ValueString() < overrides[j].Metric.ValueString()
+	})
+	return overrides
+}
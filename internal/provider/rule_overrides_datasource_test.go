@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestRuleOverrides(t *testing.T) {
+	overrides := ruleOverrides([]model.AggregationRule{
+		{Metric: "kube_", MatchType: "prefix", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}},
+		{Metric: "kube_node_", MatchType: "prefix", DropLabels: []string{"node"}, Priority: 10},
+		{Metric: "node_", MatchPrefixes: []string{"node_", "process_"}, Drop: true},
+		// Repeats the prefix rule; only its identity differs.
+		{Metric: "kube_pod_info", Name: "pod-info", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}, Tags: map[string]string{"team": "infra"}},
+		{Metric: "kube_deployment_labels", DropLabels: []string{"pod", "instance"}, Aggregations: []string{"sum"}},
+		// Outranked by the prefix rule of higher priority.
+		{Metric: "kube_node_info", Drop: true},
+		{Metric: "process_cpu_seconds_total", DropLabels: []string{"pod"}},
+		{Metric: "http_requests_total", DropLabels: []string{"pod"}},
+		{Metric: "kube_job_status", DropLabels: []string{"job"}, Archived: true},
+		{Metric: "_info", MatchType: "suffix", Drop: true},
+	})
+
+	type override struct {
+		metric      string
+		prefixRules []string
+		overridden  string
+		applied     bool
+		changes     bool
+		differing   []string
+	}
+	var got []override
+	for _, o := range overrides {
+		got = append(got, override{
+			metric:      o.Metric.ValueString(),
+			prefixRules: toStrings(o.PrefixRules),
+			overridden:  o.OverriddenRule.ValueString(),
+			applied:     o.Applied.ValueBool(),
+			changes:     o.ChangesBehavior.ValueBool(),
+			differing:   toStrings(o.DifferingFields),
+		})
+	}
+	require.Equal(t, []override{
+		{"kube_deployment_labels", []string{"kube_"}, "kube_", true, true, []string{"drop_labels"}},
+		{"kube_node_info", []string{"kube_node_", "kube_"}, "kube_node_", false, false, []string{"drop", "drop_labels"}},
+		{"kube_pod_info", []string{"kube_"}, "kube_", true, false, []string{}},
+		{"process_cpu_seconds_total", []string{"node_"}, "node_", true, true, []string{"drop", "drop_labels"}},
+	}, got)
+}
+
+// toStrings returns the values of a list attribute.
+func toStrings(in []types.String) []string {
+	out := make([]string, len(in))
+	for i, s := range in {
+		out[i] = s.ValueString()
+	}
+	return out
+}
+
+func TestRuleOverridesDatasourceRead(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "team_kube_", MatchType: "prefix", DropLabels: []string{"pod"}},
+		model.AggregationRule{Metric: "team_kube_pod_info", DropLabels: []string{"pod", "uid"}},
+		model.AggregationRule{Metric: "other_kube_pod_info", DropLabels: []string{"uid"}},
+	)
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+	d := &ruleOverridesDatasource{rules: rules}
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	// Rules outside of the metric prefix are not managed, so not listed.
+	var state model.RuleOverridesTF
+	require.False(t, resp.State.Get(ctx, &state).HasError())
+	require.Len(t, state.Overrides, 1)
+	require.Equal(t, "kube_pod_info", state.Overrides[0].Metric.ValueString())
+	require.Equal(t, "kube_", state.Overrides[0].OverriddenRule.ValueString())
+	require.True(t, state.Overrides[0].ChangesBehavior.ValueBool())
+}
@@ -2,26 +2,57 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
 )
 
+const (
+	destroyActionDelete  = "delete"
+	destroyActionArchive = "archive"
+
+	onConflictUpdate = "update"
+	onConflictAdopt  = "adopt"
+)
+
 type ruleResource struct {
-	rules *AggregationRules
+	rules               *AggregationRules
+	capabilities        *capabilities
+	autoImport          bool
+	broadMatchMinLength int
+	minSeriesCount      int
+	savings             *savingsBudget
+	schemaValidate      bool
+	requiredKeepLabels  []string
+
+	allowedMetricPatterns []string
+
+	warnShortAggregationDelay bool
+	warnOnDropDrift           bool
+	enforceOwnership          bool
 }
 
 var (
-	_ resource.Resource                = &ruleResource{}
-	_ resource.ResourceWithConfigure   = &ruleResource{}
-	_ resource.ResourceWithImportState = &ruleResource{}
+	_ resource.Resource                   = &ruleResource{}
+	_ resource.ResourceWithConfigure      = &ruleResource{}
+	_ resource.ResourceWithImportState    = &ruleResource{}
+	_ resource.ResourceWithModifyPlan     = &ruleResource{}
+	_ resource.ResourceWithValidateConfig = &ruleResource{}
 )
 
 func newRuleResource() resource.Resource {
@@ -43,18 +74,44 @@ func (r *ruleResource) Configure(_ context.Context, req resource.ConfigureReques
 	}
 
 	r.rules = data.aggRules
+	r.capabilities = data.capabilities
+	r.autoImport = data.autoImport
+	r.broadMatchMinLength = data.broadMatchMinLength
+	r.minSeriesCount = data.minSeriesCount
+	r.savings = data.savings
+	r.schemaValidate = data.schemaValidate
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+	r.warnShortAggregationDelay = data.warnShortAggregationDelay
+	r.warnOnDropDrift = data.warnOnDropDrift
+	r.enforceOwnership = data.enforceOwnership
 }
 
 func (r *ruleResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = fmt.Sprintf("%s_rule", req.ProviderTypeName)
 }
 
-func (r *ruleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *ruleResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID assigned to the rule by the API, if any. Unlike the metric, it does not change when the rule is renamed.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Description: "A stable name identifying the rule independently of its metric, for rules whose metric changes over time. The rule is found by its ID, then by its name, and only then by its metric, so it is still found after its metric was changed outside of Terraform, and it can be imported by its name. Names must be unique. The name is stored in the API by backends which support rule names, and otherwise only in the Terraform state, in which case the rule is found by its ID.",
+			},
 			"metric": schema.StringAttribute{
-				Required:    true,
-				Description: "The name of the metric to be aggregated.",
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the metric to be aggregated. If the rule has an ID, changing the metric renames the rule in place. Otherwise the new rule is created before the old one is deleted, so both rules briefly coexist and may both apply to overlapping series. Must be set unless match_prefixes is, in which case it is the first prefix.",
+				PlanModifiers: []planmodifier.String{
+					metricFromMatchPrefixes{},
+				},
 			},
 			"match_type": schema.StringAttribute{
 				Optional:    true,
@@ -62,6 +119,11 @@ func (r *ruleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp
 				Default:     stringdefault.StaticString(""),
 				Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
 			},
+			"match_prefixes": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Set instead of metric and match_type to match the metrics starting with any of the listed prefixes, rather than duplicating the rule for each prefix. The API identifies the rule by its first prefix, so changing it renames the rule. Requires a backend which supports rules with several prefixes.",
+			},
 
 			"drop": schema.BoolAttribute{
 				Optional:    true,
@@ -73,23 +135,33 @@ func (r *ruleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp
 				ElementType: types.StringType,
 				Optional:    true,
 				Computed:    true,
-				Default:     defaultEmptyList{},
-				Description: "The array of labels to keep; labels not in this array will be aggregated.",
+				Description: "The array of labels to keep; labels not in this array will be aggregated. " + unmanagedListDescription,
+				PlanModifiers: []planmodifier.List{
+					useStateWhenUnset{},
+				},
 			},
 			"drop_labels": schema.ListAttribute{
 				ElementType: types.StringType,
 				Optional:    true,
 				Computed:    true,
-				Default:     defaultEmptyList{},
-				Description: "The array of labels that will be aggregated.",
+				Description: "The array of labels that will be aggregated. " + unmanagedListDescription,
+				PlanModifiers: []planmodifier.List{
+					useStateWhenUnset{},
+				},
+			},
+			"label_match_mode": schema.StringAttribute{
+				Optional:    true,
+				Description: "How the entries of keep_labels and drop_labels match label names: 'exact', 'glob' for shell patterns such as \"k8s_*\", or 'regex' for regular expressions which must match the whole label name. Defaults to 'exact'. Patterns require a backend which supports label patterns.",
 			},
 
-			"aggregations": schema.ListAttribute{
+			"aggregations": schema.SetAttribute{
 				ElementType: types.StringType,
 				Optional:    true,
 				Computed:    true,
-				Default:     defaultEmptyList{},
-				Description: "The array of aggregation types to calculate for this metric.",
+				Description: "The set of aggregation types to calculate for this metric. Percentiles are written as p<percent>, such as p99 or p99.9, and require a backend which supports them. Set to [\"*\"] to calculate every aggregation type supported by the API. As the order does not matter, plans show the aggregation types which are added and removed rather than a replaced list. " + unmanagedListDescription,
+				PlanModifiers: []planmodifier.Set{
+					useStateWhenUnset{},
+				},
 			},
 
 			"aggregation_interval": schema.StringAttribute{
@@ -98,6 +170,11 @@ func (r *ruleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp
 				Default:     stringdefault.StaticString(""),
 				Description: "The interval at which to generate the aggregated series.",
 			},
+			"aggregation_intervals": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Intervals which override aggregation_interval for some of the aggregation types, keyed by type, such as { max = \"5m\" } to compute sum every aggregation_interval but max every 5 minutes. The types must be in aggregations. Requires a backend which supports per-aggregation intervals.",
+			},
 			"aggregation_delay": schema.StringAttribute{
 				Optional:    true,
 				Computed:    true,
@@ -105,16 +182,474 @@ func (r *ruleResource) Schema(_ context.Context, _ resource.SchemaRequest, resp
 				Description: "The delay until aggregation is performed.",
 			},
 
+			"priority": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+				Description: "The priority of the rule. When several rules match a metric, the rule with the highest priority is applied. Must not be negative. Priorities other than 0 require a backend which supports rule priorities. Defaults to 0.",
+			},
+			"rollout_percentage": schema.Int64Attribute{
+				Optional:    true,
+				Description: "The percentage, between 0 and 100, of the series matched by the rule which are aggregated, to roll out a risky rule gradually. The other series are ingested unchanged. Every series is aggregated if unset, as with 100. Requires a backend which supports rollouts.",
+			},
+			"ingest_sample_rate": schema.Float64Attribute{
+				Optional:    true,
+				Description: "The share, greater than 0 and at most 1, of the series matched by the rule which are ingested, sampled before they are aggregated, to cut the cost of very high-volume metrics further than aggregation alone. Unlike rollout_percentage, the series which are not sampled are not ingested at all. Every series is ingested if unset, as with 1. Cannot be set on rules which drop their metric. Requires a backend which supports ingestion sampling.",
+			},
+			"tags": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Description: "Tags to categorize the rule by, such as its owner or criticality. Keys must start with a letter and contain only letters, digits, '_', '.', or '-'. Requires a backend which supports rule tags. " + unmanagedMapDescription,
+				PlanModifiers: []planmodifier.Map{
+					useStateWhenUnset{},
+				},
+			},
+
 			"auto_import": schema.BoolAttribute{
+				Optional: true,
+				// Computed so that the false of the states of earlier
+				// releases, which defaulted it, is kept rather than planned
+				// as a change to null.
+				Computed:      true,
+				PlanModifiers: []planmodifier.Bool{useStateWhenUnset{}},
+				Description:   "When set to true, the rule will be automatically imported if it is not already in Terraform state. Defaults to the provider's auto_import setting.",
+			},
+			"on_conflict": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(onConflictFail),
+				Description: "What happens when the rule is created but a rule for the metric already exists. Can be 'fail', which fails the apply, 'update', which overwrites the existing rule with the configuration, or 'adopt', which imports the existing rule into Terraform state and leaves it unchanged until the next apply. Only applies when auto_import is disabled. Defaults to 'fail'.",
+			},
+			"allow_broad_match": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to allow a prefix or suffix rule whose metric is shorter than the provider's broad_match_min_length. Such rules aggregate a large share of all metrics.",
+			},
+			"ignore_metric_type": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to suppress the warnings about aggregations which are not meaningful for the type of the metric, such as sum on the running totals of a counter rather than sum:counter. The type is taken from the metric metadata of backends which provide it, and may be wrong, for example for metrics whose instrumentation reports no type.",
+			},
+			"ignore_missing_labels": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to suppress the warnings about entries of keep_labels and drop_labels which match none of the labels of the metric, such as a misspelt label, which are otherwise no-ops. The labels are taken from the metric metadata of backends which provide them, and change over time, for example when a label is only added by a later release of the instrumentation.",
+			},
+			"ignore_overlaps": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to suppress the warnings about existing rules with the same priority which may match the same metrics as the rule, such as an exact rule and a prefix rule it starts with, or nested suffix rules like _total and _bytes_total. Whichever of them the backend applies is then undefined, so only suppress them when that does not matter or the rules are identical.",
+			},
+			"force_outside_window": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to create, update, or delete the rule even outside of the provider's apply_window. The value in state is used when the resource is destroyed.",
+			},
+			"require_recommendation": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to only create the rule if the recommendations service currently recommends a rule for the metric. Otherwise the apply fails without creating it, so that rules which are no longer recommended are not added. Only checked when the rule is created.",
+			},
+			"destroy_action": schema.StringAttribute{
 				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(destroyActionDelete),
+				Description: "What happens to the rule when the resource is destroyed. Can be 'delete', which removes the rule permanently, or 'archive', which keeps the rule in the API without applying it so that it can be restored. 'archive' requires a backend which supports archived rules. Defaults to 'delete'.",
+			},
+			"allow_missing_on_destroy": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to consider the rule destroyed if it no longer exists in the API, for example because it was deleted outside of Terraform. Other errors still fail the destroy. Defaults to false.",
+			},
+			"drain_before_delete": schema.StringAttribute{
+				Optional:    true,
+				Description: "A duration such as \"15m\" for which the rule is archived before it is deleted on destroy, so that the metric is ingested unaggregated again while the rule still exists and dashboards can be moved off its aggregated series before it is gone. The destroy waits for the whole period, which must therefore fit in the delete timeout. Only applies with destroy_action = 'delete', and requires a backend which supports archived rules.",
+			},
+			"archived": schema.BoolAttribute{
 				Computed:    true,
 				Default:     defaultBoolFalse{},
-				Description: "When set to true, the rule will be automatically imported if it is not already in Terraform state.",
+				Description: "Whether the rule is archived and therefore not applied. A rule which was archived outside of Terraform is restored on the next apply.",
+			},
+			"recommended_aggregation_interval": schema.StringAttribute{
+				Computed:    true,
+				Description: "The aggregation interval which the API recommends for the metric based on its scrape interval, to compare with aggregation_interval. It is only a hint and never applied. Null if the API offers no recommendation.",
+				PlanModifiers: []planmodifier.String{
+					useStateForHint{},
+				},
+			},
+			"aggregated_metric_names": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The names of the aggregated series which the rule writes, one <metric>:<aggregation> for each aggregation type ordered by type, for example to update dashboards and queries to use them. The metric includes the provider's metric_prefix. Empty if the rule drops its metric. Null for prefix and suffix rules, whose names depend on the matched metrics, and on backends which do not name aggregated series this way.",
+			},
+			"content_id": schema.StringAttribute{
+				Computed:    true,
+				Description: "A hash of what the rule does: its metric and match, drop settings, labels, aggregations, intervals, priority, and rollout. It stays the same as long as they do, whatever the order of their lists, so that external tooling can track the rule by its content rather than by its ID or metric. The ID, tags, archived, and the attributes which only configure the provider are not part of it.",
+			},
+			"effective": schema.SingleNestedAttribute{
+				Computed:    true,
+				Description: "The values the rule is applied with, once the defaults of the provider and the API are resolved, to see what a rule which leaves attributes unset actually does. Null if they cannot be resolved, such as when the API does not report its supported aggregations.",
+				Attributes: map[string]schema.Attribute{
+					"metric": schema.StringAttribute{
+						Computed:    true,
+						Description: "The metric sent to the API, including the provider's metric_prefix.",
+					},
+					"match_type": schema.StringAttribute{
+						Computed:    true,
+						Description: "How the metric matches metric names: 'exact' unless match_type is set, and 'prefix' with match_prefixes.",
+					},
+					"label_match_mode": schema.StringAttribute{
+						Computed:    true,
+						Description: "How keep_labels and drop_labels match label names: 'exact' unless label_match_mode is set.",
+					},
+					"aggregations": schema.ListAttribute{
+						ElementType: types.StringType,
+						Computed:    true,
+						Description: "The aggregation types computed for the metric, ordered by type, with [\"*\"] expanded to the types supported by the API. Empty if the rule drops its metric.",
+					},
+					"aggregation_intervals": schema.MapAttribute{
+						ElementType: types.StringType,
+						Computed:    true,
+						Description: "The interval at which each aggregation type is computed: its entry in aggregation_intervals, or else aggregation_interval. Types with neither are computed at the default interval of the API, which it does not report, and are omitted.",
+					},
+					"rollout_percentage": schema.Int64Attribute{
+						Computed:    true,
+						Description: "The percentage of the matched series which are aggregated: 100 unless rollout_percentage is set.",
+					},
+					"auto_import": schema.BoolAttribute{
+						Computed:    true,
+						Description: "Whether an existing rule for the metric is imported rather than created: auto_import if it is set, and otherwise the provider's auto_import.",
+					},
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
+			"drop_if": schema.ListNestedBlock{
+				Description: "Label matchers restricting drop to the series which match all of them, for example to drop only the series of a test environment, or with '!=' every series but those of production. Matchers for the same label which contradict each other, so that no series would be dropped, are rejected. May only be used with drop = true, and requires an API which supports conditional drops.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"label": schema.StringAttribute{
+							Required:    true,
+							Description: "The name of the label to match.",
+						},
+						"operator": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     stringdefault.StaticString("="),
+							Description: "How the label value is matched, as in Prometheus selectors. Can be '=', '!=', '=~', or '!~'. Defaults to '='.",
+						},
+						"value": schema.StringAttribute{
+							Required:    true,
+							Description: "The value, or for '=~' and '!~' the regular expression, to match.",
+						},
+					},
+				},
+			},
+			"delay_overrides": schema.ListNestedBlock{
+				Description: "Aggregation delays for the series matching a label matcher, for sources whose samples arrive later than the others. The first override matching a series applies, and aggregation_delay to series matching none. Requires an API which supports delay overrides.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"label": schema.StringAttribute{
+							Required:    true,
+							Description: "The name of the label to match.",
+						},
+						"operator": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     stringdefault.StaticString("="),
+							Description: "How the label value is matched, as in Prometheus selectors. Can be '=', '!=', '=~', or '!~'. Defaults to '='.",
+						},
+						"value": schema.StringAttribute{
+							Required:    true,
+							Description: "The value, or for '=~' and '!~' the regular expression, to match.",
+						},
+						"delay": schema.StringAttribute{
+							Required:    true,
+							Description: "The aggregation delay of the matching series, as a Prometheus duration such as 5m.",
+						},
+					},
+				},
 			},
 		},
 	}
 }
 
+func (r *ruleResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	if !req.Config.Raw.IsFullyKnown() {
+		return
+	}
+
+	var cfg model.RuleTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	switch {
+	case cfg.MatchPrefixes != nil:
+		if !cfg.Metric.IsNull() || !cfg.MatchType.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("match_prefixes"),
+				"Conflicting match configuration",
+				"match_prefixes selects the metrics of the rule by their prefixes, so metric and match_type must not be set.",
+			)
+		}
+		if len(cfg.MatchPrefixes) == 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("match_prefixes"), "No match prefixes", "At least one prefix must be listed.")
+			return
+		}
+		resp.Diagnostics.Append(validateMetricList(cfg.MatchPrefixes, path.Root("match_prefixes"))...)
+	case cfg.Metric.IsNull():
+		resp.Diagnostics.AddAttributeError(path.Root("metric"), "Missing metric", "Either metric or match_prefixes must be set.")
+		return
+	}
+
+	resp.Diagnostics.Append(validateRule(cfg.ToAPIReq(), path.Empty())...)
+	resp.Diagnostics.Append(validateTimeouts(ctx, cfg.Timeouts)...)
+
+	switch cfg.DestroyAction.ValueString() {
+	case "", destroyActionDelete, destroyActionArchive:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("destroy_action"),
+			"Invalid destroy_action",
+			fmt.Sprintf("The rule for %q has destroy_action %q; it must be one of 'delete' or 'archive'.", cfg.Metric.ValueString(), cfg.DestroyAction.ValueString()),
+		)
+	}
+
+	switch cfg.OnConflict.ValueString() {
+	case "", onConflictFail, onConflictUpdate, onConflictAdopt:
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("on_conflict"),
+			"Invalid on_conflict",
+			fmt.Sprintf("The rule for %q has on_conflict %q; it must be one of 'fail', 'update', or 'adopt'.", cfg.Metric.ValueString(), cfg.OnConflict.ValueString()),
+		)
+	}
+
+	if drain := cfg.DrainBeforeDelete; !drain.IsNull() {
+		if d, err := time.ParseDuration(drain.ValueString()); err != nil || d <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("drain_before_delete"),
+				"Invalid drain_before_delete",
+				fmt.Sprintf("Got %q; it must be a positive duration such as \"15m\".", drain.ValueString()),
+			)
+		}
+		if cfg.DestroyAction.ValueString() == destroyActionArchive {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("drain_before_delete"),
+				"Conflicting drain_before_delete",
+				"drain_before_delete only applies when the rule is deleted on destroy, but destroy_action 'archive' keeps it.",
+			)
+		}
+	}
+}
+
+func (r *ruleResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.rules != nil && req.Plan.Raw.IsNull() && r.savings != nil {
+		var state model.RuleTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if !resp.Diagnostics.HasError() {
+			resp.Diagnostics.Append(r.estimateRemoval(ctx, state.ToAPIReq())...)
+		}
+		return
+	}
+	if r.rules == nil || req.Plan.Raw.IsNull() || !ruleKnown(req.Plan.Raw) {
+		return
+	}
+
+	var plan model.RuleTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planned := plan.ToAPIReq()
+	resp.Diagnostics.Append(r.capabilities.requireRule(planned)...)
+	if archivesOnDestroy(plan) {
+		resp.Diagnostics.Append(r.capabilities.require(client.FeatureArchivedRules)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if r.schemaValidate {
+		resp.Diagnostics.Append(validateRuleSchema(planned, path.Empty())...)
+	}
+	if !plan.AllowBroadMatch.ValueBool() {
+		resp.Diagnostics.Append(validateBroadMatch(planned, r.broadMatchMinLength, path.Empty())...)
+	}
+	resp.Diagnostics.Append(validateRequiredKeepLabels(planned, r.requiredKeepLabels, path.Empty())...)
+	resp.Diagnostics.Append(validateAllowedMetric(planned, r.allowedMetricPatterns, path.Empty())...)
+	resp.Diagnostics.Append(validateAggregationDelay(planned, r.warnShortAggregationDelay, path.Empty())...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if names, err := r.rules.aggregatedMetricNames(planned); err == nil {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("aggregated_metric_names"), names)...)
+	}
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("content_id"), planned.ContentID())...)
+	if effective, err := r.effective(ctx, r.rules, plan, planned); err == nil {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("effective"), effective)...)
+	}
+
+	var prior *model.AggregationRule
+	if !req.State.Raw.IsNull() {
+		var state model.RuleTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		rule := state.ToAPIReq()
+		if rule.Equal(planned) {
+			return
+		}
+		prior = &rule
+	}
+
+	if named, err := r.rules.ReadByName(planned.Name); err == nil {
+		own := named.Metric == planned.Metric || (prior != nil && (named.Metric == prior.Metric || (named.ID != "" && named.ID == prior.ID)))
+		if !own {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("name"),
+				"Duplicate rule name",
+				fmt.Sprintf("The existing rule for %q is already named %q. Rule names must be unique.", named.Metric, planned.Name),
+			)
+			return
+		}
+	}
+
+	if !plan.IgnoreOverlaps.ValueBool() {
+		resp.Diagnostics.Append(r.checkOverlaps(planned, prior)...)
+	}
+
+	resp.Diagnostics.Append(r.checkMetadata(ctx, plan, planned)...)
+	resp.Diagnostics.Append(checkDeprecatedAggregations(ctx, r.rules, planned)...)
+	resp.Diagnostics.Append(r.estimateImpact(ctx, planned, prior)...)
+}
+
+// checkOverlaps warns about the existing rules other than prior which have the
+// same priority as rule and may match some of the same metrics, as the backend
+// may apply either of them. Nested prefix or suffix rules, where every metric
+// matched by one is also matched by the other, are reported as such.
+func (r *ruleResource) checkOverlaps(rule model.AggregationRule, prior *model.AggregationRule) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, other := range r.rules.List() {
+		if other.Metric == rule.Metric || (prior != nil && other.Metric == prior.Metric) {
+			continue
+		}
+		if other.Priority != rule.Priority || !rulesOverlap(rule, other) {
+			continue
+		}
+
+		detail := fmt.Sprintf("The rule for %q and the existing rule for %q may match the same metrics and both have priority %d, so it is undefined which of them is applied.", rule.Metric, other.Metric, rule.Priority)
+		if broader, narrower, ok := nestedRules(rule, other); ok {
+			detail = fmt.Sprintf("Every metric matched by the %s rule for %q is also matched by the %s rule for %q, and both have priority %d, so it is undefined which of them is applied to those metrics.", narrower.MatchType, narrower.Metric, broader.MatchType, broader.Metric, rule.Priority)
+		}
+		diags.AddAttributeWarning(path.Root("priority"), "Overlapping aggregation rules with the same priority", detail+" Give the rules different priorities, or set ignore_overlaps to true if this is intended.")
+	}
+	return diags
+}
+
+// checkMetadata warns about the parts of rule which do not fit the metadata
+// of its metric: aggregations which are not meaningful for its type, unless
+// ignore_metric_type is set, and label entries which match none of its labels,
+// unless ignore_missing_labels is set. Like the estimate, the checks are
+// advisory, so they are skipped when the metadata cannot be read. Rules which
+// match several metrics may match metrics of different types and labels, so
+// they are not checked.
+func (r *ruleResource) checkMetadata(ctx context.Context, plan model.RuleTF, rule model.AggregationRule) diag.Diagnostics {
+	checkType, checkLabels := !plan.IgnoreMetricType.ValueBool(), !plan.IgnoreMissingLabels.ValueBool()
+	if rule.Drop || (rule.MatchType != "" && rule.MatchType != "exact") || len(rule.MatchPrefixes) > 0 || (!checkType && !checkLabels) {
+		return nil
+	}
+	if r.capabilities != nil {
+		if supported, err := r.capabilities.supports(client.FeatureMetricMetadata); err != nil || !supported {
+			return nil
+		}
+	}
+
+	metadata, err := r.rules.MetricMetadata(rule.Metric)
+	if err != nil {
+		tflog.Debug(ctx, "Unable to read the metadata of the metric", map[string]interface{}{"metric": rule.Metric, "error": err.Error()})
+		return nil
+	}
+
+	var diags diag.Diagnostics
+	if checkType {
+		diags.Append(validateAggregationTypes(rule, metadata.Type, path.Empty())...)
+	}
+	if checkLabels {
+		diags.Append(validateLabelsPresent(rule, metadata.Labels, path.Empty())...)
+	}
+	return diags
+}
+
+// checkDeprecatedAggregations warns about the deprecated aggregation types
+// which rule uses. Like the metric type, the check is advisory, so it is skipped
+// when the deprecations cannot be determined.
+func checkDeprecatedAggregations(ctx context.Context, rules *AggregationRules, rule model.AggregationRule) diag.Diagnostics {
+	deprecated, err := rules.DeprecatedAggregations()
+	if err != nil {
+		tflog.Debug(ctx, "Unable to read the deprecated aggregations", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	return validateDeprecatedAggregations(rule, deprecated, path.Empty())
+}
+
+// estimateImpact reports the estimated change in saved series when the prior
+// rule, if any, is replaced by the planned one, and whether the planned rule
+// matches fewer series than minSeriesCount. The estimate is advisory, so it is
+// skipped when it cannot be computed.
+func (r *ruleResource) estimateImpact(ctx context.Context, planned model.AggregationRule, prior *model.AggregationRule) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	estimate, err := r.rules.Estimate(planned)
+	if err != nil {
+		tflog.Debug(ctx, "Unable to estimate the impact of the aggregation rule", map[string]interface{}{"metric": planned.Metric, "error": err.Error()})
+		return diags
+	}
+
+	if series := estimate.TotalSeriesBeforeAggregation; r.minSeriesCount > 0 && series < int64(r.minSeriesCount) {
+		diags.AddAttributeWarning(
+			path.Root("metric"),
+			"Aggregation rule for a low cardinality metric",
+			fmt.Sprintf("The rule for %q matches %d series, fewer than the provider's min_series_count of %d, so it may not be worth adding. Set min_series_count to 0 to disable this check.", planned.Metric, series, r.minSeriesCount),
+		)
+	}
+
+	if prior == nil {
+		diags.AddWarning(
+			"Estimated impact of aggregation rule",
+			fmt.Sprintf("The rule for %q is estimated to aggregate %d series into %d, saving %d series.",
+				planned.Metric, estimate.TotalSeriesBeforeAggregation, estimate.TotalSeriesAfterAggregation, estimate.SavedSeries()),
+		)
+		return diags
+	}
+
+	priorEstimate, err := r.rules.Estimate(*prior)
+	if err != nil {
+		tflog.Debug(ctx, "Unable to estimate the impact of the aggregation rule", map[string]interface{}{"metric": prior.Metric, "error": err.Error()})
+		return diags
+	}
+
+	diags.AddWarning(
+		"Estimated impact of aggregation rule",
+		fmt.Sprintf("The change to the rule for %q is estimated to change the number of saved series from %d to %d (%+d).",
+			planned.Metric, priorEstimate.SavedSeries(), estimate.SavedSeries(), estimate.SavedSeries()-priorEstimate.SavedSeries()),
+	)
+	diags.Append(r.savings.add(prior.Metric, priorEstimate.SavedSeries()-estimate.SavedSeries(), path.Root("metric"))...)
+	return diags
+}
+
+// estimateRemoval counts the series saved by the prior rule, which is deleted
+// or archived, against the savings budget. Like any estimate, it is skipped
+// when it cannot be computed.
+func (r *ruleResource) estimateRemoval(ctx context.Context, prior model.AggregationRule) diag.Diagnostics {
+	estimate, err := r.rules.Estimate(prior)
+	if err != nil {
+		tflog.Debug(ctx, "Unable to estimate the impact of the aggregation rule", map[string]interface{}{"metric": prior.Metric, "error": err.Error()})
+		return nil
+	}
+	return r.savings.add(prior.Metric, estimate.SavedSeries(), path.Empty())
+}
+
 func (r *ruleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan model.RuleTF
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
@@ -122,33 +657,81 @@ func (r *ruleResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
-	if plan.AutoImport.ValueBool() {
-		_, err := r.rules.Read(plan.Metric.ValueString())
+	timeout, diags := plan.Timeouts.Create(ctx, 0)
+	resp.Diagnostics.Append(diags...)
+	rules, cancel := r.rulesWithTimeout(ctx, timeout, plan.ForceOutsideWindow, &resp.Diagnostics)
+	defer cancel()
+
+	if plan.RequireRecommendation.ValueBool() {
+		recommended, err := rules.Recommended(plan.Metric.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Unable to check the recommendations for the aggregation rule", err.Error())
+			return
+		}
+		if !recommended {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("require_recommendation"),
+				"Aggregation rule is not recommended",
+				fmt.Sprintf("There is no recommendation for a rule for %q, so the rule was not created. Remove the rule, or unset require_recommendation to create it anyway.", plan.Metric.ValueString()),
+			)
+			return
+		}
+	}
+
+	if autoImportEnabled(plan.AutoImport, r.autoImport) {
+		exists, err := rules.Exists(plan.Metric.ValueString())
 		if err != nil {
+			resp.Diagnostics.AddError("Unable to check for an existing aggregation rule", err.Error())
+			return
+		}
+
+		if !exists {
 			// There is no existing rule for this metric; create it.
-			err := r.rules.Create(plan.ToAPIReq())
+			err := rules.Create(plan.ToAPIReq())
 			if err != nil {
-				resp.Diagnostics.AddError("Unable to create aggregation rule", err.Error())
+				resp.Diagnostics.Append(apiRuleError("Unable to create aggregation rule", err, path.Empty())...)
 				return
 			}
 		} else {
-			// There is an existing rule for this metric; update it.
-			err := r.rules.Update(plan.ToAPIReq())
+			// There is an existing rule for this metric; update it, leaving
+			// the fields which are not managed by the resource unchanged.
+			rule := plan.ToAPIReq()
+			if existing, err := rules.Read(rule.Metric); err == nil {
+				rule = withUnmanagedFields(rule, existing)
+			}
+
+			err := rules.Update(rule)
 			if err != nil {
-				resp.Diagnostics.AddError("Unable to update aggregation rule", err.Error())
+				resp.Diagnostics.Append(apiRuleError("Unable to update aggregation rule", err, path.Empty())...)
 				return
 			}
 
 			resp.Diagnostics.AddWarning("Existing aggregation rule for metric found", "The existing rule has been updated and imported into Terraform state; no aggregation rule has been created.")
 		}
 	} else {
-		err := r.rules.Create(plan.ToAPIReq())
+		err := rules.Create(plan.ToAPIReq())
+		var exists errRuleExists
+		if errors.As(err, &exists) {
+			err = r.resolveConflict(rules, plan, err, &resp.Diagnostics)
+		}
 		if err != nil {
-			resp.Diagnostics.AddError("Unable to create aggregation rule", err.Error())
+			resp.Diagnostics.Append(apiRuleError("Unable to create aggregation rule", err, path.Empty())...)
 			return
 		}
 	}
 
+	plan.ID = types.StringValue("")
+	plan.RecommendedAggregationInterval = types.StringNull()
+	rule, err := rules.Read(plan.Metric.ValueString())
+	if err == nil {
+		plan.ID = types.StringValue(rule.ID)
+		plan.RecommendedAggregationInterval = rule.ToTF().RecommendedAggregationInterval
+	} else {
+		rule = plan.ToAPIReq()
+	}
+	resp.Diagnostics.Append(r.setAggregatedMetricNames(ctx, rules, &plan, rule)...)
+	r.setEffective(ctx, rules, &plan, rule)
+	plan.ContentID = types.StringValue(plan.ToAPIReq().ContentID())
 	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
 	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
@@ -160,18 +743,89 @@ func (r *ruleResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
-	rule, err := r.rules.Read(state.Metric.ValueString())
+	timeout, diags := state.Timeouts.Read(ctx, 0)
+	resp.Diagnostics.Append(diags...)
+	rules, cancel := r.rulesWithTimeout(ctx, timeout, state.ForceOutsideWindow, &resp.Diagnostics)
+	defer cancel()
+
+	rule, err := readRule(rules, state)
 	if err != nil {
+		var notFound errRuleNotFound
+		if !errors.As(err, &notFound) {
+			resp.Diagnostics.AddError("Unable to read aggregation rule", err.Error())
+			return
+		}
 		resp.Diagnostics.AddWarning("Unable to read aggregation rule", err.Error())
 		resp.State.RemoveResource(ctx)
 		return
 	}
 
-	tf := rule.ToTF()
+	tf := rules.collapseAllAggregations(rule, state.ToAPIReq().Aggregations).ToTF()
 
 	// AutoImport is a meta field used by this Terraform provider; the API never returns
 	// a value for it so we keep it updated separately.
 	tf.AutoImport = state.AutoImport
+	tf.OnConflict = state.OnConflict
+	tf.AllowBroadMatch = state.AllowBroadMatch
+	tf.IgnoreMetricType = state.IgnoreMetricType
+	tf.IgnoreMissingLabels = state.IgnoreMissingLabels
+	tf.IgnoreOverlaps = state.IgnoreOverlaps
+	tf.ForceOutsideWindow = state.ForceOutsideWindow
+	tf.RequireRecommendation = state.RequireRecommendation
+	tf.DestroyAction = state.DestroyAction
+	tf.AllowMissingOnDestroy = state.AllowMissingOnDestroy
+	if tf.Name.IsNull() {
+		// The backend does not store rule names.
+		tf.Name = state.Name
+	}
+	tf.DrainBeforeDelete = state.DrainBeforeDelete
+	tf.Timeouts = state.Timeouts
+	if tf.DestroyAction.IsNull() {
+		// The resource was imported.
+		tf.DestroyAction = types.StringValue(destroyActionDelete)
+	}
+	if tf.OnConflict.IsNull() {
+		tf.OnConflict = types.StringValue(onConflictFail)
+	}
+	keepNullLists(&tf, state)
+	resp.Diagnostics.Append(r.setAggregatedMetricNames(ctx, rules, &tf, rule)...)
+	r.setEffective(ctx, rules, &tf, rule)
+	tf.ContentID = types.StringValue(tf.ToAPIReq().ContentID())
+
+	if r.warnOnDropDrift && !state.Drop.IsNull() && state.Drop.ValueBool() != rule.Drop {
+		behavior := "no longer drops the metric and aggregates it instead"
+		if rule.Drop {
+			behavior = "now drops the metric entirely instead of aggregating it"
+		}
+		resp.Diagnostics.AddWarning(
+			"Aggregation rule drop changed",
+			fmt.Sprintf("The rule for %q %s, as drop was changed outside of Terraform. It will be reverted on the next apply.", rule.Metric, behavior),
+		)
+	}
+
+	if r.enforceOwnership {
+		switch {
+		case rule.ManagedBy == "":
+			resp.Diagnostics.AddWarning(
+				"Aggregation rule not marked as managed by Terraform",
+				fmt.Sprintf("The rule for %q has no managed_by marker, so it was most likely re-created outside of Terraform. The marker is restored the next time the rule is updated.", rule.Metric),
+			)
+		case !rule.IsManaged():
+			resp.Diagnostics.AddWarning(
+				"Aggregation rule managed by another owner",
+				fmt.Sprintf("The rule for %q is marked as managed by %q, so it was taken over outside of Terraform. The next update of the rule overwrites it and marks it as managed by Terraform again.", rule.Metric, rule.ManagedBy),
+			)
+		}
+	}
+
+	resp.Diagnostics.Append(checkDeprecatedAggregations(ctx, rules, tf.ToAPIReq())...)
+
+	if rule.Archived {
+		resp.Diagnostics.AddWarning(
+			"Aggregation rule is archived",
+			fmt.Sprintf("The rule for %q is archived and not applied. It will be restored on the next apply.", rule.Metric),
+		)
+	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
 }
@@ -189,26 +843,33 @@ func (r *ruleResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
-	if plan.Metric.ValueString() != state.Metric.ValueString() {
-		err := r.rules.Delete(state.ToAPIReq())
-		if err != nil {
-			resp.Diagnostics.AddError("Unable to replace aggregation rule", err.Error())
-			return
-		}
+	timeout, diags := plan.Timeouts.Update(ctx, 0)
+	resp.Diagnostics.Append(diags...)
+	rules, cancel := r.rulesWithTimeout(ctx, timeout, plan.ForceOutsideWindow, &resp.Diagnostics)
+	defer cancel()
 
-		err = r.rules.Create(plan.ToAPIReq())
+	if plan.Metric.ValueString() != state.Metric.ValueString() && state.ID.ValueString() == "" {
+		err := rules.Rename(state.ToAPIReq(), plan.ToAPIReq())
 		if err != nil {
-			resp.Diagnostics.AddError("Unable to replace aggregation rule", err.Error())
+			resp.Diagnostics.Append(apiRuleError("Unable to replace aggregation rule", err, path.Empty())...)
 			return
 		}
 	} else {
-		err := r.rules.Update(plan.ToAPIReq())
+		err := rules.Update(plan.ToAPIReq())
 		if err != nil {
-			resp.Diagnostics.AddError("Unable to update aggregation rule", err.Error())
+			resp.Diagnostics.Append(apiRuleError("Unable to update aggregation rule", err, path.Empty())...)
 			return
 		}
 	}
 
+	rule, err := rules.Read(plan.Metric.ValueString())
+	if err != nil {
+		rule = plan.ToAPIReq()
+	}
+	resp.Diagnostics.Append(r.setAggregatedMetricNames(ctx, rules, &plan, rule)...)
+	r.setEffective(ctx, rules, &plan, rule)
+	plan.ContentID = types.StringValue(plan.ToAPIReq().ContentID())
+
 	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
 	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
 }
@@ -220,12 +881,264 @@ func (r *ruleResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 		return
 	}
 
-	err := r.rules.Delete(state.ToAPIReq())
-	if err != nil {
+	timeout, diags := state.Timeouts.Delete(ctx, 0)
+	resp.Diagnostics.Append(diags...)
+	rules, cancel := r.rulesWithTimeout(ctx, timeout, state.ForceOutsideWindow, &resp.Diagnostics)
+	defer cancel()
+
+	var notFound client.ErrNotFound
+	allowMissing := state.AllowMissingOnDestroy.ValueBool()
+
+	// Backends without archived rules would drop the flag and leave the rule
+	// applied. The state may predate the check during plan.
+	if archivesOnDestroy(state) {
+		resp.Diagnostics.Append(r.capabilities.require(client.FeatureArchivedRules)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if state.DestroyAction.ValueString() == destroyActionArchive {
+		rule := state.ToAPIReq()
+		rule.Archived = true
+		err := rules.Update(rule)
+		if err != nil && !(allowMissing && errors.As(err, &notFound)) {
+			resp.Diagnostics.AddError("Unable to archive aggregation rule", err.Error())
+		}
+		return
+	}
+
+	if !state.DrainBeforeDelete.IsNull() {
+		rule := state.ToAPIReq()
+		rule.Archived = true
+		err := rules.Update(rule)
+		if err != nil {
+			if !(allowMissing && errors.As(err, &notFound)) {
+				resp.Diagnostics.AddError("Unable to drain aggregation rule", err.Error())
+			}
+			return
+		}
+
+		// Invalid durations are reported by ValidateConfig.
+		drain, _ := time.ParseDuration(state.DrainBeforeDelete.ValueString())
+		timer := time.NewTimer(drain)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-rules.context().Done():
+			resp.Diagnostics.AddError(
+				"Aggregation rule drain interrupted",
+				fmt.Sprintf("The rule for %q was archived, but the destroy was aborted before its drain period of %s ended, so it was not deleted: %s. It stays archived and is deleted by the next destroy.", rule.Metric, drain, rules.context().Err()),
+			)
+			return
+		}
+	}
+
+	err := rules.Delete(state.ToAPIReq())
+	if err != nil && !(allowMissing && errors.As(err, &notFound)) {
 		resp.Diagnostics.AddError("Unable to delete aggregation rule", err.Error())
 	}
 }
 
+// archivesOnDestroy reports whether the rule of tf is archived when it is
+// destroyed, to be kept or before it is deleted.
+func archivesOnDestroy(tf model.RuleTF) bool {
+	return tf.DestroyAction.ValueString() == destroyActionArchive || !tf.DrainBeforeDelete.IsNull()
+}
+
+// setAggregatedMetricNames sets the aggregated metric names of tf to those of
+// rule unless they are already known, as they were planned.
+func (r *ruleResource) setAggregatedMetricNames(ctx context.Context, rules *AggregationRules, tf *model.RuleTF, rule model.AggregationRule) diag.Diagnostics {
+	if !tf.AggregatedMetricNames.IsUnknown() && !tf.AggregatedMetricNames.IsNull() {
+		return nil
+	}
+
+	names, err := rules.aggregatedMetricNames(rule)
+	if err != nil {
+		tf.AggregatedMetricNames = types.ListNull(types.StringType)
+		return nil
+	}
+	list, diags := types.ListValueFrom(ctx, types.StringType, names)
+	tf.AggregatedMetricNames = list
+	return diags
+}
+
+// setEffective sets the effective values of tf to those of rule, unless they
+// were planned already. They are null if they cannot be resolved.
+func (r *ruleResource) setEffective(ctx context.Context, rules *AggregationRules, tf *model.RuleTF, rule model.AggregationRule) {
+	if !tf.Effective.IsUnknown() && !tf.Effective.IsNull() {
+		return
+	}
+
+	effective, err := r.effective(ctx, rules, *tf, rule)
+	if err != nil {
+		tf.Effective = types.ObjectNull(model.EffectiveRuleAttributeTypes)
+		return
+	}
+	tf.Effective = effective
+}
+
+// effective returns the effective values of rule, which is configured by tf.
+func (r *ruleResource) effective(ctx context.Context, rules *AggregationRules, tf model.RuleTF, rule model.AggregationRule) (types.Object, error) {
+	qualified, err := rules.qualify(rule)
+	if err != nil {
+		return types.Object{}, err
+	}
+	effective := model.EffectiveRule(qualified, autoImportEnabled(tf.AutoImport, r.autoImport))
+	obj, diags := types.ObjectValueFrom(ctx, model.EffectiveRuleAttributeTypes, effective)
+	if diags.HasError() {
+		return types.Object{}, fmt.Errorf("unable to convert the effective values: %v", diags)
+	}
+	return obj, nil
+}
+
+// readRule reads the rule of state by its ID, falling back to its name and then
+// to its metric if no rule with the ID exists, so that rules renamed outside of
+// Terraform are still found.
+func readRule(rules *AggregationRules, state model.RuleTF) (model.AggregationRule, error) {
+	if id := state.ID.ValueString(); id != "" {
+		if rule, err := rules.ReadByID(id); err == nil {
+			return rule, nil
+		}
+	}
+	if name := state.Name.ValueString(); name != "" {
+		if rule, err := rules.ReadByName(name); err == nil {
+			return rule, nil
+		}
+	}
+	return rules.Read(state.Metric.ValueString())
+}
+
+// rulesWithTimeout returns the rules with requests bounded by timeout, one of
+// the timeouts of the resource, or unbounded if it is zero. Warnings returned by the API are added to
+// diags. If force is set, the rules are changed even outside of the apply
+// window.
+func (r *ruleResource) rulesWithTimeout(ctx context.Context, timeout time.Duration, force types.Bool, diags *diag.Diagnostics) (*AggregationRules, context.CancelFunc) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	rules := r.rules.WithContext(ctx).WithWarnings(apiWarnings(diags))
+	if force.ValueBool() {
+		rules = rules.WithoutApplyWindow()
+	}
+	return rules, cancel
+}
+
+// computedRuleAttributes are the attributes of a rule which are set by the
+// provider or the API rather than configured.
+var computedRuleAttributes = map[string]bool{
+	"id":                               true,
+	"recommended_aggregation_interval": true,
+	"aggregated_metric_names":          true,
+	"content_id":                       true,
+	"effective":                        true,
+}
+
+// ruleKnown reports whether all attributes of a planned rule are known, other
+// than those set by the API, which are only known once the rule has been
+// created.
+func ruleKnown(plan tftypes.Value) bool {
+	var attrs map[string]tftypes.Value
+	if err := plan.As(&attrs); err != nil {
+		return false
+	}
+	for name, v := range attrs {
+		if !computedRuleAttributes[name] && !v.IsFullyKnown() {
+			return false
+		}
+	}
+	return true
+}
+
+// withUnmanagedFields sets the list and map fields of rule which are not
+// managed by the resource, i.e. which are null in its configuration, to their
+// value in the existing rule.
+func withUnmanagedFields(rule, existing model.AggregationRule) model.AggregationRule {
+	if rule.KeepLabels == nil {
+		rule.KeepLabels = existing.KeepLabels
+	}
+	if rule.DropLabels == nil {
+		rule.DropLabels = existing.DropLabels
+	}
+	if rule.Aggregations == nil {
+		rule.Aggregations = existing.Aggregations
+	}
+	if rule.Tags == nil {
+		rule.Tags = existing.Tags
+	}
+	return rule
+}
+
+// resolveConflict handles the conflict of creating a rule for a metric which
+// already has one, as configured by on_conflict. The existing rule is either updated
+// with plan, leaving the fields which are not managed by the resource
+// unchanged as auto_import does, or adopted as it is.
+func (r *ruleResource) resolveConflict(rules *AggregationRules, plan model.RuleTF, conflict error, diags *diag.Diagnostics) error {
+	rule := plan.ToAPIReq()
+	existing, err := rules.Read(rule.Metric)
+	if err != nil {
+		return err
+	}
+
+	switch plan.OnConflict.ValueString() {
+	case onConflictUpdate:
+		if err := rules.Update(withUnmanagedFields(rule, existing)); err != nil {
+			return err
+		}
+		diags.AddWarning("Existing aggregation rule for metric found", "The existing rule has been updated and imported into Terraform state; no aggregation rule has been created.")
+	case onConflictAdopt:
+		diags.AddWarning(
+			"Existing aggregation rule for metric found",
+			fmt.Sprintf("The existing rule for %q has been imported into Terraform state and left unchanged; the next plan shows how it differs from the configuration.", rule.Metric),
+		)
+	default:
+		return fmt.Errorf("%w. Import the rule, or set on_conflict to 'update' or 'adopt' to take it over", conflict)
+	}
+	return nil
+}
+
+// keepNullLists keeps list and map attributes which are null in state null
+// when the API has no value for them, rather than turning them into empty
+// ones.
+func keepNullLists(tf *model.RuleTF, state model.RuleTF) {
+	if state.KeepLabels == nil && len(tf.KeepLabels) == 0 {
+		tf.KeepLabels = nil
+	}
+	if state.DropLabels == nil && len(tf.DropLabels) == 0 {
+		tf.DropLabels = nil
+	}
+	if state.Aggregations == nil && len(tf.Aggregations) == 0 {
+		tf.Aggregations = nil
+	}
+	if state.Tags == nil && len(tf.Tags) == 0 {
+		tf.Tags = nil
+	}
+}
+
+// autoImportEnabled resolves whether a rule should be imported instead of
+// created. A value set on the resource always takes precedence over the
+// provider default.
+func autoImportEnabled(resourceValue types.Bool, providerDefault bool) bool {
+	if resourceValue.IsNull() || resourceValue.IsUnknown() {
+		return providerDefault
+	}
+	return resourceValue.ValueBool()
+}
+
+// ImportState imports a rule by its ID or, if no rule has the given ID, by its
+// name or else its metric.
 func (r *ruleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if r.rules != nil {
+		if rule, err := r.rules.ReadByID(req.ID); err == nil {
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), rule.ID)...)
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("metric"), rule.Metric)...)
+			return
+		}
+		if rule, err := r.rules.ReadByName(req.ID); err == nil {
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), rule.ID)...)
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), rule.Name)...)
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("metric"), rule.Metric)...)
+			return
+		}
+	}
+
 	resource.ImportStatePassthroughID(ctx, path.Root("metric"), req, resp)
 }
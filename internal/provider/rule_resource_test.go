@@ -1,13 +1,27 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/stretchr/testify/require"
 
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
 )
 
@@ -111,7 +125,7 @@ resource "grafana-adaptive-metrics_rule" "test" {
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "drop_labels.#", "1"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "drop_labels.0", "instance"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregations.#", "1"),
-					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregations.0", "sum"),
+					resource.TestCheckTypeSetElemAttr("grafana-adaptive-metrics_rule.test", "aggregations.*", "sum"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregation_interval", ""),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregation_delay", ""),
 				),
@@ -138,7 +152,7 @@ resource "grafana-adaptive-metrics_rule" "test" {
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "drop_labels.#", "1"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "drop_labels.0", "instance"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregations.#", "1"),
-					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregations.0", "sum"),
+					resource.TestCheckTypeSetElemAttr("grafana-adaptive-metrics_rule.test", "aggregations.*", "sum"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregation_interval", ""),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregation_delay", ""),
 				),
@@ -161,7 +175,7 @@ resource "grafana-adaptive-metrics_rule" "test" {
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "drop_labels.#", "1"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "drop_labels.0", "instance"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregations.#", "1"),
-					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregations.0", "sum"),
+					resource.TestCheckTypeSetElemAttr("grafana-adaptive-metrics_rule.test", "aggregations.*", "sum"),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregation_interval", ""),
 					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule.test", "aggregation_delay", ""),
 				),
@@ -170,3 +184,2023 @@ resource "grafana-adaptive-metrics_rule" "test" {
 		},
 	})
 }
+
+func TestAutoImportEnabled(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		resourceValue   types.Bool
+		providerDefault bool
+		expected        bool
+	}{
+		{name: "unset, provider default off", resourceValue: types.BoolNull(), providerDefault: false, expected: false},
+		{name: "unset, provider default on", resourceValue: types.BoolNull(), providerDefault: true, expected: true},
+		{name: "true, provider default off", resourceValue: types.BoolValue(true), providerDefault: false, expected: true},
+		{name: "true, provider default on", resourceValue: types.BoolValue(true), providerDefault: true, expected: true},
+		{name: "false, provider default off", resourceValue: types.BoolValue(false), providerDefault: false, expected: false},
+		{name: "false, provider default on", resourceValue: types.BoolValue(false), providerDefault: true, expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, autoImportEnabled(tc.resourceValue, tc.providerDefault))
+		})
+	}
+}
+
+func TestRuleResourceModifyPlanBroadMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		rule        model.RuleTF
+		expectError bool
+	}{
+		{name: "short exact rule", rule: model.RuleTF{Metric: types.StringValue("up"), MatchType: types.StringValue("")}},
+		{name: "long prefix rule", rule: model.RuleTF{Metric: types.StringValue("kube_"), MatchType: types.StringValue("prefix")}},
+		{name: "empty prefix rule", rule: model.RuleTF{Metric: types.StringValue(""), MatchType: types.StringValue("prefix")}, expectError: true},
+		{name: "short suffix rule", rule: model.RuleTF{Metric: types.StringValue("_s"), MatchType: types.StringValue("suffix")}, expectError: true},
+		{
+			name: "short prefix rule with override",
+			rule: model.RuleTF{Metric: types.StringValue("k"), MatchType: types.StringValue("prefix"), AllowBroadMatch: types.BoolValue(true)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &ruleResource{rules: newMockAPI(t).aggregationRules(), broadMatchMinLength: 3}
+			tc.rule.AggregatedMetricNames = types.ListUnknown(types.StringType)
+			tc.rule.Effective = types.ObjectUnknown(model.EffectiveRuleAttributeTypes)
+			tc.rule.Timeouts = timeouts.Value{Object: types.ObjectNull(model.TimeoutsAttributeTypes)}
+			resp := modifyPlan(t, r, nil, tc.rule)
+			require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), resp.Diagnostics)
+		})
+	}
+}
+
+// modifyPlan runs the ModifyPlan method of r for a change of a resource from
+// prior to planned. A nil prior plans the creation of the resource.
+func modifyPlan(t *testing.T, r fwresource.ResourceWithModifyPlan, prior, planned any) *fwresource.ModifyPlanResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	require.False(t, schemaResp.Diagnostics.HasError(), schemaResp.Diagnostics)
+
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	diags := plan.Set(ctx, planned)
+	require.False(t, diags.HasError(), diags)
+
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+	if prior != nil {
+		diags = state.Set(ctx, prior)
+		require.False(t, diags.HasError(), diags)
+	}
+
+	req := fwresource.ModifyPlanRequest{Plan: plan, State: state}
+	resp := &fwresource.ModifyPlanResponse{Plan: plan}
+	r.ModifyPlan(ctx, req, resp)
+
+	return resp
+}
+
+func TestRuleResourceModifyPlanRequiredKeepLabels(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		rule        model.RuleTF
+		expectError bool
+	}{
+		{name: "keeps required", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"cluster", "job"}}.ToTF()},
+		{name: "keep misses required", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"job"}}.ToTF(), expectError: true},
+		{name: "drops others", rule: model.AggregationRule{Metric: "up", DropLabels: []string{"pod"}}.ToTF()},
+		{name: "drops required", rule: model.AggregationRule{Metric: "up", DropLabels: []string{"cluster"}}.ToTF(), expectError: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &ruleResource{rules: newMockAPI(t).aggregationRules(), requiredKeepLabels: []string{"cluster"}}
+			resp := modifyPlan(t, r, nil, tc.rule)
+			require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), resp.Diagnostics)
+		})
+	}
+}
+
+func TestRuleResourceModifyPlanAllowedMetricPatterns(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		allowed     []string
+		rule        model.RuleTF
+		expectError bool
+	}{
+		{name: "allowed", allowed: []string{"team_a_.*"}, rule: model.AggregationRule{Metric: "team_a_up", DropLabels: []string{"pod"}}.ToTF()},
+		{name: "rejected", allowed: []string{"team_a_.*"}, rule: model.AggregationRule{Metric: "team_b_up", DropLabels: []string{"pod"}}.ToTF(), expectError: true},
+		{name: "empty policy", rule: model.AggregationRule{Metric: "team_b_up", DropLabels: []string{"pod"}}.ToTF()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &ruleResource{rules: newMockAPI(t).aggregationRules(), allowedMetricPatterns: tc.allowed}
+			resp := modifyPlan(t, r, nil, tc.rule)
+			require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), resp.Diagnostics)
+		})
+	}
+}
+
+func TestRuleResourceModifyPlanEstimate(t *testing.T) {
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	prior := model.AggregationRule{Metric: "old_metric", DropLabels: []string{"pod"}}.ToTF()
+	planned := model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod", "instance"}}.ToTF()
+
+	// The estimate endpoint is unavailable.
+	resp := modifyPlan(t, r, prior, planned)
+	require.Empty(t, resp.Diagnostics)
+
+	api.estimate("old_metric", 1000, 500)
+	api.estimate("test_metric", 1000, 100)
+
+	resp = modifyPlan(t, r, nil, planned)
+	require.Len(t, resp.Diagnostics, 1)
+	require.Contains(t, resp.Diagnostics[0].Detail(), "aggregate 1000 series into 100, saving 900 series")
+
+	resp = modifyPlan(t, r, prior, planned)
+	require.Len(t, resp.Diagnostics, 1)
+	require.Contains(t, resp.Diagnostics[0].Detail(), "from 500 to 900 (+400)")
+
+	// Unchanged rules are not estimated.
+	resp = modifyPlan(t, r, planned, planned)
+	require.Empty(t, resp.Diagnostics)
+}
+
+func TestRuleResourceModifyPlanMetricType(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	api.metricMetadata = map[string]model.MetricMetadata{
+		"team_requests_total": {Metric: "team_requests_total", Type: model.MetricTypeCounter},
+	}
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+	r := &ruleResource{rules: rules, capabilities: newCapabilities(api.client())}
+
+	planned := model.AggregationRule{Metric: "requests_total", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}}.ToTF()
+	resp := modifyPlan(t, r, nil, planned)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Aggregation not meaningful for metric type", resp.Diagnostics.Warnings()[0].Summary())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), "use sum:counter instead")
+
+	// The warning points at the aggregation in the set, which the plan can
+	// resolve.
+	warningPath := resp.Diagnostics.Warnings()[0].(diag.DiagnosticWithPath).Path()
+	require.Equal(t, path.Root("aggregations").AtSetValue(types.StringValue("sum")), warningPath)
+	var aggregation types.String
+	require.False(t, resp.Plan.GetAttribute(context.Background(), warningPath, &aggregation).HasError())
+	require.Equal(t, "sum", aggregation.ValueString())
+
+	// The warning can be suppressed.
+	planned.IgnoreMetricType = types.BoolValue(true)
+	resp = modifyPlan(t, r, nil, planned)
+	require.Empty(t, resp.Diagnostics)
+
+	// Metrics without metadata and prefix rules are not checked.
+	for _, rule := range []model.AggregationRule{
+		{Metric: "latency_seconds", Aggregations: []string{"sum"}},
+		{Metric: "requests_", MatchType: "prefix", Aggregations: []string{"sum"}},
+	} {
+		resp = modifyPlan(t, r, nil, rule.ToTF())
+		require.Empty(t, resp.Diagnostics, rule.Metric)
+	}
+}
+
+func TestRuleResourceModifyPlanMissingLabels(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata), string(client.FeatureLabelPatterns)}
+	api.metricMetadata = map[string]model.MetricMetadata{
+		"requests_total":  {Metric: "requests_total", Type: model.MetricTypeCounter, Labels: []string{"instance", "job", "pod"}},
+		"latency_seconds": {Metric: "latency_seconds", Type: model.MetricTypeHistogram},
+	}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	planned := model.AggregationRule{Metric: "requests_total", KeepLabels: []string{"job", "instanace"}, Aggregations: []string{"sum:counter"}}.ToTF()
+	resp := modifyPlan(t, r, nil, planned)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Label not present on metric", resp.Diagnostics.Warnings()[0].Summary())
+	require.Equal(t, path.Root("keep_labels").AtListIndex(1), resp.Diagnostics.Warnings()[0].(diag.DiagnosticWithPath).Path())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), `"instanace"`)
+
+	// The warning can be suppressed.
+	planned.IgnoreMissingLabels = types.BoolValue(true)
+	resp = modifyPlan(t, r, nil, planned)
+	require.Empty(t, resp.Diagnostics)
+
+	// Patterns match against the labels of the metric.
+	rule := model.AggregationRule{Metric: "requests_total", DropLabels: []string{"po*"}, LabelMatchMode: "glob", Aggregations: []string{"sum:counter"}}
+	resp = modifyPlan(t, r, nil, rule.ToTF())
+	require.Empty(t, resp.Diagnostics)
+
+	// Metrics whose labels are not reported are not checked.
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "latency_seconds", DropLabels: []string{"pdo"}, Aggregations: []string{"sum:counter"}}.ToTF())
+	require.Empty(t, resp.Diagnostics)
+}
+
+func TestRuleResourceDeprecatedAggregations(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{"sum", "max"}})
+	api.deprecatedAggregations = []model.AggregationDeprecation{{Type: "max", Replacement: "last", RemovalDate: "2025-01-31"}}
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	// A rule using a deprecated type is warned about when planned.
+	planned := model.AggregationRule{Metric: "other_metric", Aggregations: []string{"count", "max"}}.ToTF()
+	resp := modifyPlan(t, r, nil, planned)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Deprecated aggregation type", resp.Diagnostics.Warnings()[0].Summary())
+	require.Equal(t, path.Root("aggregations").AtSetValue(types.StringValue("max")), resp.Diagnostics.Warnings()[0].(diag.DiagnosticWithPath).Path())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), "will be removed on 2025-01-31. Replace it with last")
+
+	// And when read.
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{"sum", "max"}}.ToTF()).HasError())
+
+	readResp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, readResp)
+	require.Len(t, readResp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Deprecated aggregation type", readResp.Diagnostics.Warnings()[0].Summary())
+
+	// Rules using no deprecated type are not.
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "other_metric", Aggregations: []string{"count", "sum"}}.ToTF())
+	require.Empty(t, resp.Diagnostics)
+
+	// The deprecations are requested once per run.
+	var infoRequests int
+	for _, request := range api.requestLog() {
+		if request == "GET /aggregations/server_info" {
+			infoRequests++
+		}
+	}
+	require.Equal(t, 1, infoRequests)
+}
+
+func TestRuleResourceModifyPlanMinSeriesCount(t *testing.T) {
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules(), minSeriesCount: 100}
+
+	api.estimate("rare_metric", 20, 1)
+	api.estimate("busy_metric", 1000, 10)
+
+	resp := modifyPlan(t, r, nil, model.AggregationRule{Metric: "rare_metric", Drop: true}.ToTF())
+	require.Len(t, resp.Diagnostics.Warnings(), 2)
+	require.Equal(t, "Aggregation rule for a low cardinality metric", resp.Diagnostics.Warnings()[0].Summary())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), "matches 20 series, fewer than the provider's min_series_count of 100")
+
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "busy_metric", Drop: true}.ToTF())
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Estimated impact of aggregation rule", resp.Diagnostics.Warnings()[0].Summary())
+
+	// The check is disabled by default.
+	r.minSeriesCount = 0
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "rare_metric", Drop: true}.ToTF())
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+}
+
+func TestRuleResourceAutoImportPlan(t *testing.T) {
+	ctx := context.Background()
+	var schemaResp fwresource.SchemaResponse
+	(&ruleResource{}).Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	attr := schemaResp.Schema.Attributes["auto_import"].(schema.BoolAttribute)
+
+	plan := func(config, state types.Bool) types.Bool {
+		// The framework plans configured values as they are, and the
+		// others of computed attributes as unknown.
+		planned := config
+		if config.IsNull() {
+			planned = types.BoolUnknown()
+		}
+		req := planmodifier.BoolRequest{ConfigValue: config, StateValue: state, PlanValue: planned}
+		resp := &planmodifier.BoolResponse{PlanValue: req.PlanValue}
+		for _, m := range attr.PlanModifiers {
+			m.PlanModifyBool(ctx, req, resp)
+		}
+		return resp.PlanValue
+	}
+
+	// The false which earlier releases defaulted it to is kept in state.
+	require.Equal(t, types.BoolValue(false), plan(types.BoolNull(), types.BoolValue(false)))
+	require.Equal(t, types.BoolNull(), plan(types.BoolNull(), types.BoolNull()))
+	require.Equal(t, types.BoolValue(true), plan(types.BoolValue(true), types.BoolValue(false)))
+}
+
+func TestRuleResourceModifyPlanOverlappingPriority(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "kube_", MatchType: "prefix", Priority: 1},
+		model.AggregationRule{Metric: "node_", MatchType: "prefix", Priority: 1},
+	)
+	r := &ruleResource{rules: api.aggregationRules(), broadMatchMinLength: 3}
+
+	resp := modifyPlan(t, r, nil, model.AggregationRule{Metric: "kube_pod_info", Priority: 1}.ToTF())
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), `"kube_"`)
+
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "kube_pod_info", Priority: 2}.ToTF())
+	require.Empty(t, resp.Diagnostics)
+}
+
+func TestRuleResourceModifyPlanNestedSuffixes(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "_total", MatchType: "suffix", Aggregations: []string{"sum:counter"}},
+		model.AggregationRule{Metric: "_seconds", MatchType: "suffix", Drop: true},
+	)
+	r := &ruleResource{rules: api.aggregationRules(), broadMatchMinLength: 3}
+
+	// A suffix ending with an existing one matches a subset of its metrics.
+	planned := model.AggregationRule{Metric: "_bytes_total", MatchType: "suffix", Aggregations: []string{"sum:counter"}}.ToTF()
+	resp := modifyPlan(t, r, nil, planned)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, path.Root("priority"), resp.Diagnostics.Warnings()[0].(diag.DiagnosticWithPath).Path())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), `suffix rule for "_bytes_total" is also matched by the suffix rule for "_total"`)
+
+	// ...and one which an existing suffix ends with matches a superset.
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "total", MatchType: "suffix", Drop: true}.ToTF())
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), `suffix rule for "_total" is also matched by the suffix rule for "total"`)
+
+	// Suffixes which do not end with one another match distinct metrics.
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "_total_bytes", MatchType: "suffix", Drop: true}.ToTF())
+	require.Empty(t, resp.Diagnostics)
+
+	// Rules with different priorities are applied in order.
+	planned.Priority = types.Int64Value(1)
+	resp = modifyPlan(t, r, nil, planned)
+	require.Empty(t, resp.Diagnostics)
+
+	// The warning can be suppressed.
+	planned.Priority = types.Int64Value(0)
+	planned.IgnoreOverlaps = types.BoolValue(true)
+	resp = modifyPlan(t, r, nil, planned)
+	require.Empty(t, resp.Diagnostics)
+}
+
+// dropMatcherList is the type of the drop_if block of rules.
+var dropMatcherList = tftypes.List{ElementType: tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+	"label":    tftypes.String,
+	"operator": tftypes.String,
+	"value":    tftypes.String,
+}}}
+
+// noDelayOverrides is the value of an unset delay_overrides block.
+var noDelayOverrides = tftypes.NewValue(tftypes.List{ElementType: tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+	"label":    tftypes.String,
+	"operator": tftypes.String,
+	"value":    tftypes.String,
+	"delay":    tftypes.String,
+}}}, []tftypes.Value{})
+
+// noTimeouts is the value of an unset timeouts block.
+var noTimeouts = tftypes.NewValue(tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+	"create": tftypes.String,
+	"read":   tftypes.String,
+	"update": tftypes.String,
+	"delete": tftypes.String,
+}}, nil)
+
+// timeoutsValue is a timeouts block with the given timeouts set, and the
+// others unset.
+func timeoutsValue(set map[string]string) timeouts.Value {
+	attrs := map[string]attr.Value{}
+	for name := range model.TimeoutsAttributeTypes {
+		attrs[name] = types.StringNull()
+		if v, ok := set[name]; ok {
+			attrs[name] = types.StringValue(v)
+		}
+	}
+	return timeouts.Value{Object: types.ObjectValueMust(model.TimeoutsAttributeTypes, attrs)}
+}
+
+// stringSet is the type of the aggregations set.
+var stringSet = tftypes.Set{ElementType: tftypes.String}
+
+// effectiveObject is the type of the effective values of rules.
+var effectiveObject = tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+	"metric":                tftypes.String,
+	"match_type":            tftypes.String,
+	"label_match_mode":      tftypes.String,
+	"aggregations":          tftypes.List{ElementType: tftypes.String},
+	"aggregation_intervals": tftypes.Map{ElementType: tftypes.String},
+	"rollout_percentage":    tftypes.Number,
+	"auto_import":           tftypes.Bool,
+}}
+
+// noTags is the value of an unset tags map.
+var noTags = tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil)
+
+func TestRuleResourcePlanIsStable(t *testing.T) {
+	stringList := tftypes.List{ElementType: tftypes.String}
+	emptyList := tftypes.NewValue(stringList, []tftypes.Value{})
+	noMatchers := tftypes.NewValue(dropMatcherList, []tftypes.Value{})
+	prior := map[string]tftypes.Value{
+		"id":                               tftypes.NewValue(tftypes.String, ""),
+		"metric":                           tftypes.NewValue(tftypes.String, "test_tf_metric"),
+		"match_type":                       tftypes.NewValue(tftypes.String, ""),
+		"match_prefixes":                   tftypes.NewValue(stringList, nil),
+		"drop":                             tftypes.NewValue(tftypes.Bool, false),
+		"drop_if":                          noMatchers,
+		"delay_overrides":                  noDelayOverrides,
+		"timeouts":                         noTimeouts,
+		"tags":                             noTags,
+		"keep_labels":                      emptyList,
+		"drop_labels":                      tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "pod")}),
+		"label_match_mode":                 tftypes.NewValue(tftypes.String, nil),
+		"aggregations":                     tftypes.NewValue(stringSet, []tftypes.Value{}),
+		"aggregation_interval":             tftypes.NewValue(tftypes.String, ""),
+		"aggregation_intervals":            tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"aggregation_delay":                tftypes.NewValue(tftypes.String, ""),
+		"priority":                         tftypes.NewValue(tftypes.Number, 0),
+		"rollout_percentage":               tftypes.NewValue(tftypes.Number, nil),
+		"auto_import":                      tftypes.NewValue(tftypes.Bool, nil),
+		"effective":                        tftypes.NewValue(effectiveObject, nil),
+		"on_conflict":                      tftypes.NewValue(tftypes.String, "fail"),
+		"name":                             tftypes.NewValue(tftypes.String, nil),
+		"allow_broad_match":                tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_metric_type":               tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_missing_labels":            tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_overlaps":                  tftypes.NewValue(tftypes.Bool, nil),
+		"ingest_sample_rate":               tftypes.NewValue(tftypes.Number, nil),
+		"force_outside_window":             tftypes.NewValue(tftypes.Bool, nil),
+		"require_recommendation":           tftypes.NewValue(tftypes.Bool, nil),
+		"destroy_action":                   tftypes.NewValue(tftypes.String, "delete"),
+		"allow_missing_on_destroy":         tftypes.NewValue(tftypes.Bool, nil),
+		"drain_before_delete":              tftypes.NewValue(tftypes.String, nil),
+		"archived":                         tftypes.NewValue(tftypes.Bool, false),
+		"recommended_aggregation_interval": tftypes.NewValue(tftypes.String, nil),
+		"aggregated_metric_names":          tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"content_id":                       tftypes.NewValue(tftypes.String, nil),
+	}
+	config := map[string]tftypes.Value{
+		"id":                               tftypes.NewValue(tftypes.String, nil),
+		"metric":                           tftypes.NewValue(tftypes.String, "test_tf_metric"),
+		"match_type":                       tftypes.NewValue(tftypes.String, nil),
+		"match_prefixes":                   tftypes.NewValue(stringList, nil),
+		"drop":                             tftypes.NewValue(tftypes.Bool, nil),
+		"drop_if":                          noMatchers,
+		"delay_overrides":                  noDelayOverrides,
+		"timeouts":                         noTimeouts,
+		"tags":                             noTags,
+		"keep_labels":                      tftypes.NewValue(stringList, nil),
+		"drop_labels":                      tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "pod")}),
+		"label_match_mode":                 tftypes.NewValue(tftypes.String, nil),
+		"aggregations":                     tftypes.NewValue(stringSet, nil),
+		"aggregation_interval":             tftypes.NewValue(tftypes.String, nil),
+		"aggregation_intervals":            tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"aggregation_delay":                tftypes.NewValue(tftypes.String, nil),
+		"priority":                         tftypes.NewValue(tftypes.Number, nil),
+		"rollout_percentage":               tftypes.NewValue(tftypes.Number, nil),
+		"auto_import":                      tftypes.NewValue(tftypes.Bool, nil),
+		"effective":                        tftypes.NewValue(effectiveObject, nil),
+		"on_conflict":                      tftypes.NewValue(tftypes.String, nil),
+		"name":                             tftypes.NewValue(tftypes.String, nil),
+		"allow_broad_match":                tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_metric_type":               tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_missing_labels":            tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_overlaps":                  tftypes.NewValue(tftypes.Bool, nil),
+		"ingest_sample_rate":               tftypes.NewValue(tftypes.Number, nil),
+		"force_outside_window":             tftypes.NewValue(tftypes.Bool, nil),
+		"require_recommendation":           tftypes.NewValue(tftypes.Bool, nil),
+		"destroy_action":                   tftypes.NewValue(tftypes.String, nil),
+		"allow_missing_on_destroy":         tftypes.NewValue(tftypes.Bool, nil),
+		"drain_before_delete":              tftypes.NewValue(tftypes.String, nil),
+		"archived":                         tftypes.NewValue(tftypes.Bool, nil),
+		"recommended_aggregation_interval": tftypes.NewValue(tftypes.String, nil),
+		"aggregated_metric_names":          tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"content_id":                       tftypes.NewValue(tftypes.String, nil),
+	}
+
+	// Unset attributes are planned with their default rather than as unknown,
+	// both for a no-op plan and for an update.
+	require.Equal(t, prior, planResourceChange(t, "grafana-adaptive-metrics_rule", prior, config))
+
+	config["drop_labels"] = emptyList
+	planned := planResourceChange(t, "grafana-adaptive-metrics_rule", prior, config)
+	for name, value := range planned {
+		if name == "aggregated_metric_names" || name == "content_id" || name == "effective" {
+			// Planned by ModifyPlan, which needs a configured provider.
+			continue
+		}
+		require.True(t, value.IsFullyKnown(), "%s is unknown", name)
+	}
+}
+
+func TestRuleResourcePlanUnsetLists(t *testing.T) {
+	stringList := tftypes.List{ElementType: tftypes.String}
+	nullList := tftypes.NewValue(stringList, nil)
+	nullSet := tftypes.NewValue(stringSet, nil)
+	emptyList := tftypes.NewValue(stringList, []tftypes.Value{})
+	podList := tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "pod")})
+	noMatchers := tftypes.NewValue(dropMatcherList, []tftypes.Value{})
+	config := map[string]tftypes.Value{
+		"id":                               tftypes.NewValue(tftypes.String, nil),
+		"metric":                           tftypes.NewValue(tftypes.String, "test_tf_metric"),
+		"match_type":                       tftypes.NewValue(tftypes.String, nil),
+		"match_prefixes":                   tftypes.NewValue(stringList, nil),
+		"drop":                             tftypes.NewValue(tftypes.Bool, nil),
+		"drop_if":                          noMatchers,
+		"delay_overrides":                  noDelayOverrides,
+		"timeouts":                         noTimeouts,
+		"tags":                             noTags,
+		"keep_labels":                      nullList,
+		"drop_labels":                      emptyList,
+		"label_match_mode":                 tftypes.NewValue(tftypes.String, nil),
+		"aggregations":                     nullSet,
+		"aggregation_interval":             tftypes.NewValue(tftypes.String, nil),
+		"aggregation_intervals":            tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"aggregation_delay":                tftypes.NewValue(tftypes.String, nil),
+		"priority":                         tftypes.NewValue(tftypes.Number, nil),
+		"rollout_percentage":               tftypes.NewValue(tftypes.Number, nil),
+		"auto_import":                      tftypes.NewValue(tftypes.Bool, nil),
+		"effective":                        tftypes.NewValue(effectiveObject, nil),
+		"on_conflict":                      tftypes.NewValue(tftypes.String, nil),
+		"name":                             tftypes.NewValue(tftypes.String, nil),
+		"allow_broad_match":                tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_metric_type":               tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_missing_labels":            tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_overlaps":                  tftypes.NewValue(tftypes.Bool, nil),
+		"ingest_sample_rate":               tftypes.NewValue(tftypes.Number, nil),
+		"force_outside_window":             tftypes.NewValue(tftypes.Bool, nil),
+		"require_recommendation":           tftypes.NewValue(tftypes.Bool, nil),
+		"destroy_action":                   tftypes.NewValue(tftypes.String, nil),
+		"allow_missing_on_destroy":         tftypes.NewValue(tftypes.Bool, nil),
+		"drain_before_delete":              tftypes.NewValue(tftypes.String, nil),
+		"archived":                         tftypes.NewValue(tftypes.Bool, nil),
+		"recommended_aggregation_interval": tftypes.NewValue(tftypes.String, nil),
+		"aggregated_metric_names":          tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"content_id":                       tftypes.NewValue(tftypes.String, nil),
+	}
+
+	// On create, unset lists stay null while empty lists are kept.
+	planned := planResourceChange(t, "grafana-adaptive-metrics_rule", nil, config)
+	require.Equal(t, nullList, planned["keep_labels"])
+	require.Equal(t, emptyList, planned["drop_labels"])
+	require.Equal(t, nullSet, planned["aggregations"])
+
+	// On update, unset lists keep the value read from the API.
+	prior := make(map[string]tftypes.Value, len(planned))
+	for name, value := range planned {
+		prior[name] = value
+	}
+	prior["id"] = tftypes.NewValue(tftypes.String, "r-1")
+	prior["keep_labels"] = podList
+	teamTags := tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{"team": tftypes.NewValue(tftypes.String, "infra")})
+	prior["tags"] = teamTags
+	planned = planResourceChange(t, "grafana-adaptive-metrics_rule", prior, config)
+	require.Equal(t, podList, planned["keep_labels"])
+	require.Equal(t, nullSet, planned["aggregations"])
+	require.Equal(t, teamTags, planned["tags"])
+}
+
+func TestRuleResourceNullLists(t *testing.T) {
+	// Null lists are left unset, empty lists are kept.
+	rule := model.RuleTF{
+		Metric:     types.StringValue("test_tf_metric"),
+		DropLabels: []types.String{},
+	}.ToAPIReq()
+	require.Nil(t, rule.KeepLabels)
+	require.NotNil(t, rule.DropLabels)
+
+	// Unmanaged lists are taken from the existing rule.
+	existing := model.AggregationRule{Metric: "test_tf_metric", KeepLabels: []string{"pod"}, DropLabels: []string{"namespace"}}
+	merged := withUnmanagedFields(rule, existing)
+	require.Equal(t, []string{"pod"}, merged.KeepLabels)
+	require.Empty(t, merged.DropLabels)
+
+	// Lists which are null in state stay null when the API has no value.
+	tf := model.AggregationRule{Metric: "test_tf_metric", KeepLabels: []string{"pod"}}.ToTF()
+	keepNullLists(&tf, model.RuleTF{})
+	require.Len(t, tf.KeepLabels, 1)
+	require.Nil(t, tf.DropLabels)
+	require.Nil(t, tf.Aggregations)
+}
+
+func TestRuleResourceReadByID(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{ID: "r-1", Metric: "old_metric", Aggregations: []string{"sum"}})
+	api.rename("old_metric", "new_metric")
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.AggregationRule{ID: "r-1", Metric: "old_metric", Aggregations: []string{"sum"}}.ToTF()).HasError())
+
+	// The rule is found by its ID after it was renamed outside of Terraform.
+	resp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.RuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, "r-1", tf.ID.ValueString())
+	require.Equal(t, "new_metric", tf.Metric.ValueString())
+}
+
+func TestRuleResourceReadDropIf(t *testing.T) {
+	ctx := context.Background()
+	dropIf := []model.DropMatcher{{Label: "env", Operator: "=", Value: "test"}, {Label: "cluster", Operator: "!~", Value: "prod-.*"}}
+	api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", Drop: true, DropIf: dropIf})
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.AggregationRule{Metric: "test_tf_metric", Drop: true}.ToTF()).HasError())
+
+	resp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.RuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, dropIf, tf.ToAPIReq().DropIf)
+}
+
+func TestRuleResourceReadTags(t *testing.T) {
+	ctx := context.Background()
+	tags := map[string]string{"owner": "team-a", "criticality": "high"}
+	api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", Drop: true, Tags: tags})
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	// Tags which are not yet in state, as after an import, are read.
+	tf := model.AggregationRule{Metric: "test_tf_metric", Drop: true}.ToTF()
+	tf.Tags = nil
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, tf).HasError())
+
+	resp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, tags, tf.ToAPIReq().Tags)
+}
+
+func TestRuleResourceReadRecommendedInterval(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "hinted", DropLabels: []string{"pod"}, AggregationInterval: "1m", RecommendedAggregationInterval: "2m"},
+		model.AggregationRule{Metric: "unhinted", DropLabels: []string{"pod"}},
+	)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for metric, expected := range map[string]types.String{
+		"hinted":   types.StringValue("2m"),
+		"unhinted": types.StringNull(),
+	} {
+		tf := model.AggregationRule{Metric: metric, DropLabels: []string{"pod"}}.ToTF()
+		state := tfsdk.State{Schema: schemaResp.Schema}
+		require.False(t, state.Set(ctx, tf).HasError())
+
+		resp := &fwresource.ReadResponse{State: state}
+		r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		require.False(t, resp.State.Get(ctx, &tf).HasError())
+		require.Equal(t, expected, tf.RecommendedAggregationInterval, metric)
+		// The hint is never sent back to the API.
+		require.Empty(t, tf.ToAPIReq().RecommendedAggregationInterval)
+	}
+}
+
+func TestRuleResourceRolloutPercentage(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	rollout := int64(25)
+	tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}, RolloutPercentage: &rollout}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	got, _ := api.rule("test_tf_metric")
+	require.Equal(t, &rollout, got.RolloutPercentage)
+
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, types.Int64Value(25), tf.RolloutPercentage)
+
+	// An unset rollout is omitted from requests and read back as unset.
+	tf.RolloutPercentage = types.Int64Null()
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	got, _ = api.rule("test_tf_metric")
+	require.Nil(t, got.RolloutPercentage)
+}
+
+func TestRuleResourceIngestSampleRate(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	rate := 0.05
+	tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}, IngestSampleRate: &rate}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	got, _ := api.rule("test_tf_metric")
+	require.Equal(t, &rate, got.IngestSampleRate)
+
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, types.Float64Value(0.05), tf.IngestSampleRate)
+
+	// An unset rate is omitted from requests and read back as unset.
+	tf.IngestSampleRate = types.Float64Null()
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	got, _ = api.rule("test_tf_metric")
+	require.Nil(t, got.IngestSampleRate)
+}
+
+func TestRuleResourceIngestSampleRateRequiresFeature(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	rate := 0.5
+	resp := modifyPlan(t, r, nil, model.AggregationRule{Metric: "test_metric", IngestSampleRate: &rate}.ToTF())
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support ingestion sampling")
+
+	api.features = append(api.features, string(client.FeatureIngestSampling))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, model.AggregationRule{Metric: "test_metric", IngestSampleRate: &rate}.ToTF())
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleResourceDropIfRequiresFeature(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	tf := model.AggregationRule{Metric: "test_metric", Drop: true, DropIf: []model.DropMatcher{{Label: "env", Operator: "=", Value: "test"}}}.ToTF()
+	resp := modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support conditional drops")
+
+	api.features = append(api.features, string(client.FeatureConditionalDrops))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, tf)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleResourcePercentilesRequireFeature(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	tf := model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"sum", "p99"}}.ToTF()
+	resp := modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support percentile aggregations")
+
+	api.features = append(api.features, string(client.FeaturePercentiles))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, tf)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleResourceRolloutRequiresFeature(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	tf := model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, RolloutPercentage: ptr(int64(10))}.ToTF()
+	resp := modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support gradual rollouts")
+
+	api.features = append(api.features, string(client.FeatureRollouts))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, tf)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleResourcePriorityRequiresFeature(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	// The default priority does not need the feature.
+	tf := model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}}.ToTF()
+	resp := modifyPlan(t, r, nil, tf)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	tf = model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Priority: 10}.ToTF()
+	resp = modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support rule priorities")
+
+	api.features = append(api.features, string(client.FeaturePriority))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, tf)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleResourceTagsRequireFeature(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	tf := model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Tags: map[string]string{"owner": "team-a"}}.ToTF()
+	resp := modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support rule tags")
+
+	api.features = append(api.features, string(client.FeatureRuleTags))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, tf)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleResourceAggregationIntervals(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	tf := model.AggregationRule{
+		Metric:               "test_tf_metric",
+		DropLabels:           []string{"pod"},
+		Aggregations:         []string{"max", "sum"},
+		AggregationInterval:  "1m",
+		AggregationIntervals: map[string]string{"max": "5m"},
+	}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	got, _ := api.rule("test_tf_metric")
+	require.Equal(t, "1m", got.AggregationInterval)
+	require.Equal(t, map[string]string{"max": "5m"}, got.AggregationIntervals)
+
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, map[string]types.String{"max": types.StringValue("5m")}, tf.AggregationIntervals)
+
+	// Without overrides, every aggregation uses aggregation_interval.
+	tf.AggregationIntervals = nil
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	got, _ = api.rule("test_tf_metric")
+	require.Nil(t, got.AggregationIntervals)
+}
+
+func TestRuleResourceDelayOverrides(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	tf := model.AggregationRule{
+		Metric:           "test_tf_metric",
+		DropLabels:       []string{"pod"},
+		Aggregations:     []string{"sum"},
+		AggregationDelay: "1m",
+		DelayOverrides: []model.DelayOverride{
+			{Matcher: model.DropMatcher{Label: "source", Operator: "=", Value: "edge"}, Delay: "10m"},
+		},
+	}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	got, _ := api.rule("test_tf_metric")
+	require.Equal(t, "1m", got.AggregationDelay)
+	require.Equal(t, []model.DelayOverride{
+		{Matcher: model.DropMatcher{Label: "source", Operator: "=", Value: "edge"}, Delay: "10m"},
+	}, got.DelayOverrides)
+
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, []model.DelayOverrideTF{{
+		Label:    types.StringValue("source"),
+		Operator: types.StringValue("="),
+		Value:    types.StringValue("edge"),
+		Delay:    types.StringValue("10m"),
+	}}, tf.DelayOverrides)
+
+	// Without overrides, every series uses aggregation_delay.
+	tf.DelayOverrides = nil
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	got, _ = api.rule("test_tf_metric")
+	require.Nil(t, got.DelayOverrides)
+}
+
+func TestRuleResourceLabelMatchMode(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	tf := model.AggregationRule{
+		Metric:         "test_tf_metric",
+		KeepLabels:     []string{"job", "k8s_*"},
+		LabelMatchMode: "glob",
+		Aggregations:   []string{"sum"},
+	}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	got, _ := api.rule("test_tf_metric")
+	require.Equal(t, "glob", got.LabelMatchMode)
+	require.Equal(t, []string{"job", "k8s_*"}, got.KeepLabels)
+
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, types.StringValue("glob"), tf.LabelMatchMode)
+	require.Equal(t, []types.String{types.StringValue("job"), types.StringValue("k8s_*")}, tf.KeepLabels)
+
+	// Without a mode, the labels are names again.
+	tf.LabelMatchMode = types.StringNull()
+	tf.KeepLabels = []types.String{types.StringValue("job")}
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	got, _ = api.rule("test_tf_metric")
+	require.Empty(t, got.LabelMatchMode)
+}
+
+func TestRuleResourceMatchPrefixes(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	tf := model.AggregationRule{Metric: "kube_", MatchPrefixes: []string{"kube_", "node_"}, DropLabels: []string{"pod"}}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	// The rule is sent as a prefix rule identified by its first prefix.
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	got, ok := api.rule("kube_")
+	require.True(t, ok)
+	require.Equal(t, "prefix", got.MatchType)
+	require.Equal(t, []string{"kube_", "node_"}, got.MatchPrefixes)
+
+	// The prefixes are read back, and the match type is left unset.
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	var read model.RuleTF
+	require.False(t, readResp.State.Get(ctx, &read).HasError())
+	require.Equal(t, tf.MatchPrefixes, read.MatchPrefixes)
+	require.Equal(t, types.StringValue(""), read.MatchType)
+	require.Equal(t, types.StringValue("kube_"), read.Metric)
+}
+
+func TestRuleResourcePlanMatchPrefixes(t *testing.T) {
+	stringList := tftypes.List{ElementType: tftypes.String}
+	config := map[string]tftypes.Value{
+		"id":     tftypes.NewValue(tftypes.String, nil),
+		"metric": tftypes.NewValue(tftypes.String, nil),
+		"match_prefixes": tftypes.NewValue(stringList, []tftypes.Value{
+			tftypes.NewValue(tftypes.String, "kube_"),
+			tftypes.NewValue(tftypes.String, "node_"),
+		}),
+		"match_type":                       tftypes.NewValue(tftypes.String, nil),
+		"drop":                             tftypes.NewValue(tftypes.Bool, nil),
+		"drop_if":                          tftypes.NewValue(dropMatcherList, []tftypes.Value{}),
+		"delay_overrides":                  noDelayOverrides,
+		"timeouts":                         noTimeouts,
+		"tags":                             noTags,
+		"keep_labels":                      tftypes.NewValue(stringList, nil),
+		"drop_labels":                      tftypes.NewValue(stringList, []tftypes.Value{tftypes.NewValue(tftypes.String, "pod")}),
+		"label_match_mode":                 tftypes.NewValue(tftypes.String, nil),
+		"aggregations":                     tftypes.NewValue(stringSet, nil),
+		"aggregation_interval":             tftypes.NewValue(tftypes.String, nil),
+		"aggregation_intervals":            tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"aggregation_delay":                tftypes.NewValue(tftypes.String, nil),
+		"priority":                         tftypes.NewValue(tftypes.Number, nil),
+		"rollout_percentage":               tftypes.NewValue(tftypes.Number, nil),
+		"auto_import":                      tftypes.NewValue(tftypes.Bool, nil),
+		"effective":                        tftypes.NewValue(effectiveObject, nil),
+		"on_conflict":                      tftypes.NewValue(tftypes.String, nil),
+		"name":                             tftypes.NewValue(tftypes.String, nil),
+		"allow_broad_match":                tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_metric_type":               tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_missing_labels":            tftypes.NewValue(tftypes.Bool, nil),
+		"ignore_overlaps":                  tftypes.NewValue(tftypes.Bool, nil),
+		"ingest_sample_rate":               tftypes.NewValue(tftypes.Number, nil),
+		"force_outside_window":             tftypes.NewValue(tftypes.Bool, nil),
+		"require_recommendation":           tftypes.NewValue(tftypes.Bool, nil),
+		"destroy_action":                   tftypes.NewValue(tftypes.String, nil),
+		"allow_missing_on_destroy":         tftypes.NewValue(tftypes.Bool, nil),
+		"drain_before_delete":              tftypes.NewValue(tftypes.String, nil),
+		"archived":                         tftypes.NewValue(tftypes.Bool, nil),
+		"recommended_aggregation_interval": tftypes.NewValue(tftypes.String, nil),
+		"aggregated_metric_names":          tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"content_id":                       tftypes.NewValue(tftypes.String, nil),
+	}
+
+	// The metric of the rule is planned as its first prefix.
+	planned := planResourceChange(t, "grafana-adaptive-metrics_rule", nil, config)
+	require.Equal(t, tftypes.NewValue(tftypes.String, "kube_"), planned["metric"])
+}
+
+func TestRuleResourceValidateMatchPrefixes(t *testing.T) {
+	ctx := context.Background()
+	r := &ruleResource{}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for name, tc := range map[string]struct {
+		metric   types.String
+		prefixes []string
+		expected []string
+	}{
+		"valid":     {metric: types.StringNull(), prefixes: []string{"kube_", "node_"}},
+		"metric":    {metric: types.StringValue("kube_"), prefixes: []string{"kube_", "node_"}, expected: []string{"Conflicting match configuration"}},
+		"empty":     {metric: types.StringNull(), prefixes: []string{}, expected: []string{"No match prefixes"}},
+		"duplicate": {metric: types.StringNull(), prefixes: []string{"kube_", "kube_"}, expected: []string{"Duplicate metric"}},
+		"neither":   {metric: types.StringNull(), expected: []string{"Missing metric"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tf := model.AggregationRule{}.ToTF()
+			tf.Metric = tc.metric
+			tf.MatchType = types.StringNull()
+			if tc.prefixes != nil {
+				tf.MatchPrefixes = toTypesStrings(tc.prefixes)
+			}
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, tf).HasError())
+
+			resp := &fwresource.ValidateConfigResponse{}
+			r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: state.Raw}}, resp)
+			var summaries []string
+			for _, d := range resp.Diagnostics.Errors() {
+				summaries = append(summaries, d.Summary())
+			}
+			require.Equal(t, tc.expected, summaries)
+		})
+	}
+}
+
+func TestRuleResourceRequireRecommendation(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.recommend("", "",
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "recommended_metric"}, RecommendedAction: "add"},
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "removed_metric"}, RecommendedAction: "remove"},
+	)
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	create := func(metric string) *fwresource.CreateResponse {
+		tf := model.AggregationRule{Metric: metric, DropLabels: []string{"pod"}}.ToTF()
+		tf.DestroyAction = types.StringValue(destroyActionDelete)
+		tf.RequireRecommendation = types.BoolValue(true)
+		plan := tfsdk.Plan{Schema: schemaResp.Schema}
+		require.False(t, plan.Set(ctx, tf).HasError())
+
+		resp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+		r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+		return resp
+	}
+
+	resp := create("recommended_metric")
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	// Metrics recommended for removal or without recommendations are not
+	// created.
+	for _, metric := range []string{"removed_metric", "other_metric"} {
+		resp = create(metric)
+		require.True(t, resp.Diagnostics.HasError())
+		require.Equal(t, "Aggregation rule is not recommended", resp.Diagnostics.Errors()[0].Summary())
+	}
+	require.Equal(t, []string{"recommended_metric"}, api.metrics())
+}
+
+func TestRuleResourceImportStateByID(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{ID: "r-1", Metric: "test_tf_metric"})
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for importID, expected := range map[string][2]string{
+		"r-1":            {"r-1", "test_tf_metric"},
+		"test_tf_metric": {"", "test_tf_metric"},
+	} {
+		resp := &fwresource.ImportStateResponse{State: tfsdk.State{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+		}}
+		r.ImportState(ctx, fwresource.ImportStateRequest{ID: importID}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		var id, metric types.String
+		require.False(t, resp.State.GetAttribute(ctx, path.Root("id"), &id).HasError())
+		require.False(t, resp.State.GetAttribute(ctx, path.Root("metric"), &metric).HasError())
+		require.Equal(t, expected, [2]string{id.ValueString(), metric.ValueString()}, importID)
+	}
+}
+
+func TestRuleResourceName(t *testing.T) {
+	ctx := context.Background()
+
+	newResource := func(t *testing.T, rules ...model.AggregationRule) (*ruleResource, *mockAPI, fwresource.SchemaResponse) {
+		api := newMockAPI(t, rules...)
+		api.features = []string{string(client.FeatureRuleNames)}
+		aggRules := api.aggregationRules()
+		aggRules.capabilities = newCapabilities(api.client())
+		r := &ruleResource{rules: aggRules, capabilities: aggRules.capabilities}
+
+		var schemaResp fwresource.SchemaResponse
+		r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+		return r, api, schemaResp
+	}
+
+	t.Run("metric renamed in place", func(t *testing.T) {
+		r, api, schemaResp := newResource(t, model.AggregationRule{ID: "r-1", Name: "api_latency", Metric: "old_metric", DropLabels: []string{"pod"}})
+
+		prior := model.AggregationRule{ID: "r-1", Name: "api_latency", Metric: "old_metric", DropLabels: []string{"pod"}}.ToTF()
+		prior.DestroyAction = types.StringValue(destroyActionDelete)
+		planned := model.AggregationRule{ID: "r-1", Name: "api_latency", Metric: "new_metric", DropLabels: []string{"pod"}}.ToTF()
+		planned.DestroyAction = types.StringValue(destroyActionDelete)
+
+		planResp := modifyPlan(t, r, prior, planned)
+		require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+		require.Empty(t, planResp.RequiresReplace)
+
+		state := tfsdk.State{Schema: schemaResp.Schema}
+		require.False(t, state.Set(ctx, prior).HasError())
+		plan := tfsdk.Plan{Schema: schemaResp.Schema}
+		require.False(t, plan.Set(ctx, planned).HasError())
+
+		resp := &fwresource.UpdateResponse{State: state}
+		r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: state}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		require.Equal(t, []string{"new_metric"}, api.metrics())
+		rule, _ := api.rule("new_metric")
+		require.Equal(t, "r-1", rule.ID)
+		require.Equal(t, "api_latency", rule.Name)
+	})
+
+	t.Run("metric changed outside of Terraform", func(t *testing.T) {
+		r, _, schemaResp := newResource(t, model.AggregationRule{Name: "api_latency", Metric: "new_metric", DropLabels: []string{"pod"}})
+
+		tf := model.AggregationRule{Name: "api_latency", Metric: "old_metric", DropLabels: []string{"pod"}}.ToTF()
+		tf.DestroyAction = types.StringValue(destroyActionDelete)
+		state := tfsdk.State{Schema: schemaResp.Schema}
+		require.False(t, state.Set(ctx, tf).HasError())
+
+		// The rule is found by its name rather than removed from the state.
+		resp := &fwresource.ReadResponse{State: state}
+		r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+		require.False(t, resp.State.Raw.IsNull())
+
+		var read model.RuleTF
+		require.False(t, resp.State.Get(ctx, &read).HasError())
+		require.Equal(t, "new_metric", read.Metric.ValueString())
+		require.Equal(t, "api_latency", read.Name.ValueString())
+	})
+
+	t.Run("import", func(t *testing.T) {
+		r, _, schemaResp := newResource(t, model.AggregationRule{ID: "r-1", Name: "api_latency", Metric: "test_tf_metric"})
+
+		resp := &fwresource.ImportStateResponse{State: tfsdk.State{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+		}}
+		r.ImportState(ctx, fwresource.ImportStateRequest{ID: "api_latency"}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		var id, name, metric types.String
+		require.False(t, resp.State.GetAttribute(ctx, path.Root("id"), &id).HasError())
+		require.False(t, resp.State.GetAttribute(ctx, path.Root("name"), &name).HasError())
+		require.False(t, resp.State.GetAttribute(ctx, path.Root("metric"), &metric).HasError())
+		require.Equal(t, []string{"r-1", "api_latency", "test_tf_metric"}, []string{id.ValueString(), name.ValueString(), metric.ValueString()})
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		r, _, _ := newResource(t, model.AggregationRule{ID: "r-1", Name: "api_latency", Metric: "other_metric"})
+
+		planned := model.AggregationRule{Name: "api_latency", Metric: "test_tf_metric"}.ToTF()
+		planned.DestroyAction = types.StringValue(destroyActionDelete)
+		resp := modifyPlan(t, r, nil, planned)
+		require.Equal(t, []path.Path{path.Root("name")}, errorPaths(resp.Diagnostics))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		r, api, schemaResp := newResource(t)
+		api.features = []string{}
+
+		tf := model.AggregationRule{Name: "api_latency", Metric: "test_tf_metric"}.ToTF()
+		tf.DestroyAction = types.StringValue(destroyActionDelete)
+		plan := tfsdk.Plan{Schema: schemaResp.Schema}
+		require.False(t, plan.Set(ctx, tf).HasError())
+
+		resp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+		r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		// The name is not sent, but kept in the state.
+		rule, _ := api.rule("test_tf_metric")
+		require.Empty(t, rule.Name)
+		readResp := &fwresource.ReadResponse{State: resp.State}
+		r.Read(ctx, fwresource.ReadRequest{State: resp.State}, readResp)
+		var read model.RuleTF
+		require.False(t, readResp.State.Get(ctx, &read).HasError())
+		require.Equal(t, "api_latency", read.Name.ValueString())
+	})
+}
+
+func TestRuleResourceDestroyAction(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		action   string
+		expected []string
+	}{
+		{action: "delete", expected: []string{}},
+		{action: "archive", expected: []string{"test_tf_metric"}},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}})
+			r := &ruleResource{rules: api.aggregationRules()}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+			tf.DestroyAction = types.StringValue(tc.action)
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, tf).HasError())
+
+			resp := &fwresource.DeleteResponse{State: state}
+			r.Delete(ctx, fwresource.DeleteRequest{State: state}, resp)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+			require.Equal(t, tc.expected, api.metrics())
+			if rule, ok := api.rule("test_tf_metric"); ok {
+				require.True(t, rule.Archived)
+				require.Equal(t, []string{"pod"}, rule.DropLabels)
+			}
+		})
+	}
+}
+
+func TestRuleResourceReadArchived(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", Archived: true})
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.AggregationRule{Metric: "test_tf_metric"}.ToTF()).HasError())
+
+	resp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Aggregation rule is archived", resp.Diagnostics.Warnings()[0].Summary())
+
+	var tf model.RuleTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.True(t, tf.Archived.ValueBool())
+	require.Equal(t, "delete", tf.DestroyAction.ValueString())
+}
+
+func TestRuleResourceReadDropDrift(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		warn     bool
+		drop     bool
+		warnings int
+	}{
+		{name: "disabled", warn: false, drop: true, warnings: 0},
+		{name: "unchanged", warn: true, drop: false, warnings: 0},
+		{name: "changed", warn: true, drop: true, warnings: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", Drop: tc.drop})
+			r := &ruleResource{rules: api.aggregationRules(), warnOnDropDrift: tc.warn}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, model.AggregationRule{Metric: "test_tf_metric"}.ToTF()).HasError())
+
+			resp := &fwresource.ReadResponse{State: state}
+			r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			require.Len(t, resp.Diagnostics.Warnings(), tc.warnings)
+
+			// The rule read from the API is always kept in state.
+			var tf model.RuleTF
+			require.False(t, resp.State.Get(ctx, &tf).HasError())
+			require.Equal(t, tc.drop, tf.Drop.ValueBool())
+		})
+	}
+}
+
+func TestRuleResourceEnforceOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stamping", func(t *testing.T) {
+		api := newMockAPI(t)
+		r := &ruleResource{rules: api.aggregationRules(), enforceOwnership: true}
+
+		var schemaResp fwresource.SchemaResponse
+		r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+		tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+		tf.DestroyAction = types.StringValue(destroyActionDelete)
+		plan := tfsdk.Plan{Schema: schemaResp.Schema}
+		require.False(t, plan.Set(ctx, tf).HasError())
+
+		createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+		r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+		require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+		got, _ := api.rule("test_tf_metric")
+		require.Equal(t, "terraform", got.ManagedBy)
+
+		// An update restores the marker of a rule taken over by another
+		// owner.
+		api.mu.Lock()
+		got.ManagedBy = "ui"
+		api.rules["test_tf_metric"] = got
+		api.mu.Unlock()
+		r.rules = api.aggregationRules()
+
+		tf.DropLabels = toTypesStrings([]string{"pod", "instance"})
+		require.False(t, plan.Set(ctx, tf).HasError())
+		updateResp := &fwresource.UpdateResponse{State: createResp.State}
+		r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: createResp.State}, updateResp)
+		require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+		got, _ = api.rule("test_tf_metric")
+		require.Equal(t, "terraform", got.ManagedBy)
+	})
+
+	for _, tc := range []struct {
+		name          string
+		enforce       bool
+		managedBy     string
+		expectWarning string
+	}{
+		{name: "managed", enforce: true, managedBy: "terraform"},
+		{name: "missing marker", enforce: true, managedBy: "", expectWarning: "Aggregation rule not marked as managed by Terraform"},
+		{name: "foreign owner", enforce: true, managedBy: "ui", expectWarning: "Aggregation rule managed by another owner"},
+		{name: "disabled", enforce: false, managedBy: "ui"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", ManagedBy: tc.managedBy})
+			r := &ruleResource{rules: api.aggregationRules(), enforceOwnership: tc.enforce}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, model.AggregationRule{Metric: "test_tf_metric"}.ToTF()).HasError())
+
+			resp := &fwresource.ReadResponse{State: state}
+			r.Read(ctx, fwresource.ReadRequest{State: state}, resp)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			if tc.expectWarning == "" {
+				require.Empty(t, resp.Diagnostics.Warnings())
+				return
+			}
+			require.Len(t, resp.Diagnostics.Warnings(), 1)
+			require.Equal(t, tc.expectWarning, resp.Diagnostics.Warnings()[0].Summary())
+		})
+	}
+}
+
+func TestRuleResourceAllAggregations(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.supportedAggregations = []string{"count", "sum"}
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}, Aggregations: []string{"*"}}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+
+	// The API receives the supported aggregations.
+	rule, ok := api.rule("test_tf_metric")
+	require.True(t, ok)
+	require.Equal(t, model.Aggregations{"count", "sum"}, rule.Aggregations)
+
+	read := func(r *ruleResource) []string {
+		resp := &fwresource.ReadResponse{State: createResp.State}
+		r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, resp)
+		require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+		var tf model.RuleTF
+		require.False(t, resp.State.Get(ctx, &tf).HasError())
+		return tf.ToAPIReq().Aggregations
+	}
+	require.Equal(t, []string{"*"}, read(r))
+
+	// Once the API supports a new aggregation, the rule is out of date.
+	api.supportedAggregations = []string{"count", "max", "sum"}
+	require.Equal(t, []string{"count", "sum"}, read(&ruleResource{rules: api.aggregationRules()}))
+}
+
+func TestRuleResourceTimeouts(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		timeout string
+		block   bool
+		err     string
+	}{
+		{timeout: "10ms", block: true, err: "context deadline exceeded"},
+		{timeout: "10s"},
+	} {
+		t.Run(tc.timeout, func(t *testing.T) {
+			api := newMockAPI(t)
+			r := &ruleResource{rules: api.aggregationRules()}
+			// A blocked request only returns once the timeout expires.
+			if tc.block {
+				api.blockRequests()
+			}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+			tf.DestroyAction = types.StringValue(destroyActionDelete)
+			tf.Timeouts = timeoutsValue(map[string]string{"create": tc.timeout})
+			plan := tfsdk.Plan{Schema: schemaResp.Schema}
+			require.False(t, plan.Set(ctx, tf).HasError())
+
+			resp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+			r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+			if tc.err == "" {
+				require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+				return
+			}
+			require.True(t, resp.Diagnostics.HasError())
+			require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), tc.err)
+		})
+	}
+}
+
+func TestRuleResourceAPIWarnings(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.warnings["test_tf_metric"] = []string{"this rule shadows the rule for test_tf"}
+	r := &ruleResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+
+	resp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Aggregation rule warning", resp.Diagnostics.Warnings()[0].Summary())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), "this rule shadows the rule for test_tf")
+
+	// The rule is created despite the warning.
+	_, ok := api.rule("test_tf_metric")
+	require.True(t, ok)
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	ctx := context.Background()
+	value := timeoutsValue(map[string]string{"create": "10m", "read": "0s", "update": "-1s"})
+	require.Equal(t, []path.Path{path.Root("timeouts").AtName("update")}, errorPaths(validateTimeouts(ctx, value)))
+	require.Empty(t, validateTimeouts(ctx, timeouts.Value{Object: types.ObjectNull(model.TimeoutsAttributeTypes)}))
+}
+
+func TestRuleResourceValidateDestroyAction(t *testing.T) {
+	ctx := context.Background()
+	r := &ruleResource{}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for action, expectError := range map[string]bool{"delete": false, "archive": false, "purge": true} {
+		tf := model.AggregationRule{Metric: "test_tf_metric"}.ToTF()
+		tf.DestroyAction = types.StringValue(action)
+		state := tfsdk.State{Schema: schemaResp.Schema}
+		require.False(t, state.Set(ctx, tf).HasError())
+
+		resp := &fwresource.ValidateConfigResponse{}
+		r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: state.Raw}}, resp)
+		require.Equal(t, expectError, resp.Diagnostics.HasError(), action)
+	}
+}
+
+func TestRuleResourceAllowMissingOnDestroy(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name         string
+		allowMissing bool
+		status       int
+		expectError  bool
+	}{
+		{name: "missing", allowMissing: false, status: http.StatusNotFound, expectError: true},
+		{name: "missing allowed", allowMissing: true, status: http.StatusNotFound},
+		{name: "other error", allowMissing: true, status: http.StatusForbidden, expectError: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric"})
+			r := &ruleResource{rules: api.aggregationRules()}
+			api.failOn("DELETE", "/aggregations/rule/test_tf_metric", tc.status)
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			tf := model.AggregationRule{Metric: "test_tf_metric"}.ToTF()
+			tf.DestroyAction = types.StringValue("delete")
+			tf.AllowMissingOnDestroy = types.BoolValue(tc.allowMissing)
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, tf).HasError())
+
+			resp := &fwresource.DeleteResponse{State: state}
+			r.Delete(ctx, fwresource.DeleteRequest{State: state}, resp)
+			require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), resp.Diagnostics)
+		})
+	}
+}
+
+func TestRuleResourceDrainBeforeDelete(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name    string
+		drain   string
+		timeout string
+		deleted bool
+	}{
+		{name: "drained", drain: "50ms", deleted: true},
+		{name: "timeout", drain: "1h", timeout: "50ms"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}})
+			r := &ruleResource{rules: api.aggregationRules()}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+			tf.DestroyAction = types.StringValue(destroyActionDelete)
+			tf.DrainBeforeDelete = types.StringValue(tc.drain)
+			if tc.timeout != "" {
+				tf.Timeouts = timeoutsValue(map[string]string{"delete": tc.timeout})
+			}
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, tf).HasError())
+
+			start := time.Now()
+			resp := &fwresource.DeleteResponse{State: state}
+			r.Delete(ctx, fwresource.DeleteRequest{State: state}, resp)
+			require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+			require.Less(t, time.Since(start), time.Minute)
+
+			if !tc.deleted {
+				require.True(t, resp.Diagnostics.HasError())
+				require.Equal(t, "Aggregation rule drain interrupted", resp.Diagnostics.Errors()[0].Summary())
+				rule, ok := api.rule("test_tf_metric")
+				require.True(t, ok)
+				require.True(t, rule.Archived)
+				require.NotContains(t, api.requestLog(), "DELETE /aggregations/rule/test_tf_metric")
+				return
+			}
+
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+			require.Empty(t, api.metrics())
+			// The rule is archived before it is deleted.
+			requests := api.requestLog()
+			require.Equal(t, "DELETE /aggregations/rule/test_tf_metric", requests[len(requests)-1])
+			require.Equal(t, "PUT /aggregations/rule/test_tf_metric", requests[len(requests)-2])
+		})
+	}
+}
+
+func TestRuleResourceValidateDrainBeforeDelete(t *testing.T) {
+	ctx := context.Background()
+	r := &ruleResource{}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	for _, tc := range []struct {
+		drain       string
+		action      string
+		expectError bool
+	}{
+		{drain: "15m", action: "delete"},
+		{drain: "soon", action: "delete", expectError: true},
+		{drain: "0s", action: "delete", expectError: true},
+		{drain: "15m", action: "archive", expectError: true},
+	} {
+		tf := model.AggregationRule{Metric: "test_tf_metric"}.ToTF()
+		tf.DestroyAction = types.StringValue(tc.action)
+		tf.DrainBeforeDelete = types.StringValue(tc.drain)
+		state := tfsdk.State{Schema: schemaResp.Schema}
+		require.False(t, state.Set(ctx, tf).HasError())
+
+		resp := &fwresource.ValidateConfigResponse{}
+		r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: state.Raw}}, resp)
+		require.Equal(t, tc.expectError, resp.Diagnostics.HasError(), tc)
+	}
+}
+
+func TestRuleResourceApplyWindow(t *testing.T) {
+	ctx := context.Background()
+	window, err := parseApplyWindow(ApplyWindowTF{Days: toTypesStrings([]string{"sat"}), Start: types.StringValue("10:00"), End: types.StringValue("12:00")})
+	require.NoError(t, err)
+	// A Monday.
+	window.now = func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		name  string
+		force bool
+	}{
+		{name: "outside window"},
+		{name: "forced", force: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric"})
+			rules := api.aggregationRules()
+			rules.window = window
+			r := &ruleResource{rules: rules}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			tf := model.AggregationRule{Metric: "test_tf_metric"}.ToTF()
+			tf.DestroyAction = types.StringValue("delete")
+			tf.ForceOutsideWindow = types.BoolValue(tc.force)
+			state := tfsdk.State{Schema: schemaResp.Schema}
+			require.False(t, state.Set(ctx, tf).HasError())
+
+			resp := &fwresource.DeleteResponse{State: state}
+			r.Delete(ctx, fwresource.DeleteRequest{State: state}, resp)
+			if tc.force {
+				require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+				require.Empty(t, api.metrics())
+				return
+			}
+
+			require.True(t, resp.Diagnostics.HasError())
+			require.Contains(t, resp.Diagnostics[0].Detail(), "Sat 2026-03-07 10:00 UTC")
+			require.Equal(t, []string{"test_tf_metric"}, api.metrics())
+		})
+	}
+}
+
+func TestRuleResourceModifyPlanAggregatedMetricNames(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		rule     model.AggregationRule
+		features []string
+		expected []string
+	}{
+		{name: "aggregated", rule: model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{"sum", "count"}}, features: []string{"aggregated_metric_names"}, expected: []string{"prod_test_tf_metric:count", "prod_test_tf_metric:sum"}},
+		{name: "dropped", rule: model.AggregationRule{Metric: "test_tf_metric", Drop: true}, features: []string{"aggregated_metric_names"}, expected: []string{}},
+		{name: "prefix", rule: model.AggregationRule{Metric: "test_tf_", MatchType: "prefix", Aggregations: []string{"sum"}}, features: []string{"aggregated_metric_names"}},
+		{name: "match prefixes", rule: model.AggregationRule{Metric: "test_a_", MatchPrefixes: []string{"test_a_", "test_b_"}, Aggregations: []string{"sum"}}, features: []string{"aggregated_metric_names", "match_prefixes"}},
+		{name: "unsupported", rule: model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{"sum"}}, features: []string{"rule_names"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t)
+			api.features = tc.features
+			rules := NewAggregationRules(api.client(), "prod_")
+			rules.capabilities = newCapabilities(api.client())
+			require.NoError(t, rules.Init())
+			r := &ruleResource{rules: rules, capabilities: rules.capabilities}
+
+			tf := tc.rule.ToTF()
+			tf.AggregatedMetricNames = types.ListUnknown(types.StringType)
+			resp := modifyPlan(t, r, nil, tf)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+			var names []string
+			require.False(t, resp.Plan.GetAttribute(ctx, path.Root("aggregated_metric_names"), &names).HasError())
+			require.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestRuleResourceEffective(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.supportedAggregations = []string{"sum", "count", "max"}
+	rules := NewAggregationRules(api.client(), "prod_")
+	require.NoError(t, rules.Init())
+	r := &ruleResource{rules: rules, autoImport: true}
+
+	rollout := int64(50)
+	for _, tc := range []struct {
+		name       string
+		rule       model.AggregationRule
+		autoImport types.Bool
+		expected   model.EffectiveRuleTF
+	}{
+		{
+			name: "defaults",
+			rule: model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{"sum", "count"}},
+			expected: model.EffectiveRuleTF{
+				Metric:               types.StringValue("prod_test_tf_metric"),
+				MatchType:            types.StringValue("exact"),
+				LabelMatchMode:       types.StringValue("exact"),
+				Aggregations:         []types.String{types.StringValue("count"), types.StringValue("sum")},
+				AggregationIntervals: map[string]types.String{},
+				RolloutPercentage:    types.Int64Value(100),
+				AutoImport:           types.BoolValue(true),
+			},
+		},
+		{
+			name: "configured",
+			rule: model.AggregationRule{
+				Metric:               "test_tf_",
+				MatchType:            "prefix",
+				KeepLabels:           []string{"k8s_*"},
+				LabelMatchMode:       "glob",
+				Aggregations:         []string{allAggregations},
+				AggregationInterval:  "1m",
+				AggregationIntervals: map[string]string{"max": "5m"},
+				RolloutPercentage:    &rollout,
+			},
+			autoImport: types.BoolValue(false),
+			expected: model.EffectiveRuleTF{
+				Metric:         types.StringValue("prod_test_tf_"),
+				MatchType:      types.StringValue("prefix"),
+				LabelMatchMode: types.StringValue("glob"),
+				// The types supported by the API, with the interval
+				// of max overriding aggregation_interval.
+				Aggregations: []types.String{types.StringValue("count"), types.StringValue("max"), types.StringValue("sum")},
+				AggregationIntervals: map[string]types.String{
+					"count": types.StringValue("1m"),
+					"max":   types.StringValue("5m"),
+					"sum":   types.StringValue("1m"),
+				},
+				RolloutPercentage: types.Int64Value(50),
+				AutoImport:        types.BoolValue(false),
+			},
+		},
+		{
+			name: "dropped",
+			rule: model.AggregationRule{Metric: "test_tf_metric", Drop: true, Aggregations: []string{"sum"}, AggregationInterval: "1m"},
+			expected: model.EffectiveRuleTF{
+				Metric:               types.StringValue("prod_test_tf_metric"),
+				MatchType:            types.StringValue("exact"),
+				LabelMatchMode:       types.StringValue("exact"),
+				Aggregations:         []types.String{},
+				AggregationIntervals: map[string]types.String{},
+				RolloutPercentage:    types.Int64Value(100),
+				AutoImport:           types.BoolValue(true),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tf := tc.rule.ToTF()
+			tf.AutoImport = tc.autoImport
+			tf.Effective = types.ObjectUnknown(model.EffectiveRuleAttributeTypes)
+			resp := modifyPlan(t, r, nil, tf)
+			require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+			var planned model.EffectiveRuleTF
+			require.False(t, resp.Plan.GetAttribute(ctx, path.Root("effective"), &planned).HasError())
+			require.Equal(t, tc.expected, planned)
+		})
+	}
+
+	// Read resolves them from the rule returned by the API.
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{"sum"}, AggregationInterval: "2m"}))
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, model.AggregationRule{Metric: "test_tf_metric"}.ToTF()).HasError())
+	readResp := &fwresource.ReadResponse{State: state}
+	r.Read(ctx, fwresource.ReadRequest{State: state}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+
+	var read model.EffectiveRuleTF
+	require.False(t, readResp.State.GetAttribute(ctx, path.Root("effective"), &read).HasError())
+	require.Equal(t, "prod_test_tf_metric", read.Metric.ValueString())
+	require.Equal(t, map[string]types.String{"sum": types.StringValue("2m")}, read.AggregationIntervals)
+
+	// Without the supported aggregations, every aggregation type cannot be
+	// resolved.
+	tf := model.AggregationRule{Metric: "test_tf_metric", Aggregations: []string{allAggregations}}.ToTF()
+	r.setEffective(ctx, NewAggregationRules(newMockAPI(t).client(), ""), &tf, tf.ToAPIReq())
+	require.True(t, tf.Effective.IsNull())
+}
+
+func TestRuleResourceContentID(t *testing.T) {
+	ctx := context.Background()
+	rule := model.AggregationRule{
+		Metric:       "test_tf_metric",
+		KeepLabels:   []string{"job", "env"},
+		Aggregations: []string{"sum", "count"},
+		DropIf:       []model.DropMatcher{{Label: "env", Operator: "=", Value: "test"}, {Label: "job", Operator: "!=", Value: "api"}},
+	}
+
+	reordered := rule
+	reordered.KeepLabels = []string{"env", "job"}
+	reordered.Aggregations = []string{"count", "sum"}
+	reordered.DropIf = []model.DropMatcher{rule.DropIf[1], rule.DropIf[0]}
+	reordered.ID, reordered.MatchType, reordered.ManagedBy = "r-1", "exact", "terraform"
+	reordered.Tags = map[string]string{"owner": "team-a"}
+	require.Equal(t, rule.ContentID(), reordered.ContentID())
+
+	changed := rule
+	changed.Aggregations = []string{"sum"}
+	require.NotEqual(t, rule.ContentID(), changed.ContentID())
+
+	prefixes := model.AggregationRule{Metric: "a_", MatchType: "prefix", MatchPrefixes: []string{"a_", "b_"}}
+	reorderedPrefixes := model.AggregationRule{Metric: "b_", MatchType: "prefix", MatchPrefixes: []string{"b_", "a_"}}
+	require.Equal(t, prefixes.ContentID(), reorderedPrefixes.ContentID())
+
+	// The ID planned is the one in state after apply and refresh.
+	api := newMockAPI(t)
+	r := &ruleResource{rules: api.aggregationRules()}
+	tf := rule.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionDelete)
+	tf.ContentID = types.StringUnknown()
+	planResp := modifyPlan(t, r, nil, tf)
+	require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+	var planned string
+	require.False(t, planResp.Plan.GetAttribute(ctx, path.Root("content_id"), &planned).HasError())
+	require.Equal(t, rule.ContentID(), planned)
+
+	createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: planResp.Plan.Schema}}
+	r.Create(ctx, fwresource.CreateRequest{Plan: planResp.Plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+	require.False(t, readResp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, types.StringValue(planned), tf.ContentID)
+}
+
+func TestRuleResourceCreateOnConflict(t *testing.T) {
+	ctx := context.Background()
+	existing := model.AggregationRule{ID: "1", Metric: "test_tf_metric", DropLabels: []string{"namespace"}, Aggregations: []string{"count"}}
+
+	for _, tc := range []struct {
+		onConflict   string
+		expectError  bool
+		expectLabels []string
+	}{
+		{onConflict: onConflictFail, expectError: true, expectLabels: []string{"namespace"}},
+		{onConflict: onConflictUpdate, expectLabels: []string{"pod"}},
+		{onConflict: onConflictAdopt, expectLabels: []string{"namespace"}},
+	} {
+		t.Run(tc.onConflict, func(t *testing.T) {
+			api := newMockAPI(t, existing)
+			r := &ruleResource{rules: api.aggregationRules()}
+
+			var schemaResp fwresource.SchemaResponse
+			r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+			tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+			tf.DestroyAction = types.StringValue(destroyActionDelete)
+			tf.OnConflict = types.StringValue(tc.onConflict)
+			tf.Aggregations = nil
+			plan := tfsdk.Plan{Schema: schemaResp.Schema}
+			require.False(t, plan.Set(ctx, tf).HasError())
+
+			createResp := &fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+			r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+			got, _ := api.rule("test_tf_metric")
+			require.Equal(t, tc.expectLabels, got.DropLabels)
+
+			if tc.expectError {
+				require.True(t, createResp.Diagnostics.HasError())
+				require.Contains(t, createResp.Diagnostics.Errors()[0].Detail(), "a rule for test_tf_metric already exists")
+				return
+			}
+			require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+			require.Len(t, createResp.Diagnostics.Warnings(), 1)
+			require.Equal(t, model.Aggregations{"count"}, got.Aggregations, "fields not managed by the resource are kept")
+
+			var state model.RuleTF
+			require.False(t, createResp.State.Get(ctx, &state).HasError())
+			require.Equal(t, "1", state.ID.ValueString())
+		})
+	}
+}
+
+func TestRuleResourceArchiveRequiresFeature(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}})
+	api.features = []string{}
+	r := &ruleResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+
+	tf := model.AggregationRule{Metric: "test_tf_metric", DropLabels: []string{"pod"}}.ToTF()
+	tf.DestroyAction = types.StringValue(destroyActionArchive)
+
+	resp := modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Feature not supported by backend", resp.Diagnostics.Errors()[0].Summary())
+
+	// A destroy from an older state leaves the rule as it is rather than
+	// apparently archive it.
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, tf).HasError())
+
+	deleteResp := &fwresource.DeleteResponse{State: state}
+	r.Delete(ctx, fwresource.DeleteRequest{State: state}, deleteResp)
+	require.True(t, deleteResp.Diagnostics.HasError())
+	got, ok := api.rule("test_tf_metric")
+	require.True(t, ok)
+	require.False(t, got.Archived)
+
+	api.features = []string{string(client.FeatureArchivedRules)}
+	r.capabilities = newCapabilities(api.client())
+	deleteResp = &fwresource.DeleteResponse{State: state}
+	r.Delete(ctx, fwresource.DeleteRequest{State: state}, deleteResp)
+	require.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+	got, _ = api.rule("test_tf_metric")
+	require.True(t, got.Archived)
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// ruleSetChanges are the requests needed to reconcile the existing aggregation
+// rules with a desired set of rules.
+type ruleSetChanges struct {
+	Create []model.AggregationRule
+	Update []model.AggregationRule
+	Delete []model.AggregationRule
+
+	// DependsOn maps the metric of a rule to the metric of the rule which
+	// must be written before it when both are written.
+	DependsOn map[string]string
+}
+
+func (c ruleSetChanges) empty() bool {
+	return len(c.Create) == 0 && len(c.Update) == 0 && len(c.Delete) == 0
+}
+
+// ruleWrite is the creation or update of a rule.
+type ruleWrite struct {
+	verb string
+	rule model.AggregationRule
+}
+
+// writes returns the creations and then the updates of c, reordered so that
+// every rule is written after the rule it depends on.
+func (c ruleSetChanges) writes() []ruleWrite {
+	writes := make([]ruleWrite, 0, len(c.Create)+len(c.Update))
+	for _, rule := range c.Create {
+		writes = append(writes, ruleWrite{verb: "create", rule: rule})
+	}
+	for _, rule := range c.Update {
+		writes = append(writes, ruleWrite{verb: "update", rule: rule})
+	}
+	return orderByDependencies(writes, func(w ruleWrite) string { return w.rule.Metric }, c.DependsOn)
+}
+
+// orderByDependencies returns items reordered so that every item comes after
+// the item it depends on, if that is one of items too, and otherwise in their
+// order. dependsOn maps the metric of an item to the metric of the item it
+// depends on. Cycles, which validation rejects, are broken where they close.
+func orderByDependencies[T any](items []T, metric func(T) string, dependsOn map[string]string) []T {
+	if len(dependsOn) == 0 {
+		return items
+	}
+
+	index := make(map[string]int, len(items))
+	for i, item := range items {
+		index[metric(item)] = i
+	}
+
+	ordered := make([]T, 0, len(items))
+	visited := make([]bool, len(items))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		if dependency, ok := dependsOn[metric(items[i])]; ok {
+			if j, ok := index[dependency]; ok {
+				visit(j)
+			}
+		}
+		ordered = append(ordered, items[i])
+	}
+	for i := range items {
+		visit(i)
+	}
+	return ordered
+}
+
+// diffRuleSet computes the changes needed to go from the current rules to the
+// desired ones. Previously managed rules which are no longer desired are
+// deleted. When prune is set, the rules which are not desired and not managed
+// by Terraform are deleted as well; rules managed by Terraform which are not
+// in previous belong to other resources and are never pruned.
+func diffRuleSet(current, previous, desired []model.AggregationRule, prune bool) ruleSetChanges {
+	existing := make(map[string]model.AggregationRule, len(current))
+	for _, rule := range current {
+		existing[rule.Metric] = rule
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	var changes ruleSetChanges
+	for _, rule := range desired {
+		wanted[rule.Metric] = true
+
+		existingRule, ok := existing[rule.Metric]
+		switch {
+		case !ok:
+			changes.Create = append(changes.Create, rule)
+		case !existingRule.Equal(rule):
+			changes.Update = append(changes.Update, rule)
+		}
+	}
+
+	managed := make(map[string]bool, len(previous))
+	for _, rule := range previous {
+		managed[rule.Metric] = true
+	}
+
+	for _, rule := range current {
+		if wanted[rule.Metric] {
+			continue
+		}
+		if managed[rule.Metric] || prune && !rule.IsManaged() {
+			changes.Delete = append(changes.Delete, rule)
+		}
+	}
+
+	for _, rules := range [][]model.AggregationRule{changes.Create, changes.Update, changes.Delete} {
+		sort.Slice(rules, func(i, j int) bool { return rules[i].Metric < rules[j].Metric })
+	}
+
+	return changes
+}
+
+// withExistingTags returns desired with the tags of each rule set to those of
+// the current rule for its metric. It is used by resources whose rules do not
+// model tags, so that the tags set by other tools are neither compared nor
+// removed when the rules are updated.
+func withExistingTags(current, desired []model.AggregationRule) []model.AggregationRule {
+	tags := make(map[string]map[string]string, len(current))
+	for _, rule := range current {
+		tags[rule.Metric] = rule.Tags
+	}
+
+	rules := slices.Clone(desired)
+	for i := range rules {
+		rules[i].Tags = tags[rules[i].Metric]
+	}
+	return rules
+}
+
+// readRules returns the existing rules for the given metrics, skipping metrics
+// which have no rule and metrics which are listed more than once.
+func readRules(rules *AggregationRules, metrics []string) []model.RuleSetRuleTF {
+	tf := []model.RuleSetRuleTF{}
+	seen := make(map[string]bool, len(metrics))
+
+	for _, metric := range metrics {
+		if seen[metric] {
+			continue
+		}
+		seen[metric] = true
+
+		rule, err := rules.Read(metric)
+		if err != nil {
+			continue
+		}
+		tf = append(tf, rule.ToRuleSetRuleTF())
+	}
+
+	return tf
+}
+
+// apiWarnings returns a callback for AggregationRules.WithWarnings which adds
+// every warning returned by the API to diags. The warnings do not fail the
+// apply.
+func apiWarnings(diags *diag.Diagnostics) func(metric, warning string) {
+	return func(metric, warning string) {
+		diags.AddWarning(
+			"Aggregation rule warning",
+			fmt.Sprintf("The API returned a warning for the rule for %q: %s", metric, warning),
+		)
+	}
+}
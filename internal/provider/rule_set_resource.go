@@ -0,0 +1,473 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type ruleSetResource struct {
+	rules               *AggregationRules
+	capabilities        *capabilities
+	broadMatchMinLength int
+	schemaValidate      bool
+	requiredKeepLabels  []string
+
+	allowedMetricPatterns []string
+
+	warnShortAggregationDelay bool
+	savings                   *savingsBudget
+
+	// transactional rule sets roll back the changes already made when one
+	// of them fails.
+	transactional bool
+}
+
+var (
+	_ resource.Resource                   = &ruleSetResource{}
+	_ resource.ResourceWithConfigure      = &ruleSetResource{}
+	_ resource.ResourceWithModifyPlan     = &ruleSetResource{}
+	_ resource.ResourceWithValidateConfig = &ruleSetResource{}
+)
+
+func newRuleSetResource() resource.Resource {
+	return &ruleSetResource{}
+}
+
+func newTransactionalRuleSetResource() resource.Resource {
+	return &ruleSetResource{transactional: true}
+}
+
+func (r *ruleSetResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+	r.capabilities = data.capabilities
+	r.broadMatchMinLength = data.broadMatchMinLength
+	r.schemaValidate = data.schemaValidate
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+	r.warnShortAggregationDelay = data.warnShortAggregationDelay
+	r.savings = data.savings
+}
+
+func (r *ruleSetResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	if r.transactional {
+		resp.TypeName = fmt.Sprintf("%s_transactional_rule_set", req.ProviderTypeName)
+		return
+	}
+	resp.TypeName = fmt.Sprintf("%s_rule_set", req.ProviderTypeName)
+}
+
+func (r *ruleSetResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	description := "Manages a set of aggregation rules as a single resource."
+	if r.transactional {
+		description = "Manages a set of related aggregation rules which must be applied all together or not at all. The rules are saved one request at a time; when one of them fails, the changes already made are rolled back, deleting the rules which were created and restoring the rules which were updated or deleted as they were before, and the resource is left unchanged. Rules whose rollback fails are named in the error. The provider's error_strategy does not apply."
+	}
+
+	resp.Schema = schema.Schema{
+		Description: description,
+		Attributes: map[string]schema.Attribute{
+			"rules": schema.ListNestedAttribute{
+				Required:    true,
+				Description: "The aggregation rules managed by this rule set. Their tags are not managed: the tags of existing rules are kept.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Required:    true,
+							Description: "The name of the metric to be aggregated.",
+						},
+						"match_type": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     stringdefault.StaticString(""),
+							Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
+						},
+
+						"drop": schema.BoolAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     defaultBoolFalse{},
+							Description: "Set to true to skip both ingestion and aggregation and drop the metric entirely.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Computed:    true,
+							Default:     defaultEmptyList{},
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"drop_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Computed:    true,
+							Default:     defaultEmptyList{},
+							Description: "The array of labels that will be aggregated.",
+						},
+
+						"aggregations": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Computed:    true,
+							Default:     defaultEmptyList{},
+							Description: "The array of aggregation types to calculate for this metric. Percentiles are written as p<percent>, such as p99 or p99.9. Set to [\"*\"] to calculate every aggregation type supported by the API.",
+						},
+
+						"aggregation_interval": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     stringdefault.StaticString(""),
+							Description: "The interval at which to generate the aggregated series.",
+						},
+						"aggregation_delay": schema.StringAttribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     stringdefault.StaticString(""),
+							Description: "The delay until aggregation is performed.",
+						},
+
+						"priority": schema.Int64Attribute{
+							Optional:    true,
+							Computed:    true,
+							Default:     int64default.StaticInt64(0),
+							Description: "The priority of the rule. When several rules match a metric, the rule with the highest priority is applied. Must not be negative. Defaults to 0.",
+						},
+					},
+				},
+			},
+			"prune": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     defaultBoolFalse{},
+				Description: "When set to true, every aggregation rule which is not part of this rule set and not managed by Terraform is deleted. Rules managed by other Terraform resources are never deleted. The rules to be deleted are listed as a warning during plan.",
+			},
+			"allow_broad_match": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to allow prefix or suffix rules whose metric is shorter than the provider's broad_match_min_length. Such rules aggregate a large share of all metrics.",
+			},
+			"force_outside_window": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to change the rules even outside of the provider's apply_window. The value in state is used when the resource is destroyed.",
+			},
+			"depends_on_metric": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The order in which the rules of the set are written, as a map from the metric of a rule to the metric of another rule of the set which must be created or updated before it, such as a general prefix rule before the exact rules which override it. Rules are otherwise written ordered by metric. This only orders the rules within the rule set; Terraform's depends_on is still needed to order the rule set against other resources. Rules are deleted after every other rule is written, in no particular order.",
+			},
+		},
+	}
+}
+
+func (r *ruleSetResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var rules types.List
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("rules"), &rules)...)
+	if resp.Diagnostics.HasError() || rules.IsNull() || rules.IsUnknown() {
+		return
+	}
+
+	known := make([]*model.AggregationRule, len(rules.Elements()))
+	for i, elem := range rules.Elements() {
+		v, err := elem.ToTerraformValue(ctx)
+		if err != nil || !v.IsFullyKnown() {
+			continue
+		}
+
+		obj, ok := elem.(types.Object)
+		if !ok {
+			continue
+		}
+
+		var tf model.RuleSetRuleTF
+		resp.Diagnostics.Append(obj.As(ctx, &tf, basetypes.ObjectAsOptions{})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		rule := tf.ToAPIReq()
+		known[i] = &rule
+	}
+
+	resp.Diagnostics.Append(validateRuleSet(known)...)
+
+	var dependsOnMap types.Map
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("depends_on_metric"), &dependsOnMap)...)
+	if resp.Diagnostics.HasError() || dependsOnMap.IsNull() || dependsOnMap.IsUnknown() {
+		return
+	}
+	var dependsOn map[string]types.String
+	resp.Diagnostics.Append(dependsOnMap.ElementsAs(ctx, &dependsOn, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(validateRuleDependencies(known, dependsOn, path.Root("depends_on_metric"))...)
+}
+
+func (r *ruleSetResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.rules == nil {
+		return
+	}
+
+	// Destroying the rule set deletes its rules.
+	if req.Plan.Raw.IsNull() {
+		var state model.RuleSetTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		changes := diffRuleSet(r.rules.List(), state.ToAPIReq(), nil, false)
+		resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Empty())...)
+		return
+	}
+	if !req.Plan.Raw.IsFullyKnown() {
+		return
+	}
+
+	var plan model.RuleSetTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if r.schemaValidate {
+		for i, rule := range plan.ToAPIReq() {
+			resp.Diagnostics.Append(validateRuleSchema(rule, path.Root("rules").AtListIndex(i))...)
+		}
+	}
+	if !plan.AllowBroadMatch.ValueBool() {
+		for i, rule := range plan.ToAPIReq() {
+			resp.Diagnostics.Append(validateBroadMatch(rule, r.broadMatchMinLength, path.Root("rules").AtListIndex(i))...)
+		}
+	}
+	for i, rule := range plan.ToAPIReq() {
+		resp.Diagnostics.Append(r.capabilities.requireRule(rule)...)
+		resp.Diagnostics.Append(validateRequiredKeepLabels(rule, r.requiredKeepLabels, path.Root("rules").AtListIndex(i))...)
+		resp.Diagnostics.Append(validateAllowedMetric(rule, r.allowedMetricPatterns, path.Root("rules").AtListIndex(i))...)
+		resp.Diagnostics.Append(validateAggregationDelay(rule, r.warnShortAggregationDelay, path.Root("rules").AtListIndex(i))...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var previous []model.AggregationRule
+	if !req.State.Raw.IsNull() {
+		var state model.RuleSetTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		previous = state.ToAPIReq()
+	}
+
+	current := r.rules.List()
+	desired, diags := r.desiredRules(plan, current)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(current, previous, desired, plan.Prune.ValueBool())
+	resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Root("rules"))...)
+	if !plan.Prune.ValueBool() {
+		return
+	}
+
+	managed := make(map[string]bool, len(previous))
+	for _, rule := range previous {
+		managed[rule.Metric] = true
+	}
+
+	var pruned []string
+	for _, rule := range changes.Delete {
+		if !managed[rule.Metric] {
+			pruned = append(pruned, rule.Metric)
+		}
+	}
+
+	if len(pruned) > 0 {
+		resp.Diagnostics.AddWarning(
+			"Unmanaged aggregation rules will be pruned",
+			fmt.Sprintf("The following aggregation rules are not part of the rule set and will be deleted: %s", strings.Join(pruned, ", ")),
+		)
+	}
+}
+
+func (r *ruleSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.RuleSetTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	current := r.rules.List()
+	desired, diags := r.desiredRules(plan, current)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(current, nil, desired, plan.Prune.ValueBool())
+	changes.DependsOn = plan.DependsOn()
+	if err := r.apply(ctx, changes, plan.ForceOutsideWindow, &resp.Diagnostics); err != nil {
+		resp.Diagnostics.AddError("Unable to create aggregation rule set", err.Error())
+		if r.transactional {
+			return
+		}
+
+		// Keep track of the rules which were created before the failure.
+		if state := r.currentState(plan.Metrics(), plan); len(state.Rules) > 0 {
+			resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+		}
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *ruleSetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state model.RuleSetTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tf := r.currentState(state.Metrics(), state)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+func (r *ruleSetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.RuleSetTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.RuleSetTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	current := r.rules.List()
+	desired, diags := r.desiredRules(plan, current)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(current, state.ToAPIReq(), desired, plan.Prune.ValueBool())
+	changes.DependsOn = plan.DependsOn()
+	if err := r.apply(ctx, changes, plan.ForceOutsideWindow, &resp.Diagnostics); err != nil {
+		resp.Diagnostics.AddError("Unable to update aggregation rule set", err.Error())
+		if r.transactional {
+			// The prior state is kept, as the changes were rolled back.
+			return
+		}
+
+		// Rules which could not be deleted yet are kept in state so that the
+		// deletion is retried on the next apply.
+		tf := r.currentState(append(plan.Metrics(), state.Metrics()...), plan)
+		resp.Diagnostics.Append(resp.State.Set(ctx, tf)...)
+		return
+	}
+
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *ruleSetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.RuleSetTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(r.rules.List(), state.ToAPIReq(), nil, false)
+	if err := r.apply(ctx, changes, state.ForceOutsideWindow, &resp.Diagnostics); err != nil {
+		resp.Diagnostics.AddError("Unable to delete aggregation rule set", err.Error())
+	}
+}
+
+// desiredRules returns the rules of plan with the allAggregations sentinel
+// expanded, so that they compare equal to the current rules which compute
+// every supported aggregation. Rule sets do not model tags, so the tags of the
+// current rules are kept.
+func (r *ruleSetResource) desiredRules(plan model.RuleSetTF, current []model.AggregationRule) ([]model.AggregationRule, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	rules := withExistingTags(current, plan.ToAPIReq())
+	for i, rule := range rules {
+		if hasAllAggregations(rule.Aggregations) {
+			supported, err := r.rules.SupportedAggregations()
+			if err != nil {
+				diags.AddAttributeError(path.Root("rules").AtListIndex(i), "Unable to expand aggregations", fmt.Sprintf("The rule for %q uses %q: %s", rule.Metric, allAggregations, err))
+				return nil, diags
+			}
+			rules[i].Aggregations = slices.Clone(supported)
+		}
+	}
+	return rules, diags
+}
+
+// apply makes the changes, all or nothing if the rule set is transactional.
+// Warnings returned by the API are added to diags. If force is set, the
+// changes are made even outside of the apply window.
+func (r *ruleSetResource) apply(ctx context.Context, changes ruleSetChanges, force types.Bool, diags *diag.Diagnostics) error {
+	rules := r.rules.WithContext(ctx).WithWarnings(apiWarnings(diags))
+	if force.ValueBool() {
+		rules = rules.WithoutApplyWindow()
+	}
+	if r.transactional {
+		return rules.ApplyTransaction(changes)
+	}
+	return rules.Apply(changes)
+}
+
+// currentState builds the rule set state from the existing rules for the given
+// metrics. Settings which are not part of the API are taken from settings, as
+// is the allAggregations sentinel of rules which still compute every supported
+// aggregation.
+func (r *ruleSetResource) currentState(metrics []string, settings model.RuleSetTF) model.RuleSetTF {
+	configured := make(map[string][]string, len(settings.Rules))
+	for _, rule := range settings.ToAPIReq() {
+		configured[rule.Metric] = rule.Aggregations
+	}
+	rules := readRules(r.rules, metrics)
+	for i, rule := range rules {
+		if collapsed := r.rules.collapseAllAggregations(rule.ToAPIReq(), configured[rule.Metric.ValueString()]); hasAllAggregations(collapsed.Aggregations) {
+			rules[i].Aggregations = []types.String{types.StringValue(allAggregations)}
+		}
+	}
+
+	return model.RuleSetTF{
+		Rules:           rules,
+		Prune:           settings.Prune,
+		AllowBroadMatch: settings.AllowBroadMatch,
+
+		ForceOutsideWindow: settings.ForceOutsideWindow,
+		DependsOnMetric:    settings.DependsOnMetric,
+	}
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestAccRuleSetResource(t *testing.T) {
+	CheckAccTestsEnabled(t)
+
+	metricName := fmt.Sprintf("test_tf_metric_%s", RandString(6))
+	t.Cleanup(func() {
+		aggRules := AggregationRulesForAccTest(t)
+		_ = aggRules.Delete(model.AggregationRule{Metric: metricName + "_a"})
+		_ = aggRules.Delete(model.AggregationRule{Metric: metricName + "_b"})
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create + Read.
+			{
+				Config: providerConfig + fmt.Sprintf(`
+resource "grafana-adaptive-metrics_rule_set" "test" {
+	rules = [
+		{
+			metric = "%[1]s_a"
+			drop = true
+		},
+		{
+			metric = "%[1]s_b"
+			drop_labels = [ "instance" ]
+			aggregations = [ "sum" ]
+		},
+	]
+}
+`, metricName),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.#", "2"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.0.metric", metricName+"_a"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.0.drop", "true"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.1.metric", metricName+"_b"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.1.drop_labels.0", "instance"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.1.aggregations.0", "sum"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "prune", "false"),
+				),
+			},
+			// Update + Read, removing a rule from the set deletes it.
+			{
+				Config: providerConfig + fmt.Sprintf(`
+resource "grafana-adaptive-metrics_rule_set" "test" {
+	rules = [
+		{
+			metric = "%[1]s_b"
+			drop_labels = [ "instance", "pod" ]
+			aggregations = [ "sum" ]
+		},
+	]
+}
+`, metricName),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.#", "1"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.0.metric", metricName+"_b"),
+					resource.TestCheckResourceAttr("grafana-adaptive-metrics_rule_set.test", "rules.0.drop_labels.#", "2"),
+					checkRuleDoesNotExist(t, metricName+"_a"),
+				),
+			},
+			// Delete happens automatically.
+		},
+	})
+}
+
+func checkRuleDoesNotExist(t *testing.T, metric string) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		aggRules := AggregationRulesForAccTest(t)
+		if _, err := aggRules.Read(metric); err == nil {
+			return fmt.Errorf("expected no rule for %s", metric)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,361 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestDiffRuleSet(t *testing.T) {
+	current := []model.AggregationRule{
+		{Metric: "managed_unchanged", Aggregations: []string{"sum"}},
+		{Metric: "managed_changed", Aggregations: []string{"sum"}},
+		{Metric: "managed_removed"},
+		{Metric: "unmanaged"},
+	}
+	previous := []model.AggregationRule{
+		{Metric: "managed_unchanged", Aggregations: []string{"sum"}},
+		{Metric: "managed_changed", Aggregations: []string{"sum"}},
+		{Metric: "managed_removed"},
+	}
+	desired := []model.AggregationRule{
+		{Metric: "managed_unchanged", Aggregations: []string{"sum"}, ManagedBy: "terraform"},
+		{Metric: "managed_changed", Aggregations: []string{"count"}},
+		{Metric: "managed_new"},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		prune    bool
+		expected ruleSetChanges
+	}{
+		{
+			name:  "without prune",
+			prune: false,
+			expected: ruleSetChanges{
+				Create: []model.AggregationRule{{Metric: "managed_new"}},
+				Update: []model.AggregationRule{{Metric: "managed_changed", Aggregations: []string{"count"}}},
+				Delete: []model.AggregationRule{{Metric: "managed_removed"}},
+			},
+		},
+		{
+			name:  "with prune",
+			prune: true,
+			expected: ruleSetChanges{
+				Create: []model.AggregationRule{{Metric: "managed_new"}},
+				Update: []model.AggregationRule{{Metric: "managed_changed", Aggregations: []string{"count"}}},
+				Delete: []model.AggregationRule{{Metric: "managed_removed"}, {Metric: "unmanaged"}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, diffRuleSet(current, previous, desired, tc.prune))
+		})
+	}
+}
+
+func TestDiffRuleSetNeverPrunesManagedRules(t *testing.T) {
+	current := []model.AggregationRule{
+		{Metric: "owned", ManagedBy: "terraform"},
+		{Metric: "removed", ManagedBy: "terraform"},
+		{Metric: "other_resource", ManagedBy: "terraform"},
+		{Metric: "unmanaged"},
+		{Metric: "other_tool", ManagedBy: "other"},
+	}
+	previous := []model.AggregationRule{{Metric: "owned"}, {Metric: "removed"}}
+	desired := []model.AggregationRule{{Metric: "owned", ManagedBy: "terraform"}}
+
+	// The rule managed by another Terraform resource survives the prune.
+	changes := diffRuleSet(current, previous, desired, true)
+	require.Equal(t, []string{"other_tool", "removed", "unmanaged"}, metricsOf(changes.Delete))
+	require.Empty(t, changes.Create)
+	require.Empty(t, changes.Update)
+}
+
+func TestAggregationRulesApply(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "managed", Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "unmanaged"},
+	)
+	rules := api.aggregationRules()
+
+	desired := []model.AggregationRule{
+		{Metric: "managed", Aggregations: []string{"count"}},
+		{Metric: "new"},
+	}
+	require.NoError(t, rules.Apply(diffRuleSet(rules.List(), nil, desired, true)))
+
+	require.Equal(t, []string{"managed", "new"}, api.metrics())
+	rule, ok := api.rule("managed")
+	require.True(t, ok)
+	require.Equal(t, model.Aggregations{"count"}, rule.Aggregations)
+	require.Equal(t, []string{"managed", "new"}, metricsOf(rules.List()))
+}
+
+func TestAggregationRulesApplyStopsAtFirstFailure(t *testing.T) {
+	api := newMockAPI(t)
+	rules := api.aggregationRules()
+
+	api.failOn("POST", "/aggregations/rule/b", http.StatusBadRequest)
+
+	desired := []model.AggregationRule{{Metric: "a"}, {Metric: "b"}, {Metric: "c"}}
+	err := rules.Apply(diffRuleSet(rules.List(), nil, desired, false))
+	require.ErrorContains(t, err, "failed to create rule for b")
+
+	require.Equal(t, []string{"a"}, api.metrics())
+	require.Equal(t, []string{"a"}, metricsOf(rules.List()))
+}
+
+func TestAggregationRulesApplyContinuesOnError(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "d"}, model.AggregationRule{Metric: "e"})
+	rules := api.aggregationRules()
+	rules.continueOnError = true
+
+	api.failOn("POST", "/aggregations/rule/b", http.StatusBadRequest)
+	api.failOn("DELETE", "/aggregations/rule/d", http.StatusBadRequest)
+
+	current := rules.List()
+	desired := []model.AggregationRule{{Metric: "a"}, {Metric: "b"}, {Metric: "c"}}
+	err := rules.Apply(diffRuleSet(current, current, desired, false))
+	require.ErrorContains(t, err, "failed to create rule for b")
+	require.ErrorContains(t, err, "failed to delete rule for d")
+
+	require.Equal(t, []string{"a", "c", "d"}, api.metrics())
+	require.Equal(t, []string{"a", "c", "d"}, metricsOf(rules.List()))
+}
+
+func TestAggregationRulesApplyTransactionRollsBack(t *testing.T) {
+	updated := model.AggregationRule{Metric: "updated", DropLabels: []string{"pod"}}
+	deleted := model.AggregationRule{Metric: "deleted", Drop: true}
+	api := newMockAPI(t, updated, deleted, model.AggregationRule{Metric: "failing"})
+	rules := api.aggregationRules()
+	rules.continueOnError = true
+
+	// The last change fails, after a rule was created, updated, and deleted.
+	api.failOn("DELETE", "/aggregations/rule/failing", http.StatusInternalServerError)
+
+	err := rules.ApplyTransaction(ruleSetChanges{
+		Create: []model.AggregationRule{{Metric: "created"}},
+		Update: []model.AggregationRule{{Metric: "updated", DropLabels: []string{"namespace"}}},
+		Delete: []model.AggregationRule{{Metric: "deleted"}, {Metric: "failing"}},
+	})
+	require.ErrorContains(t, err, "failed to delete rule for failing")
+	require.ErrorContains(t, err, "have been rolled back")
+
+	require.Equal(t, []string{"deleted", "failing", "updated"}, api.metrics())
+	got, _ := api.rule("updated")
+	require.True(t, got.Equal(updated))
+	got, _ = api.rule("deleted")
+	require.True(t, got.Equal(deleted))
+	require.Equal(t, []string{"deleted", "failing", "updated"}, metricsOf(rules.List()))
+}
+
+func TestAggregationRulesApplyTransactionRollbackFails(t *testing.T) {
+	api := newMockAPI(t)
+	rules := api.aggregationRules()
+
+	api.failOn("POST", "/aggregations/rule/b", http.StatusBadRequest)
+	api.failOn("DELETE", "/aggregations/rule/a", http.StatusInternalServerError)
+
+	err := rules.ApplyTransaction(ruleSetChanges{Create: []model.AggregationRule{{Metric: "a"}, {Metric: "b"}}})
+	require.ErrorContains(t, err, "failed to create rule for b")
+	require.ErrorContains(t, err, "need to be fixed manually")
+	require.ErrorContains(t, err, "rule for a")
+	require.Equal(t, []string{"a"}, api.metrics())
+}
+
+func TestAggregationRulesApplyBulkTooLarge(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "replaced", Drop: true}, model.AggregationRule{Metric: "deleted", Drop: true})
+	rules := api.aggregationRules()
+	api.failOn("POST", "/aggregations/rules", http.StatusRequestEntityTooLarge)
+
+	// The changes are made one rule at a time instead.
+	err := rules.ApplyBulk(ruleSetChanges{
+		Create: []model.AggregationRule{{Metric: "created"}, {Metric: "replaced", DropLabels: []string{"pod"}}},
+		Delete: []model.AggregationRule{{Metric: "deleted"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"created", "replaced"}, api.metrics())
+	got, _ := api.rule("replaced")
+	require.Equal(t, []string{"pod"}, got.DropLabels)
+
+	// A rule which is too large by itself is named, and the other changes
+	// are rolled back.
+	api.failOn("POST", "/aggregations/rule/huge", http.StatusRequestEntityTooLarge)
+	err = rules.ApplyBulk(ruleSetChanges{
+		Create: []model.AggregationRule{{Metric: "small"}, {Metric: "huge"}},
+	})
+	require.ErrorContains(t, err, "failed to create rule for huge")
+	require.ErrorContains(t, err, "have been rolled back")
+	require.Equal(t, []string{"created", "replaced"}, api.metrics())
+}
+
+func TestAggregationRulesApplyDependencies(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "http_", MatchType: "prefix", Drop: true})
+	rules := api.aggregationRules()
+
+	// The exact rules are written after the prefix rule they override, which
+	// is updated, and the rule they depend on in turn.
+	err := rules.Apply(ruleSetChanges{
+		Create: []model.AggregationRule{{Metric: "http_errors_total"}, {Metric: "http_requests_total"}},
+		Update: []model.AggregationRule{{Metric: "http_", MatchType: "prefix", DropLabels: []string{"pod"}}},
+		DependsOn: map[string]string{
+			"http_errors_total":   "http_requests_total",
+			"http_requests_total": "http_",
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"GET /aggregations/rules",
+		"PUT /aggregations/rule/http_",
+		"POST /aggregations/rule/http_requests_total",
+		"POST /aggregations/rule/http_errors_total",
+	}, api.requestLog())
+
+	// As are those of transactions.
+	err = rules.ApplyTransaction(ruleSetChanges{
+		Create:    []model.AggregationRule{{Metric: "a"}},
+		Update:    []model.AggregationRule{{Metric: "http_", MatchType: "prefix"}},
+		DependsOn: map[string]string{"a": "http_"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"PUT /aggregations/rule/http_", "POST /aggregations/rule/a"}, api.requestLog()[4:])
+}
+
+func TestAggregationRulesApplyBulkDependencies(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "b"}, model.AggregationRule{Metric: "d"})
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+
+	// Rules which are not written are ordered too, and the metric prefix
+	// applies to the dependencies.
+	err := rules.ApplyBulk(ruleSetChanges{
+		Create:    []model.AggregationRule{{Metric: "a"}, {Metric: "c"}, {Metric: "e"}},
+		DependsOn: map[string]string{"a": "e", "e": "c"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"b", "d", "team_c", "team_e", "team_a"}}, api.bulkRequests)
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	identity := func(s string) string { return s }
+
+	require.Equal(t, []string{"a", "b", "c"}, orderByDependencies([]string{"a", "b", "c"}, identity, nil))
+	require.Equal(t, []string{"c", "b", "a"}, orderByDependencies([]string{"a", "b", "c"}, identity, map[string]string{"a": "b", "b": "c"}))
+	// Dependencies which are not among the items are ignored.
+	require.Equal(t, []string{"b", "a", "c"}, orderByDependencies([]string{"a", "b", "c"}, identity, map[string]string{"a": "b", "c": "x"}))
+	// Cycles are broken.
+	require.ElementsMatch(t, []string{"a", "b", "c"}, orderByDependencies([]string{"a", "b", "c"}, identity, map[string]string{"a": "b", "b": "a"}))
+}
+
+func TestRuleSetResourceRequiresFeatures(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	plan := model.RuleSetTF{
+		Rules: []model.RuleSetRuleTF{
+			model.AggregationRule{Metric: "a", Aggregations: []string{"p99"}}.ToRuleSetRuleTF(),
+			model.AggregationRule{Metric: "b", Aggregations: []string{"sum", "p50"}}.ToRuleSetRuleTF(),
+		},
+		Prune:           types.BoolValue(false),
+		AllowBroadMatch: types.BoolValue(false),
+	}
+
+	// The feature is reported once for all rules.
+	r := &ruleSetResource{rules: api.aggregationRules(), capabilities: newCapabilities(api.client())}
+	resp := modifyPlan(t, r, nil, plan)
+	require.Len(t, resp.Diagnostics.Errors(), 1)
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support percentile aggregations")
+
+	api.features = append(api.features, string(client.FeaturePercentiles))
+	r.capabilities = newCapabilities(api.client())
+	resp = modifyPlan(t, r, nil, plan)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestRuleSetResourceAllAggregationsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"count", "sum"}})
+	api.supportedAggregations = []string{"sum", "count"}
+	r := &ruleSetResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	tf := model.RuleSetTF{
+		Rules: []model.RuleSetRuleTF{
+			model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{allAggregations}}.ToRuleSetRuleTF(),
+		},
+		Prune:              types.BoolValue(false),
+		AllowBroadMatch:    types.BoolValue(false),
+		ForceOutsideWindow: types.BoolValue(false),
+	}
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, tf).HasError())
+
+	// The rule already computes every supported aggregation, so it is left
+	// as it is.
+	updateResp := &fwresource.UpdateResponse{State: state}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: state}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	for _, request := range api.requestLog() {
+		require.True(t, strings.HasPrefix(request, http.MethodGet+" "), request)
+	}
+}
+
+func TestRuleSetResourceKeepsTags(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}, Tags: map[string]string{"owner": "team-a"}})
+	r := &ruleSetResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	tf := model.RuleSetTF{
+		Rules: []model.RuleSetRuleTF{
+			model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}}.ToRuleSetRuleTF(),
+		},
+		Prune:              types.BoolValue(false),
+		AllowBroadMatch:    types.BoolValue(false),
+		ForceOutsideWindow: types.BoolValue(false),
+	}
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, tf).HasError())
+
+	// Rule sets do not model tags, so a rule which only differs by its tags
+	// is left as it is.
+	plan := tfsdk.Plan{Schema: schemaResp.Schema}
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp := &fwresource.UpdateResponse{State: state}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: state}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	for _, request := range api.requestLog() {
+		require.True(t, strings.HasPrefix(request, http.MethodGet+" "), request)
+	}
+
+	// Updating it for another change keeps its tags.
+	tf.Rules[0] = model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"count"}}.ToRuleSetRuleTF()
+	require.False(t, plan.Set(ctx, tf).HasError())
+	updateResp = &fwresource.UpdateResponse{State: state}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: state}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	got, _ := api.rule("a")
+	require.Equal(t, []string{"count"}, []string(got.Aggregations))
+	require.Equal(t, map[string]string{"owner": "team-a"}, got.Tags)
+}
+
+func metricsOf(rules []model.AggregationRule) []string {
+	metrics := make([]string, len(rules))
+	for i, rule := range rules {
+		metrics[i] = rule.Metric
+	}
+	return metrics
+}
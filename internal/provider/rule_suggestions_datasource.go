@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type ruleSuggestionsDatasource struct {
+	client       *client.Client
+	capabilities *capabilities
+	rules        *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &ruleSuggestionsDatasource{}
+	_ datasource.DataSourceWithConfigure = &ruleSuggestionsDatasource{}
+)
+
+func newRuleSuggestionsDatasource() datasource.DataSource {
+	return &ruleSuggestionsDatasource{}
+}
+
+func (r *ruleSuggestionsDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = data.client
+	r.capabilities = data.capabilities
+	r.rules = data.aggRules
+}
+
+func (r *ruleSuggestionsDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rule_suggestions", req.ProviderTypeName)
+}
+
+func (r *ruleSuggestionsDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Suggests aggregation rules for a given set of metrics, such as the metrics queried by a dashboard, based on the aggregation recommendations. Nothing is applied; the suggested rules can be passed to a rule set or used as a starting point for rule resources.",
+		Attributes: map[string]schema.Attribute{
+			"metrics": schema.ListAttribute{
+				ElementType: types.StringType,
+				Required:    true,
+				Description: "The metrics to suggest rules for.",
+			},
+			"rules": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The rules recommended for the metrics, in the order of the metrics.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the metric to be aggregated.",
+						},
+						"match_type": schema.StringAttribute{
+							Computed:    true,
+							Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
+						},
+
+						"drop": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Set to true to skip both ingestion and aggregation and drop the metric entirely.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"drop_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels that will be aggregated.",
+						},
+
+						"aggregations": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of aggregation types to calculate for this metric.",
+						},
+
+						"aggregation_interval": schema.StringAttribute{
+							Computed:    true,
+							Description: "The interval at which to generate the aggregated series.",
+						},
+						"aggregation_delay": schema.StringAttribute{
+							Computed:    true,
+							Description: "The delay until aggregation is performed.",
+						},
+
+						"priority": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The priority of the rule. When several rules match a metric, the rule with the highest priority is applied.",
+						},
+					},
+				},
+			},
+			"unmatched_metrics": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics for which there is no recommended rule, for example because they are already aggregated optimally.",
+			},
+		},
+	}
+}
+
+func (r *ruleSuggestionsDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	resp.Diagnostics.Append(r.capabilities.require(client.FeatureRecommendations)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.RuleSuggestionsTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rules, unmatched, err := r.suggest(state.GetMetrics())
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to read aggregation recommendations", err.Error())
+		return
+	}
+
+	state.Rules = make([]model.RuleSetRuleTF, len(rules))
+	for i, rule := range rules {
+		state.Rules[i] = rule.ToRuleSetRuleTF()
+	}
+	state.UnmatchedMetrics = make([]types.String, len(unmatched))
+	for i, metric := range unmatched {
+		state.UnmatchedMetrics[i] = types.StringValue(metric)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// suggest fetches the recommendations which result in a rule and returns the
+// rules for metrics, as well as the metrics without a recommended rule.
+func (r *ruleSuggestionsDatasource) suggest(metrics []string) ([]model.AggregationRule, []string, error) {
+	recs, err := r.client.AggregationRecommendations(false, bundleActions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recommended := make([]model.AggregationRule, len(recs))
+	for i, rec := range recs {
+		recommended[i] = rec.AggregationRule
+	}
+
+	rules, unmatched := suggestRules(r.rules.Scope(recommended), metrics)
+	return rules, unmatched, nil
+}
+
+// suggestRules returns the recommended rule for each of metrics, in the order
+// of metrics, and the metrics without a recommended rule. Duplicate metrics
+// are only considered once.
+func suggestRules(recommended []model.AggregationRule, metrics []string) ([]model.AggregationRule, []string) {
+	byMetric := make(map[string]model.AggregationRule, len(recommended))
+	for _, rule := range recommended {
+		byMetric[rule.Metric] = rule
+	}
+
+	rules := []model.AggregationRule{}
+	unmatched := []string{}
+	seen := make(map[string]bool, len(metrics))
+	for _, metric := range metrics {
+		if seen[metric] {
+			continue
+		}
+		seen[metric] = true
+
+		if rule, ok := byMetric[metric]; ok {
+			rules = append(rules, rule)
+		} else {
+			unmatched = append(unmatched, metric)
+		}
+	}
+
+	return rules, unmatched
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestSuggestRules(t *testing.T) {
+	recommended := []model.AggregationRule{
+		{Metric: "a", DropLabels: []string{"pod"}},
+		{Metric: "b", Aggregations: []string{"sum"}},
+		{Metric: "c"},
+	}
+
+	rules, unmatched := suggestRules(recommended, []string{"b", "x", "a", "b"})
+	require.Equal(t, []model.AggregationRule{recommended[1], recommended[0]}, rules)
+	require.Equal(t, []string{"x"}, unmatched)
+
+	rules, unmatched = suggestRules(nil, nil)
+	require.Empty(t, rules)
+	require.NotNil(t, rules)
+	require.Empty(t, unmatched)
+	require.NotNil(t, unmatched)
+}
+
+func TestRuleSuggestionsDatasourceSuggest(t *testing.T) {
+	api := newMockAPI(t)
+	api.recommend("", "",
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "a"}, RecommendedAction: "add"},
+		model.AggregationRecommendation{AggregationRule: model.AggregationRule{Metric: "b"}, RecommendedAction: "remove"},
+	)
+
+	r := &ruleSuggestionsDatasource{client: api.client(), rules: api.aggregationRules()}
+
+	// Metrics recommended for removal are not suggested.
+	rules, unmatched, err := r.suggest([]string{"a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, metricsOf(rules))
+	require.Equal(t, []string{"b"}, unmatched)
+}
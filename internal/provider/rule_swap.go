@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// withConfigOf returns rule aggregating its metric as config does. The rule
+// keeps what identifies it and how it is managed: its ID, name, metric and
+// match, priority, rollout, tags, and owner. How many series it ingests is
+// part of how it aggregates them, so the ingestion settings are swapped too.
+func withConfigOf(rule, config model.AggregationRule) model.AggregationRule {
+	rule.Drop = config.Drop
+	rule.DropIf = config.DropIf
+	rule.KeepLabels = config.KeepLabels
+	rule.DropLabels = config.DropLabels
+	rule.LabelMatchMode = config.LabelMatchMode
+	rule.Aggregations = config.Aggregations
+	rule.AggregationInterval = config.AggregationInterval
+	rule.AggregationIntervals = config.AggregationIntervals
+	rule.AggregationDelay = config.AggregationDelay
+	rule.DelayOverrides = config.DelayOverrides
+	rule.Ingest = config.Ingest
+	rule.IngestSampleRate = config.IngestSampleRate
+	return rule
+}
+
+// SwapConfigs swaps how the rules for metricA and metricB aggregate their
+// metrics, with a single request to the bulk endpoint so that neither rule is
+// ever seen with the configuration of the other. As with ApplyBulk, if the
+// request is too large the rules are updated one at a time, and the first is
+// rolled back if the second fails.
+func (r *AggregationRules) SwapConfigs(metricA, metricB string) error {
+	a, err := r.Read(metricA)
+	if err != nil {
+		return fmt.Errorf("unable to read the rule for %s: %w", metricA, err)
+	}
+	b, err := r.Read(metricB)
+	if err != nil {
+		return fmt.Errorf("unable to read the rule for %s: %w", metricB, err)
+	}
+
+	return r.ApplyBulk(ruleSetChanges{Update: []model.AggregationRule{withConfigOf(a, b), withConfigOf(b, a)}})
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type ruleSwapResource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ resource.Resource                   = &ruleSwapResource{}
+	_ resource.ResourceWithConfigure      = &ruleSwapResource{}
+	_ resource.ResourceWithValidateConfig = &ruleSwapResource{}
+)
+
+func newRuleSwapResource() resource.Resource {
+	return &ruleSwapResource{}
+}
+
+func (r *ruleSwapResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+}
+
+func (r *ruleSwapResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rule_swap", req.ProviderTypeName)
+}
+
+func (r *ruleSwapResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Swaps how the aggregation rules of two metrics aggregate them, such as for A/B experiments of two configurations. Both rules are updated in a single bulk request, so that neither is ever seen with the configuration of the other; if the request is too large, the rules are updated one at a time and the first is rolled back if the second fails. Each rule keeps its ID, name, match, priority, rollout, and tags. The rules are swapped once, when the resource is created or its metrics change; destroying the resource does not swap them back. The rule resources managing the swapped rules must be updated in the configuration as well, or the next apply swaps them back.",
+		Attributes: map[string]schema.Attribute{
+			"metric_a": schema.StringAttribute{
+				Required:    true,
+				Description: "The metric of the first rule.",
+			},
+			"metric_b": schema.StringAttribute{
+				Required:    true,
+				Description: "The metric of the second rule.",
+			},
+		},
+	}
+}
+
+func (r *ruleSwapResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var cfg model.RuleSwapTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &cfg)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !cfg.MetricA.IsUnknown() && !cfg.MetricB.IsUnknown() && cfg.MetricA.Equal(cfg.MetricB) {
+		resp.Diagnostics.AddAttributeError(path.Root("metric_b"), "Invalid metric_b", "metric_b must differ from metric_a.")
+	}
+}
+
+func (r *ruleSwapResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.RuleSwapTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.swap(ctx, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *ruleSwapResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+	// The swap is kept as it was made; the rules themselves are managed by
+	// their own resources.
+}
+
+func (r *ruleSwapResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.RuleSwapTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.swap(ctx, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *ruleSwapResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// The rules are not swapped back.
+}
+
+func (r *ruleSwapResource) swap(ctx context.Context, plan model.RuleSwapTF) diag.Diagnostics {
+	var diags diag.Diagnostics
+	rules := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&diags))
+	if err := rules.SwapConfigs(plan.MetricA.ValueString(), plan.MetricB.ValueString()); err != nil {
+		diags.AddError("Unable to swap aggregation rules", err.Error())
+	}
+	return diags
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestAggregationRulesSwapConfigs(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}, Priority: 10},
+		model.AggregationRule{Metric: "b", KeepLabels: []string{"service"}, AggregationInterval: "1m", IngestSampleRate: ptr(0.5), Tags: map[string]string{"team": "b"}},
+	)
+	rules := api.aggregationRules()
+
+	require.NoError(t, rules.SwapConfigs("a", "b"))
+	// Both rules are updated with a single request.
+	require.Equal(t, []string{"POST /aggregations/rules"}, api.requestLog()[len(api.requestLog())-1:])
+
+	a, _ := api.rule("a")
+	require.Equal(t, int64(10), a.Priority)
+	require.Equal(t, []string{"service"}, a.KeepLabels)
+	require.Empty(t, a.DropLabels)
+	require.Empty(t, a.Aggregations)
+	require.Equal(t, "1m", a.AggregationInterval)
+	require.Equal(t, ptr(0.5), a.IngestSampleRate)
+
+	b, _ := api.rule("b")
+	require.Equal(t, map[string]string{"team": "b"}, b.Tags)
+	require.Equal(t, []string{"pod"}, b.DropLabels)
+	require.Equal(t, model.Aggregations{"sum"}, b.Aggregations)
+	require.Empty(t, b.AggregationInterval)
+	require.Nil(t, b.IngestSampleRate)
+
+	err := rules.SwapConfigs("a", "missing")
+	require.ErrorContains(t, err, "unable to read the rule for missing")
+}
+
+func TestAggregationRulesSwapConfigsRollsBack(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}},
+		model.AggregationRule{Metric: "b", KeepLabels: []string{"service"}},
+	)
+	rules := api.aggregationRules()
+
+	// The rules are updated one at a time, and updating b fails.
+	api.failOn(http.MethodPost, "/aggregations/rules", http.StatusRequestEntityTooLarge)
+	api.failOn(http.MethodPut, "/aggregations/rule/b", http.StatusInternalServerError)
+
+	err := rules.SwapConfigs("a", "b")
+	require.ErrorContains(t, err, "have been rolled back")
+
+	a, _ := api.rule("a")
+	require.Equal(t, []string{"pod"}, a.DropLabels)
+	require.Empty(t, a.KeepLabels)
+	b, _ := api.rule("b")
+	require.Equal(t, []string{"service"}, b.KeepLabels)
+}
@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type ruleValidationDatasource struct {
+	rules *AggregationRules
+
+	// maxConcurrentRequests limits the rules validated at once.
+	maxConcurrentRequests int
+}
+
+var (
+	_ datasource.DataSource              = &ruleValidationDatasource{}
+	_ datasource.DataSourceWithConfigure = &ruleValidationDatasource{}
+)
+
+func newRuleValidationDatasource() datasource.DataSource {
+	return &ruleValidationDatasource{}
+}
+
+func (r *ruleValidationDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+	r.maxConcurrentRequests = data.maxConcurrentRequests
+}
+
+func (r *ruleValidationDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rule_validation", req.ProviderTypeName)
+}
+
+func (r *ruleValidationDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Validates a list of aggregation rules against the API without saving them, so that every rule the API would reject is reported at once, for example in CI before an apply. The rules are validated in parallel, up to the provider's max_concurrent_requests.",
+		Attributes: map[string]schema.Attribute{
+			"rules": schema.ListNestedAttribute{
+				Required:    true,
+				Description: "The aggregation rules to validate.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Required:    true,
+							Description: "The name of the metric to be aggregated.",
+						},
+						"match_type": schema.StringAttribute{
+							Optional:    true,
+							Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact', defaults to 'exact'.",
+						},
+
+						"drop": schema.BoolAttribute{
+							Optional:    true,
+							Description: "Set to true to skip both ingestion and aggregation and drop the metric entirely.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"drop_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "The array of labels that will be aggregated.",
+						},
+
+						"aggregations": schema.ListAttribute{
+							ElementType: types.StringType,
+							Optional:    true,
+							Description: "The array of aggregation types to calculate for this metric. Percentiles are written as p<percent>, such as p99 or p99.9. Set to [\"*\"] to calculate every aggregation type supported by the API.",
+						},
+
+						"aggregation_interval": schema.StringAttribute{
+							Optional:    true,
+							Description: "The interval at which to generate the aggregated series.",
+						},
+						"aggregation_delay": schema.StringAttribute{
+							Optional:    true,
+							Description: "The delay until aggregation is performed.",
+						},
+
+						"priority": schema.Int64Attribute{
+							Optional:    true,
+							Description: "The priority of the rule. When several rules match a metric, the rule with the highest priority is applied.",
+						},
+					},
+				},
+			},
+			"valid": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the API accepts every rule.",
+			},
+			"results": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The result of the validation of each rule, in the order of the rules.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The metric of the rule.",
+						},
+						"valid": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the API accepts the rule.",
+						},
+						"error": schema.StringAttribute{
+							Computed:    true,
+							Description: "Why the API rejects the rule; empty if the rule is valid.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *ruleValidationDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state model.RuleValidationTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rules := state.ToAPIReq()
+	results, err := r.validate(ctx, rules)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to validate aggregation rules", err.Error())
+		return
+	}
+
+	state.Valid = types.BoolValue(true)
+	state.Results = make([]model.RuleValidationResultTF, len(rules))
+	for i, rule := range rules {
+		state.Results[i] = model.RuleValidationResultTF{
+			Metric: types.StringValue(rule.Metric),
+			Valid:  types.BoolValue(results[i] == ""),
+			Error:  types.StringValue(results[i]),
+		}
+		if results[i] != "" {
+			state.Valid = types.BoolValue(false)
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// validate validates rules in parallel, up to maxConcurrentRequests at once,
+// and returns why each rule is rejected, or an empty string if it is accepted.
+// An error is returned if any rule could not be validated at all, including
+// because ctx was cancelled.
+func (r *ruleValidationDatasource) validate(ctx context.Context, rules []model.AggregationRule) ([]string, error) {
+	results := make([]string, len(rules))
+	errs := parallel(ctx, r.maxConcurrentRequests, len(rules), func(_ context.Context, i int) error {
+		err := r.rules.Validate(rules[i])
+		var rejected errRuleRejected
+		switch {
+		case errors.As(err, &rejected):
+			results[i] = rejected.Error()
+		case err != nil:
+			return fmt.Errorf("failed to validate rule for %s: %w", rules[i].Metric, err)
+		}
+		return nil
+	})
+
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestRuleValidationDatasourceValidate(t *testing.T) {
+	api := newMockAPI(t)
+	api.reject("b", "unknown aggregation type: avg")
+	api.reject("d", "invalid label: 1pod")
+
+	r := &ruleValidationDatasource{rules: api.aggregationRules()}
+
+	results, err := r.validate(context.Background(), []model.AggregationRule{
+		{Metric: "a"},
+		{Metric: "b", Aggregations: []string{"avg"}},
+		{Metric: "c"},
+		{Metric: "d", DropLabels: []string{"1pod"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "unknown aggregation type: avg", "", "invalid label: 1pod"}, results)
+
+	// Rules are only validated, never saved.
+	require.Empty(t, api.metrics())
+}
+
+func TestRuleValidationDatasourceValidateMetricPrefix(t *testing.T) {
+	api := newMockAPI(t)
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+
+	r := &ruleValidationDatasource{rules: rules}
+
+	results, err := r.validate(context.Background(), []model.AggregationRule{{Metric: "a"}, {Metric: "_total", MatchType: "suffix"}})
+	require.NoError(t, err)
+	require.Equal(t, "", results[0])
+	require.Contains(t, results[1], "cannot be used with metric_prefix")
+}
+
+func TestRuleValidationDatasourceValidateFailure(t *testing.T) {
+	api := newMockAPI(t)
+	r := &ruleValidationDatasource{rules: api.aggregationRules()}
+	api.failOn(http.MethodPost, "/aggregations/rules/validate", http.StatusInternalServerError)
+
+	// Unlike a rejected rule, a failed request does not say anything about
+	// the rule and fails the data source.
+	_, err := r.validate(context.Background(), []model.AggregationRule{{Metric: "a"}, {Metric: "b"}})
+	require.ErrorContains(t, err, "failed to validate rule for a")
+	require.ErrorContains(t, err, "failed to validate rule for b")
+}
+
+func TestRuleValidationDatasourceValidateConcurrency(t *testing.T) {
+	api := newMockAPI(t)
+	api.setLatency(20 * time.Millisecond)
+	r := &ruleValidationDatasource{rules: api.aggregationRules(), maxConcurrentRequests: 4}
+
+	rules := make([]model.AggregationRule, 40)
+	for i := range rules {
+		rules[i] = model.AggregationRule{Metric: fmt.Sprintf("metric_%02d", i)}
+	}
+	api.reject("metric_07", "invalid label: 1pod")
+
+	start := time.Now()
+	results, err := r.validate(context.Background(), rules)
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+
+	// The results are in the order of the rules, whichever completes first.
+	require.Len(t, results, len(rules))
+	for i, result := range results {
+		if i == 7 {
+			require.Equal(t, "invalid label: 1pod", result)
+		} else {
+			require.Empty(t, result, rules[i].Metric)
+		}
+	}
+
+	// At most 4 rules are validated at once, so the 40 requests take at least
+	// 10 times the latency. That they run at once is covered by TestParallel,
+	// without depending on timing.
+	require.GreaterOrEqual(t, elapsed, 10*api.latency)
+}
+
+func TestRuleValidationDatasourceValidateCancelled(t *testing.T) {
+	api := newMockAPI(t)
+	r := &ruleValidationDatasource{rules: api.aggregationRules(), maxConcurrentRequests: 4}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.validate(ctx, []model.AggregationRule{{Metric: "a"}, {Metric: "b"}})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, countRequests(api, "POST /aggregations/rules/validate"))
+}
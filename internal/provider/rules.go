@@ -1,91 +1,1061 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
 )
 
+// AggregationRules caches the aggregation rules of the tenant. The cache is
+// loaded with a single request when the provider is configured, so that the
+// rule resources are read without a request each. When a metric prefix is
+// configured, it is prepended to the metric of every rule sent to the API and
+// stripped from every rule returned, and rules outside of the prefix are
+// ignored.
 type AggregationRules struct {
-	client *client.Client
-	mu     sync.RWMutex
+	client       *client.Client
+	metricPrefix string
+
+	// onWarning is called with every warning the API returns about a
+	// saved rule.
+	onWarning func(metric, warning string)
+
+	// continueOnError makes Apply attempt every change rather than stop at
+	// the first one that fails.
+	continueOnError bool
+
+	// window blocks every change outside of the provider's apply_window.
+	window *applyWindow
+	// lock is acquired before the first change, if the provider sets a
+	// lock_id.
+	lock *applyLock
+
+	// audit records every change made to the rules, if the provider sets an
+	// audit_log_file. auditCell is the URL of the cell whose rules these
+	// are, and empty for the provider's stack.
+	audit     *auditLog
+	auditCell string
+
+	// ctx is the context given to WithContext, to which the progress of
+	// Apply is logged.
+	ctx context.Context
+	// progressInterval is how often Apply logs its progress; zero disables
+	// it.
+	progressInterval time.Duration
+
+	// capabilities tell whether rules can be updated by a partial update and
+	// which of their fields can be selected, and provide the server info.
+	// Rules are replaced by a full update and requested with every field if
+	// it is nil.
+	capabilities *capabilities
+
+	*ruleCache
+}
+
+// ruleCache is the state of AggregationRules, shared by all of its contexts.
+type ruleCache struct {
+	mu sync.RWMutex
 
 	etag  string
 	rules map[string]model.AggregationRule
+
+	// supportedAggregations and deprecatedAggregations are requested from the
+	// API when first needed.
+	infoMu                 sync.Mutex
+	supportedAggregations  []string
+	deprecatedAggregations map[string]model.AggregationDeprecation
 }
 
-func NewAggregationRules(c *client.Client) *AggregationRules {
-	return &AggregationRules{client: c, mu: sync.RWMutex{}, rules: make(map[string]model.AggregationRule)}
+func NewAggregationRules(c *client.Client, metricPrefix string) *AggregationRules {
+	return &AggregationRules{client: c, metricPrefix: metricPrefix, ruleCache: &ruleCache{rules: make(map[string]model.AggregationRule)}}
 }
 
-func (r *AggregationRules) Init() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// WithContext returns the rules with the same cache, whose requests are made
+// with ctx so that they are aborted once it is done.
+func (r *AggregationRules) WithContext(ctx context.Context) *AggregationRules {
+	rules := *r
+	rules.client = r.client.WithContext(ctx)
+	rules.ctx = ctx
+	return &rules
+}
 
-	rules, etag, err := r.client.AggregationRules()
-	if err != nil {
+// WithWarnings returns the rules with the same cache, which call fn with the
+// metric and text of every warning the API returns when a rule is created or
+// updated.
+func (r *AggregationRules) WithWarnings(fn func(metric, warning string)) *AggregationRules {
+	rules := *r
+	rules.onWarning = fn
+	return &rules
+}
+
+// WithoutApplyWindow returns the rules with the same cache, which are changed
+// even outside of the apply window.
+func (r *AggregationRules) WithoutApplyWindow() *AggregationRules {
+	rules := *r
+	rules.window = nil
+	return &rules
+}
+
+// beforeChange returns an error if the rules must not be changed now: outside
+// of the apply window, or while another run holds the apply lock.
+func (r *AggregationRules) beforeChange() error {
+	if err := r.window.check(); err != nil {
 		return err
 	}
 
-	for _, rule := range rules {
-		r.rules[rule.Metric] = rule
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	r.etag = etag
-	return nil
+	return r.lock.acquire(ctx)
+}
+
+func (r *AggregationRules) Init() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.load()
+}
+
+// errRuleNotFound is returned when no rule exists for a metric.
+type errRuleNotFound struct {
+	metric string
+}
+
+func (e errRuleNotFound) Error() string {
+	return fmt.Sprintf("no rule for %s found", e.metric)
+}
+
+// errRuleExists is returned when a rule is created for a metric which already
+// has one.
+type errRuleExists struct {
+	metric string
+	err    error
+}
+
+func (e errRuleExists) Error() string {
+	return fmt.Sprintf("a rule for %s already exists: %v", e.metric, e.err)
+}
+
+func (e errRuleExists) Unwrap() error {
+	return e.err
+}
+
+// errRuleRejected is returned when the API rejects a rule as invalid.
+type errRuleRejected struct {
+	reason string
+}
+
+func (e errRuleRejected) Error() string {
+	return e.reason
 }
 
 func (r *AggregationRules) Create(rule model.AggregationRule) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	etag, err := r.client.CreateAggregationRule(rule, r.etag)
+	qualified, err := r.qualify(rule)
 	if err != nil {
 		return err
 	}
+	return r.create(qualified)
+}
+
+// Read returns the rule for metric from the cache. Rules missing from the
+// cache, such as rules created since it was loaded, are requested from the
+// API; an errRuleNotFound is returned if there is none.
+func (r *AggregationRules) Read(metric string) (model.AggregationRule, error) {
+	r.mu.RLock()
+	rule, ok := r.rules[r.metricPrefix+metric]
+	r.mu.RUnlock()
+	if ok {
+		return r.unqualify(rule), nil
+	}
+
+	rule, _, err := r.client.ReadAggregationRuleFields(r.metricPrefix+metric, r.ruleFields())
+	var notFound client.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		return model.AggregationRule{}, errRuleNotFound{metric: metric}
+	case err != nil:
+		return model.AggregationRule{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	r.etag = etag
 	r.rules[rule.Metric] = rule
-	return nil
+	return r.unqualify(rule), nil
 }
 
-func (r *AggregationRules) Read(metric string) (model.AggregationRule, error) {
+// ReadByID returns the rule with the given ID. Unlike the metric, the ID of a
+// rule does not change when the rule is renamed.
+func (r *AggregationRules) ReadByID(id string) (model.AggregationRule, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	rule, ok := r.rules[metric]
-	if !ok {
-		return model.AggregationRule{}, fmt.Errorf("no rule for %s found", metric)
+	for metric, rule := range r.rules {
+		if rule.ID == id && strings.HasPrefix(metric, r.metricPrefix) {
+			return r.unqualify(rule), nil
+		}
 	}
 
-	return rule, nil
+	return model.AggregationRule{}, fmt.Errorf("no rule with ID %s found", id)
+}
+
+// ReadByName returns the rule with the given name. Like the ID, the name of a
+// rule does not change when the rule is renamed, but it is only stored by
+// backends which support rule names.
+func (r *AggregationRules) ReadByName(name string) (model.AggregationRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for metric, rule := range r.rules {
+		if name != "" && rule.Name == name && strings.HasPrefix(metric, r.metricPrefix) {
+			return r.unqualify(rule), nil
+		}
+	}
+
+	return model.AggregationRule{}, fmt.Errorf("no rule named %s found", name)
+}
+
+// Exists asks the API whether a rule exists for metric. Unlike Read, it
+// distinguishes a rule that definitely does not exist from a failed request.
+func (r *AggregationRules) Exists(metric string) (bool, error) {
+	metric = r.metricPrefix + metric
+	rule, _, err := r.client.ReadAggregationRuleFields(metric, r.ruleFields())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var notFound client.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+		delete(r.rules, metric)
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	r.rules[metric] = rule
+	return true, nil
+}
+
+// List returns all aggregation rules within the metric prefix, sorted by
+// metric.
+func (r *AggregationRules) List() []model.AggregationRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]model.AggregationRule, 0, len(r.rules))
+	for metric, rule := range r.rules {
+		if strings.HasPrefix(metric, r.metricPrefix) {
+			rules = append(rules, r.unqualify(rule))
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Metric < rules[j].Metric })
+
+	return rules
+}
+
+// ListFiltered requests the rules from the API, bypassing the cache, and calls
+// fn with the rules matching filter of every page, or of all rules at once if
+// the backend does not list them by page. Backends which filter the rules they
+// list are sent filter; the rules of other backends are filtered here. The
+// metric prefix of filter is relative to the provider's metric prefix.
+func (r *AggregationRules) ListFiltered(filter client.AggregationRuleFilter, fn func(rules []model.AggregationRule) error) error {
+	filter.MetricPrefix = r.metricPrefix + filter.MetricPrefix
+	return r.client.ListAggregationRules(filter, r.supportsRuleFilters(), r.supportsPagination(), func(page []model.AggregationRule) error {
+		return fn(r.Scope(page))
+	})
+}
+
+// SupportedAggregations returns the aggregation types supported by the API. They
+// are requested once and then cached; a failed request is retried on the next
+// call.
+func (r *AggregationRules) SupportedAggregations() ([]string, error) {
+	r.infoMu.Lock()
+	defer r.infoMu.Unlock()
+
+	if r.supportedAggregations == nil {
+		info, err := r.serverInfo()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the supported aggregations: %w", err)
+		}
+		if len(info.SupportedAggregations) == 0 {
+			return nil, errors.New("unable to get the supported aggregations: the API returned none")
+		}
+		r.supportedAggregations = info.SupportedAggregations
+	}
+	return r.supportedAggregations, nil
+}
+
+// DeprecatedAggregations returns the aggregation types which the API reports as
+// deprecated, keyed by type. Backends without server info deprecate none. They
+// are requested once and then cached; a failed request is retried on the next
+// call.
+func (r *AggregationRules) DeprecatedAggregations() (map[string]model.AggregationDeprecation, error) {
+	r.infoMu.Lock()
+	defer r.infoMu.Unlock()
+
+	if r.deprecatedAggregations == nil {
+		info, err := r.serverInfo()
+		var notFound client.ErrNotFound
+		if err != nil && !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("unable to get the deprecated aggregations: %w", err)
+		}
+		deprecated := make(map[string]model.AggregationDeprecation, len(info.DeprecatedAggregations))
+		for _, d := range info.DeprecatedAggregations {
+			deprecated[d.Type] = d
+		}
+		r.deprecatedAggregations = deprecated
+	}
+	return r.deprecatedAggregations, nil
+}
+
+// serverInfo returns the server info of the API. It must be called with infoMu
+// held.
+func (r *AggregationRules) serverInfo() (model.ServerInfo, error) {
+	if r.capabilities != nil {
+		// Shared with the provider's capability checks.
+		return r.capabilities.serverInfo()
+	}
+	return r.client.ServerInfo()
+}
+
+// Estimate estimates the effect of rule on the number of series without
+// saving it.
+func (r *AggregationRules) Estimate(rule model.AggregationRule) (model.AggregationRuleEstimate, error) {
+	qualified, err := r.qualify(rule)
+	if err != nil {
+		return model.AggregationRuleEstimate{}, err
+	}
+	return r.client.EstimateAggregationRule(qualified)
+}
+
+// MetricMetadata returns the metadata of metric, such as its type.
+func (r *AggregationRules) MetricMetadata(metric string) (model.MetricMetadata, error) {
+	return r.client.MetricMetadata(r.metricPrefix + metric)
+}
+
+// Recommended reports whether the recommender currently suggests a rule for
+// metric, that is whether it is recommended with one of bundleActions.
+func (r *AggregationRules) Recommended(metric string) (bool, error) {
+	recs, err := r.client.AggregationRecommendations(false, bundleActions)
+	if err != nil {
+		return false, err
+	}
+
+	for _, rec := range recs {
+		if rec.Metric == r.metricPrefix+metric {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Validate asks the API whether it would accept rule, without saving it. An
+// errRuleRejected is returned if the rule is invalid; other errors mean that
+// the rule could not be validated.
+func (r *AggregationRules) Validate(rule model.AggregationRule) error {
+	qualified, err := r.qualify(rule)
+	if err != nil {
+		return errRuleRejected{reason: err.Error()}
+	}
+
+	err = r.client.ValidateAggregationRule(qualified)
+	var status client.ErrStatus
+	if errors.As(err, &status) && (status.StatusCode == http.StatusBadRequest || status.StatusCode == http.StatusUnprocessableEntity) {
+		reason := strings.TrimSpace(string(status.BodyContents))
+		if reason == "" {
+			reason = status.Error()
+		}
+		return errRuleRejected{reason: reason}
+	}
+	return err
+}
+
+// Scope returns the rules within the metric prefix with the prefix stripped.
+// It is used for rules which are not read through AggregationRules, such as
+// recommended rules.
+func (r *AggregationRules) Scope(rules []model.AggregationRule) []model.AggregationRule {
+	scoped := make([]model.AggregationRule, 0, len(rules))
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.Metric, r.metricPrefix) {
+			scoped = append(scoped, r.unqualify(rule))
+		}
+	}
+	return scoped
 }
 
 func (r *AggregationRules) Update(rule model.AggregationRule) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	etag, err := r.client.UpdateAggregationRule(rule, r.etag)
+	qualified, err := r.qualify(rule)
 	if err != nil {
 		return err
 	}
+	return r.update(qualified)
+}
+
+func (r *AggregationRules) Delete(rule model.AggregationRule) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.delete(r.metricPrefix + rule.Metric)
+}
+
+// Rename replaces the rule for from.Metric with to. The new rule is created
+// before the old one is deleted so that the metric is never left without an
+// aggregation rule. If the old rule cannot be deleted, the new rule is deleted
+// again so that the rule set is left as it was.
+func (r *AggregationRules) Rename(from, to model.AggregationRule) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	qualified, err := r.qualify(to)
+	if err != nil {
+		return err
+	}
+	if err := r.create(qualified); err != nil {
+		return err
+	}
+
+	if err := r.delete(r.metricPrefix + from.Metric); err != nil {
+		if rollbackErr := r.delete(qualified.Metric); rollbackErr != nil {
+			return fmt.Errorf("failed to delete rule for %s: %w (rolling back rule for %s also failed: %v)", from.Metric, err, to.Metric, rollbackErr)
+		}
+		return fmt.Errorf("failed to delete rule for %s, rule for %s has been rolled back: %w", from.Metric, to.Metric, err)
+	}
 
-	r.etag = etag
-	r.rules[rule.Metric] = rule
 	return nil
 }
 
-func (r *AggregationRules) Delete(rule model.AggregationRule) error {
+// Apply creates, updates, and then deletes rules as described by changes,
+// writing every rule after the rule it depends on. It stops at the first
+// request that fails unless continueOnError is set, in
+// which case every change is attempted and all failures are returned
+// together. The progress is logged every progressInterval.
+func (r *AggregationRules) Apply(changes ruleSetChanges) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	progress := r.newProgress(len(changes.Create) + len(changes.Update) + len(changes.Delete))
+	var errs []error
+	for _, w := range changes.writes() {
+		qualified, err := r.qualify(w.rule)
+		if err == nil {
+			if w.verb == "create" {
+				err = r.create(qualified)
+			} else {
+				err = r.update(qualified)
+			}
+		}
+		progress.step()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to %s rule for %s: %w", w.verb, w.rule.Metric, err))
+			if !r.continueOnError {
+				return errs[0]
+			}
+		}
+	}
+	for _, rule := range changes.Delete {
+		err := r.delete(r.metricPrefix + rule.Metric)
+		progress.step()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete rule for %s: %w", rule.Metric, err))
+			if !r.continueOnError {
+				return errs[0]
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ApplyTransaction makes the changes like Apply, but all or nothing: once a
+// change fails, the changes already made are undone in reverse order, so that
+// created rules are deleted and updated or deleted rules are restored as they
+// were cached before. The error tells whether the rollback succeeded and names
+// the rules which could not be restored.
+func (r *AggregationRules) ApplyTransaction(changes ruleSetChanges) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.applyTransaction(changes)
+}
+
+// applyTransaction implements ApplyTransaction; r.mu must be held.
+func (r *AggregationRules) applyTransaction(changes ruleSetChanges) error {
+	ops := changes.writes()
+	for _, rule := range changes.Delete {
+		ops = append(ops, ruleWrite{verb: "delete", rule: rule})
+	}
+
+	// applied are the qualified metrics changed so far, with the rule each
+	// of them had before; prior is nil for created rules.
+	type change struct {
+		metric string
+		prior  *model.AggregationRule
+	}
+	var applied []change
+
+	progress := r.newProgress(len(ops))
+	var err error
+	for _, o := range ops {
+		metric := r.metricPrefix + o.rule.Metric
+		var prior *model.AggregationRule
+		if rule, ok := r.rules[metric]; ok {
+			prior = &rule
+		}
+
+		var qualified model.AggregationRule
+		if o.verb != "delete" {
+			qualified, err = r.qualify(o.rule)
+		}
+		switch {
+		case err != nil:
+		case o.verb == "create":
+			err = r.create(qualified)
+		case o.verb == "update":
+			err = r.update(qualified)
+		default:
+			err = r.delete(metric)
+		}
+		progress.step()
+		if err != nil {
+			err = fmt.Errorf("failed to %s rule for %s: %w", o.verb, o.rule.Metric, err)
+			break
+		}
+		applied = append(applied, change{metric: metric, prior: prior})
+	}
+	if err == nil {
+		return nil
+	}
+
+	var rollbackErrs []error
+	for i := len(applied) - 1; i >= 0; i-- {
+		c := applied[i]
+		_, exists := r.rules[c.metric]
+
+		var rbErr error
+		switch {
+		case c.prior == nil:
+			rbErr = r.delete(c.metric)
+		case !exists:
+			prior := *c.prior
+			prior.ID = ""
+			rbErr = r.create(prior)
+		default:
+			rbErr = r.update(*c.prior)
+		}
+		if rbErr != nil {
+			rollbackErrs = append(rollbackErrs, fmt.Errorf("rule for %s: %w", strings.TrimPrefix(c.metric, r.metricPrefix), rbErr))
+		}
+	}
+
+	if len(rollbackErrs) > 0 {
+		return fmt.Errorf("%w; rolling back the changes made before the failure also failed, so these rules need to be fixed manually: %v", err, errors.Join(rollbackErrs...))
+	}
+	return fmt.Errorf("%w; the changes made before the failure have been rolled back", err)
+}
+
+// withoutReplacements turns the rules created by changes for a metric which
+// already has a rule into updates of that rule.
+func (r *AggregationRules) withoutReplacements(changes ruleSetChanges) ruleSetChanges {
+	var create []model.AggregationRule
+	update := slices.Clone(changes.Update)
+	for _, rule := range changes.Create {
+		if _, exists := r.rules[r.metricPrefix+rule.Metric]; exists {
+			update = append(update, rule)
+		} else {
+			create = append(create, rule)
+		}
+	}
+	return ruleSetChanges{Create: create, Update: update, Delete: changes.Delete, DependsOn: changes.DependsOn}
+}
+
+// newProgress returns the progress of a reconciliation of total changes.
+func (r *AggregationRules) newProgress(total int) *progress {
+	return newProgress(r.context(), total, r.progressInterval)
+}
+
+// context returns the context given to WithContext, if any.
+func (r *AggregationRules) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// ApplyBulk makes the changes with a single request to the bulk endpoint,
+// which replaces every rule of the tenant with the cached rules and the
+// changes applied. Unlike Apply, either all of the changes are made or none
+// of them. Rules created for a metric which already has one replace it. The
+// rules are sent ordered by metric, except that every rule follows the rule
+// it depends on.
+//
+// If the request is too large for the backend, the changes are made one rule
+// at a time with ApplyTransaction instead, so that they are still all or
+// nothing and a rule which is too large by itself is named in the error. The
+// bulk request cannot be split into smaller batches, as each of them would
+// replace every rule of the tenant.
+func (r *AggregationRules) ApplyBulk(changes ruleSetChanges) error {
+	if err := r.beforeChange(); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	etag, err := r.client.DeleteAggregationRule(rule.Metric, r.etag)
+	desired := maps.Clone(r.rules)
+	for _, rule := range append(slices.Clone(changes.Create), changes.Update...) {
+		qualified, err := r.qualify(rule)
+		if err != nil {
+			return err
+		}
+		if qualified.ID == "" {
+			qualified.ID = r.rules[qualified.Metric].ID
+		}
+		desired[qualified.Metric] = qualified
+	}
+	for _, rule := range changes.Delete {
+		delete(desired, r.metricPrefix+rule.Metric)
+	}
+
+	metrics := make([]string, 0, len(desired))
+	for metric := range desired {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	rules := make([]model.AggregationRule, len(metrics))
+	for i, metric := range metrics {
+		rules[i] = desired[metric]
+	}
+	dependsOn := make(map[string]string, len(changes.DependsOn))
+	for metric, dependency := range changes.DependsOn {
+		dependsOn[r.metricPrefix+metric] = r.metricPrefix + dependency
+	}
+	rules = orderByDependencies(rules, func(rule model.AggregationRule) string { return rule.Metric }, dependsOn)
+
+	etag, err := r.client.UpdateAggregationRules(rules, r.etag)
+	var status client.ErrStatus
+	if errors.As(err, &status) && status.StatusCode == http.StatusRequestEntityTooLarge {
+		tflog.Warn(r.context(), fmt.Sprintf("The bulk request for %d aggregation rules is too large; making the changes one rule at a time", len(rules)))
+		return r.applyTransaction(r.withoutReplacements(changes))
+	}
+	if err != nil {
+		if rejected := r.bulkRejections(err, rules); rejected != nil {
+			return errBulkRejected{err: err, rejected: rejected}
+		}
+		return r.checkConflict(err)
+	}
+
+	r.auditBulk(desired)
+
+	r.etag = etag
+	r.rules = desired
+	return nil
+}
+
+// auditBulk records the changes of a bulk request which replaced the cached
+// rules with desired, ordered by metric.
+func (r *AggregationRules) auditBulk(desired map[string]model.AggregationRule) {
+	if r.audit == nil {
+		return
+	}
+
+	var metrics []string
+	for metric := range r.rules {
+		metrics = append(metrics, metric)
+	}
+	for metric := range desired {
+		if _, ok := r.rules[metric]; !ok {
+			metrics = append(metrics, metric)
+		}
+	}
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		before, existed := r.rules[metric]
+		after, exists := desired[metric]
+		switch {
+		case !existed:
+			r.audit.record("create", metric, r.auditCell, nil, &after)
+		case !exists:
+			r.audit.record("delete", metric, r.auditCell, &before, nil)
+		case !after.Equal(before):
+			r.audit.record("update", metric, r.auditCell, &before, &after)
+		}
+	}
+}
+
+// errBulkRejected is returned when the API rejects some of the rules of a
+// bulk request. rejected holds the reasons by the metric of each rule.
+type errBulkRejected struct {
+	err      error
+	rejected map[string][]string
+}
+
+func (e errBulkRejected) Error() string {
+	return e.err.Error()
+}
+
+func (e errBulkRejected) Unwrap() error {
+	return e.err
+}
+
+// bulkFieldRE matches the fields named by the bulk endpoint when it rejects
+// some of the rules, which start with the index of the rule in the request,
+// such as "[3]" or "[3].keep_labels".
+var bulkFieldRE = regexp.MustCompile(`^\[(\d+)\](?:\.(.+))?$`)
+
+// bulkRejections returns the reasons why the API rejected the rules of a bulk
+// request, by the unqualified metric of each rule. It returns nil if err does
+// not name any of the rules.
+func (r *AggregationRules) bulkRejections(err error, rules []model.AggregationRule) map[string][]string {
+	var status client.ErrStatus
+	if !errors.As(err, &status) {
+		return nil
+	}
+
+	var rejected map[string][]string
+	for _, f := range status.FieldErrors() {
+		m := bulkFieldRE.FindStringSubmatch(f.Field)
+		if m == nil {
+			continue
+		}
+		i, _ := strconv.Atoi(m[1])
+		if i >= len(rules) {
+			continue
+		}
+
+		reason := f.Message
+		if m[2] != "" {
+			reason = fmt.Sprintf("%s: %s", m[2], f.Message)
+		}
+		if rejected == nil {
+			rejected = make(map[string][]string)
+		}
+		metric := r.unqualify(rules[i]).Metric
+		rejected[metric] = append(rejected[metric], reason)
+	}
+	return rejected
+}
+
+// aggregatedMetricNames returns the names of the series which rule writes,
+// including the metric prefix, with the allAggregations sentinel expanded.
+// They are only known for rules of a single metric, on backends which name
+// aggregated series by their metric and aggregation type, and are nil
+// otherwise.
+func (r *AggregationRules) aggregatedMetricNames(rule model.AggregationRule) ([]string, error) {
+	if rule.MatchType != "" && rule.MatchType != "exact" || len(rule.MatchPrefixes) > 0 || !r.supportsAggregatedNames() {
+		return nil, nil
+	}
+
+	qualified, err := r.qualify(rule)
 	if err != nil {
+		return nil, err
+	}
+	return qualified.AggregatedMetricNames(), nil
+}
+
+// qualify prepends the metric prefix to the metric of rule and expands the
+// allAggregations sentinel to the supported aggregations. Suffix rules match
+// the end of a metric name, so they cannot be scoped by a prefix. The name of
+// rule is cleared for backends which do not support rule names.
+func (r *AggregationRules) qualify(rule model.AggregationRule) (model.AggregationRule, error) {
+	if rule.Name != "" && !r.supportsRuleNames() {
+		// The name is then only kept in the Terraform state.
+		rule.Name = ""
+	}
+	if hasAllAggregations(rule.Aggregations) {
+		supported, err := r.SupportedAggregations()
+		if err != nil {
+			return model.AggregationRule{}, err
+		}
+		rule.Aggregations = slices.Clone(supported)
+	}
+
+	if r.metricPrefix == "" {
+		return rule, nil
+	}
+	if rule.MatchType == "suffix" {
+		return model.AggregationRule{}, fmt.Errorf("suffix rule for %s cannot be used with metric_prefix %q", rule.Metric, r.metricPrefix)
+	}
+
+	rule.Metric = r.metricPrefix + rule.Metric
+	if rule.MatchPrefixes != nil {
+		prefixes := make([]string, len(rule.MatchPrefixes))
+		for i, prefix := range rule.MatchPrefixes {
+			prefixes[i] = r.metricPrefix + prefix
+		}
+		rule.MatchPrefixes = prefixes
+	}
+	return rule, nil
+}
+
+// unqualify strips the metric prefix from the metric and match prefixes of
+// rule.
+func (r *AggregationRules) unqualify(rule model.AggregationRule) model.AggregationRule {
+	rule.Metric = strings.TrimPrefix(rule.Metric, r.metricPrefix)
+	if rule.MatchPrefixes != nil {
+		prefixes := make([]string, len(rule.MatchPrefixes))
+		for i, prefix := range rule.MatchPrefixes {
+			prefixes[i] = strings.TrimPrefix(prefix, r.metricPrefix)
+		}
+		rule.MatchPrefixes = prefixes
+	}
+	return rule
+}
+
+// load replaces the cache with the rules returned by the API.
+func (r *AggregationRules) load() error {
+	rules, etag, err := r.client.AggregationRulesFields(r.ruleFields())
+	if err != nil {
+		return err
+	}
+
+	r.rules = make(map[string]model.AggregationRule, len(rules))
+	for _, rule := range rules {
+		r.rules[rule.Metric] = rule
+	}
+	r.etag = etag
+	return nil
+}
+
+// checkConflict reloads the cache if err reports that the rules were changed
+// since the cache was loaded, so that the next attempt uses the current etag.
+func (r *AggregationRules) checkConflict(err error) error {
+	var status client.ErrStatus
+	if !errors.As(err, &status) || status.StatusCode != http.StatusPreconditionFailed {
 		return err
 	}
 
+	if loadErr := r.load(); loadErr != nil {
+		return fmt.Errorf("aggregation rules were changed concurrently and could not be reloaded: %w (reload: %v)", err, loadErr)
+	}
+	return fmt.Errorf("aggregation rules were changed concurrently; the cached rules have been reloaded, please retry: %w", err)
+}
+
+func (r *AggregationRules) create(rule model.AggregationRule) error {
+	created, warnings, etag, err := r.client.CreateAggregationRule(rule, r.etag)
+	var status client.ErrStatus
+	if errors.As(err, &status) && status.StatusCode == http.StatusConflict {
+		return errRuleExists{metric: r.unqualify(rule).Metric, err: err}
+	}
+	if err != nil {
+		return r.checkConflict(err)
+	}
+	r.warn(rule, warnings)
+	r.audit.record("create", rule.Metric, r.auditCell, nil, &created)
+
+	r.etag = etag
+	r.rules[rule.Metric] = created
+	return nil
+}
+
+// update replaces a rule by its ID, taken from the cached rule for its metric
+// if rule has none. When the ID belongs to a rule for another metric, the
+// rule is renamed in place.
+//
+// If the backend supports partial updates, only the fields which differ from
+// the cached rule are sent, so that the fields set by others are kept.
+func (r *AggregationRules) update(rule model.AggregationRule) error {
+	if rule.ID == "" {
+		rule.ID = r.rules[rule.Metric].ID
+	}
+
+	var warnings []string
+	var etag string
+	var err error
+	prior, cached := r.cachedByID(rule)
+	before := r.auditBefore(rule, prior, cached)
+	if cached && r.supportsPartialUpdates() {
+		var patch map[string]any
+		if patch, err = rule.MergePatch(prior); err != nil {
+			return err
+		}
+		warnings, etag, err = r.client.PatchAggregationRule(rule.ID, prior.Metric, patch, r.etag)
+	} else {
+		warnings, etag, err = r.client.UpdateAggregationRule(rule, r.etag)
+	}
+	if err != nil {
+		return r.checkConflict(err)
+	}
+	r.warn(rule, warnings)
+	r.audit.record("update", rule.Metric, r.auditCell, before, &rule)
+
+	r.etag = etag
+	for metric, cached := range r.rules {
+		if rule.ID != "" && cached.ID == rule.ID {
+			delete(r.rules, metric)
+		}
+	}
+	r.rules[rule.Metric] = rule
+	return nil
+}
+
+// cachedByID returns the cached rule which rule updates: the one with its ID
+// or, if it has none, the one for its metric.
+func (r *AggregationRules) cachedByID(rule model.AggregationRule) (model.AggregationRule, bool) {
+	if rule.ID != "" {
+		for _, cached := range r.rules {
+			if cached.ID == rule.ID {
+				return cached, true
+			}
+		}
+	}
+	cached, ok := r.rules[rule.Metric]
+	return cached, ok
+}
+
+// auditBefore returns the rule which update is about to replace, for the
+// audit log: prior if it is cached, and otherwise the rule as read from the
+// API. It is nil without an audit log, or if the rule cannot be read.
+func (r *AggregationRules) auditBefore(rule, prior model.AggregationRule, cached bool) *model.AggregationRule {
+	if r.audit == nil {
+		return nil
+	}
+	if !cached {
+		var err error
+		if prior, _, err = r.client.ReadAggregationRuleFields(rule.Metric, r.ruleFields()); err != nil {
+			return nil
+		}
+	}
+	return &prior
+}
+
+// supportsPartialUpdates reports whether rules can be updated by a merge
+// patch. It reports false if the backend cannot be asked, for rules to be
+// replaced as before.
+func (r *AggregationRules) supportsPartialUpdates() bool {
+	if r.capabilities == nil {
+		return false
+	}
+	supported, err := r.capabilities.supports(client.FeaturePartialUpdates)
+	return err == nil && supported
+}
+
+func (r *AggregationRules) supportsPagination() bool {
+	if r.capabilities == nil {
+		return false
+	}
+	supported, err := r.capabilities.supports(client.FeatureRulePagination)
+	return err == nil && supported
+}
+
+func (r *AggregationRules) supportsRuleFilters() bool {
+	if r.capabilities == nil {
+		return false
+	}
+	supported, err := r.capabilities.supports(client.FeatureRuleFilters)
+	return err == nil && supported
+}
+
+func (r *AggregationRules) supportsAggregatedNames() bool {
+	if r.capabilities == nil {
+		return false
+	}
+	supported, err := r.capabilities.supports(client.FeatureAggregatedNames)
+	return err == nil && supported
+}
+
+func (r *AggregationRules) supportsRuleNames() bool {
+	if r.capabilities == nil {
+		return false
+	}
+	supported, err := r.capabilities.supports(client.FeatureRuleNames)
+	return err == nil && supported
+}
+
+// ruleFields returns the fields of the rules to request, which are the fields
+// the provider models if the backend supports field selection, and every field
+// otherwise.
+func (r *AggregationRules) ruleFields() []string {
+	if r.capabilities == nil {
+		return nil
+	}
+	if supported, err := r.capabilities.supports(client.FeatureFieldSelection); err != nil || !supported {
+		return nil
+	}
+	return model.AggregationRuleFields()
+}
+
+// warn passes the warnings returned for the qualified rule to onWarning.
+func (r *AggregationRules) warn(rule model.AggregationRule, warnings []string) {
+	if r.onWarning == nil {
+		return
+	}
+	for _, w := range warnings {
+		r.onWarning(r.unqualify(rule).Metric, w)
+	}
+}
+
+func (r *AggregationRules) delete(metric string) error {
+	var etag string
+	var err error
+	prior, cached := r.rules[metric]
+	if id := prior.ID; id != "" {
+		etag, err = r.client.DeleteAggregationRuleByID(id, r.etag)
+	} else {
+		etag, err = r.client.DeleteAggregationRule(metric, r.etag)
+	}
+
+	var notFound client.ErrNotFound
+	if errors.As(err, &notFound) {
+		// The rule was deleted by someone else.
+		delete(r.rules, metric)
+	}
+	if err != nil {
+		return r.checkConflict(err)
+	}
+	if cached {
+		r.audit.record("delete", metric, r.auditCell, &prior, nil)
+	} else {
+		r.audit.record("delete", metric, r.auditCell, nil, nil)
+	}
+
 	r.etag = etag
-	delete(r.rules, rule.Metric)
+	delete(r.rules, metric)
 	return nil
 }
@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type rulesCSVDatasource struct{}
+
+var _ datasource.DataSource = &rulesCSVDatasource{}
+
+func newRulesCSVDatasource() datasource.DataSource {
+	return &rulesCSVDatasource{}
+}
+
+func (d *rulesCSVDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rules_csv", req.ProviderTypeName)
+}
+
+func (d *rulesCSVDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Parses aggregation rules from a CSV file, such as one exported from a spreadsheet, into a list of rules to manage with for_each. The first row names the columns, which may be in any order: metric, match_type, drop, keep_labels, drop_labels, aggregations, aggregation_interval, aggregation_delay, and priority. Only metric is required. keep_labels, drop_labels, and aggregations hold semicolon-separated lists, such as \"sum;count\", and empty cells leave their field unset. Every row is validated, and problems are reported with the row they were found on.",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Optional:    true,
+				Description: "The path of the CSV file. Exactly one of path and content must be set.",
+			},
+			"content": schema.StringAttribute{
+				Optional:    true,
+				Description: "The CSV file itself, such as from templatefile(). Exactly one of path and content must be set.",
+			},
+			"rules": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The aggregation rules parsed from the rows of the file, in order.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the metric to be aggregated.",
+						},
+						"match_type": schema.StringAttribute{
+							Computed:    true,
+							Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact'.",
+						},
+
+						"drop": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the metric is dropped entirely rather than aggregated.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"drop_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels that will be aggregated.",
+						},
+
+						"aggregations": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of aggregation types to calculate for this metric.",
+						},
+
+						"aggregation_interval": schema.StringAttribute{
+							Computed:    true,
+							Description: "The interval at which to generate the aggregated series.",
+						},
+						"aggregation_delay": schema.StringAttribute{
+							Computed:    true,
+							Description: "The delay until aggregation is performed.",
+						},
+
+						"priority": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The priority of the rule.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *rulesCSVDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var tf model.RulesCSVTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &tf)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var data []byte
+	source := path.Root("content")
+	switch {
+	case tf.Path.IsNull() == tf.Content.IsNull():
+		resp.Diagnostics.AddError("Invalid rules CSV source", "Exactly one of path and content must be set.")
+		return
+	case !tf.Path.IsNull():
+		source = path.Root("path")
+		var err error
+		if data, err = os.ReadFile(tf.Path.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(source, "Unable to read rules CSV", err.Error())
+			return
+		}
+	default:
+		data = []byte(tf.Content.ValueString())
+	}
+
+	rows, err := model.ParseRulesCSV(data)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(source, "Invalid rules CSV", fmt.Sprintf("Unable to parse the rules: %s", err))
+		return
+	}
+
+	known := make([]*model.AggregationRule, len(rows))
+	for i := range rows {
+		known[i] = &rows[i].Rule
+	}
+	resp.Diagnostics.Append(rulesCSVDiagnostics(source, rows, validateRuleSet(known))...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tf.Rules = make([]model.RuleSetRuleTF, len(rows))
+	for i, row := range rows {
+		tf.Rules[i] = row.Rule.ToRuleSetRuleTF()
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+var (
+	// ruleSetPath matches the path of a diagnostic of validateRuleSet,
+	// capturing the index of the rule and the attribute of the rule.
+	ruleSetPath = regexp.MustCompile(`^rules\[(\d+)\]\.?([a-z_]*)`)
+	// ruleSetReference matches the references to other rules of the rule set
+	// in the details of the diagnostics of validateRuleSet.
+	ruleSetReference = regexp.MustCompile(`rule (\d+) of the rule set`)
+)
+
+// rulesCSVDiagnostics reports the diagnostics of validateRuleSet against
+// source, naming the row and the column of the file which each of them refers
+// to. The other rules which they refer to are named by their row as well.
+func rulesCSVDiagnostics(source path.Path, rows []model.CSVRule, diags diag.Diagnostics) diag.Diagnostics {
+	row := func(index string) int {
+		i, _ := strconv.Atoi(index)
+		return rows[i].Row
+	}
+
+	var out diag.Diagnostics
+	for _, d := range diags {
+		detail := ruleSetReference.ReplaceAllStringFunc(d.Detail(), func(ref string) string {
+			return fmt.Sprintf("row %d", row(ruleSetReference.FindStringSubmatch(ref)[1]))
+		})
+		if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+			if m := ruleSetPath.FindStringSubmatch(withPath.Path().String()); m != nil {
+				if m[2] != "" {
+					detail = fmt.Sprintf("Row %d, column %s: %s", row(m[1]), m[2], detail)
+				} else {
+					detail = fmt.Sprintf("Row %d: %s", row(m[1]), detail)
+				}
+			}
+		}
+
+		if d.Severity() == diag.SeverityError {
+			out.AddAttributeError(source, d.Summary(), detail)
+		} else {
+			out.AddAttributeWarning(source, d.Summary(), detail)
+		}
+	}
+	return out
+}
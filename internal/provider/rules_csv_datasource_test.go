@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readRulesCSV(t *testing.T, filePath, content *string) *datasource.ReadResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	d := &rulesCSVDatasource{}
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	typ := schemaResp.Schema.Type().TerraformType(ctx)
+
+	value := func(s *string) tftypes.Value {
+		if s == nil {
+			return tftypes.NewValue(tftypes.String, nil)
+		}
+		return tftypes.NewValue(tftypes.String, *s)
+	}
+	config := tfsdk.Config{
+		Schema: schemaResp.Schema,
+		Raw: tftypes.NewValue(typ, map[string]tftypes.Value{
+			"path":    value(filePath),
+			"content": value(content),
+			"rules":   tftypes.NewValue(typ.(tftypes.Object).AttributeTypes["rules"], nil),
+		}),
+	}
+
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(typ, nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+func TestRulesCSVDatasource(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.csv")
+	require.NoError(t, os.WriteFile(file, []byte("\ufeffmetric,match_type,drop_labels,aggregations,priority,drop\n"+
+		"http_requests_total,,pod;instance,sum:counter;count,,\n"+
+		"\n"+
+		"\"api_\",prefix,\"pod\",,10,\n"+
+		"debug_metric, , , , , true\n"), 0o600))
+
+	resp := readRulesCSV(t, &file, nil)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.RulesCSVTF
+	require.False(t, resp.State.Get(context.Background(), &tf).HasError())
+	require.Equal(t, []model.RuleSetRuleTF{
+		model.AggregationRule{Metric: "http_requests_total", DropLabels: []string{"pod", "instance"}, Aggregations: []string{"sum:counter", "count"}}.ToRuleSetRuleTF(),
+		model.AggregationRule{Metric: "api_", MatchType: "prefix", DropLabels: []string{"pod"}, Priority: 10}.ToRuleSetRuleTF(),
+		model.AggregationRule{Metric: "debug_metric", Drop: true}.ToRuleSetRuleTF(),
+	}, tf.Rules)
+}
+
+func TestRulesCSVDatasourceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		content       string
+		expectSummary string
+		expectDetail  string
+	}{
+		{
+			name:          "unknown column",
+			content:       "metric,keep_label\na,pod\n",
+			expectSummary: "Invalid rules CSV",
+			expectDetail:  `Unable to parse the rules: row 1: unknown column "keep_label"; the columns must be among metric, match_type, drop, keep_labels, drop_labels, aggregations, aggregation_interval, aggregation_delay, priority`,
+		},
+		{
+			name:          "missing metric column",
+			content:       "drop_labels\npod\n",
+			expectSummary: "Invalid rules CSV",
+			expectDetail:  "Unable to parse the rules: row 1: missing the metric column",
+		},
+		{
+			name:          "invalid cell",
+			content:       "metric,priority\na,1\nb,high\n",
+			expectSummary: "Invalid rules CSV",
+			expectDetail:  `Unable to parse the rules: row 3: priority is "high"; it must be an integer`,
+		},
+		{
+			name:          "unterminated quote",
+			content:       "metric,drop_labels\na,pod\nb,\"pod\n",
+			expectSummary: "Invalid rules CSV",
+			expectDetail:  "Unable to parse the rules: row 3: extraneous or missing \" in quoted-field",
+		},
+		{
+			name:          "wrong number of cells",
+			content:       "metric,drop_labels\na,pod,instance\n",
+			expectSummary: "Invalid rules CSV",
+			expectDetail:  "Unable to parse the rules: row 2: wrong number of fields",
+		},
+		{
+			name:          "invalid rule",
+			content:       "metric,match_type\na,\nb,glob\n",
+			expectSummary: "Invalid match_type",
+			expectDetail:  `Row 3, column match_type: The rule for "b" has match_type "glob"; it must be one of 'prefix', 'suffix', or 'exact'.`,
+		},
+		{
+			name:          "empty metric",
+			content:       "metric,drop\n\"\",true\n",
+			expectSummary: "Empty metric",
+			expectDetail:  "Row 2, column metric: An exact match rule must specify the metric it applies to.",
+		},
+		{
+			name:          "duplicate metric",
+			content:       "metric\na\n\nb\na\n",
+			expectSummary: "Duplicate metric",
+			expectDetail:  `Row 5, column metric: The metric "a" is already used by row 2.`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := readRulesCSV(t, nil, &tc.content)
+			require.True(t, resp.Diagnostics.HasError())
+			require.Equal(t, tc.expectSummary, resp.Diagnostics.Errors()[0].Summary())
+			require.Equal(t, tc.expectDetail, resp.Diagnostics.Errors()[0].Detail())
+		})
+	}
+}
+
+func TestRulesCSVDatasourceSource(t *testing.T) {
+	content := "metric\na\n"
+	resp := readRulesCSV(t, nil, nil)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Invalid rules CSV source", resp.Diagnostics.Errors()[0].Summary())
+
+	file := filepath.Join(t.TempDir(), "missing.csv")
+	resp = readRulesCSV(t, &file, &content)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Invalid rules CSV source", resp.Diagnostics.Errors()[0].Summary())
+
+	resp = readRulesCSV(t, &file, nil)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Unable to read rules CSV", resp.Diagnostics.Errors()[0].Summary())
+}
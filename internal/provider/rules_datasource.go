@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type rulesDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &rulesDatasource{}
+	_ datasource.DataSourceWithConfigure = &rulesDatasource{}
+)
+
+func newRulesDatasource() datasource.DataSource {
+	return &rulesDatasource{}
+}
+
+func (r *rulesDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+}
+
+func (r *rulesDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rules", req.ProviderTypeName)
+}
+
+func (r *rulesDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the existing aggregation rules. The rules are requested from the API rather than taken from the provider's cache, one page at a time from backends which list rules by page. Backends which filter the rules they list are sent the filters, so that only the matching rules are transferred; the rules of other backends are filtered by the provider. Every filter which is set must match; a data source without filters lists every rule.",
+		Attributes: map[string]schema.Attribute{
+			"metric_prefix": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only list the rules whose metric starts with this prefix. It is relative to the provider's metric_prefix.",
+			},
+			"match_type": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only list the rules with this match type. Can be 'prefix', 'suffix', or 'exact'.",
+			},
+			"drop": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Only list the rules which drop their metric if true, or which aggregate it if false.",
+			},
+			"group": schema.StringAttribute{
+				Optional:    true,
+				Description: "Only list the rules which the backend files under this group. Requires a backend which filters the rules it lists.",
+			},
+			"tags": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Only list the rules which have all of these tags, with the same values.",
+			},
+			"rules": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The aggregation rules matching the filters.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"metric": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the metric to be aggregated.",
+						},
+						"match_type": schema.StringAttribute{
+							Computed:    true,
+							Description: "Specifies how the metric field matches to incoming metric names. Can be 'prefix', 'suffix', or 'exact'.",
+						},
+
+						"drop": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the metric is dropped entirely rather than aggregated.",
+						},
+						"keep_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels to keep; labels not in this array will be aggregated.",
+						},
+						"drop_labels": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of labels that will be aggregated.",
+						},
+
+						"aggregations": schema.ListAttribute{
+							ElementType: types.StringType,
+							Computed:    true,
+							Description: "The array of aggregation types to calculate for this metric.",
+						},
+
+						"aggregation_interval": schema.StringAttribute{
+							Computed:    true,
+							Description: "The interval at which to generate the aggregated series.",
+						},
+						"aggregation_delay": schema.StringAttribute{
+							Computed:    true,
+							Description: "The delay until aggregation is performed.",
+						},
+
+						"priority": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The priority of the rule.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *rulesDatasource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var state model.RulesTF
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	filter := client.AggregationRuleFilter{
+		MetricPrefix: state.MetricPrefix.ValueString(),
+		MatchType:    state.MatchType.ValueString(),
+		Group:        state.Group.ValueString(),
+	}
+	switch filter.MatchType {
+	case "", "prefix", "suffix", "exact":
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("match_type"),
+			"Invalid match_type",
+			fmt.Sprintf("Got %q; it must be one of 'prefix', 'suffix', or 'exact'.", filter.MatchType),
+		)
+		return
+	}
+	if filter.Group != "" {
+		// Only the backend knows the group of a rule.
+		resp.Diagnostics.Append(r.rules.capabilities.require(client.FeatureRuleFilters)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if !state.Drop.IsNull() {
+		drop := state.Drop.ValueBool()
+		filter.Drop = &drop
+	}
+	if len(state.Tags) > 0 {
+		filter.Tags = make(map[string]string, len(state.Tags))
+		for k, v := range state.Tags {
+			filter.Tags[k] = v.ValueString()
+		}
+	}
+
+	// Every page is converted as it arrives, so that only one page of API
+	// rules is held in memory at a time.
+	state.Rules = []model.RuleSetRuleTF{}
+	err := r.rules.ListFiltered(filter, func(rules []model.AggregationRule) error {
+		for _, rule := range rules {
+			state.Rules = append(state.Rules, rule.ToRuleSetRuleTF())
+		}
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to list aggregation rules", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func readRulesDatasource(t *testing.T, rules *AggregationRules, cfg model.RulesTF) []string {
+	t.Helper()
+	ctx := context.Background()
+	d := &rulesDatasource{rules: rules}
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, cfg).HasError())
+
+	resp := &datasource.ReadResponse{State: state}
+	d.Read(ctx, datasource.ReadRequest{Config: tfsdk.Config{Schema: state.Schema, Raw: state.Raw}}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.RulesTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	metrics := make([]string, len(tf.Rules))
+	for i, rule := range tf.Rules {
+		metrics[i] = rule.Metric.ValueString()
+	}
+	return metrics
+}
+
+func TestRulesDatasourceFilters(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "kube_pod_info", Drop: true, Tags: map[string]string{"owner": "team-a"}},
+		model.AggregationRule{Metric: "kube_pod_status", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}, Tags: map[string]string{"owner": "team-a", "criticality": "high"}},
+		model.AggregationRule{Metric: "kube_", MatchType: "prefix", Drop: true},
+		model.AggregationRule{Metric: "_bucket", MatchType: "suffix", Drop: true},
+	)
+	rules := api.aggregationRules()
+
+	for name, tc := range map[string]struct {
+		cfg      model.RulesTF
+		expected []string
+	}{
+		"none": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringNull(), MatchType: types.StringNull(), Drop: types.BoolNull()},
+			expected: []string{"_bucket", "kube_", "kube_pod_info", "kube_pod_status"},
+		},
+		"prefix": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringValue("kube_pod"), MatchType: types.StringNull(), Drop: types.BoolNull()},
+			expected: []string{"kube_pod_info", "kube_pod_status"},
+		},
+		"match type": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringNull(), MatchType: types.StringValue("exact"), Drop: types.BoolNull()},
+			expected: []string{"kube_pod_info", "kube_pod_status"},
+		},
+		"drop": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringNull(), MatchType: types.StringNull(), Drop: types.BoolValue(false)},
+			expected: []string{"kube_pod_status"},
+		},
+		"combined": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringValue("kube_"), MatchType: types.StringNull(), Drop: types.BoolValue(true)},
+			expected: []string{"kube_", "kube_pod_info"},
+		},
+		"tags": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringNull(), MatchType: types.StringNull(), Drop: types.BoolNull(), Tags: map[string]types.String{"owner": types.StringValue("team-a")}},
+			expected: []string{"kube_pod_info", "kube_pod_status"},
+		},
+		"all tags": {
+			cfg:      model.RulesTF{MetricPrefix: types.StringNull(), MatchType: types.StringNull(), Drop: types.BoolNull(), Tags: map[string]types.String{"owner": types.StringValue("team-a"), "criticality": types.StringValue("high")}},
+			expected: []string{"kube_pod_status"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.expected, readRulesDatasource(t, rules, tc.cfg))
+		})
+	}
+}
+
+func TestRulesDatasourcePages(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "team_a"},
+		model.AggregationRule{Metric: "team_b"},
+		model.AggregationRule{Metric: "team_c"},
+		model.AggregationRule{Metric: "other"},
+	)
+	api.pageSize = 2
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+	rules.capabilities = newCapabilities(api.client())
+	cfg := model.RulesTF{MetricPrefix: types.StringNull(), MatchType: types.StringNull(), Drop: types.BoolNull()}
+
+	// Backends without pagination list every rule at once.
+	api.features = []string{}
+	before := len(api.requestLog())
+	require.Equal(t, []string{"a", "b", "c"}, readRulesDatasource(t, rules, cfg))
+	require.Equal(t, []string{"GET /aggregations/server_info", "GET /aggregations/rules"}, api.requestLog()[before:])
+
+	api.features = []string{string(client.FeatureRulePagination)}
+	rules.capabilities = newCapabilities(api.client())
+	before = len(api.requestLog())
+	require.Equal(t, []string{"a", "b", "c"}, readRulesDatasource(t, rules, cfg))
+
+	// The rules are listed by the API rather than read from the cache, in
+	// two pages of two rules of which the provider filters out "other".
+	require.Equal(t, []string{"GET /aggregations/server_info", "GET /aggregations/rules", "GET /aggregations/rules"}, api.requestLog()[before:])
+}
+
+func TestRulesDatasourceServerFilters(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "team_a", Drop: true, Tags: map[string]string{"owner": "team-a"}},
+		model.AggregationRule{Metric: "team_b", Drop: true},
+		model.AggregationRule{Metric: "team_c"},
+		model.AggregationRule{Metric: "other", Drop: true},
+	)
+	api.groups["team_a"] = "platform"
+	api.groups["team_b"] = "platform"
+	rules := NewAggregationRules(api.client(), "team_")
+	require.NoError(t, rules.Init())
+	cfg := model.RulesTF{
+		MetricPrefix: types.StringNull(),
+		MatchType:    types.StringValue("exact"),
+		Drop:         types.BoolValue(true),
+		Group:        types.StringValue("platform"),
+		Tags:         map[string]types.String{"owner": types.StringValue("team-a")},
+	}
+
+	// Backends which do not filter the rules they list do not know their
+	// group.
+	before := len(api.listQueries)
+	api.features = []string{}
+	rules.capabilities = newCapabilities(api.client())
+	ctx := context.Background()
+	d := &rulesDatasource{rules: rules}
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	state := tfsdk.State{Schema: schemaResp.Schema}
+	require.False(t, state.Set(ctx, cfg).HasError())
+	resp := &datasource.ReadResponse{State: state}
+	d.Read(ctx, datasource.ReadRequest{Config: tfsdk.Config{Schema: state.Schema, Raw: state.Raw}}, resp)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support filtering the listed rules")
+	require.Len(t, api.listQueries, before)
+
+	// Otherwise the filters are sent to the API, relative to the provider's
+	// metric prefix, and only the matching rules are listed.
+	api.features = []string{string(client.FeatureRuleFilters)}
+	rules.capabilities = newCapabilities(api.client())
+	require.Equal(t, []string{"a"}, readRulesDatasource(t, rules, cfg))
+	require.Equal(t, []string{"drop=true&group=platform&match_type=exact&metric_prefix=team_&tag=owner%3Dteam-a"}, api.listQueries[before:])
+}
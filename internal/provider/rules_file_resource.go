@@ -0,0 +1,376 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type rulesFileResource struct {
+	rules               *AggregationRules
+	capabilities        *capabilities
+	broadMatchMinLength int
+	requiredKeepLabels  []string
+
+	allowedMetricPatterns []string
+	savings               *savingsBudget
+}
+
+var (
+	_ resource.Resource               = &rulesFileResource{}
+	_ resource.ResourceWithConfigure  = &rulesFileResource{}
+	_ resource.ResourceWithModifyPlan = &rulesFileResource{}
+)
+
+func newRulesFileResource() resource.Resource {
+	return &rulesFileResource{}
+}
+
+func (r *rulesFileResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+	r.capabilities = data.capabilities
+	r.broadMatchMinLength = data.broadMatchMinLength
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+	r.savings = data.savings
+}
+
+func (r *rulesFileResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rules_file", req.ProviderTypeName)
+}
+
+func (r *rulesFileResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages the aggregation rules listed in a JSON file. The file holds an array of rules in the format of the API, or a backup exported by the `rules_snapshot` data source, and is read on every plan: rules added to, changed in, or removed from the file are created, updated, or deleted on the next apply. Rules which were never part of the file are left untouched.",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Required:    true,
+				Description: "The path of the JSON rules file. Relative paths are resolved against the working directory, so use path.module to refer to a file next to the configuration.",
+			},
+			"allow_broad_match": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to allow prefix or suffix rules whose metric is shorter than the provider's broad_match_min_length. Such rules aggregate a large share of all metrics.",
+			},
+			"metrics": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics of the rules managed by this resource, sorted by name.",
+			},
+			"rules_hash": schema.StringAttribute{
+				Computed:    true,
+				Description: "A SHA-256 hash of the managed rules. It changes when the file changes, or when a managed rule is changed outside of Terraform.",
+			},
+		},
+	}
+}
+
+func (r *rulesFileResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.rules == nil {
+		return
+	}
+
+	var previous []model.AggregationRule
+	if !req.State.Raw.IsNull() {
+		var state model.RulesFileTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		previous = state.Managed()
+	}
+
+	// Destroying the resource deletes the rules of the file.
+	if req.Plan.Raw.IsNull() {
+		changes := diffRuleSet(r.rules.List(), previous, nil, false)
+		resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Empty())...)
+		return
+	}
+
+	var plan model.RulesFileTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Path.IsUnknown() || plan.AllowBroadMatch.IsUnknown() {
+		return
+	}
+
+	desired, diags := r.desiredRules(plan)
+	resp.Diagnostics.Append(diags...)
+	for _, rule := range desired {
+		resp.Diagnostics.Append(r.capabilities.requireRule(rule)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(r.rules.List(), previous, desired, false)
+	resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Root("path"))...)
+
+	plan.Metrics = ruleMetrics(desired)
+	plan.RulesHash = types.StringValue(hashRules(desired))
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+func (r *rulesFileResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.RulesFileTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	desired, diags := r.plannedRules(plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(r.rules.List(), nil, desired, false)
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&resp.Diagnostics)).Apply(changes); err != nil {
+		resp.Diagnostics.AddError("Unable to create aggregation rules from file", err.Error())
+
+		// Keep track of the rules which were created before the failure.
+		if state := r.currentState(plan.Managed(), plan); len(state.Metrics) > 0 {
+			resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+		}
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *rulesFileResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state model.RulesFileTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tf := r.currentState(state.Managed(), state)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &tf)...)
+}
+
+func (r *rulesFileResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.RulesFileTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var state model.RulesFileTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	desired, diags := r.plannedRules(plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(r.rules.List(), state.Managed(), desired, false)
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&resp.Diagnostics)).Apply(changes); err != nil {
+		resp.Diagnostics.AddError("Unable to update aggregation rules from file", err.Error())
+
+		// Rules which could not be deleted yet are kept in state so that the
+		// deletion is retried on the next apply.
+		tf := r.currentState(append(plan.Managed(), state.Managed()...), plan)
+		resp.Diagnostics.Append(resp.State.Set(ctx, tf)...)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *rulesFileResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state model.RulesFileTF
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	changes := diffRuleSet(r.rules.List(), state.Managed(), nil, false)
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&resp.Diagnostics)).Apply(changes); err != nil {
+		resp.Diagnostics.AddError("Unable to delete aggregation rules from file", err.Error())
+	}
+}
+
+// desiredRules reads and validates the rules of the file. The allAggregations
+// sentinel is expanded, so that the rules compare equal to the ones the API
+// returns once they are applied.
+func (r *rulesFileResource) desiredRules(tf model.RulesFileTF) ([]model.AggregationRule, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	name := tf.Path.ValueString()
+
+	rules, err := loadRulesFile(name)
+	if err != nil {
+		diags.AddAttributeError(path.Root("path"), "Unable to read rules file", err.Error())
+		return nil, diags
+	}
+
+	known := make([]*model.AggregationRule, len(rules))
+	for i := range rules {
+		known[i] = &rules[i]
+	}
+	validation := validateRuleSet(known)
+	if !tf.AllowBroadMatch.ValueBool() {
+		for i, rule := range rules {
+			validation.Append(validateBroadMatch(rule, r.broadMatchMinLength, path.Root("rules").AtListIndex(i))...)
+		}
+	}
+	for i, rule := range rules {
+		validation.Append(validateRequiredKeepLabels(rule, r.requiredKeepLabels, path.Root("rules").AtListIndex(i))...)
+		validation.Append(validateAllowedMetric(rule, r.allowedMetricPatterns, path.Root("rules").AtListIndex(i))...)
+	}
+	diags.Append(rulesFileDiagnostics(name, validation)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	for i, rule := range rules {
+		if hasAllAggregations(rule.Aggregations) {
+			supported, err := r.rules.SupportedAggregations()
+			if err != nil {
+				diags.AddAttributeError(path.Root("path"), "Unable to expand aggregations", fmt.Sprintf("The rule for %q in %s uses %q: %s", rule.Metric, name, allAggregations, err))
+				return nil, diags
+			}
+			rules[i].Aggregations = slices.Clone(supported)
+		}
+	}
+
+	return rules, diags
+}
+
+// plannedRules reads the rules of the file again at apply time, and makes sure
+// the file still holds the rules shown in the plan.
+func (r *rulesFileResource) plannedRules(plan model.RulesFileTF) ([]model.AggregationRule, diag.Diagnostics) {
+	desired, diags := r.desiredRules(plan)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	if hashRules(desired) != plan.RulesHash.ValueString() {
+		diags.AddAttributeError(
+			path.Root("path"),
+			"Rules file changed after plan",
+			fmt.Sprintf("The rules in %s no longer match the plan. Run terraform plan again to review the changes.", plan.Path.ValueString()),
+		)
+		return nil, diags
+	}
+
+	return desired, diags
+}
+
+// currentState builds the state from the existing rules for the given metrics.
+// Metrics without a rule are dropped, so that they are created again.
+func (r *rulesFileResource) currentState(managed []model.AggregationRule, settings model.RulesFileTF) model.RulesFileTF {
+	rules := make([]model.AggregationRule, 0, len(managed))
+	seen := make(map[string]bool, len(managed))
+	for _, m := range managed {
+		if seen[m.Metric] {
+			continue
+		}
+		seen[m.Metric] = true
+
+		// The rule is read in full, rather than through readRules, as files
+		// may set drop_if and archived.
+		rule, err := r.rules.Read(m.Metric)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	return model.RulesFileTF{
+		Path:            settings.Path,
+		AllowBroadMatch: settings.AllowBroadMatch,
+		Metrics:         ruleMetrics(rules),
+		RulesHash:       types.StringValue(hashRules(rules)),
+	}
+}
+
+// loadRulesFile reads the aggregation rules of a rules file.
+func loadRulesFile(name string) ([]model.AggregationRule, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := model.ParseRulesFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", name, err)
+	}
+	return rules, nil
+}
+
+// rulesFileDiagnostics reports the diagnostics of validateRuleSet against the
+// path attribute, naming the rule of the file each of them refers to.
+func rulesFileDiagnostics(name string, diags diag.Diagnostics) diag.Diagnostics {
+	var out diag.Diagnostics
+	for _, d := range diags {
+		detail := d.Detail()
+		if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+			detail = fmt.Sprintf("%s%s: %s", name, strings.TrimPrefix(withPath.Path().String(), "rules"), detail)
+		}
+
+		if d.Severity() == diag.SeverityError {
+			out.AddAttributeError(path.Root("path"), d.Summary(), detail)
+		} else {
+			out.AddAttributeWarning(path.Root("path"), d.Summary(), detail)
+		}
+	}
+	return out
+}
+
+// ruleMetrics returns the sorted metrics of rules.
+func ruleMetrics(rules []model.AggregationRule) []types.String {
+	metrics := make([]types.String, len(rules))
+	for i, rule := range rules {
+		metrics[i] = types.StringValue(rule.Metric)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].ValueString() < metrics[j].ValueString() })
+	return metrics
+}
+
+// hashRules hashes the fields of rules which are compared by
+// model.AggregationRule.Equal, independently of the order of the rules and
+// of their aggregations.
+func hashRules(rules []model.AggregationRule) string {
+	sorted := slices.Clone(rules)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Metric < sorted[j].Metric })
+
+	normalized := make([]map[string]any, len(sorted))
+	for i, rule := range sorted {
+		// The fields of a rule are plain values which marshal.
+		normalized[i], _ = rule.ComparedFields()
+	}
+
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,231 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func writeRulesFile(t *testing.T, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+}
+
+// planRulesFile runs ModifyPlan for the rules file and returns the plan.
+func planRulesFile(t *testing.T, r *rulesFileResource, state tfsdk.State, name string) (tfsdk.Plan, *fwresource.ModifyPlanResponse) {
+	t.Helper()
+	ctx := context.Background()
+
+	plan := tfsdk.Plan{Schema: state.Schema}
+	require.False(t, plan.Set(ctx, model.RulesFileTF{
+		Path:            types.StringValue(name),
+		AllowBroadMatch: types.BoolNull(),
+		RulesHash:       types.StringUnknown(),
+	}).HasError())
+
+	resp := &fwresource.ModifyPlanResponse{Plan: plan}
+	r.ModifyPlan(ctx, fwresource.ModifyPlanRequest{Plan: plan, State: state}, resp)
+	return resp.Plan, resp
+}
+
+func newTestRulesFileResource(t *testing.T, rules *AggregationRules) (*rulesFileResource, tfsdk.State) {
+	t.Helper()
+
+	r := &rulesFileResource{rules: rules, broadMatchMinLength: 3}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	return r, tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+func TestRulesFileResourceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t, model.AggregationRule{Metric: "unmanaged", Drop: true})
+	rules := api.aggregationRules()
+	r, empty := newTestRulesFileResource(t, rules)
+	name := filepath.Join(t.TempDir(), "rules.json")
+
+	// Create.
+	writeRulesFile(t, name, `[
+		{"metric": "a", "drop": true},
+		{"metric": "b", "drop_labels": ["pod"], "aggregations": ["sum"]}
+	]`)
+	plan, planResp := planRulesFile(t, r, empty, name)
+	require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	require.Equal(t, []string{"a", "b", "unmanaged"}, api.metrics())
+
+	var created model.RulesFileTF
+	require.False(t, createResp.State.Get(ctx, &created).HasError())
+	require.Equal(t, []types.String{types.StringValue("a"), types.StringValue("b")}, created.Metrics)
+
+	// Read without changes keeps the hash, so that there is no diff.
+	readResp := &fwresource.ReadResponse{State: createResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: createResp.State}, readResp)
+	require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+
+	var read model.RulesFileTF
+	require.False(t, readResp.State.Get(ctx, &read).HasError())
+	require.Equal(t, created, read)
+
+	plan, planResp = planRulesFile(t, r, readResp.State, name)
+	require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+	var planned model.RulesFileTF
+	require.False(t, plan.Get(ctx, &planned).HasError())
+	require.Equal(t, read, planned)
+
+	// Update removes the rules which are no longer part of the file.
+	writeRulesFile(t, name, `[{"metric": "b", "drop_labels": ["namespace"], "aggregations": ["sum"]}]`)
+	plan, planResp = planRulesFile(t, r, readResp.State, name)
+	require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+	require.False(t, plan.Get(ctx, &planned).HasError())
+	require.NotEqual(t, read.RulesHash, planned.RulesHash)
+
+	updateResp := &fwresource.UpdateResponse{State: readResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: plan, State: readResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+	require.Equal(t, []string{"b", "unmanaged"}, api.metrics())
+	got, _ := api.rule("b")
+	require.Equal(t, []string{"namespace"}, got.DropLabels)
+
+	// A rule changed outside of Terraform changes the hash.
+	require.NoError(t, rules.Update(model.AggregationRule{Metric: "b", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}}))
+	readResp = &fwresource.ReadResponse{State: updateResp.State}
+	r.Read(ctx, fwresource.ReadRequest{State: updateResp.State}, readResp)
+	require.False(t, readResp.State.Get(ctx, &read).HasError())
+	require.NotEqual(t, planned.RulesHash, read.RulesHash)
+
+	// Delete leaves the unmanaged rule untouched.
+	deleteResp := &fwresource.DeleteResponse{}
+	r.Delete(ctx, fwresource.DeleteRequest{State: readResp.State}, deleteResp)
+	require.False(t, deleteResp.Diagnostics.HasError(), deleteResp.Diagnostics)
+	require.Equal(t, []string{"unmanaged"}, api.metrics())
+}
+
+func TestRulesFileResourceInvalidFile(t *testing.T) {
+	api := newMockAPI(t)
+	r, empty := newTestRulesFileResource(t, api.aggregationRules())
+	dir := t.TempDir()
+
+	for name, tc := range map[string]struct {
+		content string
+		detail  string
+	}{
+		"missing": {
+			detail: "no such file",
+		},
+		"malformed": {
+			content: `{"metric": "a"}`,
+			detail:  "unable to parse",
+		},
+		"unknown field": {
+			content: `[{"metric": "a", "aggregation": ["sum"]}]`,
+			detail:  `unknown field "aggregation"`,
+		},
+		"invalid rule": {
+			content: `[{"metric": "a"}, {"metric": "b", "match_type": "regex"}]`,
+			detail:  "rules.json[1].match_type: ",
+		},
+		"duplicate metric": {
+			content: `[{"metric": "a"}, {"metric": "a", "drop": true}]`,
+			detail:  "rules.json[1].metric: ",
+		},
+		"broad match": {
+			content: `[{"metric": "a", "match_type": "prefix"}]`,
+			detail:  "rules.json[0].metric: ",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, name, "rules.json")
+			if tc.content != "" {
+				require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o700))
+				writeRulesFile(t, file, tc.content)
+			}
+
+			_, resp := planRulesFile(t, r, empty, file)
+			require.True(t, resp.Diagnostics.HasError())
+			require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), tc.detail)
+		})
+	}
+}
+
+func TestRulesFileResourceChangedAfterPlan(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	r, empty := newTestRulesFileResource(t, api.aggregationRules())
+	name := filepath.Join(t.TempDir(), "rules.json")
+
+	writeRulesFile(t, name, `[{"metric": "a", "drop": true}]`)
+	plan, planResp := planRulesFile(t, r, empty, name)
+	require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+
+	writeRulesFile(t, name, `[{"metric": "a"}]`)
+	resp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, resp)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, "Rules file changed after plan", resp.Diagnostics.Errors()[0].Summary())
+	require.Empty(t, api.metrics())
+}
+
+func TestRulesFileResourceRequiresFeatures(t *testing.T) {
+	api := newMockAPI(t)
+	api.features = []string{string(client.FeatureMetricMetadata)}
+	r, empty := newTestRulesFileResource(t, api.aggregationRules())
+	r.capabilities = newCapabilities(api.client())
+	name := filepath.Join(t.TempDir(), "rules.json")
+
+	writeRulesFile(t, name, `[{"metric": "a", "drop_labels": ["pod"], "ingest_sample_rate": 0.5}]`)
+	_, resp := planRulesFile(t, r, empty, name)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "does not support ingestion sampling")
+
+	api.features = append(api.features, string(client.FeatureIngestSampling))
+	r.capabilities = newCapabilities(api.client())
+	_, resp = planRulesFile(t, r, empty, name)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+}
+
+func TestHashRules(t *testing.T) {
+	rule := model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum", "count"}}
+	other := model.AggregationRule{Metric: "b", Drop: true}
+	hash := hashRules([]model.AggregationRule{rule, other})
+
+	// The order of the rules and of their aggregations, and metadata, do
+	// not change the hash.
+	reordered := rule
+	reordered.Aggregations = []string{"count", "sum"}
+	reordered.ID = "1"
+	reordered.ManagedBy = "terraform"
+	require.Equal(t, hash, hashRules([]model.AggregationRule{other, reordered}))
+
+	for name, change := range map[string]func(*model.AggregationRule){
+		"name":                  func(r *model.AggregationRule) { r.Name = "requests" },
+		"match_prefixes":        func(r *model.AggregationRule) { r.MatchPrefixes = []string{"a_"} },
+		"label_match_mode":      func(r *model.AggregationRule) { r.LabelMatchMode = "regex" },
+		"aggregation_intervals": func(r *model.AggregationRule) { r.AggregationIntervals = map[string]string{"sum": "1m"} },
+		"drop_labels":           func(r *model.AggregationRule) { r.DropLabels = []string{"instance"} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			changed := rule
+			change(&changed)
+			require.Equal(t, []string{name}, changed.Differences(rule))
+			require.NotEqual(t, hash, hashRules([]model.AggregationRule{changed, other}))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type rulesHCLDatasource struct {
+	rules            *AggregationRules
+	providerTypeName string
+}
+
+var (
+	_ datasource.DataSource              = &rulesHCLDatasource{}
+	_ datasource.DataSourceWithConfigure = &rulesHCLDatasource{}
+)
+
+func newRulesHCLDatasource() datasource.DataSource {
+	return &rulesHCLDatasource{}
+}
+
+func (r *rulesHCLDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+}
+
+func (r *rulesHCLDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	r.providerTypeName = req.ProviderTypeName
+	resp.TypeName = fmt.Sprintf("%s_rules_hcl", req.ProviderTypeName)
+}
+
+func (r *rulesHCLDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Generates Terraform (or OpenTofu) configuration for every existing aggregation rule.",
+		Attributes: map[string]schema.Attribute{
+			"hcl": schema.StringAttribute{
+				Computed:    true,
+				Description: "A rule resource block for every existing aggregation rule, in HCL format.",
+			},
+		},
+	}
+}
+
+func (r *rulesHCLDatasource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	state := model.RulesHCLTF{
+		HCL: types.StringValue(rulesToHCL(r.providerTypeName+"_rule", r.rules.List())),
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+type hclAttribute struct {
+	name  string
+	value string
+}
+
+// rulesToHCL renders a resource block of the given type for each rule, named
+// after its metric. Only attributes which differ from the resource defaults
+// are included.
+func rulesToHCL(resourceType string, rules []model.AggregationRule) string {
+	names := make(map[string]bool)
+	blocks := make([]string, len(rules))
+	for i, rule := range rules {
+		blocks[i] = ruleToHCL(resourceType, hclResourceName(rule.Metric, names), rule)
+	}
+
+	return strings.Join(blocks, "\n")
+}
+
+// ruleToHCL renders a resource block of the given type and name for rule.
+func ruleToHCL(resourceType, name string, rule model.AggregationRule) string {
+	var b strings.Builder
+	tf := rule.ToTF()
+
+	attrs := []hclAttribute{{"metric", hclString(tf.Metric.ValueString())}}
+	if len(tf.MatchPrefixes) > 0 {
+		attrs = []hclAttribute{{"match_prefixes", hclStringList(tf.MatchPrefixes)}}
+	}
+	if v := tf.Name.ValueString(); v != "" {
+		attrs = append([]hclAttribute{{"name", hclString(v)}}, attrs...)
+	}
+	if v := tf.MatchType.ValueString(); v != "" {
+		attrs = append(attrs, hclAttribute{"match_type", hclString(v)})
+	}
+	if tf.Drop.ValueBool() {
+		attrs = append(attrs, hclAttribute{"drop", "true"})
+	}
+	if len(tf.KeepLabels) > 0 {
+		attrs = append(attrs, hclAttribute{"keep_labels", hclStringList(tf.KeepLabels)})
+	}
+	if len(tf.DropLabels) > 0 {
+		attrs = append(attrs, hclAttribute{"drop_labels", hclStringList(tf.DropLabels)})
+	}
+	if v := tf.LabelMatchMode.ValueString(); v != "" {
+		attrs = append(attrs, hclAttribute{"label_match_mode", hclString(v)})
+	}
+	if len(tf.Aggregations) > 0 {
+		attrs = append(attrs, hclAttribute{"aggregations", hclStringList(tf.Aggregations)})
+	}
+	if v := tf.AggregationInterval.ValueString(); v != "" {
+		attrs = append(attrs, hclAttribute{"aggregation_interval", hclString(v)})
+	}
+	if len(tf.AggregationIntervals) > 0 {
+		attrs = append(attrs, hclAttribute{"aggregation_intervals", hclStringMap(tf.AggregationIntervals)})
+	}
+	if v := tf.AggregationDelay.ValueString(); v != "" {
+		attrs = append(attrs, hclAttribute{"aggregation_delay", hclString(v)})
+	}
+	if v := tf.Priority.ValueInt64(); v != 0 {
+		attrs = append(attrs, hclAttribute{"priority", strconv.FormatInt(v, 10)})
+	}
+	if !tf.RolloutPercentage.IsNull() {
+		attrs = append(attrs, hclAttribute{"rollout_percentage", strconv.FormatInt(tf.RolloutPercentage.ValueInt64(), 10)})
+	}
+	if !tf.IngestSampleRate.IsNull() {
+		attrs = append(attrs, hclAttribute{"ingest_sample_rate", strconv.FormatFloat(tf.IngestSampleRate.ValueFloat64(), 'g', -1, 64)})
+	}
+	if len(tf.Tags) > 0 {
+		attrs = append(attrs, hclAttribute{"tags", hclStringMap(tf.Tags)})
+	}
+
+	width := 0
+	for _, attr := range attrs {
+		width = max(width, len(attr.name))
+	}
+
+	fmt.Fprintf(&b, "resource %s %s {\n", hclString(resourceType), hclString(name))
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, "  %-*s = %s\n", width, attr.name, attr.value)
+	}
+	for _, m := range rule.DropIf {
+		b.WriteString("\n  drop_if {\n")
+		if m.Operator == "=" {
+			fmt.Fprintf(&b, "    label = %s\n    value = %s\n", hclString(m.Label), hclString(m.Value))
+		} else {
+			fmt.Fprintf(&b, "    label    = %s\n    operator = %s\n    value    = %s\n", hclString(m.Label), hclString(m.Operator), hclString(m.Value))
+		}
+		b.WriteString("  }\n")
+	}
+	for _, o := range rule.DelayOverrides {
+		b.WriteString("\n  delay_overrides {\n")
+		if o.Matcher.Operator == "=" {
+			fmt.Fprintf(&b, "    label = %s\n    value = %s\n    delay = %s\n", hclString(o.Matcher.Label), hclString(o.Matcher.Value), hclString(o.Delay))
+		} else {
+			fmt.Fprintf(&b, "    label    = %s\n    operator = %s\n    value    = %s\n    delay    = %s\n", hclString(o.Matcher.Label), hclString(o.Matcher.Operator), hclString(o.Matcher.Value), hclString(o.Delay))
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// hclResourceName turns a metric into a unique, valid resource name.
+func hclResourceName(metric string, used map[string]bool) string {
+	var b strings.Builder
+	for _, c := range metric {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+			b.WriteRune(c)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "_" + name
+	}
+
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[unique] = true
+
+	return unique
+}
+
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i, c := range s {
+		switch {
+		case c == '"':
+			b.WriteString(`\"`)
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, c)
+		case (c == '$' || c == '%') && strings.HasPrefix(s[i+1:], "{"):
+			// Escape template sequences so they are kept literally.
+			b.WriteRune(c)
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func hclStringList(values []types.String) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = hclString(v.ValueString())
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// hclStringMap renders a map of strings on a single line, sorted by key.
+func hclStringMap(values map[string]types.String) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		entries[i] = hclString(k) + " = " + hclString(values[k].ValueString())
+	}
+
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
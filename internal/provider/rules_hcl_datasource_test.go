@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestRulesToHCL(t *testing.T) {
+	rules := []model.AggregationRule{
+		{
+			Metric:              "kube_persistentvolumeclaim_created",
+			DropLabels:          []string{"persistentvolumeclaim"},
+			Aggregations:        []string{"count", "sum"},
+			AggregationInterval: "1m",
+			ManagedBy:           "terraform",
+		},
+		{
+			Metric:    "kube.persistentvolumeclaim",
+			MatchType: "prefix",
+			Drop:      true,
+			DropIf: []model.DropMatcher{
+				{Label: "env", Operator: "=", Value: "test"},
+				{Label: "cluster", Operator: "=~", Value: "dev-.*"},
+			},
+			Priority: 2,
+			Tags:     map[string]string{"team": "storage", "criticality": "low"},
+		},
+		{
+			Metric:    "kube_persistentvolumeclaim",
+			MatchType: "suffix",
+			KeepLabels: []string{
+				"${namespace}",
+			},
+		},
+		{
+			Metric:           "0_metric\"quoted\"",
+			IngestSampleRate: ptr(0.25),
+		},
+	}
+
+	expected := `resource "grafana-adaptive-metrics_rule" "kube_persistentvolumeclaim_created" {
+  metric               = "kube_persistentvolumeclaim_created"
+  drop_labels          = ["persistentvolumeclaim"]
+  aggregations         = ["count", "sum"]
+  aggregation_interval = "1m"
+}
+
+resource "grafana-adaptive-metrics_rule" "kube_persistentvolumeclaim" {
+  metric     = "kube.persistentvolumeclaim"
+  match_type = "prefix"
+  drop       = true
+  priority   = 2
+  tags       = { "criticality" = "low", "team" = "storage" }
+
+  drop_if {
+    label = "env"
+    value = "test"
+  }
+
+  drop_if {
+    label    = "cluster"
+    operator = "=~"
+    value    = "dev-.*"
+  }
+}
+
+resource "grafana-adaptive-metrics_rule" "kube_persistentvolumeclaim_2" {
+  metric      = "kube_persistentvolumeclaim"
+  match_type  = "suffix"
+  keep_labels = ["$${namespace}"]
+}
+
+resource "grafana-adaptive-metrics_rule" "_0_metric_quoted_" {
+  metric             = "0_metric\"quoted\""
+  ingest_sample_rate = 0.25
+}
+`
+
+	require.Equal(t, expected, rulesToHCL("grafana-adaptive-metrics_rule", rules))
+}
+
+func TestRulesToHCLEmpty(t *testing.T) {
+	require.Equal(t, "", rulesToHCL("grafana-adaptive-metrics_rule", nil))
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// rulesImportConfigAttributeTypes are the attributes of the object returned
+// by rules_import_config.
+var rulesImportConfigAttributeTypes = map[string]attr.Type{
+	"import_ids": types.MapType{ElemType: types.StringType},
+	"hcl":        types.StringType,
+}
+
+type rulesImportConfigFunction struct{}
+
+var _ function.Function = &rulesImportConfigFunction{}
+
+func newRulesImportConfigFunction() function.Function {
+	return &rulesImportConfigFunction{}
+}
+
+func (f *rulesImportConfigFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "rules_import_config"
+}
+
+func (f *rulesImportConfigFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Generates the configuration and import blocks adopting exported rules",
+		MarkdownDescription: "Returns an import block and a `rule` resource block for every aggregation rule of a JSON export, so that existing rules can be adopted by Terraform in a single step, for example by writing the `hcl` attribute to a file with the `local_file` resource. The export is a JSON array of rules in the format of the API, or the `json` of the `rules_snapshot` data source. Nothing is read from the API, so the metrics are used as exported, including any `metric_prefix`.\n\n" +
+			"Rules are imported by their ID, or if the export has none by their name or else their metric, as the `rule` resource imports them. The resources are named after the metrics of the rules, and only attributes which differ from the resource defaults are set, so that the generated configuration matches the imported rules. Archived rules are included, and restored on the first apply as for any other `rule` resource.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "export",
+				Description: "The exported rules, as JSON.",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttributeTypes: rulesImportConfigAttributeTypes,
+		},
+	}
+}
+
+func (f *rulesImportConfigFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var export string
+	resp.Error = req.Arguments.Get(ctx, &export)
+	if resp.Error != nil {
+		return
+	}
+
+	rules, err := model.ParseRulesExport([]byte(export))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, "unable to parse the exported rules: "+err.Error())
+		return
+	}
+
+	importIDs, hcl, err := rulesImportConfig(providerTypeName+"_rule", rules)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	ids := make(map[string]attr.Value, len(importIDs))
+	for name, id := range importIDs {
+		ids[name] = types.StringValue(id)
+	}
+	resp.Error = resp.Result.Set(ctx, types.ObjectValueMust(rulesImportConfigAttributeTypes, map[string]attr.Value{
+		"import_ids": types.MapValueMust(types.StringType, ids),
+		"hcl":        types.StringValue(hcl),
+	}))
+}
+
+// rulesImportConfig returns the import IDs of rules keyed by the names of
+// their resources of the given type, and the import and resource blocks for
+// them. Rules which would be imported by the same ID are rejected, as they
+// would all be imported as the same rule.
+func rulesImportConfig(resourceType string, rules []model.AggregationRule) (map[string]string, string, error) {
+	importIDs := make(map[string]string, len(rules))
+	imported := make(map[string]string, len(rules))
+	names := make(map[string]bool, len(rules))
+	blocks := make([]string, len(rules))
+
+	for i, rule := range rules {
+		id := ruleImportID(rule)
+		if id == "" {
+			return nil, "", fmt.Errorf("exported rule %d has neither an ID nor a metric, so it cannot be imported", i)
+		}
+		if metric, ok := imported[id]; ok {
+			return nil, "", fmt.Errorf("exported rules for %q and %q would both be imported by the ID %q", metric, rule.Metric, id)
+		}
+		imported[id] = rule.Metric
+
+		name := hclResourceName(rule.Metric, names)
+		importIDs[name] = id
+		blocks[i] = hclImport(resourceType, name, id) + "\n" + ruleToHCL(resourceType, name, rule)
+	}
+
+	return importIDs, strings.Join(blocks, "\n"), nil
+}
+
+// ruleImportID returns the ID by which the rule resource imports rule: its
+// ID, name, or metric, whichever is set first.
+func ruleImportID(rule model.AggregationRule) string {
+	switch {
+	case rule.ID != "":
+		return rule.ID
+	case rule.Name != "":
+		return rule.Name
+	default:
+		return rule.Metric
+	}
+}
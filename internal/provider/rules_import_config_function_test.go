@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func runRulesImportConfig(t *testing.T, export string) (map[string]string, string, *function.FuncError) {
+	t.Helper()
+	ctx := context.Background()
+
+	req := function.RunRequest{Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(export)})}
+	resp := function.RunResponse{Result: function.NewResultData(types.ObjectUnknown(rulesImportConfigAttributeTypes))}
+	newRulesImportConfigFunction().Run(ctx, req, &resp)
+	if resp.Error != nil {
+		return nil, "", resp.Error
+	}
+
+	var result struct {
+		ImportIDs map[string]string `tfsdk:"import_ids"`
+		HCL       string            `tfsdk:"hcl"`
+	}
+	obj, ok := resp.Result.Value().(types.Object)
+	require.True(t, ok)
+	require.False(t, obj.As(ctx, &result, basetypes.ObjectAsOptions{}).HasError())
+	return result.ImportIDs, result.HCL, nil
+}
+
+// exportedRules is a snapshot of rules setting every attribute of the rule
+// resource, and identified by an ID, a name, or only their metric.
+const exportedRules = `{
+  "taken_at": "2024-05-01T10:00:00Z",
+  "rules": [
+    {
+      "id": "r-1",
+      "name": "checkout_latency",
+      "metric": "checkout_latency_seconds",
+      "match_type": "prefix",
+      "drop_labels": ["pod*"],
+      "label_match_mode": "glob",
+      "aggregations": ["sum", "count"],
+      "aggregation_interval": "1m",
+      "aggregation_intervals": {"count": "5m"},
+      "aggregation_delay": "2m",
+      "delay_overrides": [{"matcher": "source!=\"edge\"", "delay": "10m"}],
+      "priority": 3,
+      "rollout_percentage": 50,
+      "tags": {"team": "payments"},
+      "managed_by": "terraform",
+      "recommended_aggregation_interval": "30s",
+      "created_at": "2024-04-01T10:00:00Z",
+      "updated_at": "2024-04-02T10:00:00Z"
+    },
+    {
+      "metric": "kube_pod_info",
+      "drop": true,
+      "drop_if": ["env=\"test\""],
+      "keep_labels": ["namespace"]
+    },
+    {
+      "name": "api_requests",
+      "metric": "api.requests",
+      "match_type": "prefix",
+      "match_prefixes": ["api.requests", "api.errors"],
+      "aggregations": ["sum:counter"]
+    },
+    {
+      "metric": "api:requests",
+      "aggregations": ["sum"],
+      "archived": true
+    }
+  ]
+}`
+
+func TestRulesImportConfigFunction(t *testing.T) {
+	importIDs, hcl, funcErr := runRulesImportConfig(t, exportedRules)
+	require.Nil(t, funcErr)
+
+	// Rules are imported by their ID, name, or metric, and resources which
+	// would be named alike are numbered.
+	require.Equal(t, map[string]string{
+		"checkout_latency_seconds": "r-1",
+		"kube_pod_info":            "kube_pod_info",
+		"api_requests":             "api_requests",
+		"api_requests_2":           "api:requests",
+	}, importIDs)
+	require.Equal(t, `import {
+  to = grafana-adaptive-metrics_rule.checkout_latency_seconds
+  id = "r-1"
+}
+
+resource "grafana-adaptive-metrics_rule" "checkout_latency_seconds" {
+  name                  = "checkout_latency"
+  metric                = "checkout_latency_seconds"
+  match_type            = "prefix"
+  drop_labels           = ["pod*"]
+  label_match_mode      = "glob"
+  aggregations          = ["sum", "count"]
+  aggregation_interval  = "1m"
+  aggregation_intervals = { "count" = "5m" }
+  aggregation_delay     = "2m"
+  priority              = 3
+  rollout_percentage    = 50
+  tags                  = { "team" = "payments" }
+
+  delay_overrides {
+    label    = "source"
+    operator = "!="
+    value    = "edge"
+    delay    = "10m"
+  }
+}
+
+import {
+  to = grafana-adaptive-metrics_rule.kube_pod_info
+  id = "kube_pod_info"
+}
+
+resource "grafana-adaptive-metrics_rule" "kube_pod_info" {
+  metric      = "kube_pod_info"
+  drop        = true
+  keep_labels = ["namespace"]
+
+  drop_if {
+    label = "env"
+    value = "test"
+  }
+}
+
+import {
+  to = grafana-adaptive-metrics_rule.api_requests
+  id = "api_requests"
+}
+
+resource "grafana-adaptive-metrics_rule" "api_requests" {
+  name           = "api_requests"
+  match_prefixes = ["api.requests", "api.errors"]
+  aggregations   = ["sum:counter"]
+}
+
+import {
+  to = grafana-adaptive-metrics_rule.api_requests_2
+  id = "api:requests"
+}
+
+resource "grafana-adaptive-metrics_rule" "api_requests_2" {
+  metric       = "api:requests"
+  aggregations = ["sum"]
+}
+`, hcl)
+}
+
+func TestRulesImportConfigFunctionErrors(t *testing.T) {
+	_, _, funcErr := runRulesImportConfig(t, `[{"metric": "a", "aggregation": ["sum"]}]`)
+	require.ErrorContains(t, funcErr, `unable to parse the exported rules: json: unknown field "aggregation"`)
+
+	// Without IDs, both rules would be imported by their metric.
+	_, _, funcErr = runRulesImportConfig(t, `[{"metric": "a", "drop": true}, {"metric": "a", "match_type": "prefix", "drop": true}]`)
+	require.ErrorContains(t, funcErr, `exported rules for "a" and "a" would both be imported by the ID "a"`)
+}
+
+// TestRulesImportConfigRoundTrip imports the rules of an export by the
+// generated import IDs, and checks that each resource reads the rule it was
+// generated for.
+func TestRulesImportConfigRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	rules, err := model.ParseRulesExport([]byte(exportedRules))
+	require.NoError(t, err)
+	api := newMockAPI(t, rules...)
+	api.features = []string{string(client.FeatureRuleNames), string(client.FeatureMatchPrefixes), string(client.FeatureDelayOverrides)}
+	aggRules := api.aggregationRules()
+	aggRules.capabilities = newCapabilities(api.client())
+	r := &ruleResource{rules: aggRules, capabilities: aggRules.capabilities}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+
+	importIDs, _, funcErr := runRulesImportConfig(t, exportedRules)
+	require.Nil(t, funcErr)
+	names := []string{"checkout_latency_seconds", "kube_pod_info", "api_requests", "api_requests_2"}
+
+	for i, rule := range rules {
+		importResp := &fwresource.ImportStateResponse{State: tfsdk.State{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+		}}
+		r.ImportState(ctx, fwresource.ImportStateRequest{ID: importIDs[names[i]]}, importResp)
+		require.False(t, importResp.Diagnostics.HasError(), importResp.Diagnostics)
+
+		readResp := &fwresource.ReadResponse{State: importResp.State}
+		r.Read(ctx, fwresource.ReadRequest{State: importResp.State}, readResp)
+		require.False(t, readResp.Diagnostics.HasError(), readResp.Diagnostics)
+
+		var tf model.RuleTF
+		require.False(t, readResp.State.Get(ctx, &tf).HasError())
+		require.Empty(t, tf.ToAPIReq().Differences(rule), names[i])
+	}
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type rulesRestoreResource struct {
+	rules *AggregationRules
+
+	requiredKeepLabels    []string
+	allowedMetricPatterns []string
+	savings               *savingsBudget
+}
+
+var (
+	_ resource.Resource               = &rulesRestoreResource{}
+	_ resource.ResourceWithConfigure  = &rulesRestoreResource{}
+	_ resource.ResourceWithModifyPlan = &rulesRestoreResource{}
+)
+
+func newRulesRestoreResource() resource.Resource {
+	return &rulesRestoreResource{}
+}
+
+func (r *rulesRestoreResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected resource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	r.rules = data.aggRules
+	r.requiredKeepLabels = data.requiredKeepLabels
+	r.allowedMetricPatterns = data.allowedMetricPatterns
+	r.savings = data.savings
+}
+
+func (r *rulesRestoreResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rules_restore", req.ProviderTypeName)
+}
+
+func (r *rulesRestoreResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Restores every aggregation rule within the provider's `metric_prefix` to a snapshot exported by the `rules_snapshot` data source, such as after a bad change: the rules missing from the live rule set are created, the ones which differ are updated, and every rule which is not part of the snapshot is deleted, whether it is managed by Terraform or not.\n\n" +
+			"The plan previews the changes in `created`, `updated`, `deleted`, and `changes_json`, and nothing is changed unless `confirm` is set to true, so the resource can be applied without it as a dry run. The changes are all or nothing: once one of them fails, the ones already made are rolled back. The rules are restored once, when the resource is created or its snapshot or confirmation changes; destroying the resource does not undo the restore. Use `replace_triggered_by` to restore the same snapshot again.",
+		Attributes: map[string]schema.Attribute{
+			"snapshot_json": schema.StringAttribute{
+				Required:    true,
+				Description: "The rules to restore, as the json of the rules_snapshot data source or a JSON array of rules in the format of the API, for example from file. The IDs and timestamps of the rules are ignored.",
+			},
+			"confirm": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Set to true to make the changes. Otherwise they are only planned and reported, and no rule is changed.",
+			},
+
+			"created": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics of the rules of the snapshot which do not exist and are created, sorted by name.",
+			},
+			"updated": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics of the existing rules which differ from the snapshot and are updated, sorted by name.",
+			},
+			"deleted": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The metrics of the existing rules which are not part of the snapshot and are deleted, sorted by name.",
+			},
+			"changes_json": schema.StringAttribute{
+				Computed:    true,
+				Description: "The changes with the fields of each rule that change, as indented JSON in the format of the change_report data source.",
+			},
+			"restored": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the changes have been made, which is only the case if confirm is set.",
+			},
+		},
+	}
+}
+
+func (r *rulesRestoreResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if r.rules == nil || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan model.RulesRestoreTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.SnapshotJSON.IsUnknown() || plan.Confirm.IsUnknown() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state model.RulesRestoreTF
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		// The snapshot has been restored, or previewed, already.
+		if state.SnapshotJSON.Equal(plan.SnapshotJSON) && state.Confirm.Equal(plan.Confirm) {
+			return
+		}
+	}
+
+	changes, diags := r.changes(plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only the rules which are written must comply with the policies.
+	var policy diag.Diagnostics
+	for _, rule := range append(slices.Clone(changes.Create), changes.Update...) {
+		policy.Append(validateRequiredKeepLabels(rule, r.requiredKeepLabels, path.Empty())...)
+		policy.Append(validateAllowedMetric(rule, r.allowedMetricPatterns, path.Empty())...)
+	}
+	for _, d := range policy {
+		resp.Diagnostics.AddAttributeError(path.Root("snapshot_json"), d.Summary(), d.Detail())
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only a confirmed restore changes the rules.
+	if plan.Confirm.ValueBool() {
+		resp.Diagnostics.Append(r.savings.addChanges(ctx, r.rules, changes, path.Root("snapshot_json"))...)
+	}
+
+	resp.Diagnostics.Append(setRulesRestore(ctx, &plan, changes, r.rules.List())...)
+	plan.Restored = types.BoolValue(plan.Confirm.ValueBool())
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+
+	switch {
+	case changes.empty():
+	case !plan.Confirm.ValueBool():
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("confirm"),
+			"Aggregation rules will not be restored",
+			fmt.Sprintf("Restoring the snapshot would create %d, update %d, and delete %d rules, but confirm is not set, so no rule is changed. Review the changes and set confirm to true to make them.", len(changes.Create), len(changes.Update), len(changes.Delete)),
+		)
+	case len(changes.Delete) > 0:
+		deleted := make([]string, len(changes.Delete))
+		for i, rule := range changes.Delete {
+			deleted[i] = rule.Metric
+		}
+		resp.Diagnostics.AddWarning(
+			"Aggregation rules will be deleted",
+			fmt.Sprintf("The following aggregation rules are not part of the snapshot and will be deleted: %s", strings.Join(deleted, ", ")),
+		)
+	}
+}
+
+func (r *rulesRestoreResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan model.RulesRestoreTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.restore(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *rulesRestoreResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+	// The restore is kept as it was made; the rules may have changed since.
+}
+
+func (r *rulesRestoreResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan model.RulesRestoreTF
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.restore(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *rulesRestoreResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// The restore is not undone.
+}
+
+// restore makes the changes restoring the snapshot of plan, if confirmed, and
+// records them in plan. The changes are computed again from the current rules,
+// and nothing is changed if they differ from the planned ones.
+func (r *rulesRestoreResource) restore(ctx context.Context, plan *model.RulesRestoreTF) diag.Diagnostics {
+	changes, diags := r.changes(*plan)
+	if diags.HasError() {
+		return diags
+	}
+
+	for _, planned := range []struct {
+		list  types.List
+		rules []model.AggregationRule
+	}{
+		{plan.Created, changes.Create},
+		{plan.Updated, changes.Update},
+		{plan.Deleted, changes.Delete},
+	} {
+		if planned.list.IsUnknown() {
+			continue
+		}
+		var metrics []types.String
+		diags.Append(planned.list.ElementsAs(ctx, &metrics, false)...)
+		if diags.HasError() {
+			return diags
+		}
+		if !slices.Equal(metrics, ruleMetrics(planned.rules)) {
+			diags.AddError(
+				"Aggregation rules changed since plan",
+				"The rules changed after the plan was created, so the snapshot has not been restored. Run the plan again to review the changes.",
+			)
+			return diags
+		}
+	}
+
+	diags.Append(setRulesRestore(ctx, plan, changes, r.rules.List())...)
+	plan.Restored = types.BoolValue(plan.Confirm.ValueBool())
+	if !plan.Confirm.ValueBool() || changes.empty() {
+		return diags
+	}
+
+	if err := r.rules.WithContext(ctx).WithWarnings(apiWarnings(&diags)).ApplyTransaction(changes); err != nil {
+		diags.AddError("Unable to restore aggregation rules", err.Error())
+	}
+	return diags
+}
+
+// changes computes the changes which make the current rules match the
+// snapshot of tf exactly. Every current rule counts as previously managed, so
+// that the rules managed by other resources are deleted too.
+func (r *rulesRestoreResource) changes(tf model.RulesRestoreTF) (ruleSetChanges, diag.Diagnostics) {
+	desired, diags := parseDesiredRules(r.rules, tf.SnapshotJSON.ValueString(), path.Root("snapshot_json"))
+	if diags.HasError() {
+		return ruleSetChanges{}, diags
+	}
+	current := r.rules.List()
+	return diffRuleSet(current, current, desired, false), diags
+}
+
+func setRulesRestore(ctx context.Context, tf *model.RulesRestoreTF, changes ruleSetChanges, current []model.AggregationRule) diag.Diagnostics {
+	var diags, d diag.Diagnostics
+	tf.Created, d = types.ListValueFrom(ctx, types.StringType, ruleMetrics(changes.Create))
+	diags.Append(d...)
+	tf.Updated, d = types.ListValueFrom(ctx, types.StringType, ruleMetrics(changes.Update))
+	diags.Append(d...)
+	tf.Deleted, d = types.ListValueFrom(ctx, types.StringType, ruleMetrics(changes.Delete))
+	diags.Append(d...)
+
+	report, err := changeReport(changes, current)
+	if err == nil {
+		var data []byte
+		data, err = json.MarshalIndent(report, "", "  ")
+		tf.ChangesJSON = types.StringValue(string(data))
+	}
+	if err != nil {
+		diags.AddError("Unable to build change report", err.Error())
+	}
+	return diags
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func newTestRulesRestoreResource(t *testing.T, api *mockAPI) (*rulesRestoreResource, tfsdk.State) {
+	t.Helper()
+
+	r := &rulesRestoreResource{rules: api.aggregationRules()}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(context.Background(), fwresource.SchemaRequest{}, &schemaResp)
+	return r, tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(context.Background()), nil),
+	}
+}
+
+func rulesRestoreConfig(t *testing.T, confirm bool, rules ...model.AggregationRule) model.RulesRestoreTF {
+	snapshot, err := json.Marshal(model.RulesSnapshot{TakenAt: "2026-10-01T12:00:00Z", Rules: rules})
+	require.NoError(t, err)
+
+	return model.RulesRestoreTF{
+		SnapshotJSON: types.StringValue(string(snapshot)),
+		Confirm:      types.BoolValue(confirm),
+		Created:      types.ListUnknown(types.StringType),
+		Updated:      types.ListUnknown(types.StringType),
+		Deleted:      types.ListUnknown(types.StringType),
+		ChangesJSON:  types.StringUnknown(),
+		Restored:     types.BoolUnknown(),
+	}
+}
+
+// restoreMetrics returns the metrics of a list attribute of a restore.
+func restoreMetrics(t *testing.T, list types.List) []string {
+	var metrics []string
+	require.False(t, list.ElementsAs(context.Background(), &metrics, false).HasError())
+	return metrics
+}
+
+func TestRulesRestoreResource(t *testing.T) {
+	ctx := context.Background()
+	// The live rules diverged from the snapshot: api_requests_total was
+	// changed, api_errors_total deleted, and an unmanaged rule added.
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "api_requests_total", DropLabels: []string{"pod", "instance"}, ManagedBy: "terraform"},
+		model.AggregationRule{Metric: "api_latency_seconds", Drop: true, ManagedBy: "terraform"},
+		model.AggregationRule{Metric: "api_unmanaged_total", Drop: true},
+	)
+	r, empty := newTestRulesRestoreResource(t, api)
+
+	snapshot := []model.AggregationRule{
+		{ID: "1", Metric: "api_errors_total", Aggregations: []string{"count"}, ManagedBy: "terraform", CreatedAt: "2026-09-01T00:00:00Z"},
+		{ID: "2", Metric: "api_latency_seconds", Drop: true, ManagedBy: "terraform"},
+		{ID: "3", Metric: "api_requests_total", DropLabels: []string{"pod"}, ManagedBy: "terraform"},
+	}
+
+	// Without confirm the changes are only previewed.
+	resp := modifyPlan(t, r, nil, rulesRestoreConfig(t, false, snapshot...))
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Aggregation rules will not be restored", resp.Diagnostics.Warnings()[0].Summary())
+
+	var planned model.RulesRestoreTF
+	require.False(t, resp.Plan.Get(ctx, &planned).HasError())
+	require.Equal(t, []string{"api_errors_total"}, restoreMetrics(t, planned.Created))
+	require.Equal(t, []string{"api_requests_total"}, restoreMetrics(t, planned.Updated))
+	require.Equal(t, []string{"api_unmanaged_total"}, restoreMetrics(t, planned.Deleted))
+	require.False(t, planned.Restored.ValueBool())
+
+	var report model.ChangeReport
+	require.NoError(t, json.Unmarshal([]byte(planned.ChangesJSON.ValueString()), &report))
+	require.Equal(t, model.ChangeSummary{Create: 1, Update: 1, Delete: 1}, report.Summary)
+
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: resp.Plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+	require.Equal(t, []string{"api_latency_seconds", "api_requests_total", "api_unmanaged_total"}, api.metrics())
+
+	var state model.RulesRestoreTF
+	require.False(t, createResp.State.Get(ctx, &state).HasError())
+
+	// Once confirmed, the live rules are made to match the snapshot.
+	confirmed := rulesRestoreConfig(t, true, snapshot...)
+	resp = modifyPlan(t, r, state, confirmed)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 1)
+	require.Equal(t, "Aggregation rules will be deleted", resp.Diagnostics.Warnings()[0].Summary())
+	require.Contains(t, resp.Diagnostics.Warnings()[0].Detail(), "api_unmanaged_total")
+
+	updateResp := &fwresource.UpdateResponse{State: createResp.State}
+	r.Update(ctx, fwresource.UpdateRequest{Plan: resp.Plan, State: createResp.State}, updateResp)
+	require.False(t, updateResp.Diagnostics.HasError(), updateResp.Diagnostics)
+
+	require.Equal(t, []string{"api_errors_total", "api_latency_seconds", "api_requests_total"}, api.metrics())
+	got, _ := api.rule("api_requests_total")
+	require.Equal(t, []string{"pod"}, got.DropLabels)
+	got, _ = api.rule("api_errors_total")
+	require.Equal(t, model.Aggregations{"count"}, got.Aggregations)
+	require.NotEqual(t, "1", got.ID, "the IDs of the snapshot are ignored")
+
+	require.False(t, updateResp.State.Get(ctx, &state).HasError())
+	require.True(t, state.Restored.ValueBool())
+
+	// Planning again keeps the restore in state, although the rules now
+	// match the snapshot.
+	resp = modifyPlan(t, r, state, state)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	var replanned model.RulesRestoreTF
+	require.False(t, resp.Plan.Get(ctx, &replanned).HasError())
+	require.Equal(t, state, replanned)
+
+	// Restoring the snapshot again changes nothing.
+	resp = modifyPlan(t, r, nil, confirmed)
+	require.Empty(t, resp.Diagnostics)
+	require.False(t, resp.Plan.Get(ctx, &planned).HasError())
+	require.Empty(t, restoreMetrics(t, planned.Created))
+	require.Empty(t, restoreMetrics(t, planned.Updated))
+	require.Empty(t, restoreMetrics(t, planned.Deleted))
+}
+
+func TestRulesRestoreResourceChangedSincePlan(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "api_requests_total", ManagedBy: "terraform"},
+	)
+	r, empty := newTestRulesRestoreResource(t, api)
+
+	resp := modifyPlan(t, r, nil, rulesRestoreConfig(t, true, model.AggregationRule{Metric: "api_requests_total", ManagedBy: "terraform"}))
+	require.Empty(t, resp.Diagnostics)
+
+	require.NoError(t, api.aggregationRules().Create(model.AggregationRule{Metric: "api_errors_total", ManagedBy: "terraform"}))
+	r.rules = api.aggregationRules()
+
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: resp.Plan}, createResp)
+	require.True(t, createResp.Diagnostics.HasError())
+	require.Equal(t, "Aggregation rules changed since plan", createResp.Diagnostics.Errors()[0].Summary())
+	require.Equal(t, []string{"api_errors_total", "api_requests_total"}, api.metrics())
+}
+
+func TestRulesRestoreResourceInvalidSnapshot(t *testing.T) {
+	api := newMockAPI(t)
+	r, _ := newTestRulesRestoreResource(t, api)
+
+	tf := rulesRestoreConfig(t, true)
+	tf.SnapshotJSON = types.StringValue(`{"taken_at": "2026-10-01T12:00:00Z", "rules": [{"metric": "a", "dorp": true}]}`)
+	resp := modifyPlan(t, r, nil, tf)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Equal(t, []path.Path{path.Root("snapshot_json")}, errorPaths(resp.Diagnostics))
+}
+
+func TestRulesRestoreResourcePolicy(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "team_b_up", Drop: true},
+	)
+	r, _ := newTestRulesRestoreResource(t, api)
+	r.allowedMetricPatterns = []string{"team_a_.*"}
+
+	// The rule which is only deleted is not checked.
+	resp := modifyPlan(t, r, nil, rulesRestoreConfig(t, true, model.AggregationRule{Metric: "team_a_up", Drop: true}))
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	resp = modifyPlan(t, r, nil, rulesRestoreConfig(t, true, model.AggregationRule{Metric: "team_b_up", DropLabels: []string{"pod"}}))
+	require.Equal(t, []path.Path{path.Root("snapshot_json")}, errorPaths(resp.Diagnostics))
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), "team_b_up")
+}
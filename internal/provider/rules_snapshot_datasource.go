@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+type rulesSnapshotDatasource struct {
+	rules *AggregationRules
+}
+
+var (
+	_ datasource.DataSource              = &rulesSnapshotDatasource{}
+	_ datasource.DataSourceWithConfigure = &rulesSnapshotDatasource{}
+)
+
+func newRulesSnapshotDatasource() datasource.DataSource {
+	return &rulesSnapshotDatasource{}
+}
+
+func (d *rulesSnapshotDatasource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected datasource configure type",
+			fmt.Sprintf("Got %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+
+	d.rules = data.aggRules
+}
+
+func (d *rulesSnapshotDatasource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = fmt.Sprintf("%s_rules_snapshot", req.ProviderTypeName)
+}
+
+func (d *rulesSnapshotDatasource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Exports every aggregation rule within the provider's `metric_prefix` as a JSON snapshot, for example to store it as a backup with the `local_file` resource. Nothing is changed.\n\n" +
+			"The snapshot holds the time it was taken and the rules in the format of the API, including the IDs and timestamps set by the API. It can be restored with the `rules_file` resource, which ignores the IDs and timestamps and creates or updates the rules as needed. The `rules_restore` resource restores it exactly, deleting every rule which is not part of it as well. The rules are read again on every plan, so the snapshot changes whenever a rule does.",
+		Attributes: map[string]schema.Attribute{
+			"taken_at": schema.StringAttribute{
+				Computed:    true,
+				Description: "The time the snapshot was taken, as an RFC 3339 timestamp.",
+			},
+			"rule_count": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The number of rules in the snapshot.",
+			},
+			"json": schema.StringAttribute{
+				Computed:    true,
+				Description: "The snapshot as indented JSON, with the rules ordered by metric.",
+			},
+		},
+	}
+}
+
+func (d *rulesSnapshotDatasource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	snapshot := model.RulesSnapshot{
+		TakenAt: time.Now().UTC().Format(time.RFC3339),
+		Rules:   d.rules.List(),
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to encode rules snapshot", err.Error())
+		return
+	}
+
+	state := model.RulesSnapshotTF{
+		TakenAt:   types.StringValue(snapshot.TakenAt),
+		RuleCount: types.Int64Value(int64(len(snapshot.Rules))),
+		JSON:      types.StringValue(string(data)),
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
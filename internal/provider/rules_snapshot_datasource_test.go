@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestRulesSnapshotDatasource(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t,
+		model.AggregationRule{ID: "2", Metric: "prod_b", Drop: true, CreatedAt: "2026-01-02T03:04:05Z", UpdatedAt: "2026-02-03T04:05:06Z"},
+		model.AggregationRule{ID: "1", Metric: "prod_a", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "staging_a", Drop: true},
+	)
+	rules := NewAggregationRules(api.client(), "prod_")
+	require.NoError(t, rules.Init())
+	d := &rulesSnapshotDatasource{rules: rules}
+
+	var schemaResp datasource.SchemaResponse
+	d.Schema(ctx, datasource.SchemaRequest{}, &schemaResp)
+	resp := &datasource.ReadResponse{State: tfsdk.State{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+	}}
+	d.Read(ctx, datasource.ReadRequest{}, resp)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	var tf model.RulesSnapshotTF
+	require.False(t, resp.State.Get(ctx, &tf).HasError())
+	require.Equal(t, int64(2), tf.RuleCount.ValueInt64())
+	_, err := time.Parse(time.RFC3339, tf.TakenAt.ValueString())
+	require.NoError(t, err)
+
+	// The snapshot keeps the metadata set by the API.
+	var snapshot model.RulesSnapshot
+	require.NoError(t, json.Unmarshal([]byte(tf.JSON.ValueString()), &snapshot))
+	require.Equal(t, tf.TakenAt.ValueString(), snapshot.TakenAt)
+	require.Len(t, snapshot.Rules, 2)
+	require.Equal(t, "a", snapshot.Rules[0].Metric)
+	require.Equal(t, "1", snapshot.Rules[0].ID)
+	require.Equal(t, "b", snapshot.Rules[1].Metric)
+	require.Equal(t, "2", snapshot.Rules[1].ID)
+	require.Equal(t, "2026-01-02T03:04:05Z", snapshot.Rules[1].CreatedAt)
+	require.Equal(t, "2026-02-03T04:05:06Z", snapshot.Rules[1].UpdatedAt)
+
+	// It can be read back as a rules file, without the metadata.
+	restored, err := model.ParseRulesFile([]byte(tf.JSON.ValueString()))
+	require.NoError(t, err)
+	require.Len(t, restored, 2)
+	require.Empty(t, restored[1].ID)
+	require.Empty(t, restored[1].CreatedAt)
+	require.True(t, restored[1].Equal(snapshot.Rules[1]))
+}
@@ -0,0 +1,362 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestAggregationRulesRename(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "old_metric", Aggregations: []string{"sum"}})
+	rules := api.aggregationRules()
+
+	err := rules.Rename(model.AggregationRule{Metric: "old_metric"}, model.AggregationRule{Metric: "new_metric", Aggregations: []string{"sum"}})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"new_metric"}, api.metrics())
+	require.Equal(t, []string{
+		"GET /aggregations/rules",
+		"POST /aggregations/rule/new_metric",
+		"DELETE /aggregations/rule/old_metric",
+	}, api.requestLog())
+
+	_, err = rules.Read("old_metric")
+	require.Error(t, err)
+	rule, err := rules.Read("new_metric")
+	require.NoError(t, err)
+	require.Equal(t, model.Aggregations{"sum"}, rule.Aggregations)
+}
+
+func TestAggregationRulesRenameCreateFails(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "old_metric"})
+	rules := api.aggregationRules()
+
+	api.failOn("POST", "/aggregations/rule/new_metric", http.StatusBadRequest)
+
+	err := rules.Rename(model.AggregationRule{Metric: "old_metric"}, model.AggregationRule{Metric: "new_metric"})
+	require.Error(t, err)
+
+	require.Equal(t, []string{"old_metric"}, api.metrics())
+	_, err = rules.Read("old_metric")
+	require.NoError(t, err)
+}
+
+func TestAggregationRulesRenameRollback(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "old_metric"})
+	rules := api.aggregationRules()
+
+	api.failOn("DELETE", "/aggregations/rule/old_metric", http.StatusInternalServerError)
+
+	err := rules.Rename(model.AggregationRule{Metric: "old_metric"}, model.AggregationRule{Metric: "new_metric"})
+	require.ErrorContains(t, err, "rolled back")
+
+	require.Equal(t, []string{"old_metric"}, api.metrics())
+	require.Equal(t, []string{
+		"GET /aggregations/rules",
+		"POST /aggregations/rule/new_metric",
+		"DELETE /aggregations/rule/old_metric",
+		"DELETE /aggregations/rule/new_metric",
+	}, api.requestLog())
+
+	_, err = rules.Read("old_metric")
+	require.NoError(t, err)
+	_, err = rules.Read("new_metric")
+	require.Error(t, err)
+}
+
+func TestAggregationRulesExists(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "existing_metric"})
+	rules := api.aggregationRules()
+
+	exists, err := rules.Exists("existing_metric")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	exists, err = rules.Exists("missing_metric")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	api.failOn("GET", "/aggregations/rule/existing_metric", http.StatusInternalServerError)
+	exists, err = rules.Exists("existing_metric")
+	require.Error(t, err)
+	require.False(t, exists)
+}
+
+func TestAggregationRulesExistsRefreshesCache(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "deleted_elsewhere"})
+	rules := api.aggregationRules()
+
+	// Change the rules behind the back of the cached rule set.
+	other := api.aggregationRules()
+	require.NoError(t, other.Delete(model.AggregationRule{Metric: "deleted_elsewhere"}))
+	require.NoError(t, other.Create(model.AggregationRule{Metric: "created_elsewhere"}))
+
+	exists, err := rules.Exists("deleted_elsewhere")
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = rules.Read("deleted_elsewhere")
+	require.Error(t, err)
+
+	exists, err = rules.Exists("created_elsewhere")
+	require.NoError(t, err)
+	require.True(t, exists)
+	_, err = rules.Read("created_elsewhere")
+	require.NoError(t, err)
+}
+
+func TestAggregationRulesMetricPrefix(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{Metric: "prod_requests_total", Aggregations: []string{"sum:counter"}},
+		model.AggregationRule{Metric: "staging_requests_total"},
+	)
+	rules := NewAggregationRules(api.client(), "prod_")
+	require.NoError(t, rules.Init())
+
+	// Rules are read without the prefix, and rules outside of it are ignored.
+	require.Equal(t, []string{"requests_total"}, metricsOf(rules.List()))
+	rule, err := rules.Read("requests_total")
+	require.NoError(t, err)
+	require.Equal(t, "requests_total", rule.Metric)
+	require.Equal(t, model.Aggregations{"sum:counter"}, rule.Aggregations)
+	_, err = rules.Read("prod_requests_total")
+	require.Error(t, err)
+
+	exists, err := rules.Exists("requests_total")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	// Rules are written with the prefix.
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "errors_total"}))
+	require.NoError(t, rules.Update(model.AggregationRule{Metric: "errors_total", Drop: true}))
+	updated, ok := api.rule("prod_errors_total")
+	require.True(t, ok)
+	require.True(t, updated.Drop)
+	require.NoError(t, rules.Rename(model.AggregationRule{Metric: "errors_total"}, model.AggregationRule{Metric: "failures_total"}))
+	require.NoError(t, rules.Delete(model.AggregationRule{Metric: "requests_total"}))
+
+	require.Equal(t, []string{"prod_failures_total", "staging_requests_total"}, api.metrics())
+	require.Equal(t, []string{"failures_total"}, metricsOf(rules.List()))
+}
+
+func TestAggregationRulesMetricPrefixRejectsSuffixRules(t *testing.T) {
+	api := newMockAPI(t)
+	rules := NewAggregationRules(api.client(), "prod_")
+	require.NoError(t, rules.Init())
+
+	err := rules.Create(model.AggregationRule{Metric: "_total", MatchType: "suffix"})
+	require.ErrorContains(t, err, "metric_prefix")
+	require.Empty(t, api.metrics())
+}
+
+func TestAggregationRulesByID(t *testing.T) {
+	api := newMockAPI(t,
+		model.AggregationRule{ID: "r-1", Metric: "old_metric", Aggregations: []string{"sum"}},
+		model.AggregationRule{Metric: "other_metric"},
+	)
+	rules := api.aggregationRules()
+
+	rule, err := rules.ReadByID("r-1")
+	require.NoError(t, err)
+	require.Equal(t, "old_metric", rule.Metric)
+	_, err = rules.ReadByID("r-2")
+	require.Error(t, err)
+
+	// A rule with an ID is renamed in place.
+	require.NoError(t, rules.Update(model.AggregationRule{ID: "r-1", Metric: "new_metric", Aggregations: []string{"sum"}}))
+	require.Equal(t, []string{"new_metric", "other_metric"}, api.metrics())
+	require.Equal(t, []string{"new_metric", "other_metric"}, []string{rules.List()[0].Metric, rules.List()[1].Metric})
+
+	// Rules are deleted by ID when they have one and by metric otherwise.
+	require.NoError(t, rules.Delete(model.AggregationRule{Metric: "new_metric"}))
+	require.NoError(t, rules.Delete(model.AggregationRule{Metric: "other_metric"}))
+	require.Empty(t, api.metrics())
+	require.Equal(t, []string{
+		"GET /aggregations/rules",
+		"PUT /aggregations/rules/r-1",
+		"DELETE /aggregations/rules/r-1",
+		"DELETE /aggregations/rule/other_metric",
+	}, api.requestLog())
+}
+
+func TestAggregationRulesPartialUpdate(t *testing.T) {
+	serverSide := model.AggregationRule{
+		ID:           "r-1",
+		Metric:       "patched_metric",
+		Aggregations: []string{"sum"},
+		KeepLabels:   []string{"job"},
+		ManagedBy:    "terraform",
+		Ingest:       true,
+		CreatedAt:    "2026-01-02T03:04:05Z",
+	}
+
+	t.Run("patch", func(t *testing.T) {
+		api := newMockAPI(t, serverSide)
+		api.features = []string{string(client.FeaturePartialUpdates)}
+		rules := api.aggregationRules()
+		rules.capabilities = newCapabilities(api.client())
+
+		rule := serverSide
+		rule.Ingest, rule.CreatedAt = false, ""
+		rule.Aggregations = []string{"sum", "count"}
+		rule.KeepLabels = nil
+		require.NoError(t, rules.Update(rule))
+
+		// Only the changed fields are sent, so the fields which the provider
+		// does not manage survive, and removed ones are cleared.
+		updated, _ := api.rule("patched_metric")
+		require.Equal(t, []string{"sum", "count"}, []string(updated.Aggregations))
+		require.Nil(t, updated.KeepLabels)
+		require.True(t, updated.Ingest)
+		require.Equal(t, "2026-01-02T03:04:05Z", updated.CreatedAt)
+
+		// Renames by ID are partial updates too.
+		rule.Metric = "renamed_metric"
+		require.NoError(t, rules.Update(rule))
+		require.Equal(t, []string{"renamed_metric"}, api.metrics())
+		renamed, _ := api.rule("renamed_metric")
+		require.True(t, renamed.Ingest)
+
+		require.Equal(t, []string{
+			"GET /aggregations/rules",
+			"GET /aggregations/server_info",
+			"PATCH /aggregations/rules/r-1",
+			"PATCH /aggregations/rules/r-1",
+		}, api.requestLog())
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		api := newMockAPI(t, serverSide)
+		api.features = []string{}
+		rules := api.aggregationRules()
+		rules.capabilities = newCapabilities(api.client())
+
+		rule := serverSide
+		rule.Ingest = false
+		require.NoError(t, rules.Update(rule))
+
+		// Backends without partial updates have the rule replaced.
+		replaced, _ := api.rule("patched_metric")
+		require.False(t, replaced.Ingest)
+		require.Equal(t, []string{
+			"GET /aggregations/rules",
+			"GET /aggregations/server_info",
+			"PUT /aggregations/rules/r-1",
+		}, api.requestLog())
+	})
+}
+
+func TestAggregationRulesReadCache(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "cached_metric"})
+	rules := api.aggregationRules()
+
+	// Rules loaded by Init are read without a request.
+	for i := 0; i < 3; i++ {
+		_, err := rules.Read("cached_metric")
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{"GET /aggregations/rules"}, api.requestLog())
+
+	// A rule created since the cache was loaded is requested once.
+	api.mu.Lock()
+	api.rules["new_metric"] = model.AggregationRule{Metric: "new_metric"}
+	api.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		_, err := rules.Read("new_metric")
+		require.NoError(t, err)
+	}
+
+	_, err := rules.Read("missing_metric")
+	require.ErrorAs(t, err, &errRuleNotFound{})
+
+	api.failOn("GET", "/aggregations/rule/failing_metric", http.StatusInternalServerError)
+	_, err = rules.Read("failing_metric")
+	require.Error(t, err)
+	require.False(t, errors.As(err, &errRuleNotFound{}))
+
+	require.Equal(t, []string{
+		"GET /aggregations/rules",
+		"GET /aggregations/rule/new_metric",
+		"GET /aggregations/rule/missing_metric",
+		"GET /aggregations/rule/failing_metric",
+	}, api.requestLog())
+}
+
+func TestAggregationRulesReloadOnConflict(t *testing.T) {
+	api := newMockAPI(t, model.AggregationRule{Metric: "a"})
+	rules := api.aggregationRules()
+
+	// Another client changes the rules, which invalidates the cached etag.
+	api.rename("a", "b")
+
+	err := rules.Create(model.AggregationRule{Metric: "c"})
+	require.ErrorContains(t, err, "reloaded")
+	require.Equal(t, []string{"b"}, metricsOf(rules.List()))
+
+	require.NoError(t, rules.Create(model.AggregationRule{Metric: "c"}))
+	require.Equal(t, []string{"b", "c"}, api.metrics())
+}
+
+func TestAggregationRulesFieldSelection(t *testing.T) {
+	rollout := int64(50)
+	serverSide := model.AggregationRule{
+		ID:                   "r-1",
+		Name:                 "checkout",
+		Metric:               "selected_metric",
+		MatchType:            "prefix",
+		DropLabels:           []string{"pod"},
+		Aggregations:         []string{"sum", "count"},
+		AggregationInterval:  "1m",
+		AggregationIntervals: map[string]string{"count": "5m"},
+		RolloutPercentage:    &rollout,
+		Tags:                 map[string]string{"team": "payments"},
+		ManagedBy:            "terraform",
+		CreatedAt:            "2026-01-02T03:04:05Z",
+	}
+
+	for _, tc := range []struct {
+		name      string
+		features  []string
+		supported bool
+	}{
+		{name: "supported", features: []string{string(client.FeatureFieldSelection)}, supported: true},
+		{name: "unsupported", features: []string{string(client.FeatureRuleNames)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newMockAPI(t, serverSide)
+			api.features = tc.features
+			api.ruleMetadata = map[string]string{"selected_metric": strings.Repeat("x", 1024), "new_metric": "y"}
+
+			rules := NewAggregationRules(api.client(), "")
+			rules.capabilities = newCapabilities(api.client())
+			require.NoError(t, rules.Init())
+
+			// Rules loaded by Init and rules requested since are mapped in
+			// the same way with or without field selection.
+			rule, err := rules.Read("selected_metric")
+			require.NoError(t, err)
+			require.Equal(t, serverSide, rule)
+
+			api.mu.Lock()
+			api.rules["new_metric"] = model.AggregationRule{Metric: "new_metric", Drop: true}
+			api.mu.Unlock()
+			rule, err = rules.Read("new_metric")
+			require.NoError(t, err)
+			require.Equal(t, model.AggregationRule{Metric: "new_metric", Drop: true}, rule)
+
+			// Both the list and the rule are requested with the modeled
+			// fields only, leaving out the metadata.
+			var expected []string
+			if tc.supported {
+				fields := strings.Join(model.AggregationRuleFields(), ",")
+				expected = []string{fields, fields}
+			}
+			require.Equal(t, expected, api.fieldSelections)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+// savingsBudget limits how much the planned changes of aggregation rules may
+// reduce the number of series which the rules save in total, so that cost
+// optimizations are not undone by accident, such as by deleting many rules at
+// once. Terraform plans every resource separately, so their reductions are
+// added up as they are planned. Once the total exceeds the limit, every
+// resource whose planned changes reduce the savings further is reported, so
+// that the plan is reported whichever order the resources are planned in.
+// Increases in the savings of other rules do not offset reductions, as they
+// may be planned later. A nil savingsBudget has no limit.
+type savingsBudget struct {
+	limit int64
+	warn  bool
+
+	mu sync.Mutex
+	// reductions are keyed by the metric of the rule as it was before the
+	// change, so that a rule which is planned again is not counted twice.
+	reductions map[string]int64
+}
+
+func newSavingsBudget(limit int64, warn bool) *savingsBudget {
+	return &savingsBudget{limit: limit, warn: warn, reductions: make(map[string]int64)}
+}
+
+// add records that the planned change of the rule for metric reduces the saved
+// series by reduction, which is negative if they increase instead, and reports
+// against attr whether the total reduction exceeds the limit.
+func (b *savingsBudget) add(metric string, reduction int64, attr path.Path) diag.Diagnostics {
+	return b.addAll(map[string]int64{metric: reduction}, attr)
+}
+
+// addAll is add for the reductions of several rules, keyed by metric, which
+// are planned by one resource.
+func (b *savingsBudget) addAll(reductions map[string]int64, attr path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if b == nil {
+		return diags
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var own int64
+	for metric, reduction := range reductions {
+		b.reductions[metric] = max(reduction, 0)
+		own += max(reduction, 0)
+	}
+	var total int64
+	for _, r := range b.reductions {
+		total += r
+	}
+	if own == 0 || total <= b.limit {
+		return diags
+	}
+
+	metrics := make([]string, 0, len(reductions))
+	for metric, reduction := range reductions {
+		if reduction > 0 {
+			metrics = append(metrics, strconv.Quote(metric))
+		}
+	}
+	sort.Strings(metrics)
+
+	const summary = "Planned changes reduce the aggregation savings too much"
+	detail := fmt.Sprintf("The planned changes of aggregation rules are estimated to reduce the number of saved series by %d in total, more than the provider's max_savings_reduction of %d. The changes to the rules for %s alone reduce it by %d. Check that the rules are meant to be removed or changed this way, or raise max_savings_reduction.", total, b.limit, strings.Join(metrics, ", "), own)
+	switch {
+	case b.warn && attr.Equal(path.Empty()):
+		diags.AddWarning(summary, detail)
+	case b.warn:
+		diags.AddAttributeWarning(attr, summary, detail)
+	case attr.Equal(path.Empty()):
+		diags.AddError(summary, detail)
+	default:
+		diags.AddAttributeError(attr, summary, detail)
+	}
+	return diags
+}
+
+// addChanges counts the reductions of the saved series by changes against the
+// budget, reporting them against attr: all the savings of the deleted rules,
+// and for the updated rules those they no longer save. Rules which cannot be
+// estimated are not counted, as in the rule resource.
+func (b *savingsBudget) addChanges(ctx context.Context, rules *AggregationRules, changes ruleSetChanges, attr path.Path) diag.Diagnostics {
+	if b == nil || len(changes.Update) == 0 && len(changes.Delete) == 0 {
+		return nil
+	}
+
+	saved := func(rule model.AggregationRule) (int64, bool) {
+		estimate, err := rules.Estimate(rule)
+		if err != nil {
+			tflog.Debug(ctx, "Unable to estimate the impact of the aggregation rule", map[string]interface{}{"metric": rule.Metric, "error": err.Error()})
+			return 0, false
+		}
+		return estimate.SavedSeries(), true
+	}
+
+	current := make(map[string]model.AggregationRule)
+	for _, rule := range rules.List() {
+		current[rule.Metric] = rule
+	}
+
+	reductions := make(map[string]int64, len(changes.Update)+len(changes.Delete))
+	for _, rule := range changes.Delete {
+		if before, ok := saved(rule); ok {
+			reductions[rule.Metric] = before
+		}
+	}
+	for _, rule := range changes.Update {
+		prior, ok := current[rule.Metric]
+		if !ok {
+			continue
+		}
+		before, ok := saved(prior)
+		if !ok {
+			continue
+		}
+		if after, ok := saved(rule); ok {
+			reductions[rule.Metric] = before - after
+		}
+	}
+	return b.addAll(reductions, attr)
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestSavingsBudget(t *testing.T) {
+	b := newSavingsBudget(1000, false)
+
+	require.Empty(t, b.add("a", 600, path.Root("metric")))
+	// Increases do not offset reductions, and a rule planned again is not
+	// counted twice.
+	require.Empty(t, b.add("b", -5000, path.Root("metric")))
+	require.Empty(t, b.add("a", 600, path.Root("metric")))
+	require.Empty(t, b.add("c", 400, path.Root("metric")))
+
+	diags := b.add("d", 1, path.Root("metric"))
+	require.True(t, diags.HasError())
+	require.Equal(t, []path.Path{path.Root("metric")}, errorPaths(diags))
+	require.Contains(t, diags[0].Detail(), "by 1001 in total, more than the provider's max_savings_reduction of 1000")
+	require.Contains(t, diags[0].Detail(), `The changes to the rules for "d" alone reduce it by 1`)
+
+	// Every further reduction is reported, whichever is planned first.
+	diags = b.addAll(map[string]int64{"f": 50, "e": 100, "g": -10}, path.Root("rules"))
+	require.Equal(t, []path.Path{path.Root("rules")}, errorPaths(diags))
+	require.Contains(t, diags[0].Detail(), `The changes to the rules for "e", "f" alone reduce it by 150`)
+	// Changes which do not reduce the savings are not.
+	require.Empty(t, b.add("h", 0, path.Root("metric")))
+
+	var unlimited *savingsBudget
+	require.Empty(t, unlimited.add("a", 1e9, path.Empty()))
+}
+
+func TestRulesFileResourceSavingsBudget(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	rules := api.aggregationRules()
+	r, empty := newTestRulesFileResource(t, rules)
+	name := filepath.Join(t.TempDir(), "rules.json")
+
+	writeRulesFile(t, name, `[
+		{"metric": "a", "drop_labels": ["pod"], "aggregations": ["sum"]},
+		{"metric": "b", "drop": true}
+	]`)
+	plan, planResp := planRulesFile(t, r, empty, name)
+	require.False(t, planResp.Diagnostics.HasError(), planResp.Diagnostics)
+	createResp := &fwresource.CreateResponse{State: empty}
+	r.Create(ctx, fwresource.CreateRequest{Plan: plan}, createResp)
+	require.False(t, createResp.Diagnostics.HasError(), createResp.Diagnostics)
+
+	api.estimate("b", 1000, 0)
+	api.estimateRule(model.AggregationRule{Metric: "a", DropLabels: []string{"pod"}, Aggregations: []string{"sum"}}, 1000, 100)
+	api.estimateRule(model.AggregationRule{Metric: "a", DropLabels: []string{"pod", "instance"}, Aggregations: []string{"sum"}}, 1000, 500)
+	r.savings = newSavingsBudget(1000, false)
+
+	// Deleting b loses all of its savings, and the change of a some of them.
+	writeRulesFile(t, name, `[{"metric": "a", "drop_labels": ["pod", "instance"], "aggregations": ["sum"]}]`)
+	_, planResp = planRulesFile(t, r, createResp.State, name)
+	require.Equal(t, []path.Path{path.Root("path")}, errorPaths(planResp.Diagnostics))
+	require.Contains(t, planResp.Diagnostics.Errors()[0].Detail(), `by 1400 in total`)
+	require.Contains(t, planResp.Diagnostics.Errors()[0].Detail(), `The changes to the rules for "a", "b" alone`)
+
+	// Destroying the resource deletes both rules.
+	r.savings = newSavingsBudget(1000, false)
+	destroy := tfsdk.Plan{Schema: empty.Schema, Raw: empty.Raw}
+	resp := &fwresource.ModifyPlanResponse{Plan: destroy}
+	r.ModifyPlan(ctx, fwresource.ModifyPlanRequest{Plan: destroy, State: createResp.State}, resp)
+	require.True(t, resp.Diagnostics.HasError())
+	require.Contains(t, resp.Diagnostics.Errors()[0].Detail(), `by 1900 in total`)
+}
+
+func TestRuleResourceSavingsBudget(t *testing.T) {
+	ctx := context.Background()
+	api := newMockAPI(t)
+	api.estimate("requests_total", 1000, 100)
+	api.estimate("latency_seconds", 1000, 200)
+	api.estimate("errors_total", 500, 500)
+	r := &ruleResource{rules: api.aggregationRules(), savings: newSavingsBudget(1000, true)}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	destroy := func(rule model.AggregationRule) *fwresource.ModifyPlanResponse {
+		t.Helper()
+		state := tfsdk.State{Schema: schemaResp.Schema}
+		require.False(t, state.Set(ctx, rule.ToTF()).HasError())
+		plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+
+		resp := &fwresource.ModifyPlanResponse{Plan: plan}
+		r.ModifyPlan(ctx, fwresource.ModifyPlanRequest{Plan: plan, State: state}, resp)
+		return resp
+	}
+
+	// Deleting a rule loses all of its savings.
+	require.Empty(t, destroy(model.AggregationRule{Metric: "requests_total"}).Diagnostics)
+	// Rules which cannot be estimated are not counted.
+	require.Empty(t, destroy(model.AggregationRule{Metric: "unknown_total"}).Diagnostics)
+
+	// A change loses the difference, which crosses the limit.
+	prior := model.AggregationRule{Metric: "latency_seconds"}.ToTF()
+	planned := model.AggregationRule{Metric: "errors_total"}.ToTF()
+	resp := modifyPlan(t, r, prior, planned)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+	require.Len(t, resp.Diagnostics.Warnings(), 2)
+	require.Equal(t, "Planned changes reduce the aggregation savings too much", resp.Diagnostics.Warnings()[1].Summary())
+	require.Contains(t, resp.Diagnostics.Warnings()[1].Detail(), "by 1700 in total")
+}
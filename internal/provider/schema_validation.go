@@ -0,0 +1,220 @@
+package provider
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+//go:embed schemas/aggregation_rule.json
+var aggregationRuleSchemaJSON []byte
+
+// aggregationRuleSchema is the schema of the rules sent to the API, checked
+// when the provider's schema_validate option is enabled.
+var aggregationRuleSchema = mustParseJSONSchema(aggregationRuleSchemaJSON)
+
+// jsonSchema is the subset of JSON Schema used by the embedded schemas.
+type jsonSchema struct {
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	UniqueItems          bool                   `json:"uniqueItems"`
+	Enum                 []any                  `json:"enum"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *float64               `json:"minimum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	Maximum              *float64               `json:"maximum"`
+
+	pattern *regexp.Regexp
+}
+
+func mustParseJSONSchema(data []byte) *jsonSchema {
+	var s jsonSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		panic(fmt.Sprintf("invalid JSON schema: %v", err))
+	}
+	s.compile()
+	return &s
+}
+
+func (s *jsonSchema) compile() {
+	if s.Pattern != "" {
+		s.pattern = regexp.MustCompile(s.Pattern)
+	}
+	for _, prop := range s.Properties {
+		prop.compile()
+	}
+	if s.Items != nil {
+		s.Items.compile()
+	}
+}
+
+// validateRuleSchema checks the request which would be sent to the API for
+// rule against the aggregation rule schema. Every violation is reported
+// against the offending attribute under p.
+func validateRuleSchema(rule model.AggregationRule, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// The allAggregations sentinel is only expanded when the rule is sent.
+	if hasAllAggregations(rule.Aggregations) {
+		rule.Aggregations = nil
+	}
+	// Label patterns are not label names; validateRule checks them.
+	if usesLabelPatterns(rule) {
+		rule.KeepLabels, rule.DropLabels = nil, nil
+	}
+
+	body, err := json.Marshal(rule)
+	if err != nil {
+		diags.AddAttributeError(p, "Unable to encode aggregation rule", err.Error())
+		return diags
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		diags.AddAttributeError(p, "Unable to encode aggregation rule", err.Error())
+		return diags
+	}
+
+	for _, v := range aggregationRuleSchema.validate(value, p, "") {
+		diags.AddAttributeError(
+			v.path,
+			"Aggregation rule schema violation",
+			fmt.Sprintf("The rule for %q does not match the API schema: %s %s.", rule.Metric, v.field, v.message),
+		)
+	}
+
+	return diags
+}
+
+type schemaViolation struct {
+	path    path.Path
+	field   string
+	message string
+}
+
+// validate returns the violations of the schema by the decoded JSON value. p
+// and field locate the value, as an attribute path and as it is displayed.
+func (s *jsonSchema) validate(value any, p path.Path, field string) []schemaViolation {
+	violation := func(format string, a ...any) []schemaViolation {
+		name := field
+		if name == "" {
+			name = "the rule"
+		}
+		return []schemaViolation{{path: p, field: name, message: fmt.Sprintf(format, a...)}}
+	}
+
+	if s.Type != "" && !hasJSONType(value, s.Type) {
+		return violation("must be of type %s", s.Type)
+	}
+
+	if len(s.Enum) > 0 && !containsJSONValue(s.Enum, value) {
+		allowed := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			allowed[i] = fmt.Sprintf("%q", e)
+		}
+		return violation("must be one of %s, got %v", strings.Join(allowed, ", "), value)
+	}
+
+	var violations []schemaViolation
+	switch v := value.(type) {
+	case string:
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			violations = append(violations, violation("must match the pattern %s, got %q", s.Pattern, v)...)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			violations = append(violations, violation("must be at least %v, got %v", *s.Minimum, v)...)
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			violations = append(violations, violation("must be greater than %v, got %v", *s.ExclusiveMinimum, v)...)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			violations = append(violations, violation("must be at most %v, got %v", *s.Maximum, v)...)
+		}
+	case []any:
+		for i, elem := range v {
+			if s.UniqueItems && containsJSONValue(v[:i], elem) {
+				violations = append(violations, violation("must not contain %v more than once", elem)...)
+			}
+			if s.Items != nil {
+				violations = append(violations, s.Items.validate(elem, p.AtListIndex(i), fmt.Sprintf("%s[%d]", field, i))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, violation("is missing the required field %s", name)...)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			propSchema, ok := s.Properties[name]
+			switch {
+			case ok:
+				violations = append(violations, propSchema.validate(v[name], p.AtName(name), joinField(field, name))...)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				violations = append(violations, violation("has the unknown field %s", name)...)
+			}
+		}
+	}
+
+	return violations
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func hasJSONType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func containsJSONValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
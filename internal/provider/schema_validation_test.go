@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestValidateRuleSchema(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rule     model.AggregationRule
+		expected []path.Path
+	}{
+		{
+			name: "valid",
+			rule: model.AggregationRule{
+				Metric:              "http_request_duration_seconds",
+				MatchType:           "prefix",
+				DropLabels:          []string{"pod", "instance"},
+				Aggregations:        []string{"sum:counter", "count"},
+				AggregationInterval: "1m",
+				AggregationDelay:    "1m30s",
+				Priority:            2,
+				ManagedBy:           "terraform",
+			},
+		},
+		{
+			name: "existing rule",
+			rule: model.AggregationRule{ID: "01HZX3", Metric: "up", Archived: true},
+		},
+		{
+			name:     "invalid metric",
+			rule:     model.AggregationRule{Metric: "http-requests"},
+			expected: []path.Path{path.Root("metric")},
+		},
+		{
+			name:     "invalid match_type",
+			rule:     model.AggregationRule{Metric: "up", MatchType: "regex"},
+			expected: []path.Path{path.Root("match_type")},
+		},
+		{
+			name:     "invalid and duplicate labels",
+			rule:     model.AggregationRule{Metric: "up", KeepLabels: []string{"pod", "0pod", "pod"}},
+			expected: []path.Path{path.Root("keep_labels").AtListIndex(1), path.Root("keep_labels")},
+		},
+		{
+			name:     "unknown aggregation",
+			rule:     model.AggregationRule{Metric: "up", Aggregations: []string{"sum", "avg"}},
+			expected: []path.Path{path.Root("aggregations").AtListIndex(1)},
+		},
+		{
+			name: "percentiles",
+			rule: model.AggregationRule{Metric: "up", Aggregations: []string{"sum", "p50", "p99.9"}},
+		},
+		{
+			name:     "invalid durations",
+			rule:     model.AggregationRule{Metric: "up", AggregationInterval: "1 minute", AggregationDelay: "30"},
+			expected: []path.Path{path.Root("aggregation_delay"), path.Root("aggregation_interval")},
+		},
+		{
+			name:     "negative priority",
+			rule:     model.AggregationRule{Metric: "up", Priority: -1},
+			expected: []path.Path{path.Root("priority")},
+		},
+		{
+			name:     "rollout percentage above 100",
+			rule:     model.AggregationRule{Metric: "up", RolloutPercentage: ptr(int64(150))},
+			expected: []path.Path{path.Root("rollout_percentage")},
+		},
+		{
+			name:     "ingest sample rate of 0",
+			rule:     model.AggregationRule{Metric: "up", IngestSampleRate: ptr(0.0)},
+			expected: []path.Path{path.Root("ingest_sample_rate")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := validateRuleSchema(tc.rule, path.Empty())
+			require.Equal(t, tc.expected, errorPaths(diags))
+		})
+	}
+}
+
+func TestValidateRuleSchemaReportsField(t *testing.T) {
+	diags := validateRuleSchema(model.AggregationRule{Metric: "up", Aggregations: []string{"avg"}}, path.Root("rules").AtListIndex(2))
+	require.Equal(t, []path.Path{path.Root("rules").AtListIndex(2).AtName("aggregations").AtListIndex(0)}, errorPaths(diags))
+	require.Contains(t, diags.Errors()[0].Detail(), "aggregations[0] must match the pattern")
+}
+
+func TestRuleResourceModifyPlanSchemaValidate(t *testing.T) {
+	rule := model.AggregationRule{Metric: "test_tf_metric", AggregationInterval: "1 minute"}.ToTF()
+
+	r := &ruleResource{rules: newMockAPI(t).aggregationRules()}
+	resp := modifyPlan(t, r, nil, rule)
+	require.False(t, resp.Diagnostics.HasError(), resp.Diagnostics)
+
+	r.schemaValidate = true
+	resp = modifyPlan(t, r, nil, rule)
+	require.Equal(t, []path.Path{path.Root("aggregation_interval")}, errorPaths(resp.Diagnostics))
+}
+
+func TestRuleSetResourceModifyPlanSchemaValidate(t *testing.T) {
+	plan := model.RuleSetTF{
+		Rules: []model.RuleSetRuleTF{
+			model.AggregationRule{Metric: "a"}.ToRuleSetRuleTF(),
+			model.AggregationRule{Metric: "b", MatchType: "exact", Aggregations: []string{"avg"}}.ToRuleSetRuleTF(),
+		},
+		Prune:           types.BoolValue(false),
+		AllowBroadMatch: types.BoolValue(false),
+	}
+
+	r := &ruleSetResource{rules: newMockAPI(t).aggregationRules(), schemaValidate: true}
+	resp := modifyPlan(t, r, nil, plan)
+	require.Equal(t, []path.Path{path.Root("rules").AtListIndex(1).AtName("aggregations").AtListIndex(0)}, errorPaths(resp.Diagnostics))
+}
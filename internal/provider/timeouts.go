@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+)
+
+// timeoutsBlock is the standard timeouts block, limiting how long each
+// operation of a resource may take.
+func timeoutsBlock(ctx context.Context) schema.Block {
+	timeout := func(operation string) string {
+		return fmt.Sprintf("How long %s may take, as a duration such as \"30s\" or \"10m\". Unset means no limit.", operation)
+	}
+
+	block := timeouts.Block(ctx, timeouts.Opts{
+		Create:            true,
+		Read:              true,
+		Update:            true,
+		Delete:            true,
+		CreateDescription: timeout("creating the resource"),
+		ReadDescription:   timeout("refreshing the resource"),
+		UpdateDescription: timeout("updating the resource"),
+		DeleteDescription: timeout("destroying the resource"),
+	}).(schema.SingleNestedBlock)
+	block.Description = "Limits how long each operation may take, for example for slow backends. An operation which takes longer is aborted with an error."
+	return block
+}
+
+// validateTimeouts checks that no timeout is negative. The block itself
+// rejects values which are not durations.
+func validateTimeouts(ctx context.Context, t timeouts.Value) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for name, timeout := range map[string]func(context.Context, time.Duration) (time.Duration, diag.Diagnostics){
+		"create": t.Create,
+		"read":   t.Read,
+		"update": t.Update,
+		"delete": t.Delete,
+	} {
+		if d, _ := timeout(ctx, 0); d < 0 {
+			diags.AddAttributeError(
+				path.Root("timeouts").AtName(name),
+				"Invalid timeout",
+				fmt.Sprintf("Got %s; it must not be negative.", d),
+			)
+		}
+	}
+	return diags
+}
+
+// withTimeout returns ctx with the deadline set by timeout, if it is
+// positive.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,824 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+var dropMatcherLabelRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// tagKeyRE matches the tag keys accepted by the API.
+var tagKeyRE = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{0,62}$`)
+
+// maxTagValueLength is the longest tag value accepted by the API.
+const maxTagValueLength = 255
+
+// metricNameRE matches the metric names accepted by Prometheus.
+var metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// apiFieldRE matches the fields named by the API when it rejects part of a
+// rule, such as "aggregations", "keep_labels[1]", or "tags[owner]".
+var apiFieldRE = regexp.MustCompile(`^([a-z_]+)(?:\[([^\]]+)\])?$`)
+
+// ruleAPIFields are the fields of a rule in the API which are set by the
+// attribute of the same name.
+var ruleAPIFields = []string{
+	"metric", "match_type", "drop", "drop_if", "keep_labels", "drop_labels", "label_match_mode",
+	"aggregations", "aggregation_interval", "aggregation_intervals", "aggregation_delay", "delay_overrides",
+	"priority", "rollout_percentage", "ingest_sample_rate", "tags",
+}
+
+// ruleAPIMapFields are the fields of ruleAPIFields which are maps, whose
+// entries the API names by their key rather than an index.
+var ruleAPIMapFields = []string{"aggregation_intervals", "tags"}
+
+// validateRule checks a rule for configuration errors which the API would
+// reject. Every problem found is reported against the attribute under p.
+func validateRule(rule model.AggregationRule, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	switch rule.MatchType {
+	case "", "exact", "prefix", "suffix":
+	default:
+		diags.AddAttributeError(
+			p.AtName("match_type"),
+			"Invalid match_type",
+			fmt.Sprintf("The rule for %q has match_type %q; it must be one of 'prefix', 'suffix', or 'exact'.", rule.Metric, rule.MatchType),
+		)
+	}
+
+	if rule.Metric == "" && (rule.MatchType == "" || rule.MatchType == "exact") {
+		diags.AddAttributeError(p.AtName("metric"), "Empty metric", "An exact match rule must specify the metric it applies to.")
+	}
+
+	if rule.Priority < 0 {
+		diags.AddAttributeError(
+			p.AtName("priority"),
+			"Invalid priority",
+			fmt.Sprintf("The rule for %q has priority %d; it must not be negative.", rule.Metric, rule.Priority),
+		)
+	}
+
+	if pct := rule.RolloutPercentage; pct != nil && (*pct < 0 || *pct > 100) {
+		diags.AddAttributeError(
+			p.AtName("rollout_percentage"),
+			"Invalid rollout_percentage",
+			fmt.Sprintf("The rule for %q has rollout_percentage %d; it must be between 0 and 100.", rule.Metric, *pct),
+		)
+	}
+
+	if rate := rule.IngestSampleRate; rate != nil {
+		switch {
+		case *rate <= 0 || *rate > 1:
+			diags.AddAttributeError(
+				p.AtName("ingest_sample_rate"),
+				"Invalid ingest_sample_rate",
+				fmt.Sprintf("The rule for %q has ingest_sample_rate %g; it must be greater than 0 and at most 1. Set drop = true to ingest none of the series.", rule.Metric, *rate),
+			)
+		case rule.Drop:
+			diags.AddAttributeError(
+				p.AtName("ingest_sample_rate"),
+				"Conflicting ingest_sample_rate",
+				fmt.Sprintf("The rule for %q sets ingest_sample_rate but drops its metric, of which no series are ingested to sample.", rule.Metric),
+			)
+		}
+	}
+
+	if len(rule.KeepLabels) > 0 && len(rule.DropLabels) > 0 {
+		diags.AddAttributeError(
+			p.AtName("keep_labels"),
+			"Conflicting label configuration",
+			fmt.Sprintf("The rule for %q sets both keep_labels and drop_labels; only one of them may be set.", rule.Metric),
+		)
+	}
+
+	switch rule.LabelMatchMode {
+	case "", "exact":
+	case "glob", "regex":
+		for _, labels := range []struct {
+			name    string
+			entries []string
+		}{{"keep_labels", rule.KeepLabels}, {"drop_labels", rule.DropLabels}} {
+			for i, pattern := range labels.entries {
+				if err := checkLabelPattern(rule.LabelMatchMode, pattern); err != nil {
+					diags.AddAttributeError(
+						p.AtName(labels.name).AtListIndex(i),
+						"Invalid label pattern",
+						fmt.Sprintf("The rule for %q has the %s pattern %q in %s: %s.", rule.Metric, rule.LabelMatchMode, pattern, labels.name, err),
+					)
+				}
+			}
+		}
+	default:
+		diags.AddAttributeError(
+			p.AtName("label_match_mode"),
+			"Invalid label_match_mode",
+			fmt.Sprintf("The rule for %q has label_match_mode %q; it must be one of 'exact', 'glob', or 'regex'.", rule.Metric, rule.LabelMatchMode),
+		)
+	}
+
+	if slices.Contains(rule.Aggregations, allAggregations) && !hasAllAggregations(rule.Aggregations) {
+		diags.AddAttributeError(
+			p.AtName("aggregations"),
+			"Invalid aggregations",
+			fmt.Sprintf("The rule for %q mixes %q with explicit aggregation types; %q already includes every supported type and must be the only element.", rule.Metric, allAggregations, allAggregations),
+		)
+	}
+
+	// Aggregations are a set in some resources and a list in others, so
+	// their errors are reported against the attribute rather than an element.
+	for _, agg := range rule.Aggregations {
+		switch {
+		case strings.HasPrefix(agg, "percentile:"):
+			diags.AddAttributeError(
+				p.AtName("aggregations"),
+				"Invalid percentile",
+				fmt.Sprintf("The rule for %q has the aggregation %q; percentiles are written as p<percent>, such as p99 for the quantile 0.99.", rule.Metric, agg),
+			)
+		case model.IsPercentile(agg):
+			if _, err := model.ParsePercentile(agg); err != nil {
+				diags.AddAttributeError(
+					p.AtName("aggregations"),
+					"Invalid percentile",
+					fmt.Sprintf("The rule for %q has the aggregation %q: %s.", rule.Metric, agg, err),
+				)
+			}
+		}
+	}
+
+	aggregationTypes := make([]string, 0, len(rule.AggregationIntervals))
+	for agg := range rule.AggregationIntervals {
+		aggregationTypes = append(aggregationTypes, agg)
+	}
+	slices.Sort(aggregationTypes)
+	for _, agg := range aggregationTypes {
+		interval := rule.AggregationIntervals[agg]
+		if !slices.Contains(rule.Aggregations, agg) && !hasAllAggregations(rule.Aggregations) {
+			diags.AddAttributeError(
+				p.AtName("aggregation_intervals").AtMapKey(agg),
+				"Interval for unused aggregation",
+				fmt.Sprintf("The rule for %q sets an interval for the aggregation %q, which is not in its aggregations.", rule.Metric, agg),
+			)
+		}
+		if _, err := parsePromDuration(interval); err != nil {
+			diags.AddAttributeError(
+				p.AtName("aggregation_intervals").AtMapKey(agg),
+				"Invalid aggregation interval",
+				fmt.Sprintf("The rule for %q has the interval %q for the aggregation %q: %s.", rule.Metric, interval, agg, err),
+			)
+		}
+	}
+
+	if len(rule.DropIf) > 0 && !rule.Drop {
+		diags.AddAttributeError(
+			p.AtName("drop_if"),
+			"Conditional drop without drop",
+			fmt.Sprintf("The rule for %q sets drop_if but not drop = true; drop_if only restricts which series are dropped.", rule.Metric),
+		)
+	}
+	for i, m := range rule.DropIf {
+		diags.Append(validateDropMatcher(rule, m, "drop_if", p.AtName("drop_if").AtListIndex(i))...)
+	}
+	diags.Append(validateDropMatchersAgree(rule, p.AtName("drop_if"))...)
+
+	for i, o := range rule.DelayOverrides {
+		op := p.AtName("delay_overrides").AtListIndex(i)
+		diags.Append(validateDropMatcher(rule, o.Matcher, "delay_overrides", op)...)
+		if _, err := parsePromDuration(o.Delay); err != nil {
+			diags.AddAttributeError(
+				op.AtName("delay"),
+				"Invalid delay override",
+				fmt.Sprintf("The rule for %q has the aggregation delay %q for the series matching %s: %s.", rule.Metric, o.Delay, o.Matcher, err),
+			)
+		}
+	}
+
+	keys := make([]string, 0, len(rule.Tags))
+	for k := range rule.Tags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v := rule.Tags[k]
+		if !tagKeyRE.MatchString(k) {
+			diags.AddAttributeError(
+				p.AtName("tags").AtMapKey(k),
+				"Invalid tag key",
+				fmt.Sprintf("The rule for %q has the tag %q; tag keys must start with a letter, contain only letters, digits, '_', '.', or '-', and be at most 63 characters long.", rule.Metric, k),
+			)
+		}
+		if len(v) > maxTagValueLength {
+			diags.AddAttributeError(
+				p.AtName("tags").AtMapKey(k),
+				"Invalid tag value",
+				fmt.Sprintf("The rule for %q has a value for the tag %q which is longer than %d characters.", rule.Metric, k, maxTagValueLength),
+			)
+		}
+	}
+
+	return diags
+}
+
+// usesLabelPatterns reports whether the keep_labels and drop_labels entries
+// of rule are patterns rather than label names.
+func usesLabelPatterns(rule model.AggregationRule) bool {
+	return rule.LabelMatchMode == "glob" || rule.LabelMatchMode == "regex"
+}
+
+// checkLabelPattern returns an error if pattern is not a valid pattern for
+// the label match mode.
+func checkLabelPattern(mode, pattern string) error {
+	if mode == "glob" {
+		_, err := filepath.Match(pattern, "")
+		return err
+	}
+	_, err := regexp.Compile("^(?:" + pattern + ")$")
+	return err
+}
+
+// labelMatches reports whether the keep_labels or drop_labels entry matches
+// label under the label match mode. Invalid patterns match no label.
+func labelMatches(mode, entry, label string) bool {
+	switch mode {
+	case "glob":
+		ok, err := filepath.Match(entry, label)
+		return err == nil && ok
+	case "regex":
+		return fullMatch(entry, label)
+	default:
+		return entry == label
+	}
+}
+
+// fullMatch reports whether the regular expression pattern matches all of s.
+// Invalid patterns match nothing.
+func fullMatch(pattern, s string) bool {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	return err == nil && re.MatchString(s)
+}
+
+// validateDropMatcher checks a label matcher of a conditional drop or of a
+// delay override, which block names in the summaries.
+func validateDropMatcher(rule model.AggregationRule, m model.DropMatcher, block string, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !dropMatcherLabelRE.MatchString(m.Label) {
+		diags.AddAttributeError(
+			p.AtName("label"),
+			"Invalid "+block+" label",
+			fmt.Sprintf("The rule for %q matches the label %q, which is not a valid label name.", rule.Metric, m.Label),
+		)
+	}
+
+	switch m.Operator {
+	case "=", "!=":
+	case "=~", "!~":
+		if _, err := regexp.Compile("^(?:" + m.Value + ")$"); err != nil {
+			diags.AddAttributeError(
+				p.AtName("value"),
+				"Invalid "+block+" regular expression",
+				fmt.Sprintf("The rule for %q matches the label %q against an invalid regular expression: %s.", rule.Metric, m.Label, err),
+			)
+		}
+	default:
+		diags.AddAttributeError(
+			p.AtName("operator"),
+			"Invalid "+block+" operator",
+			fmt.Sprintf("The rule for %q matches the label %q with the operator %q; it must be one of %s.", rule.Metric, m.Label, m.Operator, strings.Join(model.DropMatcherOperators, ", ")),
+		)
+	}
+
+	return diags
+}
+
+// validateDropMatchersAgree rejects the matchers of a conditional drop which
+// exclude the value required by an equality matcher for the same label, such
+// as env="prod" with env!="prod", as no series would ever be dropped.
+func validateDropMatchersAgree(rule model.AggregationRule, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	reported := make(map[int]bool)
+	for i, eq := range rule.DropIf {
+		if eq.Operator != "=" {
+			continue
+		}
+		for j, m := range rule.DropIf {
+			if j == i || m.Label != eq.Label || reported[j] || reported[i] || dropMatcherAccepts(m, eq.Value) {
+				continue
+			}
+			reported[j] = true
+			diags.AddAttributeError(
+				p.AtListIndex(j),
+				"Conflicting drop_if matchers",
+				fmt.Sprintf("The rule for %q only drops the series matching %s, which %s excludes, so that no series is ever dropped.", rule.Metric, eq, m),
+			)
+		}
+	}
+
+	return diags
+}
+
+// dropMatcherAccepts reports whether m matches the label value. Matchers with
+// an invalid operator or regular expression, which are reported on their own,
+// accept every value.
+func dropMatcherAccepts(m model.DropMatcher, value string) bool {
+	switch m.Operator {
+	case "=":
+		return m.Value == value
+	case "!=":
+		return m.Value != value
+	case "=~", "!~":
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return true
+		}
+		return re.MatchString(value) == (m.Operator == "=~")
+	default:
+		return true
+	}
+}
+
+// validateBroadMatch rejects prefix and suffix rules whose matcher is shorter
+// than minLength, as these would aggregate almost every metric of the tenant.
+func validateBroadMatch(rule model.AggregationRule, minLength int, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for i, m := range rule.Matchers() {
+		if (m.MatchType == "prefix" || m.MatchType == "suffix") && len(m.Metric) < minLength {
+			attr := p.AtName("metric")
+			if len(rule.MatchPrefixes) > 0 {
+				attr = p.AtName("match_prefixes").AtListIndex(i)
+			}
+			diags.AddAttributeError(
+				attr,
+				"Broad match rule",
+				fmt.Sprintf("The %s rule for %q is shorter than %d characters and would aggregate a large share of all metrics. Set allow_broad_match = true to apply it anyway.", m.MatchType, m.Metric, minLength),
+			)
+		}
+	}
+
+	return diags
+}
+
+// validateRequiredKeepLabels rejects rules which would aggregate away one of
+// the required labels: rules with keep_labels must keep every required label,
+// and rules with drop_labels must not drop any of them. Rules which drop the
+// metric entirely keep no series, so they are not checked. A rule without a
+// metric stands for the default rule.
+func validateRequiredKeepLabels(rule model.AggregationRule, required []string, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if rule.Drop {
+		return diags
+	}
+
+	if len(rule.KeepLabels) > 0 {
+		for _, label := range required {
+			kept := slices.ContainsFunc(rule.KeepLabels, func(entry string) bool {
+				return labelMatches(rule.LabelMatchMode, entry, label)
+			})
+			if !kept {
+				diags.AddAttributeError(
+					p.AtName("keep_labels"),
+					"Required label not kept",
+					fmt.Sprintf("The %s does not keep the label %q, which the provider's required_keep_labels requires every rule to retain. Add it to keep_labels.", ruleName(rule), label),
+				)
+			}
+		}
+		return diags
+	}
+
+	for i, entry := range rule.DropLabels {
+		for _, label := range required {
+			if labelMatches(rule.LabelMatchMode, entry, label) {
+				diags.AddAttributeError(
+					p.AtName("drop_labels").AtListIndex(i),
+					"Required label dropped",
+					fmt.Sprintf("The %s drops the label %q, which the provider's required_keep_labels requires every rule to retain. Remove it from drop_labels.", ruleName(rule), label),
+				)
+			}
+		}
+	}
+	return diags
+}
+
+// validateAllowedMetric rejects rules with a matcher which matches none of
+// the provider's allowed_metric_patterns. Every rule is allowed if there are
+// no patterns. A rule without a metric stands for the default rule, which
+// applies to every metric like a prefix rule for the empty prefix, so it is
+// only allowed by a pattern matching the empty string, such as ".*".
+func validateAllowedMetric(rule model.AggregationRule, allowed []string, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if len(allowed) == 0 {
+		return diags
+	}
+
+	for i, m := range rule.Matchers() {
+		if slices.ContainsFunc(allowed, func(pattern string) bool { return fullMatch(pattern, m.Metric) }) {
+			continue
+		}
+		if m.Metric == "" {
+			diags.AddError(
+				"Metric not allowed",
+				fmt.Sprintf("The default rule applies to every metric which is not matched by another rule, but none of the provider's allowed_metric_patterns (%s) matches the empty prefix, so it may not be managed. Ask the owners of the provider configuration to allow it, such as with the pattern \".*\".", strings.Join(allowed, ", ")),
+			)
+			continue
+		}
+		attr := p.AtName("metric")
+		if len(rule.MatchPrefixes) > 0 {
+			attr = p.AtName("match_prefixes").AtListIndex(i)
+		}
+		matchType := m.MatchType
+		if matchType == "" {
+			matchType = "exact"
+		}
+		diags.AddAttributeError(
+			attr,
+			"Metric not allowed",
+			fmt.Sprintf("The %s rule for %q matches none of the provider's allowed_metric_patterns (%s), so it may not be managed. Change the metric, or ask the owners of the provider configuration to allow it.", matchType, m.Metric, strings.Join(allowed, ", ")),
+		)
+	}
+
+	return diags
+}
+
+// ruleName names rule in diagnostics, after "The".
+func ruleName(rule model.AggregationRule) string {
+	if rule.Metric == "" {
+		return "default rule"
+	}
+	return fmt.Sprintf("rule for %q", rule.Metric)
+}
+
+// validateAggregationDelay reports rules whose aggregation delay is shorter
+// than their aggregation interval, as the aggregation would then be performed
+// before all samples of the interval have arrived. Rules which leave either
+// to the server default, or whose durations cannot be parsed, are skipped.
+func validateAggregationDelay(rule model.AggregationRule, warn bool, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if rule.AggregationInterval == "" || rule.AggregationDelay == "" {
+		return diags
+	}
+	interval, err := parsePromDuration(rule.AggregationInterval)
+	if err != nil {
+		return diags
+	}
+	delay, err := parsePromDuration(rule.AggregationDelay)
+	if err != nil || delay >= interval {
+		return diags
+	}
+
+	summary := "Aggregation delay shorter than interval"
+	detail := fmt.Sprintf("The rule for %q has aggregation_delay %s, which is shorter than its aggregation_interval %s, so aggregations may be incomplete.", rule.Metric, rule.AggregationDelay, rule.AggregationInterval)
+	if warn {
+		diags.AddAttributeWarning(p.AtName("aggregation_delay"), summary, detail)
+	} else {
+		diags.AddAttributeError(p.AtName("aggregation_delay"), summary, detail+" Set the provider's aggregation_delay_check to 'warn' to apply it anyway.")
+	}
+
+	return diags
+}
+
+// validateAggregationTypes warns about the aggregations of rule which are not
+// meaningful for the raw values of a metric of metricType. The warnings can be
+// suppressed, as the type reported for a metric may be wrong.
+func validateAggregationTypes(rule model.AggregationRule, metricType string, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, aggregation := range rule.Aggregations {
+		var reason string
+		switch {
+		case aggregation == allAggregations:
+		case metricType == model.MetricTypeCounter || metricType == model.MetricTypeHistogram:
+			if aggregation != "sum:counter" && aggregation != "count" {
+				reason = fmt.Sprintf("the series of a %s only ever increase, so %s aggregates their running totals, which are reset whenever the process restarts; use sum:counter instead", metricType, aggregation)
+			}
+		case metricType == model.MetricTypeGauge:
+			if aggregation == "sum:counter" {
+				reason = "a gauge may decrease, which sum:counter takes for counter resets; use sum instead"
+			}
+		}
+
+		if reason != "" {
+			diags.AddAttributeWarning(
+				p.AtName("aggregations").AtSetValue(types.StringValue(aggregation)),
+				"Aggregation not meaningful for metric type",
+				fmt.Sprintf("The rule for %q aggregates a %s with %s, but %s. Set ignore_metric_type to true if the type of the metric is wrong.", rule.Metric, metricType, aggregation, reason),
+			)
+		}
+	}
+	return diags
+}
+
+// validateDeprecatedAggregations warns about the aggregations of rule whose
+// types are deprecated, so that the rule can be migrated before they are
+// removed.
+func validateDeprecatedAggregations(rule model.AggregationRule, deprecated map[string]model.AggregationDeprecation, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, aggregation := range rule.Aggregations {
+		d, ok := deprecated[aggregation]
+		if !ok {
+			continue
+		}
+
+		removal := "in a later version of the backend"
+		if d.RemovalDate != "" {
+			removal = "on " + d.RemovalDate
+		}
+		migrate := "Remove it from the rule"
+		if d.Replacement != "" {
+			migrate = fmt.Sprintf("Replace it with %s", d.Replacement)
+		}
+		diags.AddAttributeWarning(
+			p.AtName("aggregations").AtSetValue(types.StringValue(aggregation)),
+			"Deprecated aggregation type",
+			fmt.Sprintf("The rule for %q uses the aggregation type %s, which is deprecated and will be removed %s. %s before then, as the rule is rejected once the type is removed.", rule.Metric, aggregation, removal, migrate),
+		)
+	}
+	return diags
+}
+
+// validateRuleDependencies checks that every rule named by dependsOn, which
+// maps the metric of a rule to the metric of the rule it depends on, is one of
+// rules, and that no rule depends on itself, directly or not. Nil entries of
+// rules are not fully known yet, so nothing is checked unless all are known.
+func validateRuleDependencies(rules []*model.AggregationRule, dependsOn map[string]types.String, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	metrics := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule == nil {
+			return diags
+		}
+		metrics[rule.Metric] = true
+	}
+
+	keys := make([]string, 0, len(dependsOn))
+	for metric, dependency := range dependsOn {
+		if dependency.IsUnknown() {
+			return diags
+		}
+		keys = append(keys, metric)
+	}
+	slices.Sort(keys)
+
+	for _, metric := range keys {
+		dependency := dependsOn[metric].ValueString()
+		for _, m := range []string{metric, dependency} {
+			if !metrics[m] {
+				diags.AddAttributeError(
+					p.AtMapKey(metric),
+					"Unknown rule dependency",
+					fmt.Sprintf("There is no rule for %q in the rule set. Dependencies can only order the rules of the set.", m),
+				)
+			}
+		}
+
+		// Follow the dependencies from metric, which form a cycle if they
+		// lead back to it.
+		seen := make(map[string]bool)
+		for next := dependency; !seen[next]; {
+			if next == metric {
+				diags.AddAttributeError(
+					p.AtMapKey(metric),
+					"Cyclic rule dependency",
+					fmt.Sprintf("The rule for %q depends on itself through depends_on_metric, so there is no order in which to write its rules.", metric),
+				)
+				break
+			}
+			seen[next] = true
+			d, ok := dependsOn[next]
+			if !ok {
+				break
+			}
+			next = d.ValueString()
+		}
+	}
+	return diags
+}
+
+// validateLabelsPresent warns about the entries of the keep_labels and
+// drop_labels of rule which match none of labels, the labels of its metric,
+// as they are most likely misspelt. Nothing is checked if labels is nil, as
+// the labels of the metric are then unknown.
+func validateLabelsPresent(rule model.AggregationRule, labels []string, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if labels == nil {
+		return diags
+	}
+
+	for _, attr := range []struct {
+		name    string
+		entries []string
+	}{
+		{"keep_labels", rule.KeepLabels},
+		{"drop_labels", rule.DropLabels},
+	} {
+		for i, entry := range attr.entries {
+			if slices.ContainsFunc(labels, func(label string) bool { return labelMatches(rule.LabelMatchMode, entry, label) }) {
+				continue
+			}
+			diags.AddAttributeWarning(
+				p.AtName(attr.name).AtListIndex(i),
+				"Label not present on metric",
+				fmt.Sprintf("The %s entry %q of the rule for %q matches none of the labels of the metric (%s), so it has no effect. Check it for typos, or set ignore_missing_labels to true if the label is yet to be added.", attr.name, entry, rule.Metric, strings.Join(labels, ", ")),
+			)
+		}
+	}
+	return diags
+}
+
+// validateRuleSet validates every rule of a rule set rather than stopping at
+// the first invalid one, so that all problems are reported at once. Nil
+// entries are rules which are not fully known yet; they are skipped.
+func validateRuleSet(rules []*model.AggregationRule) diag.Diagnostics {
+	var diags diag.Diagnostics
+	seen := make(map[string]int, len(rules))
+
+	for i, rule := range rules {
+		if rule == nil {
+			continue
+		}
+
+		p := path.Root("rules").AtListIndex(i)
+		diags.Append(validateRule(*rule, p)...)
+
+		for j, other := range rules[:i] {
+			if other != nil && other.Metric != rule.Metric && other.Priority == rule.Priority && rulesOverlap(*rule, *other) {
+				diags.AddAttributeWarning(
+					p.AtName("priority"),
+					"Overlapping aggregation rules with the same priority",
+					fmt.Sprintf("The rule for %q may match the same metrics as rule %d of the rule set (%q), and both have priority %d, so it is undefined which of them is applied.", rule.Metric, j, other.Metric, rule.Priority),
+				)
+			}
+		}
+
+		if j, ok := seen[rule.Metric]; ok {
+			diags.AddAttributeError(
+				p.AtName("metric"),
+				"Duplicate metric",
+				fmt.Sprintf("The metric %q is already used by rule %d of the rule set.", rule.Metric, j),
+			)
+			continue
+		}
+		seen[rule.Metric] = i
+	}
+
+	return diags
+}
+
+// validateMetricList checks a list attribute of metric names, or of metric
+// prefixes, for malformed and repeated names. Names which are not known yet
+// are skipped.
+func validateMetricList(metrics []types.String, attr path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	seen := make(map[string]int, len(metrics))
+
+	for i, m := range metrics {
+		if m.IsNull() || m.IsUnknown() {
+			continue
+		}
+
+		p := attr.AtListIndex(i)
+		metric := m.ValueString()
+		if !metricNameRE.MatchString(metric) {
+			diags.AddAttributeError(p, "Invalid metric", fmt.Sprintf("%q is not a valid metric name; it must match %s.", metric, metricNameRE))
+			continue
+		}
+		if j, ok := seen[metric]; ok {
+			diags.AddAttributeError(p, "Duplicate metric", fmt.Sprintf("The metric %q is already listed as element %d.", metric, j))
+			continue
+		}
+		seen[metric] = i
+	}
+
+	return diags
+}
+
+// rulesOverlap reports whether there may be a metric which is matched by both
+// rules.
+func rulesOverlap(a, b model.AggregationRule) bool {
+	if len(a.MatchPrefixes) > 0 || len(b.MatchPrefixes) > 0 {
+		for _, ma := range a.Matchers() {
+			for _, mb := range b.Matchers() {
+				if rulesOverlap(ma, mb) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	matchType := func(r model.AggregationRule) string {
+		if r.MatchType == "" {
+			return "exact"
+		}
+		return r.MatchType
+	}
+
+	// Order the rules so that only half of the combinations need handling.
+	order := map[string]int{"exact": 0, "prefix": 1, "suffix": 2}
+	if order[matchType(a)] > order[matchType(b)] {
+		a, b = b, a
+	}
+
+	switch matchType(a) + "/" + matchType(b) {
+	case "exact/exact":
+		return a.Metric == b.Metric
+	case "exact/prefix":
+		return strings.HasPrefix(a.Metric, b.Metric)
+	case "exact/suffix":
+		return strings.HasSuffix(a.Metric, b.Metric)
+	case "prefix/prefix":
+		return strings.HasPrefix(a.Metric, b.Metric) || strings.HasPrefix(b.Metric, a.Metric)
+	case "suffix/suffix":
+		return strings.HasSuffix(a.Metric, b.Metric) || strings.HasSuffix(b.Metric, a.Metric)
+	default:
+		// A metric can always start with the prefix and end with the suffix.
+		return true
+	}
+}
+
+// nestedRules reports whether a and b are both prefix or both suffix rules, of
+// a single matcher each, such that every metric matched by narrower is also
+// matched by broader.
+func nestedRules(a, b model.AggregationRule) (broader, narrower model.AggregationRule, ok bool) {
+	if a.MatchType != b.MatchType || len(a.MatchPrefixes) > 0 || len(b.MatchPrefixes) > 0 {
+		return model.AggregationRule{}, model.AggregationRule{}, false
+	}
+	if len(a.Metric) > len(b.Metric) {
+		a, b = b, a
+	}
+
+	switch a.MatchType {
+	case "prefix":
+		ok = strings.HasPrefix(b.Metric, a.Metric)
+	case "suffix":
+		ok = strings.HasSuffix(b.Metric, a.Metric)
+	}
+	if !ok {
+		return model.AggregationRule{}, model.AggregationRule{}, false
+	}
+	return a, b, true
+}
+
+// apiRuleError reports an error saving a rule. When the API rejected
+// individual fields of the rule, each of them is reported against the
+// attribute under p which sets it, so that the error points at the offending
+// configuration. Anything else is reported as a single error under summary.
+func apiRuleError(summary string, err error, p path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var status client.ErrStatus
+	if !errors.As(err, &status) || len(status.FieldErrors()) == 0 {
+		diags.AddError(summary, err.Error())
+		return diags
+	}
+
+	var unmapped []string
+	for _, f := range status.FieldErrors() {
+		m := apiFieldRE.FindStringSubmatch(f.Field)
+		if m == nil || !slices.Contains(ruleAPIFields, m[1]) {
+			unmapped = append(unmapped, fmt.Sprintf("%s: %s", f.Field, f.Message))
+			continue
+		}
+
+		attr := p.AtName(m[1])
+		switch {
+		case m[2] == "":
+		case slices.Contains(ruleAPIMapFields, m[1]):
+			attr = attr.AtMapKey(m[2])
+		case m[1] == "aggregations":
+			// Aggregations are a set, whose elements have no index; the
+			// API names the aggregation in its message.
+		default:
+			i, err := strconv.Atoi(m[2])
+			if err != nil {
+				unmapped = append(unmapped, fmt.Sprintf("%s: %s", f.Field, f.Message))
+				continue
+			}
+			attr = attr.AtListIndex(i)
+		}
+		diags.AddAttributeError(attr, summary, fmt.Sprintf("The API rejected %s: %s (request ID: %s)", f.Field, f.Message, status.RequestID))
+	}
+
+	if len(unmapped) > 0 {
+		diags.AddError(summary, fmt.Sprintf("The API rejected the rule: %s (request ID: %s)", strings.Join(unmapped, "; "), status.RequestID))
+	}
+	return diags
+}
@@ -0,0 +1,537 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/client"
+	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/model"
+)
+
+func TestValidateRule(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rule     model.AggregationRule
+		expected []path.Path
+	}{
+		{
+			name: "valid",
+			rule: model.AggregationRule{Metric: "test_metric", MatchType: "prefix", DropLabels: []string{"pod"}},
+		},
+		{
+			name:     "invalid match_type",
+			rule:     model.AggregationRule{Metric: "test_metric", MatchType: "regex"},
+			expected: []path.Path{path.Root("match_type")},
+		},
+		{
+			name:     "empty exact metric",
+			rule:     model.AggregationRule{MatchType: "exact"},
+			expected: []path.Path{path.Root("metric")},
+		},
+		{
+			name:     "negative priority",
+			rule:     model.AggregationRule{Metric: "test_metric", Priority: -1},
+			expected: []path.Path{path.Root("priority")},
+		},
+		{
+			name:     "rollout percentage above 100",
+			rule:     model.AggregationRule{Metric: "test_metric", RolloutPercentage: ptr(int64(101))},
+			expected: []path.Path{path.Root("rollout_percentage")},
+		},
+		{
+			name: "zero rollout percentage",
+			rule: model.AggregationRule{Metric: "test_metric", RolloutPercentage: ptr(int64(0))},
+		},
+		{
+			name: "ingest sample rate",
+			rule: model.AggregationRule{Metric: "test_metric", IngestSampleRate: ptr(0.01)},
+		},
+		{
+			name: "full ingest sample rate",
+			rule: model.AggregationRule{Metric: "test_metric", IngestSampleRate: ptr(1.0)},
+		},
+		{
+			name:     "zero ingest sample rate",
+			rule:     model.AggregationRule{Metric: "test_metric", IngestSampleRate: ptr(0.0)},
+			expected: []path.Path{path.Root("ingest_sample_rate")},
+		},
+		{
+			name:     "ingest sample rate above 1",
+			rule:     model.AggregationRule{Metric: "test_metric", IngestSampleRate: ptr(50.0)},
+			expected: []path.Path{path.Root("ingest_sample_rate")},
+		},
+		{
+			name:     "ingest sample rate of dropped metric",
+			rule:     model.AggregationRule{Metric: "test_metric", Drop: true, IngestSampleRate: ptr(0.5)},
+			expected: []path.Path{path.Root("ingest_sample_rate")},
+		},
+		{
+			name:     "keep and drop labels",
+			rule:     model.AggregationRule{Metric: "test_metric", KeepLabels: []string{"namespace"}, DropLabels: []string{"pod"}},
+			expected: []path.Path{path.Root("keep_labels")},
+		},
+		{
+			name: "all aggregations",
+			rule: model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"*"}},
+		},
+		{
+			name:     "all and explicit aggregations",
+			rule:     model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"*", "sum"}},
+			expected: []path.Path{path.Root("aggregations")},
+		},
+		{
+			name: "percentiles",
+			rule: model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"p5", "p50", "p99", "p99.9", "p0.1"}},
+		},
+		{
+			name: "invalid percentiles",
+			rule: model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"sum", "p100", "p0", "p05", "p9x", "percentile:0.99"}},
+			expected: []path.Path{
+				path.Root("aggregations"),
+				path.Root("aggregations"),
+				path.Root("aggregations"),
+				path.Root("aggregations"),
+				path.Root("aggregations"),
+			},
+		},
+		{
+			name: "mixed aggregation intervals",
+			rule: model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"sum", "max"}, AggregationInterval: "1m", AggregationIntervals: map[string]string{"max": "5m"}},
+		},
+		{
+			name: "invalid aggregation intervals",
+			rule: model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, Aggregations: []string{"sum", "max"}, AggregationIntervals: map[string]string{"max": "5 minutes", "count": "1m"}},
+			expected: []path.Path{
+				path.Root("aggregation_intervals").AtMapKey("count"),
+				path.Root("aggregation_intervals").AtMapKey("max"),
+			},
+		},
+		{
+			name: "label patterns",
+			rule: model.AggregationRule{Metric: "test_metric", KeepLabels: []string{"k8s_*", "job"}, LabelMatchMode: "glob", Aggregations: []string{"sum"}},
+		},
+		{
+			name: "invalid label patterns",
+			rule: model.AggregationRule{Metric: "test_metric", DropLabels: []string{"k8s_.*", "pod(", "[a-z"}, LabelMatchMode: "regex", Aggregations: []string{"sum"}},
+			expected: []path.Path{
+				path.Root("drop_labels").AtListIndex(1),
+				path.Root("drop_labels").AtListIndex(2),
+			},
+		},
+		{
+			name:     "invalid label match mode",
+			rule:     model.AggregationRule{Metric: "test_metric", DropLabels: []string{"pod"}, LabelMatchMode: "prefix", Aggregations: []string{"sum"}},
+			expected: []path.Path{path.Root("label_match_mode")},
+		},
+		{
+			name: "conditional drop",
+			rule: model.AggregationRule{Metric: "test_metric", Drop: true, DropIf: []model.DropMatcher{{Label: "env", Operator: "=~", Value: "test|dev"}}},
+		},
+		{
+			name:     "conditional drop without drop",
+			rule:     model.AggregationRule{Metric: "test_metric", DropIf: []model.DropMatcher{{Label: "env", Operator: "=", Value: "test"}}},
+			expected: []path.Path{path.Root("drop_if")},
+		},
+		{
+			name: "invalid drop matchers",
+			rule: model.AggregationRule{Metric: "test_metric", Drop: true, DropIf: []model.DropMatcher{
+				{Label: "1env", Operator: "=", Value: "test"},
+				{Label: "env", Operator: "==", Value: "test"},
+				{Label: "env", Operator: "!~", Value: "test("},
+			}},
+			expected: []path.Path{
+				path.Root("drop_if").AtListIndex(0).AtName("label"),
+				path.Root("drop_if").AtListIndex(1).AtName("operator"),
+				path.Root("drop_if").AtListIndex(2).AtName("value"),
+			},
+		},
+		{
+			name: "negative drop matchers",
+			rule: model.AggregationRule{Metric: "test_metric", Drop: true, DropIf: []model.DropMatcher{
+				{Label: "env", Operator: "!=", Value: "production"},
+				{Label: "cluster", Operator: "!~", Value: "prod-.*|eu-.*"},
+				{Label: "team", Operator: "=", Value: "payments"},
+				{Label: "team", Operator: "!~", Value: "checkout.*"},
+			}},
+		},
+		{
+			name: "conflicting drop matchers",
+			rule: model.AggregationRule{Metric: "test_metric", Drop: true, DropIf: []model.DropMatcher{
+				{Label: "env", Operator: "=", Value: "test"},
+				{Label: "env", Operator: "!=", Value: "test"},
+				{Label: "env", Operator: "=", Value: "dev"},
+				{Label: "cluster", Operator: "=", Value: "prod-1"},
+				{Label: "cluster", Operator: "!~", Value: "prod-.*"},
+				{Label: "job", Operator: "=", Value: "api"},
+				{Label: "job", Operator: "=~", Value: "web|db"},
+			}},
+			expected: []path.Path{
+				path.Root("drop_if").AtListIndex(1),
+				path.Root("drop_if").AtListIndex(2),
+				path.Root("drop_if").AtListIndex(4),
+				path.Root("drop_if").AtListIndex(6),
+			},
+		},
+		{
+			name: "delay overrides",
+			rule: model.AggregationRule{Metric: "test_metric", Aggregations: []string{"sum"}, AggregationDelay: "1m", DelayOverrides: []model.DelayOverride{
+				{Matcher: model.DropMatcher{Label: "source", Operator: "=", Value: "edge"}, Delay: "10m"},
+				{Matcher: model.DropMatcher{Label: "region", Operator: "!~", Value: "eu-.*"}, Delay: "0"},
+			}},
+		},
+		{
+			name: "invalid delay overrides",
+			rule: model.AggregationRule{Metric: "test_metric", Aggregations: []string{"sum"}, DelayOverrides: []model.DelayOverride{
+				{Matcher: model.DropMatcher{Label: "source", Operator: "=", Value: "edge"}, Delay: "ten minutes"},
+				{Matcher: model.DropMatcher{Label: "1source", Operator: "=", Value: "edge"}, Delay: "5m"},
+				{Matcher: model.DropMatcher{Label: "region", Operator: "=~", Value: "ap-("}, Delay: ""},
+			}},
+			expected: []path.Path{
+				path.Root("delay_overrides").AtListIndex(0).AtName("delay"),
+				path.Root("delay_overrides").AtListIndex(1).AtName("label"),
+				path.Root("delay_overrides").AtListIndex(2).AtName("value"),
+				path.Root("delay_overrides").AtListIndex(2).AtName("delay"),
+			},
+		},
+		{
+			name: "tags",
+			rule: model.AggregationRule{Metric: "test_metric", Drop: true, Tags: map[string]string{"owner": "team-a", "app.kubernetes.io/name": "", "ok": strings.Repeat("x", 256)}},
+			expected: []path.Path{
+				path.Root("tags").AtMapKey("app.kubernetes.io/name"),
+				path.Root("tags").AtMapKey("ok"),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := validateRule(tc.rule, path.Empty())
+			require.Equal(t, tc.expected, errorPaths(diags))
+		})
+	}
+}
+
+func TestValidateBroadMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rule     model.AggregationRule
+		expected []path.Path
+	}{
+		{name: "exact", rule: model.AggregationRule{Metric: "up"}},
+		{name: "long prefix", rule: model.AggregationRule{Metric: "kube", MatchType: "prefix"}},
+		{name: "minimum length suffix", rule: model.AggregationRule{Metric: "_ms", MatchType: "suffix"}},
+		{name: "empty prefix", rule: model.AggregationRule{MatchType: "prefix"}, expected: []path.Path{path.Root("metric")}},
+		{name: "short suffix", rule: model.AggregationRule{Metric: "_s", MatchType: "suffix"}, expected: []path.Path{path.Root("metric")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := validateBroadMatch(tc.rule, 3, path.Empty())
+			require.Equal(t, tc.expected, errorPaths(diags))
+		})
+	}
+}
+
+func TestValidateRequiredKeepLabels(t *testing.T) {
+	required := []string{"cluster", "namespace"}
+	for _, tc := range []struct {
+		name     string
+		rule     model.AggregationRule
+		expected []path.Path
+	}{
+		{name: "keeps required", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"namespace", "job", "cluster"}}},
+		{name: "keep misses one", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"cluster", "job"}}, expected: []path.Path{path.Root("keep_labels")}},
+		{name: "keep misses both", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"job"}}, expected: []path.Path{path.Root("keep_labels"), path.Root("keep_labels")}},
+		{name: "drops others", rule: model.AggregationRule{Metric: "up", DropLabels: []string{"pod", "instance"}}},
+		{name: "drops required", rule: model.AggregationRule{Metric: "up", DropLabels: []string{"pod", "namespace"}}, expected: []path.Path{path.Root("drop_labels").AtListIndex(1)}},
+		{name: "drops metric", rule: model.AggregationRule{Metric: "up", Drop: true, DropLabels: []string{"cluster"}}},
+		{name: "no labels", rule: model.AggregationRule{Metric: "up", Aggregations: []string{"sum"}}},
+		{name: "keeps required by pattern", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"cluster", "name*"}, LabelMatchMode: "glob"}},
+		{name: "keep pattern misses one", rule: model.AggregationRule{Metric: "up", KeepLabels: []string{"clus.*"}, LabelMatchMode: "regex"}, expected: []path.Path{path.Root("keep_labels")}},
+		{name: "drops required by pattern", rule: model.AggregationRule{Metric: "up", DropLabels: []string{"pod", "*"}, LabelMatchMode: "glob"}, expected: []path.Path{path.Root("drop_labels").AtListIndex(1), path.Root("drop_labels").AtListIndex(1)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := validateRequiredKeepLabels(tc.rule, required, path.Empty())
+			require.Equal(t, tc.expected, errorPaths(diags))
+		})
+	}
+}
+
+func TestValidateAllowedMetric(t *testing.T) {
+	allowed := []string{"team_a_.*", "up"}
+	for _, tc := range []struct {
+		name     string
+		rule     model.AggregationRule
+		allowed  []string
+		expected []path.Path
+	}{
+		{name: "allowed", rule: model.AggregationRule{Metric: "team_a_requests_total"}, allowed: allowed},
+		{name: "allowed exactly", rule: model.AggregationRule{Metric: "up"}, allowed: allowed},
+		{name: "rejected", rule: model.AggregationRule{Metric: "team_b_requests_total"}, allowed: allowed, expected: []path.Path{path.Root("metric")}},
+		{name: "partial match", rule: model.AggregationRule{Metric: "team_b_up"}, allowed: allowed, expected: []path.Path{path.Root("metric")}},
+		{name: "rejected prefix", rule: model.AggregationRule{Metric: "team_a_", MatchType: "prefix", MatchPrefixes: []string{"team_a_", "team_b_"}}, allowed: allowed, expected: []path.Path{path.Root("match_prefixes").AtListIndex(1)}},
+		{name: "empty policy", rule: model.AggregationRule{Metric: "team_b_requests_total"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := validateAllowedMetric(tc.rule, tc.allowed, path.Empty())
+			require.Equal(t, tc.expected, errorPaths(diags))
+		})
+	}
+
+	diags := validateAllowedMetric(model.AggregationRule{Metric: "team_b_up"}, allowed, path.Empty())
+	require.Equal(t, "Metric not allowed", diags.Errors()[0].Summary())
+	require.Contains(t, diags.Errors()[0].Detail(), `"team_b_up"`)
+	require.Contains(t, diags.Errors()[0].Detail(), "team_a_.*, up")
+}
+
+func TestValidateAggregationTypes(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		metricType   string
+		aggregations []string
+		expected     []int
+	}{
+		{name: "counter", metricType: "counter", aggregations: []string{"sum:counter", "count"}},
+		{name: "raw counter", metricType: "counter", aggregations: []string{"sum:counter", "sum", "max", "p99"}, expected: []int{1, 2, 3}},
+		{name: "histogram", metricType: "histogram", aggregations: []string{"sum", "sum:counter"}, expected: []int{0}},
+		{name: "gauge", metricType: "gauge", aggregations: []string{"sum", "min", "max", "count"}},
+		{name: "counter aggregation of gauge", metricType: "gauge", aggregations: []string{"max", "sum:counter"}, expected: []int{1}},
+		{name: "all aggregations", metricType: "counter", aggregations: []string{"*"}},
+		{name: "unknown type", metricType: "unknown", aggregations: []string{"sum", "sum:counter"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := validateAggregationTypes(model.AggregationRule{Metric: "up", Aggregations: tc.aggregations}, tc.metricType, path.Empty())
+			require.False(t, diags.HasError())
+
+			var expected []path.Path
+			for _, i := range tc.expected {
+				expected = append(expected, path.Root("aggregations").AtSetValue(types.StringValue(tc.aggregations[i])))
+			}
+			var paths []path.Path
+			for _, d := range diags.Warnings() {
+				paths = append(paths, d.(diag.DiagnosticWithPath).Path())
+			}
+			require.Equal(t, expected, paths)
+		})
+	}
+}
+
+func TestValidateAggregationDelay(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		interval string
+		delay    string
+		invalid  bool
+	}{
+		{name: "server defaults", interval: "", delay: ""},
+		{name: "default interval", interval: "", delay: "10s"},
+		{name: "default delay", interval: "1m", delay: ""},
+		{name: "equal", interval: "1m", delay: "60s"},
+		{name: "greater", interval: "1m", delay: "1m30s"},
+		{name: "lesser", interval: "5m", delay: "1m", invalid: true},
+		{name: "lesser in days", interval: "1d", delay: "12h", invalid: true},
+		{name: "unparsable", interval: "1m", delay: "soon"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := model.AggregationRule{Metric: "up", AggregationInterval: tc.interval, AggregationDelay: tc.delay}
+
+			var expected []path.Path
+			if tc.invalid {
+				expected = []path.Path{path.Root("aggregation_delay")}
+			}
+			require.Equal(t, expected, errorPaths(validateAggregationDelay(rule, false, path.Empty())))
+
+			diags := validateAggregationDelay(rule, true, path.Empty())
+			require.False(t, diags.HasError())
+			require.Equal(t, tc.invalid, len(diags.Warnings()) == 1)
+		})
+	}
+}
+
+func TestValidateRuleSetWarnsAboutOverlappingPriorities(t *testing.T) {
+	rules := []*model.AggregationRule{
+		{Metric: "kube_", MatchType: "prefix"},
+		{Metric: "kube_pod_info"},
+		{Metric: "node_", MatchType: "prefix"},
+		{Metric: "kube_node_info", Priority: 1},
+	}
+
+	diags := validateRuleSet(rules)
+	require.False(t, diags.HasError())
+	require.Len(t, diags.Warnings(), 1)
+	require.Equal(t, path.Root("rules").AtListIndex(1).AtName("priority"), diags.Warnings()[0].(diag.DiagnosticWithPath).Path())
+}
+
+func TestValidateRuleDependencies(t *testing.T) {
+	rules := []*model.AggregationRule{{Metric: "a"}, {Metric: "b"}, {Metric: "c"}}
+	p := path.Root("depends_on_metric")
+	dependsOn := func(pairs ...string) map[string]types.String {
+		m := make(map[string]types.String)
+		for i := 0; i < len(pairs); i += 2 {
+			m[pairs[i]] = types.StringValue(pairs[i+1])
+		}
+		return m
+	}
+
+	require.Empty(t, validateRuleDependencies(rules, dependsOn("a", "b", "b", "c"), p))
+	require.Equal(t, []path.Path{p.AtMapKey("a"), p.AtMapKey("x")}, errorPaths(validateRuleDependencies(rules, dependsOn("a", "y", "x", "c"), p)))
+	require.Equal(t, []path.Path{p.AtMapKey("a")}, errorPaths(validateRuleDependencies(rules, dependsOn("a", "a"), p)))
+	require.Equal(t, []path.Path{p.AtMapKey("a"), p.AtMapKey("b"), p.AtMapKey("c")}, errorPaths(validateRuleDependencies(rules, dependsOn("a", "b", "b", "c", "c", "a"), p)))
+
+	// Rules which are not known yet are not checked.
+	require.Empty(t, validateRuleDependencies(append(rules, nil), dependsOn("a", "y"), p))
+}
+
+func TestRulesOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     model.AggregationRule
+		expected bool
+	}{
+		{a: model.AggregationRule{Metric: "up"}, b: model.AggregationRule{Metric: "up", MatchType: "exact"}, expected: true},
+		{a: model.AggregationRule{Metric: "up"}, b: model.AggregationRule{Metric: "down"}, expected: false},
+		{a: model.AggregationRule{Metric: "kube_pod_info"}, b: model.AggregationRule{Metric: "kube_", MatchType: "prefix"}, expected: true},
+		{a: model.AggregationRule{Metric: "node_", MatchType: "prefix"}, b: model.AggregationRule{Metric: "kube_pod_info"}, expected: false},
+		{a: model.AggregationRule{Metric: "kube_pod_info"}, b: model.AggregationRule{Metric: "_info", MatchType: "suffix"}, expected: true},
+		{a: model.AggregationRule{Metric: "kube_", MatchType: "prefix"}, b: model.AggregationRule{Metric: "kube_pod_", MatchType: "prefix"}, expected: true},
+		{a: model.AggregationRule{Metric: "kube_", MatchType: "prefix"}, b: model.AggregationRule{Metric: "node_", MatchType: "prefix"}, expected: false},
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_bytes_total", MatchType: "suffix"}, expected: true},
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_seconds", MatchType: "suffix"}, expected: false},
+		{a: model.AggregationRule{Metric: "_bytes_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_seconds_total", MatchType: "suffix"}, expected: false},
+		{a: model.AggregationRule{Metric: "total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, expected: true},
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_total_bytes", MatchType: "suffix"}, expected: false},
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "kube_", MatchType: "prefix"}, expected: true},
+		{a: model.AggregationRule{Metric: "kube_", MatchType: "prefix", MatchPrefixes: []string{"kube_", "node_"}}, b: model.AggregationRule{Metric: "node_cpu"}, expected: true},
+		{a: model.AggregationRule{Metric: "kube_", MatchType: "prefix", MatchPrefixes: []string{"kube_", "node_"}}, b: model.AggregationRule{Metric: "up"}, expected: false},
+	} {
+		t.Run(tc.a.Metric+"/"+tc.b.Metric, func(t *testing.T) {
+			require.Equal(t, tc.expected, rulesOverlap(tc.a, tc.b))
+			require.Equal(t, tc.expected, rulesOverlap(tc.b, tc.a))
+		})
+	}
+}
+
+func TestNestedRules(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     model.AggregationRule
+		broader  string
+		expected bool
+	}{
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_bytes_total", MatchType: "suffix"}, broader: "_total", expected: true},
+		{a: model.AggregationRule{Metric: "_requests_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "http_requests_total", MatchType: "suffix"}, broader: "_requests_total", expected: true},
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_total_bytes", MatchType: "suffix"}},
+		{a: model.AggregationRule{Metric: "kube_", MatchType: "prefix"}, b: model.AggregationRule{Metric: "kube_pod_", MatchType: "prefix"}, broader: "kube_", expected: true},
+		// A prefix and a suffix rule overlap without being nested.
+		{a: model.AggregationRule{Metric: "_total", MatchType: "suffix"}, b: model.AggregationRule{Metric: "_total", MatchType: "prefix"}},
+		{a: model.AggregationRule{Metric: "kube_pod_info"}, b: model.AggregationRule{Metric: "kube_", MatchType: "prefix"}},
+		{a: model.AggregationRule{Metric: "kube_", MatchType: "prefix", MatchPrefixes: []string{"kube_", "node_"}}, b: model.AggregationRule{Metric: "kube_pod_", MatchType: "prefix"}},
+	} {
+		t.Run(tc.a.Metric+"/"+tc.b.Metric, func(t *testing.T) {
+			for _, args := range [][2]model.AggregationRule{{tc.a, tc.b}, {tc.b, tc.a}} {
+				broader, _, ok := nestedRules(args[0], args[1])
+				require.Equal(t, tc.expected, ok)
+				require.Equal(t, tc.broader, broader.Metric)
+			}
+		})
+	}
+}
+
+func TestValidateRuleSetReportsAllErrors(t *testing.T) {
+	rules := []*model.AggregationRule{
+		{Metric: "a", MatchType: "regex"},
+		{Metric: "b"},
+		nil,
+		{Metric: "c", KeepLabels: []string{"namespace"}, DropLabels: []string{"pod"}},
+		{Metric: "b"},
+	}
+
+	diags := validateRuleSet(rules)
+	require.Equal(t, []path.Path{
+		path.Root("rules").AtListIndex(0).AtName("match_type"),
+		path.Root("rules").AtListIndex(3).AtName("keep_labels"),
+		path.Root("rules").AtListIndex(4).AtName("metric"),
+	}, errorPaths(diags))
+
+	require.Contains(t, diags.Errors()[0].Detail(), `"a"`)
+	require.Contains(t, diags.Errors()[1].Detail(), `"c"`)
+	require.Contains(t, diags.Errors()[2].Detail(), `"b"`)
+}
+
+func errorPaths(diags diag.Diagnostics) []path.Path {
+	var paths []path.Path
+	for _, d := range diags.Errors() {
+		if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+			paths = append(paths, withPath.Path())
+		}
+	}
+	return paths
+}
+
+func TestAPIRuleError(t *testing.T) {
+	rejected := func(body string) error {
+		// The error is wrapped as it is when returned by AggregationRules.
+		return fmt.Errorf("failed to create rule for test_metric: %w", client.ErrStatus{StatusCode: 400, BodyContents: []byte(body), RequestID: "req-1"})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		err      error
+		expected []path.Path
+		generic  int
+	}{
+		{
+			name:    "not a status error",
+			err:     errors.New("connection refused"),
+			generic: 1,
+		},
+		{
+			name:    "unstructured body",
+			err:     rejected("invalid aggregation"),
+			generic: 1,
+		},
+		{
+			name:     "field errors",
+			err:      rejected(`{"field_errors": [{"field": "aggregations", "message": "unknown type avg"}, {"field": "keep_labels[1]", "message": "reserved label"}]}`),
+			expected: []path.Path{path.Root("aggregations"), path.Root("keep_labels").AtListIndex(1)},
+		},
+		{
+			name:     "set and map elements",
+			err:      rejected(`{"field_errors": [{"field": "aggregations[2]", "message": "unknown type avg"}, {"field": "tags[owner]", "message": "too long"}, {"field": "aggregation_intervals[max]", "message": "too short"}]}`),
+			expected: []path.Path{path.Root("aggregations"), path.Root("tags").AtMapKey("owner"), path.Root("aggregation_intervals").AtMapKey("max")},
+		},
+		{
+			name:    "key of a list",
+			err:     rejected(`{"field_errors": [{"field": "keep_labels[pod]", "message": "reserved label"}]}`),
+			generic: 1,
+		},
+		{
+			name:     "unknown field",
+			err:      rejected(`{"field_errors": [{"field": "drop", "message": "not allowed"}, {"field": "tenant", "message": "over quota"}]}`),
+			expected: []path.Path{path.Root("drop")},
+			generic:  1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diags := apiRuleError("Unable to create aggregation rule", tc.err, path.Empty())
+			require.Equal(t, tc.expected, errorPaths(diags))
+			require.Len(t, diags.Errors(), len(tc.expected)+tc.generic)
+		})
+	}
+}
+
+func TestValidateMetricList(t *testing.T) {
+	diags := validateMetricList([]types.String{
+		types.StringValue("http_requests_total"),
+		types.StringValue("job:rate5m"),
+		types.StringValue("1xx_responses"),
+		types.StringUnknown(),
+		types.StringValue("http_requests_total"),
+		types.StringValue("kube-pod"),
+	}, path.Root("metrics"))
+	require.Len(t, diags.Errors(), 3)
+	require.Equal(t, "Invalid metric", diags.Errors()[0].Summary())
+	require.Equal(t, "Duplicate metric", diags.Errors()[1].Summary())
+	require.Equal(t, "Invalid metric", diags.Errors()[2].Summary())
+}
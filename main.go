@@ -8,6 +8,7 @@ import (
 	"flag"
 	"log"
 
+	fwprovider "github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-provider-grafana-adaptive-metrics/internal/provider"
 )
@@ -42,7 +43,14 @@ func main() {
 		Debug:   debug,
 	}
 
-	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	p := provider.New(version)()
+	err := providerserver.Serve(context.Background(), func() fwprovider.Provider { return p }, opts)
+	// The locks are released first, as the provider is killed shortly after
+	// the server stops.
+	provider.ReleaseApplyLocks(p)
+	provider.LogOperationSummary(p)
+	provider.CloseHTTPLogs(p)
+	provider.CloseAuditLogs(p)
 
 	if err != nil {
 		log.Fatal(err.Error())